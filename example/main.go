@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/ivikasavnish/go-mcp/pkg/mcp"
 	"github.com/ivikasavnish/go-mcp/pkg/specprocessor"
 )
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Create and start MCP server
 	server := mcp.NewServer(nil) // Using default in-memory store
 	go func() {
@@ -28,6 +35,12 @@ func main() {
 		log.Fatalf("Failed to process specifications: %v", err)
 	}
 
-	// Keep the server running
-	select {}
+	// Keep the server running until interrupted
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown error: %v", err)
+	}
 }
@@ -1,21 +1,86 @@
 package main
 
 import (
-  "fmt"
-)
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-//TIP <p>To run your code, right-click the code and select <b>Run</b>.</p> <p>Alternatively, click
-// the <icon src="AllIcons.Actions.Execute"/> icon in the gutter and select the <b>Run</b> menu item from here.</p>
+	"github.com/ivikasavnish/go-mcp/pkg/mcp"
+)
 
 func main() {
-  //TIP <p>Press <shortcut actionId="ShowIntentionActions"/> when your caret is at the underlined text
-  // to see how GoLand suggests fixing the warning.</p><p>Alternatively, if available, click the lightbulb to view possible fixes.</p>
-  s := "gopher"
-  fmt.Println("Hello and welcome, %s!", s)
-
-  for i := 1; i <= 5; i++ {
-	//TIP <p>To start your debugging session, right-click your code in the editor and select the Debug option.</p> <p>We have set one <icon src="AllIcons.Debugger.Db_set_breakpoint"/> breakpoint
-	// for you, but you can always add more by pressing <shortcut actionId="ToggleLineBreakpoint"/>.</p>
-	fmt.Println("i =", 100/i)
-  }
-}
\ No newline at end of file
+	cfg, err := mcp.LoadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	server, err := mcp.NewServerFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create MCP server: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger := server.Logger()
+
+	// Upstream MCP servers are connected in the background; failures are
+	// logged and do not keep the server from starting
+	if len(cfg.Upstreams) > 0 {
+		go server.ConnectUpstreams(ctx)
+	}
+
+	// MCP hosts talk to the server over stdin and stdout, so it does not
+	// listen; logs go to stderr
+	if cfg.Stdio {
+		logger.Info("MCP server serving stdio", "features", cfg.EnabledFeatures())
+		if err := server.ServeStdio(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+			logger.Error("stdio transport failed", "error", err)
+		}
+	} else if cfg.LSPStdio {
+		// Editors launch the server as their language server
+		logger.Info("MCP server serving the Language Server Protocol over stdio", "workspace", cfg.WorkspaceRoot)
+		if err := server.ServeLSP(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+			logger.Error("lsp transport failed", "error", err)
+		}
+	} else {
+		go func() {
+			logger.Info("MCP server listening", "addr", cfg.ListenAddr, "features", cfg.EnabledFeatures())
+			if err := server.Start(cfg.ListenAddr); err != nil {
+				logger.Error("failed to start MCP server", "error", err)
+				os.Exit(1)
+			}
+		}()
+
+		if cfg.GRPCListenAddr != "" {
+			go func() {
+				logger.Info("MCP gRPC server listening", "addr", cfg.GRPCListenAddr)
+				if err := server.StartGRPC(cfg.GRPCListenAddr); err != nil {
+					logger.Error("failed to start MCP gRPC server", "error", err)
+					os.Exit(1)
+				}
+			}()
+		}
+
+		if cfg.Proxy.Enabled {
+			go func() {
+				logger.Info("MCP recording proxy listening", "addr", cfg.Proxy.ListenAddr, "target", cfg.Proxy.Target)
+				if err := server.StartProxy(cfg.Proxy.ListenAddr); err != nil {
+					logger.Error("failed to start MCP recording proxy", "error", err)
+					os.Exit(1)
+				}
+			}()
+		}
+
+		<-ctx.Done()
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("shutdown failed", "error", err)
+	}
+}
@@ -2,6 +2,7 @@ package browser
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,12 +11,17 @@ import (
 	"github.com/go-rod/rod/lib/proto"
 )
 
+// ErrNoBrowser is returned when no Chromium or Chrome binary is installed
+var ErrNoBrowser = errors.New("no chromium or chrome binary found")
+
 // Browser manages browser automation
 type Browser struct {
-	config  *BrowserConfig
-	browser *rod.Browser
-	ctx     context.Context
-	cancel  context.CancelFunc
+	config   *BrowserConfig
+	browser  *rod.Browser
+	page     *rod.Page // Last page navigated to
+	launcher *launcher.Launcher
+	ctx      context.Context
+	cancel   context.CancelFunc
 }
 
 // NewBrowser creates a new browser instance
@@ -28,41 +34,78 @@ func NewBrowser(config *BrowserConfig) *Browser {
 	}
 }
 
-// Start initializes and starts the browser
+// Start initializes and starts the browser. It uses an installed Chromium
+// or Chrome rather than downloading one, and fails with ErrNoBrowser when
+// there is none.
 func (b *Browser) Start() error {
-	url := launcher.New().
+	bin, ok := launcher.LookPath()
+	if !ok {
+		return ErrNoBrowser
+	}
+	l := launcher.New().
+		Bin(bin).
 		Headless(b.config.Headless).
-		Proxy(b.config.Proxy).
-		MustLaunch()
+		Proxy(b.config.Proxy)
+	url, err := l.Launch()
+	if err != nil {
+		return fmt.Errorf("launch %s: %w", bin, err)
+	}
+	b.launcher = l
 
-	browser := rod.New().ControlURL(url).MustConnect()
+	browser := rod.New().ControlURL(url)
+	if err := browser.Connect(); err != nil {
+		b.Stop()
+		return fmt.Errorf("connect to browser: %w", err)
+	}
+	b.browser = browser
 
 	if b.config.UserAgent != "" {
-		browser = browser.MustIncognito()
+		incognito, err := browser.Incognito()
+		if err != nil {
+			b.Stop()
+			return fmt.Errorf("open incognito context: %w", err)
+		}
+		b.browser = incognito
 	}
-
-	b.browser = browser
 	return nil
 }
 
 // Stop closes the browser
 func (b *Browser) Stop() error {
+	defer b.cancel()
+
+	var err error
 	if b.browser != nil {
-		b.browser.MustClose()
+		err = b.browser.Close()
+		b.browser = nil
+		b.page = nil
 	}
-	b.cancel()
-	return nil
+
+	// Make sure the browser process is gone even if the CDP close failed
+	if b.launcher != nil {
+		b.launcher.Kill()
+		b.launcher.Cleanup()
+		b.launcher = nil
+	}
+
+	return err
 }
 
 // Navigate navigates to a URL and returns the result
 func (b *Browser) Navigate(url string) (*NavigationResult, error) {
 	start := time.Now()
 
-	page := b.browser.MustPage(url)
+	page, err := b.browser.Page(proto.TargetCreateTarget{URL: url})
+	if err != nil {
+		return nil, err
+	}
 	if b.config.UserAgent != "" {
-		page.MustSetUserAgent(&proto.NetworkSetUserAgentOverride{
+		err := page.SetUserAgent(&proto.NetworkSetUserAgentOverride{
 			UserAgent: b.config.UserAgent,
 		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if b.config.ViewPort != nil {
@@ -74,13 +117,19 @@ func (b *Browser) Navigate(url string) (*NavigationResult, error) {
 		//page.MustSetExtraHeaders(map[string]string{key: value})
 	}
 
-	// Wait for network idle
-	page.MustWaitNavigation()
+	if err := page.WaitLoad(); err != nil {
+		return nil, err
+	}
+	b.page = page
 
+	info, err := page.Info()
+	if err != nil {
+		return nil, err
+	}
 	result := &NavigationResult{
 		Success:  true,
-		URL:      page.MustInfo().URL,
-		Title:    page.MustInfo().Title,
+		URL:      info.URL,
+		Title:    info.Title,
 		LoadTime: time.Since(start).Seconds(),
 	}
 
@@ -89,7 +138,10 @@ func (b *Browser) Navigate(url string) (*NavigationResult, error) {
 
 // ExecuteSequence executes an automation sequence
 func (b *Browser) ExecuteSequence(seq *AutomationSequence) error {
-	page := b.browser.MustPage("")
+	page, err := b.browser.Page(proto.TargetCreateTarget{})
+	if err != nil {
+		return err
+	}
 
 	for _, step := range seq.Steps {
 		if err := b.executeStep(page, step); err != nil {
@@ -119,14 +171,25 @@ func (b *Browser) executeStep(page *rod.Page, step AutomationStep) error {
 		if !ok {
 			return fmt.Errorf("invalid url parameter")
 		}
-		page.MustNavigate(url).MustWaitNavigation()
+		if err := page.Context(ctx).Navigate(url); err != nil {
+			return err
+		}
+		if err := page.Context(ctx).WaitLoad(); err != nil {
+			return err
+		}
 
 	case "click":
 		selector, ok := step.Params["selector"].(string)
 		if !ok {
 			return fmt.Errorf("invalid selector parameter")
 		}
-		page.MustElement(selector).MustClick()
+		el, err := page.Context(ctx).Element(selector)
+		if err != nil {
+			return err
+		}
+		if err := el.Click(proto.InputMouseButtonLeft, 1); err != nil {
+			return err
+		}
 
 	case "type":
 		selector, ok := step.Params["selector"].(string)
@@ -137,7 +200,13 @@ func (b *Browser) executeStep(page *rod.Page, step AutomationStep) error {
 		if !ok {
 			return fmt.Errorf("invalid text parameter")
 		}
-		page.MustElement(selector).MustInput(text)
+		el, err := page.Context(ctx).Element(selector)
+		if err != nil {
+			return err
+		}
+		if err := el.Input(text); err != nil {
+			return err
+		}
 
 	case "screenshot":
 		format, _ := step.Params["format"].(string)
@@ -146,11 +215,8 @@ func (b *Browser) executeStep(page *rod.Page, step AutomationStep) error {
 		}
 		fullPage, _ := step.Params["full_page"].(bool)
 
-		var _ []byte
-		if fullPage {
-			_ = page.MustScreenshotFullPage()
-		} else {
-			_ = page.MustScreenshot()
+		if _, err := page.Context(ctx).Screenshot(fullPage, nil); err != nil {
+			return err
 		}
 
 		// Store screenshot in context or return it
@@ -160,10 +226,22 @@ func (b *Browser) executeStep(page *rod.Page, step AutomationStep) error {
 		if !ok {
 			return fmt.Errorf("invalid selector parameter")
 		}
-		elements := page.MustElements(selector)
+		elements, err := page.Context(ctx).Elements(selector)
+		if err != nil {
+			return err
+		}
 		_ = elements
 		// Process scraped elements
 
+	case StepEval:
+		script, ok := step.Params["script"].(string)
+		if !ok {
+			return fmt.Errorf("invalid script parameter")
+		}
+		if _, err := page.Context(ctx).Eval(script); err != nil {
+			return fmt.Errorf("script failed: %w", err)
+		}
+
 	default:
 		return fmt.Errorf("unknown step type: %s", step.Type)
 	}
@@ -171,24 +249,36 @@ func (b *Browser) executeStep(page *rod.Page, step AutomationStep) error {
 	return nil
 }
 
-// Scrape extracts data from the current page using selectors
+// Scrape extracts data from the page last navigated to using selectors
 func (b *Browser) Scrape(selectors map[string]string) (*ScrapingResult, error) {
-	page := b.browser.MustPage("")
+	page := b.page
+	if page == nil {
+		return nil, fmt.Errorf("no page loaded")
+	}
+	info, err := page.Info()
+	if err != nil {
+		return nil, err
+	}
 	result := &ScrapingResult{
-		URL:       page.MustInfo().URL,
+		URL:       info.URL,
 		Data:      make(map[string]interface{}),
 		Timestamp: time.Now(),
 	}
 
 	for key, selector := range selectors {
-		elements := page.MustElements(selector)
-		if len(elements) == 1 {
-			result.Data[key] = elements[0].MustText()
-		} else {
-			texts := make([]string, len(elements))
-			for i, el := range elements {
-				texts[i] = el.MustText()
+		elements, err := page.Elements(selector)
+		if err != nil {
+			return nil, err
+		}
+		texts := make([]string, len(elements))
+		for i, el := range elements {
+			if texts[i], err = el.Text(); err != nil {
+				return nil, err
 			}
+		}
+		if len(texts) == 1 {
+			result.Data[key] = texts[0]
+		} else {
 			result.Data[key] = texts
 		}
 	}
@@ -198,13 +288,14 @@ func (b *Browser) Scrape(selectors map[string]string) (*ScrapingResult, error) {
 
 // CaptureScreenshot takes a screenshot of the current page
 func (b *Browser) CaptureScreenshot(fullPage bool) (*Screenshot, error) {
-	page := b.browser.MustPage("")
+	page, err := b.browser.Page(proto.TargetCreateTarget{})
+	if err != nil {
+		return nil, err
+	}
 
-	var buf []byte
-	if fullPage {
-		buf = page.MustScreenshotFullPage()
-	} else {
-		buf = page.MustScreenshot()
+	buf, err := page.Screenshot(fullPage, nil)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Screenshot{
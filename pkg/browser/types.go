@@ -53,6 +53,10 @@ type AutomationStep struct {
 	Wait    time.Duration          `json:"wait,omitempty"`
 }
 
+// StepEval runs arbitrary JavaScript, a function such as "() => ...", in the
+// page. Servers may disable it.
+const StepEval = "eval"
+
 // AutomationSequence represents a sequence of automation steps
 type AutomationSequence struct {
 	Name        string           `json:"name"`
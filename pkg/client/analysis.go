@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// HotspotOptions filter a hotspot report; zero values use server defaults
+type HotspotOptions struct {
+	Since        string // Only count commits since this date, e.g. 3.months
+	Limit        int
+	Path         string // Only include files under this path
+	IncludeTests bool
+	Sort         string // score, commits, lines or authors
+}
+
+// AnalyzeFile analyzes the Go source in content; uri names the file in
+// locations
+func (c *Client) AnalyzeFile(ctx context.Context, uri, content string) (*AnalysisResult, error) {
+	var result AnalysisResult
+	if err := c.analyze(ctx, "/analyze/file", uri, content, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AnalyzeDependencies lists the used imports of Go source by package name
+func (c *Client) AnalyzeDependencies(ctx context.Context, uri, content string) (map[string][]string, error) {
+	var deps map[string][]string
+	if err := c.analyze(ctx, "/analyze/dependencies", uri, content, &deps); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// AnalyzeMetrics computes the code metrics of Go source
+func (c *Client) AnalyzeMetrics(ctx context.Context, uri, content string) (*CodeMetrics, error) {
+	var metrics CodeMetrics
+	if err := c.analyze(ctx, "/analyze/metrics", uri, content, &metrics); err != nil {
+		return nil, err
+	}
+	return &metrics, nil
+}
+
+func (c *Client) analyze(ctx context.Context, path, uri, content string, out interface{}) error {
+	req := map[string]string{"uri": uri, "content": content}
+	return c.do(ctx, http.MethodPost, path, req, out)
+}
+
+// Hotspots ranks the files of the server's workspace by churn and complexity
+func (c *Client) Hotspots(ctx context.Context, opts HotspotOptions) (*HotspotReport, error) {
+	query := url.Values{}
+	if opts.Since != "" {
+		query.Set("since", opts.Since)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Path != "" {
+		query.Set("path", opts.Path)
+	}
+	if opts.IncludeTests {
+		query.Set("include_tests", "true")
+	}
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+
+	path := "/analyze/hotspots"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var report HotspotReport
+	if err := c.do(ctx, http.MethodGet, path, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
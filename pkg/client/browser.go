@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateBrowser starts a browser instance on the server, registered under id
+func (c *Client) CreateBrowser(ctx context.Context, id string, config BrowserConfig) error {
+	req := map[string]interface{}{"id": id, "config": config}
+	return c.do(ctx, http.MethodPost, "/browser/create", req, nil)
+}
+
+// CloseBrowser stops a browser instance
+func (c *Client) CloseBrowser(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/browser/"+url.PathEscape(id), nil, nil)
+}
+
+// Navigate loads a URL in a browser instance
+func (c *Client) Navigate(ctx context.Context, id, pageURL string) (*NavigationResult, error) {
+	req := map[string]string{"url": pageURL}
+	var result NavigationResult
+	if err := c.do(ctx, http.MethodPost, "/browser/"+url.PathEscape(id)+"/navigate", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Automate runs an automation sequence in a browser instance
+func (c *Client) Automate(ctx context.Context, id string, sequence AutomationSequence) error {
+	req := map[string]interface{}{"sequence": sequence}
+	return c.do(ctx, http.MethodPost, "/browser/"+url.PathEscape(id)+"/automate", req, nil)
+}
@@ -0,0 +1,326 @@
+// Package client is a Go client for the go-mcp HTTP API. It covers contexts,
+// function calls, SSH, browser automation and code analysis, retrying
+// requests that failed transiently.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIVersion is the API version the client speaks
+const APIVersion = "v1"
+
+// Defaults for Client
+const (
+	DefaultRetries = 2
+	DefaultBackoff = 200 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+)
+
+// Errors matched by errors.Is against an *Error
+var (
+	ErrNotFound              = errors.New("not found")
+	ErrConflict              = errors.New("conflict")
+	ErrForbidden             = errors.New("forbidden")
+	ErrFeatureDisabled       = errors.New("feature disabled")
+	ErrRateLimited           = errors.New("rate limited")
+	ErrQuotaExceeded         = errors.New("quota exceeded")
+	ErrDependencyUnavailable = errors.New("dependency unavailable")
+)
+
+// Error codes of the server, see Error.Code
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeFeatureDisabled  = "feature_disabled"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeRateLimited      = "rate_limited"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeInternal         = "internal"
+	CodeNotImplemented   = "not_implemented"
+	CodeUpstream         = "upstream_error"
+	CodeUnavailable      = "unavailable"
+	CodeTimeout          = "timeout"
+	CodeFunctionError    = "function_error" // A called function returned an error, named by the "function" detail
+
+	CodeDependencyUnavailable = "dependency_unavailable" // The server host lacks a tool the request needs
+)
+
+// Error is a response with an error status. Responses that are not error
+// envelopes of the server, e.g. from proxies, only have a status and message.
+type Error struct {
+	StatusCode    int
+	Code          string // Machine-readable code, one of the Code constants
+	Message       string // Error message of the server, or the response body
+	Details       map[string]interface{}
+	Retryable     bool   // Repeating the request may succeed
+	CorrelationID string // Request ID of the failed request in server logs
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("mcp: %d %s (%s): %s", e.StatusCode, http.StatusText(e.StatusCode), e.Code, e.Message)
+	}
+	return fmt.Sprintf("mcp: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is matches the sentinel error of the code, or of the status code when the
+// response had no code
+func (e *Error) Is(target error) bool {
+	switch e.Code {
+	case CodeNotFound:
+		return target == ErrNotFound
+	case CodeConflict:
+		return target == ErrConflict
+	case CodeForbidden:
+		return target == ErrForbidden
+	case CodeFeatureDisabled:
+		return target == ErrFeatureDisabled || target == ErrForbidden
+	case CodeRateLimited:
+		return target == ErrRateLimited
+	case CodeQuotaExceeded:
+		return target == ErrQuotaExceeded
+	case CodeDependencyUnavailable:
+		return target == ErrDependencyUnavailable
+	case "":
+		switch e.StatusCode {
+		case http.StatusNotFound:
+			return target == ErrNotFound
+		case http.StatusConflict:
+			return target == ErrConflict
+		case http.StatusForbidden:
+			return target == ErrForbidden
+		case http.StatusTooManyRequests:
+			return target == ErrRateLimited
+		}
+	}
+	return false
+}
+
+// Client calls a go-mcp server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string // Including the API version
+	httpClient *http.Client
+	apiKey     string
+	lease      string
+	retries    int
+	backoff    time.Duration
+	timeout    time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey authenticates requests with an API key
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithRetries sets how often a failed request is retried, 0 to never retry
+func WithRetries(n int) Option {
+	return func(c *Client) {
+		c.retries = n
+	}
+}
+
+// WithBackoff sets the wait before the first retry; it doubles with every
+// further retry unless the server asks for a longer wait with Retry-After
+func WithBackoff(d time.Duration) Option {
+	return func(c *Client) {
+		c.backoff = d
+	}
+}
+
+// WithTimeout bounds each call including its retries. Contexts passed to
+// calls may end them sooner.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// New creates a client for the server at baseURL, e.g. http://localhost:8080.
+// Requests go to the versioned routes under baseURL/v1.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/") + "/" + APIVersion,
+		httpClient: http.DefaultClient,
+		retries:    DefaultRetries,
+		backoff:    DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// do sends a request with body encoded as JSON, unless nil, and decodes the
+// response into out, unless nil. Requests rejected by rate limits or an
+// unavailable server are retried; idempotent requests are also retried on
+// network and gateway errors.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	wait := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, data)
+		retry := false
+		switch {
+		case err != nil:
+			retry = idempotent(method) && ctx.Err() == nil
+		case retryable(method, resp.StatusCode):
+			retry = true
+			if after := retryAfter(resp); after > wait {
+				wait = after
+			}
+		}
+
+		if !retry || attempt >= c.retries {
+			if err != nil {
+				return err
+			}
+			return decodeResponse(resp, out)
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		wait = min(wait*2, maxBackoff)
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, data []byte) (*http.Response, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.lease != "" {
+		req.Header.Set(LeaseHeader, c.lease)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var envelope struct {
+			Error struct {
+				Code          string                 `json:"code"`
+				Message       string                 `json:"message"`
+				Details       map[string]interface{} `json:"details"`
+				Retryable     bool                   `json:"retryable"`
+				CorrelationID string                 `json:"correlation_id"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &envelope) == nil && envelope.Error.Code != "" {
+			e := envelope.Error
+			return &Error{
+				StatusCode:    resp.StatusCode,
+				Code:          e.Code,
+				Message:       e.Message,
+				Details:       e.Details,
+				Retryable:     e.Retryable,
+				CorrelationID: e.CorrelationID,
+			}
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	// An empty body leaves out unchanged
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether a request may succeed later. Rate limited and
+// unavailable requests were not processed; gateway errors leave it unknown,
+// so only idempotent requests are retried.
+func retryable(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent(method)
+	}
+	return false
+}
+
+// retryAfter returns the wait requested by a Retry-After header in seconds
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return min(time.Duration(seconds)*time.Second, maxBackoff)
+}
@@ -0,0 +1,202 @@
+// The tests run against an mcptest server, which depends on this package
+// through the spec processors, so they live in an external test package.
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/client"
+	"github.com/ivikasavnish/go-mcp/pkg/mcp"
+	"github.com/ivikasavnish/go-mcp/pkg/mcptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClient(t *testing.T) (*client.Client, *mcptest.Server) {
+	cfg := mcptest.DefaultConfig()
+	cfg.Features[mcp.FeatureAnalysis] = true
+	s := mcptest.NewServer(t, mcptest.WithConfig(cfg))
+	return client.New(strings.TrimSuffix(s.URL, "/"+mcp.APIVersion)), s
+}
+
+func TestClient_Contexts(t *testing.T) {
+	c, s := newClient(t)
+	ctx := context.Background()
+
+	created, err := c.CreateContext(ctx, "spec", map[string]interface{}{"type": "openapi"})
+	require.NoError(t, err)
+	assert.Equal(t, "spec", created.ID)
+	s.AssertContext("spec", map[string]interface{}{"type": "openapi"})
+
+	_, err = c.CreateContext(ctx, "spec", map[string]interface{}{"type": "curl"})
+	assert.ErrorIs(t, err, client.ErrConflict)
+
+	updated, err := c.UpdateContext(ctx, "spec", map[string]interface{}{"type": "postman"})
+	require.NoError(t, err)
+	assert.Equal(t, "postman", updated.Metadata["type"])
+
+	contexts, err := c.ListContexts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, contexts, 1)
+
+	require.NoError(t, c.DeleteContext(ctx, "spec"))
+	_, err = c.GetContext(ctx, "spec")
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, client.CodeNotFound, apiErr.Code)
+	assert.False(t, apiErr.Retryable)
+	assert.NotEmpty(t, apiErr.CorrelationID)
+}
+
+func TestClient_Labels(t *testing.T) {
+	c, s := newClient(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"pets", "orders"} {
+		_, err := c.CreateContext(ctx, id, map[string]interface{}{"type": "openapi"})
+		require.NoError(t, err)
+	}
+	labeled, err := c.LabelContexts(ctx, client.LabelRequest{IDs: []string{"pets"}, Add: map[string]string{"deprecated": ""}})
+	require.NoError(t, err)
+	require.Len(t, labeled, 1)
+	assert.Equal(t, map[string]string{"deprecated": ""}, labeled[0].Labels)
+
+	contexts, err := c.ListContextsBySelector(ctx, "!deprecated")
+	require.NoError(t, err)
+	require.Len(t, contexts, 1)
+	assert.Equal(t, "orders", contexts[0].ID)
+
+	result, err := c.BulkContexts(ctx, "deprecated", "delete")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pets"}, result.IDs)
+	s.AssertNoContext("pets")
+}
+
+func TestClient_Endpoints(t *testing.T) {
+	c, s := newClient(t)
+	ctx := context.Background()
+
+	result, err := c.CallFunction(ctx, "echo", "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "hi", result)
+	result, err = c.CallFunctionUncached(ctx, "echo", "fresh")
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", result)
+	result, err = c.CallFunctionNamed(ctx, "echo", map[string]interface{}{"arg0": "named"})
+	require.NoError(t, err)
+	assert.Equal(t, "named", result)
+	history, err := c.FunctionHistory(ctx, "echo")
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, map[string]interface{}{"arg0": "[redacted]"}, history[0].Arguments) // Positional arguments are redacted by default
+	plugins, err := c.Plugins(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, plugins)
+	tools, err := c.FunctionTools(ctx, "openai")
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "function", tools[0]["type"])
+	job, err := c.CallFunctionAsync(ctx, "echo", "later")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		job, err = c.FunctionJob(ctx, job.ID)
+		require.NoError(t, err)
+		return job.Done()
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, client.JobSucceeded, job.Status)
+	assert.Equal(t, "later", job.Result)
+
+	s.SSH.Handle("hostname", mcp.CommandResult{Stdout: "web-1\n"})
+	require.NoError(t, c.SSHConnect(ctx, "web", client.SSHConfig{Host: "web", Port: 22, User: "u", Password: "p"}))
+	health, err := c.SSHHealth(ctx, "web")
+	require.NoError(t, err)
+	assert.True(t, health.Connected)
+	forwards, err := c.SSHForwards(ctx, "web")
+	require.NoError(t, err)
+	assert.Empty(t, forwards)
+	_, err = c.SSHForward(ctx, "web", "", "localhost:5432")
+	assert.Error(t, err) // Fake connections cannot dial
+	_, err = c.SSHRemoteForward(ctx, "web", "", "localhost:8080")
+	assert.Error(t, err)
+	out, err := c.SSHExec(ctx, "web", "hostname")
+	require.NoError(t, err)
+	assert.Equal(t, "web-1\n", out.Stdout)
+	require.NoError(t, c.SSHDisconnect(ctx, "web"))
+
+	require.NoError(t, c.CreateBrowser(ctx, "b", client.BrowserConfig{Headless: true}))
+	nav, err := c.Navigate(ctx, "b", "https://example.com")
+	require.NoError(t, err)
+	assert.True(t, nav.Success)
+	require.NoError(t, c.Automate(ctx, "b", client.AutomationSequence{Name: "noop"}))
+	require.NoError(t, c.CloseBrowser(ctx, "b"))
+
+	metrics, err := c.AnalyzeMetrics(ctx, "main.go", "package main\n\nfunc main() {}\n")
+	require.NoError(t, err)
+	assert.Equal(t, 1, metrics.FunctionCount)
+}
+
+func TestClient_Leases(t *testing.T) {
+	c, _ := newClient(t)
+	ctx := context.Background()
+
+	lease, err := c.CreateLease(ctx, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, lease.ExpiresAt)
+	leased := c.WithLease(lease.ID)
+	require.NoError(t, leased.SSHConnect(ctx, "web", client.SSHConfig{Host: "web", Port: 22, User: "u", Password: "p"}))
+
+	lease, err = c.RenewLease(ctx, lease.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []client.LeaseResource{{Kind: "ssh", ID: "web"}}, lease.Resources)
+
+	_, err = c.ReleaseLease(ctx, lease.ID)
+	require.NoError(t, err)
+	_, err = c.SSHExec(ctx, "web", "hostname")
+	assert.ErrorIs(t, err, client.ErrNotFound, "releasing the lease disconnected")
+	assert.ErrorIs(t, leased.SSHDisconnect(ctx, "web"), client.ErrNotFound)
+}
+
+func TestClient_Retries(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			attempts.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+		case attempts.Add(1) < 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer srv.Close()
+
+	c := client.New(srv.URL, client.WithBackoff(time.Millisecond))
+	_, err := c.ListContexts(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, attempts.Load())
+
+	// Gateway errors may hide a processed request, so POSTs are not retried
+	attempts.Store(0)
+	_, err = c.CreateContext(context.Background(), "x", nil)
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	assert.Empty(t, apiErr.Code, "not an error envelope")
+	assert.EqualValues(t, 1, attempts.Load())
+
+	attempts.Store(-100)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = client.New(srv.URL, client.WithBackoff(time.Second)).ListContexts(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateContext stores a new context. It fails with ErrConflict if the ID is
+// taken.
+func (c *Client) CreateContext(ctx context.Context, id string, metadata map[string]interface{}) (*Context, error) {
+	req := map[string]interface{}{"id": id, "metadata": metadata}
+	var created Context
+	if err := c.do(ctx, http.MethodPost, "/context/create", req, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetContext returns a context. It fails with ErrNotFound if there is none.
+func (c *Client) GetContext(ctx context.Context, id string) (*Context, error) {
+	var found Context
+	if err := c.do(ctx, http.MethodGet, "/context/get?id="+url.QueryEscape(id), nil, &found); err != nil {
+		return nil, err
+	}
+	return &found, nil
+}
+
+// UpdateContext replaces the metadata of a context
+func (c *Client) UpdateContext(ctx context.Context, id string, metadata map[string]interface{}) (*Context, error) {
+	req := map[string]interface{}{"metadata": metadata}
+	var updated Context
+	if err := c.do(ctx, http.MethodPut, "/context/update?id="+url.QueryEscape(id), req, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteContext deletes a context
+func (c *Client) DeleteContext(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/context/delete?id="+url.QueryEscape(id), nil, nil)
+}
+
+// ListContexts returns every stored context
+func (c *Client) ListContexts(ctx context.Context) ([]*Context, error) {
+	var contexts []*Context
+	if err := c.do(ctx, http.MethodGet, "/context/list", nil, &contexts); err != nil {
+		return nil, err
+	}
+	return contexts, nil
+}
+
+// ListContextsBySelector returns the contexts whose labels match a selector
+// such as "team=payments,!deprecated"
+func (c *Client) ListContextsBySelector(ctx context.Context, selector string) ([]*Context, error) {
+	var contexts []*Context
+	if err := c.do(ctx, http.MethodGet, "/context/list?selector="+url.QueryEscape(selector), nil, &contexts); err != nil {
+		return nil, err
+	}
+	return contexts, nil
+}
+
+// LabelContexts adds and removes labels of many contexts at once and returns
+// the updated contexts
+func (c *Client) LabelContexts(ctx context.Context, req LabelRequest) ([]*Context, error) {
+	var contexts []*Context
+	if err := c.do(ctx, http.MethodPost, "/context/labels", req, &contexts); err != nil {
+		return nil, err
+	}
+	return contexts, nil
+}
+
+// BulkContexts applies an operation, delete, export or summarize, to the
+// contexts matching a selector
+func (c *Client) BulkContexts(ctx context.Context, selector, operation string) (*BulkResult, error) {
+	var result BulkResult
+	req := map[string]string{"selector": selector, "operation": operation}
+	if err := c.do(ctx, http.MethodPost, "/context/bulk", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
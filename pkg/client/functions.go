@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListFunctions returns the functions the server can call
+func (c *Client) ListFunctions(ctx context.Context) ([]FunctionMetadata, error) {
+	var functions []FunctionMetadata
+	if err := c.do(ctx, http.MethodGet, "/function/list", nil, &functions); err != nil {
+		return nil, err
+	}
+	return functions, nil
+}
+
+// FunctionTools returns the functions as tool definitions in the format an
+// LLM API takes them, "openai" or "anthropic", to pass as they are. Tool
+// calls of the definitions can be passed to CallFunctionNamed by name.
+func (c *Client) FunctionTools(ctx context.Context, format string) ([]map[string]interface{}, error) {
+	var tools []map[string]interface{}
+	if err := c.do(ctx, http.MethodGet, "/function/list?format="+url.QueryEscape(format), nil, &tools); err != nil {
+		return nil, err
+	}
+	return tools, nil
+}
+
+// CallFunction calls a function and returns its result as decoded from JSON
+func (c *Client) CallFunction(ctx context.Context, name string, args ...interface{}) (interface{}, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+	return c.callFunction(ctx, name, args)
+}
+
+// CallFunctionUncached calls a function like CallFunction, but calls
+// idempotent functions rather than returning their cached results
+func (c *Client) CallFunctionUncached(ctx context.Context, name string, args ...interface{}) (interface{}, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+	return c.postCall(ctx, map[string]interface{}{"name": name, "arguments": args, "no_cache": true})
+}
+
+// CallFunctionNamed calls a function with arguments keyed by parameter name,
+// the fields of a struct parameter or arg0, arg1 and so on. Optional
+// parameters left out take their defaults.
+func (c *Client) CallFunctionNamed(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	return c.callFunction(ctx, name, args)
+}
+
+func (c *Client) callFunction(ctx context.Context, name string, args interface{}) (interface{}, error) {
+	return c.postCall(ctx, map[string]interface{}{"name": name, "arguments": args})
+}
+
+func (c *Client) postCall(ctx context.Context, req map[string]interface{}) (interface{}, error) {
+	var resp struct {
+		Result interface{} `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/function/call", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+// CallFunctionAsync starts a call of a function in the background and
+// returns its job, for functions that take longer than a request should
+func (c *Client) CallFunctionAsync(ctx context.Context, name string, args ...interface{}) (*FunctionJob, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+	req := map[string]interface{}{"name": name, "arguments": args}
+	var job FunctionJob
+	if err := c.do(ctx, http.MethodPost, "/function/call?async=true", req, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FunctionJob returns a job started by CallFunctionAsync, with its result
+// once it succeeded
+func (c *Client) FunctionJob(ctx context.Context, id string) (*FunctionJob, error) {
+	var job FunctionJob
+	if err := c.do(ctx, http.MethodGet, "/function/jobs/"+url.PathEscape(id), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CancelFunctionJob cancels a queued or running job. Running functions stop
+// if they take a context.
+func (c *Client) CancelFunctionJob(ctx context.Context, id string) (*FunctionJob, error) {
+	var job FunctionJob
+	if err := c.do(ctx, http.MethodDelete, "/function/jobs/"+url.PathEscape(id), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FunctionStats returns the call counts, latencies and error rates of the
+// functions called since the server started
+func (c *Client) FunctionStats(ctx context.Context) ([]FunctionStats, error) {
+	var stats []FunctionStats
+	if err := c.do(ctx, http.MethodGet, "/function/stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// FunctionHistory returns the recent invocations of a function, newest first
+func (c *Client) FunctionHistory(ctx context.Context, name string) ([]FunctionInvocation, error) {
+	var history []FunctionInvocation
+	if err := c.do(ctx, http.MethodGet, "/function/"+url.PathEscape(name)+"/history", nil, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Plugins returns the plugins of the server's plugin directory
+func (c *Client) Plugins(ctx context.Context) ([]PluginStatus, error) {
+	var plugins []PluginStatus
+	if err := c.do(ctx, http.MethodGet, "/function/plugins", nil, &plugins); err != nil {
+		return nil, err
+	}
+	return plugins, nil
+}
+
+// LoadPlugins loads the plugins added to the server's plugin directory
+// since it started, registering their functions
+func (c *Client) LoadPlugins(ctx context.Context) ([]PluginStatus, error) {
+	var plugins []PluginStatus
+	if err := c.do(ctx, http.MethodPost, "/function/plugins/load", nil, &plugins); err != nil {
+		return nil, err
+	}
+	return plugins, nil
+}
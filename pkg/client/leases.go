@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// LeaseHeader names the lease of a request
+const LeaseHeader = "X-MCP-Lease"
+
+// CreateLease creates a lease that expires unless renewed within ttl, or the
+// server's default TTL when ttl is 0
+func (c *Client) CreateLease(ctx context.Context, ttl time.Duration) (*Lease, error) {
+	var lease Lease
+	req := map[string]int{"ttl_seconds": int(ttl / time.Second)}
+	if err := c.do(ctx, http.MethodPost, "/leases", req, &lease); err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// RenewLease moves the expiry of a lease. Every request of a client using
+// the lease renews it as well.
+func (c *Client) RenewLease(ctx context.Context, id string) (*Lease, error) {
+	var lease Lease
+	if err := c.do(ctx, http.MethodPost, "/leases/"+url.PathEscape(id)+"/renew", nil, &lease); err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// ReleaseLease ends a lease, closing the resources tied to it
+func (c *Client) ReleaseLease(ctx context.Context, id string) (*Lease, error) {
+	var lease Lease
+	if err := c.do(ctx, http.MethodDelete, "/leases/"+url.PathEscape(id), nil, &lease); err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// WithLease returns a copy of the client whose requests name a lease. The
+// browsers, SSH connections and tasks they create are closed when the lease
+// is released or expires, so an abandoned run leaves nothing behind.
+func (c *Client) WithLease(id string) *Client {
+	leased := *c
+	leased.lease = id
+	return &leased
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// SSHConnect opens an SSH connection on the server, registered under id
+func (c *Client) SSHConnect(ctx context.Context, id string, config SSHConfig) error {
+	req := map[string]interface{}{"id": id, "config": config}
+	return c.do(ctx, http.MethodPost, "/ssh/connect", req, nil)
+}
+
+// SSHDisconnect closes an SSH connection
+func (c *Client) SSHDisconnect(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/ssh/"+url.PathEscape(id), nil, nil)
+}
+
+// SSHHealth returns the state of an SSH connection
+func (c *Client) SSHHealth(ctx context.Context, id string) (*SSHHealth, error) {
+	var health SSHHealth
+	if err := c.do(ctx, http.MethodGet, "/ssh/"+url.PathEscape(id)+"/health", nil, &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// SSHExec runs a command over an SSH connection. A command that fails on
+// the remote host is reported by the exit code of the result, not an error.
+func (c *Client) SSHExec(ctx context.Context, id, command string) (*CommandResult, error) {
+	req := map[string]string{"command": command}
+	var result CommandResult
+	if err := c.do(ctx, http.MethodPost, "/ssh/"+url.PathEscape(id)+"/exec", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SSHUpload copies a file from the server's file system to the remote host
+func (c *Client) SSHUpload(ctx context.Context, id, localPath, remotePath string) error {
+	req := map[string]string{"local_path": localPath, "remote_path": remotePath}
+	return c.do(ctx, http.MethodPost, "/ssh/"+url.PathEscape(id)+"/upload", req, nil)
+}
+
+// SSHDownload copies a file from the remote host to the server's file system
+func (c *Client) SSHDownload(ctx context.Context, id, remotePath, localPath string) error {
+	req := map[string]string{"local_path": localPath, "remote_path": remotePath}
+	return c.do(ctx, http.MethodPost, "/ssh/"+url.PathEscape(id)+"/download", req, nil)
+}
+
+// SSHForward forwards localAddr on the server to remoteAddr as seen from
+// the remote host, e.g. 127.0.0.1:5433 to a database at localhost:5432. An
+// empty localAddr picks a free loopback port, returned in the forward.
+func (c *Client) SSHForward(ctx context.Context, id, localAddr, remoteAddr string) (*SSHForward, error) {
+	return c.forward(ctx, id, map[string]string{"direction": "local", "local_addr": localAddr, "remote_addr": remoteAddr})
+}
+
+// SSHRemoteForward has the remote host listen on remoteAddr and forward
+// its connections to localAddr, a loopback address of the server. An empty
+// remoteAddr picks a free loopback port of the remote host, returned in the
+// forward. The forward listens again after the connection reconnects.
+func (c *Client) SSHRemoteForward(ctx context.Context, id, remoteAddr, localAddr string) (*SSHForward, error) {
+	return c.forward(ctx, id, map[string]string{"direction": "remote", "local_addr": localAddr, "remote_addr": remoteAddr})
+}
+
+func (c *Client) forward(ctx context.Context, id string, req map[string]string) (*SSHForward, error) {
+	var forward SSHForward
+	if err := c.do(ctx, http.MethodPost, "/ssh/"+url.PathEscape(id)+"/forwards", req, &forward); err != nil {
+		return nil, err
+	}
+	return &forward, nil
+}
+
+// SSHForwards lists the port forwards of an SSH connection with their traffic
+func (c *Client) SSHForwards(ctx context.Context, id string) ([]SSHForward, error) {
+	var forwards []SSHForward
+	if err := c.do(ctx, http.MethodGet, "/ssh/"+url.PathEscape(id)+"/forwards", nil, &forwards); err != nil {
+		return nil, err
+	}
+	return forwards, nil
+}
+
+// SSHCloseForward closes a port forward and the connections open through it
+func (c *Client) SSHCloseForward(ctx context.Context, id, forward string) error {
+	return c.do(ctx, http.MethodDelete, "/ssh/"+url.PathEscape(id)+"/forwards/"+url.PathEscape(forward), nil, nil)
+}
@@ -0,0 +1,427 @@
+package client
+
+import "time"
+
+// The types below mirror the JSON of the server API
+
+// Context is a stored context
+type Context struct {
+	ID        string                 `json:"id"`
+	Metadata  map[string]interface{} `json:"metadata"`
+	Labels    map[string]string      `json:"labels,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// LabelRequest adds and removes labels of the contexts named by IDs and
+// those matching Selector
+type LabelRequest struct {
+	IDs      []string          `json:"ids,omitempty"`
+	Selector string            `json:"selector,omitempty"`
+	Add      map[string]string `json:"add,omitempty"`
+	Remove   []string          `json:"remove,omitempty"`
+}
+
+// BulkResult reports the contexts a bulk operation applied to
+type BulkResult struct {
+	Operation string     `json:"operation"`
+	IDs       []string   `json:"ids"`
+	Contexts  []*Context `json:"contexts,omitempty"`
+}
+
+// FunctionMetadata describes a callable function
+type FunctionMetadata struct {
+	Name       string                 `json:"name"`
+	Arguments  []ArgumentInfo         `json:"arguments"`
+	Parameters map[string]interface{} `json:"parameters"` // JSON Schema of the named arguments
+	ReturnType string                 `json:"return_type"`
+	Scopes     []string               `json:"scopes,omitempty"`    // Required of callers
+	CacheTTL   float64                `json:"cache_ttl,omitempty"` // Seconds results of idempotent functions are cached
+}
+
+// ArgumentInfo describes a function argument
+type ArgumentInfo struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+}
+
+// States of function jobs
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// FunctionJob is a function call running in the background
+type FunctionJob struct {
+	ID         string       `json:"id"`
+	Function   string       `json:"function"`
+	Status     string       `json:"status"` // One of the Job states
+	Result     interface{}  `json:"result,omitempty"`
+	Error      *ErrorDetail `json:"error,omitempty"`
+	Cache      string       `json:"cache,omitempty"` // HIT, MISS or BYPASS for idempotent functions
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
+
+// Done reports whether the job finished, whichever way
+func (j *FunctionJob) Done() bool {
+	return j.Status != JobQueued && j.Status != JobRunning
+}
+
+// FunctionInvocation records a call of a function
+type FunctionInvocation struct {
+	Function    string                 `json:"function"`
+	Tenant      string                 `json:"tenant,omitempty"`
+	Arguments   map[string]interface{} `json:"arguments"`
+	StartedAt   time.Time              `json:"started_at"`
+	DurationMS  float64                `json:"duration_ms"`
+	ResultBytes int                    `json:"result_bytes"`
+	Error       string                 `json:"error,omitempty"`
+}
+
+// FunctionStats summarizes the calls of a function since the server started
+type FunctionStats struct {
+	Function   string     `json:"function"`
+	Calls      int64      `json:"calls"`
+	Errors     int64      `json:"errors"`
+	ErrorRate  float64    `json:"error_rate"`
+	MeanMS     float64    `json:"mean_ms"`
+	MaxMS      float64    `json:"max_ms"`
+	LastCalled *time.Time `json:"last_called,omitempty"`
+}
+
+// PluginStatus reports a plugin of the server's plugin directory
+type PluginStatus struct {
+	Path      string     `json:"path"`
+	Loaded    bool       `json:"loaded"`
+	Functions []string   `json:"functions,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	LoadedAt  *time.Time `json:"loaded_at,omitempty"`
+}
+
+// ErrorDetail is the error a failed job ended with
+type ErrorDetail struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Retryable bool                   `json:"retryable"`
+}
+
+// SSHConfig describes how to connect to an SSH host
+type SSHConfig struct {
+	Host          string `json:"host"`
+	Port          int    `json:"port"`
+	User          string `json:"user"`
+	Password      string `json:"password,omitempty"`
+	PrivateKey    string `json:"private_key,omitempty"`
+	KeyPassphrase string `json:"key_passphrase,omitempty"`
+	// KeepAlive is the interval of keepalive requests in seconds, 30 when
+	// zero; a negative interval disables them
+	KeepAlive int `json:"keepalive_seconds,omitempty"`
+	// ReconnectAttempts are the tries to reconnect a dropped connection
+	// before a command, 3 when zero
+	ReconnectAttempts int `json:"reconnect_attempts,omitempty"`
+	// PoolSize is the number of connections parallel commands may open,
+	// at most 32; 0 and 1 keep a single connection
+	PoolSize int `json:"pool_size,omitempty"`
+}
+
+// SSHHealth is the state of an SSH connection
+type SSHHealth struct {
+	Connected     bool       `json:"connected"`
+	LastKeepAlive *time.Time `json:"last_keepalive,omitempty"`
+	Reconnects    int        `json:"reconnects"`
+	LastError     string     `json:"last_error,omitempty"`
+	PoolSize      int        `json:"pool_size,omitempty"`
+	Connections   int        `json:"connections,omitempty"`
+	Busy          int        `json:"busy,omitempty"`
+	Forwards      int        `json:"forwards,omitempty"`
+	ForwardsDown  int        `json:"forwards_down,omitempty"`
+}
+
+// SSHForward is a port forwarded over an SSH connection
+type SSHForward struct {
+	ID            string    `json:"id"`
+	Connection    string    `json:"connection"`
+	Direction     string    `json:"direction"` // local or remote
+	LocalAddr     string    `json:"local_addr"`
+	RemoteAddr    string    `json:"remote_addr"`
+	CreatedAt     time.Time `json:"created_at"`
+	Listening     bool      `json:"listening"`
+	Restarts      int       `json:"restarts"`
+	Active        int       `json:"active"`
+	Connections   int       `json:"connections"`
+	BytesSent     int64     `json:"bytes_sent"`
+	BytesReceived int64     `json:"bytes_received"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// CommandResult is the result of a command run over SSH
+type CommandResult struct {
+	Command  string `json:"command"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// BrowserConfig configures a browser instance
+type BrowserConfig struct {
+	Headless  bool              `json:"headless"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	ViewPort  *ViewPort         `json:"viewport,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Proxy     string            `json:"proxy,omitempty"`
+	Timeout   time.Duration     `json:"timeout,omitempty"`
+}
+
+// ViewPort is the size of a browser window
+type ViewPort struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// NavigationResult is the result of a page navigation
+type NavigationResult struct {
+	Success      bool    `json:"success"`
+	URL          string  `json:"url"`
+	Title        string  `json:"title"`
+	LoadTime     float64 `json:"load_time"`
+	StatusCode   int     `json:"status_code"`
+	ErrorMessage string  `json:"error_message,omitempty"`
+}
+
+// AutomationStep is a step of an automation sequence, e.g. a navigate step
+// with a url param or a click step with a selector param
+type AutomationStep struct {
+	Type    string                 `json:"type"`
+	Params  map[string]interface{} `json:"params"`
+	Timeout time.Duration          `json:"timeout,omitempty"`
+	Wait    time.Duration          `json:"wait,omitempty"`
+}
+
+// AutomationSequence is a sequence of browser automation steps
+type AutomationSequence struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Steps       []AutomationStep `json:"steps"`
+	Config      *BrowserConfig   `json:"config,omitempty"`
+}
+
+// AnalysisResult is the analysis of a Go source file
+type AnalysisResult struct {
+	Imports     []ImportInfo    `json:"imports"`
+	Functions   []FunctionInfo  `json:"functions"`
+	Types       []TypeInfo      `json:"types"`
+	Variables   []VariableInfo  `json:"variables"`
+	References  []ReferenceInfo `json:"references"`
+	Diagnostics []Diagnostic    `json:"diagnostics"`
+	Metrics     CodeMetrics     `json:"metrics"`
+	Coverage    *Coverage       `json:"coverage,omitempty"`
+}
+
+// Coverage is the share of the statements of a function or file run by
+// tests, set when a coverage profile was uploaded
+type Coverage struct {
+	Statements int     `json:"statements"`
+	Covered    int     `json:"covered"`
+	Percent    float64 `json:"percent"`
+}
+
+// CodeMetrics are size and complexity metrics of a file
+type CodeMetrics struct {
+	LinesOfCode         int `json:"lines_of_code"`
+	CommentLines        int `json:"comment_lines"`
+	FunctionCount       int `json:"function_count"`
+	ComplexityScore     int `json:"complexity_score"`
+	CognitiveComplexity int `json:"cognitive_complexity"`
+	InterfaceCount      int `json:"interface_count"`
+	StructCount         int `json:"struct_count"`
+	TestCount           int `json:"test_count"`
+	ExportedSymbols     int `json:"exported_symbols"`
+	DocumentedSymbols   int `json:"documented_symbols"`
+
+	DocCoverage          float64         `json:"doc_coverage"`
+	Halstead             HalsteadMetrics `json:"halstead"`
+	MaintainabilityIndex float64         `json:"maintainability_index"`
+	Churn                *FileChurn      `json:"churn,omitempty"`
+}
+
+// FileChurn is the history of changes of a file, set in the metrics of
+// files of the git history of the server's workspace
+type FileChurn struct {
+	Path         string        `json:"path"`
+	Commits      int           `json:"commits"`
+	LinesAdded   int           `json:"lines_added"`
+	LinesDeleted int           `json:"lines_deleted"`
+	FirstChange  time.Time     `json:"first_change"`
+	LastChange   time.Time     `json:"last_change"`
+	Authors      []AuthorChurn `json:"authors"` // Most lines changed first
+}
+
+// AuthorChurn is the share of an author in the changes of a file
+type AuthorChurn struct {
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	Commits      int    `json:"commits"`
+	LinesChanged int    `json:"lines_changed"`
+}
+
+// HalsteadMetrics measure code by its operators and operands
+type HalsteadMetrics struct {
+	DistinctOperators int     `json:"distinct_operators"`
+	DistinctOperands  int     `json:"distinct_operands"`
+	Operators         int     `json:"operators"`
+	Operands          int     `json:"operands"`
+	Volume            float64 `json:"volume"`
+	Difficulty        float64 `json:"difficulty"`
+	Effort            float64 `json:"effort"`
+}
+
+// Diagnostic is a problem found in a file
+type Diagnostic struct {
+	Severity string   `json:"severity"` // error, warning, info
+	Message  string   `json:"message"`
+	Location Location `json:"location"`
+	Code     string   `json:"code"`
+	Source   string   `json:"source"`
+}
+
+// Location is a range in a file
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// Range is a text range
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Position is a zero-based line and character offset
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// ImportInfo describes an import declaration
+type ImportInfo struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+	Used bool   `json:"used"`
+}
+
+// FunctionInfo describes a function declaration
+type FunctionInfo struct {
+	Name                 string          `json:"name"`
+	Signature            string          `json:"signature"`
+	Doc                  string          `json:"doc"`
+	Location             Location        `json:"location"`
+	Complexity           int             `json:"complexity"`
+	CognitiveComplexity  int             `json:"cognitive_complexity"`
+	Halstead             HalsteadMetrics `json:"halstead"`
+	MaintainabilityIndex float64         `json:"maintainability_index"`
+	Coverage             *Coverage       `json:"coverage,omitempty"`
+	IsMethod             bool            `json:"is_method"`
+	Receiver             string          `json:"receiver"`
+	Parameters           []ParameterInfo `json:"parameters"`
+	Returns              []ParameterInfo `json:"returns"`
+}
+
+// ParameterInfo describes a parameter or return value
+type ParameterInfo struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	IsVariadic bool   `json:"variadic"`
+}
+
+// TypeInfo describes a type declaration
+type TypeInfo struct {
+	Name       string       `json:"name"`
+	Kind       string       `json:"kind"`
+	Doc        string       `json:"doc"`
+	Location   Location     `json:"location"`
+	Fields     []FieldInfo  `json:"fields"`
+	Methods    []MethodInfo `json:"methods"`
+	Implements []string     `json:"implements"`
+}
+
+// FieldInfo describes a struct field
+type FieldInfo struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Doc     string `json:"doc"`
+	Tags    string `json:"tags"`
+	IsEmbed bool   `json:"embed"`
+}
+
+// MethodInfo describes a method declaration
+type MethodInfo struct {
+	Name       string          `json:"name"`
+	Signature  string          `json:"signature"`
+	Doc        string          `json:"doc"`
+	Parameters []ParameterInfo `json:"parameters"`
+	Returns    []ParameterInfo `json:"returns"`
+}
+
+// VariableInfo describes a variable or constant declaration
+type VariableInfo struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Location Location `json:"location"`
+	Constant bool     `json:"constant"`
+	Value    string   `json:"value"`
+	Doc      string   `json:"doc"`
+	Scope    string   `json:"scope"`
+}
+
+// ReferenceInfo describes the uses of a symbol
+type ReferenceInfo struct {
+	Name     string     `json:"name"`
+	Kind     string     `json:"kind"`
+	Location Location   `json:"location"`
+	UsedAt   []Location `json:"used_at"`
+	Scope    string     `json:"scope"`
+}
+
+// Hotspot is a file that is both frequently changed and complex
+type Hotspot struct {
+	Path                  string    `json:"path"`
+	Commits               int       `json:"commits"`
+	LinesAdded            int       `json:"lines_added"`
+	LinesDeleted          int       `json:"lines_deleted"`
+	Authors               int       `json:"authors"`
+	PrimaryAuthor         string    `json:"primary_author"`
+	Ownership             float64   `json:"ownership"` // Share of the lines changed by the primary author, 0..1
+	LastChange            time.Time `json:"last_change"`
+	Complexity            int       `json:"complexity"`
+	MaxFunctionComplexity int       `json:"max_function_complexity"`
+	Functions             int       `json:"functions"`
+	Score                 float64   `json:"score"`
+}
+
+// HotspotReport ranks files by combined churn and complexity
+type HotspotReport struct {
+	Since    string    `json:"since,omitempty"`
+	Hotspots []Hotspot `json:"hotspots"`
+}
+
+// Lease ties browsers, SSH connections and tasks to a client, see
+// Client.WithLease
+type Lease struct {
+	ID        string          `json:"id"`
+	Tenant    string          `json:"tenant,omitempty"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	Resources []LeaseResource `json:"resources"`
+}
+
+// LeaseResource is a resource held by a lease
+type LeaseResource struct {
+	Kind string `json:"kind"` // browser, ssh or task
+	ID   string `json:"id"`
+}
@@ -0,0 +1,162 @@
+// Package contextid derives deterministic context IDs for imported API
+// specifications and stores contexts under them according to a collision
+// policy. The spec and curl processors share it, so files whose names only
+// differ in their extension, such as spec.json and spec.yaml, no longer map
+// to the same context.
+package contextid
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ivikasavnish/go-mcp/pkg/client"
+)
+
+// DefaultTemplate names contexts after their type and source file, made
+// unique by a hash of the source path
+const DefaultTemplate = "{type}-{name}-{hash}"
+
+// Template placeholders
+const (
+	PlaceholderType        = "{type}"         // Context type, e.g. openapi
+	PlaceholderName        = "{name}"         // Source name, by default the file name without extension
+	PlaceholderExt         = "{ext}"          // File extension without the dot
+	PlaceholderHash        = "{hash}"         // Hash of the source path
+	PlaceholderContentHash = "{content_hash}" // Hash of the content
+)
+
+// hashLen is the number of hex digits of hashes in IDs
+const hashLen = 8
+
+// maxSuffix bounds the IDs PolicySuffix tries
+const maxSuffix = 100
+
+// invalidChars matches runs of characters context IDs may not contain
+var invalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+var placeholderPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// Source describes what a context is created from
+type Source struct {
+	Type    string // e.g. openapi, postman or curl
+	Path    string // File path, or a name such as "inline" for other content
+	Name    string // Overrides the name derived from Path
+	Content []byte
+}
+
+// Generator derives context IDs from a template
+type Generator struct {
+	template string
+}
+
+// NewGenerator creates a generator for a template of placeholders and
+// literal text, e.g. "{type}-{name}-{content_hash}"
+func NewGenerator(template string) (*Generator, error) {
+	if template == "" {
+		return nil, errors.New("id template is empty")
+	}
+	for _, p := range placeholderPattern.FindAllString(template, -1) {
+		switch p {
+		case PlaceholderType, PlaceholderName, PlaceholderExt, PlaceholderHash, PlaceholderContentHash:
+		default:
+			return nil, fmt.Errorf("unknown placeholder %s in id template", p)
+		}
+	}
+	return &Generator{template: template}, nil
+}
+
+// Default returns a generator for DefaultTemplate
+func Default() *Generator {
+	return &Generator{template: DefaultTemplate}
+}
+
+// ID returns the context ID of a source. Characters not allowed in IDs are
+// replaced by dashes; the same source always gets the same ID.
+func (g *Generator) ID(src Source) string {
+	base := filepath.Base(src.Path)
+	ext := filepath.Ext(base)
+	name := src.Name
+	if name == "" {
+		name = strings.TrimSuffix(base, ext)
+	}
+
+	id := strings.NewReplacer(
+		PlaceholderType, src.Type,
+		PlaceholderName, name,
+		PlaceholderExt, strings.TrimPrefix(ext, "."),
+		PlaceholderHash, hash([]byte(src.Path)),
+		PlaceholderContentHash, hash(src.Content),
+	).Replace(g.template)
+
+	id = strings.Trim(invalidChars.ReplaceAllString(id, "-"), "-")
+	if id == "" {
+		return hash([]byte(src.Path))
+	}
+	return id
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:hashLen]
+}
+
+// Policy decides what happens when a context ID is already taken
+type Policy string
+
+// Collision policies
+const (
+	// PolicyError fails the import
+	PolicyError Policy = "error"
+	// PolicySuffix stores the context under the first free ID of id-2, id-3, ...
+	PolicySuffix Policy = "suffix"
+	// PolicyOverwrite replaces the metadata of the existing context
+	PolicyOverwrite Policy = "overwrite"
+)
+
+// ParsePolicy parses the name of a collision policy
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(name); p {
+	case PolicyError, PolicySuffix, PolicyOverwrite:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown collision policy %q", name)
+}
+
+// Store creates and updates contexts, e.g. a *client.Client
+type Store interface {
+	CreateContext(ctx context.Context, id string, metadata map[string]interface{}) (*client.Context, error)
+	UpdateContext(ctx context.Context, id string, metadata map[string]interface{}) (*client.Context, error)
+}
+
+// Create stores a context under id, resolving a collision with policy, and
+// returns the ID it was stored under
+func Create(ctx context.Context, store Store, policy Policy, id string, metadata map[string]interface{}) (string, error) {
+	_, err := store.CreateContext(ctx, id, metadata)
+	if !errors.Is(err, client.ErrConflict) {
+		return id, err
+	}
+
+	switch policy {
+	case PolicyOverwrite:
+		_, err := store.UpdateContext(ctx, id, metadata)
+		return id, err
+	case PolicySuffix:
+		for n := 2; n <= maxSuffix; n++ {
+			candidate := id + "-" + strconv.Itoa(n)
+			_, err := store.CreateContext(ctx, candidate, metadata)
+			if !errors.Is(err, client.ErrConflict) {
+				return candidate, err
+			}
+		}
+		return "", fmt.Errorf("context %s and %d suffixed IDs are taken", id, maxSuffix-1)
+	default:
+		return "", fmt.Errorf("context %s exists: %w", id, err)
+	}
+}
@@ -0,0 +1,68 @@
+package contextid
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ivikasavnish/go-mcp/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_ID(t *testing.T) {
+	ids := Default()
+	jsonID := ids.ID(Source{Type: "openapi", Path: "specs/spec.json"})
+	yamlID := ids.ID(Source{Type: "openapi", Path: "specs/spec.yaml"})
+	assert.Regexp(t, `^openapi-spec-[0-9a-f]{8}$`, jsonID)
+	assert.NotEqual(t, jsonID, yamlID)
+	assert.Equal(t, jsonID, ids.ID(Source{Type: "openapi", Path: "specs/spec.json"}))
+
+	ids, err := NewGenerator("{type}_{name}.{ext}@{content_hash}")
+	require.NoError(t, err)
+	assert.Regexp(t, `^curl_My-API-json-[0-9a-f]{8}$`, ids.ID(Source{Type: "curl", Path: "a/x.json", Name: "My API!", Content: []byte("curl x")}))
+	assert.NotEqual(t,
+		ids.ID(Source{Type: "curl", Path: "x.json", Content: []byte("a")}),
+		ids.ID(Source{Type: "curl", Path: "x.json", Content: []byte("b")}))
+
+	_, err = NewGenerator("{type}-{version}")
+	assert.Error(t, err)
+	_, err = ParsePolicy("rename")
+	assert.Error(t, err)
+}
+
+// memoryStore is a Store holding contexts in a map
+type memoryStore map[string]map[string]interface{}
+
+func (m memoryStore) CreateContext(ctx context.Context, id string, metadata map[string]interface{}) (*client.Context, error) {
+	if _, ok := m[id]; ok {
+		return nil, &client.Error{StatusCode: http.StatusConflict, Message: "context already exists"}
+	}
+	m[id] = metadata
+	return &client.Context{ID: id, Metadata: metadata}, nil
+}
+
+func (m memoryStore) UpdateContext(ctx context.Context, id string, metadata map[string]interface{}) (*client.Context, error) {
+	m[id] = metadata
+	return &client.Context{ID: id, Metadata: metadata}, nil
+}
+
+func TestCreate_Policies(t *testing.T) {
+	ctx := context.Background()
+	store := memoryStore{"spec": {"v": 1}}
+
+	_, err := Create(ctx, store, PolicyError, "spec", map[string]interface{}{"v": 2})
+	assert.ErrorIs(t, err, client.ErrConflict)
+
+	id, err := Create(ctx, store, PolicySuffix, "spec", map[string]interface{}{"v": 2})
+	require.NoError(t, err)
+	assert.Equal(t, "spec-2", id)
+	id, err = Create(ctx, store, PolicySuffix, "spec", map[string]interface{}{"v": 3})
+	require.NoError(t, err)
+	assert.Equal(t, "spec-3", id)
+
+	id, err = Create(ctx, store, PolicyOverwrite, "spec", map[string]interface{}{"v": 4})
+	require.NoError(t, err)
+	assert.Equal(t, "spec", id)
+	assert.Equal(t, 4, store["spec"]["v"])
+}
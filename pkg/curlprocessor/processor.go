@@ -2,24 +2,59 @@
 package curlprocessor
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"strings"
 	"time"
 
-	"github.com/ivikasavnish/go-mcp/pkg/specprocessor"
+	"github.com/ivikasavnish/go-mcp/pkg/client"
+	"github.com/ivikasavnish/go-mcp/pkg/contextid"
 )
 
 // Processor processes curl collections and integrates with MCP
 type Processor struct {
-	mcpClient *specprocessor.MCPClient
+	client *client.Client
+	ids    *contextid.Generator
+	policy contextid.Policy
+}
+
+// ProcessorOption configures a Processor
+type ProcessorOption func(*Processor)
+
+// WithClient sets the client used to store contexts
+func WithClient(c *client.Client) ProcessorOption {
+	return func(p *Processor) {
+		p.client = c
+	}
+}
+
+// WithIDGenerator sets how context IDs are derived from collections
+func WithIDGenerator(ids *contextid.Generator) ProcessorOption {
+	return func(p *Processor) {
+		p.ids = ids
+	}
+}
+
+// WithCollisionPolicy sets what happens when a context ID is taken; the
+// import fails by default
+func WithCollisionPolicy(policy contextid.Policy) ProcessorOption {
+	return func(p *Processor) {
+		p.policy = policy
+	}
 }
 
 // NewProcessor creates a new curl processor
-func NewProcessor(mcpBaseURL string) *Processor {
-	return &Processor{
-		mcpClient: specprocessor.NewMCPClient(mcpBaseURL),
+func NewProcessor(mcpBaseURL string, opts ...ProcessorOption) *Processor {
+	p := &Processor{
+		client: client.New(mcpBaseURL),
+		ids:    contextid.Default(),
+		policy: contextid.PolicyError,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+	return p
 }
 
 // ProcessCurlFile processes a file containing curl commands
@@ -35,7 +70,7 @@ func (p *Processor) ProcessCurlFile(filePath string) error {
 		return fmt.Errorf("failed to parse curl collection: %w", err)
 	}
 
-	return p.createMCPContext(collection, filePath)
+	return p.createMCPContext(collection, contextid.Source{Type: "curl", Path: filePath, Content: content})
 }
 
 // ProcessCurlContent processes curl commands from a string
@@ -45,17 +80,19 @@ func (p *Processor) ProcessCurlContent(content, name string) error {
 		return fmt.Errorf("failed to parse curl collection: %w", err)
 	}
 
-	return p.createMCPContext(collection, "inline")
+	return p.createMCPContext(collection, contextid.Source{Type: "curl", Path: "inline", Name: name, Content: []byte(content)})
 }
 
-func (p *Processor) createMCPContext(collection *CurlCollection, source string) error {
+func (p *Processor) createMCPContext(collection *CurlCollection, src contextid.Source) error {
 	metadata := map[string]interface{}{
 		"type":       "curl",
 		"collection": collection,
-		"source":     source,
+		"source":     src.Path,
 		"timestamp":  time.Now(),
 	}
 
-	contextID := fmt.Sprintf("curl-%s", strings.ReplaceAll(collection.Name, " ", "-"))
-	return p.mcpClient.CreateContext(contextID, metadata)
+	if _, err := contextid.Create(context.Background(), p.client, p.policy, p.ids.ID(src), metadata); err != nil {
+		return fmt.Errorf("failed to create context: %w", err)
+	}
+	return nil
 }
@@ -156,7 +156,7 @@ func TestProcessor(t *testing.T) {
 	// Create a test MCP server
 	var receivedContext map[string]interface{}
 	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/context/create" {
+		if r.URL.Path == "/v1/context/create" {
 			err := json.NewDecoder(r.Body).Decode(&receivedContext)
 			require.NoError(t, err)
 			w.WriteHeader(http.StatusCreated)
@@ -0,0 +1,504 @@
+package ide
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Artifact kinds
+const (
+	ArtifactKindBinary   = "binary"
+	ArtifactKindCoverage = "coverage"
+	ArtifactKindArchive  = "archive"
+	ArtifactKindFile     = "file"
+)
+
+// Artifact is a stored output file of a task run. Its content lives in a
+// blob addressed by digest, shared by every artifact with the same content.
+type Artifact struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"` // Path relative to the project root when registered
+	Kind      string    `json:"kind"`
+	Digest    string    `json:"digest"` // sha256:<hex>
+	Size      int64     `json:"size"`
+	TaskID    string    `json:"task_id,omitempty"`
+	TaskRun   int       `json:"task_run,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ArtifactSource identifies the task run registering artifacts
+type ArtifactSource struct {
+	TaskID  string `json:"task_id,omitempty"`
+	TaskRun int    `json:"task_run,omitempty"`
+}
+
+// RetentionPolicy limits the artifacts kept. Zero fields impose no limit.
+type RetentionPolicy struct {
+	MaxAgeDays int   `json:"max_age_days,omitempty"` // Remove artifacts older than this
+	KeepRuns   int   `json:"keep_runs,omitempty"`    // Keep only the newest runs of each task
+	MaxBytes   int64 `json:"max_bytes,omitempty"`    // Remove the oldest artifacts beyond this total blob size
+}
+
+// DefaultRetentionPolicy is used when the project configures none
+var DefaultRetentionPolicy = RetentionPolicy{
+	MaxAgeDays: 30,
+	KeepRuns:   10,
+	MaxBytes:   1 << 30,
+}
+
+// ArtifactStore keeps task outputs content-addressed under .mcp/artifacts
+type ArtifactStore struct {
+	rootDir   string
+	storeDir  string
+	retention RetentionPolicy
+	artifacts []*Artifact
+	loaded    bool
+	mu        sync.Mutex
+}
+
+func NewArtifactStore(rootDir string) *ArtifactStore {
+	return &ArtifactStore{
+		rootDir:   rootDir,
+		storeDir:  filepath.Join(rootDir, ".mcp", "artifacts"),
+		retention: DefaultRetentionPolicy,
+	}
+}
+
+// SetRetention replaces the retention policy applied after each registration
+func (as *ArtifactStore) SetRetention(policy RetentionPolicy) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.retention = policy
+}
+
+// Register stores the files at the given paths, relative to the project
+// root, as artifacts of the source run. An empty kind is detected from the
+// file name and content.
+func (as *ArtifactStore) Register(source ArtifactSource, kind string, paths ...string) ([]*Artifact, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if err := as.load(); err != nil {
+		return nil, err
+	}
+
+	var registered []*Artifact
+	for _, p := range paths {
+		name, err := as.relativePath(p)
+		if err != nil {
+			return nil, err
+		}
+
+		artifact, err := as.storeBlob(name)
+		if err != nil {
+			return nil, err
+		}
+		artifact.TaskID = source.TaskID
+		artifact.TaskRun = source.TaskRun
+		if kind != "" {
+			artifact.Kind = kind
+		}
+		registered = append(registered, artifact)
+	}
+
+	as.artifacts = append(as.artifacts, registered...)
+	as.prune(as.retention, time.Now())
+
+	if err := as.save(); err != nil {
+		return nil, err
+	}
+	return registered, nil
+}
+
+// Collect registers every file under dir, which must be within the project
+// root, matching one of the glob patterns
+func (as *ArtifactStore) Collect(source ArtifactSource, dir string, patterns []string) ([]*Artifact, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if rel == ".git" || rel == ".mcp" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		for _, pattern := range patterns {
+			if MatchGlob(pattern, rel) {
+				paths = append(paths, p)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	return as.Register(source, "", paths...)
+}
+
+// relativePath resolves p against the project root, rejecting paths that
+// leave it
+func (as *ArtifactStore) relativePath(p string) (string, error) {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(as.rootDir, p)
+	}
+	rel, err := filepath.Rel(as.rootDir, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("artifact %s is outside the project", p)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// storeBlob copies a file into the blob store unless its content is
+// already there
+func (as *ArtifactStore) storeBlob(name string) (*Artifact, error) {
+	src, err := os.Open(filepath.Join(as.rootDir, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact: %v", err)
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("artifact %s is a directory", name)
+	}
+
+	blobDir := filepath.Join(as.storeDir, "blobs")
+	if err := os.MkdirAll(blobDir, 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := ioutil.TempFile(blobDir, "upload-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	// Keep the start of the file to detect its kind
+	var head bytes.Buffer
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash, &limitedBuffer{buf: &head, limit: 512}), src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store artifact: %v", err)
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+	blob := as.blobPath(digest)
+	if _, err := os.Stat(blob); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(tmp.Name(), blob); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	return &Artifact{
+		ID:        fmt.Sprintf("%s-%d", digest[:12], now.UnixNano()),
+		Name:      name,
+		Kind:      DetectArtifactKind(name, head.Bytes(), info.Mode()),
+		Digest:    "sha256:" + digest,
+		Size:      size,
+		CreatedAt: now,
+	}, nil
+}
+
+func (as *ArtifactStore) blobPath(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	return filepath.Join(as.storeDir, "blobs", "sha256", digest[:2], digest)
+}
+
+// limitedBuffer records the first limit bytes written to it
+type limitedBuffer struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (lb *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := lb.limit - lb.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			lb.buf.Write(p[:remaining])
+		} else {
+			lb.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// DetectArtifactKind guesses the kind of an artifact from its name, the start
+// of its content and its file mode
+func DetectArtifactKind(name string, head []byte, mode os.FileMode) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz", ".gz", ".zst", ".xz", ".bz2"} {
+		if strings.HasSuffix(lower, ext) {
+			return ArtifactKindArchive
+		}
+	}
+	if bytes.HasPrefix(head, []byte("mode: ")) || strings.HasSuffix(lower, ".coverprofile") {
+		return ArtifactKindCoverage
+	}
+
+	for _, magic := range [][]byte{
+		{0x7f, 'E', 'L', 'F'},    // ELF
+		{'M', 'Z'},               // PE
+		{0xcf, 0xfa, 0xed, 0xfe}, // Mach-O 64-bit
+		{0xce, 0xfa, 0xed, 0xfe}, // Mach-O 32-bit
+		{0xca, 0xfe, 0xba, 0xbe}, // Mach-O universal
+	} {
+		if bytes.HasPrefix(head, magic) {
+			return ArtifactKindBinary
+		}
+	}
+	if mode&0111 != 0 {
+		return ArtifactKindBinary
+	}
+	return ArtifactKindFile
+}
+
+// List returns the artifacts, newest first, optionally limited to a task
+func (as *ArtifactStore) List(taskID string) ([]*Artifact, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if err := as.load(); err != nil {
+		return nil, err
+	}
+
+	artifacts := make([]*Artifact, 0, len(as.artifacts))
+	for _, a := range as.artifacts {
+		if taskID == "" || a.TaskID == taskID {
+			artifacts = append(artifacts, a)
+		}
+	}
+	sort.SliceStable(artifacts, func(i, j int) bool {
+		return artifacts[i].CreatedAt.After(artifacts[j].CreatedAt)
+	})
+	return artifacts, nil
+}
+
+// Get returns an artifact by ID
+func (as *ArtifactStore) Get(id string) (*Artifact, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if err := as.load(); err != nil {
+		return nil, err
+	}
+	for _, a := range as.artifacts {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("artifact %s not found", id)
+}
+
+// Open opens the content of an artifact
+func (as *ArtifactStore) Open(artifact *Artifact) (*os.File, error) {
+	return os.Open(as.blobPath(artifact.Digest))
+}
+
+// Delete removes an artifact, and its blob when no other artifact shares it
+func (as *ArtifactStore) Delete(id string) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if err := as.load(); err != nil {
+		return err
+	}
+	for i, a := range as.artifacts {
+		if a.ID == id {
+			as.artifacts = append(as.artifacts[:i], as.artifacts[i+1:]...)
+			as.removeUnreferenced([]*Artifact{a})
+			return as.save()
+		}
+	}
+	return fmt.Errorf("artifact %s not found", id)
+}
+
+// Prune applies the retention policy and returns the removed artifacts
+func (as *ArtifactStore) Prune() ([]*Artifact, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if err := as.load(); err != nil {
+		return nil, err
+	}
+	removed := as.prune(as.retention, time.Now())
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return removed, as.save()
+}
+
+// prune drops artifacts exceeding the policy: first by age, then runs of
+// each task beyond the newest KeepRuns, then the oldest artifacts until the
+// blobs fit in MaxBytes
+func (as *ArtifactStore) prune(policy RetentionPolicy, now time.Time) []*Artifact {
+	sort.SliceStable(as.artifacts, func(i, j int) bool {
+		return as.artifacts[i].CreatedAt.Before(as.artifacts[j].CreatedAt)
+	})
+
+	drop := make(map[*Artifact]bool)
+
+	if policy.MaxAgeDays > 0 {
+		cutoff := now.AddDate(0, 0, -policy.MaxAgeDays)
+		for _, a := range as.artifacts {
+			if a.CreatedAt.Before(cutoff) {
+				drop[a] = true
+			}
+		}
+	}
+
+	if policy.KeepRuns > 0 {
+		runs := make(map[string]map[int]bool)
+		for _, a := range as.artifacts {
+			if a.TaskID == "" {
+				continue
+			}
+			if runs[a.TaskID] == nil {
+				runs[a.TaskID] = make(map[int]bool)
+			}
+			runs[a.TaskID][a.TaskRun] = true
+		}
+		for _, a := range as.artifacts {
+			if a.TaskID == "" {
+				continue
+			}
+			newer := 0
+			for run := range runs[a.TaskID] {
+				if run > a.TaskRun {
+					newer++
+				}
+			}
+			if newer >= policy.KeepRuns {
+				drop[a] = true
+			}
+		}
+	}
+
+	if policy.MaxBytes > 0 {
+		// Blobs shared by several artifacts count once
+		refs := make(map[string]int)
+		sizes := make(map[string]int64)
+		var total int64
+		for _, a := range as.artifacts {
+			if drop[a] {
+				continue
+			}
+			if refs[a.Digest] == 0 {
+				total += a.Size
+			}
+			refs[a.Digest]++
+			sizes[a.Digest] = a.Size
+		}
+		for _, a := range as.artifacts {
+			if total <= policy.MaxBytes {
+				break
+			}
+			if drop[a] {
+				continue
+			}
+			drop[a] = true
+			if refs[a.Digest]--; refs[a.Digest] == 0 {
+				total -= sizes[a.Digest]
+			}
+		}
+	}
+
+	if len(drop) == 0 {
+		return nil
+	}
+
+	var kept, removed []*Artifact
+	for _, a := range as.artifacts {
+		if drop[a] {
+			removed = append(removed, a)
+		} else {
+			kept = append(kept, a)
+		}
+	}
+	as.artifacts = kept
+	as.removeUnreferenced(removed)
+	return removed
+}
+
+// removeUnreferenced deletes the blobs of removed artifacts that no kept
+// artifact uses
+func (as *ArtifactStore) removeUnreferenced(removed []*Artifact) {
+	used := make(map[string]bool)
+	for _, a := range as.artifacts {
+		used[a.Digest] = true
+	}
+	for _, a := range removed {
+		if !used[a.Digest] {
+			os.Remove(as.blobPath(a.Digest))
+			used[a.Digest] = true
+		}
+	}
+}
+
+func (as *ArtifactStore) indexPath() string {
+	return filepath.Join(as.storeDir, "index.json")
+}
+
+func (as *ArtifactStore) load() error {
+	if as.loaded {
+		return nil
+	}
+	data, err := ioutil.ReadFile(as.indexPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &as.artifacts); err != nil {
+			return fmt.Errorf("failed to read artifact index: %v", err)
+		}
+	}
+	as.loaded = true
+	return nil
+}
+
+// save writes the index through a temporary file so readers never see a
+// partial index
+func (as *ArtifactStore) save() error {
+	data, err := json.MarshalIndent(as.artifacts, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(as.storeDir, 0755); err != nil {
+		return err
+	}
+	tmp := as.indexPath() + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, as.indexPath())
+}
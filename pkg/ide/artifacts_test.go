@@ -0,0 +1,128 @@
+package ide
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	path := filepath.Join(root, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestArtifactStore_Register(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "coverage.out", "mode: set\nmain.go:1.1,2.2 1 1\n")
+	writeTestFile(t, root, "copy.out", "mode: set\nmain.go:1.1,2.2 1 1\n")
+
+	store := NewArtifactStore(root)
+	artifacts, err := store.Register(ArtifactSource{TaskID: "task-1", TaskRun: 1}, "", "coverage.out", "copy.out")
+	require.NoError(t, err)
+	require.Len(t, artifacts, 2)
+
+	a := artifacts[0]
+	assert.Equal(t, "coverage.out", a.Name)
+	assert.Equal(t, ArtifactKindCoverage, a.Kind)
+	assert.Equal(t, int64(30), a.Size)
+	assert.Equal(t, artifacts[1].Digest, a.Digest, "identical content shares a blob")
+
+	f, err := store.Open(a)
+	require.NoError(t, err)
+	content, _ := ioutil.ReadAll(f)
+	f.Close()
+	assert.Equal(t, "mode: set\nmain.go:1.1,2.2 1 1\n", string(content))
+
+	_, err = store.Register(ArtifactSource{}, "", "../outside")
+	assert.Error(t, err)
+
+	// The index survives a restart; deleting one artifact keeps the shared blob
+	reopened := NewArtifactStore(root)
+	require.NoError(t, reopened.Delete(a.ID))
+	remaining, err := reopened.List("task-1")
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	_, err = os.Stat(reopened.blobPath(remaining[0].Digest))
+	assert.NoError(t, err)
+}
+
+func TestArtifactStore_Prune(t *testing.T) {
+	store := NewArtifactStore(t.TempDir())
+	now := time.Now()
+	artifact := func(id, task string, run int, digest string, size int64, age time.Duration) *Artifact {
+		return &Artifact{ID: id, TaskID: task, TaskRun: run, Digest: digest, Size: size, CreatedAt: now.Add(-age)}
+	}
+	ids := func(artifacts []*Artifact) []string {
+		var result []string
+		for _, a := range artifacts {
+			result = append(result, a.ID)
+		}
+		return result
+	}
+
+	store.artifacts = []*Artifact{
+		artifact("old", "", 0, "sha256:00", 1, 40*24*time.Hour),
+		artifact("run1", "build", 1, "sha256:01", 1, 3*time.Hour),
+		artifact("run2", "build", 2, "sha256:02", 1, 2*time.Hour),
+		artifact("run3", "build", 3, "sha256:03", 1, time.Hour),
+		artifact("test", "test", 1, "sha256:04", 1, 3*time.Hour),
+	}
+	removed := store.prune(RetentionPolicy{MaxAgeDays: 30, KeepRuns: 2}, now)
+	assert.ElementsMatch(t, []string{"old", "run1"}, ids(removed))
+
+	// Blobs shared by several artifacts count once toward the size limit
+	store.artifacts = []*Artifact{
+		artifact("a", "", 0, "sha256:aa", 60, 3*time.Hour),
+		artifact("b", "", 0, "sha256:bb", 50, 2*time.Hour),
+		artifact("c", "", 0, "sha256:bb", 50, time.Hour),
+	}
+	removed = store.prune(RetentionPolicy{MaxBytes: 100}, now)
+	assert.Equal(t, []string{"a"}, ids(removed))
+}
+
+func TestDetectArtifactKind(t *testing.T) {
+	assert.Equal(t, ArtifactKindBinary, DetectArtifactKind("bin/server", []byte("\x7fELF\x02"), 0644))
+	assert.Equal(t, ArtifactKindBinary, DetectArtifactKind("run.sh", []byte("#!/bin/sh"), 0755))
+	assert.Equal(t, ArtifactKindArchive, DetectArtifactKind("dist/release.tar.gz", nil, 0644))
+	assert.Equal(t, ArtifactKindCoverage, DetectArtifactKind("cover.out", []byte("mode: atomic\n"), 0644))
+	assert.Equal(t, ArtifactKindFile, DetectArtifactKind("report.xml", []byte("<testsuites>"), 0644))
+}
+
+func TestTaskManager_CollectArtifacts(t *testing.T) {
+	root := t.TempDir()
+	tm := NewTaskManager()
+	store := NewArtifactStore(root)
+	tm.SetArtifactStore(store)
+
+	writeTestFile(t, root, "main.go", "package main\n")
+	require.NoError(t, os.Mkdir(filepath.Join(root, "bin"), 0755))
+
+	task := &Task{ID: "build", Command: "cp main.go bin/app", Artifacts: []string{"bin/*"}}
+	require.NoError(t, tm.StartTask(task, NewCommandExecutor(root)))
+
+	events, unsubscribe := tm.TaskLog("build").Subscribe()
+	defer unsubscribe()
+	for range events {
+	}
+
+	artifacts, err := store.List("build")
+	require.NoError(t, err)
+	require.Len(t, artifacts, 1)
+	assert.Equal(t, "bin/app", artifacts[0].Name)
+	assert.Equal(t, 1, artifacts[0].TaskRun)
+
+	remote := &Task{ID: "remote", Command: "true", Artifacts: []string{"bin/*"}}
+	assert.Error(t, tm.StartTask(remote, &fakeExecutor{}))
+}
+
+// fakeExecutor stands in for a remote executor
+type fakeExecutor struct {
+	Executor
+}
@@ -0,0 +1,272 @@
+package ide
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BenchOptions configures a benchmark run
+type BenchOptions struct {
+	Packages  []string `json:"packages,omitempty"`  // Package patterns, defaults to ./...
+	Filter    string   `json:"filter,omitempty"`    // -bench regular expression, defaults to .
+	Count     int      `json:"count,omitempty"`     // Samples per benchmark, defaults to 5
+	Benchtime string   `json:"benchtime,omitempty"` // e.g. 1s or 100x
+	Benchmem  bool     `json:"benchmem,omitempty"`
+}
+
+// Benchmark holds every sample recorded for one benchmark, keyed by unit
+// (ns/op, B/op, allocs/op or custom metrics)
+type Benchmark struct {
+	Name    string               `json:"name"`
+	Package string               `json:"package"`
+	Procs   int                  `json:"procs,omitempty"`
+	Samples map[string][]float64 `json:"samples"`
+}
+
+// Key identifies the benchmark across runs
+func (b *Benchmark) Key() string {
+	return b.Package + "." + b.Name
+}
+
+// BenchRun is the stored result of running benchmarks at a commit
+type BenchRun struct {
+	Key        string       `json:"key"`
+	Commit     string       `json:"commit,omitempty"`
+	Dirty      bool         `json:"dirty,omitempty"`
+	Timestamp  time.Time    `json:"timestamp"`
+	Options    BenchOptions `json:"options"`
+	Benchmarks []*Benchmark `json:"benchmarks"`
+}
+
+// BenchRunner runs go benchmarks and keeps their results per commit
+type BenchRunner struct {
+	executor *CommandExecutor
+	git      *GitManager
+	storeDir string
+}
+
+func NewBenchRunner(rootDir string) *BenchRunner {
+	return &BenchRunner{
+		executor: NewCommandExecutor(rootDir),
+		git:      NewGitManager(rootDir),
+		storeDir: filepath.Join(rootDir, ".mcp", "bench"),
+	}
+}
+
+// Run executes the benchmarks and returns the parsed results. The run is
+// keyed by the current commit, with a -dirty suffix for uncommitted changes.
+func (br *BenchRunner) Run(ctx context.Context, opts BenchOptions) (*BenchRun, error) {
+	if opts.Filter == "" {
+		opts.Filter = "."
+	}
+	if opts.Count <= 0 {
+		opts.Count = 5
+	}
+	if len(opts.Packages) == 0 {
+		opts.Packages = []string{"./..."}
+	}
+
+	args := []string{opts.Filter, opts.Benchtime}
+	args = append(args, opts.Packages...)
+	for _, arg := range args {
+		if strings.ContainsAny(arg, " \t\n") {
+			return nil, fmt.Errorf("invalid argument %q", arg)
+		}
+	}
+
+	command := fmt.Sprintf("go test -run ^$ -bench %s -count %d", opts.Filter, opts.Count)
+	if opts.Benchtime != "" {
+		command += " -benchtime " + opts.Benchtime
+	}
+	if opts.Benchmem {
+		command += " -benchmem"
+	}
+	command += " " + strings.Join(opts.Packages, " ")
+
+	result, err := br.executor.Execute(ctx, command)
+	if err != nil {
+		return nil, err
+	}
+
+	benchmarks := ParseBenchOutput(result.Output)
+	if !result.Success && len(benchmarks) == 0 {
+		return nil, fmt.Errorf("go test failed: %s", strings.TrimSpace(result.Error+result.Output))
+	}
+
+	run := &BenchRun{
+		Timestamp:  time.Now(),
+		Options:    opts,
+		Benchmarks: benchmarks,
+	}
+	run.Commit, run.Dirty, err = br.git.HeadCommit()
+	if err != nil {
+		run.Key = "uncommitted"
+	} else {
+		run.Key = shortCommit(run.Commit)
+		if run.Dirty {
+			run.Key += "-dirty"
+		}
+	}
+
+	return run, nil
+}
+
+// ParseBenchOutput parses the output of go test -bench
+func ParseBenchOutput(output string) []*Benchmark {
+	var benchmarks []*Benchmark
+	index := make(map[string]*Benchmark)
+	pkg := ""
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "pkg: ") {
+			pkg = strings.TrimPrefix(line, "pkg: ")
+			continue
+		}
+		if !strings.HasPrefix(line, "Benchmark") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		// Name, iterations, then value/unit pairs
+		if len(fields) < 4 || len(fields)%2 != 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+
+		name, procs := splitProcs(fields[0])
+		key := pkg + "." + name
+		b, ok := index[key]
+		if !ok {
+			b = &Benchmark{Name: name, Package: pkg, Procs: procs, Samples: make(map[string][]float64)}
+			index[key] = b
+			benchmarks = append(benchmarks, b)
+		}
+
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			b.Samples[fields[i+1]] = append(b.Samples[fields[i+1]], value)
+		}
+	}
+
+	return benchmarks
+}
+
+// splitProcs splits the GOMAXPROCS suffix from a benchmark name
+func splitProcs(name string) (string, int) {
+	i := strings.LastIndex(name, "-")
+	if i < 0 {
+		return name, 0
+	}
+	procs, err := strconv.Atoi(name[i+1:])
+	if err != nil {
+		return name, 0
+	}
+	return name[:i], procs
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// Save stores the run, replacing any earlier run for the same key
+func (br *BenchRunner) Save(run *BenchRun) error {
+	if err := os.MkdirAll(br.storeDir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(run, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(br.storeDir, run.Key+".json"), data, 0644)
+}
+
+// Load returns the stored run for a key or a git revision
+func (br *BenchRunner) Load(ref string) (*BenchRun, error) {
+	if strings.ContainsAny(ref, `/\`) || ref == "" || ref == "." || ref == ".." {
+		return nil, fmt.Errorf("invalid benchmark run %q", ref)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(br.storeDir, ref+".json"))
+	if os.IsNotExist(err) {
+		commit, resolveErr := br.git.ResolveCommit(ref)
+		if resolveErr != nil {
+			return nil, fmt.Errorf("no benchmark run for %s", ref)
+		}
+		data, err = ioutil.ReadFile(filepath.Join(br.storeDir, shortCommit(commit)+".json"))
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no benchmark run for %s", ref)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var run BenchRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse benchmark run: %v", err)
+	}
+	return &run, nil
+}
+
+// List returns the stored runs, newest first
+func (br *BenchRunner) List() ([]*BenchRun, error) {
+	entries, err := ioutil.ReadDir(br.storeDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*BenchRun
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		run, err := br.Load(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Timestamp.After(runs[j].Timestamp) })
+	return runs, nil
+}
+
+// LatestBaseline returns the newest stored run other than run itself, or nil
+// if there is none. A dirty run thus compares against the clean run of the
+// same commit when that is the most recent one.
+func (br *BenchRunner) LatestBaseline(run *BenchRun) (*BenchRun, error) {
+	runs, err := br.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range runs {
+		if candidate.Key != run.Key {
+			return candidate, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,66 @@
+package ide
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const benchOutput = `goos: linux
+goarch: amd64
+pkg: example.com/demo
+cpu: Test CPU
+BenchmarkParse-8   	  100000	      1200 ns/op	     256 B/op	       4 allocs/op
+BenchmarkParse-8   	  100000	      1250 ns/op	     256 B/op	       4 allocs/op
+BenchmarkEncode/small-8   	 500000	       310.5 ns/op
+PASS
+ok  	example.com/demo	3.2s
+`
+
+func TestParseBenchOutput(t *testing.T) {
+	benchmarks := ParseBenchOutput(benchOutput)
+	require.Len(t, benchmarks, 2)
+
+	parse := benchmarks[0]
+	assert.Equal(t, "BenchmarkParse", parse.Name)
+	assert.Equal(t, "example.com/demo", parse.Package)
+	assert.Equal(t, 8, parse.Procs)
+	assert.Equal(t, []float64{1200, 1250}, parse.Samples["ns/op"])
+	assert.Equal(t, []float64{4, 4}, parse.Samples["allocs/op"])
+
+	assert.Equal(t, "BenchmarkEncode/small", benchmarks[1].Name)
+	assert.Equal(t, []float64{310.5}, benchmarks[1].Samples["ns/op"])
+}
+
+func TestCompareBenchRuns(t *testing.T) {
+	run := func(samples ...float64) *BenchRun {
+		return &BenchRun{Benchmarks: []*Benchmark{{
+			Name:    "BenchmarkParse",
+			Package: "example.com/demo",
+			Samples: map[string][]float64{"ns/op": samples},
+		}}}
+	}
+
+	base := run(100, 101, 99, 98, 102)
+
+	slower := CompareBenchRuns(base, run(120, 121, 119, 122, 118), 5)
+	require.Len(t, slower, 1)
+	assert.InDelta(t, 20, slower[0].Delta, 0.01)
+	assert.InDelta(t, 0.0079, slower[0].PValue, 0.0001)
+	assert.True(t, slower[0].Significant)
+	assert.True(t, slower[0].Regression)
+
+	same := CompareBenchRuns(base, run(100.5, 99.5, 101.5, 98.5, 100.2), 5)
+	require.Len(t, same, 1)
+	assert.False(t, same[0].Significant)
+	assert.False(t, same[0].Regression)
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{90, 100, 110, 100})
+	assert.Equal(t, 100.0, s.Median)
+	assert.InDelta(t, 10, s.Spread, 0.001)
+	assert.Equal(t, 4, s.N)
+	assert.Nil(t, Summarize(nil))
+}
@@ -0,0 +1,239 @@
+package ide
+
+import (
+	"math"
+	"sort"
+)
+
+// benchAlpha is the significance level used when comparing runs
+const benchAlpha = 0.05
+
+// BenchSummary summarizes the samples of one metric like benchstat does:
+// the median and the largest deviation from it as a percentage
+type BenchSummary struct {
+	Median float64 `json:"median"`
+	Spread float64 `json:"spread_percent"`
+	N      int     `json:"n"`
+}
+
+// BenchComparison compares one metric of a benchmark between two runs
+type BenchComparison struct {
+	Benchmark   string        `json:"benchmark"`
+	Package     string        `json:"package"`
+	Unit        string        `json:"unit"`
+	Old         *BenchSummary `json:"old,omitempty"`
+	New         *BenchSummary `json:"new,omitempty"`
+	Delta       float64       `json:"delta_percent"`
+	PValue      float64       `json:"p_value"`
+	Significant bool          `json:"significant"`
+	Regression  bool          `json:"regression"`
+}
+
+// Summarize computes the summary of a set of samples
+func Summarize(samples []float64) *BenchSummary {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	var median float64
+	if n := len(sorted); n%2 == 1 {
+		median = sorted[n/2]
+	} else {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+
+	var spread float64
+	if median != 0 {
+		for _, v := range []float64{sorted[0], sorted[len(sorted)-1]} {
+			spread = math.Max(spread, math.Abs(v-median)/math.Abs(median)*100)
+		}
+	}
+
+	return &BenchSummary{Median: median, Spread: spread, N: len(sorted)}
+}
+
+// CompareBenchRuns compares every metric present in either run. Changes are
+// significant when a Mann-Whitney U test rejects equality at p < 0.05; a
+// significant increase above threshold percent is flagged as a regression,
+// since for all standard metrics lower is better.
+func CompareBenchRuns(base, head *BenchRun, threshold float64) []BenchComparison {
+	oldIndex := make(map[string]*Benchmark)
+	for _, b := range base.Benchmarks {
+		oldIndex[b.Key()] = b
+	}
+
+	var comparisons []BenchComparison
+	seen := make(map[string]bool)
+
+	for _, nb := range head.Benchmarks {
+		seen[nb.Key()] = true
+		ob := oldIndex[nb.Key()]
+		for _, unit := range sortedUnits(nb, ob) {
+			var oldSamples []float64
+			if ob != nil {
+				oldSamples = ob.Samples[unit]
+			}
+			comparisons = append(comparisons, compareSamples(nb.Name, nb.Package, unit, oldSamples, nb.Samples[unit], threshold))
+		}
+	}
+
+	for _, ob := range base.Benchmarks {
+		if seen[ob.Key()] {
+			continue
+		}
+		for _, unit := range sortedUnits(ob, nil) {
+			comparisons = append(comparisons, compareSamples(ob.Name, ob.Package, unit, ob.Samples[unit], nil, threshold))
+		}
+	}
+
+	return comparisons
+}
+
+func sortedUnits(a, b *Benchmark) []string {
+	set := make(map[string]bool)
+	for _, bench := range []*Benchmark{a, b} {
+		if bench == nil {
+			continue
+		}
+		for unit := range bench.Samples {
+			set[unit] = true
+		}
+	}
+
+	units := make([]string, 0, len(set))
+	for unit := range set {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	return units
+}
+
+func compareSamples(name, pkg, unit string, oldSamples, newSamples []float64, threshold float64) BenchComparison {
+	c := BenchComparison{
+		Benchmark: name,
+		Package:   pkg,
+		Unit:      unit,
+		Old:       Summarize(oldSamples),
+		New:       Summarize(newSamples),
+		PValue:    1,
+	}
+	if c.Old == nil || c.New == nil {
+		return c
+	}
+
+	if c.Old.Median != 0 {
+		c.Delta = (c.New.Median - c.Old.Median) / math.Abs(c.Old.Median) * 100
+	}
+	c.PValue = mannWhitneyU(oldSamples, newSamples)
+	c.Significant = c.PValue < benchAlpha
+	c.Regression = c.Significant && c.Delta > threshold
+	return c
+}
+
+// mannWhitneyU returns the two-sided p-value of the Mann-Whitney U test.
+// Small samples without ties use the exact distribution of U, everything else
+// the normal approximation with tie correction.
+func mannWhitneyU(a, b []float64) float64 {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type sample struct {
+		value float64
+		first bool
+	}
+	all := make([]sample, 0, n1+n2)
+	for _, v := range a {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range b {
+		all = append(all, sample{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	// Assign average ranks to ties
+	var rankSum, tieTerm float64
+	ties := false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].first {
+				rankSum += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			ties = true
+			tieTerm += t*t*t - t
+		}
+		i = j
+	}
+
+	u := rankSum - float64(n1*(n1+1))/2
+	if !ties && n1+n2 <= 40 {
+		return exactUPValue(n1, n2, u)
+	}
+
+	n := float64(n1 + n2)
+	mean := float64(n1*n2) / 2
+	variance := float64(n1*n2) / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		return 1
+	}
+
+	// Continuity correction towards the mean
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		z = 0
+	}
+	return math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// exactUPValue computes the two-sided p-value of U from its exact null
+// distribution, counting the rank arrangements that produce each U
+func exactUPValue(n1, n2 int, u float64) float64 {
+	maxU := n1 * n2
+	// prev[j][k] counts the arrangements of i-1 first and j second samples
+	// with U == k
+	prev := make([][]float64, n2+1)
+	for j := range prev {
+		prev[j] = make([]float64, maxU+1)
+		prev[j][0] = 1
+	}
+	for i := 1; i <= n1; i++ {
+		cur := make([][]float64, n2+1)
+		cur[0] = make([]float64, maxU+1)
+		cur[0][0] = 1
+		for j := 1; j <= n2; j++ {
+			cur[j] = make([]float64, maxU+1)
+			for k := 0; k <= maxU; k++ {
+				// The largest value is either from the first sample, beating
+				// all j of the second, or from the second sample
+				if k >= j {
+					cur[j][k] += prev[j][k-j]
+				}
+				cur[j][k] += cur[j-1][k]
+			}
+		}
+		prev = cur
+	}
+
+	dist := prev[n2]
+	var total, tail float64
+	low := math.Min(u, float64(maxU)-u)
+	for k, count := range dist {
+		total += count
+		if float64(k) <= low {
+			tail += count
+		}
+	}
+
+	return math.Min(1, 2*tail/total)
+}
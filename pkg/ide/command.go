@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -37,13 +38,7 @@ func (ce *CommandExecutor) Execute(ctx context.Context, command string) (*Comman
 
 	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
 	cmd.Dir = ce.workDir
-
-	// Setup environment
-	env := os.Environ()
-	for k, v := range ce.env {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
-	}
-	cmd.Env = env
+	cmd.Env = ce.environ()
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -62,3 +57,34 @@ func (ce *CommandExecutor) Execute(ctx context.Context, command string) (*Comman
 
 	return result, nil
 }
+
+// Start starts a command without waiting for it, streaming its output to
+// stdout and stderr. Cancelling the context kills the command together with
+// any processes it started.
+func (ce *CommandExecutor) Start(ctx context.Context, command string, stdout, stderr io.Writer) (Process, error) {
+	parts := strings.Fields(command)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd.Dir = ce.workDir
+	cmd.Env = ce.environ()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = 5 * time.Second
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &localProcess{cmd: cmd}, nil
+}
+
+func (ce *CommandExecutor) environ() []string {
+	env := os.Environ()
+	for k, v := range ce.env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return env
+}
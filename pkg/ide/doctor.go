@@ -0,0 +1,214 @@
+package ide
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Check statuses
+const (
+	CheckOK       = "ok"
+	CheckMissing  = "missing"  // No binary found
+	CheckOutdated = "outdated" // Older than the minimum version
+	CheckError    = "error"    // Found, but the version could not be determined
+)
+
+// versionTimeout bounds how long a tool may take to print its version
+const versionTimeout = 10 * time.Second
+
+// ToolCheck describes a command line tool the workspace needs
+type ToolCheck struct {
+	Name        string   // Name reported in results
+	Binaries    []string // Candidates looked up in PATH; the first found is used
+	Path        string   // Binary to use instead of looking up Binaries
+	VersionArgs []string // Arguments that make the tool print its version
+	MinVersion  string   // Lowest acceptable version, if any
+	Required    bool     // Whether the workspace is unusable without the tool
+	Hint        string   // How to install or upgrade the tool
+}
+
+// CheckResult is the outcome of a ToolCheck
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Required bool   `json:"required"`
+	Path     string `json:"path,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Want     string `json:"want,omitempty"` // Minimum version
+	Message  string `json:"message,omitempty"`
+	Hint     string `json:"hint,omitempty"` // Set when the check did not pass
+}
+
+// DoctorReport is the result of a set of checks
+type DoctorReport struct {
+	Ready     bool          `json:"ready"` // No required check failed
+	Checks    []CheckResult `json:"checks"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// RunChecks runs the checks concurrently and reports them in order
+func RunChecks(ctx context.Context, checks []ToolCheck) *DoctorReport {
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check ToolCheck) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := &DoctorReport{Ready: true, Checks: results, CheckedAt: time.Now()}
+	for _, r := range results {
+		if r.Required && r.Status != CheckOK {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, check ToolCheck) CheckResult {
+	result := CheckResult{Name: check.Name, Required: check.Required, Want: check.MinVersion}
+
+	result.Path = check.Path
+	for _, bin := range check.Binaries {
+		if result.Path != "" {
+			break
+		}
+		result.Path, _ = exec.LookPath(bin)
+	}
+	if result.Path == "" {
+		result.Status = CheckMissing
+		result.Message = fmt.Sprintf("%s was not found in PATH", check.Name)
+		result.Hint = check.Hint
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, result.Path, check.VersionArgs...)
+	// Report the installed go rather than a toolchain go.mod would select
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+	output, err := cmd.CombinedOutput()
+	result.Version = versionPattern.FindString(string(output))
+	if result.Version == "" {
+		result.Status = CheckError
+		result.Message = "could not determine the version"
+		if err != nil {
+			result.Message = fmt.Sprintf("%s: %v", result.Message, err)
+		}
+		result.Hint = check.Hint
+		return result
+	}
+
+	if check.MinVersion != "" && compareVersions(result.Version, check.MinVersion) < 0 {
+		result.Status = CheckOutdated
+		result.Message = fmt.Sprintf("version %s is older than the required %s", result.Version, check.MinVersion)
+		result.Hint = check.Hint
+		return result
+	}
+
+	result.Status = CheckOK
+	return result
+}
+
+// versionPattern matches the first dotted version number in a tool's output,
+// e.g. 1.23.1 in "go version go1.23.1 linux/amd64"
+var versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// compareVersions compares dotted numeric versions, treating missing
+// components as zero
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// ProjectChecks returns the tool checks for a project. Tools are required
+// when the project evidently uses them: Go when it has a go.mod, whose go
+// directive sets the minimum version, git when enabled or a repository
+// exists, and docker when there is a Dockerfile or a command runs docker.
+func ProjectChecks(config *ProjectConfig) []ToolCheck {
+	root := config.Root
+
+	goVersion, hasGoMod := goDirective(filepath.Join(root, "go.mod"))
+	goHint := "install Go from https://go.dev/dl"
+	if goVersion != "" {
+		goHint = fmt.Sprintf("install Go %s or later from https://go.dev/dl", goVersion)
+	}
+
+	commands := config.BuildCommand + "\n" + config.RunCommand + "\n" + config.TestCommand
+	usesDocker := strings.Contains(commands, "docker") ||
+		exists(filepath.Join(root, "Dockerfile")) ||
+		exists(filepath.Join(root, "docker-compose.yml")) ||
+		exists(filepath.Join(root, "compose.yaml"))
+
+	return []ToolCheck{
+		{
+			Name: "go", Binaries: []string{"go"}, VersionArgs: []string{"version"},
+			MinVersion: goVersion, Required: hasGoMod, Hint: goHint,
+		},
+		{
+			Name: "git", Binaries: []string{"git"}, VersionArgs: []string{"--version"},
+			Required: config.GitEnabled || exists(filepath.Join(root, ".git")),
+			Hint:     "install git with your package manager, e.g. apt install git",
+		},
+		{
+			Name: "docker", Binaries: []string{"docker"}, VersionArgs: []string{"--version"},
+			Required: usesDocker, Hint: "install Docker from https://docs.docker.com/get-docker",
+		},
+		{
+			Name: "dlv", Binaries: []string{"dlv"}, VersionArgs: []string{"version"},
+			Hint: "go install github.com/go-delve/delve/cmd/dlv@latest",
+		},
+	}
+}
+
+// goDirective returns the go version declared in a go.mod file and whether
+// the file exists
+func goDirective(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "go" {
+			return fields[1], true
+		}
+	}
+	return "", true
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,68 @@
+package ide
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, compareVersions("1.22", "1.22.0"))
+	assert.Equal(t, -1, compareVersions("1.9.3", "1.22"))
+	assert.Equal(t, 1, compareVersions("2.0", "1.99.99"))
+}
+
+func TestProjectChecks(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "go.mod", "module example.com/demo\n\ngo 1.22.0\n")
+	writeTestFile(t, root, "Dockerfile", "FROM scratch\n")
+
+	checks := make(map[string]ToolCheck)
+	for _, check := range ProjectChecks(&ProjectConfig{Root: root}) {
+		checks[check.Name] = check
+	}
+
+	assert.True(t, checks["go"].Required)
+	assert.Equal(t, "1.22.0", checks["go"].MinVersion)
+	assert.True(t, checks["docker"].Required)
+	assert.False(t, checks["git"].Required)
+	assert.False(t, checks["dlv"].Required)
+}
+
+func TestRunChecks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the tool")
+	}
+
+	tool := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, os.WriteFile(tool, []byte("#!/bin/sh\necho 'tool version v1.4.2 (build abc)'\n"), 0755))
+
+	report := RunChecks(context.Background(), []ToolCheck{
+		{Name: "current", Path: tool, MinVersion: "1.4", Required: true},
+		{Name: "outdated", Path: tool, MinVersion: "1.10", Hint: "upgrade"},
+		{Name: "missing", Binaries: []string{"no-such-tool-for-doctor"}, Hint: "install"},
+	})
+
+	require.Len(t, report.Checks, 3)
+	assert.True(t, report.Ready)
+
+	assert.Equal(t, CheckOK, report.Checks[0].Status)
+	assert.Equal(t, "1.4.2", report.Checks[0].Version)
+	assert.Empty(t, report.Checks[0].Hint)
+
+	assert.Equal(t, CheckOutdated, report.Checks[1].Status)
+	assert.Equal(t, "upgrade", report.Checks[1].Hint)
+
+	assert.Equal(t, CheckMissing, report.Checks[2].Status)
+	assert.Equal(t, "install", report.Checks[2].Hint)
+
+	report = RunChecks(context.Background(), []ToolCheck{
+		{Name: "missing", Binaries: []string{"no-such-tool-for-doctor"}, Required: true},
+	})
+	assert.False(t, report.Ready)
+}
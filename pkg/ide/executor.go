@@ -0,0 +1,49 @@
+package ide
+
+import (
+	"context"
+	"io"
+	"os/exec"
+)
+
+// Executor runs task commands, either locally or on a remote host
+type Executor interface {
+	// Execute runs a command to completion and collects its output
+	Execute(ctx context.Context, command string) (*CommandResult, error)
+	// Start starts a command, streaming its output to stdout and stderr.
+	// Cancelling the context kills the command.
+	Start(ctx context.Context, command string, stdout, stderr io.Writer) (Process, error)
+}
+
+// Process is a command started by an Executor
+type Process interface {
+	// Wait waits for the command to exit
+	Wait() error
+	// ExitCode returns the exit code once Wait has returned, or -1 if the
+	// command was killed
+	ExitCode() int
+	// Pid returns the ID of the local process, or 0 for remote commands
+	Pid() int
+}
+
+var _ Executor = (*CommandExecutor)(nil)
+
+// localProcess is a Process running on this machine
+type localProcess struct {
+	cmd *exec.Cmd
+}
+
+func (p *localProcess) Wait() error {
+	return p.cmd.Wait()
+}
+
+func (p *localProcess) Pid() int {
+	return p.cmd.Process.Pid
+}
+
+func (p *localProcess) ExitCode() int {
+	if p.cmd.ProcessState == nil {
+		return -1
+	}
+	return p.cmd.ProcessState.ExitCode()
+}
@@ -3,6 +3,7 @@ package ide
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -19,57 +20,59 @@ func NewGitManager(workDir string) *GitManager {
 	}
 }
 
+// GetStatus returns the branch, changed files and last commit of the working
+// tree. Files with staged and unstaged changes are listed as both.
 func (gm *GitManager) GetStatus() (*GitStatus, error) {
 	ctx := context.Background()
 
-	// Get current branch
-	branchResult, err := gm.executor.Execute(ctx, "git rev-parse --abbrev-ref HEAD")
+	statusResult, err := gm.executor.Execute(ctx, "git status --porcelain --branch")
 	if err != nil {
 		return nil, err
 	}
-
-	// Get status
-	statusResult, err := gm.executor.Execute(ctx, "git status --porcelain")
-	if err != nil {
-		return nil, err
-	}
-
-	// Get last commit info
-	commitResult, err := gm.executor.Execute(ctx, "git log -1 --format=%H%n%an%n%at")
-	if err != nil {
-		return nil, err
+	if !statusResult.Success {
+		return nil, fmt.Errorf("git status failed: %s", strings.TrimSpace(statusResult.Error))
 	}
 
 	status := &GitStatus{
-		Branch:    strings.TrimSpace(branchResult.Output),
-		IsClean:   statusResult.Output == "",
 		Modified:  []string{},
 		Untracked: []string{},
 		Staged:    []string{},
 	}
 
-	// Parse status output
+	// Parse status output: a "## branch...upstream [ahead 1]" header, then
+	// lines of index and worktree state, a space and the path
 	for _, line := range strings.Split(statusResult.Output, "\n") {
-		if len(line) < 3 {
+		if header, ok := strings.CutPrefix(line, "## "); ok {
+			status.Branch, status.RemoteStatus = parseBranchHeader(header)
+			continue
+		}
+		if len(line) < 4 {
 			continue
 		}
 
-		state := line[:2]
-		file := line[3:]
-
-		switch {
-		case state == "M ":
-			status.Modified = append(status.Modified, file)
-		case state == "??":
+		index, worktree, file := line[0], line[1], line[3:]
+		if index == '?' {
 			status.Untracked = append(status.Untracked, file)
-		case state == "A ":
+			continue
+		}
+		if index != ' ' {
 			status.Staged = append(status.Staged, file)
 		}
+		if worktree != ' ' {
+			status.Modified = append(status.Modified, file)
+		}
+	}
+	status.IsClean = len(status.Modified)+len(status.Untracked)+len(status.Staged) == 0
+
+	// Get last commit info; fails before the first commit
+	commitResult, err := gm.executor.Execute(ctx, "git log -1 --format=%H%n%an%n%at")
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse commit info
 	commitInfo := strings.Split(commitResult.Output, "\n")
-	if len(commitInfo) >= 3 {
+	if commitResult.Success && len(commitInfo) >= 3 {
 		status.LastCommit = commitInfo[0]
 		status.LastCommitAuthor = commitInfo[1]
 		timestamp, _ := strconv.ParseInt(strings.TrimSpace(commitInfo[2]), 10, 64)
@@ -79,6 +82,18 @@ func (gm *GitManager) GetStatus() (*GitStatus, error) {
 	return status, nil
 }
 
+// parseBranchHeader splits the branch header of git status, such as
+// "main...origin/main [ahead 1, behind 2]", into the branch and the state
+// relative to its upstream
+func parseBranchHeader(header string) (branch, remote string) {
+	if i := strings.Index(header, " ["); i >= 0 && strings.HasSuffix(header, "]") {
+		header, remote = header[:i], header[i+2:len(header)-1]
+	}
+	header = strings.TrimPrefix(header, "No commits yet on ")
+	branch, _, _ = strings.Cut(header, "...")
+	return branch, remote
+}
+
 func (gm *GitManager) Pull() error {
 	_, err := gm.executor.Execute(context.Background(), "git pull")
 	return err
@@ -102,3 +117,179 @@ func (gm *GitManager) Commit(message string) error {
 	_, err = gm.executor.Execute(ctx, fmt.Sprintf("git commit -m %q", message))
 	return err
 }
+
+// FileChangeCounts returns the number of commits touching each file, optionally
+// limited to commits newer than since (any git date, e.g. "90.days")
+func (gm *GitManager) FileChangeCounts(since string) (map[string]int, error) {
+	command := "git log --name-only --relative --format="
+	if since != "" {
+		if strings.ContainsAny(since, " \t") {
+			return nil, fmt.Errorf("invalid since value: %q", since)
+		}
+		command += " --since=" + since
+	}
+
+	result, err := gm.executor.Execute(context.Background(), command)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("git log failed: %s", strings.TrimSpace(result.Error))
+	}
+
+	counts := make(map[string]int)
+	for _, line := range strings.Split(result.Output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			counts[line]++
+		}
+	}
+
+	return counts, nil
+}
+
+// FileChurn returns the history of changes of each file touched by commits,
+// optionally limited to commits newer than since (any git date, e.g.
+// "90.days") and to paths. Authors are told apart by their email, after
+// the mailmap of the repository. Lines of binary files are not counted.
+func (gm *GitManager) FileChurn(since string, paths ...string) (map[string]*FileChurn, error) {
+	command := "git log --numstat --no-renames --relative --format=%x1e%aN%x1f%aE%x1f%aI"
+	if since != "" {
+		if strings.ContainsAny(since, " \t") {
+			return nil, fmt.Errorf("invalid since value: %q", since)
+		}
+		command += " --since=" + since
+	}
+	if len(paths) > 0 {
+		for _, path := range paths {
+			if strings.HasPrefix(path, "-") || strings.ContainsAny(path, " \t") {
+				return nil, fmt.Errorf("invalid path: %q", path)
+			}
+		}
+		command += " -- " + strings.Join(paths, " ")
+	}
+
+	result, err := gm.executor.Execute(context.Background(), command)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("git log failed: %s", strings.TrimSpace(result.Error))
+	}
+
+	// Commits start with a header of their author and date, followed by
+	// a line of added and deleted lines per file
+	churn := make(map[string]*FileChurn)
+	var name, email string
+	var date time.Time
+	for _, line := range strings.Split(result.Output, "\n") {
+		if header, ok := strings.CutPrefix(line, "\x1e"); ok {
+			fields := strings.Split(header, "\x1f")
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("unexpected git log header: %q", header)
+			}
+			name, email = fields[0], fields[1]
+			if date, err = time.Parse(time.RFC3339, fields[2]); err != nil {
+				return nil, fmt.Errorf("unexpected git log date: %v", err)
+			}
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		added, _ := strconv.Atoi(fields[0]) // - for binary files
+		deleted, _ := strconv.Atoi(fields[1])
+
+		file := churn[fields[2]]
+		if file == nil {
+			file = &FileChurn{Path: fields[2], FirstChange: date, LastChange: date}
+			churn[fields[2]] = file
+		}
+		file.Commits++
+		file.LinesAdded += added
+		file.LinesDeleted += deleted
+		if date.Before(file.FirstChange) {
+			file.FirstChange = date
+		}
+		if date.After(file.LastChange) {
+			file.LastChange = date
+		}
+
+		i := 0
+		for i < len(file.Authors) && file.Authors[i].Email != email {
+			i++
+		}
+		if i == len(file.Authors) {
+			file.Authors = append(file.Authors, AuthorChurn{Name: name, Email: email})
+		}
+		file.Authors[i].Commits++
+		file.Authors[i].LinesChanged += added + deleted
+	}
+
+	for _, file := range churn {
+		sort.SliceStable(file.Authors, func(i, j int) bool {
+			a, b := file.Authors[i], file.Authors[j]
+			if a.LinesChanged != b.LinesChanged {
+				return a.LinesChanged > b.LinesChanged
+			}
+			return a.Commits > b.Commits
+		})
+	}
+	return churn, nil
+}
+
+// HeadCommit returns the hash of HEAD and whether the working tree has
+// uncommitted changes
+func (gm *GitManager) HeadCommit() (string, bool, error) {
+	commit, err := gm.ResolveCommit("HEAD")
+	if err != nil {
+		return "", false, err
+	}
+
+	result, err := gm.executor.Execute(context.Background(), "git status --porcelain --untracked-files=no")
+	if err != nil {
+		return "", false, err
+	}
+
+	return commit, strings.TrimSpace(result.Output) != "", nil
+}
+
+// ResolveCommit resolves a revision such as a branch, tag or short hash to a
+// full commit hash
+func (gm *GitManager) ResolveCommit(rev string) (string, error) {
+	if rev == "" || strings.HasPrefix(rev, "-") || strings.ContainsAny(rev, " \t") {
+		return "", fmt.Errorf("invalid revision: %q", rev)
+	}
+
+	result, err := gm.executor.Execute(context.Background(), "git rev-parse --verify --quiet "+rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	if !result.Success {
+		return "", fmt.Errorf("unknown revision: %s", rev)
+	}
+
+	return strings.TrimSpace(result.Output), nil
+}
+
+// Diff returns the changes of the working tree against a revision, HEAD
+// when base is empty
+func (gm *GitManager) Diff(base string) (string, error) {
+	if base == "" {
+		base = "HEAD"
+	}
+	commit, err := gm.ResolveCommit(base)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := gm.executor.Execute(context.Background(), "git diff --no-color "+commit)
+	if err != nil {
+		return "", err
+	}
+	if !result.Success {
+		return "", fmt.Errorf("git diff failed: %s", strings.TrimSpace(result.Error))
+	}
+
+	return result.Output, nil
+}
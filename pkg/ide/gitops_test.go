@@ -0,0 +1,124 @@
+package ide
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBranchHeader(t *testing.T) {
+	cases := []struct {
+		header, branch, remote string
+	}{
+		{"main", "main", ""},
+		{"main...origin/main", "main", ""},
+		{"main...origin/main [ahead 1, behind 2]", "main", "ahead 1, behind 2"},
+		{"No commits yet on main", "main", ""},
+		{"HEAD (no branch)", "HEAD (no branch)", ""},
+	}
+	for _, c := range cases {
+		branch, remote := parseBranchHeader(c.header)
+		assert.Equal(t, c.branch, branch, c.header)
+		assert.Equal(t, c.remote, remote, c.header)
+	}
+}
+
+func TestGitManager_GetStatus(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	git("init", "-q", "-b", "main")
+	gm := NewGitManager(dir)
+	status, err := gm.GetStatus()
+	require.NoError(t, err)
+	assert.Equal(t, "main", status.Branch)
+	assert.True(t, status.IsClean)
+	assert.Empty(t, status.LastCommit)
+
+	writeTestFile(t, dir, "a.txt", "a")
+	writeTestFile(t, dir, "b.txt", "b")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	writeTestFile(t, dir, "a.txt", "changed")
+	writeTestFile(t, dir, "b.txt", "staged")
+	git("add", "b.txt")
+	writeTestFile(t, dir, "b.txt", "staged and changed")
+	writeTestFile(t, dir, "c.txt", "new")
+
+	status, err = gm.GetStatus()
+	require.NoError(t, err)
+	assert.False(t, status.IsClean)
+	assert.Equal(t, []string{"a.txt", "b.txt"}, status.Modified)
+	assert.Equal(t, []string{"b.txt"}, status.Staged)
+	assert.Equal(t, []string{"c.txt"}, status.Untracked)
+	assert.Len(t, status.LastCommit, 40)
+	assert.Equal(t, "t", status.LastCommitAuthor)
+
+	diff, err := gm.Diff("")
+	require.NoError(t, err)
+	assert.Contains(t, diff, "+staged and changed")
+	assert.Contains(t, diff, "+changed")
+	_, err = gm.Diff("--output=x")
+	assert.Error(t, err)
+
+	_, err = NewGitManager(t.TempDir()).GetStatus()
+	assert.Error(t, err)
+}
+
+func TestGitManager_FileChurn(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(author string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=" + author, "-c", "user.email=" + strings.ToLower(strings.Fields(author)[0]) + "@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	git("Ann", "init", "-q", "-b", "main")
+	writeTestFile(t, dir, "a.txt", "1\n2\n3\n")
+	writeTestFile(t, dir, "b.txt", "b\n")
+	git("Ann", "add", ".")
+	git("Ann", "commit", "-q", "-m", "init")
+	writeTestFile(t, dir, "a.txt", "1\nchanged\n")
+	git("Bob Smith", "commit", "-q", "-am", "change")
+	writeTestFile(t, dir, "a.txt", "1\nchanged\n4\n5\n6\n")
+	git("Bob Smith", "commit", "-q", "-am", "append")
+
+	gm := NewGitManager(dir)
+	churn, err := gm.FileChurn("")
+	require.NoError(t, err)
+	require.Len(t, churn, 2)
+	a := churn["a.txt"]
+	assert.Equal(t, 3, a.Commits)
+	assert.Equal(t, 7, a.LinesAdded)
+	assert.Equal(t, 2, a.LinesDeleted)
+	assert.False(t, a.FirstChange.After(a.LastChange))
+	assert.Equal(t, []AuthorChurn{
+		{Name: "Bob Smith", Email: "bob@example.com", Commits: 2, LinesChanged: 6},
+		{Name: "Ann", Email: "ann@example.com", Commits: 1, LinesChanged: 3},
+	}, a.Authors)
+
+	churn, err = gm.FileChurn("", "b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b.txt"}, []string{churn["b.txt"].Path})
+	assert.Len(t, churn, 1)
+
+	_, err = gm.FileChurn("1 week")
+	assert.Error(t, err)
+	_, err = gm.FileChurn("", "--output=x")
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package ide
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInspectUnsupported is returned where process and port inspection is not
+// implemented for the host operating system
+var ErrInspectUnsupported = errors.New("process inspection is not supported on this platform")
+
+// Errors returned by KillTaskProcess
+var (
+	ErrProcessNotFound   = errors.New("process not found")
+	ErrNotTaskProcess    = errors.New("process was not started by a task")
+	ErrUnsupportedSignal = errors.New("unsupported signal")
+)
+
+// ProcessInfo describes a process on the workspace host
+type ProcessInfo struct {
+	PID       int       `json:"pid"`
+	PPID      int       `json:"ppid"`
+	PGID      int       `json:"pgid"`
+	Name      string    `json:"name"`
+	Command   string    `json:"command"`
+	UID       int       `json:"uid"`
+	RSS       int64     `json:"rss"` // Resident memory in bytes
+	StartTime time.Time `json:"start_time,omitempty"`
+	TaskID    string    `json:"task_id,omitempty"` // Task that started the process, directly or not
+}
+
+// ListeningPort is a socket accepting connections or datagrams
+type ListeningPort struct {
+	Protocol string `json:"protocol"` // tcp, tcp6, udp or udp6
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	PID      int    `json:"pid,omitempty"` // Zero when the owner cannot be determined
+	Process  string `json:"process,omitempty"`
+	TaskID   string `json:"task_id,omitempty"`
+}
+
+// Inspection is a snapshot of the host's processes and listening ports, with
+// those belonging to tasks marked
+type Inspection struct {
+	Processes []ProcessInfo
+	Ports     []ListeningPort
+}
+
+// Inspect lists the processes and listening ports of the host and marks
+// those started by the given tasks, keyed by their root process ID
+func Inspect(taskPIDs map[int]string) (*Inspection, error) {
+	processes, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+	ports, err := listListeningPorts()
+	if err != nil {
+		return nil, err
+	}
+
+	assignTasks(processes, taskPIDs)
+
+	byPID := make(map[int]*ProcessInfo, len(processes))
+	for i := range processes {
+		byPID[processes[i].PID] = &processes[i]
+	}
+	for i := range ports {
+		if p, ok := byPID[ports[i].PID]; ok {
+			ports[i].Process = p.Name
+			ports[i].TaskID = p.TaskID
+		}
+	}
+
+	sort.Slice(processes, func(i, j int) bool { return processes[i].PID < processes[j].PID })
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Port != ports[j].Port {
+			return ports[i].Port < ports[j].Port
+		}
+		return ports[i].Protocol < ports[j].Protocol
+	})
+
+	return &Inspection{Processes: processes, Ports: ports}, nil
+}
+
+// assignTasks marks processes descending from a task's root process. Tasks
+// run in their own process group, which also catches descendants that were
+// orphaned and reparented.
+func assignTasks(processes []ProcessInfo, taskPIDs map[int]string) {
+	parent := make(map[int]int, len(processes))
+	for _, p := range processes {
+		parent[p.PID] = p.PPID
+	}
+
+	for i := range processes {
+		p := &processes[i]
+		if taskID, ok := taskPIDs[p.PGID]; ok {
+			p.TaskID = taskID
+			continue
+		}
+		// Walk up the tree, bounded in case of a cycle in a racy snapshot
+		for pid, depth := p.PID, 0; pid > 1 && depth < len(processes); pid, depth = parent[pid], depth+1 {
+			if taskID, ok := taskPIDs[pid]; ok {
+				p.TaskID = taskID
+				break
+			}
+		}
+	}
+}
+
+// signals are the signals that may be sent to task processes
+var signals = map[string]bool{"TERM": true, "KILL": true, "INT": true, "HUP": true}
+
+// KillTaskProcess sends a signal (TERM by default) to a process, provided it
+// was started by a task. Other processes on the host are never signalled.
+func KillTaskProcess(pid int, signal string, taskPIDs map[int]string) (*ProcessInfo, error) {
+	signal = strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	if signal == "" {
+		signal = "TERM"
+	}
+	if !signals[signal] {
+		return nil, fmt.Errorf("%w %s", ErrUnsupportedSignal, signal)
+	}
+
+	processes, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+	assignTasks(processes, taskPIDs)
+
+	for _, p := range processes {
+		if p.PID != pid {
+			continue
+		}
+		if p.TaskID == "" {
+			return nil, fmt.Errorf("%w: %d", ErrNotTaskProcess, pid)
+		}
+		if err := signalProcess(pid, signal); err != nil {
+			return nil, fmt.Errorf("failed to signal process %d: %v", pid, err)
+		}
+		return &p, nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrProcessNotFound, pid)
+}
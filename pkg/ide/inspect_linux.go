@@ -0,0 +1,194 @@
+//go:build linux
+
+package ide
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of process start times in /proc. It is 100
+// on every mainstream Linux architecture.
+const clockTicks = 100
+
+// listProcesses reads every process from /proc. Processes that exit while
+// being read are skipped.
+func listProcesses() ([]ProcessInfo, error) {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	bootTime := readBootTime()
+	pageSize := int64(os.Getpagesize())
+
+	var processes []ProcessInfo
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		p, err := readProcess(pid, bootTime, pageSize)
+		if err != nil {
+			continue
+		}
+		processes = append(processes, *p)
+	}
+	return processes, nil
+}
+
+func readProcess(pid int, bootTime time.Time, pageSize int64) (*ProcessInfo, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+	stat, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The name is parenthesized and may itself contain spaces and parentheses
+	nameStart, nameEnd := bytes.IndexByte(stat, '('), bytes.LastIndexByte(stat, ')')
+	if nameStart < 0 || nameEnd < nameStart {
+		return nil, fmt.Errorf("malformed stat for process %d", pid)
+	}
+	fields := strings.Fields(string(stat[nameEnd+1:]))
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("malformed stat for process %d", pid)
+	}
+
+	// Fields after the name start at field 3 (state) of proc(5)
+	p := &ProcessInfo{PID: pid, Name: string(stat[nameStart+1 : nameEnd])}
+	p.PPID, _ = strconv.Atoi(fields[1])
+	p.PGID, _ = strconv.Atoi(fields[2])
+	if ticks, err := strconv.ParseInt(fields[19], 10, 64); err == nil && !bootTime.IsZero() {
+		p.StartTime = bootTime.Add(time.Duration(ticks) * time.Second / clockTicks)
+	}
+	if pages, err := strconv.ParseInt(fields[21], 10, 64); err == nil {
+		p.RSS = pages * pageSize
+	}
+
+	if cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		p.Command = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	}
+	if p.Command == "" {
+		p.Command = "[" + p.Name + "]" // Kernel threads have no command line
+	}
+
+	if status, err := ioutil.ReadFile(filepath.Join(dir, "status")); err == nil {
+		for _, line := range strings.Split(string(status), "\n") {
+			if rest, ok := strings.CutPrefix(line, "Uid:"); ok {
+				if uid := strings.Fields(rest); len(uid) > 0 {
+					p.UID, _ = strconv.Atoi(uid[0])
+				}
+				break
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func readBootTime() time.Time {
+	data, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(line, "btime "); ok {
+			if secs, err := strconv.ParseInt(strings.TrimSpace(rest), 10, 64); err == nil {
+				return time.Unix(secs, 0)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// Socket states in /proc/net tables
+const (
+	tcpListen      = "0A"
+	udpUnconnected = "07"
+)
+
+// listListeningPorts reads listening TCP and bound UDP sockets from
+// /proc/net and finds their owners through the socket links in /proc/*/fd.
+// Owners of sockets in other users' processes are only visible to root.
+func listListeningPorts() ([]ListeningPort, error) {
+	var ports []ListeningPort
+	inodes := make(map[string]int) // Index into ports by socket inode
+
+	for _, table := range []struct{ protocol, state string }{
+		{"tcp", tcpListen}, {"tcp6", tcpListen}, {"udp", udpUnconnected}, {"udp6", udpUnconnected},
+	} {
+		f, err := os.Open(filepath.Join("/proc/net", table.protocol))
+		if err != nil {
+			continue // The protocol may be disabled, e.g. IPv6
+		}
+
+		scanner := bufio.NewScanner(f)
+		scanner.Scan() // Header
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 || fields[3] != table.state {
+				continue
+			}
+			ip, port, err := parseSocketAddress(fields[1])
+			if err != nil {
+				continue
+			}
+			inodes[fields[9]] = len(ports)
+			ports = append(ports, ListeningPort{Protocol: table.protocol, Address: ip.String(), Port: port})
+		}
+		f.Close()
+	}
+
+	if len(ports) == 0 {
+		return ports, nil
+	}
+
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range fds {
+		link, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		i, ok := inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")]
+		if !ok || ports[i].PID != 0 {
+			continue
+		}
+		ports[i].PID, _ = strconv.Atoi(strings.Split(fd, "/")[2])
+	}
+
+	return ports, nil
+}
+
+// parseSocketAddress parses an address such as 0100007F:1F90 from /proc/net.
+// The IP is stored as 32-bit words in host (little-endian) byte order.
+func parseSocketAddress(s string) (net.IP, int, error) {
+	hexIP, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, 0, fmt.Errorf("malformed address %s", s)
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return nil, 0, fmt.Errorf("malformed address %s", s)
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("malformed port %s", s)
+	}
+
+	ip := make(net.IP, len(raw))
+	for word := 0; word < len(raw); word += 4 {
+		for i := 0; i < 4; i++ {
+			ip[word+i] = raw[word+3-i]
+		}
+	}
+	return ip, int(port), nil
+}
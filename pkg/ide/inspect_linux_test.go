@@ -0,0 +1,81 @@
+//go:build linux
+
+package ide
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSocketAddress(t *testing.T) {
+	ip, port, err := parseSocketAddress("0100007F:1F90")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", ip.String())
+	assert.Equal(t, 8080, port)
+
+	ip, port, err = parseSocketAddress("00000000000000000000000001000000:0050")
+	require.NoError(t, err)
+	assert.Equal(t, "::1", ip.String())
+	assert.Equal(t, 80, port)
+
+	_, _, err = parseSocketAddress("0100007F")
+	assert.Error(t, err)
+}
+
+func TestInspect_ListeningPort(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	inspection, err := Inspect(map[int]string{os.Getpid(): "test"})
+	require.NoError(t, err)
+
+	var found *ListeningPort
+	for i := range inspection.Ports {
+		if inspection.Ports[i].Port == port {
+			found = &inspection.Ports[i]
+		}
+	}
+	require.NotNil(t, found, "port %d not listed", port)
+	assert.Equal(t, "tcp", found.Protocol)
+	assert.Equal(t, "127.0.0.1", found.Address)
+	assert.Equal(t, os.Getpid(), found.PID)
+	assert.Equal(t, "test", found.TaskID)
+}
+
+func TestKillTaskProcess(t *testing.T) {
+	tm := NewTaskManager()
+	task := &Task{ID: "sleeper", Command: "sleep 30"}
+	require.NoError(t, tm.StartTask(task, NewCommandExecutor(t.TempDir())))
+	defer tm.StopTask("sleeper")
+
+	var pid int
+	require.Eventually(t, func() bool {
+		for p := range tm.TaskPIDs() {
+			pid = p
+		}
+		return pid != 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err := KillTaskProcess(os.Getpid(), "", tm.TaskPIDs())
+	assert.ErrorIs(t, err, ErrNotTaskProcess)
+	_, err = KillTaskProcess(pid, "STOP", tm.TaskPIDs())
+	assert.ErrorIs(t, err, ErrUnsupportedSignal)
+
+	process, err := KillTaskProcess(pid, "SIGKILL", tm.TaskPIDs())
+	require.NoError(t, err)
+	assert.Equal(t, "sleeper", process.TaskID)
+	assert.Equal(t, "sleep", process.Name)
+
+	require.Eventually(t, func() bool {
+		return len(tm.TaskPIDs()) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = KillTaskProcess(pid, "", tm.TaskPIDs())
+	assert.ErrorIs(t, err, ErrProcessNotFound)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package ide
+
+func listProcesses() ([]ProcessInfo, error) {
+	return nil, ErrInspectUnsupported
+}
+
+func listListeningPorts() ([]ListeningPort, error) {
+	return nil, ErrInspectUnsupported
+}
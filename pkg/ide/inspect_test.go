@@ -0,0 +1,25 @@
+package ide
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignTasks(t *testing.T) {
+	processes := []ProcessInfo{
+		{PID: 1, PPID: 0, PGID: 1},
+		{PID: 100, PPID: 1, PGID: 100},   // Task root
+		{PID: 101, PPID: 100, PGID: 100}, // Child in the task's group
+		{PID: 102, PPID: 101, PGID: 102}, // Grandchild that started its own group
+		{PID: 103, PPID: 1, PGID: 100},   // Orphan reparented to init
+		{PID: 200, PPID: 1, PGID: 200},   // Unrelated
+	}
+	assignTasks(processes, map[int]string{100: "server"})
+
+	owners := make(map[int]string)
+	for _, p := range processes {
+		owners[p.PID] = p.TaskID
+	}
+	assert.Equal(t, map[int]string{1: "", 100: "server", 101: "server", 102: "server", 103: "server", 200: ""}, owners)
+}
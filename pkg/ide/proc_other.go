@@ -0,0 +1,21 @@
+//go:build !unix
+
+package ide
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op where process groups are unavailable; cancelling
+// the command only kills the process itself
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcess kills the process; other signals cannot be delivered here
+func signalProcess(pid int, signal string) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package ide
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the command in its own process group so cancelling
+// it also kills the processes it spawned, such as the binary behind go run
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// signalProcess sends a signal, by name without the SIG prefix, to a process
+func signalProcess(pid int, signal string) error {
+	sig := map[string]syscall.Signal{
+		"TERM": syscall.SIGTERM,
+		"KILL": syscall.SIGKILL,
+		"INT":  syscall.SIGINT,
+		"HUP":  syscall.SIGHUP,
+	}[signal]
+	return syscall.Kill(pid, sig)
+}
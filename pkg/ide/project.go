@@ -7,6 +7,7 @@ import (
 	"fmt"
 	_ "github.com/gorilla/mux"
 	"io/ioutil"
+	"log/slog"
 	_ "net/http"
 	"os"
 	"path/filepath"
@@ -34,15 +35,7 @@ func NewProjectManager(rootDir string) (*ProjectManager, error) {
 
 	if err := pm.loadConfig(); err != nil {
 		// Create default config if not exists
-		pm.config = &ProjectConfig{
-			Name:         filepath.Base(rootDir),
-			Root:         rootDir,
-			BuildCommand: "go build",
-			RunCommand:   "go run .",
-			TestCommand:  "go test ./...",
-			Environment:  make(map[string]string),
-			GitEnabled:   true,
-		}
+		pm.config = defaultProjectConfig(rootDir)
 		if err := pm.saveConfig(); err != nil {
 			return nil, err
 		}
@@ -51,6 +44,56 @@ func NewProjectManager(rootDir string) (*ProjectManager, error) {
 	return pm, nil
 }
 
+func defaultProjectConfig(rootDir string) *ProjectConfig {
+	return &ProjectConfig{
+		Name:         filepath.Base(rootDir),
+		Root:         rootDir,
+		BuildCommand: "go build",
+		RunCommand:   "go run .",
+		TestCommand:  "go test ./...",
+		Environment:  make(map[string]string),
+		GitEnabled:   true,
+	}
+}
+
+// SetRoot moves the project to another directory, such as one an MCP host
+// grants. The config found in the directory is used, or a default one; it is
+// not written to the directory.
+func (pm *ProjectManager) SetRoot(rootDir string) error {
+	info, err := os.Stat(rootDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", rootDir)
+	}
+
+	configPath := filepath.Join(rootDir, ".mcp", "project.json")
+	config := defaultProjectConfig(rootDir)
+	if data, err := os.ReadFile(configPath); err == nil {
+		if err := json.Unmarshal(data, config); err != nil {
+			return fmt.Errorf("invalid project config %s: %w", configPath, err)
+		}
+		config.Root = rootDir
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.config = config
+	pm.configPath = configPath
+	pm.fileManager = NewFileManager(rootDir)
+	pm.cmdExecutor = NewCommandExecutor(rootDir)
+	pm.gitManager = NewGitManager(rootDir)
+	return nil
+}
+
+// Files returns the file manager of the project root
+func (pm *ProjectManager) Files() *FileManager {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.fileManager
+}
+
 func (pm *ProjectManager) loadConfig() error {
 	data, err := ioutil.ReadFile(pm.configPath)
 	if err != nil {
@@ -100,45 +143,107 @@ func NewTaskManager() *TaskManager {
 	return &TaskManager{
 		tasks:  make(map[string]*Task),
 		cancel: make(map[string]context.CancelFunc),
+		logs:   make(map[string]*TaskLog),
+		pids:   make(map[string]int),
+		logger: slog.Default(),
 	}
 }
 
-func (tm *TaskManager) StartTask(task *Task, executor *CommandExecutor) error {
+// SetLogger sets the logger used for task lifecycle events
+func (tm *TaskManager) SetLogger(logger *slog.Logger) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.logger = logger
+}
+
+// SetArtifactStore sets the store receiving the artifacts of tasks
+func (tm *TaskManager) SetArtifactStore(store *ArtifactStore) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.artifacts = store
+}
+
+// taskLogger returns a logger annotated with the task and, when known, the
+// request that started it
+func (tm *TaskManager) taskLogger(task *Task) *slog.Logger {
+	logger := tm.logger.With("task_id", task.ID, "task", task.Name)
+	if task.RequestID != "" {
+		logger = logger.With("request_id", task.RequestID)
+	}
+	return logger
+}
+
+// StartTask starts a task with the given executor, which determines whether
+// it runs locally or remotely
+func (tm *TaskManager) StartTask(task *Task, executor Executor) error {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
 	if _, exists := tm.tasks[task.ID]; exists {
 		return fmt.Errorf("task %s already running", task.ID)
 	}
+	if task.Type == "" {
+		task.Type = TaskTypeCommand
+	}
+	if task.Type != TaskTypeCommand && task.Type != TaskTypeWatch {
+		return fmt.Errorf("unknown task type %q", task.Type)
+	}
+	if len(task.Artifacts) > 0 {
+		if tm.artifacts == nil {
+			return fmt.Errorf("artifacts are not enabled")
+		}
+		if _, ok := executor.(*CommandExecutor); !ok {
+			return fmt.Errorf("artifacts can only be collected from local tasks")
+		}
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	log := newTaskLog()
 	tm.tasks[task.ID] = task
 	tm.cancel[task.ID] = cancel
+	tm.logs[task.ID] = log
+
+	logger := tm.taskLogger(task)
+	logger.Info("task started", "type", task.Type, "command", task.Command, "auto_restart", task.AutoRestart)
 
+	tm.wg.Add(1)
 	go func() {
+		defer tm.wg.Done()
+		defer log.close()
+
+		if task.Type == TaskTypeWatch {
+			tm.runWatch(ctx, task, executor, log, logger)
+			return
+		}
+
 		for {
 			select {
 			case <-ctx.Done():
-				tm.mu.Lock()
-				task.Status = "stopped"
-				tm.mu.Unlock()
+				tm.setStatus(task, log, "stopped")
+				logger.Info("task stopped")
 				return
 			default:
-				_, err := executor.Execute(ctx, task.Command)
+				tm.mu.Lock()
+				task.Runs++
+				tm.mu.Unlock()
+
+				err := tm.runProcess(ctx, task, executor, log)
+				if ctx.Err() == nil {
+					tm.collectArtifacts(task, executor, log, logger)
+				}
 				if err != nil {
-					tm.mu.Lock()
-					task.Status = fmt.Sprintf("error: %v", err)
-					tm.mu.Unlock()
+					tm.setStatus(task, log, fmt.Sprintf("error: %v", err))
+					logger.Warn("task failed", "error", err)
 					if !task.AutoRestart {
 						return
 					}
 				}
 				if !task.AutoRestart {
-					tm.mu.Lock()
-					task.Status = "completed"
-					tm.mu.Unlock()
+					tm.setStatus(task, log, "completed")
+					logger.Info("task completed")
 					return
 				}
+				logger.Debug("task restarting")
 				time.Sleep(time.Second) // Prevent rapid restarts
 			}
 		}
@@ -147,6 +252,95 @@ func (tm *TaskManager) StartTask(task *Task, executor *CommandExecutor) error {
 	return nil
 }
 
+// runProcess runs the task command once, streaming its output to the log
+func (tm *TaskManager) runProcess(ctx context.Context, task *Task, executor Executor, log *TaskLog) error {
+	stdout := newLineWriter(log, TaskEventStdout)
+	stderr := newLineWriter(log, TaskEventStderr)
+	process, err := executor.Start(ctx, task.Command, stdout, stderr)
+	if err != nil {
+		return err
+	}
+
+	tm.setPID(task.ID, process.Pid())
+	process.Wait()
+	tm.setPID(task.ID, 0)
+
+	stdout.Flush()
+	stderr.Flush()
+	log.Append(TaskEventExit, fmt.Sprintf("exit code %d", process.ExitCode()))
+	return nil
+}
+
+// setPID records the root process of a running local task; zero clears it
+func (tm *TaskManager) setPID(taskID string, pid int) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if pid == 0 {
+		delete(tm.pids, taskID)
+	} else {
+		tm.pids[taskID] = pid
+	}
+}
+
+// TaskPIDs returns the task ID of each running local task keyed by the ID
+// of its root process
+func (tm *TaskManager) TaskPIDs() map[int]string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	pids := make(map[int]string, len(tm.pids))
+	for taskID, pid := range tm.pids {
+		pids[pid] = taskID
+	}
+	return pids
+}
+
+// collectArtifacts registers the files matching the task's artifact globs
+// as outputs of its current run. Failed runs are collected too, since their
+// reports are often what is needed.
+func (tm *TaskManager) collectArtifacts(task *Task, executor Executor, log *TaskLog, logger *slog.Logger) {
+	local, ok := executor.(*CommandExecutor)
+	if len(task.Artifacts) == 0 || !ok {
+		return
+	}
+
+	tm.mu.RLock()
+	store := tm.artifacts
+	source := ArtifactSource{TaskID: task.ID, TaskRun: task.Runs}
+	tm.mu.RUnlock()
+
+	artifacts, err := store.Collect(source, local.workDir, task.Artifacts)
+	if err != nil {
+		log.Append(TaskEventArtifact, fmt.Sprintf("failed to collect artifacts: %v", err))
+		logger.Warn("artifact collection failed", "error", err)
+		return
+	}
+	if len(artifacts) == 0 {
+		return
+	}
+
+	names := make([]string, len(artifacts))
+	for i, a := range artifacts {
+		names[i] = a.Name
+	}
+	log.Append(TaskEventArtifact, fmt.Sprintf("registered %d artifact(s)", len(artifacts)), names...)
+	logger.Info("artifacts registered", "run", source.TaskRun, "count", len(artifacts))
+}
+
+func (tm *TaskManager) setStatus(task *Task, log *TaskLog, status string) {
+	tm.mu.Lock()
+	task.Status = status
+	tm.mu.Unlock()
+	log.Append(TaskEventStatus, status)
+}
+
+// TaskLog returns the log stream of a task, or nil if the task is unknown
+func (tm *TaskManager) TaskLog(taskID string) *TaskLog {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.logs[taskID]
+}
+
 func (tm *TaskManager) StopTask(taskID string) error {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
@@ -161,12 +355,48 @@ func (tm *TaskManager) StopTask(taskID string) error {
 	return nil
 }
 
+// Shutdown stops all running tasks and waits for them to exit, or until the
+// context is done
+func (tm *TaskManager) Shutdown(ctx context.Context) error {
+	tm.mu.Lock()
+	for id, cancel := range tm.cancel {
+		cancel()
+		delete(tm.cancel, id)
+	}
+	tm.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		tm.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for tasks to stop: %w", ctx.Err())
+	}
+}
+
 func (tm *TaskManager) GetTask(taskID string) *Task {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
 	return tm.tasks[taskID]
 }
 
+// CurrentRun returns the number of the latest run of a task
+func (tm *TaskManager) CurrentRun(taskID string) (int, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return 0, fmt.Errorf("task %s not found", taskID)
+	}
+	return task.Runs, nil
+}
+
 func (tm *TaskManager) ListTasks() []*Task {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
@@ -0,0 +1,31 @@
+package ide
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectManager_SetRoot(t *testing.T) {
+	pm, err := NewProjectManager(t.TempDir())
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644))
+	require.NoError(t, pm.SetRoot(dir))
+	config := pm.GetConfig()
+	assert.Equal(t, dir, config.Root)
+	assert.Equal(t, filepath.Base(dir), config.Name)
+	data, err := pm.Files().ReadFile("main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", string(data))
+	_, err = os.Stat(filepath.Join(dir, ".mcp"))
+	assert.True(t, os.IsNotExist(err), "the config is not written to the new root")
+
+	assert.Error(t, pm.SetRoot(filepath.Join(dir, "main.go")))
+	assert.Error(t, pm.SetRoot(filepath.Join(dir, "missing")))
+	assert.Equal(t, dir, pm.GetConfig().Root)
+}
@@ -0,0 +1,167 @@
+package ide
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// Task log event types
+const (
+	TaskEventStdout     = "stdout"
+	TaskEventStderr     = "stderr"
+	TaskEventStatus     = "status"
+	TaskEventRestart    = "restart"
+	TaskEventBuildError = "build_error"
+	TaskEventExit       = "exit"
+	TaskEventArtifact   = "artifact"
+)
+
+// taskLogSize is the number of events retained per task
+const taskLogSize = 1000
+
+// TaskEvent is a single entry in a task's log stream
+type TaskEvent struct {
+	Seq     int64     `json:"seq"`
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Message string    `json:"message,omitempty"`
+	Files   []string  `json:"files,omitempty"`
+}
+
+// TaskLog keeps the recent events of a task and fans new ones out to
+// subscribers. Subscribers that fall behind miss events rather than block
+// the task; they can catch up with Since.
+type TaskLog struct {
+	events      []TaskEvent
+	next        int64
+	subscribers map[chan TaskEvent]struct{}
+	closed      bool
+	mu          sync.Mutex
+}
+
+func newTaskLog() *TaskLog {
+	return &TaskLog{
+		next:        1,
+		subscribers: make(map[chan TaskEvent]struct{}),
+	}
+}
+
+// Append records an event and delivers it to subscribers
+func (l *TaskLog) Append(eventType, message string, files ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return
+	}
+
+	event := TaskEvent{
+		Seq:     l.next,
+		Time:    time.Now(),
+		Type:    eventType,
+		Message: message,
+		Files:   files,
+	}
+	l.next++
+
+	l.events = append(l.events, event)
+	if len(l.events) > taskLogSize {
+		l.events = append([]TaskEvent(nil), l.events[len(l.events)-taskLogSize:]...)
+	}
+
+	for ch := range l.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Since returns the retained events with a sequence number above seq
+func (l *TaskLog) Since(seq int64) []TaskEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	events := []TaskEvent{}
+	for _, event := range l.events {
+		if event.Seq > seq {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Subscribe returns a channel receiving new events and a function to
+// unsubscribe. The channel is closed when the task finishes.
+func (l *TaskLog) Subscribe() (<-chan TaskEvent, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch := make(chan TaskEvent, 64)
+	if l.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	l.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.subscribers[ch]; ok {
+			delete(l.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// close ends the stream for all subscribers
+func (l *TaskLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	for ch := range l.subscribers {
+		delete(l.subscribers, ch)
+		close(ch)
+	}
+}
+
+// lineWriter turns process output into one log event per line
+type lineWriter struct {
+	log       *TaskLog
+	eventType string
+	buf       bytes.Buffer
+	mu        sync.Mutex
+}
+
+func newLineWriter(log *TaskLog, eventType string) *lineWriter {
+	return &lineWriter{log: log, eventType: eventType}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := string(bytes.TrimRight(w.buf.Next(i+1), "\r\n"))
+		w.log.Append(w.eventType, line)
+	}
+	return len(p), nil
+}
+
+// Flush emits any trailing output without a newline
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buf.Len() > 0 {
+		w.log.Append(w.eventType, w.buf.String())
+		w.buf.Reset()
+	}
+}
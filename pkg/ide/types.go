@@ -2,6 +2,7 @@ package ide
 
 import (
 	"context"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -38,6 +39,26 @@ type GitStatus struct {
 	LastCommitDate   time.Time `json:"last_commit_date"`
 }
 
+// FileChurn is the history of changes of a file: the commits touching it,
+// the lines they added and deleted, and their authors
+type FileChurn struct {
+	Path         string        `json:"path"`
+	Commits      int           `json:"commits"`
+	LinesAdded   int           `json:"lines_added"`
+	LinesDeleted int           `json:"lines_deleted"`
+	FirstChange  time.Time     `json:"first_change"`
+	LastChange   time.Time     `json:"last_change"`
+	Authors      []AuthorChurn `json:"authors"` // Most lines changed first
+}
+
+// AuthorChurn is the share of an author in the changes of a file
+type AuthorChurn struct {
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	Commits      int    `json:"commits"`
+	LinesChanged int    `json:"lines_changed"` // Added and deleted
+}
+
 // ProjectConfig represents project configuration
 type ProjectConfig struct {
 	Name         string            `json:"name"`
@@ -47,18 +68,56 @@ type ProjectConfig struct {
 	TestCommand  string            `json:"test_command"`
 	Environment  map[string]string `json:"environment"`
 	GitEnabled   bool              `json:"git_enabled"`
+
+	ArtifactRetention *RetentionPolicy `json:"artifact_retention,omitempty"` // Defaults to DefaultRetentionPolicy
+	FormatOnSave      string           `json:"format_on_save,omitempty"`     // Formatter of Go files saved through the IDE: gofmt, goimports or gofumpt
 }
+
+// Task types
+const (
+	TaskTypeCommand = "command" // Runs the command, optionally restarting it when it exits
+	TaskTypeWatch   = "watch"   // Rebuilds and restarts the command when watched files change
+)
+
 type Task struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Command     string `json:"command"`
-	AutoRestart bool   `json:"auto_restart"`
-	Status      string `json:"status"`
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Type        string       `json:"type,omitempty"`
+	Command     string       `json:"command"`
+	AutoRestart bool         `json:"auto_restart"`
+	Watch       *WatchConfig `json:"watch,omitempty"`
+	Artifacts   []string     `json:"artifacts,omitempty"` // Globs of output files registered after each run, or each build of watch tasks
+	Target      *TaskTarget  `json:"target,omitempty"`    // Remote host to run on, local when nil
+	Status      string       `json:"status"`
+	Restarts    int          `json:"restarts,omitempty"`
+	Runs        int          `json:"runs,omitempty"`       // Number of times the command has been started
+	RequestID   string       `json:"request_id,omitempty"` // Request that started the task, for log correlation
+}
+
+// TaskTarget selects the remote host, or group of hosts, a task runs on
+type TaskTarget struct {
+	Connection string `json:"connection,omitempty"` // SSH connection ID
+	Group      string `json:"group,omitempty"`      // SSH host group; the command runs on every host
+	Dir        string `json:"dir,omitempty"`        // Remote working directory
+}
+
+// WatchConfig configures a watch task
+type WatchConfig struct {
+	Dir          string   `json:"dir,omitempty"`           // Local directory to watch, defaults to the working directory of local tasks
+	Patterns     []string `json:"patterns,omitempty"`      // Globs of files to watch, defaults to **/*.go
+	Ignore       []string `json:"ignore,omitempty"`        // Globs of files to ignore
+	BuildCommand string   `json:"build_command,omitempty"` // Run before each start; failures are reported as build errors
+	DebounceMs   int      `json:"debounce_ms,omitempty"`   // Quiet period before restarting, defaults to 300
 }
 
 // TaskManager handles long-running development tasks
 type TaskManager struct {
-	tasks  map[string]*Task
-	cancel map[string]context.CancelFunc
-	mu     sync.RWMutex
+	tasks     map[string]*Task
+	cancel    map[string]context.CancelFunc
+	logs      map[string]*TaskLog
+	pids      map[string]int // Root process of each running local task
+	artifacts *ArtifactStore
+	logger    *slog.Logger
+	wg        sync.WaitGroup
+	mu        sync.RWMutex
 }
@@ -0,0 +1,196 @@
+package ide
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWatchInterval = 500 * time.Millisecond
+	defaultWatchDebounce = 300 * time.Millisecond
+)
+
+// defaultWatchIgnore lists directories that are never worth watching
+var defaultWatchIgnore = []string{".git/**", ".mcp/**", "vendor/**", "node_modules/**"}
+
+// Watcher polls a directory tree for changes to files matching glob patterns.
+// Patterns are slash separated and relative to the root; "**" matches any
+// number of directories, e.g. "**/*.go" or "templates/**".
+type Watcher struct {
+	root     string
+	patterns []string
+	ignore   []string
+	interval time.Duration
+}
+
+func NewWatcher(root string, patterns, ignore []string) *Watcher {
+	if len(patterns) == 0 {
+		patterns = []string{"**/*.go"}
+	}
+	return &Watcher{
+		root:     root,
+		patterns: patterns,
+		ignore:   append(append([]string(nil), defaultWatchIgnore...), ignore...),
+		interval: defaultWatchInterval,
+	}
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch sends the sorted list of changed files each time the tree changes,
+// once no further change has been seen for the debounce period. Changes
+// made after Watch returns are reported. The channel is closed when the
+// context is done.
+func (w *Watcher) Watch(ctx context.Context, debounce time.Duration) <-chan []string {
+	if debounce <= 0 {
+		debounce = defaultWatchDebounce
+	}
+
+	changes := make(chan []string)
+	state := w.snapshot()
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		pending := make(map[string]bool)
+		var lastChange time.Time
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				next := w.snapshot()
+				for _, file := range diffSnapshots(state, next) {
+					pending[file] = true
+					lastChange = now
+				}
+				state = next
+
+				if len(pending) == 0 || now.Sub(lastChange) < debounce {
+					continue
+				}
+
+				files := make([]string, 0, len(pending))
+				for file := range pending {
+					files = append(files, file)
+				}
+				sort.Strings(files)
+				pending = make(map[string]bool)
+
+				select {
+				case changes <- files:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return changes
+}
+
+// snapshot records the modification time and size of every watched file
+func (w *Watcher) snapshot() map[string]fileStamp {
+	files := make(map[string]fileStamp)
+	filepath.Walk(w.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(w.root, p)
+		if err != nil || rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if w.ignored(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if w.ignored(rel) || !w.matches(rel) {
+			return nil
+		}
+
+		files[rel] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return files
+}
+
+func diffSnapshots(prev, next map[string]fileStamp) []string {
+	var changed []string
+	for file, stamp := range next {
+		if old, ok := prev[file]; !ok || old != stamp {
+			changed = append(changed, file)
+		}
+	}
+	for file := range prev {
+		if _, ok := next[file]; !ok {
+			changed = append(changed, file)
+		}
+	}
+	return changed
+}
+
+func (w *Watcher) matches(rel string) bool {
+	for _, pattern := range w.patterns {
+		if MatchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignored reports whether a path is excluded. Since "**" also matches zero
+// directories, a pattern like "vendor/**" excludes the vendor directory itself.
+func (w *Watcher) ignored(rel string) bool {
+	for _, pattern := range w.ignore {
+		if MatchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob reports whether a slash separated path matches a glob pattern in
+// which "**" matches zero or more directories
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,53 @@
+package ide
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "pkg/ide/watcher.go", true},
+		{"**/*.go", "pkg/ide/README.md", false},
+		{"templates/**", "templates/a/b.html", true},
+		{"vendor/**", "vendor", true},
+		{"cmd/*/main.go", "cmd/server/main.go", true},
+		{"cmd/*/main.go", "cmd/server/sub/main.go", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MatchGlob(tt.pattern, tt.name), "%s ~ %s", tt.pattern, tt.name)
+	}
+}
+
+func TestTaskLog(t *testing.T) {
+	log := newTaskLog()
+	events, unsubscribe := log.Subscribe()
+	defer unsubscribe()
+
+	w := newLineWriter(log, TaskEventStdout)
+	w.Write([]byte("first\nsec"))
+	w.Write([]byte("ond\npartial"))
+	w.Flush()
+	log.Append(TaskEventRestart, "changed", "main.go")
+
+	all := log.Since(0)
+	assert.Len(t, all, 4)
+	assert.Equal(t, "second", all[1].Message)
+	assert.Equal(t, "partial", all[2].Message)
+	assert.Equal(t, []string{"main.go"}, all[3].Files)
+	assert.Len(t, log.Since(2), 2)
+
+	assert.Equal(t, "first", (<-events).Message)
+
+	log.close()
+	for range events {
+	}
+	log.Append(TaskEventStdout, "ignored after close")
+	assert.Len(t, log.Since(0), 4)
+}
@@ -0,0 +1,140 @@
+package ide
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// watchedProcess is one run of a watch task's command
+type watchedProcess struct {
+	cancel   context.CancelFunc
+	done     chan struct{}
+	exitCode int
+}
+
+// stop kills the process and waits for it to exit
+func (p *watchedProcess) stop() {
+	if p == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+// exited returns a channel closed when the process exits on its own, or nil
+// (blocking forever in a select) when nothing is running
+func (p *watchedProcess) exited() <-chan struct{} {
+	if p == nil {
+		return nil
+	}
+	return p.done
+}
+
+// runWatch builds and runs the task command, rebuilding and restarting it
+// whenever watched files change, until the context is cancelled
+func (tm *TaskManager) runWatch(ctx context.Context, task *Task, executor Executor, log *TaskLog, logger *slog.Logger) {
+	cfg := task.Watch
+	if cfg == nil {
+		cfg = &WatchConfig{}
+	}
+
+	dir := cfg.Dir
+	if local, ok := executor.(*CommandExecutor); ok && dir == "" {
+		dir = local.workDir
+	}
+	if dir == "" {
+		tm.setStatus(task, log, "error: watch tasks on remote hosts need a local watch directory")
+		return
+	}
+
+	watcher := NewWatcher(dir, cfg.Patterns, cfg.Ignore)
+	changes := watcher.Watch(ctx, time.Duration(cfg.DebounceMs)*time.Millisecond)
+
+	proc := tm.launchWatched(ctx, task, executor, cfg, log, logger)
+	for {
+		select {
+		case <-ctx.Done():
+			proc.stop()
+			tm.setStatus(task, log, "stopped")
+			logger.Info("task stopped")
+			return
+
+		case <-proc.exited():
+			log.Append(TaskEventExit, fmt.Sprintf("exit code %d", proc.exitCode))
+			tm.setStatus(task, log, "exited, waiting for changes")
+			proc = nil
+
+		case files, ok := <-changes:
+			if !ok {
+				continue
+			}
+			proc.stop()
+
+			tm.mu.Lock()
+			task.Restarts++
+			tm.mu.Unlock()
+
+			log.Append(TaskEventRestart, fmt.Sprintf("%d file(s) changed, restarting", len(files)), files...)
+			logger.Info("task restarting", "changed", len(files))
+
+			proc = tm.launchWatched(ctx, task, executor, cfg, log, logger)
+		}
+	}
+}
+
+// launchWatched runs the build command, if any, then starts the task
+// command. It returns nil when the build fails or the command cannot start.
+func (tm *TaskManager) launchWatched(ctx context.Context, task *Task, executor Executor, cfg *WatchConfig, log *TaskLog, logger *slog.Logger) *watchedProcess {
+	procCtx, cancel := context.WithCancel(ctx)
+
+	tm.mu.Lock()
+	task.Runs++
+	tm.mu.Unlock()
+
+	if cfg.BuildCommand != "" {
+		tm.setStatus(task, log, "building")
+		result, err := executor.Execute(procCtx, cfg.BuildCommand)
+		if err == nil && !result.Success {
+			err = fmt.Errorf("%s", strings.TrimSpace(result.Error+result.Output))
+		}
+		if err != nil {
+			cancel()
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Append(TaskEventBuildError, err.Error())
+			tm.setStatus(task, log, "build failed, waiting for changes")
+			logger.Warn("task build failed", "error", err)
+			return nil
+		}
+		tm.collectArtifacts(task, executor, log, logger)
+	}
+
+	stdout := newLineWriter(log, TaskEventStdout)
+	stderr := newLineWriter(log, TaskEventStderr)
+	process, err := executor.Start(procCtx, task.Command, stdout, stderr)
+	if err != nil {
+		cancel()
+		tm.setStatus(task, log, fmt.Sprintf("error: %v", err))
+		logger.Warn("task failed to start", "error", err)
+		return nil
+	}
+	tm.setStatus(task, log, "running")
+	tm.setPID(task.ID, process.Pid())
+
+	proc := &watchedProcess{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		process.Wait()
+		tm.setPID(task.ID, 0)
+		stdout.Flush()
+		stderr.Flush()
+		proc.exitCode = process.ExitCode()
+		cancel()
+		close(proc.done)
+	}()
+
+	return proc
+}
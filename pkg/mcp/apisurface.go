@@ -0,0 +1,357 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"go/types"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Kinds of API symbols
+const (
+	APIConst  = "const"
+	APIVar    = "var"
+	APIFunc   = "func"
+	APIType   = "type"
+	APIMethod = "method" // Of a type, or of an interface
+	APIField  = "field"
+)
+
+// Changes of API symbols
+const (
+	APIAdded   = "added"
+	APIRemoved = "removed"
+	APIChanged = "changed"
+)
+
+// APISymbol is an exported declaration of a package, or an exported method
+// or field of one, named Type.Member
+type APISymbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Signature string `json:"signature"` // Without parameter names, e.g. func (*T) Do(context.Context, ...string) error
+}
+
+// PackageAPI is the exported API of a package
+type PackageAPI struct {
+	Path    string      `json:"path"`
+	Name    string      `json:"name"`
+	Symbols []APISymbol `json:"symbols"`
+}
+
+// APISurface is the exported API of the packages matching a pattern
+type APISurface struct {
+	Pattern  string       `json:"pattern"`
+	Packages []PackageAPI `json:"packages"`
+}
+
+// APIChange is a difference between two extractions of an API. Symbol is
+// empty when the whole package was added or removed.
+type APIChange struct {
+	Package  string `json:"package"`
+	Symbol   string `json:"symbol,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	Change   string `json:"change"` // added, removed or changed
+	Breaking bool   `json:"breaking"`
+	Old      string `json:"old,omitempty"` // Signatures before and after
+	New      string `json:"new,omitempty"`
+}
+
+// APIDiffRequest holds two extractions of an API to compare
+type APIDiffRequest struct {
+	Old APISurface `json:"old"`
+	New APISurface `json:"new"`
+}
+
+// APIDiff lists the changes from an API to another
+type APIDiff struct {
+	Breaking bool        `json:"breaking"` // Some change may break clients of the old API
+	Changes  []APIChange `json:"changes"`
+}
+
+// ExtractAPI lists the exported API of the packages matching pattern in
+// root. Commands are left out, and so are internal packages unless internal
+// is set, as other modules cannot import them.
+func ExtractAPI(root, pattern string, internal bool) (*APISurface, error) {
+	// Packages are type checked from source, like their dependencies, as
+	// the export data of the compiler may be newer than the loader reads
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes,
+		Dir:  root,
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %v", err)
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no packages match %s", pattern)
+	}
+
+	surface := &APISurface{Pattern: pattern, Packages: []PackageAPI{}}
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("failed to load package %s: %v", pkg.PkgPath, pkg.Errors[0])
+		}
+		if pkg.Name == "main" || (!internal && isInternalPackage(pkg.PkgPath)) {
+			continue
+		}
+		surface.Packages = append(surface.Packages, PackageAPI{
+			Path:    pkg.PkgPath,
+			Name:    pkg.Name,
+			Symbols: packageAPI(pkg.Types),
+		})
+	}
+	sort.Slice(surface.Packages, func(i, j int) bool {
+		return surface.Packages[i].Path < surface.Packages[j].Path
+	})
+	return surface, nil
+}
+
+// isInternalPackage reports whether a package path has an internal element
+func isInternalPackage(path string) bool {
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// packageAPI returns the exported symbols of a package by name, the members
+// of each type following it
+func packageAPI(pkg *types.Package) []APISymbol {
+	qualifier := types.RelativeTo(pkg)
+	typeString := func(t types.Type) string {
+		return types.TypeString(t, qualifier)
+	}
+
+	symbols := []APISymbol{}
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		if !token.IsExported(name) {
+			continue
+		}
+		switch obj := scope.Lookup(name).(type) {
+		case *types.Const:
+			symbols = append(symbols, APISymbol{Name: name, Kind: APIConst, Signature: "const " + name + " " + typeString(obj.Type())})
+		case *types.Var:
+			symbols = append(symbols, APISymbol{Name: name, Kind: APIVar, Signature: "var " + name + " " + typeString(obj.Type())})
+		case *types.Func:
+			symbols = append(symbols, APISymbol{Name: name, Kind: APIFunc, Signature: "func " + name + signatureString(obj.Type().(*types.Signature), qualifier)})
+		case *types.TypeName:
+			symbols = append(symbols, typeAPI(obj, qualifier)...)
+		}
+	}
+	return symbols
+}
+
+// typeAPI returns a type with its exported fields and methods. The methods
+// of interfaces include those of the interfaces they embed.
+func typeAPI(obj *types.TypeName, qualifier types.Qualifier) []APISymbol {
+	name := obj.Name()
+	if obj.IsAlias() {
+		return []APISymbol{{Name: name, Kind: APIType, Signature: "type " + name + " = " + types.TypeString(obj.Type(), qualifier)}}
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		return nil
+	}
+
+	typeParams := typeParamsString(named.TypeParams(), qualifier)
+	symbol := APISymbol{Name: name, Kind: APIType}
+	var members []APISymbol
+	switch u := named.Underlying().(type) {
+	case *types.Struct:
+		symbol.Signature = "type " + name + typeParams + " struct"
+		for i := 0; i < u.NumFields(); i++ {
+			if f := u.Field(i); f.Exported() {
+				members = append(members, APISymbol{Name: name + "." + f.Name(), Kind: APIField, Signature: name + "." + f.Name() + " " + types.TypeString(f.Type(), qualifier)})
+			}
+		}
+	case *types.Interface:
+		symbol.Signature = "type " + name + typeParams + " interface"
+		for i := 0; i < u.NumMethods(); i++ {
+			if m := u.Method(i); m.Exported() {
+				members = append(members, APISymbol{Name: name + "." + m.Name(), Kind: APIMethod, Signature: "func (" + name + ") " + m.Name() + signatureString(m.Type().(*types.Signature), qualifier)})
+			}
+		}
+	default:
+		symbol.Signature = "type " + name + typeParams + " " + types.TypeString(u, qualifier)
+	}
+
+	for i := 0; i < named.NumMethods(); i++ {
+		m := named.Method(i)
+		if !m.Exported() {
+			continue
+		}
+		sig := m.Type().(*types.Signature)
+		recv := name
+		if _, ok := sig.Recv().Type().(*types.Pointer); ok {
+			recv = "*" + name
+		}
+		members = append(members, APISymbol{Name: name + "." + m.Name(), Kind: APIMethod, Signature: "func (" + recv + ") " + m.Name() + signatureString(sig, qualifier)})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	return append([]APISymbol{symbol}, members...)
+}
+
+// signatureString formats the type parameters, parameters and results of
+// a signature without their names, which clients do not depend on
+func signatureString(sig *types.Signature, qualifier types.Qualifier) string {
+	var b strings.Builder
+	b.WriteString(typeParamsString(sig.TypeParams(), qualifier))
+	b.WriteString("(")
+	for i := 0; i < sig.Params().Len(); i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		t := sig.Params().At(i).Type()
+		if s, ok := t.(*types.Slice); ok && sig.Variadic() && i == sig.Params().Len()-1 {
+			b.WriteString("...")
+			t = s.Elem()
+		}
+		b.WriteString(types.TypeString(t, qualifier))
+	}
+	b.WriteString(")")
+
+	switch results := sig.Results(); results.Len() {
+	case 0:
+	case 1:
+		b.WriteString(" " + types.TypeString(results.At(0).Type(), qualifier))
+	default:
+		b.WriteString(" (")
+		for i := 0; i < results.Len(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(types.TypeString(results.At(i).Type(), qualifier))
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// typeParamsString formats type parameters with their constraints
+func typeParamsString(params *types.TypeParamList, qualifier types.Qualifier) string {
+	if params.Len() == 0 {
+		return ""
+	}
+	list := make([]string, params.Len())
+	for i := range list {
+		tp := params.At(i)
+		list[i] = tp.Obj().Name() + " " + types.TypeString(tp.Constraint(), qualifier)
+	}
+	return "[" + strings.Join(list, ", ") + "]"
+}
+
+// DiffAPI compares two extractions of an API. Removing or changing a symbol
+// breaks clients, and so does adding a method to an interface they may
+// implement; adding anything else does not.
+func DiffAPI(from, to *APISurface) *APIDiff {
+	diff := &APIDiff{Changes: []APIChange{}}
+	add := func(change APIChange) {
+		diff.Changes = append(diff.Changes, change)
+		diff.Breaking = diff.Breaking || change.Breaking
+	}
+
+	oldPkgs := make(map[string]PackageAPI)
+	for _, pkg := range from.Packages {
+		oldPkgs[pkg.Path] = pkg
+	}
+	newPkgs := make(map[string]PackageAPI)
+	for _, pkg := range to.Packages {
+		newPkgs[pkg.Path] = pkg
+		if _, ok := oldPkgs[pkg.Path]; !ok {
+			add(APIChange{Package: pkg.Path, Change: APIAdded})
+		}
+	}
+
+	for _, oldPkg := range from.Packages {
+		newPkg, ok := newPkgs[oldPkg.Path]
+		if !ok {
+			add(APIChange{Package: oldPkg.Path, Change: APIRemoved, Breaking: true})
+			continue
+		}
+
+		oldSymbols := symbolsByName(oldPkg.Symbols)
+		newSymbols := symbolsByName(newPkg.Symbols)
+		for _, s := range oldPkg.Symbols {
+			n, ok := newSymbols[s.Name]
+			switch {
+			case !ok:
+				add(APIChange{Package: oldPkg.Path, Symbol: s.Name, Kind: s.Kind, Change: APIRemoved, Breaking: true, Old: s.Signature})
+			case n.Signature != s.Signature || n.Kind != s.Kind:
+				add(APIChange{Package: oldPkg.Path, Symbol: s.Name, Kind: n.Kind, Change: APIChanged, Breaking: true, Old: s.Signature, New: n.Signature})
+			}
+		}
+		for _, s := range newPkg.Symbols {
+			if _, ok := oldSymbols[s.Name]; ok {
+				continue
+			}
+			// Types implementing an interface of the old API no longer do
+			// when it gets a method
+			breaking := false
+			if i := strings.Index(s.Name, "."); i > 0 && s.Kind == APIMethod {
+				owner, ok := oldSymbols[s.Name[:i]]
+				breaking = ok && strings.HasSuffix(owner.Signature, " interface")
+			}
+			add(APIChange{Package: newPkg.Path, Symbol: s.Name, Kind: s.Kind, Change: APIAdded, Breaking: breaking, New: s.Signature})
+		}
+	}
+
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		a, b := diff.Changes[i], diff.Changes[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Symbol < b.Symbol
+	})
+	return diff
+}
+
+func symbolsByName(symbols []APISymbol) map[string]APISymbol {
+	byName := make(map[string]APISymbol, len(symbols))
+	for _, s := range symbols {
+		byName[s.Name] = s
+	}
+	return byName
+}
+
+func handleAPISurface(root string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		pattern := q.Get("pattern")
+		if pattern == "" {
+			pattern = "./..."
+		}
+		if !localPattern(pattern) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("pattern must be a package path or relative to the workspace"))
+			return
+		}
+
+		surface, err := ExtractAPI(root, pattern, q.Get("internal") == "true")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, surface)
+	}
+}
+
+func handleAPIDiff() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req APIDiffRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, DiffAPI(&req.Old, &req.New))
+	}
+}
@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const apiSourceV1 = `package m
+
+import "context"
+
+const Version = "1.0"
+
+var Default = New()
+
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+}
+
+type Client struct {
+	Addr string
+	conn int
+}
+
+func New() *Client { return &Client{} }
+
+func (c *Client) Do(ctx context.Context, args ...string) error { return nil }
+
+func Map[K comparable, V any](m map[K]V) []K { return nil }
+
+func helper() {}
+`
+
+const apiSourceV2 = `package m
+
+import "context"
+
+var Default = New("")
+
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+	Put(ctx context.Context, key, value string) error
+}
+
+type Client struct {
+	Addr    string
+	Timeout int
+}
+
+func New(addr string) *Client { return &Client{} }
+
+func Open() *Client { return nil }
+
+func (client *Client) Do(c context.Context, commands ...string) error { return nil }
+
+func Map[K comparable, V any](m map[K]V) []K { return nil }
+`
+
+func TestAPISurface(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module example.com/m\n\ngo 1.22\n",
+		"m.go":            apiSourceV1,
+		"internal/x/x.go": "package x\n\nfunc X() {}\n",
+		"cmd/m/main.go":   "package main\n\nfunc Run() {}\n\nfunc main() {}\n",
+	}
+	for name, src := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddAnalysisHandler()
+
+	extract := func(query string) APISurface {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/api?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var surface APISurface
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&surface))
+		return surface
+	}
+
+	// Commands and internal packages are left out
+	v1 := extract("")
+	require.Len(t, v1.Packages, 1)
+	assert.Equal(t, "example.com/m", v1.Packages[0].Path)
+	assert.Equal(t, []APISymbol{
+		{Name: "Client", Kind: APIType, Signature: "type Client struct"},
+		{Name: "Client.Addr", Kind: APIField, Signature: "Client.Addr string"},
+		{Name: "Client.Do", Kind: APIMethod, Signature: "func (*Client) Do(context.Context, ...string) error"},
+		{Name: "Default", Kind: APIVar, Signature: "var Default *Client"},
+		{Name: "Map", Kind: APIFunc, Signature: "func Map[K comparable, V any](map[K]V) []K"},
+		{Name: "New", Kind: APIFunc, Signature: "func New() *Client"},
+		{Name: "Store", Kind: APIType, Signature: "type Store interface"},
+		{Name: "Store.Get", Kind: APIMethod, Signature: "func (Store) Get(context.Context, string) (string, error)"},
+		{Name: "Version", Kind: APIConst, Signature: "const Version untyped string"},
+	}, v1.Packages[0].Symbols)
+
+	internal := extract("internal=true")
+	require.Len(t, internal.Packages, 2)
+	assert.Equal(t, "example.com/m/internal/x", internal.Packages[1].Path)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "m.go"), []byte(apiSourceV2), 0644))
+	v2 := extract("pattern=.")
+
+	body, err := json.Marshal(APIDiffRequest{Old: v1, New: v2})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/analyze/api/diff", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var diff APIDiff
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&diff))
+	assert.True(t, diff.Breaking)
+	assert.Equal(t, []APIChange{
+		{Package: "example.com/m", Symbol: "Client.Timeout", Kind: APIField, Change: APIAdded, New: "Client.Timeout int"},
+		{Package: "example.com/m", Symbol: "New", Kind: APIFunc, Change: APIChanged, Breaking: true, Old: "func New() *Client", New: "func New(string) *Client"},
+		{Package: "example.com/m", Symbol: "Open", Kind: APIFunc, Change: APIAdded, New: "func Open() *Client"},
+		{Package: "example.com/m", Symbol: "Store.Put", Kind: APIMethod, Change: APIAdded, Breaking: true, New: "func (Store) Put(context.Context, string, string) error"},
+		{Package: "example.com/m", Symbol: "Version", Kind: APIConst, Change: APIRemoved, Breaking: true, Old: "const Version untyped string"},
+	}, diff.Changes)
+
+	// Adding packages and symbols does not break clients
+	diff = *DiffAPI(&APISurface{}, &v1)
+	assert.False(t, diff.Breaking)
+	assert.Equal(t, []APIChange{{Package: "example.com/m", Change: APIAdded}}, diff.Changes)
+	diff = *DiffAPI(&v1, &APISurface{})
+	assert.True(t, diff.Breaking)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/api?pattern=../...", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,149 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+)
+
+// RegisterArtifactsRequest registers files in the project as artifacts
+type RegisterArtifactsRequest struct {
+	Paths   []string `json:"paths"`              // Relative to the project root
+	Kind    string   `json:"kind,omitempty"`     // Detected from each file when empty
+	TaskID  string   `json:"task_id,omitempty"`  // Task that produced the files
+	TaskRun int      `json:"task_run,omitempty"` // Defaults to the task's latest run
+}
+
+// PruneArtifactsResponse lists the artifacts removed by the retention policy
+type PruneArtifactsResponse struct {
+	Removed []*ide.Artifact `json:"removed"`
+}
+
+func handleRegisterArtifacts(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req RegisterArtifactsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if len(req.Paths) == 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("paths are required"))
+			return
+		}
+
+		source := ide.ArtifactSource{TaskID: req.TaskID, TaskRun: req.TaskRun}
+		if req.TaskID != "" && req.TaskRun == 0 {
+			run, err := ideServer.taskManager.CurrentRun(req.TaskID)
+			if err != nil {
+				writeError(w, http.StatusNotFound, err)
+				return
+			}
+			source.TaskRun = run
+		}
+
+		artifacts, err := ideServer.artifactStore.Register(source, req.Kind, req.Paths...)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		LoggerFromContext(r.Context()).Info("artifacts registered",
+			"task_id", source.TaskID, "run", source.TaskRun, "count", len(artifacts))
+
+		writeJSON(w, http.StatusCreated, artifacts)
+	}
+}
+
+func handleListArtifacts(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		artifacts, err := ideServer.artifactStore.List(r.URL.Query().Get("task_id"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if kind := r.URL.Query().Get("kind"); kind != "" {
+			filtered := artifacts[:0]
+			for _, a := range artifacts {
+				if a.Kind == kind {
+					filtered = append(filtered, a)
+				}
+			}
+			artifacts = filtered
+		}
+
+		writeJSON(w, http.StatusOK, artifacts)
+	}
+}
+
+func handleGetArtifact(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		artifact, err := ideServer.artifactStore.Get(mux.Vars(r)["id"])
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, artifact)
+	}
+}
+
+// handleDownloadArtifact serves the artifact content. The digest doubles as
+// an ETag, so unchanged artifacts are not downloaded twice.
+func handleDownloadArtifact(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		artifact, err := ideServer.artifactStore.Get(mux.Vars(r)["id"])
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+
+		f, err := ideServer.artifactStore.Open(artifact)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to open artifact: %v", err))
+			return
+		}
+		defer f.Close()
+
+		name := path.Base(artifact.Name)
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" || artifact.Kind == ide.ArtifactKindBinary {
+			contentType = "application/octet-stream"
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", contentType)
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		h.Set("ETag", `"`+strings.TrimPrefix(artifact.Digest, "sha256:")+`"`)
+		http.ServeContent(w, r, name, artifact.CreatedAt, f)
+	}
+}
+
+func handleDeleteArtifact(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := ideServer.artifactStore.Delete(mux.Vars(r)["id"]); err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func handlePruneArtifacts(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		removed, err := ideServer.artifactStore.Prune()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if removed == nil {
+			removed = []*ide.Artifact{}
+		}
+		writeJSON(w, http.StatusOK, PruneArtifactsResponse{Removed: removed})
+	}
+}
@@ -7,14 +7,16 @@ import (
 	"go/types"
 	_ "golang.org/x/tools/go/ast/astutil"
 	"strings"
+
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
 )
 
 // ASTAnalyzer provides code analysis capabilities
 type ASTAnalyzer struct {
-	fileSet    *token.FileSet
-	typeInfo   *types.Info
-	packages   map[string]*ast.Package
-	complexity map[string]int
+	fileSet  *token.FileSet
+	typeInfo *types.Info
+	packages map[string]*ast.Package
+	checked  map[*ast.File]bool // Files whose identifiers typeInfo resolves
 }
 
 // AnalysisResult contains the analysis output
@@ -26,16 +28,25 @@ type AnalysisResult struct {
 	References  []ReferenceInfo `json:"references"`
 	Diagnostics []Diagnostic    `json:"diagnostics"`
 	Metrics     CodeMetrics     `json:"metrics"`
+	Coverage    *Coverage       `json:"coverage,omitempty"` // Set when an uploaded coverage profile covers the file
 }
 
 type CodeMetrics struct {
-	LinesOfCode     int `json:"lines_of_code"`
-	CommentLines    int `json:"comment_lines"`
-	FunctionCount   int `json:"function_count"`
-	ComplexityScore int `json:"complexity_score"`
-	InterfaceCount  int `json:"interface_count"`
-	StructCount     int `json:"struct_count"`
-	TestCount       int `json:"test_count"`
+	LinesOfCode         int `json:"lines_of_code"`
+	CommentLines        int `json:"comment_lines"`
+	FunctionCount       int `json:"function_count"`
+	ComplexityScore     int `json:"complexity_score"`     // Sum of the cyclomatic complexity of functions
+	CognitiveComplexity int `json:"cognitive_complexity"` // Sum of the cognitive complexity of functions
+	InterfaceCount      int `json:"interface_count"`
+	StructCount         int `json:"struct_count"`
+	TestCount           int `json:"test_count"`
+	ExportedSymbols     int `json:"exported_symbols"`
+	DocumentedSymbols   int `json:"documented_symbols"` // Exported symbols with a doc comment
+
+	DocCoverage          float64         `json:"doc_coverage"` // Percentage of exported symbols documented
+	Halstead             HalsteadMetrics `json:"halstead"`
+	MaintainabilityIndex float64         `json:"maintainability_index"` // 0 to 100, higher is better
+	Churn                *ide.FileChurn  `json:"churn,omitempty"`       // Changes of the file in the git history of the workspace
 }
 
 type Diagnostic struct {
@@ -56,9 +67,44 @@ func NewASTAnalyzer(fset *token.FileSet) *ASTAnalyzer {
 			Uses:      make(map[*ast.Ident]types.Object),
 			Implicits: make(map[ast.Node]types.Object),
 		},
-		packages:   make(map[string]*ast.Package),
-		complexity: make(map[string]int),
+		packages: make(map[string]*ast.Package),
+		checked:  make(map[*ast.File]bool),
+	}
+}
+
+// newLoadedAnalyzer creates an analyzer for the files of a package loaded
+// with its dependencies, resolving identifiers with the type information of
+// the loader, selectors of imported packages included
+func newLoadedAnalyzer(fset *token.FileSet, info *types.Info, files []*ast.File) *ASTAnalyzer {
+	a := NewASTAnalyzer(fset)
+	a.typeInfo = info
+	for _, file := range files {
+		a.checked[file] = true
 	}
+	return a
+}
+
+// check type-checks files of a package together, so identifiers resolve to
+// the objects they denote across the files. Imports are not loaded, which
+// keeps analysis fast: imported packages are empty, and selectors of them
+// stay unresolved.
+func (a *ASTAnalyzer) check(pkgPath string, files []*ast.File) {
+	conf := types.Config{
+		Importer: unloadedImporter{},
+		Error:    func(error) {}, // Unresolved imports are expected
+	}
+	conf.Check(pkgPath, a.fileSet, files, a.typeInfo)
+	for _, file := range files {
+		a.checked[file] = true
+	}
+}
+
+// unloadedImporter fails every import; the type checker then declares an
+// empty package for it
+type unloadedImporter struct{}
+
+func (unloadedImporter) Import(path string) (*types.Package, error) {
+	return nil, fmt.Errorf("package %s is not loaded", path)
 }
 
 // AnalyzeFile performs deep analysis of a Go source file
@@ -66,6 +112,9 @@ func (a *ASTAnalyzer) AnalyzeFile(file *ast.File) (*AnalysisResult, error) {
 	result := &AnalysisResult{
 		Metrics: CodeMetrics{},
 	}
+	if !a.checked[file] {
+		a.check(file.Name.Name, []*ast.File{file})
+	}
 
 	// Analyze imports
 	result.Imports = a.analyzeImports(file)
@@ -80,7 +129,7 @@ func (a *ASTAnalyzer) AnalyzeFile(file *ast.File) (*AnalysisResult, error) {
 	result.Variables = a.analyzeVariables(file)
 
 	// Collect references
-	result.References = a.analyzeReferences(file)
+	result.References = a.analyzeReferences([]*ast.File{file})
 
 	// Calculate metrics
 	result.Metrics = a.calculateMetrics(file)
@@ -122,8 +171,12 @@ func (a *ASTAnalyzer) analyzeFunctions(file *ast.File) []FunctionInfo {
 				doc = fn.Doc.Text()
 			}
 
+			var receiver string
+			if fn.Recv != nil && len(fn.Recv.List) > 0 {
+				receiver = a.getTypeString(fn.Recv.List[0].Type)
+			}
 			complexity := a.calculateFunctionComplexity(fn)
-			a.complexity[fn.Name.Name] = complexity
+			metrics := halstead(fn)
 
 			functions = append(functions, FunctionInfo{
 				Name:      fn.Name.Name,
@@ -136,7 +189,12 @@ func (a *ASTAnalyzer) analyzeFunctions(file *ast.File) []FunctionInfo {
 						End:   Position{Line: end.Line - 1, Character: end.Column - 1},
 					},
 				},
-				Complexity: complexity,
+				Complexity:           complexity,
+				CognitiveComplexity:  cognitiveComplexity(fn),
+				Halstead:             metrics,
+				MaintainabilityIndex: maintainabilityIndex(metrics.Volume, complexity, end.Line-pos.Line+1),
+				IsMethod:             receiver != "",
+				Receiver:             receiver,
 			})
 		}
 		return true
@@ -148,6 +206,21 @@ func (a *ASTAnalyzer) analyzeFunctions(file *ast.File) []FunctionInfo {
 func (a *ASTAnalyzer) analyzeTypes(file *ast.File) []TypeInfo {
 	var types []TypeInfo
 
+	// Doc comments usually sit on the enclosing declaration
+	docs := make(map[*ast.TypeSpec]string)
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.TYPE {
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if ts.Doc != nil {
+					docs[ts] = ts.Doc.Text()
+				} else if len(gen.Specs) == 1 && gen.Doc != nil {
+					docs[ts] = gen.Doc.Text()
+				}
+			}
+		}
+	}
+
 	ast.Inspect(file, func(n ast.Node) bool {
 		switch node := n.(type) {
 		case *ast.TypeSpec:
@@ -156,6 +229,7 @@ func (a *ASTAnalyzer) analyzeTypes(file *ast.File) []TypeInfo {
 
 			typeInfo := TypeInfo{
 				Name: node.Name.Name,
+				Doc:  docs[node],
 				Location: Location{
 					URI: file.Name.Name,
 					Range: Range{
@@ -215,61 +289,90 @@ func (a *ASTAnalyzer) analyzeVariables(file *ast.File) []VariableInfo {
 	return variables
 }
 
-func (a *ASTAnalyzer) analyzeReferences(file *ast.File) []ReferenceInfo {
-	var references []ReferenceInfo
-	refMap := make(map[string]*ReferenceInfo)
+// analyzeReferences returns the objects declared in files with their uses
+// in files, matched by the object they denote rather than by name, ordered
+// by declaration
+func (a *ASTAnalyzer) analyzeReferences(files []*ast.File) []ReferenceInfo {
+	location := func(ident *ast.Ident) Location {
+		pos := a.fileSet.Position(ident.Pos())
+		return Location{
+			URI: pos.Filename,
+			Range: Range{
+				Start: Position{Line: pos.Line - 1, Character: pos.Column - 1},
+				End:   Position{Line: pos.Line - 1, Character: pos.Column - 1 + len(ident.Name)},
+			},
+		}
+	}
 
 	// First pass: collect all definitions
-	ast.Inspect(file, func(n ast.Node) bool {
-		switch node := n.(type) {
-		case *ast.Ident:
-			if obj := a.typeInfo.Defs[node]; obj != nil {
-				pos := a.fileSet.Position(node.Pos())
-				refMap[node.Name] = &ReferenceInfo{
-					Name: node.Name,
-					Kind: a.getIdentKind(obj),
-					Location: Location{
-						URI: file.Name.Name,
-						Range: Range{
-							Start: Position{Line: pos.Line - 1, Character: pos.Column - 1},
-							End:   Position{Line: pos.Line - 1, Character: pos.Column - 1 + len(node.Name)},
-						},
-					},
-					UsedAt: make([]Location, 0),
+	refs := make(map[types.Object]*ReferenceInfo)
+	var order []types.Object
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok || ident.Name == "_" {
+				return true
+			}
+			if obj := a.typeInfo.Defs[ident]; obj != nil {
+				refs[obj] = &ReferenceInfo{
+					Name:     ident.Name,
+					Kind:     a.getIdentKind(obj),
+					Location: location(ident),
+					UsedAt:   make([]Location, 0),
+					Scope:    objectScope(obj),
 				}
+				order = append(order, obj)
 			}
-		}
-		return true
-	})
+			return true
+		})
+	}
 
 	// Second pass: collect all uses
-	ast.Inspect(file, func(n ast.Node) bool {
-		switch node := n.(type) {
-		case *ast.Ident:
-			if obj := a.typeInfo.Uses[node]; obj != nil {
-				if ref, ok := refMap[obj.Name()]; ok {
-					pos := a.fileSet.Position(node.Pos())
-					ref.UsedAt = append(ref.UsedAt, Location{
-						URI: file.Name.Name,
-						Range: Range{
-							Start: Position{Line: pos.Line - 1, Character: pos.Column - 1},
-							End:   Position{Line: pos.Line - 1, Character: pos.Column - 1 + len(node.Name)},
-						},
-					})
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok {
+				if ref, ok := refs[originObject(a.typeInfo.Uses[ident])]; ok {
+					ref.UsedAt = append(ref.UsedAt, location(ident))
 				}
 			}
-		}
-		return true
-	})
-
-	// Convert map to slice
-	for _, ref := range refMap {
-		references = append(references, *ref)
+			return true
+		})
 	}
 
+	references := make([]ReferenceInfo, len(order))
+	for i, obj := range order {
+		references[i] = *refs[obj]
+	}
 	return references
 }
 
+// objectScope describes where an object is declared: package for package
+// level objects, member for fields and methods, local otherwise
+func objectScope(obj types.Object) string {
+	if _, ok := obj.(*types.Label); ok {
+		return "local"
+	}
+	switch {
+	case obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope():
+		return "package"
+	case obj.Parent() == nil:
+		return "member"
+	}
+	return "local"
+}
+
+// originObject returns the generic object of an instantiated function,
+// method or field
+func originObject(obj types.Object) types.Object {
+	switch obj := obj.(type) {
+	case *types.Func:
+		return obj.Origin()
+	case *types.Var:
+		return obj.Origin()
+	}
+	return obj
+}
+
 func (a *ASTAnalyzer) calculateMetrics(file *ast.File) CodeMetrics {
 	metrics := CodeMetrics{}
 
@@ -284,6 +387,8 @@ func (a *ASTAnalyzer) calculateMetrics(file *ast.File) CodeMetrics {
 			if strings.HasPrefix(node.Name.Name, "Test") {
 				metrics.TestCount++
 			}
+			metrics.ComplexityScore += a.calculateFunctionComplexity(node)
+			metrics.CognitiveComplexity += cognitiveComplexity(node)
 		case *ast.TypeSpec:
 			switch node.Type.(type) {
 			case *ast.StructType:
@@ -296,11 +401,16 @@ func (a *ASTAnalyzer) calculateMetrics(file *ast.File) CodeMetrics {
 		}
 		return true
 	})
-
-	// Calculate total complexity
-	for _, complexity := range a.complexity {
-		metrics.ComplexityScore += complexity
+	for _, symbol := range a.exportedSymbols(file) {
+		metrics.ExportedSymbols++
+		if symbol.Documented {
+			metrics.DocumentedSymbols++
+		}
 	}
+	metrics.DocCoverage = docCoverage(metrics.DocumentedSymbols, metrics.ExportedSymbols)
+
+	metrics.Halstead = halstead(file)
+	metrics.MaintainabilityIndex = maintainabilityIndex(metrics.Halstead.Volume, metrics.ComplexityScore, metrics.LinesOfCode)
 
 	return metrics
 }
@@ -317,7 +427,7 @@ func (a *ASTAnalyzer) runDiagnostics(file *ast.File) []Diagnostic {
 				Severity: "warning",
 				Message:  fmt.Sprintf("Unused import: %s", path),
 				Location: Location{
-					URI: file.Name.Name,
+					URI: pos.Filename,
 					Range: Range{
 						Start: Position{Line: pos.Line - 1, Character: pos.Column - 1},
 						End:   Position{Line: pos.Line - 1, Character: pos.Column - 1 + len(path)},
@@ -330,28 +440,39 @@ func (a *ASTAnalyzer) runDiagnostics(file *ast.File) []Diagnostic {
 	}
 
 	// Check for exported symbols without documentation
-	ast.Inspect(file, func(n ast.Node) bool {
-		switch node := n.(type) {
-		case *ast.FuncDecl:
-			if ast.IsExported(node.Name.Name) && node.Doc == nil {
-				pos := a.fileSet.Position(node.Name.Pos())
-				diagnostics = append(diagnostics, Diagnostic{
-					Severity: "info",
-					Message:  fmt.Sprintf("Exported function %s lacks documentation", node.Name.Name),
-					Location: Location{
-						URI: file.Name.Name,
-						Range: Range{
-							Start: Position{Line: pos.Line - 1, Character: pos.Column - 1},
-							End:   Position{Line: pos.Line - 1, Character: pos.Column - 1 + len(node.Name.Name)},
-						},
-					},
-					Code:   "missing-doc",
-					Source: "go-analyzer",
-				})
-			}
+	for _, symbol := range a.exportedSymbols(file) {
+		if symbol.Documented {
+			continue
 		}
-		return true
-	})
+		kind := symbol.Kind
+		switch kind {
+		case APIFunc:
+			kind = "function"
+		case APIConst:
+			kind = "constant"
+		case APIVar:
+			kind = "variable"
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: "info",
+			Message:  fmt.Sprintf("Exported %s %s lacks documentation", kind, symbol.Name),
+			Location: symbol.Location,
+			Code:     "missing-doc",
+			Source:   "go-analyzer",
+		})
+	}
+
+	// Check for unused parameters and assignments
+	diagnostics = append(diagnostics, a.unusedDiagnostics(file)...)
+
+	// Check for concurrency mistakes
+	diagnostics = append(diagnostics, a.concurrencyDiagnostics(file)...)
+
+	// Check for dropped errors
+	diagnostics = append(diagnostics, a.errcheckDiagnostics(file)...)
+
+	// Check for security issues
+	diagnostics = append(diagnostics, a.securityDiagnostics(file)...)
 
 	return diagnostics
 }
@@ -359,6 +480,11 @@ func (a *ASTAnalyzer) runDiagnostics(file *ast.File) []Diagnostic {
 // Helper functions
 
 func (a *ASTAnalyzer) isImportUsed(file *ast.File, importPath string) bool {
+	localName := importLocalName(file, importPath)
+	if localName == "_" || localName == "." {
+		return true
+	}
+
 	used := false
 	ast.Inspect(file, func(n ast.Node) bool {
 		if sel, ok := n.(*ast.SelectorExpr); ok {
@@ -370,6 +496,10 @@ func (a *ASTAnalyzer) isImportUsed(file *ast.File, importPath string) bool {
 							return false
 						}
 					}
+				} else if ident.Obj == nil && ident.Name == localName {
+					// No type information: fall back to matching the package name
+					used = true
+					return false
 				}
 			}
 		}
@@ -378,14 +508,53 @@ func (a *ASTAnalyzer) isImportUsed(file *ast.File, importPath string) bool {
 	return used
 }
 
+// importLocalName returns the name an import is referenced by in the file,
+// guessing the package name from the import path when there is no alias
+func importLocalName(file *ast.File, importPath string) string {
+	for _, imp := range file.Imports {
+		if strings.Trim(imp.Path.Value, "\"") != importPath {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name
+		}
+	}
+
+	parts := strings.Split(importPath, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = parts[len(parts)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// calculateFunctionComplexity returns the cyclomatic complexity of a
+// function: 1 plus the number of decisions in it, including those of the
+// function literals it contains. Each condition joined by && or || is a
+// decision; default cases are not.
 func (a *ASTAnalyzer) calculateFunctionComplexity(fn *ast.FuncDecl) int {
 	complexity := 1 // Base complexity
 
 	ast.Inspect(fn, func(n ast.Node) bool {
-		switch n.(type) {
-		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.CaseClause,
-			*ast.CommClause, *ast.BinaryExpr:
+		switch n := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
 			complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
 		}
 		return true
 	})
@@ -393,6 +562,129 @@ func (a *ASTAnalyzer) calculateFunctionComplexity(fn *ast.FuncDecl) int {
 	return complexity
 }
 
+// cognitiveComplexity returns the cognitive complexity of a function, which
+// measures how hard its control flow is to follow. Every if, else, switch,
+// select, loop, goto and labeled break or continue costs 1, and so does
+// every sequence of like boolean operators and direct recursion. If,
+// switch, select and loops cost 1 more for each level they are nested in;
+// function literals nest what they contain.
+func cognitiveComplexity(fn *ast.FuncDecl) int {
+	if fn.Body == nil {
+		return 0
+	}
+	v := &cognitiveVisitor{elseIfs: make(map[*ast.IfStmt]bool), counted: make(map[*ast.BinaryExpr]bool)}
+	if fn.Recv == nil {
+		v.name = fn.Name.Name
+	}
+	ast.Walk(v, fn.Body)
+	return v.complexity
+}
+
+type cognitiveVisitor struct {
+	name       string // Of the function, to find recursion; empty for methods
+	complexity int
+	nesting    int
+	elseIfs    map[*ast.IfStmt]bool     // Cost no nesting
+	counted    map[*ast.BinaryExpr]bool // Operators of sequences already counted
+}
+
+// walk walks the nodes at the current level, skipping missing ones
+func (v *cognitiveVisitor) walk(nodes ...ast.Node) {
+	for _, n := range nodes {
+		if n != nil {
+			ast.Walk(v, n)
+		}
+	}
+}
+
+// nested walks a block one level deeper
+func (v *cognitiveVisitor) nested(block *ast.BlockStmt) {
+	v.nesting++
+	ast.Walk(v, block)
+	v.nesting--
+}
+
+func (v *cognitiveVisitor) Visit(n ast.Node) ast.Visitor {
+	switch n := n.(type) {
+	case *ast.IfStmt:
+		if v.elseIfs[n] {
+			v.complexity++
+		} else {
+			v.complexity += 1 + v.nesting
+		}
+		v.walk(n.Init, n.Cond)
+		v.nested(n.Body)
+		switch els := n.Else.(type) {
+		case *ast.IfStmt:
+			v.elseIfs[els] = true
+			v.walk(els)
+		case *ast.BlockStmt:
+			v.complexity++
+			v.nested(els)
+		}
+		return nil
+	case *ast.SwitchStmt:
+		v.complexity += 1 + v.nesting
+		v.walk(n.Init, n.Tag)
+		v.nested(n.Body)
+		return nil
+	case *ast.TypeSwitchStmt:
+		v.complexity += 1 + v.nesting
+		v.walk(n.Init, n.Assign)
+		v.nested(n.Body)
+		return nil
+	case *ast.SelectStmt:
+		v.complexity += 1 + v.nesting
+		v.nested(n.Body)
+		return nil
+	case *ast.ForStmt:
+		v.complexity += 1 + v.nesting
+		v.walk(n.Init, n.Cond, n.Post)
+		v.nested(n.Body)
+		return nil
+	case *ast.RangeStmt:
+		v.complexity += 1 + v.nesting
+		v.walk(n.X)
+		v.nested(n.Body)
+		return nil
+	case *ast.FuncLit:
+		v.nested(n.Body)
+		return nil
+	case *ast.BranchStmt:
+		if n.Tok == token.GOTO || (n.Label != nil && n.Tok != token.FALLTHROUGH) {
+			v.complexity++
+		}
+	case *ast.BinaryExpr:
+		if (n.Op == token.LAND || n.Op == token.LOR) && !v.counted[n] {
+			var last token.Token
+			for _, op := range v.logicalOps(n) {
+				if op != last {
+					v.complexity++
+				}
+				last = op
+			}
+		}
+	case *ast.CallExpr:
+		if ident, ok := ast.Unparen(n.Fun).(*ast.Ident); ok && v.name != "" && ident.Name == v.name {
+			v.complexity++
+		}
+	}
+	return v
+}
+
+// logicalOps returns the && and || operators of a chain of conditions in
+// source order, marking them counted
+func (v *cognitiveVisitor) logicalOps(expr ast.Expr) []token.Token {
+	bin, ok := ast.Unparen(expr).(*ast.BinaryExpr)
+	if !ok || (bin.Op != token.LAND && bin.Op != token.LOR) {
+		return nil
+	}
+	v.counted[bin] = true
+	ops := v.logicalOps(bin.X)
+	ops = append(ops, bin.Op)
+	return append(ops, v.logicalOps(bin.Y)...)
+}
+
 func (a *ASTAnalyzer) analyzeStructFields(structType *ast.StructType) []FieldInfo {
 	var fields []FieldInfo
 
@@ -446,6 +738,8 @@ func (a *ASTAnalyzer) getTypeString(expr ast.Expr) string {
 		return "*" + a.getTypeString(t.X)
 	case *ast.ArrayType:
 		return "[]" + a.getTypeString(t.Elt)
+	case *ast.Ellipsis:
+		return "..." + a.getTypeString(t.Elt)
 	case *ast.MapType:
 		return fmt.Sprintf("map[%s]%s", a.getTypeString(t.Key), a.getTypeString(t.Value))
 	case *ast.InterfaceType:
@@ -565,7 +859,15 @@ func (a *ASTAnalyzer) AnalyzePackage(pkgPath string, files []*ast.File) (*Analys
 		Variables: make([]VariableInfo, 0),
 		Metrics:   CodeMetrics{},
 	}
+	// Files of packages loaded with their dependencies are checked already
+	for _, file := range files {
+		if !a.checked[file] {
+			a.check(pkgPath, files)
+			break
+		}
+	}
 
+	halsteadCounts := newHalsteadCounter()
 	for _, file := range files {
 		fileResult, err := a.AnalyzeFile(file)
 		if err != nil {
@@ -583,11 +885,24 @@ func (a *ASTAnalyzer) AnalyzePackage(pkgPath string, files []*ast.File) (*Analys
 		result.Metrics.CommentLines += fileResult.Metrics.CommentLines
 		result.Metrics.FunctionCount += fileResult.Metrics.FunctionCount
 		result.Metrics.ComplexityScore += fileResult.Metrics.ComplexityScore
+		result.Metrics.CognitiveComplexity += fileResult.Metrics.CognitiveComplexity
 		result.Metrics.InterfaceCount += fileResult.Metrics.InterfaceCount
 		result.Metrics.StructCount += fileResult.Metrics.StructCount
 		result.Metrics.TestCount += fileResult.Metrics.TestCount
+		result.Metrics.ExportedSymbols += fileResult.Metrics.ExportedSymbols
+		result.Metrics.DocumentedSymbols += fileResult.Metrics.DocumentedSymbols
+		halsteadCounts.add(file)
 	}
 
+	// Identifiers are used across the files of the package
+	result.References = a.analyzeReferences(files)
+	result.Diagnostics = append(result.Diagnostics, a.unusedFieldDiagnostics(files)...)
+
+	// Operators and operands are distinct across the package
+	result.Metrics.DocCoverage = docCoverage(result.Metrics.DocumentedSymbols, result.Metrics.ExportedSymbols)
+	result.Metrics.Halstead = halsteadCounts.metrics()
+	result.Metrics.MaintainabilityIndex = maintainabilityIndex(result.Metrics.Halstead.Volume, result.Metrics.ComplexityScore, result.Metrics.LinesOfCode)
+
 	return result, nil
 }
 
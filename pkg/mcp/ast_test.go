@@ -0,0 +1,114 @@
+package mcp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionComplexity(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		cyclomatic int
+		cognitive  int
+	}{
+		{"straight line", "func f(a, b int) int { return a + b*2 - 1 }", 1, 0},
+		{"arithmetic is not a decision", "func f(a, b int) bool { return a+b > a-b }", 1, 0},
+		{"if else", "func f(a int) int { if a > 0 { return 1 } else if a < 0 { return -1 } else { return 0 } }", 3, 3},
+		{"nesting", "func f(xs []int) { for _, x := range xs { if x > 0 { for x > 0 { x-- } } } }", 4, 6},
+		{"boolean sequences", "func f(a, b, c, d bool) bool { return a && b && c || d }", 4, 2},
+		{"parenthesized sequences", "func f(a, b, c bool) bool { return a && (b || c) }", 3, 2},
+		{"switch with default", "func f(a int) int { switch a { case 1: return 1; case 2, 3: return 2; default: return 0 } }", 3, 1},
+		{"select", "func f(c chan int) { for { select { case <-c: return; default: } } }", 3, 3},
+		{"function literal", "func f(xs []int) { go func() { if len(xs) > 0 { return } }() }", 2, 2},
+		{"labeled branch", "func f(xs [][]int) { outer: for _, x := range xs { for range x { continue outer } } }", 3, 4},
+		{"recursion", "func f(n int) int { if n < 2 { return n }; return f(n-1) + f(n-2) }", 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "f.go", "package p\n\n"+tt.source+"\n", 0)
+			require.NoError(t, err)
+			fn := file.Decls[0].(*ast.FuncDecl)
+
+			assert.Equal(t, tt.cyclomatic, NewASTAnalyzer(fset).calculateFunctionComplexity(fn), "cyclomatic")
+			assert.Equal(t, tt.cognitive, cognitiveComplexity(fn), "cognitive")
+		})
+	}
+}
+
+func TestCalculateMetricsComplexity(t *testing.T) {
+	fset := token.NewFileSet()
+	analyzer := NewASTAnalyzer(fset)
+	analyze := func(src string) *AnalysisResult {
+		t.Helper()
+		file, err := parser.ParseFile(fset, "p.go", src, 0)
+		require.NoError(t, err)
+		result, err := analyzer.AnalyzeFile(file)
+		require.NoError(t, err)
+		return result
+	}
+
+	// Methods of the same name are not mixed up
+	result := analyze("package p\n\ntype A struct{}\n\nfunc (A) Run(a bool) { if a { return } }\n\ntype B struct{}\n\nfunc (B) Run() {}\n")
+	assert.Equal(t, 3, result.Metrics.ComplexityScore)
+	assert.Equal(t, 1, result.Metrics.CognitiveComplexity)
+	require.Len(t, result.Functions, 2)
+	assert.Equal(t, 2, result.Functions[0].Complexity)
+	assert.Equal(t, 1, result.Functions[0].CognitiveComplexity)
+
+	// Nor are functions of files analyzed before
+	result = analyze("package p\n\nfunc Run(a, b bool) bool { return a || b }\n")
+	assert.Equal(t, 2, result.Metrics.ComplexityScore)
+	assert.Equal(t, 1, result.Metrics.CognitiveComplexity)
+}
+
+func TestAnalyzeReferences(t *testing.T) {
+	fset := token.NewFileSet()
+	a, err := parser.ParseFile(fset, "a.go", "package p\n\nvar count int\n\nfunc f() {\n\tcount := 1\n\tcount++\n}\n", 0)
+	require.NoError(t, err)
+	b, err := parser.ParseFile(fset, "b.go", "package p\n\ntype T struct{ count int }\n\nfunc g(t T) int {\n\tcount = t.count\n\treturn count\n}\n", 0)
+	require.NoError(t, err)
+
+	references := func(refs []ReferenceInfo) map[string]ReferenceInfo {
+		byLocation := make(map[string]ReferenceInfo)
+		for _, ref := range refs {
+			at := ref.Location.Range.Start
+			byLocation[fmt.Sprintf("%s:%d:%d", ref.Location.URI, at.Line+1, at.Character+1)] = ref
+		}
+		return byLocation
+	}
+	usedAt := func(ref ReferenceInfo) []string {
+		var locations []string
+		for _, loc := range ref.UsedAt {
+			locations = append(locations, fmt.Sprintf("%s:%d:%d", loc.URI, loc.Range.Start.Line+1, loc.Range.Start.Character+1))
+		}
+		return locations
+	}
+
+	// Variables of the same name are told apart
+	result, err := NewASTAnalyzer(fset).AnalyzeFile(a)
+	require.NoError(t, err)
+	refs := references(result.References)
+	require.Contains(t, refs, "a.go:3:5")
+	assert.Equal(t, "package", refs["a.go:3:5"].Scope)
+	assert.Empty(t, refs["a.go:3:5"].UsedAt)
+	require.Contains(t, refs, "a.go:6:2")
+	assert.Equal(t, "local", refs["a.go:6:2"].Scope)
+	assert.Equal(t, []string{"a.go:7:2"}, usedAt(refs["a.go:6:2"]))
+
+	// Declarations are used across the files of a package
+	pkg, err := NewASTAnalyzer(fset).AnalyzePackage("p", []*ast.File{a, b})
+	require.NoError(t, err)
+	refs = references(pkg.References)
+	assert.Equal(t, []string{"b.go:6:2", "b.go:7:9"}, usedAt(refs["a.go:3:5"]))
+	require.Contains(t, refs, "b.go:3:16")
+	assert.Equal(t, "member", refs["b.go:3:16"].Scope)
+	assert.Equal(t, []string{"b.go:6:12"}, usedAt(refs["b.go:3:16"]))
+}
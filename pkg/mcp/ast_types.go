@@ -9,15 +9,19 @@ type ImportInfo struct {
 
 // FunctionInfo represents information about a function declaration
 type FunctionInfo struct {
-	Name       string          `json:"name"`       // Function name
-	Signature  string          `json:"signature"`  // Function signature
-	Doc        string          `json:"doc"`        // Documentation comments
-	Location   Location        `json:"location"`   // Position in source
-	Complexity int             `json:"complexity"` // Cyclomatic complexity
-	IsMethod   bool            `json:"is_method"`  // Whether it's a method
-	Receiver   string          `json:"receiver"`   // Receiver type if method
-	Parameters []ParameterInfo `json:"parameters"` // Function parameters
-	Returns    []ParameterInfo `json:"returns"`    // Return values
+	Name                 string          `json:"name"`                  // Function name
+	Signature            string          `json:"signature"`             // Function signature
+	Doc                  string          `json:"doc"`                   // Documentation comments
+	Location             Location        `json:"location"`              // Position in source
+	Complexity           int             `json:"complexity"`            // Cyclomatic complexity
+	CognitiveComplexity  int             `json:"cognitive_complexity"`  // Nesting-aware complexity
+	Halstead             HalsteadMetrics `json:"halstead"`              // Operator and operand metrics
+	MaintainabilityIndex float64         `json:"maintainability_index"` // 0 to 100, higher is better
+	Coverage             *Coverage       `json:"coverage,omitempty"`    // Statements run by tests, from an uploaded profile
+	IsMethod             bool            `json:"is_method"`             // Whether it's a method
+	Receiver             string          `json:"receiver"`              // Receiver type if method
+	Parameters           []ParameterInfo `json:"parameters"`            // Function parameters
+	Returns              []ParameterInfo `json:"returns"`               // Return values
 }
 
 // ParameterInfo represents a function parameter or return value
@@ -73,5 +77,5 @@ type ReferenceInfo struct {
 	Kind     string     `json:"kind"`     // Symbol kind (variable, function, etc.)
 	Location Location   `json:"location"` // Definition location
 	UsedAt   []Location `json:"used_at"`  // Usage locations
-	Scope    string     `json:"scope"`    // Reference scope
+	Scope    string     `json:"scope"`    // package, member (fields and methods) or local
 }
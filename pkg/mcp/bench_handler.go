@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+)
+
+// BenchRequest represents a request to run benchmarks
+type BenchRequest struct {
+	ide.BenchOptions
+	Baseline  string  `json:"baseline,omitempty"`  // Stored run key or git revision, defaults to the latest other run
+	Threshold float64 `json:"threshold,omitempty"` // Percent increase tolerated before flagging a regression
+	NoSave    bool    `json:"no_save,omitempty"`   // Do not store the run
+}
+
+// BenchResponse contains a benchmark run and its comparison with the baseline
+type BenchResponse struct {
+	Run         *ide.BenchRun         `json:"run"`
+	Baseline    string                `json:"baseline,omitempty"`
+	Comparisons []ide.BenchComparison `json:"comparisons,omitempty"`
+	Regressions int                   `json:"regressions"`
+}
+
+// defaultBenchThreshold is the tolerated slowdown, in percent, when none is given
+const defaultBenchThreshold = 5
+
+func handleRunBench(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req BenchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.Threshold == 0 {
+			req.Threshold = defaultBenchThreshold
+		}
+
+		logger := LoggerFromContext(r.Context())
+		run, err := ideServer.benchRunner.Run(r.Context(), req.BenchOptions)
+		if err != nil {
+			logger.Error("benchmark run failed", "error", err)
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		response := BenchResponse{Run: run}
+
+		var baseline *ide.BenchRun
+		if req.Baseline != "" {
+			baseline, err = ideServer.benchRunner.Load(req.Baseline)
+			if err != nil {
+				writeError(w, http.StatusNotFound, err)
+				return
+			}
+		} else {
+			baseline, err = ideServer.benchRunner.LatestBaseline(run)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+		}
+
+		if baseline != nil {
+			response.Baseline = baseline.Key
+			response.Comparisons = ide.CompareBenchRuns(baseline, run, req.Threshold)
+			for _, c := range response.Comparisons {
+				if c.Regression {
+					response.Regressions++
+				}
+			}
+		}
+
+		if !req.NoSave {
+			if err := ideServer.benchRunner.Save(run); err != nil {
+				writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to store benchmark run: %v", err))
+				return
+			}
+		}
+
+		logger.Info("benchmarks completed",
+			"run", run.Key, "benchmarks", len(run.Benchmarks),
+			"baseline", response.Baseline, "regressions", response.Regressions)
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+func handleListBenchRuns(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runs, err := ideServer.benchRunner.List()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, runs)
+	}
+}
+
+func handleGetBenchRun(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		run, err := ideServer.benchRunner.Load(mux.Vars(r)["ref"])
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, run)
+	}
+}
@@ -1,7 +1,9 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
@@ -10,16 +12,161 @@ import (
 	"github.com/ivikasavnish/go-mcp/pkg/browser"
 )
 
+// Errors returned by BrowserManager
+var (
+	ErrBrowserNotFound = errors.New("browser not found")
+	ErrBrowserExists   = errors.New("browser already exists")
+)
+
+// BrowserInstance is a browser controlled by BrowserManager. *browser.Browser
+// is the real implementation; tests substitute stubs through
+// WithBrowserLauncher.
+type BrowserInstance interface {
+	Start() error
+	Stop() error
+	Navigate(url string) (*browser.NavigationResult, error)
+	ExecuteSequence(seq *browser.AutomationSequence) error
+	Scrape(selectors map[string]string) (*browser.ScrapingResult, error)
+}
+
+// BrowserLauncher creates a browser instance, which is started separately
+type BrowserLauncher func(config *browser.BrowserConfig) BrowserInstance
+
 // BrowserManager manages browser instances
 type BrowserManager struct {
-	browsers map[string]*browser.Browser
+	browsers map[string]BrowserInstance
+	meters   map[string]func() // Charge the browser minutes of each instance
+	launch   BrowserLauncher
 	mu       sync.RWMutex
 }
 
 func NewBrowserManager() *BrowserManager {
 	return &BrowserManager{
-		browsers: make(map[string]*browser.Browser),
+		browsers: make(map[string]BrowserInstance),
+		meters:   make(map[string]func()),
+		launch: func(config *browser.BrowserConfig) BrowserInstance {
+			return browser.NewBrowser(config)
+		},
+	}
+}
+
+// remove forgets a stopped instance and charges the tenant that started it.
+// The caller holds bm.mu.
+func (bm *BrowserManager) remove(id string) {
+	if done, ok := bm.meters[id]; ok {
+		done()
+		delete(bm.meters, id)
+	}
+	delete(bm.browsers, id)
+}
+
+// Get returns the instance registered under id
+func (bm *BrowserManager) Get(id string) (BrowserInstance, error) {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+	b, ok := bm.browsers[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrBrowserNotFound, id)
+	}
+	return b, nil
+}
+
+// Close stops the instance registered under id and forgets it
+func (bm *BrowserManager) Close(id string) error {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	b, ok := bm.browsers[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrBrowserNotFound, id)
+	}
+	if err := b.Stop(); err != nil {
+		return err
+	}
+	bm.remove(id)
+	return nil
+}
+
+// navigate loads url in the instance registered under id
+func (bm *BrowserManager) navigate(ctx context.Context, id, url string) (*browser.NavigationResult, error) {
+	b, err := bm.Get(id)
+	if err != nil {
+		return nil, err
 	}
+	logger := LoggerFromContext(ctx).With("browser", id)
+	result, err := b.Navigate(url)
+	if err != nil {
+		logger.Error("browser navigation failed", "url", url, "error", err)
+		return nil, err
+	}
+	logger.Info("browser navigated", "url", url)
+	return result, nil
+}
+
+// scrape extracts the text of elements matching selectors from the page last
+// loaded by the instance registered under id
+func (bm *BrowserManager) scrape(ctx context.Context, id string, selectors map[string]string) (*browser.ScrapingResult, error) {
+	b, err := bm.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := b.Scrape(selectors)
+	if err != nil {
+		LoggerFromContext(ctx).Error("browser scrape failed", "browser", id, "error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// startBrowser launches an instance under id, charging its browser minutes
+// to the tenant of ctx until it is closed, and ties it to the lease of ctx
+func (s *Server) startBrowser(ctx context.Context, bm *BrowserManager, id string, config *browser.BrowserConfig) error {
+	if err := s.launchBrowser(ctx, bm, id, config); err != nil {
+		return err
+	}
+	return s.leaseResource(ctx, LeaseBrowser, id, func() error { return bm.Close(id) })
+}
+
+func (s *Server) launchBrowser(ctx context.Context, bm *BrowserManager, id string, config *browser.BrowserConfig) error {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	if _, exists := bm.browsers[id]; exists {
+		return fmt.Errorf("%w: %s", ErrBrowserExists, id)
+	}
+
+	done, err := s.meterMinutes(ctx, MetricBrowserMinutes)
+	if err != nil {
+		return err
+	}
+
+	logger := LoggerFromContext(ctx).With("browser", id)
+	b := bm.launch(config)
+	if err := b.Start(); err != nil {
+		logger.Error("browser start failed", "error", err)
+		if errors.Is(err, browser.ErrNoBrowser) {
+			return dependencyError(DependencyChromium)
+		}
+		return err
+	}
+
+	bm.browsers[id] = b
+	bm.meters[id] = done
+	logger.Info("browser started", "headless", config.Headless)
+	return nil
+}
+
+// CloseAll stops every managed browser instance
+func (bm *BrowserManager) CloseAll() error {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	var errs []error
+	for id, b := range bm.browsers {
+		if err := b.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("browser %s: %w", id, err))
+		}
+		bm.remove(id)
+	}
+	return errors.Join(errs...)
 }
 
 // Request/Response types
@@ -48,19 +195,124 @@ type AutomationRequest struct {
 // AddBrowserHandlers adds browser automation endpoints to the MCP server
 func (s *Server) AddBrowserHandlers() {
 	manager := NewBrowserManager()
+	if s.browserLauncher != nil {
+		manager.launch = s.browserLauncher
+	}
+	s.browsers = manager
 
 	// Browser instance management
-	s.router.HandleFunc("/browser/create", handleCreateBrowser(manager)).Methods("POST")
-	s.router.HandleFunc("/browser/{id}", handleCloseBrowser(manager)).Methods("DELETE")
+	s.handle(Route{
+		Method: "POST", Path: "/browser/create", Summary: "Start a browser instance",
+		Request: CreateBrowserRequest{}, Response: map[string]string{}, Status: http.StatusCreated, Timeout: LongRunningTimeout,
+	}, handleCreateBrowser(s, manager))
+	s.handle(Route{
+		Method: "DELETE", Path: "/browser/{id}", Summary: "Stop a browser instance",
+		Response: map[string]string{},
+	}, handleCloseBrowser(s, manager))
 
 	// Navigation and automation
-	s.router.HandleFunc("/browser/{id}/navigate", handleNavigate(manager)).Methods("POST")
-	s.router.HandleFunc("/browser/{id}/automate", handleAutomate(manager)).Methods("POST")
-	//s.router.HandleFunc("/browser/{id}/scrape", handleScrape(manager)).Methods("POST")
+	s.handle(Route{
+		Method: "POST", Path: "/browser/{id}/navigate", Summary: "Navigate to a URL",
+		Request: NavigateRequest{}, Response: browser.NavigationResult{}, Timeout: LongRunningTimeout,
+	}, handleNavigate(manager))
+	s.handle(Route{
+		Method: "POST", Path: "/browser/{id}/automate", Summary: "Run an automation sequence",
+		Request: AutomationRequest{}, Response: map[string]string{}, Timeout: LongRunningTimeout,
+	}, handleAutomate(s, manager))
+	s.handle(Route{
+		Method: "POST", Path: "/browser/{id}/scrape", Summary: "Extract the text of elements from the current page",
+		Request: ScrapingRequest{}, Response: browser.ScrapingResult{}, Timeout: LongRunningTimeout,
+	}, handleScrape(manager))
 	//s.router.HandleFunc("/browser/{id}/screenshot", handleScreenshot(manager)).Methods("POST")
+
+	s.addBrowserMethods(manager)
+}
+
+// browserParams are the params of the browser.* JSON-RPC methods
+type browserParams struct {
+	ID string `json:"id"`
+	CreateBrowserRequest
+	NavigateRequest
+	ScrapingRequest
+}
+
+// browserNavigateParams and browserScrapeParams document the params of
+// single methods
+type browserNavigateParams struct {
+	ID string `json:"id"`
+	NavigateRequest
 }
 
-func handleCreateBrowser(bm *BrowserManager) http.HandlerFunc {
+type browserScrapeParams struct {
+	ID string `json:"id"`
+	ScrapingRequest
+}
+
+// addBrowserMethods registers the browser.* JSON-RPC methods
+func (s *Server) addBrowserMethods(manager *BrowserManager) {
+	s.handleRPC(RPCMethod{
+		Name: "browser.create", Summary: "Start a browser instance", Params: CreateBrowserRequest{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p browserParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if err := s.startBrowser(ctx, manager, p.ID, &p.Config); err != nil {
+			return nil, err
+		}
+		return map[string]string{"id": p.ID, "status": "created"}, nil
+	})
+	s.handleRPC(RPCMethod{
+		Name: "browser.close", Summary: "Stop a browser instance", Params: idParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p browserParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if err := manager.Close(p.ID); err != nil {
+			return nil, err
+		}
+		s.unleaseResource(LeaseBrowser, p.ID)
+		LoggerFromContext(ctx).Info("browser closed", "browser", p.ID)
+		return map[string]string{"id": p.ID, "status": "closed"}, nil
+	})
+	s.handleRPC(RPCMethod{
+		Name: "browser.navigate", Summary: "Navigate a browser instance to a URL", Params: browserNavigateParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p browserParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return manager.navigate(ctx, p.ID, p.URL)
+	})
+	s.handleRPC(RPCMethod{
+		Name: "browser.scrape", Summary: "Extract the text of elements matching CSS selectors from the current page", Params: browserScrapeParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p browserParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return manager.scrape(ctx, p.ID, p.Selectors)
+	})
+}
+
+// browserStatus maps BrowserManager errors to HTTP status codes
+func browserStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrBrowserNotFound), errors.Is(err, ErrLeaseNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrBrowserExists):
+		return http.StatusConflict
+	case errors.Is(err, ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrDependencyUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func handleCreateBrowser(s *Server, bm *BrowserManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req CreateBrowserRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -68,23 +320,11 @@ func handleCreateBrowser(bm *BrowserManager) http.HandlerFunc {
 			return
 		}
 
-		bm.mu.Lock()
-		if _, exists := bm.browsers[req.ID]; exists {
-			bm.mu.Unlock()
-			writeError(w, http.StatusConflict, fmt.Errorf("browser with ID %s already exists", req.ID))
-			return
-		}
-
-		b := browser.NewBrowser(&req.Config)
-		if err := b.Start(); err != nil {
-			bm.mu.Unlock()
-			writeError(w, http.StatusInternalServerError, err)
+		if err := s.startBrowser(r.Context(), bm, req.ID, &req.Config); err != nil {
+			writeError(w, browserStatus(err), err)
 			return
 		}
 
-		bm.browsers[req.ID] = b
-		bm.mu.Unlock()
-
 		writeJSON(w, http.StatusCreated, map[string]string{
 			"id":     req.ID,
 			"status": "created",
@@ -92,26 +332,16 @@ func handleCreateBrowser(bm *BrowserManager) http.HandlerFunc {
 	}
 }
 
-func handleCloseBrowser(bm *BrowserManager) http.HandlerFunc {
+func handleCloseBrowser(s *Server, bm *BrowserManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := mux.Vars(r)["id"]
 
-		bm.mu.Lock()
-		b, exists := bm.browsers[id]
-		if !exists {
-			bm.mu.Unlock()
-			writeError(w, http.StatusNotFound, fmt.Errorf("browser not found"))
-			return
-		}
-
-		if err := b.Stop(); err != nil {
-			bm.mu.Unlock()
-			writeError(w, http.StatusInternalServerError, err)
+		if err := bm.Close(id); err != nil {
+			writeError(w, browserStatus(err), err)
 			return
 		}
-
-		delete(bm.browsers, id)
-		bm.mu.Unlock()
+		s.unleaseResource(LeaseBrowser, id)
+		LoggerFromContext(r.Context()).Info("browser closed", "browser", id)
 
 		writeJSON(w, http.StatusOK, map[string]string{
 			"id":     id,
@@ -130,18 +360,29 @@ func handleNavigate(bm *BrowserManager) http.HandlerFunc {
 			return
 		}
 
-		bm.mu.RLock()
-		b, exists := bm.browsers[id]
-		bm.mu.RUnlock()
+		result, err := bm.navigate(r.Context(), id, req.URL)
+		if err != nil {
+			writeError(w, browserStatus(err), err)
+			return
+		}
 
-		if !exists {
-			writeError(w, http.StatusNotFound, fmt.Errorf("browser not found"))
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func handleScrape(bm *BrowserManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		var req ScrapingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		result, err := b.Navigate(req.URL)
+		result, err := bm.scrape(r.Context(), id, req.Selectors)
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, err)
+			writeError(w, browserStatus(err), err)
 			return
 		}
 
@@ -149,7 +390,7 @@ func handleNavigate(bm *BrowserManager) http.HandlerFunc {
 	}
 }
 
-func handleAutomate(bm *BrowserManager) http.HandlerFunc {
+func handleAutomate(s *Server, bm *BrowserManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := mux.Vars(r)["id"]
 
@@ -158,6 +399,15 @@ func handleAutomate(bm *BrowserManager) http.HandlerFunc {
 			writeError(w, http.StatusBadRequest, err)
 			return
 		}
+		for _, step := range req.Sequence.Steps {
+			if step.Type != browser.StepEval {
+				continue
+			}
+			if err := s.checkCapability(CapabilityBrowserEval); err != nil {
+				writeError(w, http.StatusForbidden, err)
+				return
+			}
+		}
 
 		bm.mu.RLock()
 		b, exists := bm.browsers[id]
@@ -168,10 +418,13 @@ func handleAutomate(bm *BrowserManager) http.HandlerFunc {
 			return
 		}
 
+		logger := LoggerFromContext(r.Context()).With("browser", id)
 		if err := b.ExecuteSequence(&req.Sequence); err != nil {
+			logger.Error("browser automation failed", "sequence", req.Sequence.Name, "error", err)
 			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
+		logger.Info("browser automation completed", "sequence", req.Sequence.Name, "steps", len(req.Sequence.Steps))
 
 		writeJSON(w, http.StatusOK, map[string]string{
 			"status": "completed",
@@ -0,0 +1,332 @@
+package mcp
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/callgraph/static"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// Algorithms building call graphs
+const (
+	// CallGraphStatic only follows calls of functions known at compile time
+	CallGraphStatic = "static"
+	// CallGraphCHA resolves dynamic calls to every method of every type
+	// implementing the interface called (class hierarchy analysis)
+	CallGraphCHA = "cha"
+	// CallGraphRTA resolves dynamic calls to the types found to be used by
+	// functions reachable from main and init functions, or from every
+	// function of library packages (rapid type analysis)
+	CallGraphRTA = "rta"
+)
+
+// CallGraphNode is a function of a call graph
+type CallGraphNode struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"` // Qualified name, e.g. (*example.com/m.T).Method
+	Package string `json:"package"`
+	File    string `json:"file,omitempty"` // Relative to the workspace root when under it
+	Line    int    `json:"line,omitempty"`
+}
+
+// CallGraphEdge is the calls of a function by another
+type CallGraphEdge struct {
+	Caller  int  `json:"caller"`
+	Callee  int  `json:"callee"`
+	Calls   int  `json:"calls"`   // Number of call sites
+	Dynamic bool `json:"dynamic"` // Some calls are through interfaces or function values
+}
+
+// CallGraphReport is the call graph of the packages matching a pattern. Its
+// nodes are the functions making or receiving calls.
+type CallGraphReport struct {
+	Algorithm string          `json:"algorithm"`
+	Pattern   string          `json:"pattern"`
+	Nodes     []CallGraphNode `json:"nodes"`
+	Edges     []CallGraphEdge `json:"edges"`
+}
+
+// AnalyzeCallGraph builds the call graph of the packages matching pattern
+// in root with the given algorithm. Only functions of those packages are
+// included, unless external is set, which adds the functions of their
+// dependencies and the standard library they call. Synthetic functions,
+// like the wrappers of promoted methods, are left out.
+func AnalyzeCallGraph(root, pattern, algorithm string, external bool) (*CallGraphReport, error) {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:  root,
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %v", err)
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no packages match %s", pattern)
+	}
+	// SSA needs type checked packages
+	var loadErr error
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if loadErr == nil && len(pkg.Errors) > 0 {
+			loadErr = fmt.Errorf("failed to load package %s: %v", pkg.PkgPath, pkg.Errors[0])
+		}
+	})
+	if loadErr != nil {
+		return nil, loadErr
+	}
+
+	prog, ssaPkgs := ssautil.AllPackages(pkgs, ssa.InstantiateGenerics)
+	prog.Build()
+
+	var cg *callgraph.Graph
+	switch algorithm {
+	case CallGraphStatic:
+		cg = static.CallGraph(prog)
+	case CallGraphCHA:
+		cg = cha.CallGraph(prog)
+	case CallGraphRTA:
+		cg = rta.Analyze(rtaRoots(ssaPkgs), true).CallGraph
+	default:
+		return nil, fmt.Errorf("algorithm must be %s, %s or %s", CallGraphStatic, CallGraphCHA, CallGraphRTA)
+	}
+
+	initial := make(map[*ssa.Package]bool)
+	for _, pkg := range ssaPkgs {
+		if pkg != nil {
+			initial[pkg] = true
+		}
+	}
+	included := func(fn *ssa.Function) bool {
+		if fn == nil || fn.Synthetic != "" || fn.Pkg == nil {
+			return false
+		}
+		return external || initial[fn.Pkg]
+	}
+
+	report := &CallGraphReport{Algorithm: algorithm, Pattern: pattern, Nodes: []CallGraphNode{}, Edges: []CallGraphEdge{}}
+	ids := make(map[*ssa.Function]int)
+	node := func(fn *ssa.Function) int {
+		if id, ok := ids[fn]; ok {
+			return id
+		}
+		n := CallGraphNode{ID: len(report.Nodes), Name: fn.String(), Package: fn.Pkg.Pkg.Path()}
+		if pos := prog.Fset.Position(fn.Pos()); pos.IsValid() {
+			n.File, n.Line = relativePath(root, pos), pos.Line
+		}
+		ids[fn] = n.ID
+		report.Nodes = append(report.Nodes, n)
+		return n.ID
+	}
+
+	// Instances of generic functions are merged into their origin
+	type pair struct{ caller, callee *ssa.Function }
+	edges := make(map[pair]*CallGraphEdge)
+	var order []pair
+	for _, n := range cg.Nodes {
+		caller := origin(n.Func)
+		if !included(caller) {
+			continue
+		}
+		for _, out := range n.Out {
+			callee := origin(out.Callee.Func)
+			if !included(callee) {
+				continue
+			}
+			key := pair{caller, callee}
+			edge, ok := edges[key]
+			if !ok {
+				edge = &CallGraphEdge{}
+				edges[key] = edge
+				order = append(order, key)
+			}
+			edge.Calls++
+			if out.Site != nil && out.Site.Common().StaticCallee() == nil {
+				edge.Dynamic = true
+			}
+		}
+	}
+
+	// Nodes and edges are numbered in a stable order
+	functionLess := func(a, b *ssa.Function) bool {
+		if a.Pkg.Pkg.Path() != b.Pkg.Pkg.Path() {
+			return a.Pkg.Pkg.Path() < b.Pkg.Pkg.Path()
+		}
+		return a.String() < b.String()
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].caller != order[j].caller {
+			return functionLess(order[i].caller, order[j].caller)
+		}
+		return functionLess(order[i].callee, order[j].callee)
+	})
+	for _, key := range order {
+		edge := edges[key]
+		edge.Caller, edge.Callee = node(key.caller), node(key.callee)
+		report.Edges = append(report.Edges, *edge)
+	}
+	return report, nil
+}
+
+// origin returns the generic function of an instance
+func origin(fn *ssa.Function) *ssa.Function {
+	if fn != nil && fn.Origin() != nil {
+		return fn.Origin()
+	}
+	return fn
+}
+
+// rtaRoots returns the main and init functions of the main packages, or,
+// when there are none, every function and method of the packages
+func rtaRoots(pkgs []*ssa.Package) []*ssa.Function {
+	var roots []*ssa.Function
+	for _, pkg := range pkgs {
+		if pkg != nil && pkg.Pkg.Name() == "main" {
+			if main := pkg.Func("main"); main != nil {
+				roots = append(roots, main, pkg.Func("init"))
+			}
+		}
+	}
+	if len(roots) > 0 {
+		return roots
+	}
+
+	for _, pkg := range pkgs {
+		if pkg == nil {
+			continue
+		}
+		for _, member := range pkg.Members {
+			switch member := member.(type) {
+			case *ssa.Function:
+				if member.TypeParams().Len() == 0 {
+					roots = append(roots, member)
+				}
+			case *ssa.Type:
+				mset := pkg.Prog.MethodSets.MethodSet(types.NewPointer(member.Type()))
+				for i := 0; i < mset.Len(); i++ {
+					if fn := pkg.Prog.MethodValue(mset.At(i)); fn != nil {
+						roots = append(roots, fn)
+					}
+				}
+			}
+		}
+	}
+	return roots
+}
+
+// relativePath returns the file of pos relative to root, or its absolute
+// path when it is outside root
+func relativePath(root string, pos token.Position) string {
+	rel, err := filepath.Rel(root, pos.Filename)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return pos.Filename
+	}
+	return filepath.ToSlash(rel)
+}
+
+// writeCallGraphDOT writes a call graph in the DOT language of Graphviz,
+// with the functions of each package in a cluster. Dynamic calls are
+// dashed.
+func writeCallGraphDOT(w io.Writer, report *CallGraphReport) error {
+	var b strings.Builder
+	b.WriteString("digraph callgraph {\n\trankdir=LR;\n\tnode [shape=box];\n")
+
+	clusters := make(map[string][]CallGraphNode)
+	var names []string
+	for _, n := range report.Nodes {
+		if _, ok := clusters[n.Package]; !ok {
+			names = append(names, n.Package)
+		}
+		clusters[n.Package] = append(clusters[n.Package], n)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		fmt.Fprintf(&b, "\tsubgraph cluster_%d {\n\t\tlabel=%s;\n", i, strconv.Quote(name))
+		for _, n := range clusters[name] {
+			fmt.Fprintf(&b, "\t\tn%d [label=%s];\n", n.ID, strconv.Quote(shortFunctionName(n)))
+		}
+		b.WriteString("\t}\n")
+	}
+	for _, e := range report.Edges {
+		fmt.Fprintf(&b, "\tn%d -> n%d", e.Caller, e.Callee)
+		if e.Dynamic {
+			b.WriteString(" [style=dashed]")
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// shortFunctionName returns the name of a function without its package
+// path, which is the label of its cluster
+func shortFunctionName(n CallGraphNode) string {
+	return strings.ReplaceAll(n.Name, n.Package+".", path.Base(n.Package)+".")
+}
+
+// localPattern reports whether a package pattern names packages by import
+// path or relative to the workspace, without leaving it
+func localPattern(pattern string) bool {
+	if filepath.IsAbs(pattern) || strings.HasPrefix(pattern, "-") {
+		return false
+	}
+	for _, elem := range strings.Split(filepath.ToSlash(pattern), "/") {
+		if elem == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func handleCallGraph(root string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		pattern := q.Get("pattern")
+		if pattern == "" {
+			pattern = "./..."
+		}
+		if !localPattern(pattern) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("pattern must be a package path or relative to the workspace"))
+			return
+		}
+		algorithm := q.Get("algorithm")
+		if algorithm == "" {
+			algorithm = CallGraphStatic
+		}
+		format := q.Get("format")
+		if format != "" && format != "json" && format != "dot" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("format must be json or dot"))
+			return
+		}
+
+		report, err := AnalyzeCallGraph(root, pattern, algorithm, q.Get("external") == "true")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if format == "dot" {
+			w.Header().Set("Content-Type", "text/vnd.graphviz")
+			writeCallGraphDOT(w, report)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	}
+}
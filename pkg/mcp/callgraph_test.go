@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const callGraphSource = `package m
+
+import "strings"
+
+type Speaker interface{ Speak() string }
+
+type Dog struct{}
+
+func (Dog) Speak() string { return bark() }
+
+func bark() string { return strings.ToUpper("woof") }
+
+func Talk(s Speaker) string { return s.Speak() }
+
+func Run() string { return Talk(Dog{}) + bark() }
+`
+
+func TestHandleCallGraph(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "m.go"), []byte(callGraphSource), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddAnalysisHandler()
+
+	callGraph := func(query string) (map[string]CallGraphEdge, *CallGraphReport) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/callgraph?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var report CallGraphReport
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		edges := make(map[string]CallGraphEdge)
+		for _, e := range report.Edges {
+			edges[report.Nodes[e.Caller].Name+" -> "+report.Nodes[e.Callee].Name] = e
+		}
+		return edges, &report
+	}
+
+	// Static calls of the package only
+	edges, report := callGraph("")
+	assert.Equal(t, CallGraphStatic, report.Algorithm)
+	assert.Equal(t, "./...", report.Pattern)
+	assert.Contains(t, edges, "example.com/m.Run -> example.com/m.Talk")
+	assert.Contains(t, edges, "example.com/m.Run -> example.com/m.bark")
+	assert.Contains(t, edges, "(example.com/m.Dog).Speak -> example.com/m.bark")
+	assert.NotContains(t, edges, "example.com/m.Talk -> (example.com/m.Dog).Speak")
+	assert.NotContains(t, edges, "example.com/m.bark -> strings.ToUpper")
+	for _, n := range report.Nodes {
+		if n.Name == "example.com/m.bark" {
+			assert.Equal(t, "m.go", n.File)
+			assert.Equal(t, 11, n.Line)
+		}
+	}
+
+	// Calls through interfaces are resolved to their implementations
+	for _, algorithm := range []string{CallGraphCHA, CallGraphRTA} {
+		edges, _ = callGraph("algorithm=" + algorithm)
+		require.Contains(t, edges, "example.com/m.Talk -> (example.com/m.Dog).Speak", algorithm)
+		assert.True(t, edges["example.com/m.Talk -> (example.com/m.Dog).Speak"].Dynamic, algorithm)
+		assert.False(t, edges["example.com/m.Run -> example.com/m.Talk"].Dynamic, algorithm)
+	}
+
+	edges, _ = callGraph("external=true")
+	assert.Contains(t, edges, "example.com/m.bark -> strings.ToUpper")
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/callgraph?format=dot&algorithm=cha", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/vnd.graphviz", w.Header().Get("Content-Type"))
+	dot := w.Body.String()
+	assert.True(t, strings.HasPrefix(dot, "digraph callgraph {\n"), dot)
+	assert.Contains(t, dot, "label=\"example.com/m\";")
+	assert.Contains(t, dot, "[label=\"(m.Dog).Speak\"];")
+	assert.Contains(t, dot, "[style=dashed];")
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"unknown algorithm", "algorithm=pointer"},
+		{"unknown format", "format=svg"},
+		{"outside the workspace", "pattern=../..."},
+		{"absolute pattern", "pattern=/tmp/..."},
+		{"no packages", "pattern=./missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/callgraph?"+tt.query, nil))
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
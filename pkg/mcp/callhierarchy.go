@@ -0,0 +1,344 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Directions of a call hierarchy
+const (
+	CallsIncoming = "incoming"
+	CallsOutgoing = "outgoing"
+)
+
+// CallHierarchyItem is a function or method of a call hierarchy
+type CallHierarchyItem struct {
+	Name           string `json:"name"`
+	Kind           string `json:"kind"`             // function or method
+	Detail         string `json:"detail,omitempty"` // Qualified name
+	URI            string `json:"uri"`
+	Range          Range  `json:"range"`          // The whole declaration
+	SelectionRange Range  `json:"selectionRange"` // The name
+}
+
+// CallHierarchyIncomingCall is a function calling the item of a hierarchy
+type CallHierarchyIncomingCall struct {
+	From       CallHierarchyItem `json:"from"`
+	FromRanges []Range           `json:"fromRanges"` // The calls, in From
+}
+
+// CallHierarchyOutgoingCall is a function the item of a hierarchy calls
+type CallHierarchyOutgoingCall struct {
+	To         CallHierarchyItem `json:"to"`
+	FromRanges []Range           `json:"fromRanges"` // The calls, in the item
+}
+
+// CallHierarchy is a function with the functions calling it and the
+// functions it calls
+type CallHierarchy struct {
+	Item     CallHierarchyItem           `json:"item"`
+	Incoming []CallHierarchyIncomingCall `json:"incoming,omitempty"`
+	Outgoing []CallHierarchyOutgoingCall `json:"outgoing,omitempty"`
+}
+
+// callGraph holds the static calls between the functions of the packages
+// of a workspace and their tests. Functions are keyed by their
+// declaration, since every package and test variant has objects of its
+// own; calls through interfaces are calls of the interface method.
+type callGraph struct {
+	fset  *token.FileSet
+	funcs map[string]*callNode
+	calls []callEdge
+}
+
+type callNode struct {
+	fn   *types.Func
+	decl *ast.FuncDecl // Nil for functions declared outside the workspace
+}
+
+type callEdge struct {
+	caller, callee string
+	at             *ast.Ident // Names the callee
+}
+
+// buildCallGraph loads the packages under root and records the calls made
+// in the body of every function declaration, including the function
+// literals in it. Overlay replaces the contents of files on disk, as for
+// FindDefinition.
+func buildCallGraph(root string, overlay map[string][]byte) (*callGraph, error) {
+	fset := token.NewFileSet()
+	cfg := &packages.Config{
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:     root,
+		Fset:    fset,
+		Overlay: overlay,
+		Tests:   true,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %v", err)
+	}
+
+	g := &callGraph{fset: fset, funcs: make(map[string]*callNode)}
+	seen := make(map[string]bool) // Calls of files shared by test variants
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				caller, ok := pkg.TypesInfo.Defs[fn.Name].(*types.Func)
+				if !ok {
+					continue
+				}
+				key := g.add(caller)
+				g.funcs[key].decl = fn
+				if fn.Body == nil {
+					continue
+				}
+
+				ast.Inspect(fn.Body, func(n ast.Node) bool {
+					call, ok := n.(*ast.CallExpr)
+					if !ok {
+						return true
+					}
+					ident := calleeIdent(call.Fun)
+					if ident == nil {
+						return true
+					}
+					callee, ok := pkg.TypesInfo.Uses[ident].(*types.Func)
+					if !ok || seen[fset.Position(ident.Pos()).String()] {
+						return true
+					}
+					seen[fset.Position(ident.Pos()).String()] = true
+					g.calls = append(g.calls, callEdge{caller: key, callee: g.add(callee), at: ident})
+					return true
+				})
+			}
+		}
+	}
+	return g, nil
+}
+
+// add records a function and returns its key
+func (g *callGraph) add(fn *types.Func) string {
+	fn = fn.Origin()
+	key := g.fset.Position(fn.Pos()).String() + " " + fn.Name()
+	if _, ok := g.funcs[key]; !ok {
+		g.funcs[key] = &callNode{fn: fn}
+	}
+	return key
+}
+
+// calleeIdent returns the name of the function called by a call of fun, or
+// nil for calls of function values and conversions
+func calleeIdent(fun ast.Expr) *ast.Ident {
+	switch fun := ast.Unparen(fun).(type) {
+	case *ast.Ident:
+		return fun
+	case *ast.SelectorExpr:
+		return fun.Sel
+	case *ast.IndexExpr:
+		return calleeIdent(fun.X)
+	case *ast.IndexListExpr:
+		return calleeIdent(fun.X)
+	}
+	return nil
+}
+
+// item describes a function of the graph. uris maps file URIs to the URIs
+// of open documents.
+func (g *callGraph) item(key string, uris map[string]string) CallHierarchyItem {
+	node := g.funcs[key]
+	kind := "function"
+	if node.fn.Type().(*types.Signature).Recv() != nil {
+		kind = "method"
+	}
+	name := g.identRange(node.fn.Pos(), len(node.fn.Name()))
+	item := CallHierarchyItem{
+		Name:           node.fn.Name(),
+		Kind:           kind,
+		Detail:         node.fn.FullName(),
+		URI:            g.uri(node.fn.Pos(), uris),
+		Range:          name,
+		SelectionRange: name,
+	}
+	if node.decl != nil {
+		start, end := g.fset.Position(node.decl.Pos()), g.fset.Position(node.decl.End())
+		item.Range = Range{
+			Start: Position{Line: start.Line - 1, Character: start.Column - 1},
+			End:   Position{Line: end.Line - 1, Character: end.Column - 1},
+		}
+	}
+	return item
+}
+
+func (g *callGraph) identRange(pos token.Pos, length int) Range {
+	at := g.fset.Position(pos)
+	start := Position{Line: at.Line - 1, Character: at.Column - 1}
+	return Range{Start: start, End: Position{Line: start.Line, Character: start.Character + length}}
+}
+
+func (g *callGraph) uri(pos token.Pos, uris map[string]string) string {
+	uri := "file://" + filepath.ToSlash(g.fset.Position(pos).Filename)
+	if docURI, ok := uris[uri]; ok {
+		return docURI
+	}
+	return uri
+}
+
+// incoming returns the functions calling the function key, with the calls
+// each makes, ordered by file and position
+func (g *callGraph) incoming(key string, uris map[string]string) []CallHierarchyIncomingCall {
+	ranges := make(map[string][]Range)
+	var callers []string
+	for _, edge := range g.calls {
+		if edge.callee != key {
+			continue
+		}
+		if _, ok := ranges[edge.caller]; !ok {
+			callers = append(callers, edge.caller)
+		}
+		ranges[edge.caller] = append(ranges[edge.caller], g.identRange(edge.at.Pos(), len(edge.at.Name)))
+	}
+	sort.SliceStable(callers, func(i, j int) bool {
+		a, b := g.fset.Position(g.funcs[callers[i]].fn.Pos()), g.fset.Position(g.funcs[callers[j]].fn.Pos())
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+
+	calls := make([]CallHierarchyIncomingCall, len(callers))
+	for i, caller := range callers {
+		sortRanges(ranges[caller])
+		calls[i] = CallHierarchyIncomingCall{From: g.item(caller, uris), FromRanges: ranges[caller]}
+	}
+	return calls
+}
+
+// outgoing returns the functions the function key calls, with the calls it
+// makes of each, ordered by the first call
+func (g *callGraph) outgoing(key string, uris map[string]string) []CallHierarchyOutgoingCall {
+	ranges := make(map[string][]Range)
+	var callees []string
+	for _, edge := range g.calls {
+		if edge.caller != key {
+			continue
+		}
+		if _, ok := ranges[edge.callee]; !ok {
+			callees = append(callees, edge.callee)
+		}
+		ranges[edge.callee] = append(ranges[edge.callee], g.identRange(edge.at.Pos(), len(edge.at.Name)))
+	}
+	for _, callee := range callees {
+		sortRanges(ranges[callee])
+	}
+	sort.SliceStable(callees, func(i, j int) bool {
+		return positionBefore(ranges[callees[i]][0].Start, ranges[callees[j]][0].Start)
+	})
+
+	calls := make([]CallHierarchyOutgoingCall, len(callees))
+	for i, callee := range callees {
+		calls[i] = CallHierarchyOutgoingCall{To: g.item(callee, uris), FromRanges: ranges[callee]}
+	}
+	return calls
+}
+
+func sortRanges(ranges []Range) {
+	sort.Slice(ranges, func(i, j int) bool {
+		return positionBefore(ranges[i].Start, ranges[j].Start)
+	})
+}
+
+// lookup returns the key of the function named at pos in the file at path
+func (g *callGraph) lookup(path string, content []byte, pos Position) (string, error) {
+	offset, err := offsetAt(string(content), pos)
+	if err != nil {
+		return "", err
+	}
+	for key, node := range g.funcs {
+		at := g.fset.Position(node.fn.Pos())
+		if at.Filename == path && offset >= at.Offset && offset <= at.Offset+len(node.fn.Name()) {
+			return key, nil
+		}
+	}
+	for _, edge := range g.calls {
+		at := g.fset.Position(edge.at.Pos())
+		if at.Filename == path && offset >= at.Offset && offset <= at.Offset+len(edge.at.Name) {
+			return edge.callee, nil
+		}
+	}
+	return "", fmt.Errorf("no function at %d:%d", pos.Line, pos.Character)
+}
+
+// callHierarchy returns the function declared or called at pos in an open
+// document or a Go file of the workspace, with its callers and callees in
+// the workspace as requested by direction, or both when it is empty
+func (ls *LanguageServer) callHierarchy(uri string, pos Position, direction string) (*CallHierarchy, error) {
+	if direction != "" && direction != CallsIncoming && direction != CallsOutgoing {
+		return nil, fmt.Errorf("direction must be %s or %s", CallsIncoming, CallsOutgoing)
+	}
+	overlay, uris, exists := ls.overlay(uri)
+	if !exists {
+		return nil, errDocumentNotFound
+	}
+	path := ls.documentPath(uri)
+	content, ok := overlay[path]
+	if !ok {
+		var err error
+		if content, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	g, err := buildCallGraph(ls.workspaceRoot, overlay)
+	if err != nil {
+		return nil, err
+	}
+	key, err := g.lookup(path, content, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	hierarchy := &CallHierarchy{Item: g.item(key, uris)}
+	if direction != CallsOutgoing {
+		hierarchy.Incoming = g.incoming(key, uris)
+	}
+	if direction != CallsIncoming {
+		hierarchy.Outgoing = g.outgoing(key, uris)
+	}
+	return hierarchy, nil
+}
+
+func handleCallHierarchy(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uri, pos, err := positionParams(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		hierarchy, err := ls.callHierarchy(uri, pos, r.URL.Query().Get("direction"))
+		switch {
+		case errors.Is(err, errDocumentNotFound):
+			writeError(w, http.StatusNotFound, err)
+			return
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, hierarchy)
+	}
+}
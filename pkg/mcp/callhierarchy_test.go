@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const callHierarchySource = `package m
+
+import "strings"
+
+type Greeter struct{}
+
+func (g *Greeter) Greet(name string) string {
+	return strings.ToUpper(hello(name))
+}
+
+func hello(name string) string {
+	return "hello " + name
+}
+
+func run() {
+	g := &Greeter{}
+	g.Greet("a")
+	func() { g.Greet("b") }()
+	hello("c")
+}
+`
+
+func TestHandleCallHierarchy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "m.go"), []byte("package m\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "m_test.go"), []byte("package m\n\nimport \"testing\"\n\nfunc TestHello(t *testing.T) {\n\thello(\"t\")\n}\n"), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+
+	// The open document differs from the file on disk
+	body, err := json.Marshal(TextDocumentItem{URI: "m.go", Text: callHierarchySource})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/document/open", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	hierarchy := func(query string) CallHierarchy {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/callHierarchy?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var hierarchy CallHierarchy
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&hierarchy))
+		return hierarchy
+	}
+	testFile := "file://" + filepath.ToSlash(filepath.Join(dir, "m_test.go"))
+
+	// Callers in the package and its tests
+	h := hierarchy("uri=m.go&line=10&character=6")
+	assert.Equal(t, CallHierarchyItem{
+		Name: "hello", Kind: "function", Detail: "example.com/m.hello", URI: "m.go",
+		Range:          Range{Start: Position{Line: 10, Character: 0}, End: Position{Line: 12, Character: 1}},
+		SelectionRange: Range{Start: Position{Line: 10, Character: 5}, End: Position{Line: 10, Character: 10}},
+	}, h.Item)
+	require.Len(t, h.Incoming, 3)
+	assert.Equal(t, "Greet", h.Incoming[0].From.Name)
+	assert.Equal(t, "method", h.Incoming[0].From.Kind)
+	assert.Equal(t, []Range{{Start: Position{Line: 7, Character: 24}, End: Position{Line: 7, Character: 29}}}, h.Incoming[0].FromRanges)
+	assert.Equal(t, "run", h.Incoming[1].From.Name)
+	assert.Equal(t, "TestHello", h.Incoming[2].From.Name)
+	assert.Equal(t, testFile, h.Incoming[2].From.URI)
+	assert.Empty(t, h.Outgoing)
+
+	// Callees, in order, including the standard library; calls in function
+	// literals belong to the enclosing function
+	h = hierarchy("uri=m.go&line=6&character=20")
+	assert.Equal(t, "(*example.com/m.Greeter).Greet", h.Item.Detail)
+	require.Len(t, h.Outgoing, 2)
+	assert.Equal(t, "ToUpper", h.Outgoing[0].To.Name)
+	assert.True(t, strings.HasSuffix(h.Outgoing[0].To.URI, "/strings/strings.go"), h.Outgoing[0].To.URI)
+	assert.Equal(t, "hello", h.Outgoing[1].To.Name)
+	require.Len(t, h.Incoming, 1)
+	assert.Equal(t, "run", h.Incoming[0].From.Name)
+	assert.Len(t, h.Incoming[0].FromRanges, 2)
+
+	// A call site names the function called
+	h = hierarchy("uri=m.go&line=18&character=2&direction=outgoing")
+	assert.Equal(t, "hello", h.Item.Name)
+	assert.Empty(t, h.Incoming)
+
+	tests := []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{"not a function", "uri=m.go&line=4&character=6", http.StatusBadRequest},
+		{"unknown direction", "uri=m.go&line=10&character=6&direction=sideways", http.StatusBadRequest},
+		{"missing document", "uri=missing.go&line=0&character=0", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/v1/lsp/callHierarchy?%s", tt.query), nil))
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+		})
+	}
+}
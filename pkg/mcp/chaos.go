@@ -0,0 +1,255 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// chaosAllGroups configures faults for every route group without faults of
+// its own, except the admin endpoints needed to turn chaos off again
+const chaosAllGroups = "*"
+
+// chaosHeader tells clients which faults were injected into a response
+const chaosHeader = "X-MCP-Chaos"
+
+// ChaosConfig configures fault injection, a test mode that lets client and
+// agent developers exercise their retry and error handling against a local
+// server. Never enable it in production.
+type ChaosConfig struct {
+	Enabled bool  `yaml:"enabled"`
+	Seed    int64 `yaml:"seed"` // Makes faults reproducible; random when zero
+	// Groups configures faults per route group, keyed by the first path
+	// segment, e.g. "ssh", or "*" for all groups
+	Groups map[string]Fault `yaml:"groups"`
+}
+
+// Fault describes the faults injected into the requests of a route group.
+// Rates are probabilities between 0 and 1.
+type Fault struct {
+	Latency       time.Duration `yaml:"latency" json:"latency,omitempty"`
+	Jitter        time.Duration `yaml:"jitter" json:"jitter,omitempty"` // Random extra latency up to this much
+	ErrorRate     float64       `yaml:"error_rate" json:"error_rate,omitempty"`
+	ErrorStatus   int           `yaml:"error_status" json:"error_status,omitempty"` // Defaults to 503
+	DropEventRate float64       `yaml:"drop_event_rate" json:"drop_event_rate,omitempty"`
+}
+
+// Validate checks the fault for values that cannot work
+func (f Fault) Validate() error {
+	if f.Latency < 0 || f.Jitter < 0 {
+		return fmt.Errorf("latency must not be negative")
+	}
+	if f.ErrorRate < 0 || f.ErrorRate > 1 || f.DropEventRate < 0 || f.DropEventRate > 1 {
+		return fmt.Errorf("rates must be between 0 and 1")
+	}
+	if f.ErrorStatus != 0 && (f.ErrorStatus < 400 || f.ErrorStatus > 599) {
+		return fmt.Errorf("error_status must be a 4xx or 5xx status")
+	}
+	return nil
+}
+
+// Validate checks the faults of every group
+func (c ChaosConfig) Validate() error {
+	for group, fault := range c.Groups {
+		if err := fault.Validate(); err != nil {
+			return fmt.Errorf("group %s: %v", group, err)
+		}
+	}
+	return nil
+}
+
+// Chaos injects faults into requests. Faults can be replaced at runtime.
+type Chaos struct {
+	groups map[string]Fault
+	rand   *rand.Rand
+	sleep  func(ctx context.Context, d time.Duration)
+	mu     sync.Mutex
+}
+
+// NewChaos creates a fault injector from the configuration
+func NewChaos(config ChaosConfig) *Chaos {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	c := &Chaos{rand: rand.New(rand.NewSource(seed)), sleep: sleepContext}
+	c.SetFaults(config.Groups)
+	return c
+}
+
+func sleepContext(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// Faults returns the faults of every configured group
+func (c *Chaos) Faults() map[string]Fault {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	faults := make(map[string]Fault, len(c.groups))
+	for group, fault := range c.groups {
+		faults[group] = fault
+	}
+	return faults
+}
+
+// SetFaults replaces the faults of all groups
+func (c *Chaos) SetFaults(groups map[string]Fault) {
+	faults := make(map[string]Fault, len(groups))
+	for group, fault := range groups {
+		faults[group] = fault
+	}
+	c.mu.Lock()
+	c.groups = faults
+	c.mu.Unlock()
+}
+
+func (c *Chaos) fault(group string) (Fault, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fault, ok := c.groups[group]; ok {
+		return fault, true
+	}
+	if group == "admin" {
+		return Fault{}, false
+	}
+	fault, ok := c.groups[chaosAllGroups]
+	return fault, ok
+}
+
+// roll reports whether an event with probability rate happens
+func (c *Chaos) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rand.Float64() < rate
+}
+
+func (c *Chaos) jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.rand.Int63n(int64(max) + 1))
+}
+
+// Middleware delays requests, fails them with error responses and drops
+// server-sent events as configured for their route group. Injected faults
+// are listed in the X-MCP-Chaos response header.
+func (c *Chaos) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fault, ok := c.fault(routeGroup(r.URL.Path))
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var injected []string
+		if delay := fault.Latency + c.jitter(fault.Jitter); delay > 0 {
+			c.sleep(r.Context(), delay)
+			injected = append(injected, "latency="+delay.String())
+		}
+
+		if c.roll(fault.ErrorRate) {
+			status := fault.ErrorStatus
+			if status == 0 {
+				status = http.StatusServiceUnavailable
+			}
+			if status == http.StatusServiceUnavailable || status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "1")
+			}
+			w.Header().Set(chaosHeader, strings.Join(append(injected, "error"), ", "))
+			writeError(w, status, errors.New("injected fault"))
+			return
+		}
+
+		if len(injected) > 0 {
+			w.Header().Set(chaosHeader, strings.Join(injected, ", "))
+		}
+		if fault.DropEventRate > 0 {
+			w = &eventDropper{ResponseWriter: w, chaos: c, rate: fault.DropEventRate}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// eventDropper drops whole server-sent events from event stream responses.
+// Other responses pass through untouched.
+type eventDropper struct {
+	http.ResponseWriter
+	chaos *Chaos
+	rate  float64
+	buf   bytes.Buffer
+}
+
+func (d *eventDropper) Write(p []byte) (int, error) {
+	if !strings.HasPrefix(d.Header().Get("Content-Type"), "text/event-stream") {
+		return d.ResponseWriter.Write(p)
+	}
+
+	d.buf.Write(p)
+	for {
+		i := bytes.Index(d.buf.Bytes(), []byte("\n\n"))
+		if i < 0 {
+			break
+		}
+		event := d.buf.Next(i + 2)
+		if d.chaos.roll(d.rate) {
+			continue
+		}
+		if _, err := d.ResponseWriter.Write(event); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush lets streaming handlers flush through the dropper
+func (d *eventDropper) Flush() {
+	if f, ok := d.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (d *eventDropper) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
+
+func handleGetChaos(c *Chaos) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Faults())
+	}
+}
+
+func handleSetChaos(c *Chaos) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var groups map[string]Fault
+		if err := json.NewDecoder(r.Body).Decode(&groups); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := (ChaosConfig{Groups: groups}).Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		c.SetFaults(groups)
+		LoggerFromContext(r.Context()).Warn("chaos faults replaced", "groups", len(groups))
+		writeJSON(w, http.StatusOK, c.Faults())
+	}
+}
@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaos_Faults(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Chaos = ChaosConfig{
+		Enabled: true,
+		Seed:    1,
+		Groups: map[string]Fault{
+			"*":       {ErrorRate: 1, ErrorStatus: http.StatusBadGateway},
+			"context": {Latency: time.Second},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+	s := NewServer(nil, WithConfig(cfg))
+	var slept time.Duration
+	s.chaos.sleep = func(ctx context.Context, d time.Duration) { slept += d }
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do("GET", "/v1/context/list", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, time.Second, slept)
+	assert.Equal(t, "latency=1s", rec.Header().Get(chaosHeader))
+
+	rec = do("GET", "/v1/capabilities", "")
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, "error", rec.Header().Get(chaosHeader))
+
+	// Admin endpoints stay reachable so faults can be switched off
+	rec = do("PUT", "/v1/admin/chaos", `{"context":{"error_rate":1}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/v1/capabilities", "").Code)
+	rec = do("GET", "/v1/context/list", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "/v1/admin/chaos", `{"context":{"error_rate":2}}`).Code)
+}
+
+func TestChaos_DropEvents(t *testing.T) {
+	stream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(w, "id: %d\nevent: log\ndata: line %d\n\n", i, i)
+		}
+	})
+
+	serve := func(rate float64) string {
+		chaos := NewChaos(ChaosConfig{Seed: 1, Groups: map[string]Fault{"ide": {DropEventRate: rate}}})
+		rec := httptest.NewRecorder()
+		chaos.Middleware(stream).ServeHTTP(rec, httptest.NewRequest("GET", "/ide/tasks/1/logs", nil))
+		return rec.Body.String()
+	}
+
+	assert.Equal(t, 100, strings.Count(serve(0), "event: log"))
+	assert.Empty(t, serve(1))
+	body := serve(0.5)
+	kept := strings.Count(body, "event: log")
+	assert.Greater(t, kept, 0)
+	assert.Less(t, kept, 100)
+	assert.Equal(t, kept*4, strings.Count(body, "\n"), "events are dropped whole")
+}
@@ -0,0 +1,523 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Kinds of code actions
+const (
+	CodeActionQuickFix        = "quickfix"
+	CodeActionOrganizeImports = "source.organizeImports"
+)
+
+// CommandApplyEdit applies the WorkspaceEdit in its only argument to the
+// files of the workspace and the open documents
+const CommandApplyEdit = "mcp.applyEdit"
+
+// CodeActionRequest asks for the actions available in a document
+type CodeActionRequest struct {
+	URI     string   `json:"uri"`
+	Content string   `json:"content,omitempty"` // Falls back to the open document's text
+	Range   *Range   `json:"range,omitempty"`   // Only fix the diagnostics touching these lines
+	Only    []string `json:"only,omitempty"`    // Kinds of actions to return, like quickfix
+}
+
+// CodeAction is an edit fixing diagnostics or tidying a document
+type CodeAction struct {
+	Title       string        `json:"title"`
+	Kind        string        `json:"kind"`
+	Diagnostics []Diagnostic  `json:"diagnostics,omitempty"`
+	Edit        WorkspaceEdit `json:"edit"`
+	Command     *Command      `json:"command,omitempty"` // Applies the edit server-side
+}
+
+// Command is a command the server executes on behalf of a client
+type Command struct {
+	Title     string        `json:"title"`
+	Command   string        `json:"command"`
+	Arguments []interface{} `json:"arguments,omitempty"`
+}
+
+// ExecuteCommandRequest asks the server to execute a command
+type ExecuteCommandRequest struct {
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments"`
+}
+
+// ExecuteCommandResult lists the documents a command changed
+type ExecuteCommandResult struct {
+	Applied []string `json:"applied"`
+}
+
+// ignoredErrors are the functions whose error is conventionally dropped
+var ignoredErrors = map[string]bool{
+	"fmt.Print":                        true,
+	"fmt.Printf":                       true,
+	"fmt.Println":                      true,
+	"(*bytes.Buffer).Write":            true,
+	"(*bytes.Buffer).WriteByte":        true,
+	"(*bytes.Buffer).WriteRune":        true,
+	"(*bytes.Buffer).WriteString":      true,
+	"(*strings.Builder).Write":         true,
+	"(*strings.Builder).WriteByte":     true,
+	"(*strings.Builder).WriteRune":     true,
+	"(*strings.Builder).WriteString":   true,
+	"(*hash/maphash.Hash).Write":       true,
+	"(*hash/maphash.Hash).WriteString": true,
+}
+
+// documentedNodes maps the names of the declarations of a file to the node
+// their doc comment precedes
+func documentedNodes(file *ast.File) map[*ast.Ident]ast.Node {
+	nodes := make(map[*ast.Ident]ast.Node)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			nodes[decl.Name] = decl
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				var node ast.Node = decl
+				if decl.Lparen.IsValid() {
+					node = spec
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					nodes[spec.Name] = node
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						nodes[name] = node
+					}
+				}
+			}
+		}
+	}
+	return nodes
+}
+
+// uncheckedCall is a call statement dropping an error result
+type uncheckedCall struct {
+	stmt    *ast.ExprStmt
+	call    *ast.CallExpr
+	results int
+	// enclosing is the signature of the function containing the call
+	enclosing *types.Signature
+}
+
+// CodeActions returns the quick fixes for the analyzer's diagnostics in a Go
+// source file, and for calls dropping an error found by type-checking it
+// with the other files of its package next to path, along with an action
+// organizing its imports. Every action carries the command applying it.
+func CodeActions(path string, req CodeActionRequest, imports ImportGroupingOptions) ([]CodeAction, error) {
+	content := req.Content
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse document: %v", err)
+	}
+
+	wanted := func(kind string) bool {
+		if len(req.Only) == 0 {
+			return true
+		}
+		for _, only := range req.Only {
+			if kind == only || strings.HasPrefix(kind, only+".") {
+				return true
+			}
+		}
+		return false
+	}
+	touched := func(d Diagnostic) bool {
+		return req.Range == nil || (d.Location.Range.Start.Line <= req.Range.End.Line && d.Location.Range.End.Line >= req.Range.Start.Line)
+	}
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset
+	}
+
+	actions := []CodeAction{}
+	fix := func(title string, d Diagnostic, edits ...TextEdit) {
+		actions = append(actions, CodeAction{
+			Title:       title,
+			Kind:        CodeActionQuickFix,
+			Diagnostics: []Diagnostic{d},
+			Edit:        WorkspaceEdit{Changes: map[string][]TextEdit{req.URI: edits}},
+		})
+	}
+
+	if wanted(CodeActionQuickFix) {
+		analysis, err := NewASTAnalyzer(fset).AnalyzeFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range analysis.Diagnostics {
+			d.Location.URI = req.URI
+			if !touched(d) {
+				continue
+			}
+			switch d.Code {
+			case "unused-import":
+				for _, decl := range file.Decls {
+					gen, ok := decl.(*ast.GenDecl)
+					if !ok || gen.Tok != token.IMPORT {
+						continue
+					}
+					for _, spec := range gen.Specs {
+						imp := spec.(*ast.ImportSpec)
+						if positionAt(content, offset(imp.Pos())) == d.Location.Range.Start {
+							fix(fmt.Sprintf("Remove import %q", importPath(imp)), d, removeImportEdit(fset, content, gen, imp))
+						}
+					}
+				}
+			case "missing-doc":
+				// The comment goes above the declaration, or above the spec
+				// of a parenthesized group, indented like it
+				for name, node := range documentedNodes(file) {
+					if positionAt(content, offset(name.Pos())) != d.Location.Range.Start {
+						continue
+					}
+					at := positionAt(content, offset(node.Pos()))
+					indent := content[offset(node.Pos())-at.Character : offset(node.Pos())]
+					if strings.TrimSpace(indent) != "" {
+						indent = ""
+					}
+					at.Character = 0
+					fix(fmt.Sprintf("Add documentation for %s", name.Name), d, TextEdit{
+						Range:   Range{Start: at, End: at},
+						NewText: fmt.Sprintf("%s// %s ...\n", indent, name.Name),
+					})
+				}
+			}
+		}
+
+		files := append([]*ast.File{file}, packageFiles(fset, path, file.Name.Name)...)
+		info := &types.Info{
+			Types: make(map[ast.Expr]types.TypeAndValue),
+			Defs:  make(map[*ast.Ident]types.Object),
+			Uses:  make(map[*ast.Ident]types.Object),
+		}
+		conf := types.Config{
+			Importer: importer.ForCompiler(fset, "source", nil),
+			Error:    func(error) {}, // Fix what can be fixed in code that does not compile
+		}
+		pkg, _ := conf.Check(file.Name.Name, fset, files, info)
+		qualifier := func(other *types.Package) string {
+			if other == pkg {
+				return ""
+			}
+			return importLocalName(file, other.Path())
+		}
+
+		for _, c := range uncheckedCalls(file, info) {
+			callText := content[offset(c.call.Pos()):offset(c.call.End())]
+			d := Diagnostic{
+				Severity: "warning",
+				Message:  fmt.Sprintf("Error returned by %s is not checked", content[offset(c.call.Fun.Pos()):offset(c.call.Fun.End())]),
+				Location: Location{
+					URI: req.URI,
+					Range: Range{
+						Start: positionAt(content, offset(c.call.Pos())),
+						End:   positionAt(content, offset(c.call.End())),
+					},
+				},
+				Code:   "unchecked-error",
+				Source: "go-analyzer",
+			}
+			if !touched(d) {
+				continue
+			}
+
+			stmt := Range{
+				Start: positionAt(content, offset(c.stmt.Pos())),
+				End:   positionAt(content, offset(c.stmt.End())),
+			}
+			blanks := strings.Repeat("_, ", c.results-1)
+			if results := c.enclosing.Results(); results.Len() > 0 && isError(results.At(results.Len()-1).Type()) {
+				indent := content[offset(c.stmt.Pos())-stmt.Start.Character : offset(c.stmt.Pos())]
+				if strings.TrimSpace(indent) != "" {
+					indent = ""
+				}
+				returned := make([]string, 0, results.Len())
+				for i := 0; i < results.Len()-1; i++ {
+					returned = append(returned, zeroValue(results.At(i).Type(), qualifier))
+				}
+				returned = append(returned, "err")
+				fix("Return the error", d, TextEdit{
+					Range: stmt,
+					NewText: fmt.Sprintf("if %serr := %s; err != nil {\n%s\treturn %s\n%s}",
+						blanks, callText, indent, strings.Join(returned, ", "), indent),
+				})
+			}
+			fix("Ignore the error explicitly", d, TextEdit{Range: stmt, NewText: blanks + "_ = " + callText})
+		}
+	}
+
+	if wanted(CodeActionOrganizeImports) {
+		organized, err := OrganizeImports(req.URI, content, imports)
+		if err == nil && len(organized.Edit.Changes[req.URI]) > 0 {
+			actions = append(actions, CodeAction{
+				Title: "Organize imports",
+				Kind:  CodeActionOrganizeImports,
+				Edit:  organized.Edit,
+			})
+		}
+	}
+
+	for i := range actions {
+		actions[i].Command = &Command{
+			Title:     actions[i].Title,
+			Command:   CommandApplyEdit,
+			Arguments: []interface{}{actions[i].Edit},
+		}
+	}
+	return actions, nil
+}
+
+// uncheckedCalls returns the call statements of file dropping an error
+// result, other than those in ignoredErrors
+func uncheckedCalls(file *ast.File, info *types.Info) []uncheckedCall {
+	var calls []uncheckedCall
+	var stack []ast.Node
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, n)
+
+		stmt, ok := n.(*ast.ExprStmt)
+		if !ok {
+			return true
+		}
+		call, ok := stmt.X.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		results := 1
+		last := info.TypeOf(call)
+		if tuple, ok := last.(*types.Tuple); ok {
+			results = tuple.Len()
+			if results == 0 {
+				return true
+			}
+			last = tuple.At(results - 1).Type()
+		}
+		if last == nil || !isError(last) || ignoredErrors[calleeName(info, call)] {
+			return true
+		}
+
+		// The innermost function declaration or literal encloses the call
+		for i := len(stack) - 1; i >= 0; i-- {
+			var sig types.Type
+			switch fn := stack[i].(type) {
+			case *ast.FuncDecl:
+				if obj := info.Defs[fn.Name]; obj != nil {
+					sig = obj.Type()
+				}
+			case *ast.FuncLit:
+				sig = info.TypeOf(fn)
+			default:
+				continue
+			}
+			if sig, ok := sig.(*types.Signature); ok {
+				calls = append(calls, uncheckedCall{stmt: stmt, call: call, results: results, enclosing: sig})
+			}
+			break
+		}
+		return true
+	})
+	return calls
+}
+
+// calleeName returns the full name of the function called, like
+// (*bytes.Buffer).Write, or "" for calls of function values
+func calleeName(info *types.Info, call *ast.CallExpr) string {
+	var ident *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return ""
+	}
+	if fn, ok := info.Uses[ident].(*types.Func); ok {
+		return fn.FullName()
+	}
+	return ""
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// zeroValue returns the source of the zero value of t
+func zeroValue(t types.Type, qualifier types.Qualifier) string {
+	if _, ok := t.(*types.TypeParam); ok {
+		return "*new(" + types.TypeString(t, qualifier) + ")"
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		case u.Info()&types.IsNumeric != 0:
+			return "0"
+		case u.Info()&types.IsString != 0:
+			return `""`
+		}
+	case *types.Struct, *types.Array:
+		return types.TypeString(t, qualifier) + "{}"
+	}
+	return "nil"
+}
+
+// removeImportEdit deletes an import spec, or its whole declaration when it
+// is the only spec in it
+func removeImportEdit(fset *token.FileSet, content string, gen *ast.GenDecl, imp *ast.ImportSpec) TextEdit {
+	var start, end int
+	if gen.Lparen.IsValid() && len(gen.Specs) > 1 {
+		// Delete the lines of the spec, including its comments
+		start = fset.Position(imp.Pos()).Offset
+		if imp.Doc != nil {
+			start = fset.Position(imp.Doc.Pos()).Offset
+		}
+		start = strings.LastIndex(content[:start], "\n") + 1
+		end = fset.Position(imp.End()).Offset
+		if imp.Comment != nil {
+			end = fset.Position(imp.Comment.End()).Offset
+		}
+		if i := strings.Index(content[end:], "\n"); i >= 0 {
+			end += i + 1
+		}
+	} else {
+		start = fset.Position(gen.Pos()).Offset
+		if gen.Doc != nil {
+			start = fset.Position(gen.Doc.Pos()).Offset
+		}
+		end = fset.Position(gen.End()).Offset
+		for end < len(content) && content[end] == '\n' {
+			end++
+		}
+	}
+	return TextEdit{
+		Range: Range{
+			Start: positionAt(content, start),
+			End:   positionAt(content, end),
+		},
+	}
+}
+
+// codeActions returns the code actions of a document, which falls back to
+// the open document's text
+func (ls *LanguageServer) codeActions(req CodeActionRequest) ([]CodeAction, error) {
+	if req.Content == "" {
+		text, err := ls.text(req.URI)
+		if err != nil {
+			return nil, err
+		}
+		req.Content = text
+	}
+
+	var opts ImportGroupingOptions
+	if mod := modulePath(ls.workspaceRoot); mod != "" {
+		opts.LocalPrefixes = []string{mod}
+	}
+	return CodeActions(ls.documentPath(req.URI), req, opts)
+}
+
+// applyEdit applies a workspace edit to files of the workspace and returns
+// the URIs it changed, in order
+func (ls *LanguageServer) applyEdit(edit WorkspaceEdit) ([]string, error) {
+	edits := make(map[string][]TextEdit)
+	applied := make([]string, 0, len(edit.Changes))
+	for uri, fileEdits := range edit.Changes {
+		path := ls.documentPath(uri)
+		if !inWorkspace(ls.workspaceRoot, path) {
+			return nil, fmt.Errorf("%s is %w", uri, errOutsideWorkspace)
+		}
+		edits[path] = fileEdits
+		applied = append(applied, uri)
+	}
+	sort.Strings(applied)
+
+	overlay, uris, _ := ls.overlay("")
+	if err := ls.applyFileEdits(edits, overlay, uris); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func handleCodeAction(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CodeActionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		actions, err := ls.codeActions(req)
+		switch {
+		case errors.Is(err, errDocumentNotFound):
+			writeError(w, http.StatusNotFound, err)
+			return
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, actions)
+	}
+}
+
+func handleExecuteCommand(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ExecuteCommandRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		edit, err := commandEdit(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		applied, err := ls.applyEdit(edit)
+		switch {
+		case errors.Is(err, errOutsideWorkspace):
+			writeError(w, http.StatusBadRequest, err)
+			return
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ExecuteCommandResult{Applied: applied})
+	}
+}
+
+// commandEdit returns the workspace edit of a CommandApplyEdit command
+func commandEdit(req ExecuteCommandRequest) (WorkspaceEdit, error) {
+	var edit WorkspaceEdit
+	if req.Command != CommandApplyEdit {
+		return edit, fmt.Errorf("unknown command %q", req.Command)
+	}
+	if len(req.Arguments) != 1 {
+		return edit, fmt.Errorf("%s takes a workspace edit", CommandApplyEdit)
+	}
+	if err := json.Unmarshal(req.Arguments[0], &edit); err != nil {
+		return edit, err
+	}
+	return edit, nil
+}
@@ -0,0 +1,135 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const codeActionSource = `package m
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func Remove(name string) (int, error) {
+	os.Remove(name)
+	return 0, nil
+}
+
+func cleanup(name string) {
+	os.Remove(name)
+	fmt.Println("removed", name)
+}
+`
+
+func TestCodeActions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "m.go")
+
+	actions, err := CodeActions(path, CodeActionRequest{URI: "m.go", Content: codeActionSource}, ImportGroupingOptions{})
+	require.NoError(t, err)
+	require.Len(t, actions, 6)
+	fixed := make(map[string]string)
+	for _, action := range actions {
+		require.NotNil(t, action.Command)
+		assert.Equal(t, CommandApplyEdit, action.Command.Command)
+		if _, ok := fixed[action.Title]; !ok {
+			fixed[action.Title] = applyEdits(codeActionSource, action.Edit.Changes["m.go"])
+		}
+	}
+
+	assert.Contains(t, fixed[`Remove import "strings"`], "import (\n\t\"fmt\"\n\t\"os\"\n)\n")
+	assert.Contains(t, fixed["Add documentation for Remove"], "\n// Remove ...\nfunc Remove(")
+	assert.Contains(t, fixed["Return the error"], "\tif err := os.Remove(name); err != nil {\n\t\treturn 0, err\n\t}\n\treturn 0, nil")
+	assert.Contains(t, fixed["Ignore the error explicitly"], "\t_ = os.Remove(name)\n\treturn 0, nil")
+	assert.Contains(t, fixed["Organize imports"], "import (\n\t\"fmt\"\n\t\"os\"\n)\n")
+
+	// The call in cleanup cannot return its error; fmt.Println is ignored
+	actions, err = CodeActions(path, CodeActionRequest{
+		URI: "m.go", Content: codeActionSource,
+		Range: &Range{Start: Position{Line: 14}, End: Position{Line: 15}},
+		Only:  []string{CodeActionQuickFix},
+	}, ImportGroupingOptions{})
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, "Ignore the error explicitly", actions[0].Title)
+	require.Len(t, actions[0].Diagnostics, 1)
+	assert.Equal(t, "unchecked-error", actions[0].Diagnostics[0].Code)
+	assert.Equal(t, "Error returned by os.Remove is not checked", actions[0].Diagnostics[0].Message)
+
+	actions, err = CodeActions(path, CodeActionRequest{URI: "m.go", Content: codeActionSource, Only: []string{"source"}}, ImportGroupingOptions{})
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, CodeActionOrganizeImports, actions[0].Kind)
+}
+
+func TestHandleCodeAction(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "m.go"), []byte(codeActionSource), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+
+	body, err := json.Marshal(TextDocumentItem{URI: "m.go", Text: codeActionSource})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/document/open", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/codeAction", strings.NewReader(`{"uri":"m.go","only":["source.organizeImports"]}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var actions []CodeAction
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&actions))
+	require.Len(t, actions, 1)
+
+	// Executing the command writes the file and updates the open document
+	body, err = json.Marshal(actions[0].Command)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/executeCommand", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result ExecuteCommandResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, []string{"m.go"}, result.Applied)
+
+	data, err := os.ReadFile(filepath.Join(dir, "m.go"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "strings")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/codeAction", strings.NewReader(`{"uri":"m.go","only":["source"]}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `[]`, w.Body.String())
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		code int
+	}{
+		{"closed document", "/v1/lsp/codeAction", `{"uri":"missing.go"}`, http.StatusNotFound},
+		{"unknown command", "/v1/lsp/executeCommand", `{"command":"mcp.unknown"}`, http.StatusBadRequest},
+		{"missing edit", "/v1/lsp/executeCommand", `{"command":"mcp.applyEdit"}`, http.StatusBadRequest},
+		{"outside workspace", "/v1/lsp/executeCommand", `{"command":"mcp.applyEdit","arguments":[{"changes":{"file:///etc/passwd":[]}}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+		})
+	}
+}
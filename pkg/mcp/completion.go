@@ -0,0 +1,515 @@
+package mcp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Completion item kinds, numbered as in the Language Server Protocol
+const (
+	CompletionKindMethod    = 2
+	CompletionKindFunction  = 3
+	CompletionKindField     = 5
+	CompletionKindVariable  = 6
+	CompletionKindClass     = 7
+	CompletionKindInterface = 8
+	CompletionKindModule    = 9
+	CompletionKindKeyword   = 14
+	CompletionKindConstant  = 21
+	CompletionKindStruct    = 22
+)
+
+// CompletionItem is a candidate for the identifier at a position. Items come
+// ranked; SortText preserves the rank for clients that sort themselves.
+type CompletionItem struct {
+	Label         string `json:"label"`
+	Kind          int    `json:"kind"`
+	Detail        string `json:"detail,omitempty"`
+	Documentation string `json:"documentation,omitempty"`
+	SortText      string `json:"sortText"`
+}
+
+// Candidate ranks, lower is better
+const (
+	rankLocal = iota
+	rankPackage
+	rankUniverse
+	rankKeyword
+)
+
+type completionCandidate struct {
+	item  CompletionItem
+	obj   types.Object
+	rank  int
+	exact bool // The prefix matches with its case
+}
+
+var goKeywords = []string{
+	"break", "case", "chan", "const", "continue", "default", "defer", "else",
+	"fallthrough", "for", "func", "go", "goto", "if", "import", "interface",
+	"map", "package", "range", "return", "select", "struct", "switch", "type", "var",
+}
+
+// Complete returns the completions for the identifier ending at pos in a Go
+// source file. After a selector it offers the members of a package or the
+// fields and methods of a value or type; elsewhere it offers the names in
+// scope at pos and the keywords. The file is type-checked together with the
+// other files of its package found next to path, and may be incomplete.
+func Complete(path, content string, pos Position) ([]CompletionItem, error) {
+	offset, err := offsetAt(content, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	start := offset
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(content[:start])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		start -= size
+	}
+	prefix := content[start:offset]
+	selector := start > 0 && content[start-1] == '.'
+	if selector && prefix == "" {
+		// A dangling selector does not parse; complete a placeholder instead
+		content = content[:offset] + "_" + content[offset:]
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if file == nil || file.Name.Name == "" {
+		return nil, fmt.Errorf("failed to parse document: %v", err)
+	}
+	files := append([]*ast.File{file}, packageFiles(fset, path, file.Name.Name)...)
+
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {}, // Incomplete code is the norm here
+	}
+	pkg, _ := conf.Check(file.Name.Name, fset, files, info)
+
+	at := fset.File(file.Pos()).Pos(start)
+	var candidates []completionCandidate
+	if selector {
+		candidates = selectorCandidates(pkg, info, file, at)
+	} else {
+		candidates = scopeCandidates(pkg, at)
+	}
+
+	docs := newDocFinder(fset, files)
+	items := make([]CompletionItem, 0, len(candidates))
+	for _, c := range rankCandidates(candidates, prefix) {
+		if c.obj != nil {
+			c.item.Documentation = docs.find(c.obj.Pos())
+		}
+		c.item.SortText = fmt.Sprintf("%05d", len(items))
+		items = append(items, c.item)
+	}
+	return items, nil
+}
+
+// packageFiles parses the other Go files of package name in the directory
+// of path, leaving out tests unless path is one
+func packageFiles(fset *token.FileSet, path, name string) []*ast.File {
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.go"))
+	test := strings.HasSuffix(path, "_test.go")
+
+	var files []*ast.File
+	for _, match := range matches {
+		if filepath.Clean(match) == filepath.Clean(path) || (!test && strings.HasSuffix(match, "_test.go")) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, match, nil, parser.ParseComments)
+		if err != nil || file.Name.Name != name {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// selectorCandidates returns the members of the operand of the selector
+// whose name starts at pos
+func selectorCandidates(pkg *types.Package, info *types.Info, file *ast.File, pos token.Pos) []completionCandidate {
+	var sel *ast.SelectorExpr
+	ast.Inspect(file, func(n ast.Node) bool {
+		if s, ok := n.(*ast.SelectorExpr); ok && s.Sel.Pos() == pos {
+			sel = s
+		}
+		return sel == nil
+	})
+	if sel == nil {
+		return nil
+	}
+
+	visible := func(obj types.Object) bool {
+		return obj.Exported() || obj.Pkg() == pkg
+	}
+
+	var candidates []completionCandidate
+	if ident, ok := sel.X.(*ast.Ident); ok {
+		if pkgName, ok := info.Uses[ident].(*types.PkgName); ok {
+			scope := pkgName.Imported().Scope()
+			for _, name := range scope.Names() {
+				if obj := scope.Lookup(name); obj.Exported() {
+					candidates = append(candidates, objectCandidate(obj, pkg, rankLocal))
+				}
+			}
+			return candidates
+		}
+	}
+
+	tv, ok := info.Types[sel.X]
+	if !ok || tv.Type == nil || tv.Type == types.Typ[types.Invalid] {
+		return nil
+	}
+	if !tv.IsType() {
+		for _, field := range fieldsOf(tv.Type) {
+			if visible(field) {
+				candidates = append(candidates, objectCandidate(field, pkg, rankLocal))
+			}
+		}
+	}
+	methods := types.NewMethodSet(tv.Type)
+	if _, isPtr := tv.Type.Underlying().(*types.Pointer); !isPtr && !types.IsInterface(tv.Type) {
+		// Values are assumed to be addressable, as most are
+		methods = types.NewMethodSet(types.NewPointer(tv.Type))
+	}
+	for i := 0; i < methods.Len(); i++ {
+		if method := methods.At(i).Obj(); visible(method) {
+			candidates = append(candidates, objectCandidate(method, pkg, rankLocal))
+		}
+	}
+	return candidates
+}
+
+// fieldsOf returns the fields of a struct type, or a pointer to one,
+// including the fields promoted from embedded structs
+func fieldsOf(t types.Type) []*types.Var {
+	var fields []*types.Var
+	seen := make(map[string]bool)
+	visited := make(map[types.Type]bool)
+	for level := []types.Type{t}; len(level) > 0; {
+		var next []types.Type
+		for _, t := range level {
+			if ptr, ok := t.Underlying().(*types.Pointer); ok {
+				t = ptr.Elem()
+			}
+			st, ok := t.Underlying().(*types.Struct)
+			if !ok || visited[t] {
+				continue
+			}
+			visited[t] = true
+			for i := 0; i < st.NumFields(); i++ {
+				field := st.Field(i)
+				if !seen[field.Name()] {
+					seen[field.Name()] = true
+					fields = append(fields, field)
+				}
+				if field.Embedded() {
+					next = append(next, field.Type())
+				}
+			}
+		}
+		level = next
+	}
+	return fields
+}
+
+// scopeCandidates returns the names in scope at pos, innermost first, and
+// the keywords
+func scopeCandidates(pkg *types.Package, pos token.Pos) []completionCandidate {
+	scope := pkg.Scope().Innermost(pos)
+	if scope == nil {
+		scope = pkg.Scope()
+	}
+
+	var candidates []completionCandidate
+	seen := make(map[string]bool)
+	for ; scope != nil; scope = scope.Parent() {
+		rank := rankLocal
+		switch {
+		case scope == types.Universe:
+			rank = rankUniverse
+		case scope == pkg.Scope() || scope.Parent() == pkg.Scope():
+			// The package scope and the file scopes holding the imports
+			rank = rankPackage
+		}
+		for _, name := range scope.Names() {
+			obj := scope.Lookup(name)
+			if name == "_" || seen[name] {
+				continue
+			}
+			if rank == rankLocal {
+				// Locals are only in scope once declared
+				if inner, _ := scope.LookupParent(name, pos); inner != scope {
+					continue
+				}
+			}
+			seen[name] = true
+			candidates = append(candidates, objectCandidate(obj, pkg, rank))
+		}
+	}
+	for _, keyword := range goKeywords {
+		candidates = append(candidates, completionCandidate{
+			item: CompletionItem{Label: keyword, Kind: CompletionKindKeyword},
+			rank: rankKeyword,
+		})
+	}
+	return candidates
+}
+
+func objectCandidate(obj types.Object, pkg *types.Package, rank int) completionCandidate {
+	item := CompletionItem{
+		Label:  obj.Name(),
+		Kind:   CompletionKindVariable,
+		Detail: types.ObjectString(obj, types.RelativeTo(pkg)),
+	}
+	switch obj := obj.(type) {
+	case *types.Func:
+		item.Kind = CompletionKindFunction
+		if obj.Type().(*types.Signature).Recv() != nil {
+			item.Kind = CompletionKindMethod
+		}
+	case *types.Builtin:
+		item.Kind = CompletionKindFunction
+		item.Detail = "builtin " + obj.Name()
+	case *types.Var:
+		if obj.IsField() {
+			item.Kind = CompletionKindField
+		}
+	case *types.Const, *types.Nil:
+		item.Kind = CompletionKindConstant
+	case *types.PkgName:
+		item.Kind = CompletionKindModule
+	case *types.TypeName:
+		switch obj.Type().Underlying().(type) {
+		case *types.Struct:
+			item.Kind = CompletionKindStruct
+		case *types.Interface:
+			item.Kind = CompletionKindInterface
+		default:
+			item.Kind = CompletionKindClass
+		}
+	}
+	return completionCandidate{item: item, obj: obj, rank: rank}
+}
+
+// rankCandidates keeps the candidates starting with prefix, ignoring case,
+// and orders them by case-sensitive match first, then by rank and name
+func rankCandidates(candidates []completionCandidate, prefix string) []completionCandidate {
+	var matched []completionCandidate
+	for _, c := range candidates {
+		if strings.HasPrefix(c.item.Label, prefix) {
+			c.exact = true
+		} else if !strings.HasPrefix(strings.ToLower(c.item.Label), strings.ToLower(prefix)) {
+			continue
+		}
+		matched = append(matched, c)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if a.exact != b.exact {
+			return a.exact
+		}
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		return a.item.Label < b.item.Label
+	})
+	return matched
+}
+
+// docFinder looks up the doc comments of declarations, parsing the files of
+// imported packages as they are needed
+type docFinder struct {
+	fset  *token.FileSet
+	files map[string]*ast.File
+}
+
+func newDocFinder(fset *token.FileSet, files []*ast.File) *docFinder {
+	d := &docFinder{fset: fset, files: make(map[string]*ast.File)}
+	for _, file := range files {
+		d.files[fset.File(file.Pos()).Name()] = file
+	}
+	return d
+}
+
+// find returns the doc comment of the declaration of the identifier at pos
+func (d *docFinder) find(pos token.Pos) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	position := d.fset.Position(pos)
+	file, ok := d.files[position.Filename]
+	if !ok {
+		file, _ = parser.ParseFile(d.fset, position.Filename, nil, parser.ParseComments)
+		d.files[position.Filename] = file
+	}
+	if file == nil {
+		return ""
+	}
+
+	at := func(ident *ast.Ident) bool {
+		return d.fset.Position(ident.Pos()).Offset == position.Offset
+	}
+	var doc *ast.CommentGroup
+	found := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if at(n.Name) {
+				doc, found = n.Doc, true
+			}
+		case *ast.GenDecl:
+			for _, spec := range n.Specs {
+				var names []*ast.Ident
+				var specDoc, comment *ast.CommentGroup
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names, specDoc, comment = []*ast.Ident{spec.Name}, spec.Doc, spec.Comment
+				case *ast.ValueSpec:
+					names, specDoc, comment = spec.Names, spec.Doc, spec.Comment
+				}
+				for _, name := range names {
+					if !at(name) {
+						continue
+					}
+					switch {
+					case specDoc != nil:
+						doc = specDoc
+					case !n.Lparen.IsValid():
+						doc = n.Doc
+					default:
+						doc = comment
+					}
+					found = true
+				}
+			}
+		case *ast.Field:
+			names := n.Names
+			if len(names) == 0 {
+				names = embeddedName(n.Type)
+			}
+			for _, name := range names {
+				if at(name) {
+					doc, found = n.Doc, true
+					if doc == nil {
+						doc = n.Comment
+					}
+				}
+			}
+		}
+		return !found
+	})
+	return strings.TrimSpace(doc.Text())
+}
+
+// embeddedName returns the name of the type of an embedded field
+func embeddedName(expr ast.Expr) []*ast.Ident {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return []*ast.Ident{expr}
+	case *ast.StarExpr:
+		return embeddedName(expr.X)
+	case *ast.SelectorExpr:
+		return []*ast.Ident{expr.Sel}
+	}
+	return nil
+}
+
+// offsetAt converts a zero-based Position to a byte offset in content
+func offsetAt(content string, pos Position) (int, error) {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		i := strings.IndexByte(content[offset:], '\n')
+		if i < 0 {
+			return 0, fmt.Errorf("line %d is beyond the end of the document", pos.Line)
+		}
+		offset += i + 1
+	}
+	end := strings.IndexByte(content[offset:], '\n')
+	if end < 0 {
+		end = len(content) - offset
+	}
+	if pos.Character < 0 || pos.Character > end {
+		return 0, fmt.Errorf("character %d is beyond the end of line %d", pos.Character, pos.Line)
+	}
+	return offset + pos.Character, nil
+}
+
+// documentPath returns the absolute path of the file an open document was
+// read from, resolving file URIs and paths relative to the workspace
+func (ls *LanguageServer) documentPath(uri string) string {
+	path := filepath.FromSlash(strings.TrimPrefix(uri, "file://"))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ls.workspaceRoot, path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path
+}
+
+// positionParams reads the document and the zero-based line and character
+// of a position from the query of r
+func positionParams(r *http.Request) (string, Position, error) {
+	query := r.URL.Query()
+	uri := query.Get("uri")
+	if uri == "" {
+		return "", Position{}, fmt.Errorf("uri parameter is required")
+	}
+	var pos Position
+	var err error
+	if pos.Line, err = strconv.Atoi(query.Get("line")); err != nil || pos.Line < 0 {
+		return "", Position{}, fmt.Errorf("line must be a non-negative number")
+	}
+	if pos.Character, err = strconv.Atoi(query.Get("character")); err != nil || pos.Character < 0 {
+		return "", Position{}, fmt.Errorf("character must be a non-negative number")
+	}
+	return uri, pos, nil
+}
+
+func handleCompletion(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uri, pos, err := positionParams(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		ls.mu.RLock()
+		doc, exists := ls.documents[uri]
+		ls.mu.RUnlock()
+
+		if !exists {
+			writeError(w, http.StatusNotFound, fmt.Errorf("document not found"))
+			return
+		}
+
+		items, err := Complete(ls.documentPath(uri), doc.Text, pos)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, items)
+	}
+}
@@ -0,0 +1,183 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// completeAt completes at the cursor marked with | in src
+func completeAt(t *testing.T, path, src string) []CompletionItem {
+	t.Helper()
+	offset := strings.Index(src, "|")
+	require.GreaterOrEqual(t, offset, 0)
+	src = src[:offset] + src[offset+1:]
+	items, err := Complete(path, src, positionAt(src, offset))
+	require.NoError(t, err)
+	return items
+}
+
+func labels(items []CompletionItem) []string {
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	return labels
+}
+
+func findItem(items []CompletionItem, label string) *CompletionItem {
+	for i := range items {
+		if items[i].Label == label {
+			return &items[i]
+		}
+	}
+	return nil
+}
+
+func TestComplete_Package(t *testing.T) {
+	items := completeAt(t, "example.go", `package example
+
+import "strings"
+
+func f() {
+	strings.To|
+}
+`)
+	assert.Equal(t, []string{"ToLower", "ToLowerSpecial", "ToTitle", "ToTitleSpecial", "ToUpper", "ToUpperSpecial", "ToValidUTF8"}, labels(items))
+	lower := findItem(items, "ToLower")
+	assert.Equal(t, CompletionKindFunction, lower.Kind)
+	assert.Equal(t, "func strings.ToLower(s string) string", lower.Detail)
+	assert.Contains(t, lower.Documentation, "ToLower returns s with all Unicode letters mapped to their lower case.")
+	assert.Equal(t, "00000", lower.SortText)
+}
+
+func TestComplete_Selector(t *testing.T) {
+	src := `package example
+
+type Base struct {
+	// ID identifies it
+	ID string
+}
+
+func (b *Base) Key() string { return b.ID }
+
+type item struct {
+	Base // Base is embedded
+	name  string
+	count int
+}
+
+func (i item) Len() int { return i.count }
+
+func f(it item) {
+	it.|
+}
+`
+	items := completeAt(t, "example.go", src)
+	assert.Equal(t, []string{"Base", "ID", "Key", "Len", "count", "name"}, labels(items))
+	id := findItem(items, "ID")
+	assert.Equal(t, CompletionKindField, id.Kind)
+	assert.Equal(t, "ID identifies it", id.Documentation)
+	assert.Equal(t, CompletionKindMethod, findItem(items, "Key").Kind)
+
+	items = completeAt(t, "example.go", strings.Replace(src, "it.|", "it.Ba|", 1))
+	assert.Equal(t, []string{"Base"}, labels(items))
+	assert.Equal(t, "Base is embedded", items[0].Documentation)
+}
+
+func TestComplete_Scope(t *testing.T) {
+	items := completeAt(t, "example.go", `package example
+
+import "fmt"
+
+const limit = 3
+
+// format formats
+func format(v int) string { return fmt.Sprint(v) }
+
+func f(values []int) {
+	for _, value := range values {
+		fo|
+	}
+	found := true
+	_ = found
+}
+`)
+	assert.Equal(t, []string{"format", "for"}, labels(items))
+	assert.Equal(t, "format formats", items[0].Documentation)
+	assert.Equal(t, CompletionKindKeyword, items[1].Kind)
+
+	items = completeAt(t, "example.go", `package example
+
+func f(values []int) {
+	for _, value := range values {
+		|
+	}
+}
+`)
+	got := labels(items)
+	// Locals, then the package, the universe and the keywords
+	assert.Equal(t, []string{"value", "values", "f"}, got[:3])
+	assert.Contains(t, got, "len")
+	assert.Contains(t, got, "return")
+	assert.Less(t, indexOf(got, "len"), indexOf(got, "return"))
+}
+
+func TestComplete_PackageFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "types.go"), []byte(`package example
+
+// Config configures
+type Config struct{ Verbose bool }
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.go"), []byte("package other\n\nvar Hidden int\n"), 0644))
+
+	items := completeAt(t, filepath.Join(dir, "main.go"), `package example
+
+func f(c Config) bool {
+	return c.|
+}
+`)
+	assert.Equal(t, []string{"Verbose"}, labels(items))
+}
+
+func TestHandleCompletion(t *testing.T) {
+	s := NewServer(nil)
+	s.AddLanguageServerHandler()
+
+	body, err := json.Marshal(TextDocumentItem{URI: "example.go", Text: "package example\n\nimport \"os\"\n\nfunc f() {\n\tos.Exi\n}\n"})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/document/open", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/completion?uri=example.go&line=5&character=7", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var items []CompletionItem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&items))
+	assert.Equal(t, []string{"Exit"}, labels(items))
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/completion?uri=example.go&line=50&character=0", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/completion?uri=missing.go&line=0&character=0", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
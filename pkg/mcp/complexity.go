@@ -0,0 +1,347 @@
+package mcp
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default complexity thresholds of functions
+const (
+	DefaultCyclomaticThreshold = 15
+	DefaultCognitiveThreshold  = 15
+	DefaultLinesThreshold      = 80
+)
+
+// Complexity thresholds a function can exceed
+const (
+	ThresholdCyclomatic = "cyclomatic"
+	ThresholdCognitive  = "cognitive"
+	ThresholdLines      = "lines"
+)
+
+// ComplexityConfig sets the thresholds above which functions are too
+// complex, and the budgets of packages. Thresholds left to 0 take their
+// default.
+type ComplexityConfig struct {
+	Cyclomatic int                `yaml:"cyclomatic"`
+	Cognitive  int                `yaml:"cognitive"`
+	Lines      int                `yaml:"lines"`
+	Budgets    []ComplexityBudget `yaml:"budgets"` // The first matching a package applies
+}
+
+// ComplexityBudget bounds the functions above the thresholds in the
+// packages matching Package, a directory relative to the workspace root in
+// which ... matches any string, like pkg/legacy/.... Thresholds left to 0
+// are those of the config, and so is a MaxComplexity of 0 unlimited.
+type ComplexityBudget struct {
+	Package       string `yaml:"package" json:"package"`
+	Cyclomatic    int    `yaml:"cyclomatic" json:"cyclomatic,omitempty"`
+	Cognitive     int    `yaml:"cognitive" json:"cognitive,omitempty"`
+	Lines         int    `yaml:"lines" json:"lines,omitempty"`
+	MaxViolations int    `yaml:"max_violations" json:"max_violations"` // Functions allowed above the thresholds
+	MaxComplexity int    `yaml:"max_complexity" json:"max_complexity,omitempty"`
+}
+
+// Validate checks the complexity configuration for obvious mistakes
+func (c ComplexityConfig) Validate() error {
+	if c.Cyclomatic < 0 || c.Cognitive < 0 || c.Lines < 0 {
+		return fmt.Errorf("thresholds must not be negative")
+	}
+	for _, budget := range c.Budgets {
+		if budget.Package == "" {
+			return fmt.Errorf("budget without package")
+		}
+		if budget.Cyclomatic < 0 || budget.Cognitive < 0 || budget.Lines < 0 || budget.MaxViolations < 0 || budget.MaxComplexity < 0 {
+			return fmt.Errorf("budget %s: limits must not be negative", budget.Package)
+		}
+	}
+	return nil
+}
+
+// ComplexityThresholds are the limits a function is checked against
+type ComplexityThresholds struct {
+	Cyclomatic int `json:"cyclomatic"`
+	Cognitive  int `json:"cognitive"`
+	Lines      int `json:"lines"`
+}
+
+// thresholds returns the thresholds of the config, defaults filled in
+func (c ComplexityConfig) thresholds() ComplexityThresholds {
+	t := ComplexityThresholds{Cyclomatic: c.Cyclomatic, Cognitive: c.Cognitive, Lines: c.Lines}
+	if t.Cyclomatic == 0 {
+		t.Cyclomatic = DefaultCyclomaticThreshold
+	}
+	if t.Cognitive == 0 {
+		t.Cognitive = DefaultCognitiveThreshold
+	}
+	if t.Lines == 0 {
+		t.Lines = DefaultLinesThreshold
+	}
+	return t
+}
+
+// FunctionComplexity is the complexity and size of a function, with the
+// thresholds it exceeds
+type FunctionComplexity struct {
+	Name                string   `json:"name"`
+	Receiver            string   `json:"receiver,omitempty"`
+	Package             string   `json:"package"`  // Directory relative to the workspace root
+	Location            Location `json:"location"` // Relative to the workspace root
+	Complexity          int      `json:"complexity"`
+	CognitiveComplexity int      `json:"cognitive_complexity"`
+	Lines               int      `json:"lines"`
+	Exceeds             []string `json:"exceeds,omitempty"` // cyclomatic, cognitive or lines
+}
+
+// PackageComplexity sums up the functions of a package against its budget
+type PackageComplexity struct {
+	Package    string            `json:"package"`
+	Functions  int               `json:"functions"`
+	Violations int               `json:"violations"` // Functions above the thresholds
+	Complexity int               `json:"complexity"` // Sum of the cyclomatic complexity of functions
+	Budget     *ComplexityBudget `json:"budget,omitempty"`
+	OverBudget bool              `json:"over_budget"`
+}
+
+// ComplexityReport ranks the functions of the workspace by complexity,
+// reporting those above the thresholds and the packages over budget
+type ComplexityReport struct {
+	Thresholds  ComplexityThresholds `json:"thresholds"`
+	Functions   []FunctionComplexity `json:"functions"`
+	Packages    []PackageComplexity  `json:"packages"`
+	Diagnostics []Diagnostic         `json:"diagnostics"` // high-complexity warnings
+	Passed      bool                 `json:"passed"`      // Whether no package is over budget
+}
+
+// BuildComplexityReport measures the functions of the Go files under scope
+// in the workspace root
+func BuildComplexityReport(root, scope string, config ComplexityConfig, includeTests bool) (*ComplexityReport, error) {
+	files, err := goFilesInScope(DiagnosticScope{Root: root, Path: scope})
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := config.thresholds()
+	budgets := make([]ComplexityBudget, len(config.Budgets))
+	for i, budget := range config.Budgets {
+		budgets[i] = budget
+		budgets[i].Cyclomatic = thresholdOr(budget.Cyclomatic, defaults.Cyclomatic)
+		budgets[i].Cognitive = thresholdOr(budget.Cognitive, defaults.Cognitive)
+		budgets[i].Lines = thresholdOr(budget.Lines, defaults.Lines)
+	}
+	matchers := make([]func(string) bool, len(budgets))
+	for i, budget := range budgets {
+		matchers[i] = packagePatternRegexp(path.Clean(budget.Package), ".").MatchString
+	}
+
+	report := &ComplexityReport{
+		Thresholds:  defaults,
+		Functions:   make([]FunctionComplexity, 0),
+		Packages:    make([]PackageComplexity, 0),
+		Diagnostics: make([]Diagnostic, 0),
+		Passed:      true,
+	}
+	packageIndex := make(map[string]int)
+
+	for _, rel := range files {
+		if !includeTests && strings.HasSuffix(rel, "_test.go") {
+			continue
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, filepath.Join(root, rel), nil, 0)
+		if err != nil {
+			continue
+		}
+
+		uri := filepath.ToSlash(rel)
+		dir := path.Dir(uri)
+		i, ok := packageIndex[dir]
+		if !ok {
+			i = len(report.Packages)
+			packageIndex[dir] = i
+			pkg := PackageComplexity{Package: dir}
+			for j, match := range matchers {
+				if match(dir) {
+					pkg.Budget = &budgets[j]
+					break
+				}
+			}
+			report.Packages = append(report.Packages, pkg)
+		}
+		pkg := &report.Packages[i]
+		thresholds := defaults
+		if pkg.Budget != nil {
+			thresholds = ComplexityThresholds{Cyclomatic: pkg.Budget.Cyclomatic, Cognitive: pkg.Budget.Cognitive, Lines: pkg.Budget.Lines}
+		}
+
+		for _, fn := range NewASTAnalyzer(fset).analyzeFunctions(file) {
+			fn.Location.URI = uri
+			f := FunctionComplexity{
+				Name:                fn.Name,
+				Receiver:            fn.Receiver,
+				Package:             dir,
+				Location:            fn.Location,
+				Complexity:          fn.Complexity,
+				CognitiveComplexity: fn.CognitiveComplexity,
+				Lines:               fn.Location.Range.End.Line - fn.Location.Range.Start.Line + 1,
+			}
+			var exceeded []string
+			if f.Complexity > thresholds.Cyclomatic {
+				f.Exceeds = append(f.Exceeds, ThresholdCyclomatic)
+				exceeded = append(exceeded, fmt.Sprintf("cyclomatic complexity %d > %d", f.Complexity, thresholds.Cyclomatic))
+			}
+			if f.CognitiveComplexity > thresholds.Cognitive {
+				f.Exceeds = append(f.Exceeds, ThresholdCognitive)
+				exceeded = append(exceeded, fmt.Sprintf("cognitive complexity %d > %d", f.CognitiveComplexity, thresholds.Cognitive))
+			}
+			if f.Lines > thresholds.Lines {
+				f.Exceeds = append(f.Exceeds, ThresholdLines)
+				exceeded = append(exceeded, fmt.Sprintf("%d lines > %d", f.Lines, thresholds.Lines))
+			}
+
+			pkg.Functions++
+			pkg.Complexity += f.Complexity
+			if len(exceeded) > 0 {
+				pkg.Violations++
+				report.Diagnostics = append(report.Diagnostics, Diagnostic{
+					Severity: "warning",
+					Message:  fmt.Sprintf("Function %s is too complex: %s", functionName(f.Receiver, f.Name), strings.Join(exceeded, ", ")),
+					Location: fn.Location,
+					Code:     "high-complexity",
+					Source:   SourceAnalyzer,
+				})
+			}
+			report.Functions = append(report.Functions, f)
+		}
+	}
+
+	for i := range report.Packages {
+		pkg := &report.Packages[i]
+		if pkg.Budget == nil {
+			continue
+		}
+		pkg.OverBudget = pkg.Violations > pkg.Budget.MaxViolations ||
+			(pkg.Budget.MaxComplexity > 0 && pkg.Complexity > pkg.Budget.MaxComplexity)
+		if pkg.OverBudget {
+			report.Passed = false
+		}
+	}
+
+	sort.Slice(report.Packages, func(i, j int) bool {
+		return report.Packages[i].Package < report.Packages[j].Package
+	})
+	sort.SliceStable(report.Diagnostics, func(i, j int) bool {
+		a, b := report.Diagnostics[i].Location, report.Diagnostics[j].Location
+		if a.URI != b.URI {
+			return a.URI < b.URI
+		}
+		return positionBefore(a.Range.Start, b.Range.Start)
+	})
+	return report, nil
+}
+
+// sortFunctionComplexity ranks functions from the highest value of by,
+// one of complexity, cognitive or lines
+func sortFunctionComplexity(functions []FunctionComplexity, by string) {
+	key := func(f FunctionComplexity) int {
+		switch by {
+		case ThresholdCognitive:
+			return f.CognitiveComplexity
+		case ThresholdLines:
+			return f.Lines
+		}
+		return f.Complexity
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		a, b := functions[i], functions[j]
+		if key(a) != key(b) {
+			return key(a) > key(b)
+		}
+		if a.Location.URI != b.Location.URI {
+			return a.Location.URI < b.Location.URI
+		}
+		return positionBefore(a.Location.Range.Start, b.Location.Range.Start)
+	})
+}
+
+func thresholdOr(threshold, fallback int) int {
+	if threshold == 0 {
+		return fallback
+	}
+	return threshold
+}
+
+func functionName(receiver, name string) string {
+	if receiver == "" {
+		return name
+	}
+	return "(" + receiver + ")." + name
+}
+
+func handleComplexity(root string, config ComplexityConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := config // Thresholds are overridden per request
+		q := r.URL.Query()
+		path := q.Get("path")
+		if path != "" && !isWithinRoot(root, path) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("path %s is outside the workspace", path))
+			return
+		}
+
+		limit := 20
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %s", v))
+				return
+			}
+			limit = n
+		}
+		sortBy := q.Get("sort")
+		if sortBy == "" {
+			sortBy = "complexity"
+		}
+		if sortBy != "complexity" && sortBy != ThresholdCognitive && sortBy != ThresholdLines {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid sort: %s", sortBy))
+			return
+		}
+		for name, threshold := range map[string]*int{
+			ThresholdCyclomatic: &config.Cyclomatic,
+			ThresholdCognitive:  &config.Cognitive,
+			ThresholdLines:      &config.Lines,
+		} {
+			if v := q.Get(name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 {
+					writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s threshold: %s", name, v))
+					return
+				}
+				*threshold = n
+			}
+		}
+
+		report, err := BuildComplexityReport(root, path, config, q.Get("include_tests") == "true")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		sortFunctionComplexity(report.Functions, sortBy)
+		if len(report.Functions) > limit {
+			report.Functions = report.Functions[:limit]
+		}
+
+		status := http.StatusOK
+		if !report.Passed && q.Get("fail") == "true" {
+			status = http.StatusUnprocessableEntity
+		}
+		writeJSON(w, status, report)
+	}
+}
@@ -0,0 +1,145 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const complexitySource = `package %s
+
+func Simple() int {
+	return 1
+}
+
+func Branches(a, b, c int) int {
+	if a > 0 {
+		return 1
+	}
+	if b > 0 {
+		return 2
+	}
+	if c > 0 {
+		return 3
+	}
+	return 0
+}
+`
+
+func TestHandleComplexity(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "legacy"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, name), 0755))
+		src := fmt.Sprintf(complexitySource, name)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name, name+".go"), []byte(src), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a", "a_test.go"), []byte("package a\n\nfunc helper() {}\n"), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	cfg.Analysis.Complexity = ComplexityConfig{
+		Cyclomatic: 3,
+		Budgets: []ComplexityBudget{
+			{Package: "legacy/...", MaxViolations: 1},
+			{Package: "./a", MaxViolations: 0, MaxComplexity: 10},
+		},
+	}
+	require.NoError(t, cfg.Analysis.Validate())
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddAnalysisHandler()
+
+	analyze := func(query string, status int) *ComplexityReport {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/complexity?"+query, nil))
+		require.Equal(t, status, w.Code, w.Body.String())
+		var report ComplexityReport
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		return &report
+	}
+
+	report := analyze("", http.StatusOK)
+	assert.Equal(t, ComplexityThresholds{Cyclomatic: 3, Cognitive: DefaultCognitiveThreshold, Lines: DefaultLinesThreshold}, report.Thresholds)
+	require.Len(t, report.Functions, 4)
+	assert.Equal(t, FunctionComplexity{
+		Name:                "Branches",
+		Package:             "a",
+		Location:            Location{URI: "a/a.go", Range: Range{Start: Position{Line: 6}, End: Position{Line: 17, Character: 1}}},
+		Complexity:          4,
+		CognitiveComplexity: 3,
+		Lines:               12,
+		Exceeds:             []string{ThresholdCyclomatic},
+	}, report.Functions[0])
+	assert.Equal(t, "legacy/legacy.go", report.Functions[1].Location.URI)
+	assert.Equal(t, "Simple", report.Functions[2].Name)
+
+	require.Len(t, report.Diagnostics, 2)
+	assert.Equal(t, Diagnostic{
+		Severity: "warning",
+		Message:  "Function Branches is too complex: cyclomatic complexity 4 > 3",
+		Location: report.Functions[0].Location,
+		Code:     "high-complexity",
+		Source:   SourceAnalyzer,
+	}, report.Diagnostics[0])
+
+	// The violation of a is over its budget, that of legacy is not
+	require.Len(t, report.Packages, 2)
+	assert.Equal(t, "a", report.Packages[0].Package)
+	assert.Equal(t, 1, report.Packages[0].Violations)
+	assert.Equal(t, 5, report.Packages[0].Complexity)
+	assert.True(t, report.Packages[0].OverBudget)
+	assert.False(t, report.Packages[1].OverBudget)
+	assert.False(t, report.Passed)
+	analyze("fail=true", http.StatusUnprocessableEntity)
+
+	// Thresholds of the request apply to packages without their own
+	report = analyze("cyclomatic=5&limit=1&sort=lines&fail=true&include_tests=true", http.StatusOK)
+	assert.True(t, report.Passed)
+	require.Len(t, report.Functions, 1)
+	assert.Equal(t, 12, report.Functions[0].Lines)
+	assert.Empty(t, report.Diagnostics)
+	assert.Equal(t, 3, report.Packages[0].Functions)
+
+	report = analyze("path=legacy", http.StatusOK)
+	require.Len(t, report.Packages, 1)
+	assert.Equal(t, "legacy", report.Packages[0].Package)
+
+	for _, query := range []string{"path=../outside", "limit=0", "sort=name", "lines=many", "path=missing"} {
+		t.Run(query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/complexity?"+query, nil))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestComplexityConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ComplexityConfig
+		valid  bool
+	}{
+		{"defaults", ComplexityConfig{}, true},
+		{"budgets", ComplexityConfig{Lines: 60, Budgets: []ComplexityBudget{{Package: "pkg/...", MaxViolations: 3}}}, true},
+		{"negative threshold", ComplexityConfig{Cognitive: -1}, false},
+		{"budget without package", ComplexityConfig{Budgets: []ComplexityBudget{{MaxViolations: 1}}}, false},
+		{"negative budget", ComplexityConfig{Budgets: []ComplexityBudget{{Package: ".", MaxComplexity: -1}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
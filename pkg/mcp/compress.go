@@ -0,0 +1,285 @@
+package mcp
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported content encodings, in order of preference
+const (
+	EncodingZstd = "zstd"
+	EncodingGzip = "gzip"
+)
+
+// defaultCompressionMinSize is the smallest response worth compressing
+const defaultCompressionMinSize = 1024
+
+// CompressionConfig configures response compression
+type CompressionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	MinSize int  `yaml:"min_size"` // Responses smaller than this many bytes are sent as is
+}
+
+var (
+	gzipWriters = sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}}
+	zstdWriters = sync.Pool{New: func() interface{} {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return w
+	}}
+)
+
+// Compressor compresses responses with the best encoding the client accepts
+type Compressor struct {
+	minSize int
+}
+
+// NewCompressor creates a compressor from the configuration
+func NewCompressor(config CompressionConfig) *Compressor {
+	if config.MinSize <= 0 {
+		config.MinSize = defaultCompressionMinSize
+	}
+	return &Compressor{minSize: config.MinSize}
+}
+
+// Handler wraps next, compressing responses when the client accepts zstd or
+// gzip. Small responses, streams, already encoded and incompressible content
+// are passed through unchanged.
+func (c *Compressor) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: c.minSize}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks the supported encoding with the highest quality
+// value in an Accept-Encoding header, preferring zstd on ties
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	wildcard := -1.0
+	quality := make(map[string]float64)
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			wildcard = q
+		} else {
+			quality[name] = q
+		}
+	}
+
+	for _, encoding := range []string{EncodingZstd, EncodingGzip} {
+		q, ok := quality[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// response is worth compressing, then either compresses or passes it through
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	// Informational responses go straight through
+	if status >= 100 && status < 200 {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+	if !cw.compressible() {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < cw.minSize {
+			return len(p), nil
+		}
+		if err := cw.start(cw.compressible()); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if cw.enc != nil {
+		return cw.enc.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// compressible reports whether the response may be compressed based on its
+// status and headers
+func (cw *compressWriter) compressible() bool {
+	if cw.status == http.StatusNoContent || cw.status == http.StatusNotModified {
+		return false
+	}
+
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	contentType := strings.ToLower(h.Get("Content-Type"))
+	for _, prefix := range []string{"text/event-stream", "image/", "video/", "audio/", "application/zip", "application/gzip", "application/zstd"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// start sends the headers and buffered data, compressing from now on if asked
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+
+	if compress {
+		h := cw.Header()
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoding)
+
+		switch cw.encoding {
+		case EncodingZstd:
+			enc := zstdWriters.Get().(*zstd.Encoder)
+			enc.Reset(cw.ResponseWriter)
+			cw.enc = enc
+		case EncodingGzip:
+			enc := gzipWriters.Get().(*gzip.Writer)
+			enc.Reset(cw.ResponseWriter)
+			cw.enc = enc
+		}
+	}
+
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends buffered data to the client. A response flushed before
+// reaching the minimum size is not compressed.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.start(len(cw.buf) >= cw.minSize && cw.compressible())
+	}
+	if f, ok := cw.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the response and returns the encoder to its pool
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// Nothing was written; let net/http send its default response
+			cw.decided = true
+			return nil
+		}
+		cw.start(false)
+	}
+	if cw.enc == nil {
+		return nil
+	}
+
+	err := cw.enc.Close()
+	switch enc := cw.enc.(type) {
+	case *zstd.Encoder:
+		enc.Reset(nil)
+		zstdWriters.Put(enc)
+	case *gzip.Writer:
+		enc.Reset(nil)
+		gzipWriters.Put(enc)
+	}
+	cw.enc = nil
+	return err
+}
+
+// Hijack lets protocol upgrades such as WebSockets take over the connection
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	cw.decided = true
+	return hj.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	assert.Equal(t, EncodingZstd, negotiateEncoding("gzip, deflate, br, zstd"))
+	assert.Equal(t, EncodingGzip, negotiateEncoding("gzip, deflate"))
+	assert.Equal(t, EncodingGzip, negotiateEncoding("zstd;q=0.5, gzip;q=0.8"))
+	assert.Equal(t, EncodingGzip, negotiateEncoding("zstd;q=0, *"))
+	assert.Equal(t, "", negotiateEncoding("identity"))
+	assert.Equal(t, "", negotiateEncoding(""))
+}
+
+func TestCompressor(t *testing.T) {
+	large := strings.Repeat(`{"name":"context","type":"openapi"},`, 200)
+	handler := NewCompressor(CompressionConfig{}).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			writeJSON(w, http.StatusOK, large)
+		case "/small":
+			writeJSON(w, http.StatusOK, "ok")
+		case "/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, large)
+		}
+	}))
+
+	serve := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/large", "zstd")
+	require.Equal(t, EncodingZstd, rec.Header().Get("Content-Encoding"))
+	dec, err := zstd.NewReader(rec.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(dec)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `{\"name\":\"context\"`)
+	assert.Less(t, rec.Body.Len(), len(body))
+
+	rec = serve("/large", "gzip")
+	require.Equal(t, EncodingGzip, rec.Header().Get("Content-Encoding"))
+	gz, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	body, err = io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Contains(t, string(body), "openapi")
+
+	rec = serve("/small", "gzip")
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Contains(t, rec.Body.String(), "ok")
+	assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+
+	rec = serve("/events", "gzip")
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, rec.Body.String())
+}
@@ -0,0 +1,489 @@
+package mcp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// syncLocks are the types of sync that must not be copied after first use
+var syncLocks = map[string]bool{
+	"Mutex":     true,
+	"RWMutex":   true,
+	"WaitGroup": true,
+	"Once":      true,
+	"Cond":      true,
+}
+
+// concurrencyChecker checks a file for concurrency mistakes. The types of
+// sync are not loaded for files analyzed on their own; the declarations of
+// the file then tell which variables and fields have them.
+type concurrencyChecker struct {
+	a           *ASTAnalyzer
+	syncName    string                 // Name sync is imported as, if it is
+	declared    map[token.Pos]ast.Expr // Types of the variables, parameters and fields declared, by position
+	diagnostics []Diagnostic
+}
+
+// concurrencyDiagnostics reports common concurrency mistakes: locks of sync
+// copied by value by receivers, parameters, range variables and
+// assignments, maps written by goroutines that take no lock, WaitGroup.Add
+// called inside the goroutines waited for, which may run after Wait, and
+// sends on local channels that may be nil, which block forever.
+func (a *ASTAnalyzer) concurrencyDiagnostics(file *ast.File) []Diagnostic {
+	c := &concurrencyChecker{a: a, declared: make(map[token.Pos]ast.Expr)}
+	for _, imp := range file.Imports {
+		if importPath(imp) == "sync" {
+			c.syncName = importLocalName(file, "sync")
+		}
+	}
+	c.collectDeclarations(file)
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			c.checkSignature(n)
+			if n.Body != nil {
+				c.checkNilChannelSends(n.Body)
+			}
+		case *ast.RangeStmt:
+			if ident, ok := n.Value.(*ast.Ident); ok && ident.Name != "_" {
+				if lock := c.lockOf(ident); lock != "" {
+					c.report(ident, "copied-lock", "Range variable %s copies a sync.%s by value", ident.Name, lock)
+				}
+			}
+		case *ast.AssignStmt:
+			if len(n.Lhs) == len(n.Rhs) {
+				c.checkCopies(n.Lhs, n.Rhs)
+			}
+		case *ast.ValueSpec:
+			if len(n.Names) == len(n.Values) {
+				lhs := make([]ast.Expr, len(n.Names))
+				for i, name := range n.Names {
+					lhs[i] = name
+				}
+				c.checkCopies(lhs, n.Values)
+			}
+		case *ast.GoStmt:
+			if lit, ok := ast.Unparen(n.Call.Fun).(*ast.FuncLit); ok {
+				c.checkGoroutine(lit)
+			}
+		}
+		return true
+	})
+	return c.diagnostics
+}
+
+func (c *concurrencyChecker) report(node ast.Node, code, format string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		Severity: "warning",
+		Message:  fmt.Sprintf(format, args...),
+		Location: c.a.nodeLocation(node),
+		Code:     code,
+		Source:   SourceAnalyzer,
+	})
+}
+
+// collectDeclarations records the type expressions of the fields,
+// parameters and variables of the file, those of variables declared with a
+// composite literal included
+func (c *concurrencyChecker) collectDeclarations(file *ast.File) {
+	literalType := func(value ast.Expr) ast.Expr {
+		if unary, ok := value.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			if lit, ok := unary.X.(*ast.CompositeLit); ok && lit.Type != nil {
+				return &ast.StarExpr{Star: unary.Pos(), X: lit.Type}
+			}
+		}
+		if lit, ok := value.(*ast.CompositeLit); ok {
+			return lit.Type
+		}
+		return nil
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Field:
+			for _, name := range n.Names {
+				c.declared[name.Pos()] = n.Type
+			}
+			if len(n.Names) == 0 {
+				// Embedded fields are declared at their type name
+				typ := n.Type
+				if star, ok := typ.(*ast.StarExpr); ok {
+					typ = star.X
+				}
+				if sel, ok := typ.(*ast.SelectorExpr); ok {
+					typ = sel.Sel
+				}
+				c.declared[typ.Pos()] = n.Type
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if n.Type != nil {
+					c.declared[name.Pos()] = n.Type
+				} else if i < len(n.Values) {
+					c.declared[name.Pos()] = literalType(n.Values[i])
+				}
+			}
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE || len(n.Lhs) != len(n.Rhs) {
+				break
+			}
+			for i, lhs := range n.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && c.a.typeInfo.Defs[ident] != nil {
+					c.declared[ident.Pos()] = literalType(n.Rhs[i])
+				}
+			}
+		}
+		return true
+	})
+}
+
+// syncType returns the name of the type of sync an expression denotes,
+// through a pointer if pointer is set, or ""
+func (c *concurrencyChecker) syncType(expr ast.Expr, pointer bool) string {
+	if star, ok := expr.(*ast.StarExpr); ok && pointer {
+		expr = star.X
+	}
+	if sel, ok := expr.(*ast.SelectorExpr); ok {
+		if ident, ok := sel.X.(*ast.Ident); ok && c.syncName != "" && ident.Name == c.syncName {
+			return sel.Sel.Name
+		}
+	}
+	return ""
+}
+
+// lockOf returns the lock of sync the value of an expression contains, or
+// ""
+func (c *concurrencyChecker) lockOf(expr ast.Expr) string {
+	if t := c.a.typeInfo.TypeOf(expr); knownType(t) {
+		return c.lockIn(t, make(map[types.Type]bool))
+	}
+	// The type of a variable or field of a package not loaded
+	var ident *ast.Ident
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		ident = expr
+	case *ast.SelectorExpr:
+		ident = expr.Sel
+	default:
+		return ""
+	}
+	obj := c.a.typeInfo.Defs[ident]
+	if obj == nil {
+		obj = c.a.typeInfo.Uses[ident]
+	}
+	if obj == nil {
+		return ""
+	}
+	return c.lockInExpr(c.declared[obj.Pos()], make(map[types.Type]bool))
+}
+
+// lockIn returns the lock of sync a type contains by value, or ""
+func (c *concurrencyChecker) lockIn(t types.Type, seen map[types.Type]bool) string {
+	if seen[t] {
+		return ""
+	}
+	seen[t] = true
+	if named, ok := t.(*types.Named); ok {
+		if obj := named.Obj(); obj.Pkg() != nil && obj.Pkg().Path() == "sync" && syncLocks[obj.Name()] {
+			return obj.Name()
+		}
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			field := u.Field(i)
+			var lock string
+			if knownType(field.Type()) {
+				lock = c.lockIn(field.Type(), seen)
+			} else {
+				lock = c.lockInExpr(c.declared[field.Pos()], seen)
+			}
+			if lock != "" {
+				return lock
+			}
+		}
+	case *types.Array:
+		return c.lockIn(u.Elem(), seen)
+	}
+	return ""
+}
+
+// lockInExpr returns the lock of sync the type a type expression denotes
+// contains by value, or ""
+func (c *concurrencyChecker) lockInExpr(expr ast.Expr, seen map[types.Type]bool) string {
+	if expr == nil {
+		return ""
+	}
+	if lock := c.syncType(expr, false); syncLocks[lock] {
+		return lock
+	}
+	if t := c.a.typeInfo.TypeOf(expr); knownType(t) {
+		return c.lockIn(t, seen)
+	}
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return c.lockInExpr(expr.X, seen)
+	case *ast.ArrayType:
+		if expr.Len != nil {
+			return c.lockInExpr(expr.Elt, seen)
+		}
+	case *ast.StructType:
+		for _, field := range expr.Fields.List {
+			if lock := c.lockInExpr(field.Type, seen); lock != "" {
+				return lock
+			}
+		}
+	}
+	return ""
+}
+
+// knownType reports whether a type is known
+func knownType(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	basic, ok := t.(*types.Basic)
+	return !ok || basic.Kind() != types.Invalid
+}
+
+// checkSignature reports the receiver and parameters of a function passed
+// by value with a lock
+func (c *concurrencyChecker) checkSignature(fn *ast.FuncDecl) {
+	name := fn.Name.Name
+	var fields []*ast.Field
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		name = receiverName(fn.Recv.List[0].Type) + "." + name
+		fields = append(fields, fn.Recv.List[0])
+	}
+	fields = append(fields, fn.Type.Params.List...)
+	for i, field := range fields {
+		lock := c.lockInExpr(field.Type, make(map[types.Type]bool))
+		if lock == "" {
+			continue
+		}
+		kind := "Parameter"
+		if fn.Recv != nil && i == 0 {
+			kind = "Receiver"
+		}
+		if len(field.Names) == 0 {
+			c.report(field.Type, "copied-lock", "%s of %s copies a sync.%s by value", kind, name, lock)
+		}
+		for _, ident := range field.Names {
+			c.report(ident, "copied-lock", "%s %s of %s copies a sync.%s by value", kind, ident.Name, name, lock)
+		}
+	}
+}
+
+// checkCopies reports the values assigned that copy a variable holding a
+// lock, other than to the blank identifier
+func (c *concurrencyChecker) checkCopies(lhs, values []ast.Expr) {
+	for i, value := range values {
+		if ident, ok := lhs[i].(*ast.Ident); ok && ident.Name == "_" {
+			continue
+		}
+		switch ast.Unparen(value).(type) {
+		case *ast.Ident, *ast.SelectorExpr, *ast.StarExpr, *ast.IndexExpr:
+		default:
+			continue
+		}
+		if lock := c.lockOf(ast.Unparen(value)); lock != "" {
+			c.report(value, "copied-lock", "Assignment copies a sync.%s by value", lock)
+		}
+	}
+}
+
+// checkGoroutine reports the maps a goroutine writes without taking a
+// lock, and its calls of WaitGroup.Add
+func (c *concurrencyChecker) checkGoroutine(lit *ast.FuncLit) {
+	locks := false
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok && (sel.Sel.Name == "Lock" || sel.Sel.Name == "RLock") {
+				locks = true
+			}
+		}
+		return !locks
+	})
+
+	// shared reports whether a map is reached through a variable the
+	// goroutine does not declare
+	shared := func(expr ast.Expr) bool {
+		if _, ok := c.a.typeInfo.TypeOf(expr).Underlying().(*types.Map); !ok {
+			return false
+		}
+		for {
+			switch e := ast.Unparen(expr).(type) {
+			case *ast.SelectorExpr:
+				expr = e.X
+			case *ast.IndexExpr:
+				expr = e.X
+			case *ast.StarExpr:
+				expr = e.X
+			case *ast.Ident:
+				obj := c.a.typeInfo.Uses[e]
+				return obj != nil && (obj.Pos() < lit.Pos() || obj.Pos() >= lit.End())
+			default:
+				return false
+			}
+		}
+	}
+	mapWrite := func(node ast.Node, m ast.Expr) {
+		if !locks && c.a.typeInfo.TypeOf(m) != nil && shared(m) {
+			c.report(node, "unsynchronized-map-write", "Map %s is written by a goroutine without synchronization", types.ExprString(m))
+		}
+	}
+
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// Goroutines of their own are checked on their own
+			return false
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if index, ok := ast.Unparen(lhs).(*ast.IndexExpr); ok {
+					mapWrite(lhs, index.X)
+				}
+			}
+		case *ast.IncDecStmt:
+			if index, ok := ast.Unparen(n.X).(*ast.IndexExpr); ok {
+				mapWrite(n.X, index.X)
+			}
+		case *ast.CallExpr:
+			if ident, ok := ast.Unparen(n.Fun).(*ast.Ident); ok && ident.Name == "delete" && len(n.Args) == 2 {
+				if _, ok := c.a.typeInfo.Uses[ident].(*types.Builtin); ok {
+					mapWrite(n, n.Args[0])
+				}
+			}
+			sel, ok := ast.Unparen(n.Fun).(*ast.SelectorExpr)
+			if ok && sel.Sel.Name == "Add" && c.isWaitGroup(sel.X) {
+				c.report(n, "waitgroup-add-in-goroutine", "%s.Add is called inside the goroutine, which may run after Wait; call it before the go statement", types.ExprString(sel.X))
+			}
+		}
+		return true
+	})
+}
+
+// isWaitGroup reports whether an expression is a sync.WaitGroup or a
+// pointer to one
+func (c *concurrencyChecker) isWaitGroup(expr ast.Expr) bool {
+	if t := c.a.typeInfo.TypeOf(expr); knownType(t) {
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		named, ok := t.(*types.Named)
+		return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "sync" && named.Obj().Name() == "WaitGroup"
+	}
+	var ident *ast.Ident
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		ident = expr
+	case *ast.SelectorExpr:
+		ident = expr.Sel
+	default:
+		return false
+	}
+	obj := c.a.typeInfo.Uses[ident]
+	return obj != nil && c.syncType(c.declared[obj.Pos()], true) == "WaitGroup"
+}
+
+// checkNilChannelSends reports the sends on channels declared in a function
+// body without a value when no statement of a block enclosing the send
+// assigns one before it. Sends of select statements, for which nil
+// channels are the idiom to disable a case, are left out, and so are
+// channels assigned by closures or whose address is taken.
+func (c *concurrencyChecker) checkNilChannelSends(body *ast.BlockStmt) {
+	type assignment struct {
+		block *ast.BlockStmt
+		pos   token.Pos
+	}
+	channels := make(map[types.Object][]assignment)
+	escaped := make(map[types.Object]bool)
+	type send struct {
+		stmt   *ast.SendStmt
+		obj    types.Object
+		blocks []*ast.BlockStmt
+	}
+	var sends []send
+
+	var stack []ast.Node
+	var closures int
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			if _, ok := stack[len(stack)-1].(*ast.FuncLit); ok {
+				closures--
+			}
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		var block *ast.BlockStmt
+		if len(stack) > 0 {
+			block, _ = stack[len(stack)-1].(*ast.BlockStmt)
+		}
+		stack = append(stack, n)
+
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			closures++
+		case *ast.ValueSpec:
+			if _, ok := n.Type.(*ast.ChanType); ok && len(n.Values) == 0 {
+				for _, name := range n.Names {
+					if obj := c.a.typeInfo.Defs[name]; obj != nil {
+						channels[obj] = nil
+					}
+				}
+			}
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				ident, ok := ast.Unparen(lhs).(*ast.Ident)
+				if !ok {
+					continue
+				}
+				obj := c.a.typeInfo.Uses[ident]
+				if closures > 0 {
+					escaped[obj] = true
+				}
+				channels[obj] = append(channels[obj], assignment{block: block, pos: n.Pos()})
+			}
+		case *ast.UnaryExpr:
+			if ident, ok := ast.Unparen(n.X).(*ast.Ident); ok && n.Op == token.AND {
+				escaped[c.a.typeInfo.Uses[ident]] = true
+			}
+		case *ast.SendStmt:
+			if clause, ok := stack[len(stack)-2].(*ast.CommClause); ok && clause.Comm == n {
+				break
+			}
+			ident, ok := ast.Unparen(n.Chan).(*ast.Ident)
+			if !ok {
+				break
+			}
+			s := send{stmt: n, obj: c.a.typeInfo.Uses[ident]}
+			for _, node := range stack {
+				if block, ok := node.(*ast.BlockStmt); ok {
+					s.blocks = append(s.blocks, block)
+				}
+			}
+			sends = append(sends, s)
+		}
+		return true
+	})
+
+	for _, s := range sends {
+		assignments, declared := channels[s.obj]
+		if s.obj == nil || !declared || escaped[s.obj] {
+			continue
+		}
+		initialized := false
+		for _, assign := range assignments {
+			for _, block := range s.blocks {
+				if assign.block == block && assign.pos < s.stmt.Pos() {
+					initialized = true
+				}
+			}
+		}
+		if !initialized {
+			c.report(s.stmt, "nil-channel-send", "Send on channel %s, which may be nil and block forever", types.ExprString(s.stmt.Chan))
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyDiagnostics(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		found  []string
+	}{
+		{
+			"lock copied by a receiver",
+			"type Counter struct {\n\tmu sync.Mutex\n\tn  int\n}\n\nfunc (c Counter) Get() int { return c.n }\n\nfunc (c *Counter) Inc() { c.n++ }",
+			[]string{"Receiver c of Counter.Get copies a sync.Mutex by value"},
+		},
+		{
+			"embedded lock copied by a parameter",
+			"type Cache struct {\n\tsync.RWMutex\n}\n\nfunc size(c Cache, wg sync.WaitGroup, p *Cache) {}",
+			[]string{"Parameter c of size copies a sync.RWMutex by value", "Parameter wg of size copies a sync.WaitGroup by value"},
+		},
+		{
+			"lock copied by a range variable and an assignment",
+			"type entry struct {\n\tonce sync.Once\n}\n\nfunc f(entries []entry, p *entry) {\n\tfor _, e := range entries {\n\t\t_ = e\n\t}\n\tcopied := *p\n\t_ = copied\n\tfresh := entry{}\n\t_ = fresh\n}",
+			[]string{"Range variable e copies a sync.Once by value", "Assignment copies a sync.Once by value"},
+		},
+		{
+			"map written by a goroutine",
+			"func f(keys []string) map[string]int {\n\tcounts := map[string]int{}\n\tfor _, k := range keys {\n\t\tgo func(k string) {\n\t\t\tlocal := map[string]int{}\n\t\t\tlocal[k] = 1\n\t\t\tcounts[k]++\n\t\t\tdelete(counts, \"\")\n\t\t}(k)\n\t}\n\treturn counts\n}",
+			[]string{"Map counts is written by a goroutine without synchronization", "Map counts is written by a goroutine without synchronization"},
+		},
+		{
+			"map written under a lock",
+			"func f(mu *sync.Mutex, counts map[string]int) {\n\tgo func() {\n\t\tmu.Lock()\n\t\tdefer mu.Unlock()\n\t\tcounts[\"a\"] = 1\n\t}()\n}",
+			nil,
+		},
+		{
+			"WaitGroup.Add in the goroutine",
+			"func f(n int) {\n\tvar wg sync.WaitGroup\n\tfor i := 0; i < n; i++ {\n\t\tgo func() {\n\t\t\twg.Add(1)\n\t\t\tdefer wg.Done()\n\t\t}()\n\t}\n\twg.Wait()\n}",
+			[]string{"wg.Add is called inside the goroutine, which may run after Wait; call it before the go statement"},
+		},
+		{
+			"WaitGroup.Add before the goroutine",
+			"func f(wg *sync.WaitGroup) {\n\twg.Add(1)\n\tgo func() {\n\t\tdefer wg.Done()\n\t}()\n}",
+			nil,
+		},
+		{
+			"send on a nil channel",
+			"func f(ready bool) {\n\tvar ch chan int\n\tif ready {\n\t\tch = make(chan int, 1)\n\t}\n\tch <- 1\n}",
+			[]string{"Send on channel ch, which may be nil and block forever"},
+		},
+		{
+			"send on an initialized channel",
+			"func f() {\n\tvar ch chan int\n\tch = make(chan int, 1)\n\tch <- 1\n\tvar done chan struct{}\n\tselect {\n\tcase done <- struct{}{}:\n\tdefault:\n\t}\n}",
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "p.go", "package p\n\nimport \"sync\"\n\nvar _ sync.Locker\n\n"+tt.source+"\n", 0)
+			require.NoError(t, err)
+			result, err := NewASTAnalyzer(fset).AnalyzeFile(file)
+			require.NoError(t, err)
+
+			var found []string
+			for _, d := range result.Diagnostics {
+				switch d.Code {
+				case "copied-lock", "unsynchronized-map-write", "waitgroup-add-in-goroutine", "nil-channel-send":
+					found = append(found, d.Message)
+				}
+			}
+			assert.Equal(t, tt.found, found)
+		})
+	}
+}
+
+func TestConcurrencyDiagnosticsModule(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.22\n",
+		"m.go":   "package m\n\nimport \"sync\"\n\ntype guarded struct {\n\tsync.Mutex\n\tvalues map[string]int\n}\n\n// Snapshot copies the values\nfunc Snapshot(g guarded) map[string]int {\n\treturn g.values\n}\n",
+	}
+	for name, src := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+
+	// Locks resolve from the types of sync once loaded
+	analysis, err := AnalyzeModule(dir, ".", "")
+	require.NoError(t, err)
+	var found []Diagnostic
+	for _, d := range analysis.Diagnostics {
+		if d.Code == "copied-lock" {
+			found = append(found, d)
+		}
+	}
+	require.Len(t, found, 1, analysis.Diagnostics)
+	assert.Equal(t, "Parameter g of Snapshot copies a sync.Mutex by value", found[0].Message)
+	assert.Equal(t, Range{Start: Position{Line: 10, Character: 14}, End: Position{Line: 10, Character: 15}}, found[0].Location.Range)
+}
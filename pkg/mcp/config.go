@@ -0,0 +1,474 @@
+package mcp
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Feature names that can be toggled in the server configuration
+const (
+	FeatureCurl        = "curl"
+	FeatureFunctions   = "functions"
+	FeatureSSH         = "ssh"
+	FeatureBrowser     = "browser"
+	FeatureLSP         = "lsp"
+	FeatureAnalysis    = "analysis"
+	FeatureIDE         = "ide"
+	FeatureDiagnostics = "diagnostics"
+	FeatureGenerate    = "generate"
+	FeatureDocs        = "docs"
+)
+
+// Risky capabilities of enabled features that can be toggled separately, so
+// operators can run a locked-down profile
+const (
+	CapabilityBrowserEval = "browser.eval" // Raw JavaScript automation steps
+	CapabilitySSHSudo     = "ssh.sudo"     // Remote commands invoking sudo
+	CapabilityFileDelete  = "file.delete"  // Deleting and pruning artifacts
+)
+
+// Store backends
+const (
+	StoreBackendMemory = "memory"
+	StoreBackendFile   = "file"
+)
+
+// ServerConfig holds the configuration of an MCP server. Values are resolved
+// from defaults, then an optional YAML file, then MCP_* environment
+// variables, then command line flags.
+type ServerConfig struct {
+	ListenAddr     string            `yaml:"listen_addr"`
+	GRPCListenAddr string            `yaml:"grpc_listen_addr"` // gRPC is disabled when empty
+	Stdio          bool              `yaml:"stdio"`            // Speak MCP over stdin and stdout instead of listening
+	LSPStdio       bool              `yaml:"lsp_stdio"`        // Speak the Language Server Protocol over stdin and stdout instead
+	BaseURL        string            `yaml:"base_url"`
+	WorkspaceRoot  string            `yaml:"workspace_root"`
+	Store          StoreConfig       `yaml:"store"`
+	Features       map[string]bool   `yaml:"features"`
+	Capabilities   map[string]bool   `yaml:"capabilities"`
+	RuntimeToggles bool              `yaml:"runtime_toggles"` // Allow callers of the admin scope to change toggles through /admin/features
+	API            APIConfig         `yaml:"api"`
+	RateLimit      RateLimitConfig   `yaml:"rate_limit"`
+	CORS           CORSConfig        `yaml:"cors"`
+	Compression    CompressionConfig `yaml:"compression"`
+	Logging        LoggingConfig     `yaml:"logging"`
+	Usage          UsageConfig       `yaml:"usage"`
+	HTTP           HTTPConfig        `yaml:"http"`
+	Chaos          ChaosConfig       `yaml:"chaos"`
+	Proxy          ProxyConfig       `yaml:"proxy"`
+	WebDAV         WebDAVConfig      `yaml:"webdav"`
+	MCP            MCPConfig         `yaml:"mcp"`
+	MCPHTTP        MCPHTTPConfig     `yaml:"mcp_http"` // MCP for remote hosts, next to the -stdio transport
+	Leases         LeaseConfig       `yaml:"leases"`
+	Analysis       AnalysisConfig    `yaml:"analysis"`
+	Functions      FunctionConfig    `yaml:"functions"`
+	Prompts        []PromptConfig    `yaml:"prompts"`   // MCP prompts, added to the built-in ones
+	Upstreams      []UpstreamConfig  `yaml:"upstreams"` // MCP servers whose tools are aggregated
+}
+
+// StoreConfig selects and configures the context store backend
+type StoreConfig struct {
+	Backend string `yaml:"backend"` // memory or file
+	Path    string `yaml:"path"`    // Data file for the file backend
+}
+
+// DefaultConfig returns the configuration used when nothing else is specified
+func DefaultConfig() *ServerConfig {
+	features := make(map[string]bool)
+	for _, name := range allFeatures() {
+		features[name] = true
+	}
+	capabilities := make(map[string]bool)
+	for _, name := range allCapabilities() {
+		capabilities[name] = true
+	}
+
+	return &ServerConfig{
+		ListenAddr:    ":8080",
+		WorkspaceRoot: ".",
+		Store: StoreConfig{
+			Backend: StoreBackendMemory,
+			Path:    ".mcp/contexts.json",
+		},
+		Features:       features,
+		Capabilities:   capabilities,
+		RuntimeToggles: false,
+		API: APIConfig{
+			LegacyRoutes: true,
+		},
+		Compression: CompressionConfig{
+			Enabled: true,
+			MinSize: defaultCompressionMinSize,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		HTTP: defaultHTTPConfig(),
+		Proxy: ProxyConfig{
+			ListenAddr: ":8081",
+		},
+		MCP: MCPConfig{
+			Tools:     true,
+			Resources: true,
+			Prompts:   true,
+			Sampling:  true,
+			Logging:   true,
+			Roots:     true,
+		},
+		MCPHTTP: MCPHTTPConfig{
+			Enabled: true,
+		},
+		Functions: FunctionConfig{
+			Timeout: defaultFunctionTimeout,
+		},
+	}
+}
+
+func allFeatures() []string {
+	return []string{
+		FeatureCurl, FeatureFunctions, FeatureSSH, FeatureBrowser, FeatureLSP,
+		FeatureAnalysis, FeatureIDE, FeatureDiagnostics, FeatureGenerate, FeatureDocs,
+	}
+}
+
+func allCapabilities() []string {
+	return []string{CapabilityBrowserEval, CapabilitySSHSudo, CapabilityFileDelete}
+}
+
+// LoadConfig resolves the server configuration from defaults, the YAML file
+// named by -config or MCP_CONFIG, the environment and the given arguments
+func LoadConfig(args []string) (*ServerConfig, error) {
+	cfg := DefaultConfig()
+
+	fs := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("MCP_CONFIG"), "path to a YAML configuration file")
+	listen := fs.String("listen", "", "listen address: host:port, unix:PATH, fd:N or systemd[:NAME]")
+	grpcListen := fs.String("grpc-listen", "", "gRPC listen address, in any form -listen accepts")
+	stdio := fs.Bool("stdio", false, "serve the Model Context Protocol over stdin and stdout, for MCP hosts")
+	lspStdio := fs.Bool("lsp", false, "serve the Language Server Protocol over stdin and stdout, for editors")
+	baseURL := fs.String("base-url", "", "externally reachable base URL")
+	workspace := fs.String("workspace", "", "workspace root directory")
+	storeBackend := fs.String("store", "", "context store backend (memory, file)")
+	storePath := fs.String("store-path", "", "data file for the file store backend")
+	features := fs.String("features", "", "comma separated feature toggles, e.g. ssh,-browser")
+	capabilities := fs.String("capabilities", "", "comma separated capability toggles, e.g. -ssh.sudo,-browser.eval")
+	runtimeToggles := fs.Bool("runtime-toggles", false, "allow callers of the admin scope to change toggles through the admin endpoint")
+	logLevel := fs.String("log-level", "", "log level (debug, info, warn, error)")
+	logFormat := fs.String("log-format", "", "log format (json, text)")
+	pluginDir := fs.String("plugin-dir", "", "directory of Go plugins registering functions")
+	legacyRoutes := fs.Bool("legacy-routes", true, "also serve deprecated unversioned routes")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.LoadFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.ApplyEnv(); err != nil {
+		return nil, err
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			cfg.ListenAddr = *listen
+		case "grpc-listen":
+			cfg.GRPCListenAddr = *grpcListen
+		case "stdio":
+			cfg.Stdio = *stdio
+		case "lsp":
+			cfg.LSPStdio = *lspStdio
+		case "base-url":
+			cfg.BaseURL = *baseURL
+		case "workspace":
+			cfg.WorkspaceRoot = *workspace
+		case "store":
+			cfg.Store.Backend = *storeBackend
+		case "store-path":
+			cfg.Store.Path = *storePath
+		case "features":
+			if err := cfg.applyFeatureList(*features); err != nil {
+				flagErr = err
+			}
+		case "capabilities":
+			if err := cfg.applyCapabilityList(*capabilities); err != nil {
+				flagErr = err
+			}
+		case "runtime-toggles":
+			cfg.RuntimeToggles = *runtimeToggles
+		case "log-level":
+			cfg.Logging.Level = *logLevel
+		case "log-format":
+			cfg.Logging.Format = *logFormat
+		case "plugin-dir":
+			cfg.Functions.PluginDir = *pluginDir
+		case "legacy-routes":
+			cfg.API.LegacyRoutes = *legacyRoutes
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// LoadFile merges a YAML configuration file into the config
+func (c *ServerConfig) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	features, capabilities := c.Features, c.Capabilities
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	// Toggles listed in the file override the defaults rather than replace them
+	for name, enabled := range c.Features {
+		features[name] = enabled
+	}
+	c.Features = features
+	for name, enabled := range c.Capabilities {
+		capabilities[name] = enabled
+	}
+	c.Capabilities = capabilities
+
+	return nil
+}
+
+// ApplyEnv overrides the config with MCP_* environment variables
+func (c *ServerConfig) ApplyEnv() error {
+	if v := os.Getenv("MCP_LISTEN_ADDR"); v != "" {
+		c.ListenAddr = v
+	}
+	if v := os.Getenv("MCP_GRPC_LISTEN_ADDR"); v != "" {
+		c.GRPCListenAddr = v
+	}
+	if v := os.Getenv("MCP_BASE_URL"); v != "" {
+		c.BaseURL = v
+	}
+	if v := os.Getenv("MCP_WORKSPACE_ROOT"); v != "" {
+		c.WorkspaceRoot = v
+	}
+	if v := os.Getenv("MCP_STORE_BACKEND"); v != "" {
+		c.Store.Backend = v
+	}
+	if v := os.Getenv("MCP_STORE_PATH"); v != "" {
+		c.Store.Path = v
+	}
+	if v := os.Getenv("MCP_LOG_LEVEL"); v != "" {
+		c.Logging.Level = v
+	}
+	if v := os.Getenv("MCP_LOG_FORMAT"); v != "" {
+		c.Logging.Format = v
+	}
+	if v := os.Getenv("MCP_PLUGIN_DIR"); v != "" {
+		c.Functions.PluginDir = v
+	}
+	if v := os.Getenv("MCP_LEGACY_ROUTES"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_LEGACY_ROUTES: %v", err)
+		}
+		c.API.LegacyRoutes = enabled
+	}
+	if v := os.Getenv("MCP_STDIO"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_STDIO: %v", err)
+		}
+		c.Stdio = enabled
+	}
+	if v := os.Getenv("MCP_LSP_STDIO"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_LSP_STDIO: %v", err)
+		}
+		c.LSPStdio = enabled
+	}
+	if v := os.Getenv("MCP_RUNTIME_TOGGLES"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_RUNTIME_TOGGLES: %v", err)
+		}
+		c.RuntimeToggles = enabled
+	}
+	if v := os.Getenv("MCP_FEATURES"); v != "" {
+		if err := c.applyFeatureList(v); err != nil {
+			return err
+		}
+	}
+	if v := os.Getenv("MCP_CAPABILITIES"); v != "" {
+		return c.applyCapabilityList(v)
+	}
+	return nil
+}
+
+// applyFeatureList enables or disables features from a list such as
+// "ssh,-browser"; a leading "-" disables the feature
+func (c *ServerConfig) applyFeatureList(list string) error {
+	return applyToggleList(list, "feature", allFeatures(), c.Features)
+}
+
+// applyCapabilityList enables or disables capabilities from a list such as
+// "-ssh.sudo,-file.delete"
+func (c *ServerConfig) applyCapabilityList(list string) error {
+	return applyToggleList(list, "capability", allCapabilities(), c.Capabilities)
+}
+
+func applyToggleList(list, kind string, known []string, toggles map[string]bool) error {
+	for _, item := range splitList(list) {
+		enabled := !strings.HasPrefix(item, "-")
+		name := strings.TrimPrefix(strings.TrimPrefix(item, "-"), "+")
+		if !containsString(known, name) {
+			return fmt.Errorf("unknown %s %q", kind, name)
+		}
+		toggles[name] = enabled
+	}
+	return nil
+}
+
+// Validate checks the configuration for obvious mistakes
+func (c *ServerConfig) Validate() error {
+	switch c.Store.Backend {
+	case StoreBackendMemory:
+	case StoreBackendFile:
+		if c.Store.Path == "" {
+			return fmt.Errorf("file store backend requires a path")
+		}
+	default:
+		return fmt.Errorf("unknown store backend %q", c.Store.Backend)
+	}
+
+	for name := range c.Features {
+		if !containsString(allFeatures(), name) {
+			return fmt.Errorf("unknown feature %q", name)
+		}
+	}
+	for name := range c.Capabilities {
+		if !containsString(allCapabilities(), name) {
+			return fmt.Errorf("unknown capability %q", name)
+		}
+	}
+
+	if c.Stdio && c.LSPStdio {
+		return fmt.Errorf("stdio and lsp_stdio cannot both be set")
+	}
+
+	if err := c.HTTP.Validate(); err != nil {
+		return fmt.Errorf("http: %v", err)
+	}
+
+	if err := c.Usage.Validate(); err != nil {
+		return fmt.Errorf("usage: %v", err)
+	}
+
+	if err := c.Chaos.Validate(); err != nil {
+		return fmt.Errorf("chaos: %v", err)
+	}
+
+	if err := c.Proxy.Validate(); err != nil {
+		return fmt.Errorf("proxy: %v", err)
+	}
+
+	if err := c.WebDAV.Validate(); err != nil {
+		return fmt.Errorf("webdav: %v", err)
+	}
+
+	if err := c.MCPHTTP.Validate(); err != nil {
+		return fmt.Errorf("mcp_http: %v", err)
+	}
+
+	if err := c.Leases.Validate(); err != nil {
+		return fmt.Errorf("leases: %v", err)
+	}
+
+	if err := c.Analysis.Validate(); err != nil {
+		return fmt.Errorf("analysis: %v", err)
+	}
+
+	if err := c.Functions.Validate(); err != nil {
+		return fmt.Errorf("functions: %v", err)
+	}
+
+	if err := validatePrompts(c.Prompts); err != nil {
+		return fmt.Errorf("prompts: %v", err)
+	}
+
+	if err := validateUpstreams(c.Upstreams); err != nil {
+		return fmt.Errorf("upstreams: %v", err)
+	}
+
+	if _, err := NewLogger(c.Logging, io.Discard); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FeatureEnabled reports whether a feature is switched on
+func (c *ServerConfig) FeatureEnabled(name string) bool {
+	return c.Features[name]
+}
+
+// CapabilityEnabled reports whether a capability is switched on
+func (c *ServerConfig) CapabilityEnabled(name string) bool {
+	return c.Capabilities[name]
+}
+
+// EnabledFeatures returns the sorted names of all enabled features
+func (c *ServerConfig) EnabledFeatures() []string {
+	var names []string
+	for name, enabled := range c.Features {
+		if enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolvedBaseURL returns the configured base URL, deriving one from the
+// listen address when none is set
+func (c *ServerConfig) ResolvedBaseURL() string {
+	if c.BaseURL != "" {
+		return strings.TrimSuffix(c.BaseURL, "/")
+	}
+
+	host, port, err := net.SplitHostPort(c.ListenAddr)
+	if err != nil {
+		return "http://localhost:8080"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// NewStoreFromConfig creates the context store selected by the config
+func NewStoreFromConfig(cfg StoreConfig) (Store, error) {
+	switch cfg.Backend {
+	case StoreBackendMemory, "":
+		return NewMemoryStore(), nil
+	case StoreBackendFile:
+		return NewFileStore(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unknown store backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,88 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "mcp.yaml")
+	err := os.WriteFile(configPath, []byte(`
+listen_addr: ":7000"
+base_url: "http://file.example"
+workspace_root: "/srv/file"
+store:
+  backend: file
+  path: /tmp/contexts.json
+features:
+  browser: false
+`), 0644)
+	require.NoError(t, err)
+
+	t.Setenv("MCP_BASE_URL", "http://env.example")
+	t.Setenv("MCP_FEATURES", "-ssh")
+
+	cfg, err := LoadConfig([]string{"-config", configPath, "-workspace", "/srv/flag"})
+	require.NoError(t, err)
+
+	assert.Equal(t, ":7000", cfg.ListenAddr)
+	assert.Equal(t, "http://env.example", cfg.BaseURL)
+	assert.Equal(t, "/srv/flag", cfg.WorkspaceRoot)
+	assert.Equal(t, StoreBackendFile, cfg.Store.Backend)
+	assert.False(t, cfg.FeatureEnabled(FeatureBrowser))
+	assert.False(t, cfg.FeatureEnabled(FeatureSSH))
+	assert.True(t, cfg.FeatureEnabled(FeatureLSP))
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	_, err := LoadConfig([]string{"-store", "redis"})
+	assert.Error(t, err)
+
+	_, err = LoadConfig([]string{"-features", "teleport"})
+	assert.Error(t, err)
+}
+
+func TestResolvedBaseURL(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, "http://localhost:8080", cfg.ResolvedBaseURL())
+
+	cfg.ListenAddr = "127.0.0.1:6666"
+	assert.Equal(t, "http://127.0.0.1:6666", cfg.ResolvedBaseURL())
+
+	cfg.BaseURL = "https://mcp.example.com/"
+	assert.Equal(t, "https://mcp.example.com", cfg.ResolvedBaseURL())
+}
+
+func TestFileStore_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "contexts.json")
+
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Create(&Context{ID: "spec-1", Metadata: map[string]interface{}{"type": "openapi"}}))
+
+	reopened, err := NewFileStore(path)
+	require.NoError(t, err)
+	ctx, err := reopened.Get("spec-1")
+	require.NoError(t, err)
+	assert.Equal(t, "openapi", ctx.Metadata["type"])
+}
+
+func TestLoadConfig_Capabilities(t *testing.T) {
+	t.Setenv("MCP_CAPABILITIES", "-browser.eval")
+
+	cfg, err := LoadConfig([]string{"-capabilities", "-ssh.sudo", "-runtime-toggles"})
+	require.NoError(t, err)
+	assert.False(t, cfg.CapabilityEnabled(CapabilityBrowserEval))
+	assert.False(t, cfg.CapabilityEnabled(CapabilitySSHSudo))
+	assert.True(t, cfg.CapabilityEnabled(CapabilityFileDelete))
+	assert.True(t, cfg.RuntimeToggles)
+	assert.False(t, DefaultConfig().RuntimeToggles)
+
+	_, err = LoadConfig([]string{"-capabilities", "raw.sockets"})
+	assert.Error(t, err)
+}
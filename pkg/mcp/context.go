@@ -11,14 +11,17 @@ var (
 	ErrContextExists   = errors.New("context already exists")
 	ErrInvalidID       = errors.New("invalid context ID")
 	ErrInvalidMetadata = errors.New("invalid metadata")
+	ErrInvalidLabels   = errors.New("invalid labels")
 )
 
 var validIDPattern = regexp.MustCompile(`^[a-zA-Z0-9-_]+$`)
 
-// Context represents a model context with metadata
+// Context represents a model context with metadata. Labels group contexts
+// for listing and bulk operations, see LabelSelector.
 type Context struct {
 	ID        string                 `json:"id"`
 	Metadata  map[string]interface{} `json:"metadata"`
+	Labels    map[string]string      `json:"labels,omitempty"`
 	CreatedAt time.Time              `json:"created_at"`
 	UpdatedAt time.Time              `json:"updated_at"`
 }
@@ -31,7 +34,7 @@ func (c *Context) Validate() error {
 	if c.Metadata == nil {
 		return ErrInvalidMetadata
 	}
-	return nil
+	return validateLabels(c.Labels)
 }
 
 // Clone creates a deep copy of the context
@@ -40,9 +43,17 @@ func (c *Context) Clone() *Context {
 	for k, v := range c.Metadata {
 		metadata[k] = v
 	}
+	var labels map[string]string
+	if c.Labels != nil {
+		labels = make(map[string]string, len(c.Labels))
+		for k, v := range c.Labels {
+			labels[k] = v
+		}
+	}
 	return &Context{
 		ID:        c.ID,
 		Metadata:  metadata,
+		Labels:    labels,
 		CreatedAt: c.CreatedAt,
 		UpdatedAt: c.UpdatedAt,
 	}
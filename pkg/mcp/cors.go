@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig configures cross-origin resource sharing for browser clients
+type CORSConfig struct {
+	Enabled          bool     `yaml:"enabled"`
+	AllowedOrigins   []string `yaml:"allowed_origins"` // Exact origins, "*", or wildcards like "https://*.example.com"
+	AllowedMethods   []string `yaml:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers"`
+	ExposedHeaders   []string `yaml:"exposed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials"`
+	MaxAge           int      `yaml:"max_age"` // Preflight cache duration in seconds
+}
+
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization", "X-API-Key"}
+)
+
+// CORS applies a CORSConfig to HTTP handlers
+type CORS struct {
+	config CORSConfig
+}
+
+// NewCORS creates a CORS handler, filling in default methods and headers
+func NewCORS(config CORSConfig) *CORS {
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = defaultCORSMethods
+	}
+	if len(config.AllowedHeaders) == 0 {
+		config.AllowedHeaders = defaultCORSHeaders
+	}
+	return &CORS{config: config}
+}
+
+// Handler wraps next, answering preflight requests and adding CORS headers
+// to responses for allowed origins
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !c.originAllowed(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c.allowsAnyOrigin() && !c.config.AllowCredentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			// Credentials cannot be combined with a wildcard, so echo the origin
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if c.config.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if len(c.config.ExposedHeaders) > 0 {
+				h.Set("Access-Control-Expose-Headers", strings.Join(c.config.ExposedHeaders, ", "))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		method := r.Header.Get("Access-Control-Request-Method")
+		if !containsFold(c.config.AllowedMethods, method) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		h.Set("Access-Control-Allow-Methods", strings.Join(c.config.AllowedMethods, ", "))
+		h.Set("Access-Control-Allow-Headers", strings.Join(c.config.AllowedHeaders, ", "))
+		if c.config.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(c.config.MaxAge))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (c *CORS) allowsAnyOrigin() bool {
+	return containsString(c.config.AllowedOrigins, "*")
+}
+
+func (c *CORS) originAllowed(origin string) bool {
+	for _, allowed := range c.config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if i := strings.Index(allowed, "*"); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if len(origin) >= len(prefix)+len(suffix) &&
+				strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS_Preflight(t *testing.T) {
+	cors := NewCORS(CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.preview.example.com"},
+		MaxAge:         600,
+	})
+	handler := cors.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("preflight reached the handler")
+	}))
+
+	tests := []struct {
+		name   string
+		origin string
+		method string
+		status int
+		allow  string
+	}{
+		{"exact origin", "https://app.example.com", "POST", http.StatusNoContent, "https://app.example.com"},
+		{"wildcard origin", "https://pr-7.preview.example.com", "DELETE", http.StatusNoContent, "https://pr-7.preview.example.com"},
+		{"disallowed origin", "https://evil.example.com", "POST", http.StatusForbidden, ""},
+		{"wildcard without subdomain", "https://preview.example.com", "POST", http.StatusForbidden, ""},
+		{"disallowed method", "https://app.example.com", "PATCH", http.StatusForbidden, "https://app.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("OPTIONS", "/v1/context/list", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.allow, rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Contains(t, rec.Header().Values("Vary"), "Origin")
+			if tt.status == http.StatusNoContent {
+				assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", rec.Header().Get("Access-Control-Allow-Methods"))
+				assert.Equal(t, "Content-Type, Authorization, X-API-Key", rec.Header().Get("Access-Control-Allow-Headers"))
+				assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+				assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+			}
+		})
+	}
+}
+
+func TestCORS_Requests(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		config      CORSConfig
+		origin      string
+		allow       string
+		credentials string
+		vary        bool
+	}{
+		{"no origin", CORSConfig{AllowedOrigins: []string{"*"}}, "", "", "", false},
+		{"wildcard", CORSConfig{AllowedOrigins: []string{"*"}}, "https://a.example", "*", "", true},
+		// Browsers reject a wildcard with credentials, so the origin is echoed
+		{"wildcard with credentials", CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, "https://a.example", "https://a.example", "true", true},
+		{"exact with credentials", CORSConfig{AllowedOrigins: []string{"https://a.example"}, AllowCredentials: true}, "https://a.example", "https://a.example", "true", true},
+		{"case of origin", CORSConfig{AllowedOrigins: []string{"https://A.example"}}, "https://a.example", "https://a.example", "", true},
+		{"disallowed", CORSConfig{AllowedOrigins: []string{"https://a.example"}, AllowCredentials: true}, "https://b.example", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/context/list", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			NewCORS(tt.config).Handler(next).ServeHTTP(rec, req)
+
+			// Disallowed origins are served without CORS headers, which the
+			// browser then refuses to expose
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.allow, rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.credentials, rec.Header().Get("Access-Control-Allow-Credentials"))
+			assert.Equal(t, tt.vary, rec.Header().Get("Vary") == "Origin")
+		})
+	}
+}
+
+func TestCORS_ExposedHeadersOnServer(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CORS = CORSConfig{Enabled: true, AllowedOrigins: []string{"https://a.example"}, ExposedHeaders: []string{"X-Request-ID"}}
+	s := NewServer(nil, WithConfig(cfg))
+
+	// Preflights of any route are answered before routing
+	req := httptest.NewRequest("OPTIONS", "/v1/no/such/route", nil)
+	req.Header.Set("Origin", "https://a.example")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	req = httptest.NewRequest("GET", "/v1/context/list", nil)
+	req.Header.Set("Origin", "https://a.example")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://a.example", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Request-ID", rec.Header().Get("Access-Control-Expose-Headers"))
+	assert.Contains(t, rec.Header().Values("Vary"), "Origin")
+}
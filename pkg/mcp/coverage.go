@@ -0,0 +1,252 @@
+package mcp
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+// Coverage is the share of the statements of a function or file run by
+// tests
+type Coverage struct {
+	Statements int     `json:"statements"`
+	Covered    int     `json:"covered"`
+	Percent    float64 `json:"percent"`
+}
+
+// add counts the statements of a block of a coverage profile
+func (c *Coverage) add(block cover.ProfileBlock) {
+	c.Statements += block.NumStmt
+	if block.Count > 0 {
+		c.Covered += block.NumStmt
+	}
+	c.Percent = 100 * float64(c.Covered) / float64(max(c.Statements, 1))
+}
+
+// FunctionCoverage is the coverage of a function next to its complexity
+type FunctionCoverage struct {
+	Name                string   `json:"name"`
+	Receiver            string   `json:"receiver,omitempty"`
+	Line                int      `json:"line"`
+	Complexity          int      `json:"complexity"`
+	CognitiveComplexity int      `json:"cognitive_complexity"`
+	Coverage            Coverage `json:"coverage"`
+}
+
+// FileCoverage is the coverage of a file and of its functions
+type FileCoverage struct {
+	Path      string             `json:"path"` // Relative to the workspace root for the files of its module
+	Coverage  Coverage           `json:"coverage"`
+	Functions []FunctionCoverage `json:"functions"`
+}
+
+// CoverageReport is the coverage of the files of the last profile uploaded
+type CoverageReport struct {
+	Mode       string         `json:"mode"` // set, count or atomic
+	UploadedAt time.Time      `json:"uploaded_at"`
+	Coverage   Coverage       `json:"coverage"`
+	Files      []FileCoverage `json:"files"`
+}
+
+// coverageProfile is a coverage profile with its blocks by file
+type coverageProfile struct {
+	mode     string
+	uploaded time.Time
+	files    map[string][]cover.ProfileBlock
+}
+
+// coverageStore holds the last coverage profile uploaded. Files of the
+// module of the workspace are keyed by their path relative to its root,
+// other files by their name in the profile.
+type coverageStore struct {
+	root    string
+	mu      sync.RWMutex
+	profile *coverageProfile
+}
+
+func newCoverageStore(root string) *coverageStore {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &coverageStore{root: root}
+}
+
+// load parses a coverage profile, as written by go test -coverprofile, and
+// replaces the profile held
+func (c *coverageStore) load(r io.Reader) error {
+	profiles, err := cover.ParseProfilesFromReader(r)
+	if err != nil {
+		return fmt.Errorf("invalid coverage profile: %v", err)
+	}
+	if len(profiles) == 0 {
+		return fmt.Errorf("coverage profile has no blocks")
+	}
+
+	module := modulePath(c.root)
+	profile := &coverageProfile{mode: profiles[0].Mode, uploaded: time.Now(), files: make(map[string][]cover.ProfileBlock)}
+	for _, p := range profiles {
+		name := p.FileName
+		if module != "" && strings.HasPrefix(name, module+"/") {
+			name = strings.TrimPrefix(name, module+"/")
+		}
+		profile.files[name] = p.Blocks
+	}
+
+	c.mu.Lock()
+	c.profile = profile
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *coverageStore) current() *coverageProfile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.profile
+}
+
+// blocks returns the blocks of the file of a URI, which is relative to the
+// workspace root, absolute, a file:// URI or the name of the file in the
+// profile
+func (c *coverageStore) blocks(uri string) ([]cover.ProfileBlock, bool) {
+	profile := c.current()
+	if profile == nil {
+		return nil, false
+	}
+	if blocks, ok := profile.files[filepath.ToSlash(uri)]; ok {
+		return blocks, true
+	}
+
+	path := strings.TrimPrefix(uri, "file://")
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(c.root, path)
+		if err != nil || !isWithinRoot(c.root, rel) {
+			return nil, false
+		}
+		path = rel
+	}
+	blocks, ok := profile.files[filepath.ToSlash(filepath.Clean(path))]
+	return blocks, ok
+}
+
+// applyCoverage sets the coverage of a file analyzed and of its functions
+// from the blocks of its profile. The statements of function literals count
+// for the function declaring them.
+func applyCoverage(result *AnalysisResult, blocks []cover.ProfileBlock) {
+	result.Coverage = &Coverage{}
+	for i := range result.Functions {
+		result.Functions[i].Coverage = &Coverage{}
+	}
+
+	for _, block := range blocks {
+		result.Coverage.add(block)
+		start := Position{Line: block.StartLine - 1, Character: block.StartCol - 1}
+		end := Position{Line: block.EndLine - 1, Character: block.EndCol - 1}
+		for _, fn := range result.Functions {
+			r := fn.Location.Range
+			if !positionBefore(start, r.Start) && !positionBefore(r.End, end) {
+				fn.Coverage.add(block)
+				break
+			}
+		}
+	}
+}
+
+// report analyzes the files of the profile under path in the workspace,
+// reporting the coverage of their functions next to their complexity.
+// Files that cannot be read, like those of other modules, are reported
+// without functions.
+func (c *coverageStore) report(path string) (*CoverageReport, error) {
+	profile := c.current()
+	if profile == nil {
+		return nil, fmt.Errorf("no coverage profile uploaded")
+	}
+
+	report := &CoverageReport{Mode: profile.mode, UploadedAt: profile.uploaded, Files: []FileCoverage{}}
+	prefix := filepath.ToSlash(filepath.Clean(path))
+	for name, blocks := range profile.files {
+		if path != "" && name != prefix && !strings.HasPrefix(name, prefix+"/") {
+			continue
+		}
+
+		file := FileCoverage{Path: name, Functions: []FunctionCoverage{}}
+		result := &AnalysisResult{}
+		if src, err := os.ReadFile(filepath.Join(c.root, filepath.FromSlash(name))); err == nil {
+			fset := token.NewFileSet()
+			if f, err := parser.ParseFile(fset, name, src, parser.ParseComments); err == nil {
+				result, err = NewASTAnalyzer(fset).AnalyzeFile(f)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		applyCoverage(result, blocks)
+
+		file.Coverage = *result.Coverage
+		for _, fn := range result.Functions {
+			file.Functions = append(file.Functions, FunctionCoverage{
+				Name:                fn.Name,
+				Receiver:            fn.Receiver,
+				Line:                fn.Location.Range.Start.Line + 1,
+				Complexity:          fn.Complexity,
+				CognitiveComplexity: fn.CognitiveComplexity,
+				Coverage:            *fn.Coverage,
+			})
+		}
+		for _, block := range blocks {
+			report.Coverage.add(block)
+		}
+		report.Files = append(report.Files, file)
+	}
+
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].Path < report.Files[j].Path
+	})
+	return report, nil
+}
+
+func handleUploadCoverage(coverage *coverageStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := coverage.load(r.Body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		report, err := coverage.report("")
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	}
+}
+
+func handleCoverageReport(coverage *coverageStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		if path != "" && !isWithinRoot(coverage.root, path) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("path %s is outside the workspace", path))
+			return
+		}
+		if coverage.current() == nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no coverage profile uploaded"))
+			return
+		}
+
+		report, err := coverage.report(path)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	}
+}
@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const coverageSource = `package m
+
+func Covered(n int) int {
+	if n > 0 {
+		return n
+	}
+	return -n
+}
+
+func Uncovered() int {
+	return 0
+}
+`
+
+const coverageProfileText = `mode: set
+example.com/m/m.go:3.25,4.11 1 1
+example.com/m/m.go:4.11,6.3 1 0
+example.com/m/m.go:7.2,7.11 1 1
+example.com/m/m.go:10.22,11.10 1 0
+example.com/other/x.go:1.1,2.2 3 1
+`
+
+func TestCoverage(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "m.go"), []byte(coverageSource), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddAnalysisHandler()
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/coverage", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/analyze/coverage", strings.NewReader("mode: set\nm.go:bad\n")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Blocks are mapped to the functions of the files of the module
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/analyze/coverage", strings.NewReader(coverageProfileText)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report CoverageReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, "set", report.Mode)
+	assert.Equal(t, Coverage{Statements: 7, Covered: 5, Percent: 100 * 5.0 / 7}, report.Coverage)
+	require.Len(t, report.Files, 2)
+	assert.Equal(t, "example.com/other/x.go", report.Files[0].Path)
+	assert.Empty(t, report.Files[0].Functions)
+	assert.Equal(t, FileCoverage{
+		Path:     "m.go",
+		Coverage: Coverage{Statements: 4, Covered: 2, Percent: 50},
+		Functions: []FunctionCoverage{
+			{Name: "Covered", Line: 3, Complexity: 2, CognitiveComplexity: 1, Coverage: Coverage{Statements: 3, Covered: 2, Percent: 100 * 2.0 / 3}},
+			{Name: "Uncovered", Line: 10, Complexity: 1, Coverage: Coverage{Statements: 1}},
+		},
+	}, report.Files[1])
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/coverage?path=m.go", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	require.Len(t, report.Files, 1)
+	assert.Equal(t, "m.go", report.Files[0].Path)
+
+	// Files analyzed get the coverage of the profile
+	for _, uri := range []string{"m.go", filepath.Join(dir, "m.go"), "file://" + filepath.Join(dir, "m.go")} {
+		body, err := json.Marshal(AnalysisRequest{URI: uri, Content: coverageSource})
+		require.NoError(t, err)
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/analyze/file", strings.NewReader(string(body))))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result AnalysisResult
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		require.NotNil(t, result.Coverage, uri)
+		assert.Equal(t, 50.0, result.Coverage.Percent)
+		require.Len(t, result.Functions, 2)
+		require.NotNil(t, result.Functions[1].Coverage)
+		assert.Equal(t, 0, result.Functions[1].Coverage.Covered)
+	}
+
+	body, err := json.Marshal(AnalysisRequest{URI: "n.go", Content: coverageSource})
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/analyze/file", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), `"coverage"`)
+}
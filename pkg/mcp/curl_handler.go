@@ -2,8 +2,13 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/ivikasavnish/go-mcp/pkg/curlprocessor"
 )
@@ -14,9 +19,123 @@ type CurlRequest struct {
 	Commands string `json:"commands"`
 }
 
+// CurlReplayRequest is a curl command to send
+type CurlReplayRequest struct {
+	Command string `json:"command"`
+}
+
+// CurlReplayResult is the response to a replayed curl command
+type CurlReplayResult struct {
+	Method     string            `json:"method"`
+	URL        string            `json:"url"`
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+	Truncated  bool              `json:"truncated"` // Body exceeded maxReplayBody
+	DurationMS float64           `json:"duration_ms"`
+}
+
+// Limits of replayed requests
+const (
+	maxReplayBody = 1 << 20
+	replayTimeout = 30 * time.Second
+)
+
 // AddCurlHandler adds curl processing capabilities to the MCP server
 func (s *Server) AddCurlHandler() {
-	s.router.HandleFunc("/curl/process", s.handleProcessCurl).Methods("POST")
+	s.handle(Route{
+		Method: "POST", Path: "/curl/process", Summary: "Import curl commands as API specs",
+		Request: CurlRequest{}, Response: map[string]string{}, Status: http.StatusCreated,
+	}, s.handleProcessCurl)
+	s.handle(Route{
+		Method: "POST", Path: "/curl/replay", Summary: "Send the request of a curl command",
+		Request: CurlReplayRequest{}, Response: CurlReplayResult{},
+	}, handleReplayCurl)
+
+	s.handleRPC(RPCMethod{
+		Name: "curl.replay", Summary: "Send the request of a curl command and return the response", Params: CurlReplayRequest{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var req CurlReplayRequest
+		if err := decodeParams(params, &req); err != nil {
+			return nil, err
+		}
+		cmd, err := curlprocessor.ParseCurlCommand(req.Command)
+		if err != nil {
+			return nil, invalidParams(err)
+		}
+		return replayCurl(ctx, cmd)
+	})
+}
+
+func handleReplayCurl(w http.ResponseWriter, r *http.Request) {
+	var req CurlReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	cmd, err := curlprocessor.ParseCurlCommand(req.Command)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := replayCurl(r.Context(), cmd)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// replayCurl sends the request of a parsed curl command. Response bodies are
+// cut at maxReplayBody.
+func replayCurl(ctx context.Context, cmd *curlprocessor.CurlCommand) (*CurlReplayResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, replayTimeout)
+	defer cancel()
+
+	var body io.Reader
+	if cmd.Body != "" {
+		body = strings.NewReader(cmd.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(cmd.Method), cmd.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range cmd.Headers {
+		req.Header.Set(k, v)
+	}
+	if cmd.Auth != nil && cmd.Auth.Type == "basic" {
+		req.SetBasicAuth(cmd.Auth.Username, cmd.Auth.Password)
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxReplayBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	result := &CurlReplayResult{
+		Method:     req.Method,
+		URL:        cmd.URL,
+		StatusCode: resp.StatusCode,
+		Headers:    make(map[string]string, len(resp.Header)),
+		Truncated:  len(data) > maxReplayBody,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if result.Truncated {
+		data = data[:maxReplayBody]
+	}
+	result.Body = string(data)
+	for k := range resp.Header {
+		result.Headers[k] = resp.Header.Get(k)
+	}
+	return result, nil
 }
 
 func (s *Server) handleProcessCurl(w http.ResponseWriter, r *http.Request) {
@@ -40,6 +159,5 @@ func (s *Server) handleProcessCurl(w http.ResponseWriter, r *http.Request) {
 
 // GetBaseURL returns the base URL of the MCP server
 func (s *Server) GetBaseURL() string {
-	// In a real implementation, this would be configurable
-	return "http://localhost:8080"
+	return s.config.ResolvedBaseURL()
 }
@@ -0,0 +1,185 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// FindDefinition returns the declaration of the identifier at pos in the Go
+// file at path. The package of the file is loaded with the go command, so
+// declarations are found across the workspace, its dependencies, vendored
+// packages and the standard library. Overlay replaces the contents of files
+// on disk, such as documents open in an editor. Identifiers without a
+// declaration in source, like builtins, have no definition.
+func FindDefinition(path string, overlay map[string][]byte, pos Position) ([]Location, error) {
+	target, err := resolveIdent(path, overlay, pos)
+	if err != nil {
+		return nil, err
+	}
+	if target.obj == nil || !target.obj.Pos().IsValid() {
+		return []Location{}, nil
+	}
+
+	decl := target.fset.Position(target.obj.Pos())
+	start := Position{Line: decl.Line - 1, Character: decl.Column - 1}
+	return []Location{{
+		URI: "file://" + filepath.ToSlash(decl.Filename),
+		Range: Range{
+			Start: start,
+			End:   Position{Line: start.Line, Character: start.Character + len(target.obj.Name())},
+		},
+	}}, nil
+}
+
+// resolvedIdent is an identifier of a loaded package and the object it
+// denotes; both are nil when there is no identifier at the position
+type resolvedIdent struct {
+	fset  *token.FileSet
+	pkg   *packages.Package
+	ident *ast.Ident
+	obj   types.Object
+}
+
+// resolveIdent loads the package of the Go file at path and resolves the
+// identifier at pos
+func resolveIdent(path string, overlay map[string][]byte, pos Position) (*resolvedIdent, error) {
+	content, ok := overlay[path]
+	if !ok {
+		var err error
+		if content, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	offset, err := offsetAt(string(content), pos)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	cfg := &packages.Config{
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:     filepath.Dir(path),
+		Fset:    fset,
+		Overlay: overlay,
+		Tests:   strings.HasSuffix(path, "_test.go"),
+	}
+	pkgs, err := packages.Load(cfg, "file="+path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load package: %v", err)
+	}
+
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			if fset.File(file.Pos()).Name() != path {
+				continue
+			}
+			target := &resolvedIdent{fset: fset, pkg: pkg, ident: identAt(fset, file, offset)}
+			if target.ident != nil {
+				target.obj = pkg.TypesInfo.Uses[target.ident]
+				if target.obj == nil {
+					target.obj = pkg.TypesInfo.Defs[target.ident]
+				}
+			}
+			return target, nil
+		}
+	}
+	if len(pkgs) > 0 && len(pkgs[0].Errors) > 0 {
+		return nil, fmt.Errorf("failed to load package: %v", pkgs[0].Errors[0])
+	}
+	return nil, fmt.Errorf("%s is not part of a Go package", path)
+}
+
+// identAt returns the identifier of file covering offset, if any
+func identAt(fset *token.FileSet, file *ast.File, offset int) *ast.Ident {
+	var found *ast.Ident
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil || found != nil {
+			return false
+		}
+		start, end := fset.Position(n.Pos()).Offset, fset.Position(n.End()).Offset
+		if offset < start || offset > end {
+			return false
+		}
+		if ident, ok := n.(*ast.Ident); ok {
+			found = ident
+		}
+		return true
+	})
+	return found
+}
+
+// definition finds the declaration of the identifier at pos in an open
+// document or a Go file of the workspace. Declarations in open documents
+// are located by their URI.
+func (ls *LanguageServer) definition(uri string, pos Position) ([]Location, error) {
+	overlay, uris, exists := ls.overlay(uri)
+	if !exists {
+		return nil, errDocumentNotFound
+	}
+
+	locations, err := FindDefinition(ls.documentPath(uri), overlay, pos)
+	if err != nil {
+		return nil, err
+	}
+	for i, location := range locations {
+		if docURI, ok := uris[location.URI]; ok {
+			locations[i].URI = docURI
+		}
+	}
+	return locations, nil
+}
+
+func handleDefinition(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uri, pos, err := positionParams(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		locations, err := ls.definition(uri, pos)
+		switch {
+		case errors.Is(err, errDocumentNotFound):
+			writeError(w, http.StatusNotFound, err)
+			return
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, locations)
+	}
+}
+
+// overlay returns the text of the open documents keyed by the files they
+// were read from, which it replaces, and their URIs keyed by file URI. It
+// also reports whether uri is open or a Go file of the workspace, which
+// works without opening it.
+func (ls *LanguageServer) overlay(uri string) (map[string][]byte, map[string]string, bool) {
+	overlay := make(map[string][]byte)
+	uris := make(map[string]string)
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	for _, doc := range ls.documents {
+		path := ls.documentPath(doc.URI)
+		overlay[path] = []byte(doc.Text)
+		uris["file://"+filepath.ToSlash(path)] = doc.URI
+	}
+	_, exists := ls.documents[uri]
+	if !exists && strings.HasSuffix(uri, ".go") {
+		path := ls.documentPath(uri)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() && inWorkspace(ls.workspaceRoot, path) {
+			exists = true
+		}
+	}
+	return overlay, uris, exists
+}
@@ -0,0 +1,83 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleDefinition(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.go"), []byte("package m\n\n// Config configures\ntype Config struct{ Name string }\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package m\n"), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+
+	// The open document differs from the file on disk
+	src := "package m\n\nimport \"fmt\"\n\nfunc show(c Config) {\n\tname := c.Name\n\tfmt.Println(name, len(name))\n}\n"
+	body, err := json.Marshal(TextDocumentItem{URI: "main.go", Text: src})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/document/open", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	definition := func(line, character int) []Location {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/v1/lsp/definition?uri=main.go&line=%d&character=%d", line, character), nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var locations []Location
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&locations))
+		return locations
+	}
+
+	// A type in another file of the package
+	locations := definition(4, 13)
+	require.Len(t, locations, 1)
+	assert.Equal(t, "file://"+filepath.ToSlash(filepath.Join(dir, "config.go")), locations[0].URI)
+	assert.Equal(t, Range{Start: Position{Line: 3, Character: 5}, End: Position{Line: 3, Character: 11}}, locations[0].Range)
+
+	// A field, reached through a selector
+	locations = definition(5, 12)
+	require.Len(t, locations, 1)
+	assert.Equal(t, Position{Line: 3, Character: 20}, locations[0].Range.Start)
+
+	// A local variable in the open document keeps its URI
+	locations = definition(6, 14)
+	require.Len(t, locations, 1)
+	assert.Equal(t, "main.go", locations[0].URI)
+	assert.Equal(t, Position{Line: 5, Character: 1}, locations[0].Range.Start)
+
+	// The standard library
+	locations = definition(6, 6)
+	require.Len(t, locations, 1)
+	assert.Equal(t, "file://"+filepath.ToSlash(filepath.Join(runtime.GOROOT(), "src", "fmt", "print.go")), locations[0].URI)
+
+	// Builtins are declared nowhere
+	assert.Empty(t, definition(6, 20))
+
+	// Files of the workspace work without being opened
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/definition?uri=config.go&line=3&character=7", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&locations))
+	require.Len(t, locations, 1)
+	assert.Equal(t, Position{Line: 3, Character: 5}, locations[0].Range.Start)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/definition?uri=missing.go&line=0&character=0", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
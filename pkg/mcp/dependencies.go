@@ -0,0 +1,194 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/go-rod/rod/lib/launcher"
+)
+
+// Optional external dependencies. The server starts without them; routes and
+// JSON-RPC methods needing a missing one answer 503 with a hint, and are left
+// out of the advertised capabilities and MCP tools.
+const (
+	DependencyChromium = "chromium"
+	DependencyGit      = "git"
+	DependencyNetwork  = "network"
+)
+
+// ErrDependencyUnavailable is returned for requests needing a dependency that
+// is not installed or reachable
+var ErrDependencyUnavailable = errors.New("dependency unavailable")
+
+// Dependency is the detected state of an optional dependency
+type Dependency struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Path      string `json:"path,omitempty"`
+	Hint      string `json:"hint,omitempty"` // How to provide it, when unavailable
+}
+
+// dependencyHints tell operators how to provide missing dependencies
+var dependencyHints = map[string]string{
+	DependencyChromium: "install chromium or google-chrome, e.g. apt install chromium, then restart the server",
+	DependencyGit:      "install git, e.g. apt install git, then restart the server",
+	DependencyNetwork:  "connect the host to a network; requests to remote hosts fail until then",
+}
+
+// routeDependencies maps route path prefixes to the dependency they need
+var routeDependencies = map[string]string{
+	"/browser/": DependencyChromium,
+	"/ide/git/": DependencyGit,
+}
+
+// rpcDependencies maps JSON-RPC method namespaces to the dependency they need
+var rpcDependencies = map[string]string{
+	"browser": DependencyChromium,
+	"git":     DependencyGit,
+}
+
+// featureDependencies maps features to the dependency without which they
+// cannot serve anything
+var featureDependencies = map[string]string{
+	FeatureBrowser: DependencyChromium,
+}
+
+func routeDependency(path string) string {
+	for prefix, name := range routeDependencies {
+		if strings.HasPrefix(path, prefix) {
+			return name
+		}
+	}
+	return ""
+}
+
+func rpcDependency(method string) string {
+	namespace, _, _ := strings.Cut(method, ".")
+	return rpcDependencies[namespace]
+}
+
+// WithDependency overrides the detected availability of a dependency, e.g. to
+// test degraded servers on hosts that have it
+func WithDependency(name string, available bool) ServerOption {
+	return func(s *Server) {
+		if s.dependencyOverrides == nil {
+			s.dependencyOverrides = make(map[string]bool)
+		}
+		s.dependencyOverrides[name] = available
+	}
+}
+
+// detectDependencies records which dependencies are present. A custom
+// browser launcher stands in for an installed chromium.
+func (s *Server) detectDependencies() {
+	deps := make(map[string]Dependency)
+	chromium := Dependency{Name: DependencyChromium, Available: s.browserLauncher != nil}
+	if !chromium.Available {
+		chromium.Path, chromium.Available = launcher.LookPath()
+	}
+	deps[DependencyChromium] = chromium
+
+	git := Dependency{Name: DependencyGit}
+	if path, err := exec.LookPath("git"); err == nil {
+		git.Path, git.Available = path, true
+	}
+	deps[DependencyGit] = git
+
+	deps[DependencyNetwork] = Dependency{Name: DependencyNetwork, Available: hasNetwork()}
+
+	for name, dep := range deps {
+		if available, ok := s.dependencyOverrides[name]; ok {
+			dep.Available = available
+		}
+		if !dep.Available {
+			dep.Path, dep.Hint = "", dependencyHints[name]
+		}
+		deps[name] = dep
+	}
+
+	s.mu.Lock()
+	s.dependencies = deps
+	s.mu.Unlock()
+}
+
+// hasNetwork reports whether an interface other than loopback is up with a
+// routable address
+func hasNetwork() bool {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return false
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.IsGlobalUnicast() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Dependencies returns the detected state of every dependency by name
+func (s *Server) Dependencies() []Dependency {
+	s.mu.Lock()
+	deps := make([]Dependency, 0, len(s.dependencies))
+	for _, dep := range s.dependencies {
+		deps = append(deps, dep)
+	}
+	s.mu.Unlock()
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Name < deps[j].Name })
+	return deps
+}
+
+// checkDependency returns an API error wrapping ErrDependencyUnavailable
+// unless the dependency is available. An empty name needs nothing.
+func (s *Server) checkDependency(name string) error {
+	if name == "" {
+		return nil
+	}
+	s.mu.Lock()
+	dep, ok := s.dependencies[name]
+	s.mu.Unlock()
+	if !ok || dep.Available {
+		return nil
+	}
+	return dependencyError(name)
+}
+
+func dependencyError(name string) error {
+	hint := dependencyHints[name]
+	return newAPIError(CodeDependencyUnavailable, fmt.Errorf("%w: %s; %s", ErrDependencyUnavailable, name, hint),
+		map[string]interface{}{"dependency": name, "hint": hint})
+}
+
+// requireDependency rejects requests while a dependency is missing
+func (s *Server) requireDependency(name string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.checkDependency(name); err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// logMissingDependencies warns about missing dependencies at startup
+func (s *Server) logMissingDependencies() {
+	for _, dep := range s.Dependencies() {
+		if !dep.Available {
+			s.logger.Warn("dependency unavailable, its subsystems are disabled", "dependency", dep.Name, "hint", dep.Hint)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ivikasavnish/go-mcp/pkg/browser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_MissingDependencies(t *testing.T) {
+	s := NewServer(nil, WithDependency(DependencyChromium, false), WithDependency(DependencyGit, false))
+	s.AddCurlHandler()
+	s.AddBrowserHandlers()
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/browser/create", strings.NewReader(`{"id":"b1"}`)))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var body ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, CodeDependencyUnavailable, body.Error.Code)
+	assert.False(t, body.Error.Retryable)
+	assert.Equal(t, DependencyChromium, body.Error.Details["dependency"])
+	assert.Contains(t, body.Error.Details["hint"], "install chromium")
+
+	resp := s.call(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"browser.create","params":{"id":"b1"}}`))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, RPCUnavailable, resp.Error.Code)
+
+	caps := s.Capabilities()
+	assert.Contains(t, caps.Features, FeatureCurl)
+	assert.NotContains(t, caps.Features, FeatureBrowser)
+	assert.Contains(t, caps.RPCMethods, "curl.replay")
+	assert.NotContains(t, caps.RPCMethods, "browser.create")
+	for _, dep := range caps.Dependencies {
+		if dep.Name == DependencyChromium || dep.Name == DependencyGit {
+			assert.False(t, dep.Available, dep.Name)
+			assert.NotEmpty(t, dep.Hint, dep.Name)
+		}
+	}
+	for _, tool := range s.MCPTools() {
+		assert.False(t, strings.HasPrefix(tool.Name, "browser_"), tool.Name)
+	}
+}
+
+// missingBrowser fails to start like a browser without a binary
+type missingBrowser struct {
+	BrowserInstance
+}
+
+func (missingBrowser) Start() error {
+	return browser.ErrNoBrowser
+}
+
+func TestServer_BrowserBinaryMissingAtLaunch(t *testing.T) {
+	s := NewServer(nil, WithBrowserLauncher(func(config *browser.BrowserConfig) BrowserInstance {
+		return missingBrowser{}
+	}))
+	s.AddBrowserHandlers()
+	assert.Contains(t, s.Capabilities().Features, FeatureBrowser)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/browser/create", strings.NewReader(`{"id":"b1"}`)))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var body ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, CodeDependencyUnavailable, body.Error.Code)
+}
@@ -0,0 +1,754 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+)
+
+// Diagnostic source names
+const (
+	SourceAnalyzer = "go-analyzer"
+	SourceLint     = "golangci-lint"
+	SourceVuln     = "govulncheck"
+	SourceSpecLint = "spec-lint"
+)
+
+// severityRank orders severities from most to least severe
+var severityRank = map[string]int{
+	"error":   0,
+	"warning": 1,
+	"info":    2,
+	"hint":    3,
+}
+
+// DiagnosticScope describes what a diagnostic source should inspect
+type DiagnosticScope struct {
+	Root string // Workspace root directory
+	Path string // File or directory relative to Root, empty for the whole workspace
+}
+
+// DiagnosticSource produces diagnostics for the aggregation endpoint
+type DiagnosticSource interface {
+	Name() string
+	Collect(ctx context.Context, scope DiagnosticScope) ([]Diagnostic, error)
+}
+
+// DiagnosticsQuery filters the aggregated diagnostics
+type DiagnosticsQuery struct {
+	Path       string   `json:"path,omitempty"`
+	Sources    []string `json:"sources,omitempty"`
+	Severities []string `json:"severities,omitempty"`
+	File       string   `json:"file,omitempty"`
+	Context    string   `json:"context,omitempty"`
+}
+
+// DiagnosticSourceStatus reports how a single source contributed to a report
+type DiagnosticSourceStatus struct {
+	Name    string `json:"name"`
+	Count   int    `json:"count"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DiagnosticsReport is the merged output of all diagnostic sources
+type DiagnosticsReport struct {
+	Diagnostics []Diagnostic             `json:"diagnostics"`
+	Counts      map[string]int           `json:"counts"`
+	Suppressed  int                      `json:"suppressed"`
+	Sources     []DiagnosticSourceStatus `json:"sources"`
+}
+
+// errSourceUnavailable marks a source whose tooling is not installed
+var errSourceUnavailable = errors.New("tool not available")
+
+// DiagnosticsAggregator merges findings from multiple diagnostic sources
+type DiagnosticsAggregator struct {
+	root    string
+	store   Store
+	sources []DiagnosticSource
+	mu      sync.RWMutex
+}
+
+// NewDiagnosticsAggregator creates an aggregator over the given sources. The
+// store is consulted for suppressions on context diagnostics and may be nil.
+func NewDiagnosticsAggregator(root string, store Store, sources ...DiagnosticSource) *DiagnosticsAggregator {
+	return &DiagnosticsAggregator{
+		root:    root,
+		store:   store,
+		sources: sources,
+	}
+}
+
+// AddSource registers an additional diagnostic source
+func (a *DiagnosticsAggregator) AddSource(src DiagnosticSource) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sources = append(a.sources, src)
+}
+
+// Collect runs the selected sources and returns the filtered, merged diagnostics
+func (a *DiagnosticsAggregator) Collect(ctx context.Context, query DiagnosticsQuery) *DiagnosticsReport {
+	a.mu.RLock()
+	sources := make([]DiagnosticSource, 0, len(a.sources))
+	for _, src := range a.sources {
+		if len(query.Sources) == 0 || containsString(query.Sources, src.Name()) {
+			sources = append(sources, src)
+		}
+	}
+	a.mu.RUnlock()
+
+	scope := DiagnosticScope{Root: a.root, Path: query.Path}
+	statuses := make([]DiagnosticSourceStatus, len(sources))
+	results := make([][]Diagnostic, len(sources))
+
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		go func(i int, src DiagnosticSource) {
+			defer wg.Done()
+			statuses[i].Name = src.Name()
+			diags, err := src.Collect(ctx, scope)
+			if errors.Is(err, errSourceUnavailable) {
+				statuses[i].Skipped = true
+				return
+			}
+			if err != nil {
+				statuses[i].Error = err.Error()
+			}
+			results[i] = diags
+		}(i, src)
+	}
+	wg.Wait()
+
+	report := &DiagnosticsReport{
+		Diagnostics: make([]Diagnostic, 0),
+		Counts:      make(map[string]int),
+		Sources:     statuses,
+	}
+
+	suppressions := newSuppressionIndex(a.root, a.store)
+	for i, diags := range results {
+		for _, d := range diags {
+			if !query.matches(d) {
+				continue
+			}
+			if suppressions.suppressed(d) {
+				report.Suppressed++
+				continue
+			}
+			report.Diagnostics = append(report.Diagnostics, d)
+			report.Counts[d.Severity]++
+			report.Sources[i].Count++
+		}
+	}
+
+	sort.SliceStable(report.Diagnostics, func(i, j int) bool {
+		di, dj := report.Diagnostics[i], report.Diagnostics[j]
+		if severityRank[di.Severity] != severityRank[dj.Severity] {
+			return severityRank[di.Severity] < severityRank[dj.Severity]
+		}
+		if di.Location.URI != dj.Location.URI {
+			return di.Location.URI < dj.Location.URI
+		}
+		return di.Location.Range.Start.Line < dj.Location.Range.Start.Line
+	})
+
+	return report
+}
+
+func (q DiagnosticsQuery) matches(d Diagnostic) bool {
+	if len(q.Severities) > 0 && !containsString(q.Severities, d.Severity) {
+		return false
+	}
+	if q.File != "" && !strings.HasPrefix(d.Location.URI, q.File) {
+		return false
+	}
+	if q.Context != "" && d.Location.URI != contextURI(q.Context) {
+		return false
+	}
+	return true
+}
+
+// AddDiagnosticsHandler adds the unified diagnostics endpoint to the MCP server
+func (s *Server) AddDiagnosticsHandler() {
+	s.diagnostics = NewDiagnosticsAggregator(s.GetWorkspaceRoot(), s.store,
+		&analyzerDiagnosticSource{},
+		&lintDiagnosticSource{},
+		&vulnDiagnosticSource{},
+		&specLintDiagnosticSource{store: s.store},
+	)
+
+	s.handle(Route{
+		Method: "GET", Path: "/diagnostics", Summary: "Collect diagnostics from every source",
+		Query: []QueryParam{
+			{Name: "path", Description: "Limit to files under this workspace path"},
+			{Name: "source", Description: "Comma separated sources to run"},
+			{Name: "severity", Description: "Comma separated severities to include"},
+			{Name: "file", Description: "Only include diagnostics of files with this prefix"},
+			{Name: "context", Description: "Only include diagnostics of this context"},
+		},
+		Response: DiagnosticsReport{},
+	}, handleDiagnostics(s.diagnostics))
+}
+
+func handleDiagnostics(agg *DiagnosticsAggregator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		query := DiagnosticsQuery{
+			Path:       q.Get("path"),
+			Sources:    splitList(q.Get("source")),
+			Severities: splitList(q.Get("severity")),
+			File:       q.Get("file"),
+			Context:    q.Get("context"),
+		}
+
+		if query.Path != "" && !isWithinRoot(agg.root, query.Path) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("path %s is outside the workspace", query.Path))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, agg.Collect(r.Context(), query))
+	}
+}
+
+// linterName matches the names of golangci-lint linters
+var linterName = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+func handleLint(root string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		query := DiagnosticsQuery{
+			Path:       q.Get("path"),
+			Severities: splitList(q.Get("severity")),
+		}
+		if query.Path != "" && !isWithinRoot(root, query.Path) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("path %s is outside the workspace", query.Path))
+			return
+		}
+		linters := splitList(q.Get("linters"))
+		for _, name := range linters {
+			if !linterName.MatchString(name) {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid linter name: %s", name))
+				return
+			}
+		}
+
+		// Findings of the analyzer come first when both report the same line
+		sources := []DiagnosticSource{&lintDiagnosticSource{linters: linters}}
+		if q.Get("native") != "false" {
+			sources = append([]DiagnosticSource{&analyzerDiagnosticSource{}}, sources...)
+		}
+		agg := NewDiagnosticsAggregator(root, nil, sources...)
+		writeJSON(w, http.StatusOK, agg.Collect(r.Context(), query))
+	}
+}
+
+// analyzerDiagnosticSource runs the AST analyzer over Go files in scope
+type analyzerDiagnosticSource struct{}
+
+func (s *analyzerDiagnosticSource) Name() string { return SourceAnalyzer }
+
+func (s *analyzerDiagnosticSource) Collect(ctx context.Context, scope DiagnosticScope) ([]Diagnostic, error) {
+	files, err := goFilesInScope(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []Diagnostic
+	for _, rel := range files {
+		if ctx.Err() != nil {
+			return diagnostics, ctx.Err()
+		}
+
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, filepath.Join(scope.Root, rel), nil, parser.ParseComments)
+		if err != nil {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: "error",
+				Message:  err.Error(),
+				Location: Location{URI: filepath.ToSlash(rel)},
+				Code:     "parse-error",
+				Source:   SourceAnalyzer,
+			})
+			continue
+		}
+
+		result, err := NewASTAnalyzer(fset).AnalyzeFile(file)
+		if err != nil {
+			return diagnostics, err
+		}
+
+		for _, d := range result.Diagnostics {
+			d.Location.URI = filepath.ToSlash(rel)
+			diagnostics = append(diagnostics, d)
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// lintDiagnosticSource shells out to golangci-lint when it is installed,
+// running the linters enabled by the workspace configuration, or only those
+// listed. Both v1 and v2, whose flags differ, are supported.
+type lintDiagnosticSource struct {
+	linters []string
+}
+
+func (s *lintDiagnosticSource) Name() string { return SourceLint }
+
+type golangciReport struct {
+	Issues []struct {
+		FromLinter string `json:"FromLinter"`
+		Text       string `json:"Text"`
+		Severity   string `json:"Severity"`
+		Pos        struct {
+			Filename string `json:"Filename"`
+			Line     int    `json:"Line"`
+			Column   int    `json:"Column"`
+		} `json:"Pos"`
+	} `json:"Issues"`
+}
+
+func (s *lintDiagnosticSource) Collect(ctx context.Context, scope DiagnosticScope) ([]Diagnostic, error) {
+	if _, err := exec.LookPath("golangci-lint"); err != nil {
+		return nil, errSourceUnavailable
+	}
+
+	executor := ide.NewCommandExecutor(scope.Root)
+	major, err := golangciMajorVersion(ctx, executor)
+	if err != nil {
+		return nil, err
+	}
+	// v2 renamed the flags, and writes its statistics after the report
+	// unless told not to
+	command, onlyEnabled := "golangci-lint run --out-format json", "--disable-all"
+	if major >= 2 {
+		command, onlyEnabled = "golangci-lint run --output.json.path stdout --show-stats=false", "--default none"
+	}
+	if len(s.linters) > 0 {
+		command += " " + onlyEnabled + " --enable " + strings.Join(s.linters, ",")
+	}
+	result, err := executor.Execute(ctx, command+" "+packagePattern(scope))
+	if err != nil {
+		return nil, err
+	}
+	// golangci-lint exits with 1 when it finds issues, which are reported
+	// on stdout; it failed when there are none
+	if !result.Success && strings.TrimSpace(result.Output) == "" {
+		return nil, fmt.Errorf("golangci-lint failed: %s", strings.TrimSpace(result.Error))
+	}
+
+	var report golangciReport
+	if err := json.Unmarshal([]byte(result.Output), &report); err != nil {
+		return nil, fmt.Errorf("failed to parse golangci-lint output: %v", err)
+	}
+
+	diagnostics := make([]Diagnostic, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		severity := issue.Severity
+		if _, ok := severityRank[severity]; !ok {
+			severity = "warning"
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: severity,
+			Message:  issue.Text,
+			Location: pointLocation(filepath.ToSlash(issue.Pos.Filename), issue.Pos.Line, issue.Pos.Column),
+			Code:     issue.FromLinter,
+			Source:   SourceLint,
+		})
+	}
+
+	return diagnostics, nil
+}
+
+// golangciVersion matches the major version golangci-lint --version prints,
+// e.g. "golangci-lint has version 2.1.6 built with go1.24.2"
+var golangciVersion = regexp.MustCompile(`version v?(\d+)\.`)
+
+// golangciMajorVersion returns the major version of the installed
+// golangci-lint
+func golangciMajorVersion(ctx context.Context, executor *ide.CommandExecutor) (int, error) {
+	result, err := executor.Execute(ctx, "golangci-lint --version")
+	if err != nil {
+		return 0, err
+	}
+	m := golangciVersion.FindStringSubmatch(result.Output)
+	if !result.Success || m == nil {
+		return 0, fmt.Errorf("failed to read the golangci-lint version: %s", strings.TrimSpace(result.Output+result.Error))
+	}
+	return strconv.Atoi(m[1])
+}
+
+// vulnDiagnosticSource reports reachable vulnerabilities found by govulncheck
+type vulnDiagnosticSource struct{}
+
+func (s *vulnDiagnosticSource) Name() string { return SourceVuln }
+
+type govulncheckMessage struct {
+	OSV *struct {
+		ID      string `json:"id"`
+		Summary string `json:"summary"`
+	} `json:"osv"`
+	Finding *struct {
+		OSV   string `json:"osv"`
+		Trace []struct {
+			Module   string `json:"module"`
+			Function string `json:"function"`
+			Position *struct {
+				Filename string `json:"filename"`
+				Line     int    `json:"line"`
+				Column   int    `json:"column"`
+			} `json:"position"`
+		} `json:"trace"`
+	} `json:"finding"`
+}
+
+func (s *vulnDiagnosticSource) Collect(ctx context.Context, scope DiagnosticScope) ([]Diagnostic, error) {
+	if _, err := exec.LookPath("govulncheck"); err != nil {
+		return nil, errSourceUnavailable
+	}
+
+	result, err := ide.NewCommandExecutor(scope.Root).Execute(ctx, "govulncheck -json "+packagePattern(scope))
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[string]string)
+	var findings []govulncheckMessage
+
+	dec := json.NewDecoder(strings.NewReader(result.Output))
+	for dec.More() {
+		var msg govulncheckMessage
+		if err := dec.Decode(&msg); err != nil {
+			return nil, fmt.Errorf("failed to parse govulncheck output: %v", err)
+		}
+		if msg.OSV != nil {
+			summaries[msg.OSV.ID] = msg.OSV.Summary
+		}
+		if msg.Finding != nil {
+			findings = append(findings, msg)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var diagnostics []Diagnostic
+	for _, msg := range findings {
+		location := Location{URI: "go.mod"}
+		for _, frame := range msg.Finding.Trace {
+			if frame.Position != nil {
+				rel, err := filepath.Rel(scope.Root, frame.Position.Filename)
+				if err != nil {
+					rel = frame.Position.Filename
+				}
+				location = pointLocation(filepath.ToSlash(rel), frame.Position.Line, frame.Position.Column)
+				break
+			}
+		}
+
+		key := msg.Finding.OSV + location.URI + fmt.Sprint(location.Range.Start.Line)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: "error",
+			Message:  fmt.Sprintf("%s: %s", msg.Finding.OSV, summaries[msg.Finding.OSV]),
+			Location: location,
+			Code:     msg.Finding.OSV,
+			Source:   SourceVuln,
+		})
+	}
+
+	return diagnostics, nil
+}
+
+// specLintDiagnosticSource checks OpenAPI contexts in the store for common problems
+type specLintDiagnosticSource struct {
+	store Store
+}
+
+func (s *specLintDiagnosticSource) Name() string { return SourceSpecLint }
+
+func (s *specLintDiagnosticSource) Collect(ctx context.Context, scope DiagnosticScope) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+	for _, c := range s.store.List() {
+		if c.Metadata["type"] != "openapi" {
+			continue
+		}
+		spec, ok := c.Metadata["spec"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		diagnostics = append(diagnostics, lintOpenAPISpec(c.ID, spec)...)
+	}
+	return diagnostics, nil
+}
+
+func lintOpenAPISpec(id string, spec map[string]interface{}) []Diagnostic {
+	var diagnostics []Diagnostic
+	add := func(severity, code, message string) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: severity,
+			Message:  message,
+			Location: Location{URI: contextURI(id)},
+			Code:     code,
+			Source:   SourceSpecLint,
+		})
+	}
+
+	info, _ := spec["info"].(map[string]interface{})
+	if info == nil {
+		add("error", "missing-info", "Specification has no info object")
+	} else {
+		if title, _ := info["title"].(string); title == "" {
+			add("warning", "missing-title", "Specification info has no title")
+		}
+		if _, ok := info["version"]; !ok {
+			add("warning", "missing-version", "Specification info has no version")
+		}
+	}
+
+	paths, _ := spec["paths"].(map[string]interface{})
+	if len(paths) == 0 {
+		add("warning", "no-paths", "Specification defines no paths")
+	}
+
+	pathNames := make([]string, 0, len(paths))
+	for p := range paths {
+		pathNames = append(pathNames, p)
+	}
+	sort.Strings(pathNames)
+
+	for _, p := range pathNames {
+		item, _ := paths[p].(map[string]interface{})
+		for _, method := range []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"} {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			opName := fmt.Sprintf("%s %s", strings.ToUpper(method), p)
+			if _, ok := op["responses"]; !ok {
+				add("error", "missing-responses", fmt.Sprintf("Operation %s defines no responses", opName))
+			}
+			if id, _ := op["operationId"].(string); id == "" {
+				add("info", "missing-operation-id", fmt.Sprintf("Operation %s has no operationId", opName))
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+// suppressionIndex honors //mcp:ignore and //nolint annotations in source
+// files and x-mcp-ignore code lists in stored specifications
+type suppressionIndex struct {
+	root  string
+	store Store
+	lines map[string][]string
+}
+
+func newSuppressionIndex(root string, store Store) *suppressionIndex {
+	return &suppressionIndex{
+		root:  root,
+		store: store,
+		lines: make(map[string][]string),
+	}
+}
+
+func (s *suppressionIndex) suppressed(d Diagnostic) bool {
+	if id := strings.TrimPrefix(d.Location.URI, contextURI("")); id != d.Location.URI {
+		return s.contextSuppresses(id, d)
+	}
+	if !strings.HasSuffix(d.Location.URI, ".go") {
+		return false
+	}
+
+	lines := s.fileLines(d.Location.URI)
+	line := d.Location.Range.Start.Line
+	for _, l := range []int{line, line - 1} {
+		if l < 0 || l >= len(lines) {
+			continue
+		}
+		if annotationSuppresses(lines[l], d) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *suppressionIndex) contextSuppresses(id string, d Diagnostic) bool {
+	if s.store == nil {
+		return false
+	}
+	c, err := s.store.Get(id)
+	if err != nil {
+		return false
+	}
+	spec, _ := c.Metadata["spec"].(map[string]interface{})
+	ignored, _ := spec["x-mcp-ignore"].([]interface{})
+	return containsInterface(ignored, d.Code)
+}
+
+func (s *suppressionIndex) fileLines(uri string) []string {
+	if lines, ok := s.lines[uri]; ok {
+		return lines
+	}
+
+	var lines []string
+	if f, err := os.Open(filepath.Join(s.root, filepath.FromSlash(uri))); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		f.Close()
+	}
+
+	s.lines[uri] = lines
+	return lines
+}
+
+func annotationSuppresses(line string, d Diagnostic) bool {
+	for _, marker := range []string{"//mcp:ignore", "//nolint"} {
+		idx := strings.Index(line, marker)
+		if idx < 0 {
+			continue
+		}
+
+		rest := line[idx+len(marker):]
+		if !strings.HasPrefix(rest, ":") {
+			return true
+		}
+
+		codes := strings.Fields(strings.TrimPrefix(rest, ":"))
+		if len(codes) == 0 {
+			return true
+		}
+		for _, code := range strings.Split(codes[0], ",") {
+			if code == d.Code || code == d.Source {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Helper functions
+
+func goFilesInScope(scope DiagnosticScope) ([]string, error) {
+	start := filepath.Join(scope.Root, scope.Path)
+	info, err := os.Stat(start)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{scope.Path}, nil
+	}
+
+	var files []string
+	err = filepath.Walk(start, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != start && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".go") {
+			rel, err := filepath.Rel(scope.Root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, rel)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// packagePattern returns the package pattern of the go tools covering a
+// scope: the packages under a directory, or the package of a file
+func packagePattern(scope DiagnosticScope) string {
+	if scope.Path == "" {
+		return "./..."
+	}
+	path := filepath.Clean(scope.Path)
+	if info, err := os.Stat(filepath.Join(scope.Root, path)); err == nil && !info.IsDir() {
+		if dir := filepath.Dir(path); dir != "." {
+			return "./" + filepath.ToSlash(dir)
+		}
+		return "."
+	}
+	if path == "." {
+		return "./..."
+	}
+	return "./" + filepath.ToSlash(path) + "/..."
+}
+
+func pointLocation(uri string, line, column int) Location {
+	pos := Position{Line: max(line-1, 0), Character: max(column-1, 0)}
+	return Location{URI: uri, Range: Range{Start: pos, End: pos}}
+}
+
+func contextURI(id string) string {
+	return "mcp://context/" + id
+}
+
+func isWithinRoot(root, rel string) bool {
+	if filepath.IsAbs(rel) {
+		return false
+	}
+	clean := filepath.Clean(rel)
+	return clean != ".." && !strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInterface(list []interface{}, value string) bool {
+	for _, item := range list {
+		if s, ok := item.(string); ok && s == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,278 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGolangciLint installs a golangci-lint on PATH that saves its
+// arguments to args and prints issues, or the version of
+// FAKE_GOLANGCI_VERSION
+const fakeGolangciLint = `#!/bin/sh
+if [ "$1" = "--version" ]; then
+	echo "golangci-lint has version ${FAKE_GOLANGCI_VERSION:-v1.64.8} built with go1.24.2"
+	exit 0
+fi
+echo "$@" > "$(dirname "$0")/args"
+cat <<'EOF'
+{"Issues":[{"FromLinter":"errcheck","Text":"Error return value is not checked","Severity":"","Pos":{"Filename":"a/a.go","Line":6,"Column":2}}]}
+EOF
+exit 1
+`
+
+func TestHandleLint(t *testing.T) {
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "golangci-lint"), []byte(fakeGolangciLint), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "a"), 0755))
+	src := "package a\n\nimport \"os\"\n\nfunc Remove() {\n\tos.Remove(\"x\")\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a", "a.go"), []byte(src), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddAnalysisHandler()
+
+	lint := func(query string) *DiagnosticsReport {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/lint?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var report DiagnosticsReport
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		return &report
+	}
+
+	// Issues of golangci-lint are merged with the findings of the analyzer
+	report := lint("path=a&linters=errcheck,govet")
+	args, err := os.ReadFile(filepath.Join(bin, "args"))
+	require.NoError(t, err)
+	assert.Equal(t, "run --out-format json --disable-all --enable errcheck,govet ./a/...\n", string(args))
+	require.Len(t, report.Sources, 2)
+	assert.Equal(t, DiagnosticSourceStatus{Name: SourceAnalyzer, Count: 1}, report.Sources[0])
+	assert.Equal(t, DiagnosticSourceStatus{Name: SourceLint, Count: 1}, report.Sources[1])
+	require.Len(t, report.Diagnostics, 2)
+	assert.Equal(t, Diagnostic{
+		Severity: "warning",
+		Message:  "Error return value is not checked",
+		Location: pointLocation("a/a.go", 6, 2),
+		Code:     "errcheck",
+		Source:   SourceLint,
+	}, report.Diagnostics[0])
+	assert.Equal(t, "missing-doc", report.Diagnostics[1].Code)
+
+	report = lint("native=false")
+	args, err = os.ReadFile(filepath.Join(bin, "args"))
+	require.NoError(t, err)
+	assert.Equal(t, "run --out-format json ./...\n", string(args))
+	require.Len(t, report.Sources, 1)
+	assert.Len(t, report.Diagnostics, 1)
+
+	// v2 renamed the flags
+	t.Setenv("FAKE_GOLANGCI_VERSION", "2.1.6")
+	versions := []struct {
+		query string
+		args  string
+	}{
+		{"native=false", "run --output.json.path stdout --show-stats=false ./...\n"},
+		{"native=false&linters=errcheck", "run --output.json.path stdout --show-stats=false --default none --enable errcheck ./...\n"},
+	}
+	for _, tt := range versions {
+		report = lint(tt.query)
+		args, err = os.ReadFile(filepath.Join(bin, "args"))
+		require.NoError(t, err)
+		assert.Equal(t, tt.args, string(args))
+		assert.Len(t, report.Diagnostics, 1)
+	}
+	t.Setenv("FAKE_GOLANGCI_VERSION", "unknown")
+	report = lint("native=false")
+	require.Len(t, report.Sources, 1)
+	assert.Contains(t, report.Sources[0].Error, "golangci-lint version")
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"outside the workspace", "path=../x"},
+		{"invalid linter", "linters=errcheck,rm%20-rf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/lint?"+tt.query, nil))
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+
+	// Without golangci-lint, only the analyzer reports
+	t.Setenv("PATH", t.TempDir())
+	report = lint("")
+	require.Len(t, report.Sources, 2)
+	assert.True(t, report.Sources[1].Skipped)
+	assert.Len(t, report.Diagnostics, 1)
+}
+
+// fakeDiagnosticSource reports fixed diagnostics, or fails with err
+type fakeDiagnosticSource struct {
+	name        string
+	diagnostics []Diagnostic
+	err         error
+}
+
+func (s *fakeDiagnosticSource) Name() string { return s.name }
+
+func (s *fakeDiagnosticSource) Collect(ctx context.Context, scope DiagnosticScope) ([]Diagnostic, error) {
+	return s.diagnostics, s.err
+}
+
+func TestDiagnosticsAggregator_Collect(t *testing.T) {
+	dir := t.TempDir()
+	src := "package a\n\nfunc A() {} //mcp:ignore\n\nfunc B() {}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte(src), 0644))
+
+	store := NewMemoryStore()
+	require.NoError(t, store.Create(&Context{ID: "petstore", Metadata: map[string]interface{}{
+		"spec": map[string]interface{}{"x-mcp-ignore": []interface{}{"no-paths"}},
+	}}))
+
+	agg := NewDiagnosticsAggregator(dir, store,
+		&fakeDiagnosticSource{name: "code", diagnostics: []Diagnostic{
+			{Severity: "warning", Code: "missing-doc", Location: pointLocation("a.go", 5, 1)},
+			{Severity: "error", Code: "shadow", Location: pointLocation("a.go", 3, 1)},
+			{Severity: "info", Code: "naming", Location: pointLocation("b.go", 1, 1)},
+		}},
+		&fakeDiagnosticSource{name: "spec", diagnostics: []Diagnostic{
+			{Severity: "warning", Code: "no-paths", Location: Location{URI: contextURI("petstore")}},
+			{Severity: "warning", Code: "missing-title", Location: Location{URI: contextURI("petstore")}},
+		}},
+		&fakeDiagnosticSource{name: "missing", err: fmt.Errorf("lookup: %w", errSourceUnavailable)},
+		&fakeDiagnosticSource{name: "broken", err: fmt.Errorf("exit status 2")},
+	)
+
+	report := agg.Collect(context.Background(), DiagnosticsQuery{})
+	require.Len(t, report.Diagnostics, 3)
+	assert.Equal(t, 2, report.Suppressed)
+	// Ordered by severity, then file and line
+	assert.Equal(t, "missing-doc", report.Diagnostics[0].Code)
+	assert.Equal(t, "missing-title", report.Diagnostics[1].Code)
+	assert.Equal(t, "naming", report.Diagnostics[2].Code)
+	assert.Equal(t, map[string]int{"warning": 2, "info": 1}, report.Counts)
+	assert.Equal(t, []DiagnosticSourceStatus{
+		{Name: "code", Count: 2},
+		{Name: "spec", Count: 1},
+		{Name: "missing", Skipped: true},
+		{Name: "broken", Error: "exit status 2"},
+	}, report.Sources)
+
+	tests := []struct {
+		name  string
+		query DiagnosticsQuery
+		want  []string
+	}{
+		{"by source", DiagnosticsQuery{Sources: []string{"spec"}}, []string{"missing-title"}},
+		{"by severity", DiagnosticsQuery{Severities: []string{"info"}}, []string{"naming"}},
+		{"by file", DiagnosticsQuery{File: "a."}, []string{"missing-doc"}},
+		{"by context", DiagnosticsQuery{Context: "petstore"}, []string{"missing-title"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var codes []string
+			for _, d := range agg.Collect(context.Background(), tt.query).Diagnostics {
+				codes = append(codes, d.Code)
+			}
+			assert.Equal(t, tt.want, codes)
+		})
+	}
+}
+
+func TestAnnotationSuppresses(t *testing.T) {
+	d := Diagnostic{Code: "errcheck", Source: SourceLint}
+	tests := []struct {
+		name string
+		line string
+		want bool
+	}{
+		{"no annotation", "\tos.Remove(x)", false},
+		{"mcp:ignore", "\tos.Remove(x) //mcp:ignore", true},
+		{"mcp:ignore by code", "\tos.Remove(x) //mcp:ignore:errcheck", true},
+		{"mcp:ignore other code", "\tos.Remove(x) //mcp:ignore:govet", false},
+		{"nolint", "\tos.Remove(x) //nolint", true},
+		{"nolint by code list", "\tos.Remove(x) //nolint:govet,errcheck // cleanup", true},
+		{"nolint by source", "\tos.Remove(x) //nolint:golangci-lint", true},
+		{"nolint other codes", "\tos.Remove(x) //nolint:govet,unused", false},
+		{"spaced marker", "\tos.Remove(x) // nolint", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, annotationSuppresses(tt.line, d))
+		})
+	}
+}
+
+func TestSuppressionIndex(t *testing.T) {
+	dir := t.TempDir()
+	src := "package a\n\n//nolint:errcheck\nfunc A() {}\n\nfunc B() {}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte(src), 0644))
+	store := NewMemoryStore()
+	require.NoError(t, store.Create(&Context{ID: "api", Metadata: map[string]interface{}{
+		"spec": map[string]interface{}{"x-mcp-ignore": []interface{}{"no-paths", 7}},
+	}}))
+	index := newSuppressionIndex(dir, store)
+
+	tests := []struct {
+		name string
+		d    Diagnostic
+		want bool
+	}{
+		{"line below annotation", Diagnostic{Code: "errcheck", Location: pointLocation("a.go", 4, 1)}, true},
+		{"annotation line", Diagnostic{Code: "errcheck", Location: pointLocation("a.go", 3, 1)}, true},
+		{"two lines below", Diagnostic{Code: "errcheck", Location: pointLocation("a.go", 5, 1)}, false},
+		{"other code", Diagnostic{Code: "govet", Location: pointLocation("a.go", 4, 1)}, false},
+		{"missing file", Diagnostic{Code: "errcheck", Location: pointLocation("b.go", 1, 1)}, false},
+		{"not a go file", Diagnostic{Code: "errcheck", Location: pointLocation("go.mod", 1, 1)}, false},
+		{"x-mcp-ignore", Diagnostic{Code: "no-paths", Location: Location{URI: contextURI("api")}}, true},
+		{"x-mcp-ignore other code", Diagnostic{Code: "missing-title", Location: Location{URI: contextURI("api")}}, false},
+		{"unknown context", Diagnostic{Code: "no-paths", Location: Location{URI: contextURI("other")}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, index.suppressed(tt.d))
+		})
+	}
+}
+
+func TestPackagePattern(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg", "a"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pkg", "a", "a.go"), []byte("package a\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644))
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", "./..."},
+		{".", "./..."},
+		{"pkg", "./pkg/..."},
+		{"pkg/a/", "./pkg/a/..."},
+		{"pkg/a/a.go", "./pkg/a"},
+		{"main.go", "."},
+		{"pkg/missing", "./pkg/missing/..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, packagePattern(DiagnosticScope{Root: dir, Path: tt.path}))
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package mcp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DocSymbol is an exported declaration of a file, documented or not.
+// Methods are named Type.Method.
+type DocSymbol struct {
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"` // const, var, func, type or method
+	Location   Location `json:"location"`
+	Documented bool     `json:"documented"`
+}
+
+// PackageDocCoverage is the documentation coverage of the exported
+// declarations of a package
+type PackageDocCoverage struct {
+	Package    string      `json:"package"` // Directory relative to the workspace root
+	Exported   int         `json:"exported"`
+	Documented int         `json:"documented"`
+	Percent    float64     `json:"percent"`
+	Symbols    []DocSymbol `json:"symbols"`
+}
+
+// DocCoverageReport is the documentation coverage of the packages of the
+// workspace
+type DocCoverageReport struct {
+	Exported   int                  `json:"exported"`
+	Documented int                  `json:"documented"`
+	Percent    float64              `json:"percent"`
+	Packages   []PackageDocCoverage `json:"packages"`
+}
+
+// docCoverage is the percentage of documented exported symbols, 100 when
+// there are none
+func docCoverage(documented, exported int) float64 {
+	if exported == 0 {
+		return 100
+	}
+	return 100 * float64(documented) / float64(exported)
+}
+
+// exportedSymbols lists the exported functions, methods, types, constants
+// and variables of a file. Constants and variables are documented by the
+// comment of their group too.
+func (a *ASTAnalyzer) exportedSymbols(file *ast.File) []DocSymbol {
+	var symbols []DocSymbol
+	add := func(name *ast.Ident, display, kind string, doc ...*ast.CommentGroup) {
+		pos := a.fileSet.Position(name.Pos())
+		symbol := DocSymbol{
+			Name: display,
+			Kind: kind,
+			Location: Location{
+				URI: pos.Filename,
+				Range: Range{
+					Start: Position{Line: pos.Line - 1, Character: pos.Column - 1},
+					End:   Position{Line: pos.Line - 1, Character: pos.Column - 1 + len(name.Name)},
+				},
+			},
+		}
+		for _, d := range doc {
+			if d != nil {
+				symbol.Documented = true
+			}
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() {
+				continue
+			}
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				add(decl.Name, receiverName(decl.Recv.List[0].Type)+"."+decl.Name.Name, APIMethod, decl.Doc)
+			} else {
+				add(decl.Name, decl.Name.Name, APIFunc, decl.Doc)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.IsExported() {
+						add(spec.Name, spec.Name.Name, APIType, spec.Doc, singleSpecDoc(decl))
+					}
+				case *ast.ValueSpec:
+					kind := APIVar
+					if decl.Tok == token.CONST {
+						kind = APIConst
+					}
+					for _, name := range spec.Names {
+						if name.IsExported() {
+							add(name, name.Name, kind, spec.Doc, decl.Doc)
+						}
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// singleSpecDoc is the comment of a declaration of a single type, like
+// type T struct{}, which documents the type
+func singleSpecDoc(decl *ast.GenDecl) *ast.CommentGroup {
+	if decl.Lparen.IsValid() {
+		return nil
+	}
+	return decl.Doc
+}
+
+// BuildDocCoverageReport measures the documentation coverage of the
+// packages of the Go files under scope in the workspace root, tests left
+// out. Only undocumented symbols are listed when undocumented is set.
+func BuildDocCoverageReport(root, scope string, undocumented bool) (*DocCoverageReport, error) {
+	files, err := goFilesInScope(DiagnosticScope{Root: root, Path: scope})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DocCoverageReport{Packages: make([]PackageDocCoverage, 0)}
+	packageIndex := make(map[string]int)
+	for _, rel := range files {
+		if strings.HasSuffix(rel, "_test.go") {
+			continue
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, filepath.Join(root, rel), nil, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+
+		uri := filepath.ToSlash(rel)
+		dir := path.Dir(uri)
+		i, ok := packageIndex[dir]
+		if !ok {
+			i = len(report.Packages)
+			packageIndex[dir] = i
+			report.Packages = append(report.Packages, PackageDocCoverage{Package: dir, Symbols: make([]DocSymbol, 0)})
+		}
+		pkg := &report.Packages[i]
+
+		for _, symbol := range NewASTAnalyzer(fset).exportedSymbols(file) {
+			symbol.Location.URI = uri
+			pkg.Exported++
+			if symbol.Documented {
+				pkg.Documented++
+			}
+			if !undocumented || !symbol.Documented {
+				pkg.Symbols = append(pkg.Symbols, symbol)
+			}
+		}
+	}
+
+	for i := range report.Packages {
+		pkg := &report.Packages[i]
+		pkg.Percent = docCoverage(pkg.Documented, pkg.Exported)
+		report.Exported += pkg.Exported
+		report.Documented += pkg.Documented
+	}
+	report.Percent = docCoverage(report.Documented, report.Exported)
+
+	sort.Slice(report.Packages, func(i, j int) bool {
+		return report.Packages[i].Package < report.Packages[j].Package
+	})
+	return report, nil
+}
+
+func handleDocCoverage(root string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		path := q.Get("path")
+		if path != "" && !isWithinRoot(root, path) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("path %s is outside the workspace", path))
+			return
+		}
+
+		report, err := BuildDocCoverageReport(root, path, q.Get("undocumented") == "true")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	}
+}
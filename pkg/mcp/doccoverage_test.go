@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const docCoverageSource = `package m
+
+// Limits of requests
+const (
+	MaxSize = 10
+	MaxTime = 5
+)
+
+var (
+	// Default is the default client
+	Default = New()
+	Fallback = New()
+)
+
+// Client sends requests
+type Client struct{}
+
+type (
+	Option func(*Client)
+)
+
+// New creates a client
+func New() *Client { return &Client{} }
+
+func (c *Client) Do() {}
+
+func helper() {}
+`
+
+func TestHandleDocCoverage(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"m/m.go":      docCoverageSource,
+		"m/m_test.go": "package m\n\nfunc TestUndocumented() {}\n",
+		"n/n.go":      "package n\n\nfunc unexported() {}\n",
+	}
+	for name, src := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddAnalysisHandler()
+
+	report := func(query string) *DocCoverageReport {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/docs?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var report DocCoverageReport
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		return &report
+	}
+
+	all := report("")
+	assert.Equal(t, 8, all.Exported)
+	assert.Equal(t, 5, all.Documented)
+	assert.Equal(t, 62.5, all.Percent)
+	require.Len(t, all.Packages, 2)
+	assert.Len(t, all.Packages[0].Symbols, 8)
+	assert.Equal(t, PackageDocCoverage{Package: "n", Percent: 100, Symbols: []DocSymbol{}}, all.Packages[1])
+
+	undocumented := report("path=m&undocumented=true")
+	require.Len(t, undocumented.Packages, 1)
+	assert.Equal(t, 62.5, undocumented.Packages[0].Percent)
+	assert.Equal(t, []DocSymbol{
+		{Name: "Fallback", Kind: APIVar, Location: Location{URI: "m/m.go", Range: Range{Start: Position{Line: 11, Character: 1}, End: Position{Line: 11, Character: 9}}}},
+		{Name: "Option", Kind: APIType, Location: Location{URI: "m/m.go", Range: Range{Start: Position{Line: 18, Character: 1}, End: Position{Line: 18, Character: 7}}}},
+		{Name: "Client.Do", Kind: APIMethod, Location: Location{URI: "m/m.go", Range: Range{Start: Position{Line: 24, Character: 17}, End: Position{Line: 24, Character: 19}}}},
+	}, undocumented.Packages[0].Symbols)
+
+	// Files analyzed report their coverage in their metrics
+	body, err := json.Marshal(AnalysisRequest{URI: "m.go", Content: docCoverageSource})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/analyze/file", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result AnalysisResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, 8, result.Metrics.ExportedSymbols)
+	assert.Equal(t, 5, result.Metrics.DocumentedSymbols)
+	assert.Equal(t, 62.5, result.Metrics.DocCoverage)
+	var messages []string
+	for _, d := range result.Diagnostics {
+		if d.Code == "missing-doc" {
+			messages = append(messages, d.Message)
+		}
+	}
+	assert.Equal(t, []string{
+		"Exported variable Fallback lacks documentation",
+		"Exported type Option lacks documentation",
+		"Exported method Client.Do lacks documentation",
+	}, messages)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/docs?path=../outside", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMissingDocCodeActions(t *testing.T) {
+	actions, err := CodeActions(filepath.Join(t.TempDir(), "m.go"), CodeActionRequest{
+		URI: "m.go", Content: docCoverageSource, Only: []string{CodeActionQuickFix},
+	}, ImportGroupingOptions{})
+	require.NoError(t, err)
+	fixed := make(map[string]string)
+	for _, action := range actions {
+		fixed[action.Title] = applyEdits(docCoverageSource, action.Edit.Changes["m.go"])
+	}
+
+	// Specs of groups get a comment of their own
+	assert.Contains(t, fixed["Add documentation for Fallback"], "\n\t// Fallback ...\n\tFallback = New()")
+	assert.Contains(t, fixed["Add documentation for Option"], "\n\t// Option ...\n\tOption func")
+	assert.Contains(t, fixed["Add documentation for Do"], "\n// Do ...\nfunc (c *Client) Do()")
+}
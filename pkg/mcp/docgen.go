@@ -0,0 +1,536 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+)
+
+// Complexity thresholds used for documentation notes
+const (
+	moderateComplexity = 5
+	highComplexity     = 10
+)
+
+// GenerateDocsRequest represents a request to generate package documentation
+type GenerateDocsRequest struct {
+	Package   string `json:"package"`              // Package directory relative to the workspace root
+	Output    string `json:"output,omitempty"`     // Output path, defaults to docs/<package>.md
+	Template  string `json:"template,omitempty"`   // Optional text/template overriding the default
+	ContextID string `json:"context_id,omitempty"` // Optional context ID, defaults to docs-<package>
+}
+
+// GenerateDocsResponse describes the generated documentation
+type GenerateDocsResponse struct {
+	Package   string `json:"package"`
+	Path      string `json:"path"`
+	ContextID string `json:"context_id"`
+	Markdown  string `json:"markdown"`
+}
+
+// DocPackage is the data passed to documentation templates
+type DocPackage struct {
+	Name       string
+	ImportPath string
+	Doc        string
+	Functions  []DocFunction
+	Types      []DocType
+	Constants  []VariableInfo
+	Variables  []VariableInfo
+	Examples   []DocExample
+	Metrics    CodeMetrics
+}
+
+// DocFunction describes an exported function or method
+type DocFunction struct {
+	FunctionInfo
+	ComplexityNote string
+	Examples       []DocExample
+}
+
+// DocType describes an exported type with its methods
+type DocType struct {
+	TypeInfo
+	Definition string // Underlying type as written in source
+	Methods    []DocFunction
+	Examples   []DocExample
+}
+
+// DocExample is an Example function found in the package tests
+type DocExample struct {
+	Name   string
+	Suffix string
+	Code   string
+	Output string
+}
+
+const defaultDocsTemplate = `# Package {{.Name}}
+
+` + "```go" + `
+import "{{.ImportPath}}"
+` + "```" + `
+{{if .Doc}}
+{{.Doc}}{{end}}
+{{- range .Examples}}
+{{template "example" .}}{{end}}
+{{- if .Constants}}
+
+## Constants
+{{range .Constants}}
+- ` + "`{{.Name}}`" + `{{if .Doc}} — {{trim .Doc}}{{end}}{{end}}{{end}}
+{{- if .Variables}}
+
+## Variables
+{{range .Variables}}
+- ` + "`{{.Name}}`" + `{{if ne .Type "unknown"}} ({{.Type}}){{end}}{{if .Doc}} — {{trim .Doc}}{{end}}{{end}}{{end}}
+{{- if .Functions}}
+
+## Functions
+{{range .Functions}}
+{{template "function" .}}{{end}}{{end}}
+{{- if .Types}}
+
+## Types
+{{range .Types}}
+### {{.Name}}
+
+` + "```go" + `
+type {{.Name}} {{.Definition}}
+` + "```" + `
+{{if .Doc}}
+{{.Doc}}{{end}}
+{{- if .Fields}}
+| Field | Type | Description |
+|-------|------|-------------|
+{{range .Fields}}| ` + "`{{.Name}}`" + ` | ` + "`{{.Type}}`" + ` | {{trim .Doc}} |
+{{end}}{{end}}
+{{- range .Examples}}
+{{template "example" .}}{{end}}
+{{- range .Methods}}
+{{template "function" .}}{{end}}{{end}}{{end}}
+{{define "function"}}#### {{if .IsMethod}}({{.Receiver}}) {{end}}{{.Name}}
+
+` + "```go" + `
+{{.Signature}}
+` + "```" + `
+{{if .Doc}}
+{{.Doc}}{{end}}
+{{- if .ComplexityNote}}
+> {{.ComplexityNote}}
+{{end}}
+{{- range .Examples}}
+{{template "example" .}}{{end}}{{end}}
+{{define "example"}}<details><summary>Example{{if .Suffix}} ({{.Suffix}}){{end}}</summary>
+
+` + "```go" + `
+{{.Code}}
+` + "```" + `
+{{if .Output}}
+Output:
+
+` + "```" + `
+{{.Output}}
+` + "```" + `
+{{end}}
+</details>
+{{end}}`
+
+// DocGenerator renders Markdown API documentation from analyzer output
+type DocGenerator struct {
+	root        string
+	fileManager *ide.FileManager
+	store       Store
+}
+
+// NewDocGenerator creates a documentation generator for a workspace
+func NewDocGenerator(root string, store Store) *DocGenerator {
+	return &DocGenerator{
+		root:        root,
+		fileManager: ide.NewFileManager(root),
+		store:       store,
+	}
+}
+
+// Generate renders documentation for the package in dir (relative to the
+// workspace root) using the given template, or the default one when empty
+func (g *DocGenerator) Generate(dir string, tmpl string) (string, *DocPackage, error) {
+	pkg, err := g.buildPackage(dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if tmpl == "" {
+		tmpl = defaultDocsTemplate
+	}
+	t, err := template.New("docs").Funcs(template.FuncMap{
+		"trim": func(s string) string { return strings.Join(strings.Fields(s), " ") },
+	}).Parse(tmpl)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, pkg); err != nil {
+		return "", nil, fmt.Errorf("failed to render documentation: %v", err)
+	}
+
+	return buf.String(), pkg, nil
+}
+
+func (g *DocGenerator) buildPackage(dir string) (*DocPackage, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, filepath.Join(g.root, dir), func(fi os.FileInfo) bool {
+		return strings.HasSuffix(fi.Name(), ".go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse package: %v", err)
+	}
+
+	var files, testFiles []*ast.File
+	var pkgName string
+	for name, p := range pkgs {
+		if strings.HasSuffix(name, "_test") {
+			for _, f := range p.Files {
+				testFiles = append(testFiles, f)
+			}
+			continue
+		}
+		pkgName = name
+		for filename, f := range p.Files {
+			if strings.HasSuffix(filename, "_test.go") {
+				testFiles = append(testFiles, f)
+			} else {
+				files = append(files, f)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go package found in %s", dir)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return fset.Position(files[i].Pos()).Filename < fset.Position(files[j].Pos()).Filename
+	})
+
+	result, err := NewASTAnalyzer(fset).AnalyzePackage(dir, files)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &DocPackage{
+		Name:       pkgName,
+		ImportPath: importPathFor(g.root, dir),
+		Metrics:    result.Metrics,
+	}
+	definitions := make(map[string]string)
+	for _, f := range files {
+		if f.Doc != nil && pkg.Doc == "" && !isFileHeader(f.Doc.Text()) {
+			pkg.Doc = f.Doc.Text()
+		}
+		for _, decl := range f.Decls {
+			if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.TYPE {
+				for _, spec := range gen.Specs {
+					ts := spec.(*ast.TypeSpec)
+					switch ts.Type.(type) {
+					case *ast.StructType, *ast.InterfaceType:
+						definitions[ts.Name.Name] = nodeKeyword(ts.Type)
+					default:
+						definitions[ts.Name.Name] = nodeString(fset, ts.Type)
+					}
+				}
+			}
+		}
+	}
+
+	examples := collectExamples(fset, testFiles)
+	pkg.Examples = examples[""]
+
+	types := make(map[string]*DocType)
+	var typeNames []string
+	for _, t := range result.Types {
+		if !ast.IsExported(t.Name) {
+			continue
+		}
+		var fields []FieldInfo
+		for _, field := range t.Fields {
+			if ast.IsExported(field.Name) {
+				fields = append(fields, field)
+			}
+		}
+		t.Fields = fields
+
+		types[t.Name] = &DocType{
+			TypeInfo:   t,
+			Definition: definitions[t.Name],
+			Examples:   examples[t.Name],
+		}
+		typeNames = append(typeNames, t.Name)
+	}
+
+	for _, fn := range result.Functions {
+		if !ast.IsExported(fn.Name) {
+			continue
+		}
+
+		df := DocFunction{
+			FunctionInfo:   fn,
+			ComplexityNote: complexityNote(fn.Complexity),
+		}
+
+		if fn.IsMethod {
+			recv := strings.TrimPrefix(fn.Receiver, "*")
+			t, ok := types[recv]
+			if !ok {
+				continue
+			}
+			df.Examples = examples[recv+"."+fn.Name]
+			t.Methods = append(t.Methods, df)
+			continue
+		}
+
+		df.Examples = examples[fn.Name]
+		pkg.Functions = append(pkg.Functions, df)
+	}
+
+	sort.Strings(typeNames)
+	for _, name := range typeNames {
+		pkg.Types = append(pkg.Types, *types[name])
+	}
+	sort.Slice(pkg.Functions, func(i, j int) bool {
+		return pkg.Functions[i].Name < pkg.Functions[j].Name
+	})
+
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || (gen.Tok != token.CONST && gen.Tok != token.VAR) {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				doc := vs.Doc
+				if doc == nil {
+					doc = vs.Comment
+				}
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				for _, name := range vs.Names {
+					if !ast.IsExported(name.Name) {
+						continue
+					}
+					v := VariableInfo{Name: name.Name, Type: "unknown", Doc: doc.Text()}
+					if vs.Type != nil {
+						v.Type = nodeString(fset, vs.Type)
+					}
+					if gen.Tok == token.CONST {
+						v.Constant = true
+						pkg.Constants = append(pkg.Constants, v)
+					} else {
+						pkg.Variables = append(pkg.Variables, v)
+					}
+				}
+			}
+		}
+	}
+
+	return pkg, nil
+}
+
+// collectExamples indexes Example functions by the symbol they document:
+// "" for the package, "Name" for functions and types, "Type.Method" for methods
+func collectExamples(fset *token.FileSet, files []*ast.File) map[string][]DocExample {
+	examples := make(map[string][]DocExample)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !strings.HasPrefix(fn.Name.Name, "Example") || fn.Body == nil {
+				continue
+			}
+
+			name := strings.TrimPrefix(fn.Name.Name, "Example")
+			name = strings.TrimPrefix(name, "_")
+
+			var suffix string
+			if i := strings.LastIndex(name, "_"); i >= 0 && i+1 < len(name) && !ast.IsExported(name[i+1:]) {
+				name, suffix = name[:i], name[i+1:]
+			}
+			name = strings.Replace(name, "_", ".", 1)
+
+			var output string
+			for _, c := range f.Comments {
+				if c.Pos() > fn.Body.Lbrace && c.End() < fn.Body.Rbrace {
+					text := c.Text()
+					if strings.HasPrefix(text, "Output:") {
+						output = strings.TrimSpace(strings.TrimPrefix(text, "Output:"))
+					}
+				}
+			}
+
+			code := nodeString(fset, fn.Body)
+			code = strings.TrimSuffix(strings.TrimPrefix(code, "{"), "}")
+			examples[name] = append(examples[name], DocExample{
+				Name:   fn.Name.Name,
+				Suffix: suffix,
+				Code:   strings.TrimSpace(dedent(code)),
+				Output: output,
+			})
+		}
+	}
+	return examples
+}
+
+// isFileHeader reports whether a file comment is just a path marker like
+// "pkg/mcp/server.go" rather than package documentation
+func isFileHeader(doc string) bool {
+	doc = strings.TrimSpace(doc)
+	return !strings.Contains(doc, "\n") && strings.HasSuffix(doc, ".go")
+}
+
+func nodeKeyword(node ast.Node) string {
+	if _, ok := node.(*ast.InterfaceType); ok {
+		return "interface"
+	}
+	return "struct"
+}
+
+func complexityNote(complexity int) string {
+	switch {
+	case complexity >= highComplexity:
+		return fmt.Sprintf("High cyclomatic complexity (%d); consider splitting this function.", complexity)
+	case complexity >= moderateComplexity:
+		return fmt.Sprintf("Moderate cyclomatic complexity (%d).", complexity)
+	default:
+		return ""
+	}
+}
+
+func nodeString(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+func dedent(code string) string {
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, "\t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// importPathFor derives the import path of dir from the workspace module path
+func importPathFor(root, dir string) string {
+	mod := modulePath(root)
+	rel := filepath.ToSlash(filepath.Clean(dir))
+	if mod == "" {
+		return rel
+	}
+	if rel == "." {
+		return mod
+	}
+	return mod + "/" + rel
+}
+
+// docsContextID builds the default context ID for a package's documentation
+func docsContextID(dir string) string {
+	rel := strings.Trim(filepath.ToSlash(filepath.Clean(dir)), "./")
+	if rel == "" {
+		rel = "root"
+	}
+	return "docs-" + strings.NewReplacer("/", "-", ".", "-").Replace(rel)
+}
+
+func handleGenerateDocs(g *DocGenerator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req GenerateDocsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if req.Package == "" {
+			req.Package = "."
+		}
+		if !isWithinRoot(g.root, req.Package) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("package %s is outside the workspace", req.Package))
+			return
+		}
+
+		markdown, pkg, err := g.Generate(req.Package, req.Template)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		output := req.Output
+		if output == "" {
+			output = filepath.Join("docs", pkg.Name+".md")
+		}
+		if !isWithinRoot(g.root, output) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("output %s is outside the workspace", output))
+			return
+		}
+
+		if err := g.fileManager.CreateFile(output, []byte(markdown)); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		contextID := req.ContextID
+		if contextID == "" {
+			contextID = docsContextID(req.Package)
+		}
+
+		now := time.Now()
+		ctx := &Context{
+			ID: contextID,
+			Metadata: map[string]interface{}{
+				"type":        "docs",
+				"package":     pkg.ImportPath,
+				"source":      output,
+				"markdown":    markdown,
+				"generatedAt": now,
+			},
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		if err := g.store.Create(ctx); err == ErrContextExists {
+			existing, getErr := g.store.Get(contextID)
+			if getErr == nil {
+				ctx.CreatedAt = existing.CreatedAt
+			}
+			err = g.store.Update(ctx)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+		} else if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, GenerateDocsResponse{
+			Package:   pkg.ImportPath,
+			Path:      filepath.ToSlash(output),
+			ContextID: contextID,
+			Markdown:  markdown,
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const docgenSource = `// Package routes maps paths to handlers.
+package routes
+
+// DefaultMethod is used when a route has none
+const DefaultMethod = "GET"
+
+// ErrNoRoute is returned for unknown paths
+var ErrNoRoute error
+
+// Route binds a path to a handler
+type Route struct {
+	// Path is matched exactly
+	Path    string
+	Handler Handler
+	hits    int
+}
+
+// Handler serves a route
+type Handler interface {
+	Serve(path string) string
+}
+
+// Method is an HTTP method name
+type Method string
+
+// Match reports whether the route serves path
+func (r *Route) Match(path string) bool { return r.Path == path }
+
+// Find returns the route for path
+func Find(routes []Route, path string) (*Route, error) {
+	for i := range routes {
+		if routes[i].Match(path) {
+			return &routes[i], nil
+		}
+	}
+	return nil, ErrNoRoute
+}
+`
+
+const docgenExample = `package routes
+
+import "fmt"
+
+func ExampleFind() {
+	r, _ := Find([]Route{{Path: "/"}}, "/")
+	fmt.Println(r.Path)
+	// Output: /
+}
+`
+
+// writeDocgenWorkspace lays out a module with the routes package
+func writeDocgenWorkspace(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                 "module example.com/app\n\ngo 1.22\n",
+		"routes/routes.go":       docgenSource,
+		"routes/example_test.go": docgenExample,
+	}
+	for name, src := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+	return dir
+}
+
+func TestDocGenerator_Golden(t *testing.T) {
+	markdown, pkg, err := NewDocGenerator(writeDocgenWorkspace(t), NewMemoryStore()).Generate("routes", "")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com/app/routes", pkg.ImportPath)
+
+	golden, err := os.ReadFile(filepath.Join("testdata", "docgen.golden.md"))
+	require.NoError(t, err)
+	assert.Equal(t, string(golden), markdown)
+}
+
+func TestDocGenerator_Template(t *testing.T) {
+	g := NewDocGenerator(writeDocgenWorkspace(t), NewMemoryStore())
+
+	markdown, _, err := g.Generate("routes", "{{range .Types}}{{.Name}}:{{len .Methods}} {{end}}")
+	require.NoError(t, err)
+	assert.Equal(t, "Handler:0 Method:0 Route:1 ", markdown)
+
+	_, _, err = g.Generate("routes", "{{.Missing")
+	assert.ErrorContains(t, err, "invalid template")
+	_, _, err = g.Generate("missing", "")
+	assert.Error(t, err)
+}
+
+func TestHandleGenerateDocs(t *testing.T) {
+	dir := writeDocgenWorkspace(t)
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddGenerateHandlers()
+
+	generate := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/generate/docs", strings.NewReader(body)))
+		return w
+	}
+
+	w := generate(`{"package":"routes"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp GenerateDocsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "example.com/app/routes", resp.Package)
+	assert.Equal(t, "docs/routes.md", resp.Path)
+	assert.Equal(t, "docs-routes", resp.ContextID)
+
+	written, err := os.ReadFile(filepath.Join(dir, "docs", "routes.md"))
+	require.NoError(t, err)
+	assert.Equal(t, resp.Markdown, string(written))
+	ctx, err := s.store.Get("docs-routes")
+	require.NoError(t, err)
+	assert.Equal(t, resp.Markdown, ctx.Metadata["markdown"])
+
+	// Generating again updates the context in place
+	require.Equal(t, http.StatusCreated, generate(`{"package":"routes","template":"{{.Name}}"}`).Code)
+	ctx, err = s.store.Get("docs-routes")
+	require.NoError(t, err)
+	assert.Equal(t, "routes", ctx.Metadata["markdown"])
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"package outside workspace", `{"package":"../elsewhere"}`},
+		{"output outside workspace", `{"package":"routes","output":"../routes.md"}`},
+		{"invalid template", `{"package":"routes","template":"{{"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, http.StatusBadRequest, generate(tt.body).Code)
+		})
+	}
+}
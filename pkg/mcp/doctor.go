@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+)
+
+// preflightChecks collects the tool checks of every IDE project and of the
+// browser module when it is enabled
+func (s *Server) preflightChecks() []ide.ToolCheck {
+	s.mu.Lock()
+	ideServers := append([]*IDEServer(nil), s.ideServers...)
+	s.mu.Unlock()
+
+	var checks []ide.ToolCheck
+	index := make(map[string]int)
+	for _, ideServer := range ideServers {
+		for _, check := range ide.ProjectChecks(ideServer.projectManager.GetConfig()) {
+			// A tool shared by several projects is required if any needs it
+			if i, ok := index[check.Name]; ok {
+				checks[i].Required = checks[i].Required || check.Required
+				continue
+			}
+			index[check.Name] = len(checks)
+			checks = append(checks, check)
+		}
+	}
+
+	if s.browsers != nil {
+		path, _ := launcher.LookPath()
+		checks = append(checks, ide.ToolCheck{
+			Name: "chromium", Path: path, VersionArgs: []string{"--version"},
+			Hint: dependencyHints[DependencyChromium],
+		})
+	}
+	return checks
+}
+
+// RunPreflight checks the tooling required by the enabled features, logs
+// problems and records the report served by /readyz
+func (s *Server) RunPreflight(ctx context.Context) *ide.DoctorReport {
+	// Tools installed since startup bring their subsystems back
+	s.detectDependencies()
+	report := ide.RunChecks(ctx, s.preflightChecks())
+
+	for _, check := range report.Checks {
+		if check.Status == ide.CheckOK {
+			continue
+		}
+		log := s.logger.Info
+		if check.Required {
+			log = s.logger.Warn
+		}
+		log("preflight check failed", "tool", check.Name, "status", check.Status,
+			"required", check.Required, "message", check.Message, "hint", check.Hint)
+	}
+
+	s.mu.Lock()
+	s.preflight = report
+	s.mu.Unlock()
+	return report
+}
+
+// handleReadyz reports ready once preflight checks have run and all required
+// tools are present
+func handleReadyz(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		report := s.preflight
+		s.mu.Unlock()
+
+		if report == nil {
+			writeError(w, http.StatusServiceUnavailable, fmt.Errorf("preflight checks have not completed"))
+			return
+		}
+
+		status := http.StatusOK
+		if !report.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	}
+}
+
+func handleDoctor(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.RunPreflight(r.Context()))
+	}
+}
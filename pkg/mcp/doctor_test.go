@@ -0,0 +1,41 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadyz(t *testing.T) {
+	s := NewServer(nil)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	s.RunPreflight(context.Background())
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+
+	var report ide.DoctorReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.True(t, report.Ready)
+
+	s.mu.Lock()
+	s.preflight = &ide.DoctorReport{Checks: []ide.CheckResult{{Name: "go", Status: ide.CheckMissing, Required: true}}}
+	s.mu.Unlock()
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"missing"`)
+}
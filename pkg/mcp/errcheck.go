@@ -0,0 +1,100 @@
+package mcp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+)
+
+// errcheckDiagnostics reports the errors a file drops, like errcheck: calls
+// whose error result is not used, deferred calls like defer f.Close() and
+// calls of go statements included, and errors assigned to the blank
+// identifier, which are explicit and only informational. Functions of
+// ignoredErrors are left out, and so are calls whose type is unknown, like
+// those of packages the analyzer did not load.
+func (a *ASTAnalyzer) errcheckDiagnostics(file *ast.File) []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(call *ast.CallExpr, severity, code, format string) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: severity,
+			Message:  fmt.Sprintf(format, types.ExprString(call.Fun)),
+			Location: a.nodeLocation(call),
+			Code:     code,
+			Source:   SourceAnalyzer,
+		})
+	}
+	// dropsError reports whether a call whose results are all dropped
+	// returns an error
+	dropsError := func(call *ast.CallExpr) bool {
+		results := a.resultTypes(call)
+		return len(results) > 0 && isError(results[len(results)-1]) && !ignoredErrors[calleeName(a.typeInfo, call)]
+	}
+	blank := func(expr ast.Expr) bool {
+		ident, ok := expr.(*ast.Ident)
+		return ok && ident.Name == "_"
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ExprStmt:
+			if call, ok := ast.Unparen(n.X).(*ast.CallExpr); ok && dropsError(call) {
+				report(call, "warning", "unchecked-error", "Error returned by %s is not checked")
+			}
+		case *ast.DeferStmt:
+			if dropsError(n.Call) {
+				report(n.Call, "warning", "unchecked-error", "Error returned by deferred %s is not checked")
+			}
+		case *ast.GoStmt:
+			if dropsError(n.Call) {
+				report(n.Call, "warning", "unchecked-error", "Error returned by %s in a goroutine is not checked")
+			}
+		case *ast.AssignStmt:
+			if len(n.Rhs) == 1 && len(n.Lhs) > 1 {
+				// x, _ := f()
+				call, ok := ast.Unparen(n.Rhs[0]).(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				results := a.resultTypes(call)
+				for i, lhs := range n.Lhs {
+					if i < len(results) && blank(lhs) && isError(results[i]) {
+						report(call, "info", "ignored-error", "Error returned by %s is assigned to _")
+					}
+				}
+				return true
+			}
+			for i, rhs := range n.Rhs {
+				call, ok := ast.Unparen(rhs).(*ast.CallExpr)
+				if !ok || i >= len(n.Lhs) || !blank(n.Lhs[i]) {
+					continue
+				}
+				if results := a.resultTypes(call); len(results) == 1 && isError(results[0]) {
+					report(call, "info", "ignored-error", "Error returned by %s is assigned to _")
+				}
+			}
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// resultTypes returns the types of the results of a call, none when its
+// type is unknown
+func (a *ASTAnalyzer) resultTypes(call *ast.CallExpr) []types.Type {
+	t := a.typeInfo.TypeOf(call)
+	switch t := t.(type) {
+	case nil:
+		return nil
+	case *types.Tuple:
+		results := make([]types.Type, t.Len())
+		for i := range results {
+			results[i] = t.At(i).Type()
+		}
+		return results
+	case *types.Basic:
+		if t.Kind() == types.Invalid {
+			return nil
+		}
+	}
+	return []types.Type{t}
+}
@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const errcheckSource = `package p
+
+type closer interface {
+	Close() error
+}
+
+func open() (closer, error) { return nil, nil }
+
+func check() error { return nil }
+
+func run(c closer) {
+	check()
+	defer c.Close()
+	go check()
+	_ = check()
+	_, _ = open()
+	x, _ := open()
+	_ = x
+	a, _ := check(), check()
+	_ = a
+	if err := check(); err != nil {
+		return
+	}
+	f := func() {}
+	f()
+	println("done")
+}
+`
+
+func TestErrcheckDiagnostics(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", errcheckSource, parser.ParseComments)
+	require.NoError(t, err)
+	result, err := NewASTAnalyzer(fset).AnalyzeFile(file)
+	require.NoError(t, err)
+
+	var found []string
+	for _, d := range result.Diagnostics {
+		if d.Code == "unchecked-error" || d.Code == "ignored-error" {
+			found = append(found, d.Severity+": "+d.Message)
+		}
+	}
+	assert.Equal(t, []string{
+		"warning: Error returned by check is not checked",
+		"warning: Error returned by deferred c.Close is not checked",
+		"warning: Error returned by check in a goroutine is not checked",
+		"info: Error returned by check is assigned to _",
+		"info: Error returned by open is assigned to _",
+		"info: Error returned by open is assigned to _",
+		"info: Error returned by check is assigned to _",
+	}, found)
+
+	for _, d := range result.Diagnostics {
+		if d.Message == "Error returned by deferred c.Close is not checked" {
+			assert.Equal(t, Range{Start: Position{Line: 12, Character: 7}, End: Position{Line: 12, Character: 16}}, d.Location.Range)
+		}
+	}
+}
+
+func TestErrcheckDiagnosticsModule(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.22\n",
+		"m.go": `package m
+
+import (
+	"fmt"
+	"os"
+)
+
+func write(name string) {
+	f, _ := os.Create(name)
+	defer f.Close()
+	fmt.Println("writing", name)
+	f.WriteString("data")
+}
+`,
+	}
+	for name, src := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+
+	analysis, err := AnalyzeModule(dir, ".", "")
+	require.NoError(t, err)
+	var found []string
+	for _, d := range analysis.Diagnostics {
+		if d.Code == "unchecked-error" || d.Code == "ignored-error" {
+			found = append(found, d.Message)
+		}
+	}
+	// Functions of packages loaded resolve, fmt.Println is ignored
+	assert.Equal(t, []string{
+		"Error returned by os.Create is assigned to _",
+		"Error returned by deferred f.Close is not checked",
+		"Error returned by f.WriteString is not checked",
+	}, found)
+}
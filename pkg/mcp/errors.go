@@ -1 +1,135 @@
 package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Error codes of API error responses. Clients branch on codes rather than
+// messages, which may change between releases.
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeFeatureDisabled  = "feature_disabled"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeRateLimited      = "rate_limited"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeInternal         = "internal"
+	CodeNotImplemented   = "not_implemented"
+	CodeUpstream         = "upstream_error"
+	CodeUnavailable      = "unavailable"
+	CodeTimeout          = "timeout"
+	CodeFunctionError    = "function_error" // A registered function returned an error
+
+	CodeDependencyUnavailable = "dependency_unavailable" // Needs a tool the host lacks; not retryable until it is installed
+)
+
+// APIError is the machine-readable error of an error response. Handlers
+// return it to choose the code or add details; other errors are classified
+// by their sentinel and the response status.
+type APIError struct {
+	Code          string                 `json:"code"`
+	Message       string                 `json:"message"`
+	Details       map[string]interface{} `json:"details,omitempty"`
+	Retryable     bool                   `json:"retryable"`                // Repeating the request may succeed
+	CorrelationID string                 `json:"correlation_id,omitempty"` // The X-Request-ID of the request, for finding it in logs
+	err           error
+}
+
+// newAPIError creates an API error with details
+func newAPIError(code string, err error, details map[string]interface{}) *APIError {
+	return &APIError{Code: code, Message: err.Error(), Details: details, Retryable: retryableCodes[code], err: err}
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the error the API error was created from
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// statusCodes are the codes of error statuses
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            CodeInvalidRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusUnprocessableEntity:   CodeInvalidRequest,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusInternalServerError:   CodeInternal,
+	http.StatusNotImplemented:        CodeNotImplemented,
+	http.StatusBadGateway:            CodeUpstream,
+	http.StatusServiceUnavailable:    CodeUnavailable,
+	http.StatusGatewayTimeout:        CodeTimeout,
+}
+
+// codeStatus returns the status of errors with a code, the lowest of those
+// with it, or 500 for codes of no status
+func codeStatus(code string) int {
+	status := http.StatusInternalServerError
+	for s, c := range statusCodes {
+		if c == code && s < status {
+			status = s
+		}
+	}
+	return status
+}
+
+// retryableCodes are the codes of transient failures
+var retryableCodes = map[string]bool{
+	CodeRateLimited: true,
+	CodeUpstream:    true,
+	CodeUnavailable: true,
+	CodeTimeout:     true,
+}
+
+// classifyError returns the API error of an error written with a status.
+// Quotas, disabled features, body limits and deadlines have codes of their
+// own; other errors get the code of the status.
+func classifyError(status int, err error) APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return *apiErr
+	}
+
+	e := APIError{Message: err.Error()}
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		e.Code = CodePayloadTooLarge
+		e.Details = map[string]interface{}{"limit": tooLarge.Limit}
+	case errors.Is(err, ErrQuotaExceeded):
+		e.Code = CodeQuotaExceeded
+	case errors.Is(err, ErrFeatureDisabled), errors.Is(err, ErrCapabilityDisabled):
+		e.Code = CodeFeatureDisabled
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Code = CodeTimeout
+	default:
+		code, ok := statusCodes[status]
+		switch {
+		case ok:
+			e.Code = code
+		case status >= 500:
+			e.Code = CodeInternal
+		default:
+			e.Code = CodeInvalidRequest
+		}
+	}
+	e.Retryable = retryableCodes[e.Code]
+	return e
+}
@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	for _, tc := range []struct {
+		status    int
+		err       error
+		code      string
+		retryable bool
+	}{
+		{http.StatusNotFound, fmt.Errorf("%w: spec", ErrContextNotFound), CodeNotFound, false},
+		{http.StatusBadRequest, errors.New("bad json"), CodeInvalidRequest, false},
+		{http.StatusTeapot, errors.New("short and stout"), CodeInvalidRequest, false},
+		{http.StatusTooManyRequests, fmt.Errorf("%w: requests of acme", ErrQuotaExceeded), CodeQuotaExceeded, false},
+		{http.StatusForbidden, fmt.Errorf("%w: ssh", ErrFeatureDisabled), CodeFeatureDisabled, false},
+		{http.StatusInternalServerError, context.DeadlineExceeded, CodeTimeout, true},
+		{http.StatusBadGateway, errors.New("connection refused"), CodeUpstream, true},
+		{http.StatusServiceUnavailable, errors.New("injected fault"), CodeUnavailable, true},
+		{http.StatusInsufficientStorage, errors.New("disk full"), CodeInternal, false},
+		{http.StatusBadRequest, newAPIError(CodeConflict, errors.New("taken"), nil), CodeConflict, false},
+	} {
+		e := classifyError(tc.status, tc.err)
+		assert.Equal(t, tc.code, e.Code, tc.err.Error())
+		assert.Equal(t, tc.retryable, e.Retryable, tc.err.Error())
+		assert.Equal(t, tc.err.Error(), e.Message)
+	}
+
+	e := classifyError(http.StatusBadRequest, fmt.Errorf("decode: %w", &http.MaxBytesError{Limit: 10}))
+	assert.Equal(t, CodePayloadTooLarge, e.Code)
+	assert.Equal(t, map[string]interface{}{"limit": int64(10)}, e.Details)
+
+	assert.ErrorIs(t, newAPIError(CodeFeatureDisabled, fmt.Errorf("%w: ssh", ErrFeatureDisabled), nil), ErrFeatureDisabled)
+}
+
+func TestErrorResponses(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Features[FeatureSSH] = false
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddSSHHandler()
+
+	do := func(method, path string) (*httptest.ResponseRecorder, APIError) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+		s.ServeHTTP(rec, req)
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp), rec.Body.String())
+		return rec, resp.Error
+	}
+
+	rec, e := do("GET", "/v1/context/get?id=missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, APIError{Code: CodeNotFound, Message: "context not found", CorrelationID: "req-1"}, e)
+
+	rec, e = do("DELETE", "/v1/ssh/web")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, CodeFeatureDisabled, e.Code)
+	assert.Equal(t, map[string]interface{}{"feature": FeatureSSH}, e.Details)
+
+	rec, e = do("GET", "/v1/nowhere")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, CodeNotFound, e.Code)
+
+	rec, e = do("DELETE", "/v1/context/list")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, CodeMethodNotAllowed, e.Code)
+}
@@ -0,0 +1,298 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Errors returned when a request needs something that is switched off
+var (
+	ErrFeatureDisabled    = errors.New("feature is disabled")
+	ErrCapabilityDisabled = errors.New("capability is disabled")
+	ErrFeatureNotLoaded   = errors.New("feature was not enabled at startup")
+	ErrUnknownToggle      = errors.New("unknown toggle")
+	ErrTogglesLocked      = errors.New("runtime toggles are disabled")
+)
+
+// routeFeatures maps the first segment of a route path to the feature serving
+// it. Routes of other segments, such as /context, are always available.
+var routeFeatures = map[string]string{
+	"analyze":     FeatureAnalysis,
+	"browser":     FeatureBrowser,
+	"curl":        FeatureCurl,
+	"diagnostics": FeatureDiagnostics,
+	"docs":        FeatureDocs,
+	"function":    FeatureFunctions,
+	"generate":    FeatureGenerate,
+	"ide":         FeatureIDE,
+	"lsp":         FeatureLSP,
+	"refactor":    FeatureLSP,
+	"ssh":         FeatureSSH,
+}
+
+// rpcFeatures maps JSON-RPC method namespaces to features
+var rpcFeatures = map[string]string{
+	"analysis": FeatureAnalysis,
+	"browser":  FeatureBrowser,
+	"curl":     FeatureCurl,
+	"function": FeatureFunctions,
+	"git":      FeatureIDE,
+	"ssh":      FeatureSSH,
+}
+
+// grpcFeatures maps gRPC services to features
+var grpcFeatures = map[string]string{
+	"mcp.v1.AnalysisService": FeatureAnalysis,
+	"mcp.v1.FunctionService": FeatureFunctions,
+}
+
+func routeFeature(path string) string {
+	return routeFeatures[strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]]
+}
+
+func rpcFeature(method string) string {
+	namespace, _, _ := strings.Cut(method, ".")
+	return rpcFeatures[namespace]
+}
+
+// grpcFeature returns the feature of a full method name such as
+// /mcp.v1.FunctionService/Call
+func grpcFeature(fullMethod string) string {
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return grpcFeatures[service]
+}
+
+// FeatureState is the runtime state of features and capabilities. Updates
+// may list only the toggles they change.
+type FeatureState struct {
+	Features     map[string]bool `json:"features"`
+	Capabilities map[string]bool `json:"capabilities"`
+}
+
+// toggles holds the runtime state, seeded from the config. Handlers of
+// features disabled in the config are never registered, so only features
+// that were enabled at startup can be switched back on.
+type toggles struct {
+	mu           sync.RWMutex
+	features     map[string]bool
+	capabilities map[string]bool
+}
+
+func newToggles(cfg *ServerConfig) *toggles {
+	t := &toggles{features: make(map[string]bool), capabilities: make(map[string]bool)}
+	for _, name := range allFeatures() {
+		t.features[name] = cfg.FeatureEnabled(name)
+	}
+	for _, name := range allCapabilities() {
+		t.capabilities[name] = cfg.CapabilityEnabled(name)
+	}
+	return t
+}
+
+// FeatureEnabled reports whether a feature is currently switched on
+func (s *Server) FeatureEnabled(name string) bool {
+	s.toggles.mu.RLock()
+	defer s.toggles.mu.RUnlock()
+	return s.toggles.features[name]
+}
+
+// CapabilityEnabled reports whether a capability is currently switched on
+func (s *Server) CapabilityEnabled(name string) bool {
+	s.toggles.mu.RLock()
+	defer s.toggles.mu.RUnlock()
+	return s.toggles.capabilities[name]
+}
+
+// FeatureState returns the current state of every feature and capability
+func (s *Server) FeatureState() FeatureState {
+	s.toggles.mu.RLock()
+	defer s.toggles.mu.RUnlock()
+
+	state := FeatureState{Features: make(map[string]bool), Capabilities: make(map[string]bool)}
+	for name, enabled := range s.toggles.features {
+		state.Features[name] = enabled
+	}
+	for name, enabled := range s.toggles.capabilities {
+		state.Capabilities[name] = enabled
+	}
+	return state
+}
+
+// UpdateFeatures applies the toggles listed in update. Nothing is changed if
+// any toggle is unknown or names a feature whose handlers are not registered.
+func (s *Server) UpdateFeatures(update FeatureState) (FeatureState, error) {
+	for name, enabled := range update.Features {
+		if !containsString(allFeatures(), name) {
+			return FeatureState{}, fmt.Errorf("%w: feature %s", ErrUnknownToggle, name)
+		}
+		if enabled && !s.featureLoaded(name) {
+			return FeatureState{}, fmt.Errorf("%w: %s", ErrFeatureNotLoaded, name)
+		}
+	}
+	for name := range update.Capabilities {
+		if !containsString(allCapabilities(), name) {
+			return FeatureState{}, fmt.Errorf("%w: capability %s", ErrUnknownToggle, name)
+		}
+	}
+
+	s.toggles.mu.Lock()
+	for name, enabled := range update.Features {
+		s.toggles.features[name] = enabled
+	}
+	for name, enabled := range update.Capabilities {
+		s.toggles.capabilities[name] = enabled
+	}
+	s.toggles.mu.Unlock()
+	s.tools.publish()
+
+	return s.FeatureState(), nil
+}
+
+// featureLoaded reports whether any route of a feature is registered
+func (s *Server) featureLoaded(name string) bool {
+	for _, route := range s.Routes() {
+		if routeFeature(route.Path) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFeature returns an error wrapping ErrFeatureDisabled unless the
+// feature is on. An empty name belongs to no feature.
+func (s *Server) checkFeature(name string) error {
+	if name != "" && !s.FeatureEnabled(name) {
+		return fmt.Errorf("%w: %s", ErrFeatureDisabled, name)
+	}
+	return nil
+}
+
+// checkCapability returns an error wrapping ErrCapabilityDisabled unless the
+// capability is on
+func (s *Server) checkCapability(name string) error {
+	if !s.CapabilityEnabled(name) {
+		return fmt.Errorf("%w: %s", ErrCapabilityDisabled, name)
+	}
+	return nil
+}
+
+// checkSudo rejects remote commands invoking sudo unless the ssh.sudo
+// capability is on
+func (s *Server) checkSudo(command string) error {
+	if usesSudo(command) {
+		return s.checkCapability(CapabilitySSHSudo)
+	}
+	return nil
+}
+
+// requireFeature rejects requests while a feature is switched off
+func (s *Server) requireFeature(name string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.checkFeature(name); err != nil {
+			writeError(w, http.StatusForbidden, newAPIError(CodeFeatureDisabled, err, map[string]interface{}{"feature": name}))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// requireCapability rejects requests while a capability is switched off
+func (s *Server) requireCapability(name string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.checkCapability(name); err != nil {
+			writeError(w, http.StatusForbidden, newAPIError(CodeFeatureDisabled, err, map[string]interface{}{"capability": name}))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// ServerCapabilities advertises what the server currently offers
+type ServerCapabilities struct {
+	APIVersion   string       `json:"api_version"`
+	Features     []string     `json:"features"`
+	Capabilities []string     `json:"capabilities"`
+	RPCMethods   []string     `json:"rpc_methods"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// Capabilities returns the enabled features and capabilities, and the
+// JSON-RPC methods that can currently be called. Features and methods whose
+// dependencies are missing are left out.
+func (s *Server) Capabilities() ServerCapabilities {
+	state := s.FeatureState()
+	caps := ServerCapabilities{
+		APIVersion:   APIVersion,
+		Features:     []string{},
+		Capabilities: []string{},
+		RPCMethods:   []string{},
+		Dependencies: s.Dependencies(),
+	}
+	for name, enabled := range state.Features {
+		if enabled && s.featureLoaded(name) && s.checkDependency(featureDependencies[name]) == nil {
+			caps.Features = append(caps.Features, name)
+		}
+	}
+	for name, enabled := range state.Capabilities {
+		if enabled {
+			caps.Capabilities = append(caps.Capabilities, name)
+		}
+	}
+	for _, method := range s.RPCMethods() {
+		if feature := rpcFeature(method); (feature == "" || state.Features[feature]) && s.checkDependency(rpcDependency(method)) == nil {
+			caps.RPCMethods = append(caps.RPCMethods, method)
+		}
+	}
+	sort.Strings(caps.Features)
+	sort.Strings(caps.Capabilities)
+	return caps
+}
+
+func handleCapabilities(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Capabilities())
+	}
+}
+
+func handleGetFeatures(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.FeatureState())
+	}
+}
+
+func handleUpdateFeatures(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.config.RuntimeToggles {
+			writeError(w, http.StatusForbidden, ErrTogglesLocked)
+			return
+		}
+		if err := requireScope(r.Context(), ScopeAdmin); err != nil {
+			writeError(w, http.StatusForbidden, newAPIError(CodeForbidden, err, map[string]interface{}{"scope": ScopeAdmin}))
+			return
+		}
+
+		var req FeatureState
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		state, err := s.UpdateFeatures(req)
+		switch {
+		case errors.Is(err, ErrUnknownToggle):
+			writeError(w, http.StatusBadRequest, err)
+		case errors.Is(err, ErrFeatureNotLoaded):
+			writeError(w, http.StatusConflict, err)
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err)
+		default:
+			LoggerFromContext(r.Context()).Info("toggles updated", "features", req.Features, "capabilities", req.Capabilities)
+			writeJSON(w, http.StatusOK, state)
+		}
+	}
+}
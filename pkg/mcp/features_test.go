@@ -0,0 +1,133 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatures_RuntimeToggles(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Features[FeatureBrowser] = false
+	cfg.WorkspaceRoot = t.TempDir()
+	cfg.RuntimeToggles = true
+	cfg.Usage = UsageConfig{Enabled: true, Tenants: map[string]TenantConfig{
+		"ops": {Keys: []string{"key-ops"}, Scopes: []string{ScopeAdmin}},
+	}}
+	s := NewServer(nil, WithConfig(cfg))
+	require.NoError(t, s.EnableFeatures())
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", "key-ops")
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("GET", "/v1/function/list", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = do("PUT", "/v1/admin/features", `{"features":{"functions":false},"capabilities":{"ssh.sudo":false}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var state FeatureState
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state))
+	assert.False(t, state.Features[FeatureFunctions])
+	assert.False(t, state.Capabilities[CapabilitySSHSudo])
+	assert.True(t, state.Capabilities[CapabilityFileDelete])
+
+	// Disabled features are rejected over REST and JSON-RPC
+	rec = do("GET", "/v1/function/list", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = postRPC(t, s, `{"jsonrpc":"2.0","method":"function.list","id":1}`)
+	var resp RPCResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, RPCForbidden, resp.Error.Code)
+
+	// Disabled capabilities are rejected before anything runs
+	rec = do("POST", "/v1/ssh/web-1/exec", `{"command":"cd /srv && sudo systemctl restart app"}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = do("POST", "/v1/ssh/web-1/exec", `{"command":"uptime"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// The advertisement reflects the runtime state
+	rec = do("GET", "/v1/capabilities", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var caps ServerCapabilities
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &caps))
+	assert.NotContains(t, caps.Features, FeatureFunctions)
+	assert.NotContains(t, caps.Features, FeatureBrowser)
+	assert.Contains(t, caps.Features, FeatureSSH)
+	assert.NotContains(t, caps.Capabilities, CapabilitySSHSudo)
+	assert.NotContains(t, caps.RPCMethods, "function.call")
+	assert.Contains(t, caps.RPCMethods, "context.get")
+
+	// Features that were not registered at startup cannot be switched on
+	rec = do("PUT", "/v1/admin/features", `{"features":{"browser":true,"functions":true}}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.False(t, s.FeatureEnabled(FeatureFunctions))
+
+	rec = do("PUT", "/v1/admin/features", `{"capabilities":{"teleport":true}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestFeatures_Locked(t *testing.T) {
+	tests := []struct {
+		name    string
+		toggles bool
+		usage   bool
+		key     string
+	}{
+		{"toggles off by default", false, true, "key-ops"},
+		{"no usage accounting", true, false, ""},
+		{"no key", true, true, ""},
+		{"key without admin scope", true, true, "key-dev"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.RuntimeToggles = tt.toggles
+			cfg.Capabilities[CapabilitySSHSudo] = false
+			cfg.Usage = UsageConfig{Enabled: tt.usage, Tenants: map[string]TenantConfig{
+				"ops": {Keys: []string{"key-ops"}, Scopes: []string{ScopeAdmin}},
+				"dev": {Keys: []string{"key-dev"}, Scopes: []string{"deploy"}},
+			}}
+			s := NewServer(nil, WithConfig(cfg))
+
+			req := httptest.NewRequest("PUT", "/v1/admin/features", strings.NewReader(`{"capabilities":{"ssh.sudo":true}}`))
+			req.Header.Set("X-API-Key", tt.key)
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusForbidden, rec.Code)
+			assert.False(t, s.CapabilityEnabled(CapabilitySSHSudo))
+		})
+	}
+}
+
+func TestUsesSudo(t *testing.T) {
+	for command, want := range map[string]bool{
+		"sudo reboot":                    true,
+		"make && sudo make install":      true,
+		"echo $(sudo cat /etc/shadow)":   true,
+		"/usr/bin/sudo -i":               true,
+		"ls|sudo tee /etc/motd":          true,
+		`"su""do" reboot`:                true,
+		`s\udo reboot`:                   true,
+		`'sudo' reboot`:                  true,
+		`echo "$(sudo id)"`:              true,
+		"{sudo,} reboot":                 true,
+		"/usr/bin/s'u'do -i":             true,
+		`echo "pseudo code"`:             false,
+		"go test ./...":                  false,
+		"cat pseudocode.txt":             false,
+		"grep -r sudoers_file README.md": false,
+	} {
+		assert.Equal(t, want, usesSudo(command), command)
+	}
+}
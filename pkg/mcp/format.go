@@ -0,0 +1,214 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/tools/imports"
+)
+
+// Formatters of Go source
+const (
+	FormatterGofmt     = "gofmt"
+	FormatterGoimports = "goimports" // Also adds missing and removes unused imports
+	FormatterGofumpt   = "gofumpt"   // Runs the gofumpt binary, which must be installed
+)
+
+// maxDiffCells bounds the work of diffing lines; larger changes are
+// replaced as a whole
+const maxDiffCells = 4 << 20
+
+// FormatRequest asks for a document to be formatted
+type FormatRequest struct {
+	URI       string `json:"uri"`
+	Content   string `json:"content,omitempty"`   // Falls back to the open document's text
+	Formatter string `json:"formatter,omitempty"` // gofmt (default), goimports or gofumpt
+	Range     *Range `json:"range,omitempty"`     // Only return the edits touching these lines
+}
+
+// FormatResult contains the edits produced by formatting a document
+type FormatResult struct {
+	Edit WorkspaceEdit `json:"edit"`
+}
+
+// FormatSource formats Go source with the named formatter. Filename locates
+// the file for goimports, which looks for missing imports in its module.
+func FormatSource(filename, content, formatter string) (string, error) {
+	var formatted []byte
+	var err error
+	switch formatter {
+	case "", FormatterGofmt:
+		formatted, err = format.Source([]byte(content))
+	case FormatterGoimports:
+		formatted, err = imports.Process(filename, []byte(content), &imports.Options{Comments: true, TabIndent: true, TabWidth: 8})
+	case FormatterGofumpt:
+		cmd := exec.Command(FormatterGofumpt)
+		cmd.Stdin = strings.NewReader(content)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		formatted, err = cmd.Output()
+		if err != nil && stderr.Len() > 0 {
+			err = fmt.Errorf("%s", strings.TrimSpace(stderr.String()))
+		}
+	default:
+		return "", fmt.Errorf("unknown formatter %q", formatter)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to format document: %v", err)
+	}
+	return string(formatted), nil
+}
+
+// lineEdits returns the edits turning before into after, replacing whole
+// lines
+func lineEdits(before, after string) []TextEdit {
+	a, b := splitLines(before), splitLines(after)
+
+	// Trim the common lines at both ends before diffing the rest
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// offsets[i] is the offset in before of line prefix+i
+	offsets := make([]int, len(a)+1)
+	offset := 0
+	for _, line := range splitLines(before)[:prefix] {
+		offset += len(line)
+	}
+	for i, line := range a {
+		offsets[i] = offset
+		offset += len(line)
+	}
+	offsets[len(a)] = offset
+
+	edit := func(i0, i1, j0, j1 int) TextEdit {
+		return TextEdit{
+			Range: Range{
+				Start: positionAt(before, offsets[i0]),
+				End:   positionAt(before, offsets[i1]),
+			},
+			NewText: strings.Join(b[j0:j1], ""),
+		}
+	}
+	if len(a) == 0 && len(b) == 0 {
+		return []TextEdit{}
+	}
+	if len(a)*len(b) > maxDiffCells {
+		return []TextEdit{edit(0, len(a), 0, len(b))}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := []TextEdit{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && a[i] == b[j] {
+			i++
+			j++
+			continue
+		}
+		i0, j0 := i, j
+		for (i < len(a) || j < len(b)) && !(i < len(a) && j < len(b) && a[i] == b[j]) {
+			if j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]) {
+				i++
+			} else {
+				j++
+			}
+		}
+		edits = append(edits, edit(i0, i, j0, j))
+	}
+	return edits
+}
+
+// splitLines splits s after every newline
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editsTouching keeps the edits touching the lines of r
+func editsTouching(edits []TextEdit, r Range) []TextEdit {
+	kept := []TextEdit{}
+	for _, edit := range edits {
+		if edit.Range.Start.Line <= r.End.Line && edit.Range.End.Line >= r.Start.Line {
+			kept = append(kept, edit)
+		}
+	}
+	return kept
+}
+
+// format returns the edits formatting a document, which falls back to the
+// open document's text
+func (ls *LanguageServer) format(req FormatRequest) ([]TextEdit, error) {
+	if req.Content == "" {
+		text, err := ls.text(req.URI)
+		if err != nil {
+			return nil, err
+		}
+		req.Content = text
+	}
+
+	formatted, err := FormatSource(ls.documentPath(req.URI), req.Content, req.Formatter)
+	if err != nil {
+		return nil, err
+	}
+
+	edits := lineEdits(req.Content, formatted)
+	if req.Range != nil {
+		edits = editsTouching(edits, *req.Range)
+	}
+	return edits, nil
+}
+
+func handleFormat(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req FormatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		edits, err := ls.format(req)
+		switch {
+		case errors.Is(err, errDocumentNotFound):
+			writeError(w, http.StatusNotFound, err)
+			return
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, FormatResult{
+			Edit: WorkspaceEdit{Changes: map[string][]TextEdit{req.URI: edits}},
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const unformatted = `package example
+
+import "os"
+
+func a( ) {
+return
+}
+
+// b is fine
+func b() {}
+
+func c() { x := strings.ToUpper("c") ; _ = x }
+`
+
+func TestFormatSource(t *testing.T) {
+	formatted, err := FormatSource("example.go", unformatted, FormatterGofmt)
+	require.NoError(t, err)
+	assert.Contains(t, formatted, "func a() {\n\treturn\n}")
+	assert.Contains(t, formatted, "import \"os\"")
+
+	formatted, err = FormatSource("example.go", unformatted, FormatterGoimports)
+	require.NoError(t, err)
+	assert.Contains(t, formatted, "import \"strings\"")
+	assert.NotContains(t, formatted, "\"os\"")
+
+	_, err = FormatSource("example.go", "package example\nfunc (", FormatterGofmt)
+	assert.ErrorContains(t, err, "failed to format document")
+	_, err = FormatSource("example.go", unformatted, "prettier")
+	assert.ErrorContains(t, err, "unknown formatter")
+}
+
+func TestLineEdits(t *testing.T) {
+	formatted, err := FormatSource("example.go", unformatted, FormatterGofmt)
+	require.NoError(t, err)
+
+	edits := lineEdits(unformatted, formatted)
+	require.Len(t, edits, 2, "unchanged lines are kept")
+	assert.Equal(t, Range{Start: Position{Line: 4, Character: 0}, End: Position{Line: 6, Character: 0}}, edits[0].Range)
+	assert.Equal(t, formatted, applyEdits(unformatted, edits))
+
+	assert.Empty(t, lineEdits(formatted, formatted))
+	assert.Equal(t, "b\nc\n", applyEdits("a\nb\n", lineEdits("a\nb\n", "b\nc\n")))
+	assert.Equal(t, "x\n", applyEdits("x", lineEdits("x", "x\n")))
+}
+
+func TestHandleFormat(t *testing.T) {
+	s := NewServer(nil)
+	s.AddLanguageServerHandler()
+
+	// Only the edits touching the range
+	body, err := json.Marshal(FormatRequest{
+		URI:     "example.go",
+		Content: unformatted,
+		Range:   &Range{Start: Position{Line: 11, Character: 0}, End: Position{Line: 11, Character: 5}},
+	})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/format", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result FormatResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	edits := result.Edit.Changes["example.go"]
+	require.Len(t, edits, 1)
+	assert.Equal(t, "func c() { x := strings.ToUpper(\"c\"); _ = x }\n", edits[0].NewText)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/format", strings.NewReader(`{"uri":"missing.go"}`)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleSaveFile(t *testing.T) {
+	dir := t.TempDir()
+	s := NewServer(nil)
+	ideServer, err := NewIDEServer(dir)
+	require.NoError(t, err)
+	s.AddIDEServer(ideServer)
+
+	save := func(path, content string) (int, SaveFileResult) {
+		t.Helper()
+		body, err := json.Marshal(SaveFileRequest{Path: path, Content: content})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/ide/files/save", strings.NewReader(string(body))))
+		var result SaveFileResult
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		}
+		return w.Code, result
+	}
+	read := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	// Saved as is until the project formats on save
+	code, result := save("pkg/a.go", unformatted)
+	require.Equal(t, http.StatusOK, code)
+	assert.False(t, result.Formatted)
+	assert.Equal(t, unformatted, read("pkg/a.go"))
+
+	config := *ideServer.projectManager.GetConfig()
+	config.FormatOnSave = FormatterGoimports
+	require.NoError(t, ideServer.projectManager.UpdateConfig(&config))
+	code, result = save("pkg/a.go", unformatted)
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, result.Formatted)
+	assert.Contains(t, read("pkg/a.go"), "import \"strings\"")
+
+	// Files that do not parse, and other files, are saved as they are
+	code, result = save("pkg/b.go", "package pkg\nfunc (")
+	require.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, result.FormatError)
+	assert.Equal(t, "package pkg\nfunc (", read("pkg/b.go"))
+	code, result = save("notes.txt", "func a( ) {}\n")
+	require.Equal(t, http.StatusOK, code)
+	assert.False(t, result.Formatted)
+
+	code, _ = save("../escape.go", "package escape\n")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	body, err := json.Marshal(UpdateProjectConfigRequest{Config: ide.ProjectConfig{Root: dir, FormatOnSave: "prettier"}})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/ide/project/config", strings.NewReader(string(body))))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
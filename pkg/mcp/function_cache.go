@@ -0,0 +1,255 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCachedResults bounds the results cached of all idempotent functions
+const maxCachedResults = 1000
+
+// Idempotent caches the results of a function for a TTL, so calls with the
+// same arguments by the same tenant return the cached result instead of
+// calling it again. Only successful calls are cached.
+func Idempotent(ttl time.Duration) FunctionOption {
+	return func(o *functionOptions) {
+		o.cacheTTL = ttl
+	}
+}
+
+// cacheKey identifies a call of a function
+type cacheKey struct {
+	function  string
+	tenant    string
+	arguments string // JSON of the converted arguments
+}
+
+type cachedResult struct {
+	result  interface{}
+	created time.Time
+	expires time.Time
+}
+
+// resultCache holds the results of idempotent functions
+type resultCache struct {
+	mu      sync.Mutex
+	results map[cacheKey]cachedResult
+}
+
+func newResultCache() *resultCache {
+	return &resultCache{results: make(map[cacheKey]cachedResult)}
+}
+
+// get returns the cached result of a call, unless it expired
+func (c *resultCache) get(key cacheKey, now time.Time) (cachedResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.results[key]
+	if ok && !now.Before(cached.expires) {
+		delete(c.results, key)
+		return cachedResult{}, false
+	}
+	return cached, ok
+}
+
+// put caches the result of a call. Expired results make room for it; it is
+// not cached while the cache is full of live ones.
+func (c *resultCache) put(key cacheKey, cached cachedResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.results[key]; !ok && len(c.results) >= maxCachedResults {
+		for k, r := range c.results {
+			if !cached.created.Before(r.expires) {
+				delete(c.results, k)
+			}
+		}
+		if len(c.results) >= maxCachedResults {
+			return
+		}
+	}
+	c.results[key] = cached
+}
+
+// forget drops the cached results of a function
+func (c *resultCache) forget(function string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.results {
+		if key.function == function {
+			delete(c.results, key)
+		}
+	}
+}
+
+// cacheStatus reports how the result cache served a call of an idempotent
+// function
+type cacheStatus struct {
+	Cacheable bool          // The function is idempotent
+	Hit       bool          // The result was cached
+	Bypassed  bool          // The cache was not read
+	Age       time.Duration // Of the cached result
+	TTL       time.Duration // Left until the result expires
+}
+
+type cacheStatusKey struct{}
+
+type cacheBypassKey struct{}
+
+// withCacheStatus returns a context whose calls of functions report the use
+// of the result cache to status
+func withCacheStatus(ctx context.Context, status *cacheStatus) context.Context {
+	return context.WithValue(ctx, cacheStatusKey{}, status)
+}
+
+// WithoutCache returns a context whose calls of idempotent functions call
+// them rather than returning cached results. Their results are still
+// cached.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// cached calls an idempotent function through the result cache
+func (h *FunctionHandler) cached(ctx context.Context, name string, args []reflect.Value, ttl time.Duration, call func() (interface{}, error)) (interface{}, error) {
+	status, _ := ctx.Value(cacheStatusKey{}).(*cacheStatus)
+	if status == nil {
+		status = &cacheStatus{}
+	}
+	status.Cacheable = true
+
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Interface()
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		// Arguments that have no JSON are not cached
+		return call()
+	}
+	key := cacheKey{function: name, tenant: TenantFromContext(ctx), arguments: string(data)}
+
+	now := time.Now()
+	if bypass, _ := ctx.Value(cacheBypassKey{}).(bool); bypass {
+		status.Bypassed = true
+	} else if cached, ok := h.cache.get(key, now); ok {
+		status.Hit = true
+		status.Age = now.Sub(cached.created)
+		status.TTL = cached.expires.Sub(now)
+		return copyResult(cached.result), nil
+	}
+
+	result, err := call()
+	if err != nil {
+		return nil, err
+	}
+	h.cache.put(key, cachedResult{result: copyResult(result), created: now, expires: now.Add(ttl)})
+	status.TTL = ttl
+	return result, nil
+}
+
+// copyResult deep-copies a result, so callers changing the maps, slices or
+// pointers of a cached result do not change what later callers get.
+// Unexported fields of structs are copied as they are.
+func copyResult(result interface{}) interface{} {
+	if result == nil {
+		return nil
+	}
+	return copyValue(reflect.ValueOf(result)).Interface()
+}
+
+func copyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		if v.Kind() == reflect.Ptr {
+			c := reflect.New(v.Type().Elem())
+			c.Elem().Set(copyValue(v.Elem()))
+			return c
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(copyValue(v.Elem()))
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), copyValue(iter.Value()))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(copyValue(v.Field(i)))
+			}
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// cacheRequest returns the context of a call requested over HTTP, without
+// the cache when the request or its Cache-Control header asks for it
+func cacheRequest(r *http.Request, noCache bool, status *cacheStatus) context.Context {
+	ctx := withCacheStatus(r.Context(), status)
+	if noCache || strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-cache") {
+		ctx = WithoutCache(ctx)
+	}
+	return ctx
+}
+
+// outcome is HIT, MISS or BYPASS for calls of idempotent functions, and
+// empty for others
+func (s *cacheStatus) outcome() string {
+	switch {
+	case !s.Cacheable:
+		return ""
+	case s.Hit:
+		return "HIT"
+	case s.Bypassed:
+		return "BYPASS"
+	default:
+		return "MISS"
+	}
+}
+
+// writeCacheHeaders tells clients how long the result of an idempotent
+// function stays fresh and whether it was cached
+func writeCacheHeaders(w http.ResponseWriter, status *cacheStatus) {
+	if !status.Cacheable {
+		return
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(math.Ceil(status.TTL.Seconds()))))
+	if status.Hit {
+		w.Header().Set("Age", fmt.Sprintf("%d", int(status.Age.Seconds())))
+	}
+	w.Header().Set("X-Cache", status.outcome())
+}
@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionHandler_Idempotent(t *testing.T) {
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+	calls := 0
+	require.NoError(t, s.functions.RegisterFunction("lookup", func(id string) (string, error) {
+		calls++
+		if id == "bad" {
+			return "", errors.New("no such id")
+		}
+		return strings.ToUpper(id), nil
+	}, Idempotent(time.Minute)))
+
+	call := func(body, cacheControl string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/function/call", strings.NewReader(body))
+		if cacheControl != "" {
+			req.Header.Set("Cache-Control", cacheControl)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		cacheControl string
+		cache        string
+		result       string
+		calls        int
+	}{
+		{"miss", `{"name":"lookup","arguments":["a"]}`, "", "MISS", `"A"`, 1},
+		{"hit", `{"name":"lookup","arguments":["a"]}`, "", "HIT", `"A"`, 1},
+		{"named arguments hit", `{"name":"lookup","arguments":{"arg0":"a"}}`, "", "HIT", `"A"`, 1},
+		{"other arguments", `{"name":"lookup","arguments":["b"]}`, "", "MISS", `"B"`, 2},
+		{"bypass flag", `{"name":"lookup","arguments":["a"],"no_cache":true}`, "", "BYPASS", `"A"`, 3},
+		{"bypass header", `{"name":"lookup","arguments":["a"]}`, "no-cache", "BYPASS", `"A"`, 4},
+		{"error", `{"name":"lookup","arguments":["bad"]}`, "", "", "", 5},
+		{"errors are not cached", `{"name":"lookup","arguments":["bad"]}`, "", "", "", 6},
+		{"not idempotent", `{"name":"echo","arguments":["a"]}`, "", "", "", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := call(tt.body, tt.cacheControl)
+			assert.Equal(t, tt.cache, w.Header().Get("X-Cache"))
+			if tt.cache != "" {
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+				assert.JSONEq(t, `{"result":`+tt.result+`}`, w.Body.String())
+				assert.Regexp(t, `^private, max-age=(59|60)$`, w.Header().Get("Cache-Control"))
+			} else {
+				assert.Empty(t, w.Header().Get("Cache-Control"))
+			}
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+
+	// In-process calls share the cache, unless told otherwise
+	result, err := s.functions.Call("lookup", []interface{}{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, "A", result)
+	assert.Equal(t, 6, calls)
+	_, err = s.functions.CallContext(WithoutCache(context.Background()), "lookup", []interface{}{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, 7, calls)
+
+	for _, m := range s.functions.GetFunctionMetadata() {
+		if m.Name == "lookup" {
+			assert.Equal(t, 60.0, m.CacheTTL)
+		}
+	}
+
+	// Unregistering forgets the cached results
+	s.functions.UnregisterFunction("lookup")
+	assert.Empty(t, s.functions.cache.results)
+}
+
+func TestFunctionHandler_IdempotentJobs(t *testing.T) {
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+	defer s.functionJobs.stop(context.Background())
+	var calls int32
+	require.NoError(t, s.functions.RegisterFunction("lookup", func(id string) string {
+		atomic.AddInt32(&calls, 1)
+		return strings.ToUpper(id)
+	}, Idempotent(time.Minute)))
+
+	// submit runs a job to the end and returns it
+	submit := func(body, cacheControl string) FunctionJob {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/v1/function/call?async=true", strings.NewReader(body))
+		if cacheControl != "" {
+			req.Header.Set("Cache-Control", cacheControl)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		var job FunctionJob
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		require.Eventually(t, func() bool {
+			job, _ = s.functionJobs.get("", job.ID)
+			return job.Status == JobSucceeded
+		}, 5*time.Second, 5*time.Millisecond)
+		return job
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		cacheControl string
+		cache        string
+		calls        int32
+	}{
+		{"miss", `{"name":"lookup","arguments":["a"]}`, "", "MISS", 1},
+		{"hit", `{"name":"lookup","arguments":["a"]}`, "", "HIT", 1},
+		{"bypass flag", `{"name":"lookup","arguments":["a"],"no_cache":true}`, "", "BYPASS", 2},
+		{"bypass header", `{"name":"lookup","arguments":["a"]}`, "no-cache", "BYPASS", 3},
+		{"not idempotent", `{"name":"echo","arguments":["a"]}`, "", "", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := submit(tt.body, tt.cacheControl)
+			assert.Equal(t, tt.cache, job.Cache)
+			assert.Equal(t, tt.calls, atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestFunctionHandler_IdempotentCopies(t *testing.T) {
+	type record struct {
+		Tags  []string
+		Attrs map[string]interface{}
+	}
+	h := NewFunctionHandler()
+	require.NoError(t, h.RegisterFunction("record", func() *record {
+		return &record{Tags: []string{"a"}, Attrs: map[string]interface{}{"nested": []interface{}{"x"}}}
+	}, Idempotent(time.Minute)))
+
+	want := &record{Tags: []string{"a"}, Attrs: map[string]interface{}{"nested": []interface{}{"x"}}}
+	for i := 0; i < 3; i++ {
+		result, err := h.Call("record", nil)
+		require.NoError(t, err)
+		assert.Equal(t, want, result)
+
+		// Changing a result leaves the cached one as it was
+		r := result.(*record)
+		r.Tags[0] = "changed"
+		r.Attrs["nested"].([]interface{})[0] = "changed"
+		r.Attrs["added"] = true
+	}
+}
+
+func TestFunctionHandler_IdempotentExpiry(t *testing.T) {
+	h := NewFunctionHandler()
+	calls := 0
+	require.NoError(t, h.RegisterFunction("now", func() int {
+		calls++
+		return calls
+	}, Idempotent(20*time.Millisecond)))
+
+	first, err := h.Call("now", nil)
+	require.NoError(t, err)
+	second, err := h.Call("now", nil)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	time.Sleep(30 * time.Millisecond)
+	third, err := h.Call("now", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, third)
+}
@@ -1,24 +1,117 @@
 package mcp
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
-// FunctionHandler manages function registration and execution
+var (
+	// ErrFunctionNotFound is returned when calling a function that was
+	// never registered
+	ErrFunctionNotFound = errors.New("function not found")
+	// ErrScopeRequired is returned when the caller of a function lacks a
+	// scope it was registered with
+	ErrScopeRequired = errors.New("scope required")
+)
+
+// reservedFunctionName cannot be registered, since /function/jobs/{id} would
+// shadow /function/{name}/history of a function of that name
+const reservedFunctionName = "jobs"
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// defaultFunctionTimeout bounds calls of functions taking a context, as
+// long as calls of upstream tools
+const defaultFunctionTimeout = 5 * time.Minute
+
+// FunctionConfig configures the calls of registered functions
+type FunctionConfig struct {
+	// Timeout cancels the context of functions taking one as their first
+	// parameter. Zero keeps the default; a negative timeout disables it.
+	Timeout time.Duration `yaml:"timeout"`
+	// Workers run asynchronous calls, of which MaxQueued may wait. Finished
+	// jobs are kept for JobRetention. Zero values keep the defaults of 4
+	// workers, 100 queued jobs and an hour.
+	Workers      int           `yaml:"workers"`
+	MaxQueued    int           `yaml:"max_queued"`
+	JobRetention time.Duration `yaml:"job_retention"`
+	// PluginDir holds Go plugins, .so files registering functions, loaded
+	// at startup and again through POST /function/plugins/load
+	PluginDir string `yaml:"plugin_dir"`
+	// History is the number of invocations kept per function, 50 when
+	// zero; a negative history keeps none. Tenants see only their own
+	// invocations. The values of arguments and of their fields at any depth
+	// named in RedactArguments, case-insensitively, are left out of it;
+	// positional arguments are named arg0, arg1 and so on. "*" redacts all.
+	// By default common names of secrets are redacted, along with every
+	// positional argument.
+	History         int      `yaml:"history"`
+	RedactArguments []string `yaml:"redact_arguments"`
+}
+
+// Validate checks the function config
+func (c FunctionConfig) Validate() error {
+	if c.Workers < 0 || c.MaxQueued < 0 || c.JobRetention < 0 {
+		return fmt.Errorf("workers, max_queued and job_retention must not be negative")
+	}
+	return nil
+}
+
+// FunctionHandler manages function registration and execution. Functions
+// whose first parameter is a context.Context get the context of the
+// request, canceled when the client goes away or the timeout passes; their
+// arguments are the parameters after it.
 type FunctionHandler struct {
 	functions map[string]interface{}
+	scopes    map[string][]string      // Scopes required to call functions, by name
+	schemas   map[string]*Schema       // Schemas of functions not derived from their parameters
+	cacheTTLs map[string]time.Duration // TTLs of the results of idempotent functions
+	cache     *resultCache
 	mu        sync.RWMutex
+	changed   func() // Called after functions are registered or unregistered
+	timeout   time.Duration
+	hooks     []FunctionHooks
+	metrics   *functionMetrics
+	unscoped  bool // Callers carry no scopes, so functions cannot require them
+}
+
+// FunctionError is an error returned by a registered function, as opposed
+// to a failure to call it
+type FunctionError struct {
+	Function string
+	Err      error
+}
+
+func (e *FunctionError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error the function returned
+func (e *FunctionError) Unwrap() error {
+	return e.Err
 }
 
 // FunctionMetadata represents metadata about a registered function
 type FunctionMetadata struct {
 	Name       string         `json:"name"`
 	Arguments  []ArgumentInfo `json:"arguments"`
+	Parameters *Schema        `json:"parameters"` // JSON Schema of the named arguments
 	ReturnType string         `json:"return_type"`
+	Scopes     []string       `json:"scopes,omitempty"`    // Required of callers
+	CacheTTL   float64        `json:"cache_ttl,omitempty"` // Seconds results of idempotent functions are cached
 }
 
 // ArgumentInfo represents information about a function argument
@@ -28,37 +121,185 @@ type ArgumentInfo struct {
 	Required bool   `json:"required"`
 }
 
-// FunctionRequest represents a function call request
+// FunctionRequest represents a function call request. Arguments are an
+// array of positional arguments, or an object keyed by parameter name as
+// the function's tool takes them. Names may be those of exported tool
+// definitions.
 type FunctionRequest struct {
-	Name      string        `json:"name"`
-	Arguments []interface{} `json:"arguments"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+	NoCache   bool            `json:"no_cache,omitempty"` // Call idempotent functions rather than return cached results
 }
 
 // NewFunctionHandler creates a new function handler instance
 func NewFunctionHandler() *FunctionHandler {
 	return &FunctionHandler{
 		functions: make(map[string]interface{}),
+		scopes:    make(map[string][]string),
+		schemas:   make(map[string]*Schema),
+		cacheTTLs: make(map[string]time.Duration),
+		cache:     newResultCache(),
+		timeout:   defaultFunctionTimeout,
+		metrics:   newFunctionMetrics(0, nil),
+	}
+}
+
+// SetTimeout sets the timeout of the contexts functions get; zero or a
+// negative timeout disables it
+func (h *FunctionHandler) SetTimeout(timeout time.Duration) {
+	h.mu.Lock()
+	h.timeout = timeout
+	h.mu.Unlock()
+}
+
+// names returns the set of registered functions
+func (h *FunctionHandler) names() map[string]bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	names := make(map[string]bool, len(h.functions))
+	for name := range h.functions {
+		names[name] = true
+	}
+	return names
+}
+
+// has reports whether a function is registered
+func (h *FunctionHandler) has(name string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, exists := h.functions[name]
+	return exists
+}
+
+// authorize checks that the caller of a function has the scopes it requires.
+// In-process callers, whose context carries no scopes, are not limited.
+func (h *FunctionHandler) authorize(ctx context.Context, name string) error {
+	granted, ok := ScopesFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	h.mu.RLock()
+	required := h.scopes[name]
+	h.mu.RUnlock()
+	for _, scope := range required {
+		if !containsString(granted, scope) {
+			return fmt.Errorf("%w: calling %s requires the %s scope", ErrScopeRequired, name, scope)
+		}
+	}
+	return nil
+}
+
+// takesContext reports whether the first parameter of a function is a
+// context.Context, and so not an argument
+func takesContext(fnType reflect.Type) bool {
+	return fnType.NumIn() > 0 && fnType.In(0) == contextType
+}
+
+// parameters returns the types of the arguments of a function
+func parameters(fnType reflect.Type) []reflect.Type {
+	params := make([]reflect.Type, 0, fnType.NumIn())
+	for i := 0; i < fnType.NumIn(); i++ {
+		params = append(params, fnType.In(i))
+	}
+	if takesContext(fnType) {
+		params = params[1:]
+	}
+	return params
+}
+
+// FunctionOption configures a registered function
+type FunctionOption func(*functionOptions)
+
+type functionOptions struct {
+	scopes   []string
+	schema   *Schema
+	cacheTTL time.Duration
+}
+
+// RequireScopes limits calls of a function to tenants granted all the
+// scopes, e.g. "deploy" for functions changing production. Handlers of a
+// server refuse it unless usage accounting is enabled, as only tenants have
+// scopes.
+func RequireScopes(scopes ...string) FunctionOption {
+	return func(o *functionOptions) {
+		o.scopes = append(o.scopes, scopes...)
 	}
 }
 
 // RegisterFunction registers a function with the handler
-func (h *FunctionHandler) RegisterFunction(name string, fn interface{}) error {
+func (h *FunctionHandler) RegisterFunction(name string, fn interface{}, opts ...FunctionOption) error {
+	if err := h.register(name, fn, opts); err != nil {
+		return err
+	}
+	// Listeners may call back into the handler, so h.mu is not held
+	if h.changed != nil {
+		h.changed()
+	}
+	return nil
+}
+
+// register adds a function to the handler
+func (h *FunctionHandler) register(name string, fn interface{}, opts []FunctionOption) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	if _, exists := h.functions[name]; exists {
 		return fmt.Errorf("function %s is already registered", name)
 	}
+	if name == reservedFunctionName {
+		return fmt.Errorf("function name %s is reserved", name)
+	}
 
 	fnType := reflect.TypeOf(fn)
 	if fnType.Kind() != reflect.Func {
 		return fmt.Errorf("provided value must be a function")
 	}
+	if takesObject(fnType) {
+		if err := applyDefaults(reflect.New(parameters(fnType)[0]).Elem()); err != nil {
+			return err
+		}
+	}
+
+	var options functionOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	// Without usage accounting remote callers have no scopes to check, so
+	// scoped functions would be open to anyone
+	if len(options.scopes) > 0 && h.unscoped {
+		return fmt.Errorf("function %s requires scopes, which need usage accounting enabled", name)
+	}
 
 	h.functions[name] = fn
+	if len(options.scopes) > 0 {
+		h.scopes[name] = options.scopes
+	}
+	if options.schema != nil {
+		h.schemas[name] = options.schema
+	}
+	if options.cacheTTL > 0 {
+		h.cacheTTLs[name] = options.cacheTTL
+	}
 	return nil
 }
 
+// UnregisterFunction removes a function, reporting whether it was registered
+func (h *FunctionHandler) UnregisterFunction(name string) bool {
+	h.mu.Lock()
+	_, exists := h.functions[name]
+	delete(h.functions, name)
+	delete(h.scopes, name)
+	delete(h.schemas, name)
+	delete(h.cacheTTLs, name)
+	h.cache.forget(name)
+	h.mu.Unlock()
+
+	if exists && h.changed != nil {
+		h.changed()
+	}
+	return exists
+}
+
 // GetFunctionMetadata returns metadata for all registered functions
 func (h *FunctionHandler) GetFunctionMetadata() []FunctionMetadata {
 	h.mu.RLock()
@@ -67,113 +308,583 @@ func (h *FunctionHandler) GetFunctionMetadata() []FunctionMetadata {
 	metadata := make([]FunctionMetadata, 0, len(h.functions))
 	for name, fn := range h.functions {
 		fnType := reflect.TypeOf(fn)
-		args := make([]ArgumentInfo, fnType.NumIn())
+		params := parameters(fnType)
+		args := make([]ArgumentInfo, len(params))
 
-		for i := 0; i < fnType.NumIn(); i++ {
-			argType := fnType.In(i)
+		for i, argType := range params {
 			args[i] = ArgumentInfo{
 				Name:     fmt.Sprintf("arg%d", i),
 				Type:     argType.String(),
 				Required: true,
 			}
 		}
-
-		returnType := "void"
-		if fnType.NumOut() > 0 {
-			returnType = fnType.Out(0).String()
+		if fnType.IsVariadic() {
+			last := &args[len(args)-1]
+			last.Type = "..." + params[len(params)-1].Elem().String()
+			last.Required = false
 		}
 
 		metadata = append(metadata, FunctionMetadata{
 			Name:       name,
 			Arguments:  args,
-			ReturnType: returnType,
+			Parameters: h.schema(name, fnType),
+			ReturnType: returnType(fnType),
+			Scopes:     h.scopes[name],
+			CacheTTL:   h.cacheTTLs[name].Seconds(),
 		})
 	}
 
 	return metadata
 }
 
-// AddFunctionHandler adds function handling capabilities to the MCP server
-func (s *Server) AddFunctionHandler() {
-	handler := NewFunctionHandler()
+// returnType describes the results of a function other than a trailing
+// error: void, the type of the result, or the types of the results in
+// parentheses
+func returnType(fnType reflect.Type) string {
+	var results []string
+	for i := 0; i < fnType.NumOut(); i++ {
+		if i < fnType.NumOut()-1 || fnType.Out(i) != errorType {
+			results = append(results, fnType.Out(i).String())
+		}
+	}
+	switch len(results) {
+	case 0:
+		return "void"
+	case 1:
+		return results[0]
+	default:
+		return "(" + strings.Join(results, ", ") + ")"
+	}
+}
 
-	// Add example built-in functions
-	handler.RegisterFunction("echo", func(msg string) string { return msg })
+// Call calls a registered function, converting JSON-decoded arguments to the
+// parameter types, and returns its result. Functions whose last result is an
+// error fail with it as a *FunctionError; functions with several other
+// results return them as a slice.
+func (h *FunctionHandler) Call(name string, arguments []interface{}) (interface{}, error) {
+	return h.CallContext(context.Background(), name, arguments)
+}
 
-	// Register routes
-	s.router.HandleFunc("/function/list", handleListFunctions(handler)).Methods("GET")
-	s.router.HandleFunc("/function/call", handleCallFunction(handler)).Methods("POST")
+// CallContext is Call with the context functions taking one get
+func (h *FunctionHandler) CallContext(ctx context.Context, name string, arguments []interface{}) (interface{}, error) {
+	h.mu.RLock()
+	fn, exists := h.functions[name]
+	h.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrFunctionNotFound, name)
+	}
+	if err := h.authorize(ctx, name); err != nil {
+		return nil, err
+	}
+
+	fnValue := reflect.ValueOf(fn)
+	params := parameters(fnValue.Type())
+
+	if fnValue.Type().IsVariadic() {
+		if len(arguments) < len(params)-1 {
+			return nil, fmt.Errorf("expected at least %d arguments, got %d", len(params)-1, len(arguments))
+		}
+		arguments = variadicArguments(arguments, params)
+	}
+	if len(arguments) != len(params) {
+		return nil, fmt.Errorf("expected %d arguments, got %d", len(params), len(arguments))
+	}
+
+	args := make([]reflect.Value, len(arguments))
+	for i, arg := range arguments {
+		expectedType := params[i]
+		argValue := reflect.ValueOf(arg)
+
+		// Handle type conversion
+		if !argValue.IsValid() || !argValue.Type().AssignableTo(expectedType) {
+			convertedArg, err := convertArgument(arg, expectedType)
+			if err != nil {
+				return nil, fmt.Errorf("invalid argument %d: %v", i, err)
+			}
+			args[i] = convertedArg
+		} else {
+			args[i] = argValue
+		}
+	}
+
+	return h.call(ctx, name, fnValue, args)
 }
 
-func handleListFunctions(h *FunctionHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		metadata := h.GetFunctionMetadata()
-		writeJSON(w, http.StatusOK, metadata)
+// variadicArguments gathers the trailing arguments of a call of a variadic
+// function into the slice of its last parameter. A last argument that
+// already is such a slice is passed as it is.
+func variadicArguments(arguments []interface{}, params []reflect.Type) []interface{} {
+	last := len(params) - 1
+	if len(arguments) == len(params) {
+		if v := reflect.ValueOf(arguments[last]); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			if _, err := convertArgument(arguments[last], params[last]); err == nil {
+				return arguments
+			}
+		}
 	}
+	gathered := make([]interface{}, last, last+1)
+	copy(gathered, arguments[:last])
+	return append(gathered, arguments[last:])
 }
 
-func handleCallFunction(h *FunctionHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var req FunctionRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			writeError(w, http.StatusBadRequest, err)
-			return
+// callArguments calls a registered function with the arguments of a
+// FunctionRequest, positional or named
+func (h *FunctionHandler) callArguments(ctx context.Context, name string, arguments json.RawMessage) (interface{}, error) {
+	name = h.resolve(name)
+	trimmed := bytes.TrimSpace(arguments)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return h.callNamed(ctx, name, arguments)
+	}
+
+	var positional []interface{}
+	if len(trimmed) > 0 {
+		if err := json.Unmarshal(trimmed, &positional); err != nil {
+			return nil, fmt.Errorf("arguments must be an array or an object: %v", err)
 		}
+	}
+	return h.CallContext(ctx, name, positional)
+}
 
+// call calls a function with converted arguments through the hooks, and
+// records the invocation. Results of idempotent functions may be cached.
+func (h *FunctionHandler) call(ctx context.Context, name string, fn reflect.Value, args []reflect.Value) (interface{}, error) {
+	h.mu.RLock()
+	hooks, ttl := h.hooks, h.cacheTTLs[name]
+	h.mu.RUnlock()
+	call := func() (interface{}, error) {
+		return h.observe(ctx, name, fn, args, func() (interface{}, error) {
+			if len(hooks) == 0 {
+				return h.invoke(ctx, name, fn, args)
+			}
+			return h.intercept(ctx, name, fn, args, hooks)
+		})
+	}
+	if ttl > 0 {
+		return h.cached(ctx, name, args, ttl, call)
+	}
+	return call()
+}
+
+// invoke calls a function and returns its results other than a trailing
+// error, which it fails with. Functions taking a context get ctx, bounded by
+// the timeout. Functions that panic fail with a *FunctionError.
+func (h *FunctionHandler) invoke(ctx context.Context, name string, fn reflect.Value, args []reflect.Value) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			LoggerFromContext(ctx).Error("function panicked", "function", name, "panic", r, "stack", string(debug.Stack()))
+			result, err = nil, &FunctionError{Function: name, Err: fmt.Errorf("function %s panicked: %v", name, r)}
+		}
+	}()
+
+	if takesContext(fn.Type()) {
 		h.mu.RLock()
-		fn, exists := h.functions[req.Name]
+		timeout := h.timeout
 		h.mu.RUnlock()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		args = append([]reflect.Value{reflect.ValueOf(&ctx).Elem()}, args...)
+	}
 
-		if !exists {
-			writeError(w, http.StatusNotFound, fmt.Errorf("function %s not found", req.Name))
-			return
+	var results []reflect.Value
+	if fn.Type().IsVariadic() {
+		results = fn.CallSlice(args)
+	} else {
+		results = fn.Call(args)
+	}
+	if n := len(results); n > 0 && fn.Type().Out(n-1) == errorType {
+		if err, _ := results[n-1].Interface().(error); err != nil {
+			return nil, &FunctionError{Function: name, Err: err}
 		}
+		results = results[:n-1]
+	}
+	switch len(results) {
+	case 0:
+		return nil, nil
+	case 1:
+		return results[0].Interface(), nil
+	}
+	values := make([]interface{}, len(results))
+	for i, result := range results {
+		values[i] = result.Interface()
+	}
+	return values, nil
+}
+
+// tools returns the registered functions as MCP tools named
+// function_<name>, whose input schemas are derived from the parameters
+func (h *FunctionHandler) tools() []MCPTool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 
-		fnValue := reflect.ValueOf(fn)
-		fnType := fnValue.Type()
+	tools := make([]MCPTool, 0, len(h.functions))
+	for name, fn := range h.functions {
+		tools = append(tools, MCPTool{
+			Name:        strings.ReplaceAll("function."+name, ".", "_"),
+			Description: fmt.Sprintf("Call the registered function %s", name),
+			InputSchema: h.schema(name, reflect.TypeOf(fn)),
+			function:    name,
+		})
+	}
+	return tools
+}
 
-		if len(req.Arguments) != fnType.NumIn() {
-			writeError(w, http.StatusBadRequest, fmt.Errorf("expected %d arguments, got %d", fnType.NumIn(), len(req.Arguments)))
-			return
+// takesObject reports whether a function takes the named arguments of a
+// tool as a whole: its only parameter is a struct or a map with string keys.
+// Other functions take them positionally as arg0, arg1 and so on.
+func takesObject(fnType reflect.Type) bool {
+	params := parameters(fnType)
+	if len(params) != 1 {
+		return false
+	}
+	t := params[0]
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return (t.Kind() == reflect.Struct && t != timeType) || (t.Kind() == reflect.Map && t.Key().Kind() == reflect.String)
+}
+
+// schema describes the named arguments of a registered function. Callers
+// hold h.mu.
+func (h *FunctionHandler) schema(name string, fnType reflect.Type) *Schema {
+	if schema, ok := h.schemas[name]; ok {
+		return schema
+	}
+	return functionSchema(fnType)
+}
+
+// functionSchema describes the named arguments of a function. The fields
+// of struct parameters are described by their json, description, required
+// and default tags.
+func functionSchema(fnType reflect.Type) *Schema {
+	generator := newInlineSchemaGenerator()
+	params := parameters(fnType)
+	if takesObject(fnType) {
+		schema := generator.schemaFor(params[0])
+		schema.Nullable = false
+		return schema
+	}
+
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i, param := range params {
+		name := fmt.Sprintf("arg%d", i)
+		schema.Properties[name] = generator.schemaFor(param)
+		// The trailing arguments of variadic functions are an array
+		if !fnType.IsVariadic() || i < len(params)-1 {
+			schema.Required = append(schema.Required, name)
 		}
+	}
+	return schema
+}
+
+// callNamed calls a registered function with the named arguments of a tool
+func (h *FunctionHandler) callNamed(ctx context.Context, name string, arguments json.RawMessage) (interface{}, error) {
+	h.mu.RLock()
+	fn, exists := h.functions[name]
+	var schema *Schema
+	if exists {
+		schema = h.schema(name, reflect.TypeOf(fn))
+	}
+	h.mu.RUnlock()
 
-		args := make([]reflect.Value, len(req.Arguments))
-		for i, arg := range req.Arguments {
-			expectedType := fnType.In(i)
-			argValue := reflect.ValueOf(arg)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrFunctionNotFound, name)
+	}
+	if err := h.authorize(ctx, name); err != nil {
+		return nil, err
+	}
+	if len(arguments) == 0 || string(arguments) == "null" {
+		arguments = json.RawMessage("{}")
+	}
 
-			// Handle type conversion
-			if !argValue.Type().AssignableTo(expectedType) {
-				convertedArg, err := convertArgument(arg, expectedType)
-				if err != nil {
-					writeError(w, http.StatusBadRequest, fmt.Errorf("invalid argument %d: %v", i, err))
-					return
+	fnType := reflect.TypeOf(fn)
+	if takesObject(fnType) {
+		if required := schema.Required; len(required) > 0 {
+			var named map[string]json.RawMessage
+			if err := json.Unmarshal(arguments, &named); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %v", err)
+			}
+			for _, key := range required {
+				if _, ok := named[key]; !ok {
+					return nil, fmt.Errorf("missing argument %s", key)
 				}
-				args[i] = convertedArg
-			} else {
-				args[i] = argValue
 			}
 		}
+		// Fields left out keep their defaults
+		arg := reflect.New(parameters(fnType)[0])
+		if err := applyDefaults(arg.Elem()); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(arguments, arg.Interface()); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %v", err)
+		}
+		return h.call(ctx, name, reflect.ValueOf(fn), []reflect.Value{arg.Elem()})
+	}
 
-		results := fnValue.Call(args)
-		response := make(map[string]interface{})
+	var named map[string]interface{}
+	if err := json.Unmarshal(arguments, &named); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %v", err)
+	}
+	positional := make([]interface{}, len(parameters(fnType)))
+	for i := range positional {
+		key := fmt.Sprintf("arg%d", i)
+		value, ok := named[key]
+		if !ok && fnType.IsVariadic() && i == len(positional)-1 {
+			value = []interface{}{}
+		} else if !ok {
+			return nil, fmt.Errorf("missing argument %s", key)
+		}
+		positional[i] = value
+	}
+	return h.CallContext(ctx, name, positional)
+}
 
-		if len(results) > 0 {
-			response["result"] = results[0].Interface()
-		} else {
-			response["result"] = nil
+// applyDefaults sets the fields of a struct, or of the struct a pointer
+// points to once allocated, to the values of their default tags. Defaults
+// of string fields are the text of the tag, others are JSON.
+func applyDefaults(v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := applyDefaults(v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
 		}
+		value, ok, err := fieldDefault(field)
+		if err != nil {
+			return err
+		}
+		if ok {
+			v.Field(i).Set(value)
+		}
+	}
+	return nil
+}
 
+// fieldDefault returns the value of the default tag of a field, if it has
+// one
+func fieldDefault(field reflect.StructField) (reflect.Value, bool, error) {
+	tag, ok := field.Tag.Lookup("default")
+	if !ok {
+		return reflect.Value{}, false, nil
+	}
+	value := reflect.New(field.Type).Elem()
+	if field.Type.Kind() == reflect.String {
+		value.SetString(tag)
+		return value, true, nil
+	}
+	if err := json.Unmarshal([]byte(tag), value.Addr().Interface()); err != nil {
+		return reflect.Value{}, false, fmt.Errorf("invalid default of field %s: %v", field.Name, err)
+	}
+	return value, true, nil
+}
+
+// functionTool calls a registered function for an MCP tool, charging it like
+// function.call
+func (s *Server) functionTool(name string) rpcMethod {
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		if err := s.usage.Consume(TenantFromContext(ctx), MetricFunctionCalls, 1); err != nil {
+			return nil, err
+		}
+		return s.functions.callNamed(ctx, name, params)
+	}
+}
+
+// AddFunctionHandler adds function handling capabilities to the MCP server
+func (s *Server) AddFunctionHandler() {
+	handler := NewFunctionHandler()
+	handler.changed = s.tools.publish
+	handler.unscoped = s.usage == nil
+	if timeout := s.config.Functions.Timeout; timeout != 0 {
+		handler.timeout = timeout
+	}
+	handler.metrics = newFunctionMetrics(s.config.Functions.History, s.config.Functions.RedactArguments)
+	s.functions = handler
+	jobs := newFunctionJobs(handler, s.config.Functions)
+	s.functionJobs = jobs
+
+	// Add example built-in functions
+	handler.RegisterFunction("echo", func(msg string) string { return msg })
+
+	plugins := newPluginLoader(s.config.Functions.PluginDir, handler)
+	if plugins.dir != "" {
+		s.loadPlugins(plugins)
+	}
+
+	// Register routes
+	s.handle(Route{
+		Method: "GET", Path: "/function/list", Summary: "List callable functions",
+		Query: []QueryParam{
+			{Name: "format", Description: "openai or anthropic for tool definitions to pass to their APIs"},
+		},
+		Response: []FunctionMetadata{},
+	}, handleListFunctions(handler))
+	s.handle(Route{
+		Method: "POST", Path: "/function/call", Summary: "Call a function",
+		Query: []QueryParam{
+			{Name: "async", Description: "Run the call as a job, returned at once with status 202"},
+		},
+		Request: FunctionRequest{}, Response: map[string]interface{}{}, Timeout: LongRunningTimeout,
+	}, handleCallFunction(s, handler, jobs))
+	s.handle(Route{
+		Method: "GET", Path: "/function/jobs", Summary: "List function jobs",
+		Response: []FunctionJob{},
+	}, handleListFunctionJobs(jobs))
+	s.handle(Route{
+		Method: "GET", Path: "/function/jobs/{id}", Summary: "Get the status and result of a function job",
+		Response: FunctionJob{},
+	}, handleGetFunctionJob(jobs))
+	s.handle(Route{
+		Method: "DELETE", Path: "/function/jobs/{id}", Summary: "Cancel a function job",
+		Response: FunctionJob{},
+	}, handleCancelFunctionJob(jobs))
+	s.handle(Route{
+		Method: "GET", Path: "/function/stats", Summary: "Get the call counts, latencies and error rates of functions",
+		Response: []FunctionStats{},
+	}, handleFunctionStats(handler))
+	s.handle(Route{
+		Method: "GET", Path: "/function/{name}/history", Summary: "List the recent invocations of a function, newest first",
+		Response: []FunctionInvocation{},
+	}, handleFunctionHistory(handler))
+	s.handle(Route{
+		Method: "GET", Path: "/function/plugins", Summary: "List the plugins of the plugin directory",
+		Response: []PluginStatus{},
+	}, handleListPlugins(plugins))
+	s.handle(Route{
+		Method: "POST", Path: "/function/plugins/load", Summary: "Load the plugins added to the plugin directory",
+		Response: []PluginStatus{},
+	}, handleLoadPlugins(plugins))
+
+	s.handleRPC(RPCMethod{Name: "function.list", Summary: "List the callable functions"}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return handler.GetFunctionMetadata(), nil
+	})
+	s.handleRPC(RPCMethod{
+		Name: "function.call", Summary: "Call a function with positional or named arguments", Params: FunctionRequest{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var req FunctionRequest
+		if err := decodeParams(params, &req); err != nil {
+			return nil, err
+		}
+		if err := s.usage.Consume(TenantFromContext(ctx), MetricFunctionCalls, 1); err != nil {
+			return nil, err
+		}
+		if req.NoCache {
+			ctx = WithoutCache(ctx)
+		}
+		result, err := handler.callArguments(ctx, req.Name, req.Arguments)
+		var fnErr *FunctionError
+		if err != nil && !errors.Is(err, ErrFunctionNotFound) && !errors.Is(err, ErrScopeRequired) && !errors.As(err, &fnErr) {
+			return nil, invalidParams(err)
+		}
+		return result, err
+	})
+}
+
+func handleCallFunction(s *Server, h *FunctionHandler, jobs *functionJobs) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req FunctionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.usage.Consume(TenantFromContext(r.Context()), MetricFunctionCalls, 1); err != nil {
+			writeError(w, http.StatusTooManyRequests, err)
+			return
+		}
+
+		var cache cacheStatus
+		ctx := cacheRequest(r, req.NoCache, &cache)
+		if async, _ := strconv.ParseBool(r.URL.Query().Get("async")); async {
+			job, err := jobs.submit(ctx, req.Name, req.Arguments)
+			if err != nil {
+				writeError(w, jobStatus(err), err)
+				return
+			}
+			LoggerFromContext(r.Context()).Info("function job queued", "job", job.ID, "function", job.Function)
+			writeJSON(w, http.StatusAccepted, job)
+			return
+		}
+
+		result, err := h.callArguments(ctx, req.Name, req.Arguments)
+		if err != nil {
+			status, err := functionCallError(err)
+			writeError(w, status, err)
+			return
+		}
+		writeCacheHeaders(w, &cache)
+
+		response := map[string]interface{}{"result": result}
 		writeJSON(w, http.StatusOK, response)
 	}
 }
 
-// convertArgument attempts to convert an argument to the expected type
+// functionCallError returns the status and error of a failed function call.
+// Arguments that do not fit are bad requests. Errors returned by functions
+// have the status of their sentinel or of the code of the *APIError they
+// are; others fail with a function_error naming the function.
+func functionCallError(err error) (int, error) {
+	var apiErr *APIError
+	var fnErr *FunctionError
+	switch {
+	case errors.Is(err, ErrFunctionNotFound):
+		return http.StatusNotFound, err
+	case errors.Is(err, ErrScopeRequired):
+		return http.StatusForbidden, err
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, err
+	case errors.Is(err, ErrUpstreamUnavailable):
+		return http.StatusServiceUnavailable, err
+	case errors.Is(err, ErrUpstreamTool):
+		return http.StatusBadGateway, err
+	case errors.As(err, &apiErr):
+		return codeStatus(apiErr.Code), apiErr
+	case errors.As(err, &fnErr):
+		return http.StatusInternalServerError, newAPIError(CodeFunctionError, fnErr.Err, map[string]interface{}{"function": fnErr.Function})
+	default:
+		return http.StatusBadRequest, err
+	}
+}
+
+// convertArgument converts an argument, as decoded from JSON or passed to
+// Call, to the expected type. Slices, arrays, maps and pointers are
+// converted element by element; structs are decoded from objects by their
+// json tags, with the defaults of their default tags.
 func convertArgument(arg interface{}, expectedType reflect.Type) (reflect.Value, error) {
 	argValue := reflect.ValueOf(arg)
+	if !argValue.IsValid() {
+		switch expectedType.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			return reflect.Zero(expectedType), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot convert null to %v", expectedType)
+	}
+	if argValue.Type().AssignableTo(expectedType) {
+		return argValue.Convert(expectedType), nil
+	}
 
-	// Handle numeric type conversions
 	switch expectedType.Kind() {
+	// Handle numeric type conversions
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if v, ok := arg.(float64); ok {
 			return reflect.ValueOf(int64(v)).Convert(expectedType), nil
@@ -186,6 +897,66 @@ func convertArgument(arg interface{}, expectedType reflect.Type) (reflect.Value,
 		if v, ok := arg.(float64); ok {
 			return reflect.ValueOf(v).Convert(expectedType), nil
 		}
+
+	case reflect.Ptr:
+		elem, err := convertArgument(arg, expectedType.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(expectedType.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+
+	case reflect.Slice, reflect.Array:
+		if argValue.Kind() != reflect.Slice && argValue.Kind() != reflect.Array {
+			if s, ok := arg.(string); ok && expectedType.Kind() == reflect.Slice && expectedType.Elem().Kind() == reflect.Uint8 {
+				// JSON carries bytes in base64
+				return convertJSON(s, expectedType)
+			}
+			break
+		}
+		n := argValue.Len()
+		var converted reflect.Value
+		if expectedType.Kind() == reflect.Slice {
+			converted = reflect.MakeSlice(expectedType, n, n)
+		} else {
+			if n != expectedType.Len() {
+				return reflect.Value{}, fmt.Errorf("expected %d elements, got %d", expectedType.Len(), n)
+			}
+			converted = reflect.New(expectedType).Elem()
+		}
+		for i := 0; i < n; i++ {
+			elem, err := convertArgument(argValue.Index(i).Interface(), expectedType.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %v", i, err)
+			}
+			converted.Index(i).Set(elem)
+		}
+		return converted, nil
+
+	case reflect.Map:
+		if argValue.Kind() != reflect.Map {
+			break
+		}
+		converted := reflect.MakeMapWithSize(expectedType, argValue.Len())
+		iter := argValue.MapRange()
+		for iter.Next() {
+			key, err := convertMapKey(iter.Key().Interface(), expectedType.Key())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %v: %v", iter.Key().Interface(), err)
+			}
+			value, err := convertArgument(iter.Value().Interface(), expectedType.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %v: %v", iter.Key().Interface(), err)
+			}
+			converted.SetMapIndex(key, value)
+		}
+		return converted, nil
+
+	case reflect.Struct:
+		if argValue.Kind() == reflect.Map || expectedType == timeType {
+			return convertJSON(arg, expectedType)
+		}
 	}
 
 	if !argValue.Type().ConvertibleTo(expectedType) {
@@ -194,3 +965,44 @@ func convertArgument(arg interface{}, expectedType reflect.Type) (reflect.Value,
 
 	return argValue.Convert(expectedType), nil
 }
+
+// convertMapKey converts a map key. Keys of JSON objects are strings, so
+// they are parsed for maps with numeric keys.
+func convertMapKey(key interface{}, keyType reflect.Type) (reflect.Value, error) {
+	s, ok := key.(string)
+	if !ok {
+		return convertArgument(key, keyType)
+	}
+	switch keyType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, keyType.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(n).Convert(keyType), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, keyType.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(n).Convert(keyType), nil
+	}
+	return convertArgument(key, keyType)
+}
+
+// convertJSON converts an argument through JSON, as for structs decoded
+// from objects; their fields left out keep their defaults
+func convertJSON(arg interface{}, expectedType reflect.Type) (reflect.Value, error) {
+	data, err := json.Marshal(arg)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	converted := reflect.New(expectedType)
+	if err := applyDefaults(converted.Elem()); err != nil {
+		return reflect.Value{}, err
+	}
+	if err := json.Unmarshal(data, converted.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("cannot convert to %v: %v", expectedType, err)
+	}
+	return converted.Elem(), nil
+}
@@ -0,0 +1,448 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionHandler_StructParameters(t *testing.T) {
+	type paging struct {
+		Limit int `json:"limit,omitempty" description:"Maximum number of results"`
+	}
+	type search struct {
+		Query  string `json:"query" description:"Text to search for" required:"true"`
+		Fields []string
+		paging
+		internal bool
+	}
+	h := NewFunctionHandler()
+	require.NoError(t, h.RegisterFunction("search", func(s search) int { return len(s.Query) + s.Limit }))
+
+	metadata := h.GetFunctionMetadata()
+	require.Len(t, metadata, 1)
+	schema, err := json.Marshal(metadata[0].Parameters)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"query": {"type": "string", "description": "Text to search for"},
+			"Fields": {"type": "array", "items": {"type": "string"}},
+			"limit": {"type": "integer", "format": "int64", "description": "Maximum number of results"}
+		},
+		"required": ["query"]
+	}`, string(schema))
+
+	// Required fields must be given by name
+	result, err := h.callNamed(context.Background(), "search", json.RawMessage(`{"query":"abc","limit":2}`))
+	require.NoError(t, err)
+	assert.Equal(t, 5, result)
+	_, err = h.callNamed(context.Background(), "search", json.RawMessage(`{"limit":2}`))
+	assert.EqualError(t, err, "missing argument query")
+}
+
+func TestFunctionHandler_NamedArguments(t *testing.T) {
+	type listing struct {
+		Dir   string `json:"dir" required:"true"`
+		Order string `json:"order" default:"name"`
+		Limit int    `json:"limit" default:"10"`
+	}
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+	require.NoError(t, s.functions.RegisterFunction("add", func(a, b int) int { return a + b }))
+	require.NoError(t, s.functions.RegisterFunction("list", func(l *listing) listing { return *l }))
+	assert.Error(t, s.functions.RegisterFunction("bad", func(struct {
+		Limit int `default:"ten"`
+	}) {
+	}))
+
+	metadata := make(map[string]FunctionMetadata)
+	for _, fn := range s.functions.GetFunctionMetadata() {
+		metadata[fn.Name] = fn
+	}
+	assert.Equal(t, "name", metadata["list"].Parameters.Properties["order"].Default)
+	assert.Equal(t, 10, metadata["list"].Parameters.Properties["limit"].Default)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		result string
+	}{
+		{"positional", `{"name":"add","arguments":[2,3]}`, http.StatusOK, `5`},
+		{"named positional parameters", `{"name":"add","arguments":{"arg1":3,"arg0":2}}`, http.StatusOK, `5`},
+		{"defaults", `{"name":"list","arguments":{"dir":"src"}}`, http.StatusOK, `{"dir":"src","order":"name","limit":10}`},
+		{"defaults overridden", `{"name":"list","arguments":{"dir":"src","order":"size","limit":0}}`, http.StatusOK, `{"dir":"src","order":"size","limit":0}`},
+		{"missing required field", `{"name":"list","arguments":{"order":"size"}}`, http.StatusBadRequest, ""},
+		{"missing positional parameter", `{"name":"add","arguments":{"arg0":2}}`, http.StatusBadRequest, ""},
+		{"neither array nor object", `{"name":"add","arguments":"2,3"}`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/function/call", strings.NewReader(tt.body)))
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.result != "" {
+				var resp struct {
+					Result json.RawMessage `json:"result"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.JSONEq(t, tt.result, string(resp.Result))
+			}
+		})
+	}
+
+	// JSON-RPC takes the same arguments
+	rec := postRPC(t, s, `{"jsonrpc":"2.0","method":"function.call","params":{"name":"add","arguments":{"arg0":1,"arg1":1}},"id":1}`)
+	var resp RPCResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Nil(t, resp.Error)
+	assert.JSONEq(t, `2`, string(resp.Result))
+}
+
+func TestFunctionHandler_Context(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Functions.Timeout = 50 * time.Millisecond
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddFunctionHandler()
+	require.NoError(t, s.functions.RegisterFunction("sleep", func(ctx context.Context, ms int) (string, error) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(time.Duration(ms) * time.Millisecond):
+			return "awake", nil
+		}
+	}))
+
+	// The context is not an argument
+	for _, fn := range s.functions.GetFunctionMetadata() {
+		if fn.Name == "sleep" {
+			assert.Equal(t, []ArgumentInfo{{Name: "arg0", Type: "int", Required: true}}, fn.Arguments)
+			assert.Equal(t, []string{"arg0"}, fn.Parameters.Required)
+		}
+	}
+
+	call := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/function/call", strings.NewReader(body)))
+		return w
+	}
+	w := call(`{"name":"sleep","arguments":[1]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"result":"awake"}`, w.Body.String())
+	w = call(`{"name":"sleep","arguments":{"arg0":5000}}`)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code, w.Body.String())
+
+	// Functions stop with the request calling them
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.functions.CallContext(ctx, "sleep", []interface{}{5000})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	s.functions.SetTimeout(0)
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = s.functions.CallContext(ctx, "sleep", []interface{}{5000})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFunctionHandler_Errors(t *testing.T) {
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+	require.NoError(t, s.functions.RegisterFunction("divide", func(a, b int) (int, int, error) {
+		if b == 0 {
+			return 0, 0, errors.New("division by zero")
+		}
+		return a / b, a % b, nil
+	}))
+	require.NoError(t, s.functions.RegisterFunction("user", func(id string) (string, error) {
+		return "", newAPIError(CodeNotFound, fmt.Errorf("no user %s", id), nil)
+	}))
+	require.NoError(t, s.functions.RegisterFunction("check", func() error { return nil }))
+
+	returns := make(map[string]string)
+	for _, fn := range s.functions.GetFunctionMetadata() {
+		returns[fn.Name] = fn.ReturnType
+	}
+	assert.Equal(t, "(int, int)", returns["divide"])
+	assert.Equal(t, "string", returns["user"])
+	assert.Equal(t, "void", returns["check"])
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		result string
+		err    *APIError
+	}{
+		{"all results", `{"name":"divide","arguments":[7,2]}`, http.StatusOK, `[3,1]`, nil},
+		{"no results", `{"name":"check","arguments":[]}`, http.StatusOK, `null`, nil},
+		{"function error", `{"name":"divide","arguments":[7,0]}`, http.StatusInternalServerError, "",
+			&APIError{Code: CodeFunctionError, Message: "division by zero", Details: map[string]interface{}{"function": "divide"}}},
+		{"API error", `{"name":"user","arguments":["ada"]}`, http.StatusNotFound, "", &APIError{Code: CodeNotFound, Message: "no user ada"}},
+		{"bad argument", `{"name":"divide","arguments":["a",1]}`, http.StatusBadRequest, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/function/call", strings.NewReader(tt.body)))
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.result != "" {
+				var resp struct {
+					Result json.RawMessage `json:"result"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.JSONEq(t, tt.result, string(resp.Result))
+			}
+			if tt.err != nil {
+				var resp ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				resp.Error.CorrelationID = ""
+				assert.Equal(t, *tt.err, resp.Error)
+			}
+		})
+	}
+
+	// JSON-RPC names the function that failed
+	rec := postRPC(t, s, `{"jsonrpc":"2.0","method":"function.call","params":{"name":"divide","arguments":[1,0]},"id":1}`)
+	var resp RPCResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, RPCInternalError, resp.Error.Code)
+	assert.Equal(t, map[string]interface{}{"function": "divide"}, resp.Error.Data)
+}
+
+func TestFunctionHandler_Scopes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Usage = UsageConfig{
+		Enabled:       true,
+		DefaultScopes: []string{"read"},
+		Tenants: map[string]TenantConfig{
+			"ops": {Keys: []string{"ops-key"}, Scopes: []string{"read", "deploy"}},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddFunctionHandler()
+	defer s.functionJobs.stop(context.Background())
+
+	require.NoError(t, s.functions.RegisterFunction("deploy", func(version string) string { return version }, RequireScopes("deploy")))
+
+	do := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	call := `{"name":"deploy","arguments":["v2"]}`
+	tests := []struct {
+		name   string
+		path   string
+		key    string
+		status int
+	}{
+		{"granted", "/v1/function/call", "ops-key", http.StatusOK},
+		{"unassigned key", "/v1/function/call", "other-key", http.StatusForbidden},
+		{"no key", "/v1/function/call", "", http.StatusForbidden},
+		{"async granted", "/v1/function/call?async=true", "ops-key", http.StatusAccepted},
+		{"async denied", "/v1/function/call?async=true", "other-key", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(tt.path, tt.key, call)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+
+	req := httptest.NewRequest("POST", "/v1/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"function.call","params":`+call+`,"id":1}`))
+	req.Header.Set("X-API-Key", "other-key")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	var resp RPCResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, RPCForbidden, resp.Error.Code)
+
+	// In-process calls are not limited
+	result, err := s.functions.Call("deploy", []interface{}{"v3"})
+	require.NoError(t, err)
+	assert.Equal(t, "v3", result)
+
+	for _, m := range s.functions.GetFunctionMetadata() {
+		if m.Name == "deploy" {
+			assert.Equal(t, []string{"deploy"}, m.Scopes)
+		} else {
+			assert.Empty(t, m.Scopes)
+		}
+	}
+}
+
+func TestFunctionHandler_ScopesWithoutUsage(t *testing.T) {
+	s := NewServer(nil, WithConfig(DefaultConfig()))
+	s.AddFunctionHandler()
+	defer s.functionJobs.stop(context.Background())
+
+	// Remote callers have no scopes to check without usage accounting
+	err := s.functions.RegisterFunction("deploy", func(version string) string { return version }, RequireScopes("deploy"))
+	require.Error(t, err)
+	assert.False(t, s.functions.has("deploy"))
+
+	req := httptest.NewRequest("POST", "/v1/function/call", strings.NewReader(`{"name":"deploy","arguments":["v2"]}`))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+	// Handlers of no server only have in-process callers
+	h := NewFunctionHandler()
+	require.NoError(t, h.RegisterFunction("deploy", func(version string) string { return version }, RequireScopes("deploy")))
+}
+
+func TestFunctionHandler_ChangedListener(t *testing.T) {
+	h := NewFunctionHandler()
+	var seen [][]string
+	h.changed = func() {
+		// Listeners may read the functions they are told changed
+		var names []string
+		for _, m := range h.GetFunctionMetadata() {
+			names = append(names, m.Name)
+		}
+		seen = append(seen, names)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, h.RegisterFunction("ping", func() string { return "pong" }))
+		assert.Error(t, h.RegisterFunction("ping", func() {}))
+		h.UnregisterFunction("ping")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener deadlocked on the handler")
+	}
+	assert.Equal(t, [][]string{{"ping"}, nil}, seen)
+}
+
+func TestConvertArgument(t *testing.T) {
+	type point struct {
+		X     int    `json:"x"`
+		Y     int    `json:"y"`
+		Label string `json:"label" default:"origin"`
+	}
+	tests := []struct {
+		name string
+		arg  interface{}
+		typ  reflect.Type
+		want interface{}
+		err  string
+	}{
+		{"int", 3.0, reflect.TypeOf(0), 3, ""},
+		{"strings", []interface{}{"a", "b"}, reflect.TypeOf([]string{}), []string{"a", "b"}, ""},
+		{"nested slices", []interface{}{[]interface{}{1.0}, []interface{}{}}, reflect.TypeOf([][]int{}), [][]int{{1}, {}}, ""},
+		{"array", []interface{}{1.0, 2.0}, reflect.TypeOf([2]uint8{}), [2]uint8{1, 2}, ""},
+		{"array length", []interface{}{1.0}, reflect.TypeOf([2]int{}), nil, "expected 2 elements, got 1"},
+		{"bytes", "aGk=", reflect.TypeOf([]byte{}), []byte("hi"), ""},
+		{"map", map[string]interface{}{"a": 1.0}, reflect.TypeOf(map[string]int{}), map[string]int{"a": 1}, ""},
+		{"numeric keys", map[string]interface{}{"7": "x"}, reflect.TypeOf(map[int]string{}), map[int]string{7: "x"}, ""},
+		{"bad key", map[string]interface{}{"x": "x"}, reflect.TypeOf(map[int]string{}), nil, "key x"},
+		{"struct", map[string]interface{}{"x": 1.0, "y": 2.0}, reflect.TypeOf(point{}), point{X: 1, Y: 2, Label: "origin"}, ""},
+		{"structs", []interface{}{map[string]interface{}{"x": 1.0, "label": "p"}}, reflect.TypeOf([]point{}), []point{{X: 1, Label: "p"}}, ""},
+		{"pointer", map[string]interface{}{"y": 5.0}, reflect.TypeOf(&point{}), &point{Y: 5, Label: "origin"}, ""},
+		{"null pointer", nil, reflect.TypeOf(&point{}), (*point)(nil), ""},
+		{"null slice", nil, reflect.TypeOf([]string{}), []string(nil), ""},
+		{"null int", nil, reflect.TypeOf(0), nil, "cannot convert null to int"},
+		{"bad element", []interface{}{"a", 1.0}, reflect.TypeOf([]string{}), nil, "element 1: cannot convert float64 to string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertArgument(tt.arg, tt.typ)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Interface())
+		})
+	}
+}
+
+func TestFunctionHandler_Variadic(t *testing.T) {
+	h := NewFunctionHandler()
+	require.NoError(t, h.RegisterFunction("join", func(sep string, parts ...string) string { return strings.Join(parts, sep) }))
+	require.NoError(t, h.RegisterFunction("sum", func(ctx context.Context, xs ...int) int {
+		total := 0
+		for _, x := range xs {
+			total += x
+		}
+		return total
+	}))
+
+	tests := []struct {
+		name      string
+		function  string
+		arguments string
+		want      interface{}
+		err       string
+	}{
+		{"trailing", "join", `["-","a","b","c"]`, "a-b-c", ""},
+		{"none", "join", `["-"]`, "", ""},
+		{"slice", "join", `["-",["a","b"]]`, "a-b", ""},
+		{"too few", "join", `[]`, nil, "expected at least 1 arguments, got 0"},
+		{"named", "join", `{"arg0":"+","arg1":["x","y"]}`, "x+y", ""},
+		{"named without rest", "join", `{"arg0":"+"}`, "", ""},
+		{"context", "sum", `[1,2,3]`, 6, ""},
+		{"bad trailing", "sum", `[1,"two"]`, nil, "invalid argument 0: element 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.callArguments(context.Background(), tt.function, json.RawMessage(tt.arguments))
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, m := range h.GetFunctionMetadata() {
+		if m.Name == "join" {
+			assert.Equal(t, "...string", m.Arguments[1].Type)
+			assert.False(t, m.Arguments[1].Required)
+			assert.Equal(t, []string{"arg0"}, m.Parameters.Required)
+		}
+	}
+}
+
+func TestServer_Functions(t *testing.T) {
+	s := NewServer(nil)
+	assert.Nil(t, s.Functions())
+
+	cfg := DefaultConfig()
+	cfg.Store.Backend = "memory"
+	cfg.WorkspaceRoot = t.TempDir()
+	s, err := NewServerFromConfig(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, s.Functions())
+	require.NoError(t, s.Functions().RegisterFunction("double", func(n int) int { return 2 * n }))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/function/call", strings.NewReader(`{"name":"double","arguments":[21]}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"result":42}`, w.Body.String())
+}
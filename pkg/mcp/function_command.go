@@ -0,0 +1,302 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// CommandOption configures a command registered as a function
+type CommandOption func(*commandFunction)
+
+// commandFunction runs a subprocess for the calls of a function
+type commandFunction struct {
+	name      string
+	argv      []*template.Template
+	schema    *Schema
+	dir       string
+	env       []string
+	timeout   time.Duration
+	exitCodes map[int]error
+	options   []FunctionOption
+}
+
+// CommandDir runs the command in a directory rather than the server's
+func CommandDir(dir string) CommandOption {
+	return func(c *commandFunction) {
+		c.dir = dir
+	}
+}
+
+// CommandEnv adds KEY=value variables to the environment of the command
+func CommandEnv(env ...string) CommandOption {
+	return func(c *commandFunction) {
+		c.env = append(c.env, env...)
+	}
+}
+
+// CommandTimeout kills the command after a timeout shorter than that of the
+// function handler
+func CommandTimeout(timeout time.Duration) CommandOption {
+	return func(c *commandFunction) {
+		c.timeout = timeout
+	}
+}
+
+// CommandExitCodes maps exit codes of the command to the errors calls fail
+// with; a nil error makes the exit code succeed, as 1 does for grep finding
+// nothing. Calls ending with other exit codes but 0 fail with the end of
+// stderr.
+func CommandExitCodes(codes map[int]error) CommandOption {
+	return func(c *commandFunction) {
+		for code, err := range codes {
+			c.exitCodes[code] = err
+		}
+	}
+}
+
+// CommandFunctionOptions passes options on to RegisterFunction, such as
+// RequireScopes
+func CommandFunctionOptions(opts ...FunctionOption) CommandOption {
+	return func(c *commandFunction) {
+		c.options = append(c.options, opts...)
+	}
+}
+
+// RegisterCommandFunction registers a subprocess as a function taking the
+// named arguments the schema describes. The elements of argv are
+// text/templates over the arguments, e.g. {{.path}}, each rendering one
+// argument of the command; elements rendering empty are left out, so
+// {{if .verbose}}-v{{end}} passes a flag. No shell is involved, and
+// arguments the schema does not declare are refused unless it allows
+// additional properties. Values starting with -, which the command would
+// take for options, are refused unless the enum of their property lists
+// them. Calls return the CommandResult of the command.
+func (h *FunctionHandler) RegisterCommandFunction(name string, argv []string, schema *Schema, opts ...CommandOption) error {
+	if len(argv) == 0 {
+		return fmt.Errorf("command of function %s is empty", name)
+	}
+	if schema == nil {
+		schema = &Schema{Type: "object", Properties: map[string]*Schema{}}
+	}
+	if schema.Type != "object" {
+		return fmt.Errorf("schema of command function %s must be an object", name)
+	}
+
+	c := &commandFunction{name: name, schema: schema, exitCodes: map[int]error{0: nil}}
+	for i, arg := range argv {
+		tmpl, err := template.New(fmt.Sprintf("%s[%d]", name, i)).Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return fmt.Errorf("command of function %s: %v", name, err)
+		}
+		c.argv = append(c.argv, tmpl)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	options := append([]FunctionOption{func(o *functionOptions) { o.schema = schema }}, c.options...)
+	return h.RegisterFunction(name, c.call, options...)
+}
+
+// call runs the command with the arguments of a call
+func (c *commandFunction) call(ctx context.Context, arguments map[string]interface{}) (CommandResult, error) {
+	argv, err := c.render(arguments)
+	if err != nil {
+		return CommandResult{}, err
+	}
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = c.dir
+	if len(c.env) > 0 {
+		cmd.Env = append(os.Environ(), c.env...)
+	}
+	cmd.WaitDelay = 5 * time.Second
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	result := CommandResult{
+		Command:  strings.Join(argv, " "),
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: cmd.ProcessState.ExitCode(),
+	}
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		// The command did not start
+		return result, err
+	}
+
+	mapped, ok := c.exitCodes[result.ExitCode]
+	switch {
+	case !ok:
+		message := lastLine(result.Stderr)
+		if message == "" {
+			return result, fmt.Errorf("%s exited with status %d", argv[0], result.ExitCode)
+		}
+		return result, fmt.Errorf("%s exited with status %d: %s", argv[0], result.ExitCode, message)
+	case mapped != nil:
+		return result, mapped
+	}
+	return result, nil
+}
+
+// render renders the arguments of the command. Arguments must be declared
+// by the schema and have the types it declares for them; properties of the
+// schema missing from the arguments take their defaults, or render empty.
+func (c *commandFunction) render(arguments map[string]interface{}) ([]string, error) {
+	for name, value := range arguments {
+		property, declared := c.schema.Properties[name]
+		if !declared && c.schema.AdditionalProperties != nil {
+			property, declared = c.schema.AdditionalProperties, true
+		}
+		err := fmt.Errorf("unknown argument %s", name)
+		if declared {
+			if err = checkArgument(name, value, property); err == nil {
+				err = checkOption(name, value, property)
+			}
+		}
+		if err != nil {
+			return nil, newAPIError(CodeInvalidRequest, err, map[string]interface{}{"argument": name})
+		}
+	}
+
+	data := make(map[string]interface{}, len(c.schema.Properties)+len(arguments))
+	for name, property := range c.schema.Properties {
+		data[name] = ""
+		if property != nil && property.Default != nil {
+			data[name] = property.Default
+		}
+	}
+	for name, value := range arguments {
+		data[name] = value
+	}
+
+	var argv []string
+	for _, tmpl := range c.argv {
+		var arg strings.Builder
+		if err := tmpl.Execute(&arg, data); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %v", err)
+		}
+		if arg.Len() > 0 {
+			argv = append(argv, arg.String())
+		}
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("command of function %s rendered empty", c.name)
+	}
+	return argv, nil
+}
+
+// checkArgument checks that an argument, as decoded from JSON or passed to
+// Call, has the type its schema declares and is one of its enum. Arguments
+// without a schema or type are not checked.
+func checkArgument(name string, value interface{}, schema *Schema) error {
+	if schema == nil {
+		return nil
+	}
+	if len(schema.Enum) > 0 && value != nil && !inEnum(schema.Enum, value) {
+		return fmt.Errorf("argument %s must be one of %v", name, schema.Enum)
+	}
+	if schema.Type == "" {
+		return nil
+	}
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return fmt.Errorf("argument %s must not be null", name)
+	}
+
+	v := reflect.ValueOf(value)
+	var ok bool
+	switch schema.Type {
+	case "string":
+		ok = v.Kind() == reflect.String
+	case "boolean":
+		ok = v.Kind() == reflect.Bool
+	case "integer", "number":
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			ok = true
+		case reflect.Float32, reflect.Float64:
+			f := v.Float()
+			ok = !math.IsInf(f, 0) && !math.IsNaN(f) && (schema.Type == "number" || f == math.Trunc(f))
+		}
+	case "array":
+		ok = v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+		for i := 0; ok && i < v.Len(); i++ {
+			if err := checkArgument(fmt.Sprintf("%s[%d]", name, i), v.Index(i).Interface(), schema.Items); err != nil {
+				return err
+			}
+		}
+	case "object":
+		ok = v.Kind() == reflect.Map || v.Kind() == reflect.Struct
+	default:
+		ok = true
+	}
+	if !ok {
+		return fmt.Errorf("argument %s must be of type %s", name, schema.Type)
+	}
+	return nil
+}
+
+// checkOption refuses values rendering with a leading -, which the command
+// would take for options, unless the enum of their schema lists them.
+// Elements of arrays are checked against the items of the schema.
+func checkOption(name string, value interface{}, schema *Schema) error {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		var items *Schema
+		if schema != nil {
+			items = schema.Items
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := checkOption(fmt.Sprintf("%s[%d]", name, i), v.Index(i).Interface(), items); err != nil {
+				return err
+			}
+		}
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		if strings.HasPrefix(fmt.Sprint(value), "-") && (schema == nil || !inEnum(schema.Enum, value)) {
+			return fmt.Errorf("argument %s must not start with - unless the enum of its schema lists it", name)
+		}
+	}
+	return nil
+}
+
+// inEnum reports whether value is one of enum, comparing them as rendered so
+// numbers decoded from JSON match Go integers
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastLine returns the last non-empty line of output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,213 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commandHelperEnv makes the test binary run as commandHelper
+const commandHelperEnv = "MCP_COMMAND_HELPER=1"
+
+func TestMain(m *testing.M) {
+	if os.Getenv("MCP_COMMAND_HELPER") == "1" {
+		os.Exit(commandHelper(os.Args[1:]))
+	}
+	os.Exit(m.Run())
+}
+
+// commandHelper stands in for the commands of command functions. It prints
+// its arguments to stdout, or to stderr with -stderr, and exits with the
+// status of -exit after sleeping for -sleep.
+func commandHelper(args []string) int {
+	flags := flag.NewFlagSet("helper", flag.ContinueOnError)
+	stderr := flags.Bool("stderr", false, "print to stderr")
+	status := flags.Int("exit", 0, "exit status")
+	sleep := flags.Duration("sleep", 0, "time to sleep first")
+	if err := flags.Parse(args); err != nil {
+		return 125
+	}
+	time.Sleep(*sleep)
+	out := os.Stdout
+	if *stderr {
+		out = os.Stderr
+	}
+	fmt.Fprintln(out, strings.Join(flags.Args(), " "))
+	return *status
+}
+
+func TestRegisterCommandFunction(t *testing.T) {
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+	h := s.functions
+
+	errNoMatch := &APIError{Code: CodeNotFound, Message: "no match"}
+	schema := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"text":   {Type: "string", Description: "Text to print"},
+			"status": {Type: "integer", Default: 0},
+			"stderr": {Type: "boolean"},
+		},
+		Required: []string{"text"},
+	}
+	helper := os.Args[0]
+	require.NoError(t, h.RegisterCommandFunction("print", []string{
+		helper, "{{if .stderr}}-stderr{{end}}", "-exit={{.status}}", "--", "{{.text}}",
+	}, schema, CommandEnv(commandHelperEnv), CommandExitCodes(map[int]error{1: nil, 2: errNoMatch})))
+	require.NoError(t, h.RegisterCommandFunction("sleep", []string{helper, "-sleep=5s"}, nil,
+		CommandEnv(commandHelperEnv), CommandTimeout(50*time.Millisecond)))
+	require.NoError(t, h.RegisterCommandFunction("missing", []string{"/nonexistent/command"}, nil))
+	require.NoError(t, h.RegisterCommandFunction("flag", []string{helper, "{{.flag}}", "flagged"}, &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"flag": {Type: "string", Enum: []interface{}{"-stderr", "plain"}}},
+	}, CommandEnv(commandHelperEnv)))
+
+	call := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/function/call", strings.NewReader(body)))
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		result CommandResult
+		code   string
+	}{
+		{"stdout", `{"name":"print","arguments":{"text":"hello; rm -rf /"}}`, http.StatusOK,
+			CommandResult{Command: helper + " -exit=0 -- hello; rm -rf /", Stdout: "hello; rm -rf /\n"}, ""},
+		{"successful exit code", `{"name":"print","arguments":{"text":"hi","status":1}}`, http.StatusOK,
+			CommandResult{Command: helper + " -exit=1 -- hi", Stdout: "hi\n", ExitCode: 1}, ""},
+		{"mapped exit code", `{"name":"print","arguments":{"text":"hi","status":2}}`, http.StatusNotFound, CommandResult{}, CodeNotFound},
+		{"failed", `{"name":"print","arguments":{"text":"bad input","status":3,"stderr":true}}`, http.StatusInternalServerError, CommandResult{}, CodeFunctionError},
+		{"missing argument", `{"name":"print","arguments":{}}`, http.StatusBadRequest, CommandResult{}, ""},
+		{"string for integer", `{"name":"print","arguments":{"text":"hi","status":"0; rm -rf /"}}`, http.StatusBadRequest, CommandResult{}, CodeInvalidRequest},
+		{"fraction for integer", `{"name":"print","arguments":{"text":"hi","status":1.5}}`, http.StatusBadRequest, CommandResult{}, CodeInvalidRequest},
+		{"string for boolean", `{"name":"print","arguments":{"text":"hi","stderr":"yes"}}`, http.StatusBadRequest, CommandResult{}, CodeInvalidRequest},
+		{"object for string", `{"name":"print","arguments":{"text":{"a":1}}}`, http.StatusBadRequest, CommandResult{}, CodeInvalidRequest},
+		{"null for string", `{"name":"print","arguments":{"text":null}}`, http.StatusBadRequest, CommandResult{}, CodeInvalidRequest},
+		{"unknown argument", `{"name":"print","arguments":{"text":"hi","exit":"1"}}`, http.StatusBadRequest, CommandResult{}, CodeInvalidRequest},
+		{"option", `{"name":"print","arguments":{"text":"--help"}}`, http.StatusBadRequest, CommandResult{}, CodeInvalidRequest},
+		{"negative number", `{"name":"print","arguments":{"text":"hi","status":-1}}`, http.StatusBadRequest, CommandResult{}, CodeInvalidRequest},
+		{"option in enum", `{"name":"flag","arguments":{"flag":"-stderr"}}`, http.StatusOK,
+			CommandResult{Command: helper + " -stderr flagged", Stderr: "flagged\n"}, ""},
+		{"not in enum", `{"name":"flag","arguments":{"flag":"-exit=0"}}`, http.StatusBadRequest, CommandResult{}, CodeInvalidRequest},
+		{"timeout", `{"name":"sleep","arguments":{}}`, http.StatusGatewayTimeout, CommandResult{}, ""},
+		{"not started", `{"name":"missing","arguments":{}}`, http.StatusInternalServerError, CommandResult{}, CodeFunctionError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := call(tt.body)
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.status == http.StatusOK {
+				var resp struct {
+					Result CommandResult `json:"result"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.result, resp.Result)
+			}
+			if tt.code != "" {
+				var resp ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.code, resp.Error.Code)
+			}
+		})
+	}
+
+	_, err := h.callArguments(context.Background(), "print", []byte(`{"text":"oops","status":3,"stderr":true}`))
+	assert.EqualError(t, err, helper+" exited with status 3: oops")
+
+	// The schema describes the function
+	for _, m := range h.GetFunctionMetadata() {
+		if m.Name == "print" {
+			assert.Same(t, schema, m.Parameters)
+		}
+	}
+
+	err = h.RegisterCommandFunction("bad", []string{"{{.x"}, nil)
+	assert.Error(t, err)
+	err = h.RegisterCommandFunction("empty", nil, nil)
+	assert.Error(t, err)
+	err = h.RegisterCommandFunction("undeclared", []string{"echo", "{{.typo}}"}, nil)
+	require.NoError(t, err)
+	_, err = h.callArguments(context.Background(), "undeclared", []byte(`{}`))
+	assert.ErrorContains(t, err, `no entry for key "typo"`)
+}
+
+func TestCheckArgument(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		schema *Schema
+		valid  bool
+	}{
+		{"no schema", "x", nil, true},
+		{"untyped", 1.0, &Schema{}, true},
+		{"go integer", 3, &Schema{Type: "integer"}, true},
+		{"whole float", 3.0, &Schema{Type: "integer"}, true},
+		{"fraction", 3.5, &Schema{Type: "integer"}, false},
+		{"number", 3.5, &Schema{Type: "number"}, true},
+		{"string number", "3", &Schema{Type: "number"}, false},
+		{"array items", []interface{}{"a", "b"}, &Schema{Type: "array", Items: &Schema{Type: "string"}}, true},
+		{"array bad item", []interface{}{"a", 1.0}, &Schema{Type: "array", Items: &Schema{Type: "string"}}, false},
+		{"go slice", []string{"a"}, &Schema{Type: "array"}, true},
+		{"object", map[string]interface{}{}, &Schema{Type: "object"}, true},
+		{"null", nil, &Schema{Type: "string"}, false},
+		{"nullable", nil, &Schema{Type: "string", Nullable: true}, true},
+		{"in enum", "fast", &Schema{Type: "string", Enum: []interface{}{"fast", "slow"}}, true},
+		{"not in enum", "medium", &Schema{Type: "string", Enum: []interface{}{"fast", "slow"}}, false},
+		{"integer in enum", 2, &Schema{Type: "integer", Enum: []interface{}{1.0, 2.0}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkArgument("arg", tt.value, tt.schema)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckOption(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		schema *Schema
+		valid  bool
+	}{
+		{"plain", "file.txt", &Schema{Type: "string"}, true},
+		{"dash inside", "a-b", &Schema{Type: "string"}, true},
+		{"option", "-rf", &Schema{Type: "string"}, false},
+		{"long option", "--output=/etc/passwd", nil, false},
+		{"option in enum", "-v", &Schema{Type: "string", Enum: []interface{}{"-v", "-q"}}, true},
+		{"negative number", -1.0, &Schema{Type: "number"}, false},
+		{"negative number in enum", -1, &Schema{Type: "integer", Enum: []interface{}{-1.0}}, true},
+		{"array item", []interface{}{"a", "-b"}, &Schema{Type: "array", Items: &Schema{Type: "string"}}, false},
+		{"array without items", []string{"-b"}, &Schema{Type: "array"}, false},
+		{"boolean", true, &Schema{Type: "boolean"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOption("arg", tt.value, tt.schema)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
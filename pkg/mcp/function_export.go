@@ -0,0 +1,174 @@
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Formats of function definitions for LLM APIs
+const (
+	FunctionFormatOpenAI    = "openai"
+	FunctionFormatAnthropic = "anthropic"
+)
+
+// OpenAITool defines a function as the tools of OpenAI chat completions
+// take it
+type OpenAITool struct {
+	Type     string         `json:"type"` // Always function
+	Function OpenAIFunction `json:"function"`
+}
+
+// OpenAIFunction is the function of an OpenAITool
+type OpenAIFunction struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Parameters  *Schema `json:"parameters"`
+}
+
+// AnthropicTool defines a function as the tools of Anthropic messages take
+// it
+type AnthropicTool struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	InputSchema *Schema `json:"input_schema"`
+}
+
+// maxToolName is the longest tool name LLM APIs accept
+const maxToolName = 64
+
+// toolName returns the name of a function as LLM APIs allow it, with
+// characters other than letters, digits, _ and - replaced by _ and cut to
+// maxToolName characters. Calls resolve such names back to the function.
+func toolName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, name)
+	if len(name) > maxToolName {
+		name = name[:maxToolName]
+	}
+	return name
+}
+
+// toolNames returns the tool names of the registered functions by their
+// registered names. Functions whose names are valid tool names keep them;
+// others that would get a tool name already taken get the suffix _2, _3
+// and so on, in order of their names. Callers hold h.mu.
+func (h *FunctionHandler) toolNames() map[string]string {
+	names := make([]string, 0, len(h.functions))
+	for name := range h.functions {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		valid := toolName(names[i]) == names[i]
+		if valid != (toolName(names[j]) == names[j]) {
+			return valid
+		}
+		return names[i] < names[j]
+	})
+
+	tools := make(map[string]string, len(names))
+	taken := make(map[string]bool, len(names))
+	for _, name := range names {
+		tool := toolName(name)
+		for n := 2; taken[tool]; n++ {
+			suffix := fmt.Sprintf("_%d", n)
+			tool = toolName(name)
+			if len(tool)+len(suffix) > maxToolName {
+				tool = tool[:maxToolName-len(suffix)]
+			}
+			tool += suffix
+		}
+		taken[tool] = true
+		tools[name] = tool
+	}
+	return tools
+}
+
+// resolve returns the registered name of a function called by name or by
+// the tool name it was exported with. Unknown names are returned as they
+// are.
+func (h *FunctionHandler) resolve(name string) string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if _, exists := h.functions[name]; exists {
+		return name
+	}
+	for registered, tool := range h.toolNames() {
+		if tool == name {
+			return registered
+		}
+	}
+	return name
+}
+
+// exported describes a registered function for LLM APIs
+type exported struct {
+	name        string
+	description string
+	parameters  *Schema
+}
+
+// export returns the registered functions sorted by name, for LLM APIs
+func (h *FunctionHandler) export() []exported {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	tools := h.toolNames()
+	functions := make([]exported, 0, len(h.functions))
+	for name, fn := range h.functions {
+		functions = append(functions, exported{
+			name:        tools[name],
+			description: fmt.Sprintf("Call the registered function %s", name),
+			parameters:  h.schema(name, reflect.TypeOf(fn)),
+		})
+	}
+	sort.Slice(functions, func(i, j int) bool { return functions[i].name < functions[j].name })
+	return functions
+}
+
+// OpenAITools returns the registered functions as tools of OpenAI chat
+// completions
+func (h *FunctionHandler) OpenAITools() []OpenAITool {
+	functions := h.export()
+	tools := make([]OpenAITool, len(functions))
+	for i, fn := range functions {
+		tools[i] = OpenAITool{
+			Type:     "function",
+			Function: OpenAIFunction{Name: fn.name, Description: fn.description, Parameters: fn.parameters},
+		}
+	}
+	return tools
+}
+
+// AnthropicTools returns the registered functions as tools of Anthropic
+// messages
+func (h *FunctionHandler) AnthropicTools() []AnthropicTool {
+	functions := h.export()
+	tools := make([]AnthropicTool, len(functions))
+	for i, fn := range functions {
+		tools[i] = AnthropicTool{Name: fn.name, Description: fn.description, InputSchema: fn.parameters}
+	}
+	return tools
+}
+
+func handleListFunctions(h *FunctionHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch format := r.URL.Query().Get("format"); format {
+		case "":
+			writeJSON(w, http.StatusOK, h.GetFunctionMetadata())
+		case FunctionFormatOpenAI:
+			writeJSON(w, http.StatusOK, h.OpenAITools())
+		case FunctionFormatAnthropic:
+			writeJSON(w, http.StatusOK, h.AnthropicTools())
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Errorf("format must be %s or %s", FunctionFormatOpenAI, FunctionFormatAnthropic))
+		}
+	}
+}
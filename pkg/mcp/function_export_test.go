@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFunctions_Formats(t *testing.T) {
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+	type search struct {
+		Query string `json:"query" required:"true" description:"Text to find"`
+	}
+	require.NoError(t, s.functions.RegisterFunction("docs.search", func(q search) []string { return []string{q.Query} }))
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/function/list?"+query, nil))
+		return w
+	}
+
+	w := get("format=openai")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `[
+		{"type":"function","function":{"name":"docs_search","description":"Call the registered function docs.search","parameters":{
+			"type":"object","properties":{"query":{"type":"string","description":"Text to find"}},"required":["query"]}}},
+		{"type":"function","function":{"name":"echo","description":"Call the registered function echo","parameters":{
+			"type":"object","properties":{"arg0":{"type":"string"}},"required":["arg0"]}}}
+	]`, w.Body.String())
+
+	w = get("format=anthropic")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var tools []AnthropicTool
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tools))
+	require.Len(t, tools, 2)
+	assert.Equal(t, "docs_search", tools[0].Name)
+	assert.Equal(t, []string{"query"}, tools[0].InputSchema.Required)
+
+	assert.Equal(t, http.StatusBadRequest, get("format=gemini").Code)
+
+	// Tool calls of the exported names call the functions
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/function/call", strings.NewReader(`{"name":"docs_search","arguments":{"query":"schema"}}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"result":["schema"]}`, w.Body.String())
+}
+
+func TestToolName(t *testing.T) {
+	tests := map[string]string{
+		"echo":                  "echo",
+		"github.search":         "github_search",
+		"list-issues_v2":        "list-issues_v2",
+		"a b/c":                 "a_b_c",
+		strings.Repeat("x", 70): strings.Repeat("x", maxToolName),
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, toolName(name), name)
+	}
+}
+
+func TestFunctionHandler_ToolNames(t *testing.T) {
+	long := strings.Repeat("x", 70)
+	h := NewFunctionHandler()
+	for _, name := range []string{"a.b", "a_b", "a/b", "a_b_2", long + ".one", long + ".two"} {
+		name := name
+		require.NoError(t, h.RegisterFunction(name, func() string { return name }))
+	}
+
+	want := map[string]string{
+		"a_b":         "a_b",
+		"a_b_2":       "a_b_2",
+		"a.b":         "a_b_3",
+		"a/b":         "a_b_4",
+		long + ".one": strings.Repeat("x", maxToolName),
+		long + ".two": strings.Repeat("x", maxToolName-2) + "_2",
+	}
+	h.mu.RLock()
+	assert.Equal(t, want, h.toolNames())
+	h.mu.RUnlock()
+
+	// Every exported name calls the function it was exported for
+	for _, tool := range h.OpenAITools() {
+		assert.LessOrEqual(t, len(tool.Function.Name), maxToolName)
+		result, err := h.Call(h.resolve(tool.Function.Name), nil)
+		require.NoError(t, err)
+		assert.Equal(t, tool.Function.Name, want[result.(string)])
+	}
+}
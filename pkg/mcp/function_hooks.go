@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// FunctionCall is a call of a registered function as hooks see it
+type FunctionCall struct {
+	Function string
+	// Arguments are the converted arguments, without the context of
+	// functions taking one, or the parameter object of functions taking
+	// named arguments. BeforeCall hooks may replace them.
+	Arguments []interface{}
+
+	result   interface{}
+	returned bool
+}
+
+// Return makes the call return a result without calling the function, as
+// when a BeforeCall hook finds it cached. AfterCall hooks still run.
+func (c *FunctionCall) Return(result interface{}) {
+	c.result = result
+	c.returned = true
+}
+
+// FunctionHooks run around the calls of all registered functions, however
+// they are called. Any of them may be nil.
+type FunctionHooks struct {
+	// BeforeCall runs before the function, and fails the call without
+	// calling it by returning an error
+	BeforeCall func(ctx context.Context, call *FunctionCall) error
+	// AfterCall runs after successful calls, and returns the result to
+	// return instead
+	AfterCall func(ctx context.Context, call *FunctionCall, result interface{}) (interface{}, error)
+	// OnError runs after failed calls, including those failed by other
+	// hooks, and returns the error to fail with instead. Returning a nil
+	// error recovers with the result.
+	OnError func(ctx context.Context, call *FunctionCall, err error) (interface{}, error)
+}
+
+// Use adds hooks to the calls of functions. Hooks run in the order they
+// were added; the first BeforeCall error or Return skips the rest of the
+// BeforeCall hooks, and the first OnError recovery the rest of the OnError
+// hooks.
+func (h *FunctionHandler) Use(hooks FunctionHooks) {
+	h.mu.Lock()
+	h.hooks = append(h.hooks, hooks)
+	h.mu.Unlock()
+}
+
+// intercept runs the hooks around calling a function with converted
+// arguments
+func (h *FunctionHandler) intercept(ctx context.Context, name string, fn reflect.Value, args []reflect.Value, hooks []FunctionHooks) (interface{}, error) {
+	call := &FunctionCall{Function: name, Arguments: make([]interface{}, len(args))}
+	for i, arg := range args {
+		call.Arguments[i] = arg.Interface()
+	}
+
+	var err error
+	for _, hook := range hooks {
+		if hook.BeforeCall == nil {
+			continue
+		}
+		if err = hook.BeforeCall(ctx, call); err != nil || call.returned {
+			break
+		}
+	}
+
+	var result interface{}
+	switch {
+	case err != nil:
+	case call.returned:
+		result = call.result
+	default:
+		if args, err = argumentValues(fn.Type(), call.Arguments); err == nil {
+			result, err = h.invoke(ctx, name, fn, args)
+		}
+	}
+
+	if err == nil {
+		for _, hook := range hooks {
+			if hook.AfterCall == nil {
+				continue
+			}
+			if result, err = hook.AfterCall(ctx, call, result); err != nil {
+				result = nil
+				break
+			}
+		}
+	}
+	if err != nil {
+		for _, hook := range hooks {
+			if hook.OnError == nil {
+				continue
+			}
+			if result, err = hook.OnError(ctx, call, err); err == nil {
+				break
+			}
+		}
+	}
+	return result, err
+}
+
+// argumentValues converts the arguments hooks left to the parameter types
+// of a function
+func argumentValues(fnType reflect.Type, arguments []interface{}) ([]reflect.Value, error) {
+	params := parameters(fnType)
+	if len(arguments) != len(params) {
+		return nil, fmt.Errorf("expected %d arguments, got %d", len(params), len(arguments))
+	}
+	args := make([]reflect.Value, len(arguments))
+	for i, arg := range arguments {
+		value := reflect.ValueOf(arg)
+		if value.IsValid() && value.Type().AssignableTo(params[i]) {
+			args[i] = value
+			continue
+		}
+		converted, err := convertArgument(arg, params[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument %d: %v", i, err)
+		}
+		args[i] = converted
+	}
+	return args, nil
+}
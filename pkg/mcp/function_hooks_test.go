@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionHandler_Hooks(t *testing.T) {
+	h := NewFunctionHandler()
+	calls := 0
+	require.NoError(t, h.RegisterFunction("divide", func(a, b int) (int, error) {
+		calls++
+		if b == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return a / b, nil
+	}))
+
+	var log []string
+	cache := map[string]interface{}{}
+	h.Use(FunctionHooks{
+		BeforeCall: func(ctx context.Context, call *FunctionCall) error {
+			log = append(log, "before "+call.Function)
+			if call.Arguments[0].(int) < 0 {
+				return errors.New("negative dividend")
+			}
+			if result, ok := cache[fmt.Sprint(call.Arguments...)]; ok {
+				call.Return(result)
+			}
+			return nil
+		},
+		AfterCall: func(ctx context.Context, call *FunctionCall, result interface{}) (interface{}, error) {
+			log = append(log, "after "+call.Function)
+			cache[fmt.Sprint(call.Arguments...)] = result
+			return result, nil
+		},
+		OnError: func(ctx context.Context, call *FunctionCall, err error) (interface{}, error) {
+			log = append(log, "error "+err.Error())
+			return nil, err
+		},
+	})
+	h.Use(FunctionHooks{
+		// Dividing by zero gives zero
+		OnError: func(ctx context.Context, call *FunctionCall, err error) (interface{}, error) {
+			var fnErr *FunctionError
+			if errors.As(err, &fnErr) {
+				return 0, nil
+			}
+			return nil, err
+		},
+	})
+
+	result, err := h.Call("divide", []interface{}{6, 3})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result)
+
+	// Cached results skip the function
+	result, err = h.CallContext(context.Background(), "divide", []interface{}{6.0, 3.0})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result)
+	assert.Equal(t, 1, calls)
+
+	result, err = h.Call("divide", []interface{}{1, 0})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result)
+
+	_, err = h.Call("divide", []interface{}{-1, 1})
+	assert.EqualError(t, err, "negative dividend")
+	assert.Equal(t, 2, calls)
+
+	assert.Equal(t, []string{
+		"before divide", "after divide",
+		"before divide", "after divide",
+		"before divide", "error division by zero",
+		"before divide", "error negative dividend",
+	}, log)
+}
+
+func TestFunctionHandler_HooksReplaceArguments(t *testing.T) {
+	type greeting struct {
+		Name string `json:"name" default:"world"`
+	}
+	h := NewFunctionHandler()
+	require.NoError(t, h.RegisterFunction("greet", func(g greeting) string { return "hello " + g.Name }))
+	require.NoError(t, h.RegisterFunction("repeat", func(s string, n int) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = s
+		}
+		return out
+	}))
+	h.Use(FunctionHooks{
+		BeforeCall: func(ctx context.Context, call *FunctionCall) error {
+			switch call.Function {
+			case "greet":
+				g := call.Arguments[0].(greeting)
+				g.Name = "Ada"
+				call.Arguments[0] = g
+			case "repeat":
+				// JSON numbers are converted as arguments are
+				call.Arguments[1] = 2.0
+			}
+			return nil
+		},
+		AfterCall: func(ctx context.Context, call *FunctionCall, result interface{}) (interface{}, error) {
+			if call.Function == "greet" {
+				return result.(string) + "!", nil
+			}
+			return result, nil
+		},
+	})
+
+	result, err := h.callArguments(context.Background(), "greet", []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, "hello Ada!", result)
+
+	result, err = h.callArguments(context.Background(), "repeat", []byte(`{"arg0":"a","arg1":5}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "a"}, result)
+}
@@ -0,0 +1,338 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// States of function jobs
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// Defaults of function jobs
+const (
+	defaultJobWorkers   = 4
+	defaultJobQueue     = 100
+	defaultJobRetention = time.Hour
+)
+
+var (
+	// ErrJobNotFound is returned for jobs that were forgotten or never
+	// existed
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobQueueFull is returned when more jobs wait than the queue holds
+	ErrJobQueueFull = errors.New("job queue is full")
+)
+
+// FunctionJob is a function call running in the background, started with
+// POST /function/call?async=true
+type FunctionJob struct {
+	ID         string      `json:"id"`
+	Tenant     string      `json:"tenant,omitempty"`
+	Function   string      `json:"function"`
+	Status     string      `json:"status"` // queued, running, succeeded, failed or canceled
+	Result     interface{} `json:"result,omitempty"`
+	Error      *APIError   `json:"error,omitempty"`
+	Cache      string      `json:"cache,omitempty"` // HIT, MISS or BYPASS for idempotent functions
+	CreatedAt  time.Time   `json:"created_at"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+}
+
+type functionJob struct {
+	FunctionJob
+	arguments json.RawMessage
+	cache     cacheStatus
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// functionJobs runs function calls on a bounded pool of workers. Finished
+// jobs are kept for the retention period, then forgotten.
+type functionJobs struct {
+	functions *FunctionHandler
+	retention time.Duration
+	maxQueued int
+	wg        sync.WaitGroup
+
+	mu      sync.Mutex
+	ready   *sync.Cond // Signaled when jobs are queued or the workers stop
+	jobs    map[string]*functionJob
+	queue   []*functionJob // Jobs waiting for a worker, oldest first
+	stopped bool
+}
+
+// newFunctionJobs starts the workers of the jobs of a function handler
+func newFunctionJobs(functions *FunctionHandler, config FunctionConfig) *functionJobs {
+	workers, queued, retention := config.Workers, config.MaxQueued, config.JobRetention
+	if workers == 0 {
+		workers = defaultJobWorkers
+	}
+	if queued == 0 {
+		queued = defaultJobQueue
+	}
+	if retention == 0 {
+		retention = defaultJobRetention
+	}
+
+	j := &functionJobs{
+		functions: functions,
+		retention: retention,
+		maxQueued: queued,
+		jobs:      make(map[string]*functionJob),
+	}
+	j.ready = sync.NewCond(&j.mu)
+	j.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go j.work()
+	}
+	return j
+}
+
+// submit queues a call of a function for a tenant. Jobs keep the values of
+// ctx, such as the tenant, logger and whether to bypass the result cache,
+// but not its cancellation.
+func (j *functionJobs) submit(ctx context.Context, name string, arguments json.RawMessage) (FunctionJob, error) {
+	name = j.functions.resolve(name)
+	if !j.functions.has(name) {
+		return FunctionJob{}, fmt.Errorf("%w: %s", ErrFunctionNotFound, name)
+	}
+	if err := j.functions.authorize(ctx, name); err != nil {
+		return FunctionJob{}, err
+	}
+
+	job := &functionJob{
+		FunctionJob: FunctionJob{
+			ID:        newRequestID(),
+			Tenant:    TenantFromContext(ctx),
+			Function:  name,
+			Status:    JobQueued,
+			CreatedAt: time.Now(),
+		},
+		arguments: arguments,
+	}
+	job.ctx, job.cancel = context.WithCancel(withCacheStatus(context.WithoutCancel(ctx), &job.cache))
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.stopped {
+		job.cancel()
+		return FunctionJob{}, fmt.Errorf("%w: the server is stopping", ErrJobQueueFull)
+	}
+	if len(j.queue) >= j.maxQueued {
+		job.cancel()
+		return FunctionJob{}, ErrJobQueueFull
+	}
+	j.queue = append(j.queue, job)
+	j.jobs[job.ID] = job
+	j.ready.Signal()
+	return job.FunctionJob, nil
+}
+
+// work runs queued jobs until the jobs are stopped
+func (j *functionJobs) work() {
+	defer j.wg.Done()
+	for {
+		j.mu.Lock()
+		for len(j.queue) == 0 && !j.stopped {
+			j.ready.Wait()
+		}
+		if j.stopped {
+			j.mu.Unlock()
+			return
+		}
+		job := j.queue[0]
+		j.queue = j.queue[1:]
+		started := time.Now()
+		job.Status = JobRunning
+		job.StartedAt = &started
+		j.mu.Unlock()
+
+		result, err := j.functions.callArguments(job.ctx, job.Function, job.arguments)
+		j.finish(job, result, err)
+	}
+}
+
+// finish records the outcome of a job and schedules forgetting it
+func (j *functionJobs) finish(job *functionJob, result interface{}, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	job.Cache = job.cache.outcome()
+	switch {
+	case job.Status == JobCanceled:
+	case err != nil:
+		status, err := functionCallError(err)
+		apiErr := classifyError(status, err)
+		job.Status = JobFailed
+		job.Error = &apiErr
+	default:
+		job.Status = JobSucceeded
+		job.Result = result
+	}
+	job.cancel()
+	j.forget(job.ID)
+}
+
+// forget removes a finished job after the retention period. Callers hold
+// j.mu.
+func (j *functionJobs) forget(id string) {
+	time.AfterFunc(j.retention, func() {
+		j.mu.Lock()
+		delete(j.jobs, id)
+		j.mu.Unlock()
+	})
+}
+
+// lookup returns a job of a tenant. Callers hold j.mu.
+func (j *functionJobs) lookup(tenant, id string) (*functionJob, error) {
+	job, ok := j.jobs[id]
+	if !ok || job.Tenant != tenant {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return job, nil
+}
+
+// get returns a job of a tenant
+func (j *functionJobs) get(tenant, id string) (FunctionJob, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, err := j.lookup(tenant, id)
+	if err != nil {
+		return FunctionJob{}, err
+	}
+	return job.FunctionJob, nil
+}
+
+// list returns the jobs of a tenant, oldest first
+func (j *functionJobs) list(tenant string) []FunctionJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	jobs := make([]FunctionJob, 0, len(j.jobs))
+	for _, job := range j.jobs {
+		if job.Tenant == tenant {
+			jobs = append(jobs, job.FunctionJob)
+		}
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].CreatedAt.Before(jobs[b].CreatedAt) })
+	return jobs
+}
+
+// cancel cancels a queued or running job of a tenant. Running functions stop
+// when they heed the cancellation of their context; finished jobs are left
+// as they are.
+func (j *functionJobs) cancel(tenant, id string) (FunctionJob, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, err := j.lookup(tenant, id)
+	if err != nil {
+		return FunctionJob{}, err
+	}
+
+	switch job.Status {
+	case JobQueued:
+		// Canceled jobs leave room in the queue for others
+		for i, queued := range j.queue {
+			if queued == job {
+				j.queue = append(j.queue[:i], j.queue[i+1:]...)
+				break
+			}
+		}
+		finished := time.Now()
+		job.Status = JobCanceled
+		job.FinishedAt = &finished
+		job.cancel()
+		j.forget(job.ID)
+	case JobRunning:
+		job.Status = JobCanceled
+		job.cancel()
+	}
+	return job.FunctionJob, nil
+}
+
+// stop cancels the jobs and waits for the workers to exit, or until the
+// context is done
+func (j *functionJobs) stop(ctx context.Context) error {
+	j.mu.Lock()
+	if !j.stopped {
+		j.stopped = true
+		for _, job := range j.jobs {
+			if job.Status == JobQueued || job.Status == JobRunning {
+				job.Status = JobCanceled
+				job.cancel()
+			}
+		}
+		j.queue = nil
+		j.ready.Broadcast()
+	}
+	j.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for function jobs to stop: %w", ctx.Err())
+	}
+}
+
+func jobStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrJobNotFound), errors.Is(err, ErrFunctionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrScopeRequired):
+		return http.StatusForbidden
+	case errors.Is(err, ErrJobQueueFull):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func handleListFunctionJobs(j *functionJobs) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, j.list(TenantFromContext(r.Context())))
+	}
+}
+
+func handleGetFunctionJob(j *functionJobs) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := j.get(TenantFromContext(r.Context()), mux.Vars(r)["id"])
+		if err != nil {
+			writeError(w, jobStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	}
+}
+
+func handleCancelFunctionJob(j *functionJobs) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := j.cancel(TenantFromContext(r.Context()), mux.Vars(r)["id"])
+		if err != nil {
+			writeError(w, jobStatus(err), err)
+			return
+		}
+		LoggerFromContext(r.Context()).Info("function job canceled", "job", job.ID, "function", job.Function)
+		writeJSON(w, http.StatusOK, job)
+	}
+}
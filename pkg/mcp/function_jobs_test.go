@@ -0,0 +1,194 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionJobs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Functions.Workers = 1
+	cfg.Functions.MaxQueued = 1
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddFunctionHandler()
+	defer s.functionJobs.stop(context.Background())
+
+	release := make(chan struct{})
+	require.NoError(t, s.functions.RegisterFunction("wait", func(ctx context.Context) (string, error) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-release:
+			return "released", nil
+		}
+	}))
+	require.NoError(t, s.functions.RegisterFunction("fail", func() error { return assert.AnError }))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	submit := func(body string) FunctionJob {
+		t.Helper()
+		w := do("POST", "/v1/function/call?async=true", body)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		var job FunctionJob
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		return job
+	}
+	// await polls a job until it leaves the given status
+	await := func(id, status string) FunctionJob {
+		t.Helper()
+		var job FunctionJob
+		require.Eventually(t, func() bool {
+			w := do("GET", "/v1/function/jobs/"+id, "")
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+			return job.Status != status
+		}, 5*time.Second, 10*time.Millisecond)
+		return job
+	}
+
+	running := submit(`{"name":"wait"}`)
+	assert.Equal(t, JobQueued, running.Status)
+	assert.Equal(t, JobRunning, await(running.ID, JobQueued).Status)
+
+	// The only worker is busy and the queue holds one job
+	queued := submit(`{"name":"wait"}`)
+	w := do("POST", "/v1/function/call?async=true", `{"name":"wait"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	w = do("POST", "/v1/function/call?async=true", `{"name":"missing"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Canceling stops the running function, and the queued job runs next
+	w = do("DELETE", "/v1/function/jobs/"+running.ID, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	job := await(queued.ID, JobQueued)
+	assert.Equal(t, JobRunning, job.Status)
+	close(release)
+	job = await(queued.ID, JobRunning)
+	assert.Equal(t, JobSucceeded, job.Status)
+	assert.Equal(t, "released", job.Result)
+	require.NotNil(t, job.FinishedAt)
+
+	job, err := s.functionJobs.get("", running.ID)
+	require.NoError(t, err)
+	assert.Equal(t, JobCanceled, job.Status)
+
+	failed := await(submit(`{"name":"fail","arguments":[]}`).ID, JobQueued)
+	failed = await(failed.ID, JobRunning)
+	assert.Equal(t, JobFailed, failed.Status)
+	require.NotNil(t, failed.Error)
+	assert.Equal(t, CodeFunctionError, failed.Error.Code)
+
+	w = do("GET", "/v1/function/jobs", "")
+	var jobs []FunctionJob
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &jobs))
+	require.Len(t, jobs, 3)
+	assert.Equal(t, running.ID, jobs[0].ID)
+
+	// Jobs belong to the tenant that started them
+	_, err = s.functionJobs.get("other", running.ID)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	w = do("GET", "/v1/function/jobs/unknown", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	require.NoError(t, s.functionJobs.stop(context.Background()))
+	_, err = s.functionJobs.submit(context.Background(), "wait", nil)
+	assert.ErrorIs(t, err, ErrJobQueueFull)
+}
+
+func TestFunctionJobs_CancelQueued(t *testing.T) {
+	h := NewFunctionHandler()
+	release := make(chan struct{})
+	require.NoError(t, h.RegisterFunction("wait", func(ctx context.Context) error {
+		<-release
+		return nil
+	}))
+	jobs := newFunctionJobs(h, FunctionConfig{Workers: 1, MaxQueued: 1})
+	defer jobs.stop(context.Background())
+	ctx := context.Background()
+
+	running, err := jobs.submit(ctx, "wait", nil)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		job, err := jobs.get("", running.ID)
+		require.NoError(t, err)
+		return job.Status == JobRunning
+	}, 5*time.Second, 5*time.Millisecond)
+
+	// Canceling queued jobs frees their place in the queue
+	for i := 0; i < 3; i++ {
+		queued, err := jobs.submit(ctx, "wait", nil)
+		require.NoError(t, err)
+		_, err = jobs.submit(ctx, "wait", nil)
+		assert.ErrorIs(t, err, ErrJobQueueFull)
+		_, err = jobs.cancel("", queued.ID)
+		require.NoError(t, err)
+	}
+
+	last, err := jobs.submit(ctx, "wait", nil)
+	require.NoError(t, err)
+	close(release)
+	require.Eventually(t, func() bool {
+		job, err := jobs.get("", last.ID)
+		require.NoError(t, err)
+		return job.Status == JobSucceeded
+	}, 5*time.Second, 5*time.Millisecond)
+}
+
+func TestFunctionHandler_ReservedName(t *testing.T) {
+	h := NewFunctionHandler()
+	assert.ErrorContains(t, h.RegisterFunction("jobs", func() {}), "reserved")
+	assert.NoError(t, h.RegisterFunction("jobs_report", func() {}))
+}
+
+func TestFunctionJobs_Panic(t *testing.T) {
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+	defer s.functionJobs.stop(context.Background())
+	require.NoError(t, s.functions.RegisterFunction("boom", func() string { panic("boom") }))
+
+	do := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(`{"name":"boom"}`)))
+		return w
+	}
+
+	// Synchronous calls fail rather than take the server down
+	w := do("/v1/function/call")
+	require.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, CodeFunctionError, resp.Error.Code)
+	assert.Contains(t, resp.Error.Message, "panicked: boom")
+
+	w = do("/v1/function/call?async=true")
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var job FunctionJob
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	require.Eventually(t, func() bool {
+		job, _ = s.functionJobs.get("", job.ID)
+		return job.Status == JobFailed
+	}, 5*time.Second, 5*time.Millisecond)
+	require.NotNil(t, job.Error)
+	assert.Equal(t, CodeFunctionError, job.Error.Code)
+
+	// The workers keep running jobs
+	require.NoError(t, s.functions.RegisterFunction("ok", func() string { return "ok" }))
+	job, err := s.functionJobs.submit(context.Background(), "ok", nil)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		job, _ = s.functionJobs.get("", job.ID)
+		return job.Status == JobSucceeded
+	}, 5*time.Second, 5*time.Millisecond)
+}
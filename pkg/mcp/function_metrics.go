@@ -0,0 +1,297 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultFunctionHistory is the number of invocations kept per function
+const defaultFunctionHistory = 50
+
+// redacted replaces the values of redacted arguments in the history
+const redacted = "[redacted]"
+
+// defaultRedactedArguments are the names of arguments, and of fields at any
+// depth within them, whose values the history leaves out unless configured
+// otherwise. Positional arguments have no names to match, so the default
+// policy leaves all of them out.
+var defaultRedactedArguments = []string{"password", "secret", "token", "api_key", "authorization"}
+
+// durationBuckets are the upper bounds, in seconds, of the latency histogram
+// of functions
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// FunctionInvocation records a call of a function
+type FunctionInvocation struct {
+	Function    string                 `json:"function"`
+	Tenant      string                 `json:"tenant,omitempty"`
+	Arguments   map[string]interface{} `json:"arguments"` // By name, with redacted values replaced at any depth
+	StartedAt   time.Time              `json:"started_at"`
+	DurationMS  float64                `json:"duration_ms"`
+	ResultBytes int                    `json:"result_bytes"` // Size of the result as JSON
+	Error       string                 `json:"error,omitempty"`
+}
+
+// FunctionStats summarizes the calls of a function since the server started
+type FunctionStats struct {
+	Function   string     `json:"function"`
+	Calls      int64      `json:"calls"`
+	Errors     int64      `json:"errors"`
+	ErrorRate  float64    `json:"error_rate"` // Errors per call
+	MeanMS     float64    `json:"mean_ms"`
+	MaxMS      float64    `json:"max_ms"`
+	LastCalled *time.Time `json:"last_called,omitempty"`
+}
+
+// functionRecord holds the metrics and recent invocations of a function
+type functionRecord struct {
+	calls, errors int64
+	total, max    time.Duration
+	buckets       []int64 // Calls by latency bucket, the last one beyond all bounds
+	last          time.Time
+	history       []FunctionInvocation // Oldest first
+}
+
+// functionMetrics records the calls of functions
+type functionMetrics struct {
+	mu         sync.Mutex
+	size       int
+	redact     []string
+	positional bool // Whether positional arguments are redacted as a whole
+	functions  map[string]*functionRecord
+}
+
+func newFunctionMetrics(size int, redact []string) *functionMetrics {
+	if size == 0 {
+		size = defaultFunctionHistory
+	}
+	positional := redact == nil
+	if redact == nil {
+		redact = defaultRedactedArguments
+	}
+	return &functionMetrics{size: size, redact: redact, positional: positional, functions: make(map[string]*functionRecord)}
+}
+
+// record adds an invocation of a function
+func (m *functionMetrics) record(invocation FunctionInvocation, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.functions[invocation.Function]
+	if !ok {
+		r = &functionRecord{buckets: make([]int64, len(durationBuckets)+1)}
+		m.functions[invocation.Function] = r
+	}
+	r.calls++
+	if invocation.Error != "" {
+		r.errors++
+	}
+	r.total += duration
+	if duration > r.max {
+		r.max = duration
+	}
+	r.buckets[sort.SearchFloat64s(durationBuckets, duration.Seconds())]++
+	r.last = invocation.StartedAt
+
+	if m.size > 0 {
+		r.history = append(r.history, invocation)
+		if len(r.history) > m.size {
+			r.history = r.history[len(r.history)-m.size:]
+		}
+	}
+}
+
+// arguments returns the arguments of a call by name, as the named
+// arguments of the function's tool, with redacted values replaced.
+// Positional arguments are named arg0, arg1 and so on; unless redaction is
+// configured they are all replaced, since the names of Go parameters are
+// unknown.
+func (m *functionMetrics) arguments(fnType reflect.Type, args []reflect.Value) map[string]interface{} {
+	named := make(map[string]interface{}, len(args))
+	if takesObject(fnType) && len(args) == 1 {
+		data, err := json.Marshal(args[0].Interface())
+		if err == nil {
+			json.Unmarshal(data, &named)
+		}
+	} else {
+		for i, arg := range args {
+			key := fmt.Sprintf("arg%d", i)
+			if m.positional {
+				named[key] = redacted
+				continue
+			}
+			// Round trip through JSON so fields of structs can be redacted
+			var value interface{}
+			if data, err := json.Marshal(arg.Interface()); err == nil && json.Unmarshal(data, &value) == nil {
+				named[key] = value
+			}
+		}
+	}
+	m.scrub(named)
+	return named
+}
+
+// scrub replaces the values of redacted keys within v at any depth
+func (m *functionMetrics) scrub(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if m.redacts(key) {
+				v[key] = redacted
+			} else {
+				m.scrub(value)
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			m.scrub(value)
+		}
+	}
+}
+
+// redacts reports whether the values of key are left out of the history
+func (m *functionMetrics) redacts(key string) bool {
+	for _, name := range m.redact {
+		if name == "*" || strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// history returns the recent invocations of a function by a tenant, newest
+// first
+func (m *functionMetrics) history(name, tenant string) []FunctionInvocation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invocations := []FunctionInvocation{}
+	if r, ok := m.functions[name]; ok {
+		for i := len(r.history) - 1; i >= 0; i-- {
+			if r.history[i].Tenant == tenant {
+				invocations = append(invocations, r.history[i])
+			}
+		}
+	}
+	return invocations
+}
+
+// stats returns the stats of every function called, by name
+func (m *functionMetrics) stats() []FunctionStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]FunctionStats, 0, len(m.functions))
+	for name, r := range m.functions {
+		last := r.last
+		stats = append(stats, FunctionStats{
+			Function:   name,
+			Calls:      r.calls,
+			Errors:     r.errors,
+			ErrorRate:  float64(r.errors) / float64(r.calls),
+			MeanMS:     float64(r.total) / float64(r.calls) / float64(time.Millisecond),
+			MaxMS:      float64(r.max) / float64(time.Millisecond),
+			LastCalled: &last,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Function < stats[j].Function })
+	return stats
+}
+
+// writePrometheus writes the metrics in the Prometheus text format
+func (m *functionMetrics) writePrometheus(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.functions))
+	for name := range m.functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	label := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	float := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+
+	fmt.Fprintln(w, "# HELP mcp_function_calls_total Calls of registered functions.")
+	fmt.Fprintln(w, "# TYPE mcp_function_calls_total counter")
+	for _, name := range names {
+		fmt.Fprintf(w, "mcp_function_calls_total{function=\"%s\"} %d\n", label.Replace(name), m.functions[name].calls)
+	}
+	fmt.Fprintln(w, "# HELP mcp_function_errors_total Calls of registered functions that failed.")
+	fmt.Fprintln(w, "# TYPE mcp_function_errors_total counter")
+	for _, name := range names {
+		fmt.Fprintf(w, "mcp_function_errors_total{function=\"%s\"} %d\n", label.Replace(name), m.functions[name].errors)
+	}
+	fmt.Fprintln(w, "# HELP mcp_function_duration_seconds Latency of calls of registered functions.")
+	fmt.Fprintln(w, "# TYPE mcp_function_duration_seconds histogram")
+	for _, name := range names {
+		r, fn := m.functions[name], label.Replace(name)
+		var cumulative int64
+		for i, bound := range durationBuckets {
+			cumulative += r.buckets[i]
+			fmt.Fprintf(w, "mcp_function_duration_seconds_bucket{function=\"%s\",le=\"%s\"} %d\n", fn, float(bound), cumulative)
+		}
+		fmt.Fprintf(w, "mcp_function_duration_seconds_bucket{function=\"%s\",le=\"+Inf\"} %d\n", fn, r.calls)
+		fmt.Fprintf(w, "mcp_function_duration_seconds_sum{function=\"%s\"} %s\n", fn, float(r.total.Seconds()))
+		fmt.Fprintf(w, "mcp_function_duration_seconds_count{function=\"%s\"} %d\n", fn, r.calls)
+	}
+}
+
+// observe calls a function and records the invocation
+func (h *FunctionHandler) observe(ctx context.Context, name string, fn reflect.Value, args []reflect.Value, call func() (interface{}, error)) (interface{}, error) {
+	invocation := FunctionInvocation{
+		Function:  name,
+		Tenant:    TenantFromContext(ctx),
+		Arguments: h.metrics.arguments(fn.Type(), args),
+		StartedAt: time.Now(),
+	}
+	result, err := call()
+	duration := time.Since(invocation.StartedAt)
+
+	invocation.DurationMS = float64(duration) / float64(time.Millisecond)
+	if err != nil {
+		invocation.Error = err.Error()
+	} else if data, marshalErr := json.Marshal(result); marshalErr == nil {
+		invocation.ResultBytes = len(data)
+	}
+	h.metrics.record(invocation, duration)
+	return result, err
+}
+
+func handleFunctionHistory(h *FunctionHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+		if !h.has(name) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrFunctionNotFound, name))
+			return
+		}
+		writeJSON(w, http.StatusOK, h.metrics.history(name, TenantFromContext(r.Context())))
+	}
+}
+
+func handleFunctionStats(h *FunctionHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, h.metrics.stats())
+	}
+}
+
+// handleMetrics serves the metrics of the server in the Prometheus text
+// format
+func handleMetrics(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if s.functions != nil {
+			s.functions.metrics.writePrometheus(w)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionMetrics(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Functions.History = 2
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddFunctionHandler()
+
+	type login struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+	require.NoError(t, s.functions.RegisterFunction("login", func(l login) (string, error) {
+		if l.Password != "hunter2" {
+			return "", errors.New("wrong password")
+		}
+		return "welcome " + l.User, nil
+	}))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	do("POST", "/v1/function/call", `{"name":"login","arguments":{"user":"ada","password":"guess"}}`)
+	do("POST", "/v1/function/call", `{"name":"login","arguments":{"user":"bob","password":"guess"}}`)
+	do("POST", "/v1/function/call", `{"name":"login","arguments":{"user":"ada","password":"hunter2"}}`)
+	do("POST", "/v1/function/call", `{"name":"echo","arguments":["hi"]}`)
+
+	// The history keeps the last two invocations, newest first
+	w := do("GET", "/v1/function/login/history", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var history []FunctionInvocation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	require.Len(t, history, 2)
+	assert.Equal(t, map[string]interface{}{"user": "ada", "password": redacted}, history[0].Arguments)
+	assert.Equal(t, len(`"welcome ada"`), history[0].ResultBytes)
+	assert.Empty(t, history[0].Error)
+	assert.Equal(t, map[string]interface{}{"user": "bob", "password": redacted}, history[1].Arguments)
+	assert.Equal(t, "wrong password", history[1].Error)
+
+	w = do("GET", "/v1/function/echo/history", "")
+	history = nil
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	require.Len(t, history, 1)
+	assert.Equal(t, map[string]interface{}{"arg0": redacted}, history[0].Arguments)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/v1/function/missing/history", "").Code)
+
+	w = do("GET", "/v1/function/stats", "")
+	var stats []FunctionStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	require.Len(t, stats, 2)
+	assert.Equal(t, "echo", stats[0].Function)
+	assert.Equal(t, "login", stats[1].Function)
+	assert.EqualValues(t, 3, stats[1].Calls)
+	assert.EqualValues(t, 2, stats[1].Errors)
+	assert.InDelta(t, 2.0/3, stats[1].ErrorRate, 1e-9)
+
+	w = do("GET", "/metrics", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	body := w.Body.String()
+	assert.Contains(t, body, `mcp_function_calls_total{function="login"} 3`)
+	assert.Contains(t, body, `mcp_function_errors_total{function="login"} 2`)
+	assert.Contains(t, body, `mcp_function_duration_seconds_bucket{function="login",le="+Inf"} 3`)
+	assert.Contains(t, body, `mcp_function_duration_seconds_count{function="echo"} 1`)
+}
+
+func TestFunctionMetrics_Redaction(t *testing.T) {
+	type creds struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+	type request struct {
+		Host  string  `json:"host"`
+		Creds creds   `json:"creds"`
+		Hops  []creds `json:"hops"`
+	}
+	login := func(user, password string) string { return user }
+	connect := func(r request) string { return r.Host }
+	inspect := func(label string, r request) string { return label }
+	req := request{Host: "db", Creds: creds{User: "ada", Password: "hunter2"}, Hops: []creds{{User: "bob", Password: "swordfish"}}}
+	scrubbed := map[string]interface{}{
+		"host":  "db",
+		"creds": map[string]interface{}{"user": "ada", "password": redacted},
+		"hops":  []interface{}{map[string]interface{}{"user": "bob", "password": redacted}},
+	}
+
+	tests := []struct {
+		name   string
+		redact []string
+		fn     interface{}
+		args   []interface{}
+		want   map[string]interface{}
+	}{
+		{"positional by default", nil, login, []interface{}{"ada", "hunter2"}, map[string]interface{}{"arg0": redacted, "arg1": redacted}},
+		{"by position", []string{"ARG1"}, login, []interface{}{"ada", "hunter2"}, map[string]interface{}{"arg0": "ada", "arg1": redacted}},
+		{"all", []string{"*"}, login, []interface{}{"ada", "hunter2"}, map[string]interface{}{"arg0": redacted, "arg1": redacted}},
+		{"nested by default", nil, connect, []interface{}{req}, scrubbed},
+		{"nested in positional", []string{"password"}, inspect, []interface{}{"x", req}, map[string]interface{}{"arg0": "x", "arg1": scrubbed}},
+		{"whole field", []string{"creds"}, connect, []interface{}{req}, map[string]interface{}{
+			"host": "db", "creds": redacted,
+			"hops": []interface{}{map[string]interface{}{"user": "bob", "password": "swordfish"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewFunctionHandler()
+			h.metrics = newFunctionMetrics(0, tt.redact)
+			require.NoError(t, h.RegisterFunction("fn", tt.fn))
+			_, err := h.Call("fn", tt.args)
+			require.NoError(t, err)
+			history := h.metrics.history("fn", "")
+			require.Len(t, history, 1)
+			assert.Equal(t, tt.want, history[0].Arguments)
+		})
+	}
+}
+
+func TestFunctionMetrics_HistoryByTenant(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Usage = UsageConfig{
+		Enabled: true,
+		Tenants: map[string]TenantConfig{
+			"team-a": {Keys: []string{"key-a"}},
+			"team-b": {Keys: []string{"key-b"}},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddFunctionHandler()
+	require.NoError(t, s.functions.RegisterFunction("greet", func(g struct {
+		Name string `json:"name"`
+	}) string {
+		return "hi " + g.Name
+	}))
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+	require.Equal(t, http.StatusOK, do("POST", "/v1/function/call", "key-a", `{"name":"greet","arguments":{"name":"ada"}}`).Code)
+	require.Equal(t, http.StatusOK, do("POST", "/v1/function/call", "key-b", `{"name":"greet","arguments":{"name":"bob"}}`).Code)
+
+	// Each tenant sees only the arguments it passed
+	for key, name := range map[string]string{"key-a": "ada", "key-b": "bob"} {
+		w := do("GET", "/v1/function/greet/history", key, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var history []FunctionInvocation
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+		require.Len(t, history, 1, key)
+		assert.Equal(t, map[string]interface{}{"name": name}, history[0].Arguments)
+	}
+	assert.Empty(t, s.functions.metrics.history("greet", ""))
+}
@@ -0,0 +1,183 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PluginSymbol is the symbol of the function plugins export to register
+// their functions, of type func(*mcp.FunctionHandler) or
+// func(*mcp.FunctionHandler) error. Plugins must be built with
+// -buildmode=plugin against the same version of this module as the server.
+const PluginSymbol = "Register"
+
+// ErrNoPluginDir is returned when loading plugins without a plugin directory
+var ErrNoPluginDir = errors.New("no plugin directory is configured")
+
+// PluginStatus reports a plugin of the plugin directory
+type PluginStatus struct {
+	Path      string     `json:"path"`
+	Loaded    bool       `json:"loaded"`
+	Functions []string   `json:"functions,omitempty"` // Registered by the plugin
+	Error     string     `json:"error,omitempty"`     // Why the plugin failed to load
+	LoadedAt  *time.Time `json:"loaded_at,omitempty"`
+}
+
+// pluginLoader loads the .so files of a directory into a function handler.
+// Go plugins cannot be unloaded, so loaded plugins stay loaded; those that
+// failed are retried on the next load.
+type pluginLoader struct {
+	dir       string
+	functions *FunctionHandler
+	open      func(path string) (func(*FunctionHandler) error, error)
+
+	mu      sync.Mutex
+	plugins map[string]*PluginStatus
+}
+
+func newPluginLoader(dir string, functions *FunctionHandler) *pluginLoader {
+	return &pluginLoader{
+		dir:       dir,
+		functions: functions,
+		open:      openPlugin,
+		plugins:   make(map[string]*PluginStatus),
+	}
+}
+
+// openPlugin opens a plugin and looks up its register function
+func openPlugin(path string) (func(*FunctionHandler) error, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+	switch register := symbol.(type) {
+	case func(*FunctionHandler):
+		return func(h *FunctionHandler) error {
+			register(h)
+			return nil
+		}, nil
+	case func(*FunctionHandler) error:
+		return register, nil
+	default:
+		return nil, fmt.Errorf("%s is a %T, not a func(*mcp.FunctionHandler)", PluginSymbol, symbol)
+	}
+}
+
+// load loads the plugins of the directory not loaded yet, and returns the
+// status of every plugin
+func (l *pluginLoader) load() ([]PluginStatus, error) {
+	if l.dir == "" {
+		return nil, ErrNoPluginDir
+	}
+	if _, err := os.Stat(l.dir); err != nil {
+		return nil, fmt.Errorf("reading plugin directory: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(l.dir, "*.so"))
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, path := range paths {
+		if status, ok := l.plugins[path]; ok && status.Loaded {
+			continue
+		}
+		l.plugins[path] = l.register(path)
+	}
+	return l.statuses(), nil
+}
+
+// register opens a plugin and registers its functions, recovering from
+// plugins that panic. Callers hold l.mu.
+func (l *pluginLoader) register(path string) (status *PluginStatus) {
+	status = &PluginStatus{Path: path}
+	before := l.functions.names()
+	defer func() {
+		if r := recover(); r != nil {
+			status.Error = fmt.Sprintf("%s panicked: %v", PluginSymbol, r)
+		}
+		for name := range l.functions.names() {
+			if !before[name] {
+				status.Functions = append(status.Functions, name)
+			}
+		}
+		sort.Strings(status.Functions)
+	}()
+
+	register, err := l.open(path)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if err := register(l.functions); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	loaded := time.Now()
+	status.Loaded = true
+	status.LoadedAt = &loaded
+	return status
+}
+
+// list returns the status of every plugin
+func (l *pluginLoader) list() []PluginStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.statuses()
+}
+
+// statuses returns the status of every plugin by path. Callers hold l.mu.
+func (l *pluginLoader) statuses() []PluginStatus {
+	statuses := make([]PluginStatus, 0, len(l.plugins))
+	for _, status := range l.plugins {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Path < statuses[j].Path })
+	return statuses
+}
+
+// loadPlugins loads the plugins at startup, logging those that fail
+func (s *Server) loadPlugins(l *pluginLoader) {
+	statuses, err := l.load()
+	if err != nil {
+		s.logger.Warn("cannot load plugins", "dir", l.dir, "error", err)
+		return
+	}
+	for _, status := range statuses {
+		if status.Loaded {
+			s.logger.Info("plugin loaded", "path", status.Path, "functions", status.Functions)
+		} else {
+			s.logger.Warn("cannot load plugin", "path", status.Path, "error", status.Error)
+		}
+	}
+}
+
+func handleListPlugins(l *pluginLoader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, l.list())
+	}
+}
+
+func handleLoadPlugins(l *pluginLoader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses, err := l.load()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		LoggerFromContext(r.Context()).Info("plugins loaded", "dir", l.dir, "plugins", len(statuses))
+		writeJSON(w, http.StatusOK, statuses)
+	}
+}
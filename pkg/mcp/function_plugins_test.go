@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginLoader(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
+		return path
+	}
+	weather := write("weather.so")
+	broken := write("broken.so")
+	write("README.md")
+
+	h := NewFunctionHandler()
+	l := newPluginLoader(dir, h)
+	opened := map[string]int{}
+	fixed := false
+	l.open = func(path string) (func(*FunctionHandler) error, error) {
+		opened[filepath.Base(path)]++
+		switch filepath.Base(path) {
+		case "weather.so":
+			return func(h *FunctionHandler) error {
+				return h.RegisterFunction("weather", func(city string) string { return "sunny in " + city })
+			}, nil
+		case "broken.so":
+			if fixed {
+				return func(h *FunctionHandler) error { return nil }, nil
+			}
+			return nil, errors.New("plugin was built with a different version of package mcp")
+		default:
+			return func(h *FunctionHandler) error { panic("boom") }, nil
+		}
+	}
+
+	statuses, err := l.load()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, broken, statuses[0].Path)
+	assert.False(t, statuses[0].Loaded)
+	assert.Contains(t, statuses[0].Error, "different version")
+	assert.Equal(t, weather, statuses[1].Path)
+	assert.True(t, statuses[1].Loaded)
+	assert.Equal(t, []string{"weather"}, statuses[1].Functions)
+
+	result, err := h.Call("weather", []interface{}{"Oslo"})
+	require.NoError(t, err)
+	assert.Equal(t, "sunny in Oslo", result)
+
+	// Loading again retries failed plugins and loads new ones only
+	fixed = true
+	panics := write("panics.so")
+	statuses, err = l.load()
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.True(t, statuses[0].Loaded)
+	assert.Equal(t, panics, statuses[1].Path)
+	assert.Equal(t, "Register panicked: boom", statuses[1].Error)
+	assert.Equal(t, map[string]int{"weather.so": 1, "broken.so": 2, "panics.so": 1}, opened)
+
+	_, err = newPluginLoader("", h).load()
+	assert.ErrorIs(t, err, ErrNoPluginDir)
+	_, err = newPluginLoader(filepath.Join(dir, "missing"), h).load()
+	assert.Error(t, err)
+}
+
+func TestPluginRoutes(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.Functions.PluginDir = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddFunctionHandler()
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	// Files that are not Go plugins fail to load
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.so"), []byte("not a plugin"), 0o644))
+	w := do("POST", "/v1/function/plugins/load")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var statuses []PluginStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 1)
+	assert.False(t, statuses[0].Loaded)
+	assert.NotEmpty(t, statuses[0].Error)
+
+	w = do("GET", "/v1/function/plugins")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &statuses))
+	assert.Len(t, statuses, 1)
+
+	s = NewServer(nil)
+	s.AddFunctionHandler()
+	w = do("POST", "/v1/function/plugins/load")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
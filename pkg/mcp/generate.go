@@ -0,0 +1,18 @@
+package mcp
+
+import "net/http"
+
+// AddGenerateHandlers adds code and documentation generation endpoints to the MCP server
+func (s *Server) AddGenerateHandlers() {
+	docs := NewDocGenerator(s.GetWorkspaceRoot(), s.store)
+	mocks := NewMockGenerator(s.GetWorkspaceRoot())
+
+	s.handle(Route{
+		Method: "POST", Path: "/generate/docs", Summary: "Generate package documentation",
+		Request: GenerateDocsRequest{}, Response: GenerateDocsResponse{}, Status: http.StatusCreated,
+	}, handleGenerateDocs(docs))
+	s.handle(Route{
+		Method: "POST", Path: "/generate/mock", Summary: "Generate a stub and mock for an interface",
+		Request: GenerateMockRequest{}, Response: []GeneratedFile{}, Status: http.StatusCreated,
+	}, handleGenerateMock(mocks))
+}
@@ -0,0 +1,518 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go/parser"
+	"go/token"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/mcppb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// requestIDMetadata carries the request ID in gRPC metadata, which uses
+// lowercase keys
+const requestIDMetadata = "x-request-id"
+
+// GRPCServer creates a gRPC server exposing the context store, and function
+// calling and analysis when their handlers have been added. The services
+// share their backends with the HTTP API, and messages are bounded by its
+// body limit.
+func (s *Server) GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	defaults := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryLogger, s.unaryGate),
+		grpc.ChainStreamInterceptor(s.streamLogger, s.streamGate),
+	}
+	if limit := s.config.HTTP.MaxBodyBytes; limit > 0 {
+		defaults = append(defaults, grpc.MaxRecvMsgSize(int(limit)))
+	}
+	opts = append(defaults, opts...)
+	srv := grpc.NewServer(opts...)
+
+	mcppb.RegisterContextServiceServer(srv, &contextService{server: s})
+	if s.functions != nil {
+		mcppb.RegisterFunctionServiceServer(srv, &functionService{functions: s.functions, usage: s.usage})
+	}
+	if s.analysis {
+		mcppb.RegisterAnalysisServiceServer(srv, &analysisService{})
+	}
+	return srv
+}
+
+// StartGRPC serves the gRPC API on the specified address, which may be any
+// address accepted by Listen. Like Start, it blocks until the server fails or
+// Shutdown is called.
+func (s *Server) StartGRPC(addr string) error {
+	lis, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.grpcServer = s.GRPCServer()
+	srv := s.grpcServer
+	s.mu.Unlock()
+
+	if err := srv.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+		return err
+	}
+	return nil
+}
+
+// stopGRPC stops the gRPC server gracefully, cancelling the remaining calls
+// once ctx is done
+func stopGRPC(ctx context.Context, srv *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		srv.Stop()
+		return ctx.Err()
+	}
+}
+
+// rpcContext assigns a call its request ID, reusing a well-formed one from the
+// client's metadata, and stores it with a call scoped logger like
+// RequestLogger does for HTTP requests
+func (s *Server) rpcContext(ctx context.Context) (context.Context, *slog.Logger) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadata); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, id))
+
+	logger := s.logger.With("request_id", id)
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	ctx = context.WithValue(ctx, loggerKey{}, logger)
+	if s.usage != nil {
+		ctx = s.usage.withTenant(ctx, s.usage.grpcTenant(ctx))
+	}
+	return ctx, logger
+}
+
+func logRPC(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+
+	logger.LogAttrs(ctx, level, "rpc",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+	)
+}
+
+func (s *Server) unaryLogger(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx, logger := s.rpcContext(ctx)
+	resp, err := handler(ctx, req)
+	logRPC(ctx, logger, info.FullMethod, start, err)
+	return resp, err
+}
+
+func (s *Server) streamLogger(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, logger := s.rpcContext(stream.Context())
+	err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	logRPC(ctx, logger, info.FullMethod, start, err)
+	return err
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// admit rejects calls to services whose feature is switched off, and calls
+// over the request quota of the caller's tenant
+func (s *Server) admit(ctx context.Context, fullMethod string) error {
+	if err := s.checkFeature(grpcFeature(fullMethod)); err != nil {
+		return err
+	}
+	return s.usage.Consume(TenantFromContext(ctx), MetricRequests, 1)
+}
+
+func (s *Server) unaryGate(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := s.admit(ctx, info.FullMethod); err != nil {
+		return nil, grpcError(err)
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamGate(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.admit(stream.Context(), info.FullMethod); err != nil {
+		return grpcError(err)
+	}
+	return handler(srv, stream)
+}
+
+// grpcError maps store and handler errors to gRPC status errors
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrContextNotFound), errors.Is(err, ErrFunctionNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrContextExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ErrFeatureDisabled), errors.Is(err, ErrCapabilityDisabled), errors.Is(err, ErrScopeRequired):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, ErrQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, ErrInvalidID), errors.Is(err, ErrInvalidMetadata):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// contextService implements mcppb.ContextServiceServer on the context store
+// of a server
+type contextService struct {
+	mcppb.UnimplementedContextServiceServer
+	server *Server
+}
+
+func contextToProto(c *Context) (*mcppb.Context, error) {
+	metadata, err := structpb.NewStruct(c.Metadata)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "context %s has metadata that cannot be encoded: %v", c.ID, err)
+	}
+	return &mcppb.Context{
+		Id:        c.ID,
+		Metadata:  metadata,
+		CreatedAt: timestamppb.New(c.CreatedAt),
+		UpdatedAt: timestamppb.New(c.UpdatedAt),
+	}, nil
+}
+
+// metadataFromProto converts metadata, keeping it nil when absent so the
+// store rejects it as the HTTP API does
+func metadataFromProto(metadata *structpb.Struct) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	return metadata.AsMap()
+}
+
+func (cs *contextService) CreateContext(ctx context.Context, req *mcppb.CreateContextRequest) (*mcppb.Context, error) {
+	c := &Context{
+		ID:        req.Id,
+		Metadata:  metadataFromProto(req.Metadata),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := cs.server.createContext(ctx, c); err != nil {
+		return nil, grpcError(err)
+	}
+	return contextToProto(c)
+}
+
+func (cs *contextService) GetContext(ctx context.Context, req *mcppb.GetContextRequest) (*mcppb.Context, error) {
+	c, err := cs.server.store.Get(req.Id)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return contextToProto(c)
+}
+
+func (cs *contextService) UpdateContext(ctx context.Context, req *mcppb.UpdateContextRequest) (*mcppb.Context, error) {
+	c, err := cs.server.store.Get(req.Id)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	c.Metadata = metadataFromProto(req.Metadata)
+	c.UpdatedAt = time.Now()
+	if err := cs.server.updateContext(ctx, c); err != nil {
+		return nil, grpcError(err)
+	}
+	return contextToProto(c)
+}
+
+func (cs *contextService) DeleteContext(ctx context.Context, req *mcppb.DeleteContextRequest) (*emptypb.Empty, error) {
+	if err := cs.server.deleteContext(req.Id); err != nil {
+		return nil, grpcError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (cs *contextService) ListContexts(req *mcppb.ListContextsRequest, stream mcppb.ContextService_ListContextsServer) error {
+	for _, c := range cs.server.store.List() {
+		pb, err := contextToProto(c)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(pb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// functionService implements mcppb.FunctionServiceServer on a FunctionHandler
+type functionService struct {
+	mcppb.UnimplementedFunctionServiceServer
+	functions *FunctionHandler
+	usage     *UsageTracker
+}
+
+func (fs *functionService) ListFunctions(ctx context.Context, req *mcppb.ListFunctionsRequest) (*mcppb.ListFunctionsResponse, error) {
+	resp := &mcppb.ListFunctionsResponse{}
+	for _, fn := range fs.functions.GetFunctionMetadata() {
+		pb := &mcppb.FunctionMetadata{Name: fn.Name, ReturnType: fn.ReturnType}
+		for _, arg := range fn.Arguments {
+			pb.Arguments = append(pb.Arguments, &mcppb.ArgumentInfo{Name: arg.Name, Type: arg.Type, Required: arg.Required})
+		}
+		resp.Functions = append(resp.Functions, pb)
+	}
+	return resp, nil
+}
+
+func (fs *functionService) CallFunction(ctx context.Context, req *mcppb.CallFunctionRequest) (*mcppb.CallFunctionResponse, error) {
+	// Arguments take the same shape as decoded JSON, as over HTTP
+	args := make([]interface{}, len(req.Arguments))
+	for i, arg := range req.Arguments {
+		args[i] = arg.AsInterface()
+	}
+
+	if err := fs.usage.Consume(TenantFromContext(ctx), MetricFunctionCalls, 1); err != nil {
+		return nil, grpcError(err)
+	}
+	result, err := fs.functions.CallContext(ctx, fs.functions.resolve(req.Name), args)
+	if err != nil {
+		var fnErr *FunctionError
+		if errors.Is(err, ErrFunctionNotFound) || errors.Is(err, ErrScopeRequired) || errors.As(err, &fnErr) {
+			return nil, grpcError(err)
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	generic, err := jsonValue(result)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "result cannot be encoded: %v", err)
+	}
+	value, err := structpb.NewValue(generic)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "result cannot be encoded: %v", err)
+	}
+	return &mcppb.CallFunctionResponse{Result: value}, nil
+}
+
+// jsonValue converts a function result to the generic form encoding/json
+// gives it, so results have the same shape as over HTTP
+func jsonValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// analysisService implements mcppb.AnalysisServiceServer, analyzing every
+// file with an ASTAnalyzer of its own
+type analysisService struct {
+	mcppb.UnimplementedAnalysisServiceServer
+}
+
+func (as *analysisService) analyze(req *mcppb.AnalysisRequest) (*AnalysisResult, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, req.Uri, req.Content, parser.ParseComments)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	result, err := NewASTAnalyzer(fset).AnalyzeFile(file)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return result, nil
+}
+
+func (as *analysisService) AnalyzeFile(ctx context.Context, req *mcppb.AnalysisRequest) (*mcppb.AnalysisResult, error) {
+	result, err := as.analyze(req)
+	if err != nil {
+		return nil, err
+	}
+	return analysisResultToProto(result), nil
+}
+
+func (as *analysisService) AnalyzeDependencies(ctx context.Context, req *mcppb.AnalysisRequest) (*mcppb.Dependencies, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, req.Uri, req.Content, parser.ParseComments)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	deps := &mcppb.Dependencies{Imports: make(map[string]*mcppb.Symbols)}
+	for path, names := range NewASTAnalyzer(fset).AnalyzeDependencies(file) {
+		deps.Imports[path] = &mcppb.Symbols{Names: names}
+	}
+	return deps, nil
+}
+
+func (as *analysisService) AnalyzeMetrics(ctx context.Context, req *mcppb.AnalysisRequest) (*mcppb.CodeMetrics, error) {
+	result, err := as.analyze(req)
+	if err != nil {
+		return nil, err
+	}
+	return metricsToProto(result.Metrics), nil
+}
+
+func (as *analysisService) AnalyzeFiles(stream mcppb.AnalysisService_AnalyzeFilesServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		resp := &mcppb.FileAnalysis{Uri: req.Uri}
+		if result, err := as.analyze(req); err != nil {
+			resp.Error = status.Convert(err).Message()
+		} else {
+			resp.Result = analysisResultToProto(result)
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func analysisResultToProto(r *AnalysisResult) *mcppb.AnalysisResult {
+	pb := &mcppb.AnalysisResult{Metrics: metricsToProto(r.Metrics)}
+	for _, imp := range r.Imports {
+		pb.Imports = append(pb.Imports, &mcppb.ImportInfo{Path: imp.Path, Name: imp.Name, Used: imp.Used})
+	}
+	for _, fn := range r.Functions {
+		pb.Functions = append(pb.Functions, &mcppb.FunctionInfo{
+			Name:       fn.Name,
+			Signature:  fn.Signature,
+			Doc:        fn.Doc,
+			Location:   locationToProto(fn.Location),
+			Complexity: int64(fn.Complexity),
+			IsMethod:   fn.IsMethod,
+			Receiver:   fn.Receiver,
+			Parameters: parametersToProto(fn.Parameters),
+			Returns:    parametersToProto(fn.Returns),
+		})
+	}
+	for _, t := range r.Types {
+		typ := &mcppb.TypeInfo{
+			Name:       t.Name,
+			Kind:       t.Kind,
+			Doc:        t.Doc,
+			Location:   locationToProto(t.Location),
+			Implements: t.Implements,
+		}
+		for _, f := range t.Fields {
+			typ.Fields = append(typ.Fields, &mcppb.FieldInfo{Name: f.Name, Type: f.Type, Doc: f.Doc, Tags: f.Tags, Embed: f.IsEmbed})
+		}
+		for _, m := range t.Methods {
+			typ.Methods = append(typ.Methods, &mcppb.MethodInfo{
+				Name:       m.Name,
+				Signature:  m.Signature,
+				Doc:        m.Doc,
+				Parameters: parametersToProto(m.Parameters),
+				Returns:    parametersToProto(m.Returns),
+			})
+		}
+		pb.Types = append(pb.Types, typ)
+	}
+	for _, v := range r.Variables {
+		pb.Variables = append(pb.Variables, &mcppb.VariableInfo{
+			Name:     v.Name,
+			Type:     v.Type,
+			Location: locationToProto(v.Location),
+			Constant: v.Constant,
+			Value:    v.Value,
+			Doc:      v.Doc,
+			Scope:    v.Scope,
+		})
+	}
+	for _, ref := range r.References {
+		pbRef := &mcppb.ReferenceInfo{Name: ref.Name, Kind: ref.Kind, Location: locationToProto(ref.Location), Scope: ref.Scope}
+		for _, loc := range ref.UsedAt {
+			pbRef.UsedAt = append(pbRef.UsedAt, locationToProto(loc))
+		}
+		pb.References = append(pb.References, pbRef)
+	}
+	for _, d := range r.Diagnostics {
+		pb.Diagnostics = append(pb.Diagnostics, &mcppb.Diagnostic{
+			Severity: d.Severity,
+			Message:  d.Message,
+			Location: locationToProto(d.Location),
+			Code:     d.Code,
+			Source:   d.Source,
+		})
+	}
+	return pb
+}
+
+func metricsToProto(m CodeMetrics) *mcppb.CodeMetrics {
+	return &mcppb.CodeMetrics{
+		LinesOfCode:     int64(m.LinesOfCode),
+		CommentLines:    int64(m.CommentLines),
+		FunctionCount:   int64(m.FunctionCount),
+		ComplexityScore: int64(m.ComplexityScore),
+		InterfaceCount:  int64(m.InterfaceCount),
+		StructCount:     int64(m.StructCount),
+		TestCount:       int64(m.TestCount),
+	}
+}
+
+func parametersToProto(params []ParameterInfo) []*mcppb.ParameterInfo {
+	var pb []*mcppb.ParameterInfo
+	for _, p := range params {
+		pb = append(pb, &mcppb.ParameterInfo{Name: p.Name, Type: p.Type, Variadic: p.IsVariadic})
+	}
+	return pb
+}
+
+func locationToProto(loc Location) *mcppb.Location {
+	return &mcppb.Location{
+		Uri: loc.URI,
+		Range: &mcppb.Range{
+			Start: &mcppb.Position{Line: int64(loc.Range.Start.Line), Character: int64(loc.Range.Start.Character)},
+			End:   &mcppb.Position{Line: int64(loc.Range.End.Line), Character: int64(loc.Range.End.Character)},
+		},
+	}
+}
@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ivikasavnish/go-mcp/pkg/mcppb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func dialTestGRPC(t *testing.T, s *Server) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := s.GRPCServer()
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGRPC_ContextService(t *testing.T) {
+	s := NewServer(nil)
+	contexts := mcppb.NewContextServiceClient(dialTestGRPC(t, s))
+	ctx := context.Background()
+
+	metadataPB, err := structpb.NewStruct(map[string]interface{}{"model": "small"})
+	require.NoError(t, err)
+
+	var header metadata.MD
+	created, err := contexts.CreateContext(metadata.AppendToOutgoingContext(ctx, "x-request-id", "grpc-1"),
+		&mcppb.CreateContextRequest{Id: "ctx-1", Metadata: metadataPB}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, "ctx-1", created.Id)
+	assert.Equal(t, []string{"grpc-1"}, header.Get("x-request-id"))
+
+	_, err = contexts.CreateContext(ctx, &mcppb.CreateContextRequest{Id: "ctx-1", Metadata: metadataPB})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = contexts.CreateContext(ctx, &mcppb.CreateContextRequest{Id: "ctx-2"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// The HTTP API sees contexts created over gRPC
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/context/get?id=ctx-1", nil))
+	assert.Equal(t, 200, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"model":"small"`)
+
+	stream, err := contexts.ListContexts(ctx, &mcppb.ListContextsRequest{})
+	require.NoError(t, err)
+	listed, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "small", listed.Metadata.AsMap()["model"])
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = contexts.DeleteContext(ctx, &mcppb.DeleteContextRequest{Id: "ctx-1"})
+	require.NoError(t, err)
+	_, err = contexts.GetContext(ctx, &mcppb.GetContextRequest{Id: "ctx-1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPC_FunctionAndAnalysisServices(t *testing.T) {
+	s := NewServer(nil)
+	conn := dialTestGRPC(t, s)
+	ctx := context.Background()
+
+	// Services are only served once their handlers are added
+	_, err := mcppb.NewFunctionServiceClient(conn).ListFunctions(ctx, &mcppb.ListFunctionsRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	s.AddFunctionHandler()
+	s.AddAnalysisHandler()
+	conn = dialTestGRPC(t, s)
+
+	functions := mcppb.NewFunctionServiceClient(conn)
+	resp, err := functions.CallFunction(ctx, &mcppb.CallFunctionRequest{
+		Name: "echo", Arguments: []*structpb.Value{structpb.NewStringValue("hello")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", resp.Result.GetStringValue())
+
+	_, err = functions.CallFunction(ctx, &mcppb.CallFunctionRequest{Name: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = functions.CallFunction(ctx, &mcppb.CallFunctionRequest{Name: "echo"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	analysis := mcppb.NewAnalysisServiceClient(conn)
+	metrics, err := analysis.AnalyzeMetrics(ctx, &mcppb.AnalysisRequest{
+		Uri: "main.go", Content: "package main\n\nfunc main() {}\n",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), metrics.FunctionCount)
+
+	files, err := analysis.AnalyzeFiles(ctx)
+	require.NoError(t, err)
+	require.NoError(t, files.Send(&mcppb.AnalysisRequest{Uri: "a.go", Content: "package a\n\ntype T struct{ N int }\n"}))
+	require.NoError(t, files.Send(&mcppb.AnalysisRequest{Uri: "b.go", Content: "not go"}))
+	require.NoError(t, files.CloseSend())
+
+	first, err := files.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "a.go", first.Uri)
+	require.Len(t, first.Result.Types, 1)
+	assert.Equal(t, "T", first.Result.Types[0].Name)
+
+	second, err := files.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "b.go", second.Uri)
+	assert.NotEmpty(t, second.Error)
+
+	_, err = files.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	// Switched off features are denied
+	_, err = s.UpdateFeatures(FeatureState{Features: map[string]bool{FeatureAnalysis: false}})
+	require.NoError(t, err)
+	_, err = analysis.AnalyzeMetrics(ctx, &mcppb.AnalysisRequest{Uri: "main.go", Content: "package main\n"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	files, err = analysis.AnalyzeFiles(ctx)
+	require.NoError(t, err)
+	_, err = files.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
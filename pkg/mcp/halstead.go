@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"go/ast"
+	"go/token"
+	"math"
+)
+
+// HalsteadMetrics measure code by its operators, like +, := or return, and
+// its operands, identifiers and literals
+type HalsteadMetrics struct {
+	DistinctOperators int     `json:"distinct_operators"`
+	DistinctOperands  int     `json:"distinct_operands"`
+	Operators         int     `json:"operators"`
+	Operands          int     `json:"operands"`
+	Volume            float64 `json:"volume"`     // Size of the code in bits: length × log2(vocabulary)
+	Difficulty        float64 `json:"difficulty"` // How error-prone it is to write or read
+	Effort            float64 `json:"effort"`     // Difficulty × volume
+}
+
+// halsteadCounter counts the operators and operands of syntax trees
+type halsteadCounter struct {
+	operators map[string]int
+	operands  map[string]int
+}
+
+func newHalsteadCounter() *halsteadCounter {
+	return &halsteadCounter{operators: make(map[string]int), operands: make(map[string]int)}
+}
+
+// halstead returns the Halstead metrics of a syntax tree
+func halstead(node ast.Node) HalsteadMetrics {
+	c := newHalsteadCounter()
+	c.add(node)
+	return c.metrics()
+}
+
+// add counts the operators and operands of a syntax tree. Keywords,
+// operators and the brackets of calls, indexes and composite literals are
+// operators.
+func (c *halsteadCounter) add(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			c.operands[n.Name]++
+		case *ast.BasicLit:
+			c.operands[n.Value]++
+		case *ast.BinaryExpr:
+			c.operators[n.Op.String()]++
+		case *ast.UnaryExpr:
+			c.operators[n.Op.String()]++
+		case *ast.StarExpr:
+			c.operators["*"]++
+		case *ast.AssignStmt:
+			c.operators[n.Tok.String()]++
+		case *ast.IncDecStmt:
+			c.operators[n.Tok.String()]++
+		case *ast.SendStmt:
+			c.operators[token.ARROW.String()]++
+		case *ast.CallExpr:
+			c.operators["()"]++
+		case *ast.IndexExpr, *ast.IndexListExpr:
+			c.operators["[]"]++
+		case *ast.SliceExpr:
+			c.operators["[:]"]++
+		case *ast.SelectorExpr:
+			c.operators["."]++
+		case *ast.TypeAssertExpr:
+			c.operators[".()"]++
+		case *ast.CompositeLit:
+			c.operators["{}"]++
+		case *ast.KeyValueExpr:
+			c.operators[":"]++
+		case *ast.FuncType:
+			c.operators["func"]++
+		case *ast.GenDecl:
+			c.operators[n.Tok.String()]++
+		case *ast.IfStmt:
+			c.operators["if"]++
+			if n.Else != nil {
+				c.operators["else"]++
+			}
+		case *ast.ForStmt:
+			c.operators["for"]++
+		case *ast.RangeStmt:
+			c.operators["for"]++
+			c.operators["range"]++
+		case *ast.SwitchStmt, *ast.TypeSwitchStmt:
+			c.operators["switch"]++
+		case *ast.SelectStmt:
+			c.operators["select"]++
+		case *ast.CaseClause:
+			c.caseClause(n.List == nil)
+		case *ast.CommClause:
+			c.caseClause(n.Comm == nil)
+		case *ast.ReturnStmt:
+			c.operators["return"]++
+		case *ast.GoStmt:
+			c.operators["go"]++
+		case *ast.DeferStmt:
+			c.operators["defer"]++
+		case *ast.BranchStmt:
+			c.operators[n.Tok.String()]++
+		}
+		return true
+	})
+}
+
+func (c *halsteadCounter) caseClause(isDefault bool) {
+	if isDefault {
+		c.operators["default"]++
+	} else {
+		c.operators["case"]++
+	}
+}
+
+// metrics derives the Halstead metrics from the counts
+func (c *halsteadCounter) metrics() HalsteadMetrics {
+	m := HalsteadMetrics{DistinctOperators: len(c.operators), DistinctOperands: len(c.operands)}
+	for _, n := range c.operators {
+		m.Operators += n
+	}
+	for _, n := range c.operands {
+		m.Operands += n
+	}
+
+	if vocabulary := m.DistinctOperators + m.DistinctOperands; vocabulary > 1 {
+		m.Volume = float64(m.Operators+m.Operands) * math.Log2(float64(vocabulary))
+	}
+	if m.DistinctOperands > 0 {
+		m.Difficulty = float64(m.DistinctOperators) / 2 * float64(m.Operands) / float64(m.DistinctOperands)
+	}
+	m.Effort = m.Difficulty * m.Volume
+	return m
+}
+
+// maintainabilityIndex rates how easy code is to maintain from 0 to 100,
+// higher being better, from its Halstead volume, cyclomatic complexity and
+// lines, as 171 - 5.2 ln(volume) - 0.23 complexity - 16.2 ln(lines) scaled
+// to 0..100. Code of 20 or more is usually considered maintainable.
+func maintainabilityIndex(volume float64, complexity, lines int) float64 {
+	if lines < 1 {
+		return 100
+	}
+	index := 171 - 0.23*float64(complexity) - 16.2*math.Log(float64(lines))
+	if volume > 0 {
+		index -= 5.2 * math.Log(volume)
+	}
+	return math.Max(0, math.Min(100, index*100/171))
+}
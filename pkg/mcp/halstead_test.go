@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHalstead(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", "package p\n\nfunc f(a, b int) int { return a + b }\n", 0)
+	require.NoError(t, err)
+
+	// Operators func, return and +; operands f, a, b and int
+	m := halstead(file.Decls[0])
+	assert.Equal(t, 3, m.DistinctOperators)
+	assert.Equal(t, 3, m.Operators)
+	assert.Equal(t, 4, m.DistinctOperands)
+	assert.Equal(t, 7, m.Operands)
+	assert.InDelta(t, 10*math.Log2(7), m.Volume, 1e-9)
+	assert.InDelta(t, 2.625, m.Difficulty, 1e-9)
+	assert.InDelta(t, m.Difficulty*m.Volume, m.Effort, 1e-9)
+
+	assert.Equal(t, HalsteadMetrics{}, halstead(&ast.BlockStmt{}))
+}
+
+func TestMaintainabilityIndex(t *testing.T) {
+	tests := []struct {
+		name       string
+		volume     float64
+		complexity int
+		lines      int
+		want       float64
+	}{
+		{"empty", 0, 1, 0, 100},
+		{"one line", 0, 1, 1, (171 - 0.23) * 100 / 171},
+		{"typical function", 500, 5, 30, (171 - 5.2*math.Log(500) - 0.23*5 - 16.2*math.Log(30)) * 100 / 171},
+		{"huge function", 1e9, 300, 5000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, maintainabilityIndex(tt.volume, tt.complexity, tt.lines), 1e-9)
+		})
+	}
+}
+
+func TestAnalyzeFileMaintainability(t *testing.T) {
+	fset := token.NewFileSet()
+	src := "package p\n\nfunc small() int { return 1 }\n\nfunc branchy(a, b, c int) int {\n\tif a > b && b > c {\n\t\treturn a\n\t}\n\tfor i := 0; i < c; i++ {\n\t\ta += i * b\n\t}\n\treturn a - c\n}\n"
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	require.NoError(t, err)
+	result, err := NewASTAnalyzer(fset).AnalyzeFile(file)
+	require.NoError(t, err)
+
+	require.Len(t, result.Functions, 2)
+	small, branchy := result.Functions[0], result.Functions[1]
+	assert.Greater(t, branchy.Halstead.Volume, small.Halstead.Volume)
+	assert.Greater(t, branchy.Halstead.Effort, small.Halstead.Effort)
+	assert.Greater(t, small.MaintainabilityIndex, branchy.MaintainabilityIndex)
+	assert.Greater(t, result.Metrics.Halstead.Volume, branchy.Halstead.Volume)
+	assert.Greater(t, result.Metrics.MaintainabilityIndex, 0.0)
+	assert.Less(t, result.Metrics.MaintainabilityIndex, 100.0)
+
+	// Package metrics count operators and operands across files: func,
+	// return and the new () of q.go
+	other, err := parser.ParseFile(fset, "q.go", "package p\n\nfunc other() int { return small() }\n", 0)
+	require.NoError(t, err)
+	pkg, err := NewASTAnalyzer(fset).AnalyzePackage("p", []*ast.File{file, other})
+	require.NoError(t, err)
+	assert.Equal(t, result.Metrics.Halstead.Operators+3, pkg.Metrics.Halstead.Operators)
+	assert.Equal(t, result.Metrics.Halstead.DistinctOperators+1, pkg.Metrics.Halstead.DistinctOperators)
+}
@@ -0,0 +1,145 @@
+package mcp
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"net/http"
+)
+
+// Kinds of document highlights
+const (
+	HighlightRead  = "read"
+	HighlightWrite = "write"
+)
+
+// DocumentHighlight is an occurrence of a symbol in a document
+type DocumentHighlight struct {
+	Range Range  `json:"range"`
+	Kind  string `json:"kind"` // read, or write for declarations and assignments
+}
+
+// HighlightSymbol returns the occurrences, in the Go file at path, of the
+// symbol at pos. Declarations, assignments, increments and range variables
+// are writes; other occurrences are reads. The package is loaded as for
+// FindDefinition. It returns no highlights when there is no symbol at pos.
+func HighlightSymbol(path string, overlay map[string][]byte, pos Position) ([]DocumentHighlight, error) {
+	target, err := resolveIdent(path, overlay, pos)
+	if err != nil {
+		return nil, err
+	}
+	highlights := []DocumentHighlight{}
+	if target.obj == nil {
+		return highlights, nil
+	}
+
+	var file *ast.File
+	for _, f := range target.pkg.Syntax {
+		if target.fset.File(f.Pos()).Name() == path {
+			file = f
+		}
+	}
+	writes := assignedIdents(file)
+
+	// Instances of generic functions and fields have objects of their own,
+	// so objects are matched by their declaration
+	declaredAt := target.obj.Pos()
+	info := target.pkg.TypesInfo
+	ast.Inspect(file, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		kind := HighlightRead
+		obj := info.Uses[ident]
+		if def := info.Defs[ident]; def != nil {
+			obj, kind = def, HighlightWrite
+		}
+		if obj == nil || obj.Pos() != declaredAt || obj.Name() != target.obj.Name() {
+			return true
+		}
+		if writes[ident] {
+			kind = HighlightWrite
+		}
+
+		start := target.fset.Position(ident.Pos())
+		end := target.fset.Position(ident.End())
+		highlights = append(highlights, DocumentHighlight{
+			Range: Range{
+				Start: Position{Line: start.Line - 1, Character: start.Column - 1},
+				End:   Position{Line: end.Line - 1, Character: end.Column - 1},
+			},
+			Kind: kind,
+		})
+		return true
+	})
+	return highlights, nil
+}
+
+// assignedIdents returns the identifiers of file that are assigned to:
+// the variables and fields on the left of assignments, incremented or
+// decremented, or assigned by a range clause
+func assignedIdents(file *ast.File) map[*ast.Ident]bool {
+	writes := make(map[*ast.Ident]bool)
+	assigned := func(expr ast.Expr) {
+		switch expr := ast.Unparen(expr).(type) {
+		case *ast.Ident:
+			writes[expr] = true
+		case *ast.SelectorExpr:
+			writes[expr.Sel] = true
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				assigned(lhs)
+			}
+		case *ast.IncDecStmt:
+			assigned(n.X)
+		case *ast.RangeStmt:
+			if n.Tok == token.ASSIGN || n.Tok == token.DEFINE {
+				if n.Key != nil {
+					assigned(n.Key)
+				}
+				if n.Value != nil {
+					assigned(n.Value)
+				}
+			}
+		}
+		return true
+	})
+	return writes
+}
+
+// highlights returns the occurrences of the symbol at pos in an open
+// document or a Go file of the workspace
+func (ls *LanguageServer) highlights(uri string, pos Position) ([]DocumentHighlight, error) {
+	overlay, _, exists := ls.overlay(uri)
+	if !exists {
+		return nil, errDocumentNotFound
+	}
+	return HighlightSymbol(ls.documentPath(uri), overlay, pos)
+}
+
+func handleDocumentHighlight(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uri, pos, err := positionParams(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		highlights, err := ls.highlights(uri, pos)
+		switch {
+		case errors.Is(err, errDocumentNotFound):
+			writeError(w, http.StatusNotFound, err)
+			return
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, highlights)
+	}
+}
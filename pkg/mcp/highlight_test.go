@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const highlightSource = `package m
+
+type counter struct{ n int }
+
+func count(items []string) int {
+	total := 0
+	for i := range items {
+		total += i
+	}
+	c := counter{}
+	c.n++
+	return total + c.n
+}
+`
+
+func TestHandleDocumentHighlight(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "m.go"), []byte("package m\n"), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+
+	body, err := json.Marshal(TextDocumentItem{URI: "m.go", Text: highlightSource})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/document/open", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	highlight := func(line, character int) []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/v1/lsp/documentHighlight?uri=m.go&line=%d&character=%d", line, character), nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var highlights []DocumentHighlight
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&highlights))
+		var found []string
+		for _, h := range highlights {
+			found = append(found, fmt.Sprintf("%s %d:%d-%d", h.Kind, h.Range.Start.Line, h.Range.Start.Character, h.Range.End.Character))
+		}
+		return found
+	}
+
+	// Declarations and assignments are writes
+	assert.Equal(t, []string{"write 5:1-6", "write 7:2-7", "read 11:8-13"}, highlight(11, 9))
+	assert.Equal(t, []string{"write 6:5-6", "read 7:11-12"}, highlight(6, 5))
+
+	// Fields, through selectors
+	assert.Equal(t, []string{"write 2:21-22", "write 10:3-4", "read 11:18-19"}, highlight(2, 21))
+
+	assert.Empty(t, highlight(5, 10))
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/documentHighlight?uri=missing.go&line=0&character=0", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
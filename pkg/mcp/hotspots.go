@@ -0,0 +1,196 @@
+package mcp
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+)
+
+// Hotspot describes a file that is both frequently changed and complex
+type Hotspot struct {
+	Path                  string    `json:"path"`
+	Commits               int       `json:"commits"`
+	LinesAdded            int       `json:"lines_added"`
+	LinesDeleted          int       `json:"lines_deleted"`
+	Authors               int       `json:"authors"`
+	PrimaryAuthor         string    `json:"primary_author"` // Author of the most lines changed
+	Ownership             float64   `json:"ownership"`      // Share of the lines changed by the primary author, 0..1
+	LastChange            time.Time `json:"last_change"`
+	Complexity            int       `json:"complexity"`
+	MaxFunctionComplexity int       `json:"max_function_complexity"`
+	Functions             int       `json:"functions"`
+	Score                 float64   `json:"score"` // Normalized churn × complexity, 0..1
+}
+
+// Orders of hotspots
+const (
+	HotspotsByScore   = "score"
+	HotspotsByCommits = "commits"
+	HotspotsByLines   = "lines"   // Lines changed
+	HotspotsByAuthors = "authors" // Files changed by many authors first
+)
+
+// HotspotReport ranks files by combined churn and complexity
+type HotspotReport struct {
+	Since    string    `json:"since,omitempty"`
+	Hotspots []Hotspot `json:"hotspots"`
+}
+
+// BuildHotspotReport combines git change frequency with cyclomatic complexity
+// for every Go file under root that appears in the history, with the lines
+// changed and the authors who changed them
+func BuildHotspotReport(root, since string, includeTests bool) (*HotspotReport, error) {
+	churn, err := ide.NewGitManager(root).FileChurn(since)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &HotspotReport{Since: since, Hotspots: make([]Hotspot, 0)}
+	maxCommits, maxComplexity := 0, 0
+
+	for path, changes := range churn {
+		commits := changes.Commits
+		if !strings.HasSuffix(path, ".go") || (!includeTests && strings.HasSuffix(path, "_test.go")) {
+			continue
+		}
+
+		fullPath := filepath.Join(root, filepath.FromSlash(path))
+		if _, err := os.Stat(fullPath); err != nil {
+			continue // Deleted or renamed since
+		}
+
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, fullPath, nil, 0)
+		if err != nil {
+			continue
+		}
+
+		h := Hotspot{
+			Path:         path,
+			Commits:      commits,
+			LinesAdded:   changes.LinesAdded,
+			LinesDeleted: changes.LinesDeleted,
+			Authors:      len(changes.Authors),
+			LastChange:   changes.LastChange,
+		}
+		if len(changes.Authors) > 0 {
+			h.PrimaryAuthor = changes.Authors[0].Name
+			if lines := changes.LinesAdded + changes.LinesDeleted; lines > 0 {
+				h.Ownership = float64(changes.Authors[0].LinesChanged) / float64(lines)
+			}
+		}
+		for _, fn := range NewASTAnalyzer(fset).analyzeFunctions(file) {
+			h.Functions++
+			h.Complexity += fn.Complexity
+			h.MaxFunctionComplexity = max(h.MaxFunctionComplexity, fn.Complexity)
+		}
+
+		maxCommits = max(maxCommits, commits)
+		maxComplexity = max(maxComplexity, h.Complexity)
+		report.Hotspots = append(report.Hotspots, h)
+	}
+
+	for i := range report.Hotspots {
+		h := &report.Hotspots[i]
+		if maxCommits > 0 && maxComplexity > 0 {
+			h.Score = float64(h.Commits) / float64(maxCommits) * float64(h.Complexity) / float64(maxComplexity)
+		}
+	}
+
+	sortHotspots(report.Hotspots, HotspotsByScore)
+	return report, nil
+}
+
+// sortHotspots orders hotspots by score, commits, lines changed or authors,
+// the highest first, then by path
+func sortHotspots(hotspots []Hotspot, by string) {
+	key := func(h Hotspot) float64 {
+		switch by {
+		case HotspotsByCommits:
+			return float64(h.Commits)
+		case HotspotsByLines:
+			return float64(h.LinesAdded + h.LinesDeleted)
+		case HotspotsByAuthors:
+			return float64(h.Authors)
+		}
+		return h.Score
+	}
+	sort.Slice(hotspots, func(i, j int) bool {
+		if a, b := key(hotspots[i]), key(hotspots[j]); a != b {
+			return a > b
+		}
+		return hotspots[i].Path < hotspots[j].Path
+	})
+}
+
+// applyChurn sets the churn of the metrics of a file analyzed when its URI
+// is a path of the workspace whose changes its git history records
+func applyChurn(root string, req AnalysisRequest, metrics *CodeMetrics) {
+	if req.URI == "" || !isWithinRoot(root, req.URI) {
+		return
+	}
+	path := filepath.ToSlash(filepath.Clean(req.URI))
+	churn, err := ide.NewGitManager(root).FileChurn(req.Since, path)
+	if err != nil {
+		return // Not a git repository, or since is invalid
+	}
+	metrics.Churn = churn[path]
+}
+
+func handleHotspots(root string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		limit := 20
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %s", v))
+				return
+			}
+			limit = n
+		}
+
+		by := q.Get("sort")
+		switch by {
+		case "":
+			by = HotspotsByScore
+		case HotspotsByScore, HotspotsByCommits, HotspotsByLines, HotspotsByAuthors:
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Errorf("sort must be score, commits, lines or authors"))
+			return
+		}
+
+		report, err := BuildHotspotReport(root, q.Get("since"), q.Get("include_tests") == "true")
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		sortHotspots(report.Hotspots, by)
+
+		if prefix := q.Get("path"); prefix != "" {
+			filtered := make([]Hotspot, 0)
+			for _, h := range report.Hotspots {
+				if strings.HasPrefix(h.Path, prefix) {
+					filtered = append(filtered, h)
+				}
+			}
+			report.Hotspots = filtered
+		}
+
+		if len(report.Hotspots) > limit {
+			report.Hotspots = report.Hotspots[:limit]
+		}
+
+		writeJSON(w, http.StatusOK, report)
+	}
+}
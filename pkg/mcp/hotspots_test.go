@@ -0,0 +1,199 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const hotspotSourceV1 = `package hot
+
+func A(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+`
+
+// hotspotSourceV2 has a complexity of 3
+const hotspotSourceV2 = `package hot
+
+func A(x int) int {
+	if x > 0 {
+		return 1
+	}
+	if x < 0 {
+		return -1
+	}
+	return 0
+}
+`
+
+// coldSource has a complexity of 4
+const coldSource = `package cold
+
+func C(x int) int {
+	for i := 0; i < x; i++ {
+		if i > 3 && i < 10 {
+			return i
+		}
+	}
+	return 0
+}
+`
+
+// hotspotRepo creates a git repository in which hot/a.go changed three
+// times by two authors, and cold/c.go and hot/b.go once
+func hotspotRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(author string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=" + author, "-c", "user.email=" + strings.ToLower(author) + "@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	git("Ann", "init", "-q", "-b", "main")
+	write("hot/a.go", hotspotSourceV1)
+	write("hot/b.go", "package hot\n\nfunc B() {}\n")
+	write("hot/a_test.go", "package hot\n\nfunc helper() {}\n")
+	write("cold/c.go", coldSource)
+	write("gone.go", "package gone\n")
+	write("notes.txt", "not go\n")
+	git("Ann", "add", ".")
+	git("Ann", "commit", "-q", "-m", "init")
+
+	write("hot/a.go", hotspotSourceV2)
+	write("hot/a_test.go", "package hot\n\nfunc helper() { _ = 1 }\n")
+	git("Bob", "commit", "-q", "-am", "negative")
+	write("hot/a.go", strings.Replace(hotspotSourceV2, "return 0", "return 0 // zero", 1))
+	git("Bob", "rm", "-q", "gone.go")
+	git("Bob", "commit", "-q", "-am", "comment")
+	return dir
+}
+
+func TestBuildHotspotReport(t *testing.T) {
+	dir := hotspotRepo(t)
+
+	report, err := BuildHotspotReport(dir, "", false)
+	require.NoError(t, err)
+	require.Len(t, report.Hotspots, 3, "tests, deleted files and other files are left out")
+
+	a, c, b := report.Hotspots[0], report.Hotspots[1], report.Hotspots[2]
+	assert.Equal(t, []string{"hot/a.go", "cold/c.go", "hot/b.go"}, []string{a.Path, c.Path, b.Path})
+
+	// Scores are commits × complexity, each relative to the highest
+	assert.Equal(t, 3, a.Commits)
+	assert.Equal(t, 3, a.Complexity)
+	assert.Equal(t, 3, a.MaxFunctionComplexity)
+	assert.Equal(t, 1, a.Functions)
+	assert.InDelta(t, 3.0/3*3/4, a.Score, 1e-9)
+	assert.Equal(t, 4, c.Complexity)
+	assert.InDelta(t, 1.0/3*4/4, c.Score, 1e-9)
+	assert.Equal(t, 1, b.Complexity)
+	assert.InDelta(t, 1.0/3*1/4, b.Score, 1e-9)
+
+	// Bob added 3 lines to the 8 Ann wrote, then changed 1
+	assert.Equal(t, 12, a.LinesAdded)
+	assert.Equal(t, 1, a.LinesDeleted)
+	assert.Equal(t, 2, a.Authors)
+	assert.Equal(t, "Ann", a.PrimaryAuthor)
+	assert.InDelta(t, 8.0/13, a.Ownership, 1e-9)
+	assert.False(t, a.LastChange.IsZero())
+	assert.Equal(t, 1, c.Authors)
+	assert.Equal(t, "Ann", c.PrimaryAuthor)
+	assert.Equal(t, 1.0, c.Ownership)
+
+	report, err = BuildHotspotReport(dir, "", true)
+	require.NoError(t, err)
+	assert.Len(t, report.Hotspots, 4)
+
+	_, err = BuildHotspotReport(t.TempDir(), "", false)
+	assert.Error(t, err)
+}
+
+func TestSortHotspots(t *testing.T) {
+	hotspots := []Hotspot{
+		{Path: "b.go", Score: 0.5, Commits: 2, LinesAdded: 1, Authors: 3},
+		{Path: "a.go", Score: 0.5, Commits: 1, LinesAdded: 10, LinesDeleted: 5, Authors: 1},
+		{Path: "c.go", Score: 0.9, Commits: 2, LinesDeleted: 12, Authors: 2},
+	}
+
+	tests := []struct {
+		by    string
+		paths []string
+	}{
+		{HotspotsByScore, []string{"c.go", "a.go", "b.go"}},
+		{HotspotsByCommits, []string{"b.go", "c.go", "a.go"}},
+		{HotspotsByLines, []string{"a.go", "c.go", "b.go"}},
+		{HotspotsByAuthors, []string{"b.go", "c.go", "a.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			sortHotspots(hotspots, tt.by)
+			var paths []string
+			for _, h := range hotspots {
+				paths = append(paths, h.Path)
+			}
+			assert.Equal(t, tt.paths, paths)
+		})
+	}
+}
+
+func TestHandleHotspots(t *testing.T) {
+	dir := hotspotRepo(t)
+	handler := handleHotspots(dir)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		paths  []string
+	}{
+		{"default", "", http.StatusOK, []string{"hot/a.go", "cold/c.go", "hot/b.go"}},
+		{"limit", "limit=2", http.StatusOK, []string{"hot/a.go", "cold/c.go"}},
+		{"path", "path=hot/", http.StatusOK, []string{"hot/a.go", "hot/b.go"}},
+		{"path and limit", "path=hot/&limit=1&sort=commits", http.StatusOK, []string{"hot/a.go"}},
+		{"by commits", "sort=commits", http.StatusOK, []string{"hot/a.go", "cold/c.go", "hot/b.go"}},
+		{"by lines", "sort=lines", http.StatusOK, []string{"hot/a.go", "cold/c.go", "hot/b.go"}},
+		{"tests", "include_tests=true&path=hot/", http.StatusOK, []string{"hot/a.go", "hot/a_test.go", "hot/b.go"}},
+		{"no match", "path=none/", http.StatusOK, []string{}},
+		{"bad limit", "limit=0", http.StatusBadRequest, nil},
+		{"bad sort", "sort=size", http.StatusBadRequest, nil},
+		{"bad since", "since=1%20week", http.StatusInternalServerError, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest("GET", "/v1/analyze/hotspots?"+tt.query, nil))
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.status != http.StatusOK {
+				return
+			}
+			var report HotspotReport
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			paths := []string{}
+			for _, h := range report.Hotspots {
+				paths = append(paths, h.Path)
+			}
+			assert.Equal(t, tt.paths, paths)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"fmt"
+	"go/types"
+	"net/http"
+)
+
+// Hover describes the symbol at a position
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    Range         `json:"range"` // The identifier described
+}
+
+// MarkupContent is text in the given format, plaintext or markdown
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// DescribeSymbol returns the declaration of the symbol at pos in the Go file
+// at path as markdown: its signature or type, with the value of constants,
+// followed by its doc comment. The package is loaded as for FindDefinition.
+// It returns nil when there is no symbol at pos.
+func DescribeSymbol(path string, overlay map[string][]byte, pos Position) (*Hover, error) {
+	target, err := resolveIdent(path, overlay, pos)
+	if err != nil {
+		return nil, err
+	}
+	if target.obj == nil {
+		return nil, nil
+	}
+
+	signature := types.ObjectString(target.obj, types.RelativeTo(target.pkg.Types))
+	if c, ok := target.obj.(*types.Const); ok {
+		signature += " = " + c.Val().String()
+	}
+	value := "```go\n" + signature + "\n```"
+	if doc := newDocFinder(target.fset, target.pkg.Syntax).find(target.obj.Pos()); doc != "" {
+		value += "\n\n" + doc
+	}
+
+	start := target.fset.Position(target.ident.Pos())
+	end := target.fset.Position(target.ident.End())
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: value},
+		Range: Range{
+			Start: Position{Line: start.Line - 1, Character: start.Column - 1},
+			End:   Position{Line: end.Line - 1, Character: end.Column - 1},
+		},
+	}, nil
+}
+
+func handleHover(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uri, pos, err := positionParams(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		overlay, _, exists := ls.overlay(uri)
+		if !exists {
+			writeError(w, http.StatusNotFound, fmt.Errorf("document not found"))
+			return
+		}
+
+		hover, err := DescribeSymbol(ls.documentPath(uri), overlay, pos)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, hover)
+	}
+}
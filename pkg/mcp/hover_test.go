@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleHover(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n"), 0644))
+	src := `package m
+
+import "fmt"
+
+// Limit bounds the retries
+const Limit = 3 * 2
+
+// Config configures
+type Config struct {
+	Name string // Name is shown
+}
+
+func show(c Config) {
+	fmt.Println(c.Name, Limit)
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+	body, err := json.Marshal(TextDocumentItem{URI: "main.go", Text: src})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/document/open", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	hover := func(line, character int) *Hover {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/v1/lsp/hover?uri=main.go&line=%d&character=%d", line, character), nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var hover *Hover
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&hover))
+		return hover
+	}
+
+	h := hover(13, 23)
+	require.NotNil(t, h)
+	assert.Equal(t, "markdown", h.Contents.Kind)
+	assert.Equal(t, "```go\nconst Limit untyped int = 6\n```\n\nLimit bounds the retries", h.Contents.Value)
+	assert.Equal(t, Range{Start: Position{Line: 13, Character: 21}, End: Position{Line: 13, Character: 26}}, h.Range)
+
+	h = hover(13, 17)
+	require.NotNil(t, h)
+	assert.Equal(t, "```go\nfield Name string\n```\n\nName is shown", h.Contents.Value)
+
+	h = hover(12, 13)
+	require.NotNil(t, h)
+	assert.Equal(t, "```go\ntype Config struct{Name string}\n```\n\nConfig configures", h.Contents.Value)
+
+	// Docs of the standard library come from its sources
+	h = hover(13, 6)
+	require.NotNil(t, h)
+	assert.True(t, strings.HasPrefix(h.Contents.Value, "```go\nfunc fmt.Println(a ...any) (n int, err error)\n```\n\nPrintln formats"), h.Contents.Value)
+
+	// Nothing to describe between identifiers
+	assert.Nil(t, hover(1, 0))
+}
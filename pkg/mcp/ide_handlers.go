@@ -1,11 +1,16 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/ivikasavnish/go-mcp/pkg/ide"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Additional request/response types
@@ -14,15 +19,34 @@ type UpdateProjectConfigRequest struct {
 }
 
 type CreateTaskRequest struct {
-	Name        string `json:"name"`
-	Command     string `json:"command"`
-	AutoRestart bool   `json:"auto_restart"`
+	Name        string           `json:"name"`
+	Type        string           `json:"type,omitempty"` // command (default) or watch
+	Command     string           `json:"command"`
+	AutoRestart bool             `json:"auto_restart"`
+	Watch       *ide.WatchConfig `json:"watch,omitempty"`
+	Artifacts   []string         `json:"artifacts,omitempty"` // Globs of output files to register after each run
+	Target      *ide.TaskTarget  `json:"target,omitempty"`    // Run over SSH instead of locally
+}
+
+// SaveFileRequest writes a file of the project
+type SaveFileRequest struct {
+	Path    string `json:"path"` // Relative to the project root
+	Content string `json:"content"`
+}
+
+// SaveFileResult describes a saved file
+type SaveFileResult struct {
+	Path        string `json:"path"`
+	Formatted   bool   `json:"formatted"`              // Formatting changed the content
+	FormatError string `json:"format_error,omitempty"` // Formatting failed and the content was saved as it was
 }
 
 // IDE server extension
 type IDEServer struct {
 	projectManager *ide.ProjectManager
 	taskManager    *ide.TaskManager
+	benchRunner    *ide.BenchRunner
+	artifactStore  *ide.ArtifactStore
 }
 
 func (s IDEServer) NewCommandExecutor(root string) interface{} {
@@ -35,22 +59,224 @@ func NewIDEServer(projectRoot string) (*IDEServer, error) {
 		return nil, err
 	}
 
-	return &IDEServer{
+	ideServer := &IDEServer{
 		projectManager: pm,
 		taskManager:    ide.NewTaskManager(),
-	}, nil
+		benchRunner:    ide.NewBenchRunner(projectRoot),
+		artifactStore:  ide.NewArtifactStore(projectRoot),
+	}
+	ideServer.applyRetention()
+	ideServer.taskManager.SetArtifactStore(ideServer.artifactStore)
+
+	return ideServer, nil
+}
+
+// applyRetention sets the artifact retention policy from the project config
+func (s *IDEServer) applyRetention() {
+	policy := ide.DefaultRetentionPolicy
+	if retention := s.projectManager.GetConfig().ArtifactRetention; retention != nil {
+		policy = *retention
+	}
+	s.artifactStore.SetRetention(policy)
 }
 
 func (s *Server) AddIDEServer(ideServer *IDEServer) {
+	s.mu.Lock()
+	s.ideServers = append(s.ideServers, ideServer)
+	s.mu.Unlock()
+
+	ideServer.taskManager.SetLogger(s.logger)
+
 	// Project management
-	s.router.HandleFunc("/ide/project/config", handleGetProjectConfig(ideServer)).Methods("GET")
-	s.router.HandleFunc("/ide/project/config", handleUpdateProjectConfig(ideServer)).Methods("PUT")
+	s.handle(Route{
+		Method: "GET", Path: "/ide/project/config", Summary: "Get the project configuration",
+		Response: ide.ProjectConfig{},
+	}, handleGetProjectConfig(ideServer))
+	s.handle(Route{
+		Method: "PUT", Path: "/ide/project/config", Summary: "Replace the project configuration",
+		Request: UpdateProjectConfigRequest{}, Response: ide.ProjectConfig{},
+	}, handleUpdateProjectConfig(ideServer))
 
 	// Task management
-	s.router.HandleFunc("/ide/tasks", handleListTasks(ideServer)).Methods("GET")
-	//s.router.HandleFunc("/ide/tasks", handleCreateTask(ideServer)).Methods("POST")
-	s.router.HandleFunc("/ide/tasks/{id}", handleGetTask(ideServer)).Methods("GET")
-	s.router.HandleFunc("/ide/tasks/{id}", handleStopTask(ideServer)).Methods("DELETE")
+	s.handle(Route{
+		Method: "GET", Path: "/ide/tasks", Summary: "List tasks",
+		Response: []*ide.Task{},
+	}, handleListTasks(ideServer))
+	s.handle(Route{
+		Method: "POST", Path: "/ide/tasks", Summary: "Start a task",
+		Request: CreateTaskRequest{}, Response: ide.Task{}, Status: http.StatusCreated,
+	}, handleCreateTask(s, ideServer))
+	s.handle(Route{
+		Method: "GET", Path: "/ide/tasks/{id}", Summary: "Get a task",
+		Response: ide.Task{},
+	}, handleGetTask(ideServer))
+	s.handle(Route{
+		Method: "DELETE", Path: "/ide/tasks/{id}", Summary: "Stop a task",
+		Response: map[string]string{},
+	}, handleStopTask(s, ideServer))
+	s.handle(Route{
+		Method: "GET", Path: "/ide/tasks/{id}/logs", Summary: "Get the log events of a task",
+		Query: []QueryParam{
+			{Name: "since", Description: "Only return events after this sequence number"},
+			{Name: "follow", Description: "Stream events as text/event-stream until the task ends"},
+		},
+		Response: []ide.TaskEvent{},
+		Timeout:  NoTimeout,
+	}, handleTaskLogs(ideServer))
+
+	// Benchmarks
+	s.handle(Route{
+		Method: "POST", Path: "/ide/bench", Summary: "Run benchmarks and compare them with a baseline",
+		Request: BenchRequest{}, Response: BenchResponse{}, Timeout: LongRunningTimeout,
+	}, handleRunBench(ideServer))
+	s.handle(Route{
+		Method: "GET", Path: "/ide/bench", Summary: "List stored benchmark runs",
+		Response: []*ide.BenchRun{},
+	}, handleListBenchRuns(ideServer))
+	s.handle(Route{
+		Method: "GET", Path: "/ide/bench/{ref}", Summary: "Get a benchmark run by key or git revision",
+		Response: ide.BenchRun{},
+	}, handleGetBenchRun(ideServer))
+
+	// Files
+	s.handle(Route{
+		Method: "POST", Path: "/ide/files/save", Summary: "Write a project file, formatting Go files when the project formats on save",
+		Request: SaveFileRequest{}, Response: SaveFileResult{},
+	}, handleSaveFile(ideServer))
+
+	// Artifacts
+	s.handle(Route{
+		Method: "GET", Path: "/ide/artifacts", Summary: "List artifacts, newest first",
+		Query: []QueryParam{
+			{Name: "task_id", Description: "Only list artifacts of this task"},
+			{Name: "kind", Description: "Only list artifacts of this kind"},
+		},
+		Response: []*ide.Artifact{},
+	}, handleListArtifacts(ideServer))
+	s.handle(Route{
+		Method: "POST", Path: "/ide/artifacts", Summary: "Register project files as artifacts",
+		Request: RegisterArtifactsRequest{}, Response: []*ide.Artifact{}, Status: http.StatusCreated,
+	}, handleRegisterArtifacts(ideServer))
+	s.handle(Route{
+		Method: "POST", Path: "/ide/artifacts/prune", Summary: "Apply the artifact retention policy",
+		Response: PruneArtifactsResponse{},
+	}, s.requireCapability(CapabilityFileDelete, handlePruneArtifacts(ideServer)))
+	s.handle(Route{
+		Method: "GET", Path: "/ide/artifacts/{id}", Summary: "Get an artifact",
+		Response: ide.Artifact{},
+	}, handleGetArtifact(ideServer))
+	s.handle(Route{
+		Method: "DELETE", Path: "/ide/artifacts/{id}", Summary: "Delete an artifact",
+		Status: http.StatusNoContent,
+	}, s.requireCapability(CapabilityFileDelete, handleDeleteArtifact(ideServer)))
+	s.handle(Route{
+		Method: "GET", Path: "/ide/artifacts/{id}/download", Summary: "Download the content of an artifact",
+		Produces: "application/octet-stream",
+	}, handleDownloadArtifact(ideServer))
+
+	// Version control
+	s.handle(Route{
+		Method: "GET", Path: "/ide/git/status", Summary: "Get the branch, changed files and last commit of the project",
+		Response: ide.GitStatus{},
+	}, handleGitStatus(ideServer))
+	s.handleRPC(RPCMethod{
+		Name: "git.status", Summary: "Get the branch, changed files and last commit of the project",
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return ideServer.gitManager().GetStatus()
+	})
+	s.handleRPC(RPCMethod{
+		Name: "git.summarize_diff", Summary: "Ask the LLM of the MCP host to summarize the uncommitted changes of the project",
+		Params: summarizeDiffParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p summarizeDiffParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return summarizeDiff(ctx, ideServer.gitManager(), p.Base)
+	})
+
+	// Environment
+	s.handle(Route{
+		Method: "GET", Path: "/ide/doctor", Summary: "Check the tooling required by the project and enabled features",
+		Response: ide.DoctorReport{},
+	}, handleDoctor(s))
+
+	// Processes and ports
+	s.handle(Route{
+		Method: "GET", Path: "/ide/processes", Summary: "List processes started by tasks",
+		Query:    []QueryParam{{Name: "all", Description: "List every process on the host"}},
+		Response: []ide.ProcessInfo{},
+	}, handleListProcesses(ideServer))
+	s.handle(Route{
+		Method: "POST", Path: "/ide/processes/{pid}/kill", Summary: "Signal a process started by a task",
+		Request: KillProcessRequest{}, Response: ide.ProcessInfo{},
+	}, handleKillProcess(ideServer))
+	s.handle(Route{
+		Method: "GET", Path: "/ide/ports", Summary: "List listening ports held by tasks",
+		Query: []QueryParam{
+			{Name: "all", Description: "List every listening port on the host"},
+			{Name: "port", Description: "Only list this port, whichever process holds it"},
+		},
+		Response: []ide.ListeningPort{},
+	}, handleListPorts(ideServer))
+}
+
+// maxSampledDiff bounds the diff sent to the host LLM
+const maxSampledDiff = 64 << 10
+
+type summarizeDiffParams struct {
+	Base string `json:"base,omitempty"` // Revision to diff against, defaults to HEAD
+}
+
+// DiffSummary is the summary of a diff written by the host LLM
+type DiffSummary struct {
+	Base      string `json:"base"`
+	Summary   string `json:"summary"`
+	Truncated bool   `json:"truncated,omitempty"` // The diff was cut to fit the request
+}
+
+// summarizeDiff samples a summary of the working tree changes against base
+func summarizeDiff(ctx context.Context, git *ide.GitManager, base string) (*DiffSummary, error) {
+	if base == "" {
+		base = "HEAD"
+	}
+	diff, err := git.Diff(base)
+	if err != nil {
+		return nil, err
+	}
+	result := &DiffSummary{Base: base}
+	if strings.TrimSpace(diff) == "" {
+		result.Summary = "No changes."
+		return result, nil
+	}
+	if len(diff) > maxSampledDiff {
+		diff, result.Truncated = diff[:maxSampledDiff], true
+	}
+
+	result.Summary, err = sampleText(ctx,
+		"You review code changes. Answer with a short summary a reviewer can read in a minute.",
+		"Summarize the following diff against "+base+": what changed and why it matters, and point out risky changes.\n\n"+diff,
+		1024)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// gitManager returns a git manager for the project root
+func (s *IDEServer) gitManager() *ide.GitManager {
+	return ide.NewGitManager(s.projectManager.GetConfig().Root)
+}
+
+func handleGitStatus(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := ideServer.gitManager().GetStatus()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	}
 }
 
 // Project config handlers
@@ -69,53 +295,203 @@ func handleUpdateProjectConfig(ide *IDEServer) http.HandlerFunc {
 			return
 		}
 
+		switch req.Config.FormatOnSave {
+		case "", FormatterGofmt, FormatterGoimports, FormatterGofumpt:
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unknown formatter %q", req.Config.FormatOnSave))
+			return
+		}
+
 		if err := ide.projectManager.UpdateConfig(&req.Config); err != nil {
 			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
+		ide.applyRetention()
 
 		writeJSON(w, http.StatusOK, req.Config)
 	}
 }
 
+// handleSaveFile writes a file through the file manager of the project,
+// formatting Go files first when the project config asks for it
+func handleSaveFile(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SaveFileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.Path == "" || !isWithinRoot("", req.Path) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("path must be inside the project"))
+			return
+		}
+
+		config := ideServer.projectManager.GetConfig()
+		result := SaveFileResult{Path: req.Path}
+		content := req.Content
+		if config.FormatOnSave != "" && strings.HasSuffix(req.Path, ".go") {
+			formatted, err := FormatSource(filepath.Join(config.Root, req.Path), content, config.FormatOnSave)
+			if err != nil {
+				result.FormatError = err.Error()
+			} else {
+				result.Formatted = formatted != content
+				content = formatted
+			}
+		}
+
+		if err := ideServer.projectManager.Files().CreateFile(req.Path, []byte(content)); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
 // Task management handlers
-//func handleCreateTask(ide *IDEServer) http.HandlerFunc {
-//	return func(w http.ResponseWriter, r *http.Request) {
-//		var req CreateTaskRequest
-//		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-//			writeError(w, http.StatusBadRequest, err)
-//			return
-//		}
-//
-//		taskID := fmt.Sprintf("task-%d", time.Now().UnixNano())
-//		task := &ide.Task{
-//			ID:          taskID,
-//			Name:        req.Name,
-//			Command:     req.Command,
-//			AutoRestart: req.AutoRestart,
-//			Status:      "starting",
-//		}
-//
-//		task := ide.taskManager.StartTask()
-//
-//		config := ide.projectManager.GetConfig()
-//		executor := ide.NewCommandExecutor(config.Root)
-//		if err := ide.taskManager.StartTask(task, executor); err != nil {
-//			writeError(w, http.StatusInternalServerError, err)
-//			return
-//		}
-//
-//		writeJSON(w, http.StatusCreated, task)
-//	}
-//}
-
-func handleStopTask(ide *IDEServer) http.HandlerFunc {
+
+// handleCreateTask starts a task, tied to the lease of the request
+func handleCreateTask(s *Server, ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.Command == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("command is required"))
+			return
+		}
+
+		task := &ide.Task{
+			ID:          fmt.Sprintf("task-%d", time.Now().UnixNano()),
+			Name:        req.Name,
+			Type:        req.Type,
+			Command:     req.Command,
+			AutoRestart: req.AutoRestart,
+			Watch:       req.Watch,
+			Artifacts:   req.Artifacts,
+			Target:      req.Target,
+			Status:      "starting",
+			RequestID:   RequestIDFromContext(r.Context()),
+		}
+
+		config := ideServer.projectManager.GetConfig()
+
+		var executor ide.Executor
+		if req.Target != nil {
+			remoteExecutor, err := s.remoteExecutor(req.Target, config.Environment)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			// Remote watch tasks still watch the local project
+			if task.Watch != nil && task.Watch.Dir == "" {
+				task.Watch.Dir = config.Root
+			}
+			executor = remoteExecutor
+		} else {
+			local := ide.NewCommandExecutor(config.Root)
+			for k, v := range config.Environment {
+				local.SetEnv(k, v)
+			}
+			executor = local
+		}
+
+		if err := ideServer.taskManager.StartTask(task, executor); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.leaseResource(r.Context(), LeaseTask, task.ID, func() error {
+			return ideServer.taskManager.StopTask(task.ID)
+		}); err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, task)
+	}
+}
+
+// handleTaskLogs returns the buffered log events of a task, or streams them
+// as server-sent events when follow is set
+func handleTaskLogs(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		taskID := mux.Vars(r)["id"]
+		log := ideServer.taskManager.TaskLog(taskID)
+		if log == nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("task not found"))
+			return
+		}
+
+		var since int64
+		if v := r.URL.Query().Get("since"); v != "" {
+			var err error
+			if since, err = strconv.ParseInt(v, 10, 64); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid since: %v", err))
+				return
+			}
+		}
+
+		follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+		if !follow {
+			writeJSON(w, http.StatusOK, log.Since(since))
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+			return
+		}
+
+		// Subscribe before replaying the backlog so no event falls in between
+		events, unsubscribe := log.Subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+
+		send := func(event ide.TaskEvent) {
+			if event.Seq <= since {
+				return
+			}
+			since = event.Seq
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+		}
+
+		for _, event := range log.Since(since) {
+			send(event)
+		}
+		flusher.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					fmt.Fprint(w, "event: end\ndata: {}\n\n")
+					flusher.Flush()
+					return
+				}
+				send(event)
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func handleStopTask(s *Server, ide *IDEServer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		taskID := mux.Vars(r)["id"]
 		if err := ide.taskManager.StopTask(taskID); err != nil {
 			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
+		s.unleaseResource(LeaseTask, taskID)
+		LoggerFromContext(r.Context()).Info("task stop requested", "task_id", taskID)
 
 		writeJSON(w, http.StatusOK, map[string]string{
 			"status": "stopped",
@@ -0,0 +1,249 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Implementation is a type or method implementing, or implemented by, the
+// type or method at the position of an implementations request
+type Implementation struct {
+	Name     string   `json:"name"`
+	Kind     string   `json:"kind"`             // type, interface or method
+	Detail   string   `json:"detail,omitempty"` // Qualified name
+	Location Location `json:"location"`
+}
+
+// FindImplementations returns, for the interface at pos in the Go file at
+// path, the named types of the packages under root and their tests that
+// implement it, directly or through a pointer; for any other named type,
+// the non-empty interfaces of those packages and their dependencies it
+// implements. For a method, it returns the matching methods of those types
+// or interfaces instead. Overlay replaces the contents of files on disk,
+// as for FindDefinition.
+func FindImplementations(root, path string, overlay map[string][]byte, pos Position) ([]Implementation, error) {
+	content, ok := overlay[path]
+	if !ok {
+		var err error
+		if content, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	offset, err := offsetAt(string(content), pos)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	cfg := &packages.Config{
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:     root,
+		Fset:    fset,
+		Overlay: overlay,
+		Tests:   true,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %v", err)
+	}
+
+	var target types.Object
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			if target != nil || fset.File(file.Pos()).Name() != path {
+				continue
+			}
+			ident := identAt(fset, file, offset)
+			if ident == nil {
+				return nil, fmt.Errorf("no identifier at %d:%d", pos.Line, pos.Character)
+			}
+			if target = pkg.TypesInfo.Defs[ident]; target == nil {
+				target = pkg.TypesInfo.Uses[ident]
+			}
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("no type or method at %d:%d", pos.Line, pos.Character)
+	}
+
+	// The type of a method is its receiver type
+	method := ""
+	typeName, ok := target.(*types.TypeName)
+	if fn, isFunc := target.(*types.Func); isFunc {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			method = fn.Name()
+			typeName, ok = namedOf(recv.Type())
+		}
+	}
+	if !ok || typeName.IsAlias() {
+		return nil, fmt.Errorf("%s is not a named type or a method", target.Name())
+	}
+
+	// Every package and test variant has objects of its own, so types are
+	// matched by their declaration
+	declaredAt := func(obj types.Object) string {
+		return fset.Position(obj.Pos()).String()
+	}
+	workspace := make(map[*packages.Package]bool)
+	for _, pkg := range pkgs {
+		workspace[pkg] = true
+	}
+	var targets, concrete, interfaces []*types.TypeName
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types == nil {
+			return
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			named, ok := tn.Type().(*types.Named)
+			if !ok || named.TypeParams().Len() > 0 {
+				continue
+			}
+			if declaredAt(tn) == declaredAt(typeName) {
+				targets = append(targets, tn)
+				continue
+			}
+			switch iface, ok := named.Underlying().(*types.Interface); {
+			case ok && iface.NumMethods() > 0:
+				interfaces = append(interfaces, tn)
+			case !ok && workspace[pkg]:
+				concrete = append(concrete, tn)
+			}
+		}
+	})
+	if len(targets) == 0 {
+		// Types declared in functions are not in a package scope
+		targets = append(targets, typeName)
+	}
+
+	_, isInterface := typeName.Type().Underlying().(*types.Interface)
+	candidates := interfaces
+	if isInterface {
+		candidates = concrete
+	}
+	found := []Implementation{}
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		for _, tn := range targets {
+			typ, named := candidate.Type(), tn.Type()
+			if !isInterface {
+				typ, named = tn.Type(), candidate.Type()
+			}
+			iface := named.Underlying().(*types.Interface)
+			if !types.Implements(typ, iface) && !types.Implements(types.NewPointer(typ), iface) {
+				continue
+			}
+
+			var obj types.Object = candidate
+			if method != "" {
+				if obj, _, _ = types.LookupFieldOrMethod(candidate.Type(), true, candidate.Pkg(), method); obj == nil {
+					break
+				}
+			}
+			if !seen[declaredAt(obj)] {
+				seen[declaredAt(obj)] = true
+				found = append(found, implementation(fset, obj))
+			}
+			break
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i].Location, found[j].Location
+		if a.URI != b.URI {
+			return a.URI < b.URI
+		}
+		return positionBefore(a.Range.Start, b.Range.Start)
+	})
+	return found, nil
+}
+
+// namedOf returns the name of a named type or of the type a pointer
+// points to
+func namedOf(t types.Type) (*types.TypeName, bool) {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return nil, false
+	}
+	return named.Origin().Obj(), true
+}
+
+func implementation(fset *token.FileSet, obj types.Object) Implementation {
+	impl := Implementation{Name: obj.Name(), Kind: "type"}
+	switch obj := obj.(type) {
+	case *types.Func:
+		impl.Kind = "method"
+		impl.Detail = obj.FullName()
+	case *types.TypeName:
+		if types.IsInterface(obj.Type()) {
+			impl.Kind = "interface"
+		}
+		impl.Detail = types.TypeString(obj.Type(), nil)
+	}
+
+	at := fset.Position(obj.Pos())
+	start := Position{Line: at.Line - 1, Character: at.Column - 1}
+	impl.Location = Location{
+		URI:   "file://" + filepath.ToSlash(at.Filename),
+		Range: Range{Start: start, End: Position{Line: start.Line, Character: start.Character + len(obj.Name())}},
+	}
+	return impl
+}
+
+// implementations finds the implementations of the type or method at pos
+// in an open document or a Go file of the workspace. Implementations in
+// open documents are located by their URI.
+func (ls *LanguageServer) implementations(uri string, pos Position) ([]Implementation, error) {
+	overlay, uris, exists := ls.overlay(uri)
+	if !exists {
+		return nil, errDocumentNotFound
+	}
+
+	found, err := FindImplementations(ls.workspaceRoot, ls.documentPath(uri), overlay, pos)
+	if err != nil {
+		return nil, err
+	}
+	for i, impl := range found {
+		if docURI, ok := uris[impl.Location.URI]; ok {
+			found[i].Location.URI = docURI
+		}
+	}
+	return found, nil
+}
+
+func handleImplementations(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uri, pos, err := positionParams(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		found, err := ls.implementations(uri, pos)
+		switch {
+		case errors.Is(err, errDocumentNotFound):
+			writeError(w, http.StatusNotFound, err)
+			return
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, found)
+	}
+}
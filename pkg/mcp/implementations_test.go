@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const implementationsSource = `package m
+
+import "fmt"
+
+type Shape interface {
+	Area() float64
+}
+
+type Square struct{ Side float64 }
+
+func (s Square) Area() float64 { return s.Side * s.Side }
+
+func (s Square) String() string { return fmt.Sprint(s.Side) }
+
+type Circle struct{ R float64 }
+
+func (c *Circle) Area() float64 { return 3 * c.R * c.R }
+
+type Label string
+`
+
+func TestHandleImplementations(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shapes.go"), []byte(implementationsSource), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+
+	implementations := func(line, character int) []Implementation {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/v1/lsp/implementations?uri=shapes.go&line=%d&character=%d", line, character), nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var found []Implementation
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&found))
+		return found
+	}
+	names := func(found []Implementation) []string {
+		var names []string
+		for _, impl := range found {
+			names = append(names, impl.Kind+" "+impl.Detail)
+		}
+		return names
+	}
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "shapes.go"))
+
+	// The types implementing an interface, also through pointers
+	found := implementations(4, 6)
+	assert.Equal(t, []string{"type example.com/m.Square", "type example.com/m.Circle"}, names(found))
+	assert.Equal(t, Location{URI: uri, Range: Range{Start: Position{Line: 8, Character: 5}, End: Position{Line: 8, Character: 11}}}, found[0].Location)
+
+	// The methods implementing an interface method
+	found = implementations(5, 2)
+	assert.Equal(t, []string{"method (example.com/m.Square).Area", "method (*example.com/m.Circle).Area"}, names(found))
+	assert.Equal(t, Position{Line: 16, Character: 17}, found[1].Location.Range.Start)
+
+	// The interfaces a type implements, in its package and its dependencies
+	assert.Subset(t, names(implementations(8, 6)), []string{"interface example.com/m.Shape", "interface fmt.Stringer"})
+	found = implementations(16, 18)
+	assert.Equal(t, []string{"method (example.com/m.Shape).Area"}, names(found))
+	assert.Equal(t, Position{Line: 5, Character: 1}, found[0].Location.Range.Start)
+	assert.Empty(t, implementations(18, 6))
+
+	tests := []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{"not a type", "uri=shapes.go&line=2&character=8", http.StatusBadRequest},
+		{"missing document", "uri=missing.go&line=0&character=0", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/implementations?"+tt.query, nil))
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package mcp
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ImportCycle is a cycle of imports between packages of a module, which the
+// go command refuses to build
+type ImportCycle struct {
+	Packages []string `json:"packages"`       // The cycle, ending with the package it starts with
+	Test     bool     `json:"test,omitempty"` // Through an import of the test files of the first package
+}
+
+// NearCycle is an import of the external tests of a package that would
+// close a cycle were the package itself, or its internal tests, to make it
+type NearCycle struct {
+	Package string   `json:"package"`
+	Import  string   `json:"import"`
+	Cycle   []string `json:"cycle"` // The cycle the import would close
+}
+
+// ImportCycleReport contains the import cycles and near cycles of the
+// packages of a module
+type ImportCycleReport struct {
+	Module     string        `json:"module"`
+	Pattern    string        `json:"pattern"`
+	Cycles     []ImportCycle `json:"cycles"`
+	NearCycles []NearCycle   `json:"near_cycles"`
+}
+
+// AnalyzeImportCycles finds the import cycles between the packages of the
+// module in root involving packages matching pattern, and the near cycles:
+// packages whose external tests import packages that import them, which
+// would cycle if the package imported them. Cycles are the shortest paths
+// of imports from packages back to themselves, each reported once. Imports
+// are read from the files of the packages, as the go command breaks cycles
+// in the imports it lists.
+func AnalyzeImportCycles(root, pattern string) (*ImportCycleReport, error) {
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedModule,
+		Dir:   root,
+		Tests: true,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %v", err)
+	}
+
+	report := &ImportCycleReport{Pattern: pattern, Cycles: []ImportCycle{}, NearCycles: []NearCycle{}}
+	fset := token.NewFileSet()
+	imports := func(files []string) (map[string]bool, error) {
+		paths := make(map[string]bool)
+		for _, name := range files {
+			file, err := parser.ParseFile(fset, name, nil, parser.ImportsOnly)
+			if err != nil {
+				return nil, err
+			}
+			for _, imp := range file.Imports {
+				paths[importPath(imp)] = true
+			}
+		}
+		return paths, nil
+	}
+
+	// Packages import what their files do; the variants of packages with
+	// their tests add the imports of their internal test files, and
+	// external test packages are named after the package they test
+	graph := make(map[string][]string)
+	testImports := make(map[string]map[string]bool)
+	externalImports := make(map[string]map[string]bool)
+	nonTestFiles := make(map[string][]string)
+	var variants []*packages.Package
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			if e.Kind == packages.ListError && !strings.Contains(e.Msg, "import cycle not allowed") {
+				return nil, fmt.Errorf("failed to load package %s: %v", pkg.PkgPath, e)
+			}
+		}
+		if pkg.Module == nil || !pkg.Module.Main || strings.HasSuffix(pkg.PkgPath, ".test") {
+			continue
+		}
+		report.Module = pkg.Module.Path
+		switch {
+		case strings.HasSuffix(pkg.PkgPath, "_test"):
+			paths, err := imports(pkg.GoFiles)
+			if err != nil {
+				return nil, err
+			}
+			externalImports[strings.TrimSuffix(pkg.PkgPath, "_test")] = paths
+		case pkg.ID != pkg.PkgPath:
+			variants = append(variants, pkg)
+		default:
+			paths, err := imports(pkg.GoFiles)
+			if err != nil {
+				return nil, err
+			}
+			graph[pkg.PkgPath] = []string{}
+			for path := range paths {
+				graph[pkg.PkgPath] = append(graph[pkg.PkgPath], path)
+			}
+			nonTestFiles[pkg.PkgPath] = pkg.GoFiles
+		}
+	}
+	if report.Module == "" {
+		return nil, fmt.Errorf("no packages of the main module in %s", root)
+	}
+	for _, pkg := range variants {
+		if _, ok := graph[pkg.PkgPath]; !ok {
+			continue
+		}
+		var testFiles []string
+		for _, name := range pkg.GoFiles {
+			if !containsString(nonTestFiles[pkg.PkgPath], name) {
+				testFiles = append(testFiles, name)
+			}
+		}
+		paths, err := imports(testFiles)
+		if err != nil {
+			return nil, err
+		}
+		testImports[pkg.PkgPath] = paths
+	}
+
+	// Only imports of packages of the module can cycle
+	for from, imports := range graph {
+		var internal []string
+		for _, to := range imports {
+			if _, ok := graph[to]; ok {
+				internal = append(internal, to)
+			}
+		}
+		sort.Strings(internal)
+		graph[from] = internal
+	}
+
+	matcher := packagePatternRegexp(pattern, report.Module)
+	matches := func(cycle []string) bool {
+		for _, path := range cycle {
+			if matcher.MatchString(path) {
+				return true
+			}
+		}
+		return false
+	}
+	paths := make([]string, 0, len(graph))
+	for path := range graph {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		// The shortest cycle back to the package, unless found from
+		// another package of it
+		if cycle := shortestImportPath(graph, graph[path], path); cycle != nil && matches(cycle) {
+			cycle = append([]string{path}, cycle...)
+			if key := cycleKey(cycle); !seen[key] {
+				seen[key] = true
+				report.Cycles = append(report.Cycles, ImportCycle{Packages: cycle})
+			}
+		}
+
+		// Imports of the internal tests of the package cycle, those of
+		// its external tests would if the package made them
+		for i, test := range []map[string]bool{testImports[path], externalImports[path]} {
+			var imported []string
+			for imp := range test {
+				if _, ok := graph[imp]; ok && imp != path && !containsString(graph[path], imp) {
+					imported = append(imported, imp)
+				}
+			}
+			sort.Strings(imported)
+			for _, imp := range imported {
+				cycle := shortestImportPath(graph, []string{imp}, path)
+				if cycle == nil || !matches(cycle) {
+					continue
+				}
+				cycle = append([]string{path}, cycle...)
+				if i == 0 {
+					report.Cycles = append(report.Cycles, ImportCycle{Packages: cycle, Test: true})
+				} else {
+					report.NearCycles = append(report.NearCycles, NearCycle{Package: path, Import: imp, Cycle: cycle})
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+// shortestImportPath returns the shortest path of imports of graph from one
+// of the packages from to the package to, starting with the first, or nil
+// if none reaches it
+func shortestImportPath(graph map[string][]string, from []string, to string) []string {
+	parent := make(map[string]string)
+	queue := make([]string, 0, len(from))
+	for _, path := range from {
+		if _, seen := parent[path]; !seen {
+			parent[path] = ""
+			queue = append(queue, path)
+		}
+	}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		if path == to {
+			var cycle []string
+			for ; path != ""; path = parent[path] {
+				cycle = append([]string{path}, cycle...)
+			}
+			return cycle
+		}
+		for _, next := range graph[path] {
+			if _, seen := parent[next]; !seen {
+				parent[next] = path
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
+
+// cycleKey identifies a cycle whichever package it starts with
+func cycleKey(cycle []string) string {
+	cycle = cycle[:len(cycle)-1]
+	first := 0
+	for i, path := range cycle {
+		if path < cycle[first] {
+			first = i
+		}
+	}
+	return strings.Join(append(append([]string(nil), cycle[first:]...), cycle[:first]...), " ")
+}
+
+func handleImportCycles(root string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pattern := r.URL.Query().Get("pattern")
+		if pattern == "" {
+			pattern = "./..."
+		}
+		if !localPattern(pattern) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("pattern must be a package path or relative to the workspace"))
+			return
+		}
+
+		report, err := AnalyzeImportCycles(root, pattern)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	}
+}
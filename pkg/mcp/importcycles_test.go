@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleImportCycles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":               "module example.com/m\n\ngo 1.22\n",
+		"a/a.go":               "package a\n\nimport _ \"example.com/m/b\"\n",
+		"b/b.go":               "package b\n\nimport _ \"example.com/m/c\"\n",
+		"c/c.go":               "package c\n\nimport (\n\t_ \"fmt\"\n\n\t_ \"example.com/m/a\"\n)\n",
+		"store/store.go":       "package store\n",
+		"store/store_test.go":  "package store\n\nimport _ \"example.com/m/api\"\n",
+		"store/export_test.go": "package store_test\n\nimport _ \"example.com/m/handler\"\n",
+		"api/api.go":           "package api\n\nimport _ \"example.com/m/store\"\n",
+		"handler/handler.go":   "package handler\n\nimport _ \"example.com/m/api\"\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddAnalysisHandler()
+
+	get := func(query string) *ImportCycleReport {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/imports/cycles?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var report ImportCycleReport
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		return &report
+	}
+
+	// Cycles are reported once, from their first package
+	report := get("")
+	assert.Equal(t, "example.com/m", report.Module)
+	assert.Equal(t, []ImportCycle{
+		{Packages: []string{"example.com/m/a", "example.com/m/b", "example.com/m/c", "example.com/m/a"}},
+		{Packages: []string{"example.com/m/store", "example.com/m/api", "example.com/m/store"}, Test: true},
+	}, report.Cycles)
+	assert.Equal(t, []NearCycle{{
+		Package: "example.com/m/store",
+		Import:  "example.com/m/handler",
+		Cycle:   []string{"example.com/m/store", "example.com/m/handler", "example.com/m/api", "example.com/m/store"},
+	}}, report.NearCycles)
+
+	report = get("pattern=./handler")
+	assert.Empty(t, report.Cycles)
+	assert.Len(t, report.NearCycles, 1)
+
+	report = get("pattern=./b/...")
+	require.Len(t, report.Cycles, 1)
+	assert.Empty(t, report.NearCycles)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/imports/cycles?pattern=../other", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCycleKey(t *testing.T) {
+	assert.Equal(t, cycleKey([]string{"b", "c", "a", "b"}), cycleKey([]string{"a", "b", "c", "a"}))
+	assert.NotEqual(t, cycleKey([]string{"a", "c", "b", "a"}), cycleKey([]string{"a", "b", "c", "a"}))
+}
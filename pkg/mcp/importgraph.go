@@ -0,0 +1,299 @@
+package mcp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Kinds of packages of an import graph
+const (
+	PackageInternal = "internal" // Of the main module
+	PackageExternal = "external" // Of another module
+	PackageStd      = "std"      // Of the standard library
+)
+
+// AnalysisConfig configures the analysis of the workspace
+type AnalysisConfig struct {
+	Layers     []LayerRule      `yaml:"layers"` // Imports forbidden between layers
+	Complexity ComplexityConfig `yaml:"complexity"`
+}
+
+// LayerRule forbids the packages matching From to import the packages
+// matching Deny, except those matching Allow. Patterns are import paths in
+// which ... matches any string, like example.com/m/pkg/...; a leading ./
+// stands for the path of the main module.
+type LayerRule struct {
+	Name  string   `yaml:"name" json:"name"`
+	From  string   `yaml:"from" json:"from"`
+	Deny  []string `yaml:"deny" json:"deny"`
+	Allow []string `yaml:"allow" json:"allow,omitempty"`
+}
+
+// Validate checks the analysis configuration for obvious mistakes
+func (c AnalysisConfig) Validate() error {
+	names := make(map[string]bool)
+	for _, rule := range c.Layers {
+		if rule.Name == "" {
+			return fmt.Errorf("layer rule without name")
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate layer rule %q", rule.Name)
+		}
+		names[rule.Name] = true
+		if rule.From == "" || len(rule.Deny) == 0 {
+			return fmt.Errorf("layer rule %s: from and deny are required", rule.Name)
+		}
+	}
+	if err := c.Complexity.Validate(); err != nil {
+		return fmt.Errorf("complexity: %v", err)
+	}
+	return nil
+}
+
+// ImportGraphPackage is a package of an import graph with the packages it
+// imports
+type ImportGraphPackage struct {
+	Path    string   `json:"path"`
+	Kind    string   `json:"kind"`              // internal, external or std
+	Module  string   `json:"module,omitempty"`  // Path of the module of external packages
+	Imports []string `json:"imports,omitempty"` // Only for internal packages
+}
+
+// LayerViolation is an import forbidden by a layer rule
+type LayerViolation struct {
+	Rule string `json:"rule"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ImportGraph is the import graph of the packages of a module matching a
+// pattern: the packages, the packages they import and the imports breaking
+// layer rules
+type ImportGraph struct {
+	Module     string               `json:"module"`
+	Pattern    string               `json:"pattern"`
+	Packages   []ImportGraphPackage `json:"packages"`
+	Violations []LayerViolation     `json:"violations"`
+}
+
+// AnalyzeImportGraph builds the import graph of the packages matching
+// pattern in root, with their test files, and checks their imports against
+// rules. Packages of the standard library are left out unless std is set.
+func AnalyzeImportGraph(root, pattern string, rules []LayerRule, std bool) (*ImportGraph, error) {
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedImports | packages.NeedModule,
+		Dir:   root,
+		Tests: true,
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %v", err)
+	}
+
+	graph := &ImportGraph{Pattern: pattern, Packages: []ImportGraphPackage{}, Violations: []LayerViolation{}}
+	nodes := make(map[string]*ImportGraphPackage)
+	add := func(pkg *packages.Package) *ImportGraphPackage {
+		if node, ok := nodes[pkg.PkgPath]; ok {
+			return node
+		}
+		node := &ImportGraphPackage{Path: pkg.PkgPath, Kind: PackageStd}
+		switch {
+		case pkg.Module != nil && pkg.Module.Main:
+			node.Kind = PackageInternal
+			graph.Module = pkg.Module.Path
+		case pkg.Module != nil:
+			node.Kind, node.Module = PackageExternal, pkg.Module.Path
+		}
+		nodes[pkg.PkgPath] = node
+		return node
+	}
+
+	// Test variants and test mains share the path of the package, or of
+	// its external test package
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			if e.Kind == packages.ListError {
+				return nil, fmt.Errorf("failed to load package %s: %v", pkg.PkgPath, e)
+			}
+		}
+		if strings.HasSuffix(pkg.PkgPath, ".test") {
+			continue
+		}
+		from := add(pkg)
+		for _, imp := range pkg.Imports {
+			to := add(imp)
+			if to.Kind == PackageStd && !std {
+				continue
+			}
+			if !containsString(from.Imports, to.Path) {
+				from.Imports = append(from.Imports, to.Path)
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no packages match %s", pattern)
+	}
+
+	matchers := make([]layerMatcher, len(rules))
+	for i, rule := range rules {
+		matchers[i] = newLayerMatcher(rule, graph.Module)
+	}
+	for _, node := range nodes {
+		if node.Kind == PackageStd && !std {
+			continue
+		}
+		sort.Strings(node.Imports)
+		graph.Packages = append(graph.Packages, *node)
+		for _, imp := range node.Imports {
+			for _, m := range matchers {
+				if m.forbids(node.Path, imp) {
+					graph.Violations = append(graph.Violations, LayerViolation{Rule: m.name, From: node.Path, To: imp})
+				}
+			}
+		}
+	}
+	sort.Slice(graph.Packages, func(i, j int) bool {
+		return graph.Packages[i].Path < graph.Packages[j].Path
+	})
+	sort.Slice(graph.Violations, func(i, j int) bool {
+		a, b := graph.Violations[i], graph.Violations[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Rule < b.Rule
+	})
+	return graph, nil
+}
+
+// layerMatcher matches imports against a layer rule
+type layerMatcher struct {
+	name        string
+	from        *regexp.Regexp
+	deny, allow []*regexp.Regexp
+}
+
+func newLayerMatcher(rule LayerRule, module string) layerMatcher {
+	m := layerMatcher{name: rule.Name, from: packagePatternRegexp(rule.From, module)}
+	for _, pattern := range rule.Deny {
+		m.deny = append(m.deny, packagePatternRegexp(pattern, module))
+	}
+	for _, pattern := range rule.Allow {
+		m.allow = append(m.allow, packagePatternRegexp(pattern, module))
+	}
+	return m
+}
+
+// forbids reports whether the rule forbids package from importing to
+func (m layerMatcher) forbids(from, to string) bool {
+	if !m.from.MatchString(from) || !matchAny(m.deny, to) {
+		return false
+	}
+	return !matchAny(m.allow, to)
+}
+
+func matchAny(patterns []*regexp.Regexp, path string) bool {
+	for _, re := range patterns {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// packagePatternRegexp compiles a package pattern as the go command does:
+// ... matches any string, and a trailing /... also matches the path before
+// it. A leading ./ is replaced by the module path.
+func packagePatternRegexp(pattern, module string) *regexp.Regexp {
+	if pattern == "." || strings.HasPrefix(pattern, "./") {
+		pattern = module + strings.TrimPrefix(pattern, ".")
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\.\.\.`, `.*`)
+	if strings.HasSuffix(expr, `/.*`) {
+		expr = strings.TrimSuffix(expr, `/.*`) + `(/.*)?`
+	}
+	return regexp.MustCompile(`^` + expr + `$`)
+}
+
+// writeImportGraphDOT writes an import graph in the DOT language of
+// Graphviz. Packages of other modules are ellipses, those of the standard
+// library are gray, and imports breaking layer rules are red.
+func writeImportGraphDOT(w io.Writer, graph *ImportGraph) error {
+	var b strings.Builder
+	b.WriteString("digraph imports {\n\trankdir=LR;\n\tnode [shape=box];\n")
+
+	violations := make(map[[2]string][]string)
+	for _, v := range graph.Violations {
+		key := [2]string{v.From, v.To}
+		violations[key] = append(violations[key], v.Rule)
+	}
+	for _, pkg := range graph.Packages {
+		label := strings.TrimPrefix(strings.TrimPrefix(pkg.Path, graph.Module), "/")
+		if label == "" || pkg.Kind != PackageInternal {
+			label = pkg.Path
+		}
+		fmt.Fprintf(&b, "\t%s [label=%s", strconv.Quote(pkg.Path), strconv.Quote(label))
+		switch pkg.Kind {
+		case PackageExternal:
+			b.WriteString(", shape=ellipse")
+		case PackageStd:
+			b.WriteString(", shape=ellipse, color=gray, fontcolor=gray")
+		}
+		b.WriteString("];\n")
+	}
+	for _, pkg := range graph.Packages {
+		for _, imp := range pkg.Imports {
+			fmt.Fprintf(&b, "\t%s -> %s", strconv.Quote(pkg.Path), strconv.Quote(imp))
+			if rules, ok := violations[[2]string{pkg.Path, imp}]; ok {
+				fmt.Fprintf(&b, " [color=red, label=%s]", strconv.Quote(strings.Join(rules, ", ")))
+			}
+			b.WriteString(";\n")
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func handleImportGraph(root string, rules []LayerRule) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		pattern := q.Get("pattern")
+		if pattern == "" {
+			pattern = "./..."
+		}
+		if !localPattern(pattern) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("pattern must be a package path or relative to the workspace"))
+			return
+		}
+		format := q.Get("format")
+		if format != "" && format != "json" && format != "dot" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("format must be json or dot"))
+			return
+		}
+
+		graph, err := AnalyzeImportGraph(root, pattern, rules, q.Get("std") == "true")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if format == "dot" {
+			w.Header().Set("Content-Type", "text/vnd.graphviz")
+			writeImportGraphDOT(w, graph)
+			return
+		}
+		writeJSON(w, http.StatusOK, graph)
+	}
+}
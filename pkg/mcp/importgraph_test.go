@@ -0,0 +1,136 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleImportGraph(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":           "module example.com/m\n\ngo 1.22\n\nrequire example.com/ext v0.0.0\n\nreplace example.com/ext => ./ext\n",
+		"api/api.go":       "package api\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/m/store\"\n\t\"example.com/m/store/cache\"\n)\n\nvar _ = fmt.Sprint(store.Get, cache.Get)\n",
+		"api/api_test.go":  "package api_test\n\nimport (\n\t\"testing\"\n\n\t\"example.com/m/api\"\n)\n\nfunc TestAPI(t *testing.T) { _ = api.Version }\n",
+		"api/version.go":   "package api\n\nconst Version = 1\n",
+		"store/store.go":   "package store\n\nimport \"example.com/ext\"\n\nfunc Get() int { return ext.Value }\n",
+		"store/cache/c.go": "package cache\n\nfunc Get() int { return 0 }\n",
+		"ext/go.mod":       "module example.com/ext\n\ngo 1.22\n",
+		"ext/ext.go":       "package ext\n\nconst Value = 1\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	cfg.Analysis.Layers = []LayerRule{
+		{Name: "api-no-store", From: "./api/...", Deny: []string{"./store/..."}, Allow: []string{"./store/cache"}},
+		{Name: "no-ext", From: "./...", Deny: []string{"example.com/ext/..."}},
+	}
+	require.NoError(t, cfg.Validate())
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddAnalysisHandler()
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/imports?"+query, nil))
+		return w
+	}
+
+	w := get("")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var graph ImportGraph
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&graph))
+	assert.Equal(t, "example.com/m", graph.Module)
+	assert.Equal(t, []ImportGraphPackage{
+		{Path: "example.com/ext", Kind: PackageExternal, Module: "example.com/ext"},
+		{Path: "example.com/m/api", Kind: PackageInternal, Imports: []string{"example.com/m/store", "example.com/m/store/cache"}},
+		{Path: "example.com/m/api_test", Kind: PackageInternal, Imports: []string{"example.com/m/api"}},
+		{Path: "example.com/m/store", Kind: PackageInternal, Imports: []string{"example.com/ext"}},
+		{Path: "example.com/m/store/cache", Kind: PackageInternal},
+	}, graph.Packages)
+	assert.Equal(t, []LayerViolation{
+		{Rule: "api-no-store", From: "example.com/m/api", To: "example.com/m/store"},
+		{Rule: "no-ext", From: "example.com/m/store", To: "example.com/ext"},
+	}, graph.Violations)
+
+	// The standard library on request
+	w = get("pattern=./api&std=true")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	graph = ImportGraph{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&graph))
+	var fmtPkg *ImportGraphPackage
+	for i, pkg := range graph.Packages {
+		if pkg.Path == "fmt" {
+			fmtPkg = &graph.Packages[i]
+		}
+	}
+	require.NotNil(t, fmtPkg)
+	assert.Equal(t, PackageStd, fmtPkg.Kind)
+	assert.Empty(t, fmtPkg.Imports)
+
+	w = get("format=dot")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/vnd.graphviz", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "\"example.com/m/api\" [label=\"api\"];")
+	assert.Contains(t, w.Body.String(), "\"example.com/ext\" [label=\"example.com/ext\", shape=ellipse];")
+	assert.Contains(t, w.Body.String(), "\"example.com/m/api\" -> \"example.com/m/store\" [color=red, label=\"api-no-store\"];")
+	assert.Contains(t, w.Body.String(), "\"example.com/m/api\" -> \"example.com/m/store/cache\";")
+
+	for _, query := range []string{"format=svg", "pattern=../...", "pattern=./missing"} {
+		assert.Equal(t, http.StatusBadRequest, get(query).Code, query)
+	}
+}
+
+func TestPackagePatternRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"./...", "example.com/m", true},
+		{"./...", "example.com/m/a/b", true},
+		{"./...", "example.com/mm", false},
+		{"./api/...", "example.com/m/api", true},
+		{"./api/...", "example.com/m/apis", false},
+		{".", "example.com/m", true},
+		{"net/...", "net/http", true},
+		{"example.com/m/.../internal", "example.com/m/a/b/internal", true},
+		{"example.com/m/.../internal", "example.com/m/a/internal/x", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, packagePatternRegexp(tt.pattern, "example.com/m").MatchString(tt.path), "%s %s", tt.pattern, tt.path)
+	}
+}
+
+func TestAnalysisConfigValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []LayerRule
+		ok    bool
+	}{
+		{"valid", []LayerRule{{Name: "a", From: "./a/...", Deny: []string{"./b/..."}}}, true},
+		{"without name", []LayerRule{{From: "./a/...", Deny: []string{"./b/..."}}}, false},
+		{"duplicate", []LayerRule{{Name: "a", From: "./a", Deny: []string{"./b"}}, {Name: "a", From: "./c", Deny: []string{"./d"}}}, false},
+		{"without deny", []LayerRule{{Name: "a", From: "./a/..."}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := AnalysisConfig{Layers: tt.rules}.Validate()
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
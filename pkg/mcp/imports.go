@@ -0,0 +1,295 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Import group names
+const (
+	ImportGroupStdlib   = "stdlib"
+	ImportGroupExternal = "external"
+	ImportGroupInternal = "internal"
+)
+
+// ImportGroupingOptions configures how an import block is organized
+type ImportGroupingOptions struct {
+	// Groups lists the group order; defaults to stdlib, external, internal
+	Groups []string `json:"groups,omitempty"`
+	// LocalPrefixes are import path prefixes treated as internal; defaults
+	// to the workspace module path
+	LocalPrefixes []string `json:"local_prefixes,omitempty"`
+	// KeepUnused disables removal of imports the type checker finds unused
+	KeepUnused bool `json:"keep_unused,omitempty"`
+}
+
+// OrganizeImportsRequest asks for a document's imports to be organized
+type OrganizeImportsRequest struct {
+	URI     string                `json:"uri"`
+	Content string                `json:"content,omitempty"` // Falls back to the open document's text
+	Options ImportGroupingOptions `json:"options"`
+}
+
+// OrganizeImportsResult contains the edits produced by organizing imports
+type OrganizeImportsResult struct {
+	Edit    WorkspaceEdit `json:"edit"`
+	Removed []string      `json:"removed"`
+}
+
+type importEntry struct {
+	path string
+	text string
+}
+
+// OrganizeImports regroups and sorts the import declarations of a Go source
+// file and removes unused imports, returning the edits needed to get there
+func OrganizeImports(uri, content string, opts ImportGroupingOptions) (*OrganizeImportsResult, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, uri, content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse document: %v", err)
+	}
+
+	result := &OrganizeImportsResult{
+		Edit:    WorkspaceEdit{Changes: map[string][]TextEdit{uri: {}}},
+		Removed: make([]string, 0),
+	}
+
+	var decls []*ast.GenDecl
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		decls = append(decls, gen)
+	}
+	if len(decls) == 0 {
+		return result, nil
+	}
+
+	for _, imp := range file.Imports {
+		if importPath(imp) == "C" {
+			return nil, fmt.Errorf("files importing \"C\" are not supported")
+		}
+	}
+
+	unused := make(map[*ast.ImportSpec]bool)
+	if !opts.KeepUnused {
+		unused = findUnusedImports(fset, file)
+	}
+
+	groups := opts.Groups
+	if len(groups) == 0 {
+		groups = []string{ImportGroupStdlib, ImportGroupExternal, ImportGroupInternal}
+	}
+
+	grouped := make(map[string][]importEntry)
+	seen := make(map[string]bool)
+	for _, imp := range file.Imports {
+		path := importPath(imp)
+		if unused[imp] {
+			result.Removed = append(result.Removed, path)
+			continue
+		}
+
+		text := specText(fset, content, imp)
+		if seen[text] {
+			continue
+		}
+		seen[text] = true
+
+		group := classifyImport(path, opts.LocalPrefixes)
+		grouped[group] = append(grouped[group], importEntry{path: path, text: text})
+	}
+
+	var blocks []string
+	for _, group := range groups {
+		entries := grouped[group]
+		if len(entries) == 0 {
+			continue
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].path < entries[j].path
+		})
+
+		lines := make([]string, len(entries))
+		for i, e := range entries {
+			lines[i] = "\t" + e.text
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+
+	var newText string
+	switch count := len(file.Imports) - len(result.Removed); {
+	case count == 0:
+		newText = ""
+	case count == 1 && !strings.Contains(blocks[0], "//"):
+		newText = "import " + strings.TrimPrefix(blocks[0], "\t")
+	default:
+		newText = "import (\n" + strings.Join(blocks, "\n\n") + "\n)"
+	}
+
+	start := fset.Position(decls[0].Pos()).Offset
+	if decls[0].Doc != nil {
+		start = fset.Position(decls[0].Doc.Pos()).Offset
+		newText = content[start:fset.Position(decls[0].Pos()).Offset] + newText
+	}
+	end := fset.Position(decls[len(decls)-1].End()).Offset
+	if newText == "" {
+		// Swallow the blank line left behind by the removed block
+		for end < len(content) && content[end] == '\n' {
+			end++
+		}
+	}
+
+	if content[start:end] == newText {
+		return result, nil
+	}
+
+	result.Edit.Changes[uri] = []TextEdit{{
+		Range: Range{
+			Start: positionAt(content, start),
+			End:   positionAt(content, end),
+		},
+		NewText: newText,
+	}}
+
+	return result, nil
+}
+
+// findUnusedImports type-checks the file and returns the imports whose
+// package name is never referenced
+func findUnusedImports(fset *token.FileSet, file *ast.File) map[*ast.ImportSpec]bool {
+	info := &types.Info{
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Implicits: make(map[ast.Node]types.Object),
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {}, // Keep going; we only need the resolved identifiers
+	}
+	conf.Check(file.Name.Name, fset, []*ast.File{file}, info)
+
+	used := make(map[types.Object]bool)
+	for _, obj := range info.Uses {
+		if pkgName, ok := obj.(*types.PkgName); ok {
+			used[pkgName] = true
+		}
+	}
+
+	unused := make(map[*ast.ImportSpec]bool)
+	for _, imp := range file.Imports {
+		if imp.Name != nil && (imp.Name.Name == "_" || imp.Name.Name == ".") {
+			continue
+		}
+
+		var obj types.Object
+		if imp.Name != nil {
+			obj = info.Defs[imp.Name]
+		} else {
+			obj = info.Implicits[imp]
+		}
+		if obj != nil && !used[obj] {
+			unused[imp] = true
+		}
+	}
+
+	return unused
+}
+
+// classifyImport assigns an import path to the stdlib, external or internal group
+func classifyImport(path string, localPrefixes []string) string {
+	for _, prefix := range localPrefixes {
+		if path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/") {
+			return ImportGroupInternal
+		}
+	}
+
+	first := strings.SplitN(path, "/", 2)[0]
+	if !strings.Contains(first, ".") {
+		return ImportGroupStdlib
+	}
+	return ImportGroupExternal
+}
+
+// specText returns the source text of an import spec, including any
+// trailing line comment
+func specText(fset *token.FileSet, content string, imp *ast.ImportSpec) string {
+	end := imp.End()
+	if imp.Comment != nil {
+		end = imp.Comment.End()
+	}
+	return content[fset.Position(imp.Pos()).Offset:fset.Position(end).Offset]
+}
+
+func importPath(imp *ast.ImportSpec) string {
+	path, err := strconv.Unquote(imp.Path.Value)
+	if err != nil {
+		return strings.Trim(imp.Path.Value, "\"`")
+	}
+	return path
+}
+
+// modulePath reads the module path from the go.mod file in root
+func modulePath(root string) string {
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, []byte("module ")) {
+			return strings.Trim(string(bytes.TrimSpace(line[len("module "):])), "\"")
+		}
+	}
+	return ""
+}
+
+func handleOrganizeImports(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req OrganizeImportsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if req.Content == "" {
+			ls.mu.RLock()
+			doc, exists := ls.documents[req.URI]
+			ls.mu.RUnlock()
+
+			if !exists {
+				writeError(w, http.StatusNotFound, fmt.Errorf("document not found"))
+				return
+			}
+			req.Content = doc.Text
+		}
+
+		if len(req.Options.LocalPrefixes) == 0 {
+			if mod := modulePath(ls.workspaceRoot); mod != "" {
+				req.Options.LocalPrefixes = []string{mod}
+			}
+		}
+
+		result, err := OrganizeImports(req.URI, req.Content, req.Options)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, result)
+	}
+}
@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func applyEdits(content string, edits []TextEdit) string {
+	// Apply from the end so earlier offsets stay valid
+	for i := len(edits) - 1; i >= 0; i-- {
+		start := offsetOf(content, edits[i].Range.Start)
+		end := offsetOf(content, edits[i].Range.End)
+		content = content[:start] + edits[i].NewText + content[end:]
+	}
+	return content
+}
+
+func offsetOf(content string, pos Position) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		offset += strings.IndexByte(content[offset:], '\n') + 1
+	}
+	return offset + pos.Character
+}
+
+func TestOrganizeImports(t *testing.T) {
+	src := `package example
+
+import "github.com/ivikasavnish/go-mcp/pkg/ide"
+import (
+	"strings"
+	"github.com/gorilla/mux"
+	"fmt" // printing
+	"os"
+)
+
+func f() {
+	fmt.Println(strings.ToUpper("x"), mux.NewRouter(), ide.NewFileManager("."))
+}
+`
+
+	result, err := OrganizeImports("example.go", src, ImportGroupingOptions{
+		LocalPrefixes: []string{"github.com/ivikasavnish/go-mcp"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"os"}, result.Removed)
+
+	edits := result.Edit.Changes["example.go"]
+	require.Len(t, edits, 1)
+
+	expected := `package example
+
+import (
+	"fmt" // printing
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+)
+
+func f() {
+	fmt.Println(strings.ToUpper("x"), mux.NewRouter(), ide.NewFileManager("."))
+}
+`
+	assert.Equal(t, expected, applyEdits(src, edits))
+}
+
+func TestOrganizeImports_AlreadyOrganized(t *testing.T) {
+	src := `package example
+
+import "fmt"
+
+func f() { fmt.Println() }
+`
+
+	result, err := OrganizeImports("example.go", src, ImportGroupingOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, result.Edit.Changes["example.go"])
+	assert.Empty(t, result.Removed)
+}
+
+func TestClassifyImport(t *testing.T) {
+	assert.Equal(t, ImportGroupStdlib, classifyImport("net/http", nil))
+	assert.Equal(t, ImportGroupExternal, classifyImport("golang.org/x/tools/go/ast/astutil", nil))
+	assert.Equal(t, ImportGroupInternal, classifyImport("example.com/app/pkg", []string{"example.com/app"}))
+	assert.Equal(t, ImportGroupExternal, classifyImport("example.com/application", []string{"example.com/app"}))
+}
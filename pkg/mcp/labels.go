@@ -0,0 +1,387 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bulk operations on the contexts matching a label selector
+const (
+	BulkDelete    = "delete"    // Delete the contexts
+	BulkExport    = "export"    // Return the contexts, ready to be created elsewhere
+	BulkSummarize = "summarize" // Recompute the summary metadata, written by the host LLM over MCP
+)
+
+var (
+	labelKeyPattern   = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/-]*$`)
+	labelValuePattern = regexp.MustCompile(`^[a-zA-Z0-9._/-]*$`)
+)
+
+var (
+	// ErrNoContextsSelected is returned for bulk requests without IDs or
+	// selector, which would otherwise apply to every context
+	ErrNoContextsSelected = errors.New("no ids or selector given")
+	ErrUnknownOperation   = errors.New("unknown bulk operation")
+)
+
+// validateLabels checks label keys and values. Tags are labels with an
+// empty value.
+func validateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if !labelKeyPattern.MatchString(k) {
+			return fmt.Errorf("%w: key %q", ErrInvalidLabels, k)
+		}
+		if !labelValuePattern.MatchString(v) {
+			return fmt.Errorf("%w: value %q of %s", ErrInvalidLabels, v, k)
+		}
+	}
+	return nil
+}
+
+// LabelSelector picks contexts by their labels. It is parsed from
+// comma-separated requirements that must all hold:
+//
+//	key=value   the label has the value
+//	key!=value  the label is missing or has another value
+//	key         the label is set, with any value
+//	!key        the label is missing
+type LabelSelector []labelRequirement
+
+type labelRequirement struct {
+	key   string
+	value string
+	op    string // =, !=, exists or !exists
+}
+
+// ParseLabelSelector parses a selector such as "team=payments,!deprecated".
+// The empty selector matches every context.
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	var sel LabelSelector
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var req labelRequirement
+		switch {
+		case strings.Contains(part, "!="):
+			req.key, req.value, _ = strings.Cut(part, "!=")
+			req.op = "!="
+		case strings.Contains(part, "="):
+			req.key, req.value, _ = strings.Cut(part, "=")
+			req.value = strings.TrimPrefix(req.value, "=")
+			req.op = "="
+		case strings.HasPrefix(part, "!"):
+			req.key, req.op = part[1:], "!exists"
+		default:
+			req.key, req.op = part, "exists"
+		}
+		req.key, req.value = strings.TrimSpace(req.key), strings.TrimSpace(req.value)
+		if !labelKeyPattern.MatchString(req.key) || !labelValuePattern.MatchString(req.value) {
+			return nil, fmt.Errorf("%w: invalid selector requirement %q", ErrInvalidLabels, part)
+		}
+		sel = append(sel, req)
+	}
+	return sel, nil
+}
+
+// Matches reports whether labels meet every requirement of the selector
+func (sel LabelSelector) Matches(labels map[string]string) bool {
+	for _, req := range sel {
+		value, ok := labels[req.key]
+		switch req.op {
+		case "=":
+			if !ok || value != req.value {
+				return false
+			}
+		case "!=":
+			if ok && value == req.value {
+				return false
+			}
+		case "exists":
+			if !ok {
+				return false
+			}
+		case "!exists":
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// LabelContextsRequest adds and removes labels of the contexts named by IDs
+// and those matching Selector
+type LabelContextsRequest struct {
+	IDs      []string          `json:"ids,omitempty"`
+	Selector string            `json:"selector,omitempty"`
+	Add      map[string]string `json:"add,omitempty"`    // Tags have empty values
+	Remove   []string          `json:"remove,omitempty"` // Keys of labels to remove
+}
+
+// BulkContextRequest applies an operation to the contexts matching Selector
+type BulkContextRequest struct {
+	Selector  string `json:"selector"`
+	Operation string `json:"operation"` // delete, export or summarize
+}
+
+// BulkContextResult reports the contexts an operation applied to
+type BulkContextResult struct {
+	Operation string     `json:"operation"`
+	IDs       []string   `json:"ids"`
+	Contexts  []*Context `json:"contexts,omitempty"` // Exported or summarized contexts
+}
+
+// listContexts returns the contexts matching a selector, sorted by ID
+func (s *Server) listContexts(selector string) ([]*Context, error) {
+	sel, err := ParseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	contexts := make([]*Context, 0)
+	for _, c := range s.store.List() {
+		if sel.Matches(c.Labels) {
+			contexts = append(contexts, c)
+		}
+	}
+	sort.Slice(contexts, func(i, j int) bool { return contexts[i].ID < contexts[j].ID })
+	return contexts, nil
+}
+
+// labelContexts updates the labels of the selected contexts. Every named
+// context must exist before any is changed.
+func (s *Server) labelContexts(ctx context.Context, req LabelContextsRequest) ([]*Context, error) {
+	if len(req.IDs) == 0 && strings.TrimSpace(req.Selector) == "" {
+		return nil, ErrNoContextsSelected
+	}
+	if err := validateLabels(req.Add); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]*Context)
+	for _, id := range req.IDs {
+		c, err := s.store.Get(id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, id)
+		}
+		selected[id] = c
+	}
+	if strings.TrimSpace(req.Selector) != "" {
+		matches, err := s.listContexts(req.Selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range matches {
+			selected[c.ID] = c
+		}
+	}
+
+	updated := make([]*Context, 0, len(selected))
+	for _, c := range selected {
+		if c.Labels == nil {
+			c.Labels = make(map[string]string)
+		}
+		for k, v := range req.Add {
+			c.Labels[k] = v
+		}
+		for _, k := range req.Remove {
+			delete(c.Labels, k)
+		}
+		if len(c.Labels) == 0 {
+			c.Labels = nil
+		}
+		c.UpdatedAt = time.Now()
+		if err := s.updateContext(ctx, c); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.ID, err)
+		}
+		updated = append(updated, c)
+	}
+	sort.Slice(updated, func(i, j int) bool { return updated[i].ID < updated[j].ID })
+	return updated, nil
+}
+
+// bulkContexts applies an operation to the contexts matching a selector. An
+// empty selector is refused rather than matching every context.
+func (s *Server) bulkContexts(ctx context.Context, req BulkContextRequest) (*BulkContextResult, error) {
+	if strings.TrimSpace(req.Selector) == "" {
+		return nil, ErrNoContextsSelected
+	}
+	switch req.Operation {
+	case BulkDelete, BulkExport, BulkSummarize:
+	default:
+		return nil, fmt.Errorf("%w %q, use %s, %s or %s", ErrUnknownOperation, req.Operation, BulkDelete, BulkExport, BulkSummarize)
+	}
+	contexts, err := s.listContexts(req.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkContextResult{Operation: req.Operation, IDs: make([]string, 0, len(contexts))}
+	for _, c := range contexts {
+		switch req.Operation {
+		case BulkDelete:
+			// Contexts deleted meanwhile are gone either way
+			if err := s.deleteContext(c.ID); err != nil && !errors.Is(err, ErrContextNotFound) {
+				return result, fmt.Errorf("%s: %w", c.ID, err)
+			}
+		case BulkExport:
+			result.Contexts = append(result.Contexts, c)
+		case BulkSummarize:
+			c.Metadata["summary"] = sampleSummary(ctx, c)
+			c.UpdatedAt = time.Now()
+			if err := s.updateContext(ctx, c); err != nil {
+				return result, fmt.Errorf("%s: %w", c.ID, err)
+			}
+			result.Contexts = append(result.Contexts, c)
+		}
+		result.IDs = append(result.IDs, c.ID)
+	}
+	LoggerFromContext(ctx).Info("bulk context operation", "operation", req.Operation, "selector", req.Selector, "contexts", len(result.IDs))
+	return result, nil
+}
+
+// maxSampledContext bounds the metadata of a context sent to the host LLM
+const maxSampledContext = 32 << 10
+
+// sampleSummary asks the host LLM to summarize a context when the request
+// arrived over MCP. Without a host, or when sampling fails, the summary is
+// the outline of summarizeContext.
+func sampleSummary(ctx context.Context, c *Context) string {
+	outline := summarizeContext(c)
+	if _, ok := SamplerFromContext(ctx); !ok {
+		return outline
+	}
+	data, err := json.Marshal(c.Metadata)
+	if err != nil {
+		return outline
+	}
+	if len(data) > maxSampledContext {
+		data = data[:maxSampledContext]
+	}
+	summary, err := sampleText(ctx,
+		"You catalogue API specs and recorded traffic. Answer with two sentences and nothing else.",
+		fmt.Sprintf("Summarize what this context is about and what it is useful for. Outline: %s\n\nMetadata:\n%s", outline, data),
+		256)
+	if errors.Is(err, ErrSamplingUnavailable) {
+		return outline
+	}
+	if err != nil {
+		LoggerFromContext(ctx).Warn("sampling a context summary failed, using its outline", "context", c.ID, "error", err)
+		return outline
+	}
+	return strings.TrimSpace(summary)
+}
+
+// summarizeContext describes the content of a context in a line, from the
+// metadata the processors store for its type
+func summarizeContext(c *Context) string {
+	// Metadata stored through the API is decoded JSON, but contexts created
+	// in process may hold Go values
+	var metadata map[string]interface{}
+	if data, err := json.Marshal(c.Metadata); err == nil {
+		json.Unmarshal(data, &metadata)
+	}
+	contextType, _ := metadata["type"].(string)
+
+	switch contextType {
+	case "openapi":
+		spec, _ := metadata["spec"].(map[string]interface{})
+		info, _ := spec["info"].(map[string]interface{})
+		paths, _ := spec["paths"].(map[string]interface{})
+		operations := 0
+		for _, item := range paths {
+			ops, _ := item.(map[string]interface{})
+			for method := range ops {
+				if httpMethods[strings.ToUpper(method)] {
+					operations++
+				}
+			}
+		}
+		return fmt.Sprintf("OpenAPI spec %s %s: %d paths, %d operations", info["title"], info["version"], len(paths), operations)
+	case "postman":
+		collection, _ := metadata["collection"].(map[string]interface{})
+		info, _ := collection["info"].(map[string]interface{})
+		return fmt.Sprintf("Postman collection %s: %d requests", info["name"], countPostmanRequests(collection["item"]))
+	case "curl", "proxy":
+		collection, _ := metadata["collection"].(map[string]interface{})
+		commands, _ := collection["commands"].([]interface{})
+		return fmt.Sprintf("%s collection %s: %d requests", contextType, collection["name"], len(commands))
+	case "docs":
+		return fmt.Sprintf("Documentation of package %s", metadata["package"])
+	case "":
+		return fmt.Sprintf("Context with %d metadata fields", len(metadata))
+	default:
+		return fmt.Sprintf("%s context with %d metadata fields", contextType, len(metadata))
+	}
+}
+
+var httpMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPut: true, http.MethodPost: true, http.MethodDelete: true,
+	http.MethodOptions: true, http.MethodHead: true, http.MethodPatch: true, http.MethodTrace: true,
+}
+
+// countPostmanRequests counts the requests of Postman items, descending into
+// folders
+func countPostmanRequests(items interface{}) int {
+	list, _ := items.([]interface{})
+	n := 0
+	for _, item := range list {
+		entry, _ := item.(map[string]interface{})
+		if nested, ok := entry["item"]; ok {
+			n += countPostmanRequests(nested)
+		} else if _, ok := entry["request"]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// labelStatus maps errors of label operations to HTTP status codes
+func labelStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrContextNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrInvalidLabels), errors.Is(err, ErrNoContextsSelected), errors.Is(err, ErrUnknownOperation):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *Server) handleLabelContexts(w http.ResponseWriter, r *http.Request) {
+	var req LabelContextsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	contexts, err := s.labelContexts(r.Context(), req)
+	if err != nil {
+		writeError(w, labelStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, contexts)
+}
+
+func (s *Server) handleBulkContexts(w http.ResponseWriter, r *http.Request) {
+	var req BulkContextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := s.bulkContexts(r.Context(), req)
+	if err != nil {
+		writeError(w, labelStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelSelector(t *testing.T) {
+	labels := map[string]string{"team": "payments", "reviewed": ""}
+	tests := []struct {
+		selector string
+		matches  bool
+	}{
+		{"", true},
+		{"team=payments", true},
+		{"team==payments", true},
+		{"team=search", false},
+		{"team!=search", true},
+		{"owner!=search", true},
+		{"reviewed", true},
+		{"!reviewed", false},
+		{"!deprecated, team=payments", true},
+		{"team=payments,deprecated", false},
+	}
+	for _, tt := range tests {
+		sel, err := ParseLabelSelector(tt.selector)
+		require.NoError(t, err, tt.selector)
+		assert.Equal(t, tt.matches, sel.Matches(labels), tt.selector)
+	}
+
+	for _, invalid := range []string{"=payments", "team=pay ments", "!", "te am"} {
+		_, err := ParseLabelSelector(invalid)
+		assert.ErrorIs(t, err, ErrInvalidLabels, invalid)
+	}
+}
+
+func postContexts(t *testing.T, s *Server, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+	return w
+}
+
+func listContextIDs(t *testing.T, s *Server, selector string) []string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/context/list?selector="+selector, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var contexts []*Context
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&contexts))
+	ids := make([]string, 0, len(contexts))
+	for _, c := range contexts {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestContextLabels(t *testing.T) {
+	s := NewServer(nil)
+	spec := `{"openapi":"3.0.0","info":{"title":"Pets","version":"1.0"},"paths":{"/pets":{"get":{},"post":{}},"/pets/{id}":{"get":{},"parameters":[]}}}`
+	for _, body := range []string{
+		`{"id":"pets","metadata":{"type":"openapi","spec":` + spec + `},"labels":{"team":"payments"}}`,
+		`{"id":"orders","metadata":{"type":"openapi"},"labels":{"team":"payments"}}`,
+		`{"id":"search","metadata":{"type":"curl","collection":{"name":"search","commands":[{},{}]}},"labels":{"team":"search"}}`,
+	} {
+		w := postContexts(t, s, "/v1/context/create", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	w := postContexts(t, s, "/v1/context/create", `{"id":"bad","metadata":{},"labels":{"team":"a b"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.Equal(t, []string{"orders", "pets"}, listContextIDs(t, s, "team=payments"))
+	assert.Equal(t, []string{"orders", "pets", "search"}, listContextIDs(t, s, ""))
+
+	// Tag by selector and by ID, then untag
+	w = postContexts(t, s, "/v1/context/labels", `{"selector":"team=payments","ids":["search"],"add":{"legacy":""}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"orders", "pets", "search"}, listContextIDs(t, s, "legacy"))
+	w = postContexts(t, s, "/v1/context/labels", `{"ids":["orders"],"remove":["legacy"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"pets", "search"}, listContextIDs(t, s, "legacy"))
+
+	w = postContexts(t, s, "/v1/context/labels", `{"ids":["orders","missing"],"add":{"x":""}}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	c, err := s.store.Get("orders")
+	require.NoError(t, err)
+	assert.NotContains(t, c.Labels, "x", "nothing changes when an ID is unknown")
+	w = postContexts(t, s, "/v1/context/labels", `{"add":{"x":""}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postContexts(t, s, "/v1/context/bulk", `{"selector":"legacy","operation":"summarize"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result BulkContextResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, []string{"pets", "search"}, result.IDs)
+	require.Len(t, result.Contexts, 2)
+	assert.Equal(t, "OpenAPI spec Pets 1.0: 2 paths, 3 operations", result.Contexts[0].Metadata["summary"])
+	assert.Equal(t, "curl collection search: 2 requests", result.Contexts[1].Metadata["summary"])
+
+	w = postContexts(t, s, "/v1/context/bulk", `{"selector":"team=payments","operation":"export"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	require.Len(t, result.Contexts, 2)
+	assert.Equal(t, map[string]string{"team": "payments", "legacy": ""}, result.Contexts[1].Labels)
+
+	w = postContexts(t, s, "/v1/context/bulk", `{"operation":"delete"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "an empty selector would delete everything")
+	w = postContexts(t, s, "/v1/context/bulk", `{"selector":"legacy","operation":"archive"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = postContexts(t, s, "/v1/context/bulk", `{"selector":"legacy","operation":"delete"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"orders"}, listContextIDs(t, s, ""))
+}
+
+func TestContextLabels_RPC(t *testing.T) {
+	s := NewServer(nil)
+	require.NoError(t, s.store.Create(&Context{ID: "a", Metadata: map[string]interface{}{}, Labels: map[string]string{"env": "dev"}}))
+	require.NoError(t, s.store.Create(&Context{ID: "b", Metadata: map[string]interface{}{}}))
+
+	resp := s.call(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"context.label","params":{"ids":["b"],"add":{"env":"prod"}}}`))
+	require.Nil(t, resp.Error)
+	resp = s.call(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"context.list","params":{"selector":"env=prod"}}`))
+	require.Nil(t, resp.Error)
+	var contexts []*Context
+	require.NoError(t, json.Unmarshal(resp.Result, &contexts))
+	require.Len(t, contexts, 1)
+	assert.Equal(t, "b", contexts[0].ID)
+
+	resp = s.call(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":3,"method":"context.bulk","params":{"selector":"env","operation":"archive"}}`))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, RPCInvalidParams, resp.Error.Code)
+}
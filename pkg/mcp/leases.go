@@ -0,0 +1,439 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// LeaseHeader names the lease that browsers, SSH connections and tasks
+// created by a request are tied to. Every request naming a lease renews it.
+const LeaseHeader = "X-MCP-Lease"
+
+// Kinds of leased resources
+const (
+	LeaseBrowser = "browser"
+	LeaseSSH     = "ssh"
+	LeaseTask    = "task"
+)
+
+// Defaults of leases
+const (
+	defaultLeaseTTL    = 5 * time.Minute
+	defaultMaxLeaseTTL = time.Hour
+)
+
+// ErrLeaseNotFound is returned for leases that were released, expired or
+// never existed
+var ErrLeaseNotFound = errors.New("lease not found")
+
+// LeaseConfig configures leases. Clients of abandoned runs stop renewing
+// their lease, and the resources tied to it are released when it expires.
+type LeaseConfig struct {
+	DefaultTTL time.Duration `yaml:"default_ttl"` // Defaults to 5 minutes
+	MaxTTL     time.Duration `yaml:"max_ttl"`     // Longest TTL clients may ask for, defaults to 1 hour
+}
+
+// Validate checks the lease config
+func (c LeaseConfig) Validate() error {
+	if c.DefaultTTL < 0 || c.MaxTTL < 0 {
+		return fmt.Errorf("ttls must not be negative")
+	}
+	if c.DefaultTTL > 0 && c.MaxTTL > 0 && c.DefaultTTL > c.MaxTTL {
+		return fmt.Errorf("default_ttl exceeds max_ttl")
+	}
+	return nil
+}
+
+// Lease ties resources to a client. MCP sessions hold a lease without expiry
+// that is released when the host disconnects.
+type Lease struct {
+	ID        string          `json:"id"`
+	Tenant    string          `json:"tenant,omitempty"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	Resources []LeaseResource `json:"resources"`
+}
+
+// LeaseResource is a resource tied to a lease
+type LeaseResource struct {
+	Kind string `json:"kind"` // browser, ssh or task
+	ID   string `json:"id"`
+}
+
+// LeaseRequest creates or renews a lease
+type LeaseRequest struct {
+	TTLSeconds int `json:"ttl_seconds"` // Defaults to the configured TTL
+}
+
+type lease struct {
+	Lease
+	ttl      time.Duration // Zero for leases held until released
+	timer    *time.Timer
+	releases map[LeaseResource]func() error
+}
+
+// snapshot returns the lease with its resources sorted. Callers hold the
+// manager's lock.
+func (l *lease) snapshot() Lease {
+	out := l.Lease
+	if l.ExpiresAt != nil {
+		expires := *l.ExpiresAt
+		out.ExpiresAt = &expires
+	}
+	out.Resources = make([]LeaseResource, 0, len(l.releases))
+	for res := range l.releases {
+		out.Resources = append(out.Resources, res)
+	}
+	sort.Slice(out.Resources, func(i, j int) bool {
+		if out.Resources[i].Kind != out.Resources[j].Kind {
+			return out.Resources[i].Kind < out.Resources[j].Kind
+		}
+		return out.Resources[i].ID < out.Resources[j].ID
+	})
+	return out
+}
+
+// LeaseManager holds the leases of a server and releases the resources of
+// leases that expire
+type LeaseManager struct {
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+	logger     *slog.Logger
+
+	mu     sync.Mutex
+	leases map[string]*lease
+}
+
+// NewLeaseManager creates a lease manager logging released resources to
+// logger
+func NewLeaseManager(config LeaseConfig, logger *slog.Logger) *LeaseManager {
+	m := &LeaseManager{defaultTTL: config.DefaultTTL, maxTTL: config.MaxTTL, logger: logger, leases: make(map[string]*lease)}
+	if m.defaultTTL == 0 {
+		m.defaultTTL = defaultLeaseTTL
+	}
+	if m.maxTTL == 0 {
+		m.maxTTL = defaultMaxLeaseTTL
+	}
+	return m
+}
+
+// TTL returns the TTL of a lease request, capped at the maximum
+func (m *LeaseManager) TTL(req LeaseRequest) (time.Duration, error) {
+	switch {
+	case req.TTLSeconds < 0:
+		return 0, fmt.Errorf("ttl_seconds must not be negative")
+	case req.TTLSeconds == 0:
+		return m.defaultTTL, nil
+	}
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if ttl > m.maxTTL {
+		ttl = m.maxTTL
+	}
+	return ttl, nil
+}
+
+// Create starts a lease of a tenant that expires after ttl, or is held until
+// released when ttl is zero
+func (m *LeaseManager) Create(tenant string, ttl time.Duration) Lease {
+	l := &lease{Lease: Lease{ID: newRequestID(), Tenant: tenant}, ttl: ttl, releases: make(map[LeaseResource]func() error)}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.leases[l.ID] = l
+	m.schedule(l)
+	return l.snapshot()
+}
+
+// schedule moves the expiry of a lease one TTL from now. Callers hold m.mu.
+func (m *LeaseManager) schedule(l *lease) {
+	if l.ttl == 0 {
+		return
+	}
+	expires := time.Now().Add(l.ttl)
+	l.ExpiresAt = &expires
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	l.timer = time.AfterFunc(l.ttl, func() { m.expire(l.ID) })
+}
+
+// lookup returns a lease of a tenant. Callers hold m.mu.
+func (m *LeaseManager) lookup(tenant, id string) (*lease, error) {
+	l, ok := m.leases[id]
+	if !ok || l.Tenant != tenant {
+		return nil, fmt.Errorf("%w: %s", ErrLeaseNotFound, id)
+	}
+	return l, nil
+}
+
+// Get returns a lease of a tenant
+func (m *LeaseManager) Get(tenant, id string) (Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, err := m.lookup(tenant, id)
+	if err != nil {
+		return Lease{}, err
+	}
+	return l.snapshot(), nil
+}
+
+// List returns the leases of a tenant
+func (m *LeaseManager) List(tenant string) []Lease {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leases := make([]Lease, 0, len(m.leases))
+	for _, l := range m.leases {
+		if l.Tenant == tenant {
+			leases = append(leases, l.snapshot())
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].ID < leases[j].ID })
+	return leases
+}
+
+// Renew moves the expiry of a lease, with a new TTL unless ttl is zero
+func (m *LeaseManager) Renew(tenant, id string, ttl time.Duration) (Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, err := m.lookup(tenant, id)
+	if err != nil {
+		return Lease{}, err
+	}
+	if ttl > 0 && l.ttl > 0 {
+		l.ttl = ttl
+	}
+	m.schedule(l)
+	return l.snapshot(), nil
+}
+
+// Release ends a lease and releases its resources
+func (m *LeaseManager) Release(tenant, id string) (Lease, error) {
+	m.mu.Lock()
+	l, err := m.lookup(tenant, id)
+	if err != nil {
+		m.mu.Unlock()
+		return Lease{}, err
+	}
+	released := m.remove(l)
+	m.mu.Unlock()
+
+	m.release(released)
+	return released.Lease, nil
+}
+
+// remove forgets a lease and returns it with the release functions of its
+// resources. Callers hold m.mu.
+func (m *LeaseManager) remove(l *lease) releasedLease {
+	delete(m.leases, l.ID)
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	released := releasedLease{Lease: l.snapshot(), releases: l.releases}
+	l.releases = nil
+	return released
+}
+
+type releasedLease struct {
+	Lease
+	releases map[LeaseResource]func() error
+}
+
+// release releases the resources of a lease, which may take a while, as
+// stopping browsers does
+func (m *LeaseManager) release(l releasedLease) {
+	for _, res := range l.Resources {
+		if err := l.releases[res](); err != nil {
+			m.logger.Warn("failed to release leased resource", "lease", l.ID, "kind", res.Kind, "id", res.ID, "error", err)
+			continue
+		}
+		m.logger.Info("leased resource released", "lease", l.ID, "kind", res.Kind, "id", res.ID)
+	}
+}
+
+// expire releases a lease whose TTL passed without renewal
+func (m *LeaseManager) expire(id string) {
+	m.mu.Lock()
+	l, ok := m.leases[id]
+	if !ok || l.ExpiresAt == nil || time.Now().Before(*l.ExpiresAt) {
+		m.mu.Unlock()
+		return // Released or renewed meanwhile
+	}
+	released := m.remove(l)
+	m.mu.Unlock()
+
+	m.logger.Info("lease expired", "lease", id, "tenant", l.Tenant, "resources", len(released.Resources))
+	m.release(released)
+}
+
+// attach ties a resource to a lease
+func (m *LeaseManager) attach(id string, res LeaseResource, release func() error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok := m.leases[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrLeaseNotFound, id)
+	}
+	l.releases[res] = release
+	return nil
+}
+
+// detach unties a resource closed by its client from its lease
+func (m *LeaseManager) detach(res LeaseResource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, l := range m.leases {
+		delete(l.releases, res)
+	}
+}
+
+// stop forgets every lease without releasing resources, which the server
+// closes itself when it shuts down
+func (m *LeaseManager) stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, l := range m.leases {
+		if l.timer != nil {
+			l.timer.Stop()
+		}
+		delete(m.leases, id)
+	}
+}
+
+// Middleware renews the lease named by the LeaseHeader of a request and
+// ties the resources the request creates to it
+func (m *LeaseManager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(LeaseHeader)
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, err := m.Renew(TenantFromContext(r.Context()), id, 0); err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withLease(r.Context(), id)))
+	})
+}
+
+type leaseKey struct{}
+
+// LeaseFromContext returns the lease of a request, or an empty string
+func LeaseFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(leaseKey{}).(string)
+	return id
+}
+
+func withLease(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, leaseKey{}, id)
+}
+
+// leaseResource ties a resource created for a request to the lease of the
+// request, if any. When the lease has ended meanwhile, the resource is
+// released at once.
+func (s *Server) leaseResource(ctx context.Context, kind, id string, release func() error) error {
+	leaseID := LeaseFromContext(ctx)
+	if leaseID == "" {
+		return nil
+	}
+	if err := s.leases.attach(leaseID, LeaseResource{Kind: kind, ID: id}, release); err != nil {
+		release()
+		return err
+	}
+	return nil
+}
+
+// unleaseResource unties a resource its client closed
+func (s *Server) unleaseResource(kind, id string) {
+	s.leases.detach(LeaseResource{Kind: kind, ID: id})
+}
+
+func leaseStatus(err error) int {
+	if errors.Is(err, ErrLeaseNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+func handleCreateLease(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req LeaseRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		ttl, err := s.leases.TTL(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		l := s.leases.Create(TenantFromContext(r.Context()), ttl)
+		LoggerFromContext(r.Context()).Info("lease created", "lease", l.ID, "ttl", ttl)
+		writeJSON(w, http.StatusCreated, l)
+	}
+}
+
+func handleListLeases(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.leases.List(TenantFromContext(r.Context())))
+	}
+}
+
+func handleGetLease(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l, err := s.leases.Get(TenantFromContext(r.Context()), mux.Vars(r)["id"])
+		if err != nil {
+			writeError(w, leaseStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, l)
+	}
+}
+
+func handleRenewLease(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req LeaseRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		var ttl time.Duration
+		if req.TTLSeconds != 0 {
+			var err error
+			if ttl, err = s.leases.TTL(req); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		l, err := s.leases.Renew(TenantFromContext(r.Context()), mux.Vars(r)["id"], ttl)
+		if err != nil {
+			writeError(w, leaseStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, l)
+	}
+}
+
+// handleReleaseLease ends a lease and returns the resources it released
+func handleReleaseLease(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l, err := s.leases.Release(TenantFromContext(r.Context()), mux.Vars(r)["id"])
+		if err != nil {
+			writeError(w, leaseStatus(err), err)
+			return
+		}
+		LoggerFromContext(r.Context()).Info("lease released", "lease", l.ID, "resources", len(l.Resources))
+		writeJSON(w, http.StatusOK, l)
+	}
+}
@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/browser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubBrowser starts without chromium and records being stopped
+type stubBrowser struct {
+	BrowserInstance
+	stopped *atomic.Bool
+}
+
+func (stubBrowser) Start() error {
+	return nil
+}
+
+func (b stubBrowser) Stop() error {
+	b.stopped.Store(true)
+	return nil
+}
+
+func newLeaseServer(t *testing.T, ttl time.Duration) (*Server, *atomic.Bool) {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Leases.DefaultTTL = ttl
+	var stopped atomic.Bool
+	s := NewServer(nil, WithConfig(cfg), WithBrowserLauncher(func(config *browser.BrowserConfig) BrowserInstance {
+		return stubBrowser{stopped: &stopped}
+	}))
+	s.AddBrowserHandlers()
+	return s, &stopped
+}
+
+func leaseRequest(s *Server, method, path, lease, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if lease != "" {
+		r.Header.Set(LeaseHeader, lease)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func createLease(t *testing.T, s *Server) Lease {
+	t.Helper()
+	w := leaseRequest(s, "POST", "/v1/leases", "", "")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var l Lease
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&l))
+	return l
+}
+
+func TestLeases_Release(t *testing.T) {
+	s, stopped := newLeaseServer(t, 0)
+	l := createLease(t, s)
+	require.NotNil(t, l.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(defaultLeaseTTL), *l.ExpiresAt, time.Minute)
+
+	w := leaseRequest(s, "POST", "/v1/browser/create", l.ID, `{"id":"b1"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = leaseRequest(s, "GET", "/v1/leases/"+l.ID, "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&l))
+	assert.Equal(t, []LeaseResource{{Kind: LeaseBrowser, ID: "b1"}}, l.Resources)
+
+	w = leaseRequest(s, "DELETE", "/v1/leases/"+l.ID, "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, stopped.Load())
+	w = leaseRequest(s, "DELETE", "/v1/browser/b1", "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "the lease closed the browser")
+
+	// Released leases are gone, and requests naming them are refused
+	w = leaseRequest(s, "GET", "/v1/leases/"+l.ID, "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = leaseRequest(s, "POST", "/v1/browser/create", l.ID, `{"id":"b2"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestLeases_Expiry(t *testing.T) {
+	s, stopped := newLeaseServer(t, 50*time.Millisecond)
+	l := createLease(t, s)
+	w := leaseRequest(s, "POST", "/v1/browser/create", l.ID, `{"id":"b1"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	assert.Eventually(t, stopped.Load, time.Second, 10*time.Millisecond)
+	w = leaseRequest(s, "GET", "/v1/leases", "", "")
+	assert.JSONEq(t, `[]`, w.Body.String())
+}
+
+func TestLeases_ClosedResourcesAreDetached(t *testing.T) {
+	s, _ := newLeaseServer(t, 0)
+	l := createLease(t, s)
+	w := leaseRequest(s, "POST", "/v1/browser/create", l.ID, `{"id":"b1"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = leaseRequest(s, "DELETE", "/v1/browser/b1", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	l, err := s.leases.Get("", l.ID)
+	require.NoError(t, err)
+	assert.Empty(t, l.Resources)
+}
+
+func TestLeases_MCPSessionEnd(t *testing.T) {
+	s, stopped := newLeaseServer(t, 0)
+	session := s.newMCPSession("")
+	ctx := withLease(context.Background(), session.lease)
+	require.NoError(t, s.startBrowser(ctx, s.browsers, "b1", &browser.BrowserConfig{}))
+
+	session.end()
+	assert.True(t, stopped.Load())
+	assert.Empty(t, s.leases.List(""))
+}
+
+func TestLeaseConfig_Validate(t *testing.T) {
+	assert.NoError(t, LeaseConfig{}.Validate())
+	assert.Error(t, LeaseConfig{DefaultTTL: -time.Second}.Validate())
+	assert.Error(t, LeaseConfig{DefaultTTL: 2 * time.Hour, MaxTTL: time.Hour}.Validate())
+}
@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Defaults for HTTPConfig
+const (
+	defaultMaxBodyBytes      = 10 << 20
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = time.Minute
+	defaultWriteTimeout      = 90 * time.Second
+	defaultIdleTimeout       = 2 * time.Minute
+	defaultHandlerTimeout    = time.Minute
+)
+
+// Route timeouts for handlers that take longer than the server default
+const (
+	// LongRunningTimeout suits browser automation and remote commands
+	LongRunningTimeout = 10 * time.Minute
+	// NoTimeout disables the handler timeout, for streaming responses
+	NoTimeout time.Duration = -1
+)
+
+// writeGrace is the time a handler has to write its response after its
+// timeout when the route overrides the server write timeout
+const writeGrace = 10 * time.Second
+
+// HTTPConfig bounds request bodies and the time spent on requests. Routes
+// may override the body limit and handler timeout, and configured groups
+// override both again.
+type HTTPConfig struct {
+	MaxBodyBytes      int64         `yaml:"max_body_bytes"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"` // Reading the whole request
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// HandlerTimeout cancels the context of a request. It should be shorter
+	// than WriteTimeout so handlers can still report the error.
+	HandlerTimeout time.Duration `yaml:"handler_timeout"`
+	// Groups overrides limits per route group, keyed by the first path
+	// segment, e.g. "browser" or "ssh"
+	Groups map[string]RouteLimits `yaml:"groups"`
+}
+
+// RouteLimits overrides the body limit and handler timeout of routes. Zero
+// values keep the default; a negative timeout disables it.
+type RouteLimits struct {
+	MaxBodyBytes int64         `yaml:"max_body_bytes"`
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+func defaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		MaxBodyBytes:      defaultMaxBodyBytes,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+		ReadTimeout:       defaultReadTimeout,
+		WriteTimeout:      defaultWriteTimeout,
+		IdleTimeout:       defaultIdleTimeout,
+		HandlerTimeout:    defaultHandlerTimeout,
+	}
+}
+
+// Validate checks the limits for values that cannot work
+func (c HTTPConfig) Validate() error {
+	if c.MaxBodyBytes < 0 {
+		return fmt.Errorf("max_body_bytes must not be negative")
+	}
+	for group, limits := range c.Groups {
+		if limits.MaxBodyBytes < 0 {
+			return fmt.Errorf("group %s: max_body_bytes must not be negative", group)
+		}
+	}
+	return nil
+}
+
+// routeLimits resolves the limits of a route from the server defaults, the
+// route's own limits and the configured group
+func (c HTTPConfig) routeLimits(route Route) RouteLimits {
+	limits := RouteLimits{MaxBodyBytes: c.MaxBodyBytes, Timeout: c.HandlerTimeout}
+	for _, override := range []RouteLimits{
+		{MaxBodyBytes: route.MaxBodyBytes, Timeout: route.Timeout},
+		c.Groups[routeGroup(route.Path)],
+	} {
+		if override.MaxBodyBytes > 0 {
+			limits.MaxBodyBytes = override.MaxBodyBytes
+		}
+		if override.Timeout != 0 {
+			limits.Timeout = override.Timeout
+		}
+	}
+	return limits
+}
+
+// limitRoute bounds the request body and handler time of a route. Bodies over
+// the limit fail to read with an *http.MaxBytesError; bodies declared too
+// large are rejected up front. Handlers stop when their context is done.
+func (s *Server) limitRoute(route Route, handler http.HandlerFunc) http.HandlerFunc {
+	limits := s.config.HTTP.routeLimits(route)
+	overridden := route.Timeout != 0 || s.config.HTTP.Groups[routeGroup(route.Path)].Timeout != 0
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if limits.MaxBodyBytes > 0 {
+			if r.ContentLength > limits.MaxBodyBytes {
+				writeError(w, http.StatusRequestEntityTooLarge, &http.MaxBytesError{Limit: limits.MaxBodyBytes})
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limits.MaxBodyBytes)
+		}
+
+		// Routes with their own timeout get a write deadline to match, which
+		// may be later than the server's. Recorders in tests cannot do this.
+		if overridden {
+			var deadline time.Time
+			if limits.Timeout > 0 {
+				deadline = time.Now().Add(limits.Timeout + writeGrace)
+			}
+			http.NewResponseController(w).SetWriteDeadline(deadline)
+		}
+
+		if limits.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), limits.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitRoute_BodySize(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.HTTP.MaxBodyBytes = 64
+	s := NewServer(nil, WithConfig(cfg))
+
+	body := `{"id":"ctx-1","metadata":{"padding":"` + strings.Repeat("x", 64) + `"}}`
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/context/create", strings.NewReader(body)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	// Bodies of unknown length fail once they are read past the limit
+	req := httptest.NewRequest("POST", "/v1/context/create", io.MultiReader(strings.NewReader(body)))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/context/create", strings.NewReader(`{"id":"ctx-1","metadata":{}}`)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestLimitRoute_Timeout(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.HTTP.Groups = map[string]RouteLimits{"slow": {Timeout: 20 * time.Millisecond}}
+	s := NewServer(nil, WithConfig(cfg))
+
+	s.handle(Route{Method: "GET", Path: "/slow/wait"}, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			writeError(w, http.StatusGatewayTimeout, r.Context().Err())
+		case <-time.After(5 * time.Second):
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/slow/wait", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+}
+
+func TestRouteLimits(t *testing.T) {
+	cfg := defaultHTTPConfig()
+	cfg.Groups = map[string]RouteLimits{"browser": {Timeout: time.Hour}}
+
+	limits := cfg.routeLimits(Route{Path: "/context/list"})
+	assert.Equal(t, RouteLimits{MaxBodyBytes: defaultMaxBodyBytes, Timeout: defaultHandlerTimeout}, limits)
+
+	limits = cfg.routeLimits(Route{Path: "/ssh/{id}/exec", Timeout: LongRunningTimeout, MaxBodyBytes: 1024})
+	assert.Equal(t, RouteLimits{MaxBodyBytes: 1024, Timeout: LongRunningTimeout}, limits)
+
+	// Configured groups take precedence over the route
+	limits = cfg.routeLimits(Route{Path: "/browser/{id}/automate", Timeout: LongRunningTimeout})
+	assert.Equal(t, time.Hour, limits.Timeout)
+
+	assert.Equal(t, NoTimeout, cfg.routeLimits(Route{Path: "/ide/tasks/{id}/logs", Timeout: NoTimeout}).Timeout)
+}
+
+func TestLoadConfig_HTTPLimits(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "mcp.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+http:
+  max_body_bytes: 1048576
+  handler_timeout: 30s
+  groups:
+    ssh:
+      timeout: 1h
+`), 0644))
+
+	cfg, err := LoadConfig([]string{"-config", configPath})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1<<20), cfg.HTTP.MaxBodyBytes)
+	assert.Equal(t, 30*time.Second, cfg.HTTP.HandlerTimeout)
+	assert.Equal(t, defaultReadHeaderTimeout, cfg.HTTP.ReadHeaderTimeout)
+	assert.Equal(t, time.Hour, cfg.HTTP.Groups["ssh"].Timeout)
+}
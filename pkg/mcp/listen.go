@@ -0,0 +1,129 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Listen address schemes besides host:port
+const (
+	unixScheme    = "unix:"   // unix:/run/mcp.sock
+	fdScheme      = "fd:"     // fd:3, a listening socket inherited from the parent
+	systemdScheme = "systemd" // systemd or systemd:NAME, socket activation
+)
+
+// systemdFDBase is the first descriptor passed by socket activation,
+// SD_LISTEN_FDS_START
+const systemdFDBase = 3
+
+// socketMode restricts unix sockets to their owner, since anyone who can
+// connect can use the whole API
+const socketMode = 0600
+
+// staleTimeout bounds the probe of an existing socket file
+const staleTimeout = time.Second
+
+// Listen opens a listener for an address. Besides TCP addresses such as
+// :8080 it accepts unix:PATH for a unix domain socket, fd:N for an inherited
+// listening socket, and systemd or systemd:NAME for a socket passed by
+// systemd socket activation, selected by its FileDescriptorName.
+func Listen(addr string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, unixScheme):
+		return listenUnix(strings.TrimPrefix(addr, unixScheme))
+	case strings.HasPrefix(addr, fdScheme):
+		fd, err := strconv.Atoi(strings.TrimPrefix(addr, fdScheme))
+		if err != nil || fd < 0 {
+			return nil, fmt.Errorf("invalid file descriptor in %q", addr)
+		}
+		return fileListener(fd, addr)
+	case addr == systemdScheme || strings.HasPrefix(addr, systemdScheme+":"):
+		name := strings.TrimPrefix(strings.TrimPrefix(addr, systemdScheme), ":")
+		fd, err := systemdFD(os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS"), os.Getenv("LISTEN_FDNAMES"), name)
+		if err != nil {
+			return nil, err
+		}
+		return fileListener(fd, addr)
+	default:
+		return net.Listen("tcp", addr)
+	}
+}
+
+// listenUnix listens on a unix socket, replacing a stale socket file left by
+// a previous run but refusing to take over one that is still served
+func listenUnix(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, fmt.Errorf("unix socket path is empty")
+	}
+
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, staleTimeout); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another server", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %v", err)
+		}
+	}
+
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, socketMode); err != nil {
+		lis.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %v", err)
+	}
+	return lis, nil
+}
+
+func fileListener(fd int, addr string) (net.Listener, error) {
+	f := os.NewFile(uintptr(fd), addr)
+	if f == nil {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	defer f.Close() // FileListener duplicates the descriptor
+
+	lis, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("file descriptor %d is not a listening socket: %v", fd, err)
+	}
+	return lis, nil
+}
+
+// systemdFD picks the descriptor of a socket passed by systemd from the
+// LISTEN_* environment, see sd_listen_fds(3). Without a name there must be
+// exactly one socket.
+func systemdFD(pid, fds, names, name string) (int, error) {
+	if pid == "" || fds == "" {
+		return 0, errors.New("no sockets were passed by systemd")
+	}
+	if pid != strconv.Itoa(os.Getpid()) {
+		return 0, fmt.Errorf("sockets were passed to process %s, not this one", pid)
+	}
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid LISTEN_FDS %q", fds)
+	}
+
+	if name == "" {
+		if n != 1 {
+			return 0, fmt.Errorf("systemd passed %d sockets, select one with systemd:NAME", n)
+		}
+		return systemdFDBase, nil
+	}
+	for i, fdName := range strings.Split(names, ":") {
+		if fdName == name && i < n {
+			return systemdFDBase + i, nil
+		}
+	}
+	return 0, fmt.Errorf("systemd passed no socket named %s", name)
+}
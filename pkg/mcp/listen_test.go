@@ -0,0 +1,103 @@
+//go:build unix
+
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStart_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.sock")
+	s := NewServer(nil)
+
+	done := make(chan error, 1)
+	go func() { done <- s.Start(unixScheme + path) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	require.Eventually(t, func() bool {
+		resp, err := client.Get("http://mcp/v1/context/list")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(socketMode), info.Mode().Perm())
+
+	// A socket that is still served is not taken over
+	_, err = Listen(unixScheme + path)
+	assert.Error(t, err)
+
+	require.NoError(t, s.Shutdown(context.Background()))
+	require.NoError(t, <-done)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestListen_StaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.sock")
+	lis, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	lis.(*net.UnixListener).SetUnlinkOnClose(false)
+	lis.Close()
+
+	lis, err = Listen(unixScheme + path)
+	require.NoError(t, err)
+	lis.Close()
+}
+
+func TestListen_FileDescriptor(t *testing.T) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer tcp.Close()
+	f, err := tcp.(*net.TCPListener).File()
+	require.NoError(t, err)
+	defer f.Close()
+
+	lis, err := Listen(fmt.Sprintf("%s%d", fdScheme, f.Fd()))
+	require.NoError(t, err)
+	defer lis.Close()
+	assert.Equal(t, tcp.Addr().String(), lis.Addr().String())
+
+	_, err = Listen(fdScheme + "stdin")
+	assert.Error(t, err)
+}
+
+func TestSystemdFD(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+
+	fd, err := systemdFD(pid, "1", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, fd)
+
+	fd, err = systemdFD(pid, "2", "http:grpc", "grpc")
+	require.NoError(t, err)
+	assert.Equal(t, 4, fd)
+
+	_, err = systemdFD(pid, "2", "http:grpc", "")
+	assert.Error(t, err)
+	_, err = systemdFD(pid, "1", "http", "grpc")
+	assert.Error(t, err)
+	_, err = systemdFD("1", "1", "", "")
+	assert.Error(t, err)
+	_, err = systemdFD("", "", "", "")
+	assert.Error(t, err)
+}
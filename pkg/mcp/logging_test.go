@@ -0,0 +1,61 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	var seen string
+	handler := RequestLogger(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+		LoggerFromContext(r.Context()).Info("inside handler")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest("GET", "/ssh/box/exec", nil)
+	req.Header.Set(RequestIDHeader, "client-id-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "client-id-1", seen)
+	assert.Equal(t, "client-id-1", rec.Header().Get(RequestIDHeader))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var inner, entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &inner))
+	require.NoError(t, json.Unmarshal(lines[1], &entry))
+	assert.Equal(t, "client-id-1", inner["request_id"])
+	assert.Equal(t, "client-id-1", entry["request_id"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/ssh/box/exec", entry["path"])
+	assert.Equal(t, float64(http.StatusTeapot), entry["status"])
+	assert.Equal(t, "WARN", entry["level"])
+	assert.Contains(t, entry, "duration_ms")
+}
+
+func TestRequestLogger_GeneratesID(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+	handler := RequestLogger(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/context/list", nil)
+	req.Header.Set(RequestIDHeader, "bad id\n")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	id := rec.Header().Get(RequestIDHeader)
+	assert.Len(t, id, 32)
+	assert.NotEqual(t, "bad id\n", id)
+}
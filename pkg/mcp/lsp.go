@@ -2,30 +2,48 @@ package mcp
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/scanner"
 	"go/token"
 	"net/http"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
 )
 
 // LanguageServer handles LSP functionality
 type LanguageServer struct {
 	workspaceRoot string
+	fileManager   *ide.FileManager
 	documents     map[string]*Document
 	fileSet       *token.FileSet
+	symbols       *symbolIndex // Symbols of every Go file in the workspace
 	mu            sync.RWMutex
 }
 
-// Document represents a source code document
+// Document represents a source code document. While its text has syntax
+// errors, AST and Symbols are those of the last version that parsed.
 type Document struct {
-	URI     string       `json:"uri"`
-	Text    string       `json:"text"`
-	AST     *ast.File    `json:"ast,omitempty"`
-	Symbols []SymbolInfo `json:"symbols"`
-	Version int          `json:"version"`
+	URI           string           `json:"uri"`
+	Text          string           `json:"text"`
+	AST           *ast.File        `json:"ast,omitempty"`
+	Symbols       []DocumentSymbol `json:"symbols"`
+	Version       int              `json:"version"`
+	ParsedVersion int              `json:"parsedVersion"`         // Version of AST; 0 when no version parsed and AST is partial
+	ParseErrors   []Diagnostic     `json:"parseErrors,omitempty"` // Syntax errors of Text
+}
+
+// parsedText reports whether the AST was parsed from the text of the
+// document, partially when it has syntax errors, rather than kept from the
+// last version that parsed
+func (doc *Document) parsedText() bool {
+	return doc.ParsedVersion == 0 || doc.ParsedVersion == doc.Version
 }
 
 // SymbolInfo represents a code symbol (function, type, variable, etc.)
@@ -55,6 +73,17 @@ type Position struct {
 	Character int `json:"character"`
 }
 
+// TextEdit represents a textual change to a document
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// WorkspaceEdit represents changes to many documents, keyed by URI
+type WorkspaceEdit struct {
+	Changes map[string][]TextEdit `json:"changes"`
+}
+
 // LSPRequest represents an incoming LSP request
 type LSPRequest struct {
 	Method string          `json:"method"`
@@ -68,147 +97,322 @@ type TextDocumentItem struct {
 	Version int    `json:"version"`
 }
 
+// TextDocumentContentChangeEvent is a change of a document. Without a range,
+// Text replaces the whole document.
+type TextDocumentContentChangeEvent struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+// DocumentChange changes an open document, either by replacing its text or
+// by applying content changes in order, each to the result of the previous
+type DocumentChange struct {
+	URI            string                           `json:"uri"`
+	Version        int                              `json:"version,omitempty"` // Must be newer than the document; defaults to the next version
+	Text           string                           `json:"text,omitempty"`    // The new text when there are no content changes
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges,omitempty"`
+}
+
+var (
+	errDocumentNotFound = errors.New("document not found")
+	errStaleVersion     = errors.New("stale document version")
+	errOutsideWorkspace = errors.New("outside the workspace")
+)
+
 // NewLanguageServer creates a new language server instance
 func NewLanguageServer(workspaceRoot string) *LanguageServer {
+	root, err := filepath.Abs(workspaceRoot)
+	if err != nil {
+		root = workspaceRoot
+	}
 	return &LanguageServer{
 		workspaceRoot: workspaceRoot,
+		fileManager:   ide.NewFileManager(workspaceRoot),
 		documents:     make(map[string]*Document),
 		fileSet:       token.NewFileSet(),
+		symbols:       newSymbolIndex(root),
 	}
 }
 
 // AddLanguageServerHandler adds LSP capabilities to the MCP server
 func (s *Server) AddLanguageServerHandler() {
 	ls := NewLanguageServer(s.GetWorkspaceRoot())
+	if status, err := ls.restoreState(); err != nil {
+		s.logger.Warn("language server state not restored", "path", status.Path, "error", err)
+	} else if !status.SavedAt.IsZero() {
+		s.logger.Info("language server state restored", "path", status.Path, "documents", status.Documents, "files", status.Files)
+	}
+	go ls.symbols.watch()
+	s.lsp = ls
 
 	// Document management
-	s.router.HandleFunc("/lsp/document/open", handleOpenDocument(ls)).Methods("POST")
-	s.router.HandleFunc("/lsp/document/close", handleCloseDocument(ls)).Methods("POST")
-	s.router.HandleFunc("/lsp/document/change", handleChangeDocument(ls)).Methods("POST")
+	s.handle(Route{
+		Method: "POST", Path: "/lsp/document/open", Summary: "Open a document",
+		Request: TextDocumentItem{}, Response: map[string]string{},
+	}, handleOpenDocument(ls))
+	s.handle(Route{
+		Method: "POST", Path: "/lsp/document/close", Summary: "Close a document",
+		Request: TextDocumentItem{}, Response: map[string]string{},
+	}, handleCloseDocument(ls))
+	s.handle(Route{
+		Method: "POST", Path: "/lsp/document/change", Summary: "Replace or edit ranges of the text of an open document",
+		Request: DocumentChange{}, Response: map[string]string{},
+	}, handleChangeDocument(ls))
+	s.handle(Route{
+		Method: "POST", Path: "/lsp/state/save", Summary: "Save the open documents and the workspace index under .mcp, to be restored when the server starts",
+		Response: LSPStateStatus{},
+	}, handleSaveLSPState(ls))
 
 	// Code intelligence
-	s.router.HandleFunc("/lsp/symbols", handleDocumentSymbols(ls)).Methods("GET")
-	s.router.HandleFunc("/lsp/completion", handleCompletion(ls)).Methods("GET")
-	s.router.HandleFunc("/lsp/definition", handleDefinition(ls)).Methods("GET")
-	s.router.HandleFunc("/lsp/hover", handleHover(ls)).Methods("GET")
+	documentURI := []QueryParam{{Name: "uri", Description: "URI of an open document", Required: true}}
+	s.handle(Route{
+		Method: "GET", Path: "/lsp/symbols", Summary: "Outline an open document: its symbols and the symbols they contain",
+		Query: documentURI, Response: []DocumentSymbol{},
+	}, handleDocumentSymbols(ls))
+	s.handle(Route{
+		Method: "GET", Path: "/lsp/folding", Summary: "List the ranges of lines of an open document that can be folded",
+		Query: documentURI, Response: []FoldingRange{},
+	}, handleFoldingRanges(ls))
+	s.handle(Route{
+		Method: "GET", Path: "/lsp/workspace/symbols", Summary: "Fuzzy search the symbols of every Go file in the workspace",
+		Query: []QueryParam{
+			{Name: "query", Description: "Characters of the symbol name in order, like srvHdl; Type.Name also matches methods and fields"},
+			{Name: "limit", Description: "Maximum number of symbols (default 100)"},
+		},
+		Response: []SymbolInfo{},
+	}, handleWorkspaceSymbols(ls))
+	s.handle(Route{
+		Method: "POST", Path: "/lsp/workspace/index", Summary: "Index the Go files of the workspace again, which is otherwise kept up to date in the background",
+		Response: IndexStatus{}, Timeout: LongRunningTimeout,
+	}, handleWorkspaceIndex(ls))
+	documentPosition := append(documentURI,
+		QueryParam{Name: "line", Description: "Zero-based line", Required: true},
+		QueryParam{Name: "character", Description: "Zero-based byte offset in the line", Required: true},
+	)
+	s.handle(Route{
+		Method: "GET", Path: "/lsp/completion", Summary: "Complete the identifier at a position",
+		Query: documentPosition, Response: []CompletionItem{},
+	}, handleCompletion(ls))
+	s.handle(Route{
+		Method: "GET", Path: "/lsp/definition", Summary: "Find the declaration of the identifier at a position",
+		Query: documentPosition, Response: []Location{},
+	}, handleDefinition(ls))
+	s.handle(Route{
+		Method: "GET", Path: "/lsp/hover", Summary: "Describe the symbol at a position as markdown, or null",
+		Query: documentPosition, Response: Hover{},
+	}, handleHover(ls))
+	s.handle(Route{
+		Method: "GET", Path: "/lsp/documentHighlight", Summary: "List the reads and writes of the symbol at a position in its document",
+		Query: documentPosition, Response: []DocumentHighlight{},
+	}, handleDocumentHighlight(ls))
+	s.handle(Route{
+		Method: "GET", Path: "/lsp/implementations", Summary: "List the types implementing the interface at a position, or the interfaces a type implements, or their matching methods",
+		Query: documentPosition, Response: []Implementation{}, Timeout: LongRunningTimeout,
+	}, handleImplementations(ls))
+	s.handle(Route{
+		Method: "GET", Path: "/lsp/callHierarchy", Summary: "List the callers and callees in the workspace of the function at a position",
+		Query: append(documentPosition,
+			QueryParam{Name: "direction", Description: "incoming or outgoing calls only; both by default"},
+		),
+		Response: CallHierarchy{}, Timeout: LongRunningTimeout,
+	}, handleCallHierarchy(ls))
+
+	// Code actions
+	s.handle(Route{
+		Method: "POST", Path: "/lsp/rename", Summary: "Rename a symbol across the workspace",
+		Request: RenameRequest{}, Response: RenameResult{},
+	}, handleRename(ls))
+	s.handle(Route{
+		Method: "POST", Path: "/refactor/rename", Summary: "Rename a declaration, named like \"example.com/m/pkg\".Type.Member or at a position, and its references across the workspace after checking the rename changes no meaning",
+		Request: RefactorRenameRequest{}, Response: RefactorRenameResult{}, Timeout: LongRunningTimeout,
+	}, handleRefactorRename(ls))
+	s.handle(Route{
+		Method: "POST", Path: "/refactor/extractInterface", Summary: "Declare an interface of the methods of a type, named like \"example.com/m/pkg\".Type, after the type",
+		Request: ExtractInterfaceRequest{}, Response: RefactorEditResult{}, Timeout: LongRunningTimeout,
+	}, handleExtractInterface(ls))
+	s.handle(Route{
+		Method: "POST", Path: "/refactor/implementInterface", Summary: "Generate stubs of the methods a type lacks to implement an interface, named like \"io\".Reader",
+		Request: ImplementInterfaceRequest{}, Response: RefactorEditResult{}, Timeout: LongRunningTimeout,
+	}, handleImplementInterface(ls))
+	s.handle(Route{
+		Method: "POST", Path: "/lsp/imports", Summary: "Organize the imports of a file",
+		Request: OrganizeImportsRequest{}, Response: OrganizeImportsResult{},
+	}, handleOrganizeImports(ls))
+	s.handle(Route{
+		Method: "POST", Path: "/lsp/format", Summary: "Format a file, or lines of it, with gofmt, goimports or gofumpt",
+		Request: FormatRequest{}, Response: FormatResult{},
+	}, handleFormat(ls))
+	s.handle(Route{
+		Method: "POST", Path: "/lsp/codeAction", Summary: "List the quick fixes and source actions of a file",
+		Request: CodeActionRequest{}, Response: []CodeAction{},
+	}, handleCodeAction(ls))
+	s.handle(Route{
+		Method: "POST", Path: "/lsp/executeCommand", Summary: "Execute a command of a code action, applying its edit",
+		Request: ExecuteCommandRequest{}, Response: ExecuteCommandResult{},
+	}, handleExecuteCommand(ls))
+
+	// Editors speak the Language Server Protocol over a WebSocket, one
+	// JSON-RPC message per frame
+	s.handle(Route{
+		Method: "GET", Path: "/lsp/ws", Summary: "Upgrade to a WebSocket speaking the Language Server Protocol",
+		Status: http.StatusSwitchingProtocols, Timeout: NoTimeout,
+	}, s.lspWebSocket())
 }
 
+// parseDocument stores content as the next version of a document
 func (ls *LanguageServer) parseDocument(uri string, content string) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.storeDocument(uri, content, 0)
+}
+
+// openDocument opens a document, or replaces an open one with a newer
+// version
+func (ls *LanguageServer) openDocument(item TextDocumentItem) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if prev, ok := ls.documents[item.URI]; ok && item.Version > 0 && item.Version <= prev.Version {
+		return fmt.Errorf("%w: %d is not newer than %d", errStaleVersion, item.Version, prev.Version)
+	}
+	return ls.storeDocument(item.URI, item.Text, item.Version)
+}
+
+// storeDocument parses content as the given version of a document, or the
+// next one when version is not positive, replacing the previous version.
+// ls.mu must be held.
+func (ls *LanguageServer) storeDocument(uri string, content string, version int) error {
 	file, err := parser.ParseFile(ls.fileSet, uri, content, parser.ParseComments)
-	if err != nil {
+	syntax, hasSyntaxErrors := err.(scanner.ErrorList)
+	if err != nil && !hasSyntaxErrors {
 		return fmt.Errorf("failed to parse document: %v", err)
 	}
 
-	symbols := ls.extractSymbols(file)
+	prev, ok := ls.documents[uri]
+	if version <= 0 {
+		version = 1
+		if ok {
+			version = prev.Version + 1
+		}
+	}
+	doc := &Document{
+		URI:           uri,
+		Text:          content,
+		AST:           file,
+		Version:       version,
+		ParsedVersion: version,
+	}
 
-	ls.mu.Lock()
-	defer ls.mu.Unlock()
+	if hasSyntaxErrors {
+		for _, e := range syntax {
+			at := Position{Line: e.Pos.Line - 1, Character: e.Pos.Column - 1}
+			doc.ParseErrors = append(doc.ParseErrors, Diagnostic{
+				Severity: "error",
+				Message:  e.Msg,
+				Location: Location{URI: uri, Range: Range{Start: at, End: at}},
+				Code:     "parse-error",
+				Source:   SourceAnalyzer,
+			})
+		}
+		// Documents being edited rarely parse; keep the outline of the last
+		// version that did, or the partial AST until one does. The kept AST
+		// is not of Text, which parsedText reports.
+		doc.ParsedVersion = 0
+		if ok && prev.ParsedVersion > 0 {
+			ls.forget(file)
+			doc.AST, doc.Symbols, doc.ParsedVersion = prev.AST, prev.Symbols, prev.ParsedVersion
+		}
+	}
+	if doc.AST == file {
+		doc.Symbols = ls.extractSymbols(file)
+	}
 
-	ls.documents[uri] = &Document{
-		URI:     uri,
-		Text:    content,
-		AST:     file,
-		Symbols: symbols,
-		Version: ls.documents[uri].Version + 1,
+	if ok && prev.AST != doc.AST {
+		ls.forget(prev.AST)
 	}
+	ls.documents[uri] = doc
 
 	return nil
 }
 
-func (ls *LanguageServer) extractSymbols(file *ast.File) []SymbolInfo {
-	var symbols []SymbolInfo
-
-	ast.Inspect(file, func(n ast.Node) bool {
-		switch node := n.(type) {
-		case *ast.FuncDecl:
-			pos := ls.fileSet.Position(node.Pos())
-			end := ls.fileSet.Position(node.End())
-
-			symbols = append(symbols, SymbolInfo{
-				Name: node.Name.Name,
-				Kind: "function",
-				Location: Location{
-					URI: file.Name.Name,
-					Range: Range{
-						Start: Position{Line: pos.Line - 1, Character: pos.Column - 1},
-						End:   Position{Line: end.Line - 1, Character: end.Column - 1},
-					},
-				},
-				Signature: ls.getFunctionSignature(node),
-			})
+// changeDocument applies a change to an open document and returns its new
+// version. The document is only parsed again when its text changed.
+func (ls *LanguageServer) changeDocument(change DocumentChange) (int, bool, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
 
-		case *ast.TypeSpec:
-			pos := ls.fileSet.Position(node.Pos())
-			end := ls.fileSet.Position(node.End())
-
-			symbols = append(symbols, SymbolInfo{
-				Name: node.Name.Name,
-				Kind: "type",
-				Location: Location{
-					URI: file.Name.Name,
-					Range: Range{
-						Start: Position{Line: pos.Line - 1, Character: pos.Column - 1},
-						End:   Position{Line: end.Line - 1, Character: end.Column - 1},
-					},
-				},
-			})
+	doc, ok := ls.documents[change.URI]
+	if !ok {
+		return 0, false, errDocumentNotFound
+	}
+	version := doc.Version + 1
+	if change.Version != 0 {
+		if change.Version <= doc.Version {
+			return 0, false, fmt.Errorf("%w: %d is not newer than %d", errStaleVersion, change.Version, doc.Version)
 		}
-		return true
-	})
-
-	return symbols
-}
+		version = change.Version
+	}
 
-func (ls *LanguageServer) getFunctionSignature(fn *ast.FuncDecl) string {
-	var params []string
-	if fn.Type.Params != nil {
-		for _, param := range fn.Type.Params.List {
-			paramType := ls.nodeToString(param.Type)
-			for _, name := range param.Names {
-				params = append(params, fmt.Sprintf("%s %s", name.Name, paramType))
-			}
+	changes := change.ContentChanges
+	if len(changes) == 0 {
+		changes = []TextDocumentContentChangeEvent{{Text: change.Text}}
+	}
+	text := doc.Text
+	for i, c := range changes {
+		if c.Range == nil {
+			text = c.Text
+			continue
+		}
+		var err error
+		if text, err = applyTextEdits(text, []TextEdit{{Range: *c.Range, NewText: c.Text}}); err != nil {
+			return 0, false, fmt.Errorf("content change %d: %v", i, err)
 		}
 	}
 
-	var returns []string
-	if fn.Type.Results != nil {
-		for _, result := range fn.Type.Results.List {
-			resultType := ls.nodeToString(result.Type)
-			if len(result.Names) > 0 {
-				for _, name := range result.Names {
-					returns = append(returns, fmt.Sprintf("%s %s", name.Name, resultType))
-				}
-			} else {
-				returns = append(returns, resultType)
-			}
+	if text == doc.Text {
+		updated := *doc
+		updated.Version = version
+		if doc.ParsedVersion == doc.Version {
+			updated.ParsedVersion = version
 		}
+		ls.documents[change.URI] = &updated
+		return version, false, nil
 	}
+	if err := ls.storeDocument(change.URI, text, version); err != nil {
+		return 0, false, err
+	}
+	return version, true, nil
+}
 
-	signature := fmt.Sprintf("func %s(%s)", fn.Name.Name, strings.Join(params, ", "))
-	if len(returns) > 0 {
-		if len(returns) == 1 {
-			signature += " " + returns[0]
-		} else {
-			signature += fmt.Sprintf(" (%s)", strings.Join(returns, ", "))
-		}
+// forget releases the positions of the AST of a document that is closed or
+// replaced
+func (ls *LanguageServer) forget(file *ast.File) {
+	if file == nil {
+		return
+	}
+	if f := ls.fileSet.File(file.Pos()); f != nil {
+		ls.fileSet.RemoveFile(f)
 	}
+}
 
-	return signature
+// closeDocument drops an open document
+func (ls *LanguageServer) closeDocument(uri string) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if doc, ok := ls.documents[uri]; ok {
+		ls.forget(doc.AST)
+		delete(ls.documents, uri)
+	}
 }
 
-func (ls *LanguageServer) nodeToString(node ast.Node) string {
-	switch n := node.(type) {
-	case *ast.Ident:
-		return n.Name
-	case *ast.StarExpr:
-		return "*" + ls.nodeToString(n.X)
-	case *ast.ArrayType:
-		return "[]" + ls.nodeToString(n.Elt)
-	case *ast.SelectorExpr:
-		return ls.nodeToString(n.X) + "." + n.Sel.Name
-	default:
-		return fmt.Sprintf("%T", node)
+// text returns the text of an open document
+func (ls *LanguageServer) text(uri string) (string, error) {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	doc, ok := ls.documents[uri]
+	if !ok {
+		return "", errDocumentNotFound
 	}
+	return doc.Text, nil
 }
 
 // Handler functions
@@ -220,7 +424,12 @@ func handleOpenDocument(ls *LanguageServer) http.HandlerFunc {
 			return
 		}
 
-		if err := ls.parseDocument(doc.URI, doc.Text); err != nil {
+		err := ls.openDocument(doc)
+		switch {
+		case errors.Is(err, errStaleVersion):
+			writeError(w, http.StatusConflict, err)
+			return
+		case err != nil:
 			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
@@ -240,9 +449,7 @@ func handleCloseDocument(ls *LanguageServer) http.HandlerFunc {
 			return
 		}
 
-		ls.mu.Lock()
-		delete(ls.documents, doc.URI)
-		ls.mu.Unlock()
+		ls.closeDocument(doc.URI)
 
 		writeJSON(w, http.StatusOK, map[string]string{
 			"status": "closed",
@@ -253,20 +460,33 @@ func handleCloseDocument(ls *LanguageServer) http.HandlerFunc {
 
 func handleChangeDocument(ls *LanguageServer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var doc TextDocumentItem
-		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		var change DocumentChange
+		if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
 			writeError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		if err := ls.parseDocument(doc.URI, doc.Text); err != nil {
-			writeError(w, http.StatusInternalServerError, err)
+		version, changed, err := ls.changeDocument(change)
+		switch {
+		case errors.Is(err, errDocumentNotFound):
+			writeError(w, http.StatusNotFound, err)
+			return
+		case errors.Is(err, errStaleVersion):
+			writeError(w, http.StatusConflict, err)
+			return
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err)
 			return
 		}
 
+		status := "unchanged"
+		if changed {
+			status = "changed"
+		}
 		writeJSON(w, http.StatusOK, map[string]string{
-			"status": "changed",
-			"uri":    doc.URI,
+			"status":  status,
+			"uri":     change.URI,
+			"version": strconv.Itoa(version),
 		})
 	}
 }
@@ -292,35 +512,20 @@ func handleDocumentSymbols(ls *LanguageServer) http.HandlerFunc {
 	}
 }
 
-func handleCompletion(ls *LanguageServer) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Basic completion handler - can be extended based on needs
-		writeJSON(w, http.StatusNotImplemented, map[string]string{
-			"status": "not implemented",
-		})
+// positionAt converts a byte offset in content to a zero-based Position
+func positionAt(content string, offset int) Position {
+	if offset > len(content) {
+		offset = len(content)
 	}
+	line := strings.Count(content[:offset], "\n")
+	lineStart := strings.LastIndex(content[:offset], "\n") + 1
+	return Position{Line: line, Character: offset - lineStart}
 }
 
-func handleDefinition(ls *LanguageServer) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Basic definition handler - can be extended based on needs
-		writeJSON(w, http.StatusNotImplemented, map[string]string{
-			"status": "not implemented",
-		})
-	}
-}
-
-func handleHover(ls *LanguageServer) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Basic hover handler - can be extended based on needs
-		writeJSON(w, http.StatusNotImplemented, map[string]string{
-			"status": "not implemented",
-		})
-	}
-}
-
-// Helper method for the Server struct
+// GetWorkspaceRoot returns the configured workspace root directory
 func (s *Server) GetWorkspaceRoot() string {
-	// This should be configurable in your actual implementation
-	return "."
+	if s.config.WorkspaceRoot == "" {
+		return "."
+	}
+	return s.config.WorkspaceRoot
 }
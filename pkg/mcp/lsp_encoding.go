@@ -0,0 +1,209 @@
+package mcp
+
+import (
+	"os"
+	"strings"
+)
+
+// Position encodings of LSP. The language server counts bytes; editors not
+// offering utf-8 count UTF-16 code units, the default of the protocol.
+const (
+	lspEncodingUTF8  = "utf-8"
+	lspEncodingUTF16 = "utf-16"
+)
+
+// lspPositionEncoding picks utf-8 when the editor offers it and utf-16,
+// which every editor supports, otherwise
+func lspPositionEncoding(offered []string) string {
+	if containsString(offered, lspEncodingUTF8) {
+		return lspEncodingUTF8
+	}
+	return lspEncodingUTF16
+}
+
+// utf16Len returns the number of UTF-16 code units encoding r
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// utf16Column converts a byte offset in a line to UTF-16 code units
+func utf16Column(line string, column int) int {
+	if column < 0 {
+		return column
+	}
+	if column > len(line) {
+		return column - len(line) + utf16Column(line, len(line))
+	}
+	units := 0
+	for _, r := range line[:column] {
+		units += utf16Len(r)
+	}
+	return units
+}
+
+// byteColumn converts an offset in UTF-16 code units in a line to bytes.
+// Offsets inside a character move to its start, and offsets beyond the end
+// of the line stay beyond it.
+func byteColumn(line string, column int) int {
+	if column < 0 {
+		return column
+	}
+	units := 0
+	for i, r := range line {
+		if units >= column {
+			return i
+		}
+		n := utf16Len(r)
+		if units+n > column {
+			return i
+		}
+		units += n
+	}
+	return len(line) + column - units
+}
+
+// lspPositions converts the positions of a request or its result between
+// the bytes of the language server and the encoding of the session, reading
+// each document once. Documents open in the session are read from memory
+// and others from disk; positions in documents that cannot be read are left
+// as they are.
+type lspPositions struct {
+	m     *lspSession
+	utf16 bool
+	lines map[string][]string
+}
+
+func (m *lspSession) positions() *lspPositions {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &lspPositions{m: m, utf16: m.encoding == lspEncodingUTF16, lines: make(map[string][]string)}
+}
+
+// document sets the text of a document read along with the positions in it
+func (p *lspPositions) document(uri, text string) {
+	p.lines[uri] = strings.Split(text, "\n")
+}
+
+// line returns a line of a document, reporting whether it was read
+func (p *lspPositions) line(uri string, n int) (string, bool) {
+	lines, ok := p.lines[uri]
+	if !ok {
+		text, err := p.m.ls.text(uri)
+		if err != nil {
+			data, readErr := os.ReadFile(p.m.ls.documentPath(uri))
+			if readErr == nil {
+				text, err = string(data), nil
+			}
+		}
+		if err == nil {
+			lines = strings.Split(text, "\n")
+		}
+		p.lines[uri] = lines
+	}
+	if n < 0 || n >= len(lines) {
+		return "", false
+	}
+	return lines[n], true
+}
+
+// toBytes converts a position the editor sent
+func (p *lspPositions) toBytes(uri string, pos Position) Position {
+	if !p.utf16 {
+		return pos
+	}
+	if line, ok := p.line(uri, pos.Line); ok {
+		pos.Character = byteColumn(line, pos.Character)
+	}
+	return pos
+}
+
+// toBytesRange converts a range the editor sent
+func (p *lspPositions) toBytesRange(uri string, r *Range) *Range {
+	if r == nil {
+		return nil
+	}
+	return &Range{Start: p.toBytes(uri, r.Start), End: p.toBytes(uri, r.End)}
+}
+
+// position converts a position sent to the editor
+func (p *lspPositions) position(uri string, pos Position) Position {
+	if !p.utf16 {
+		return pos
+	}
+	if line, ok := p.line(uri, pos.Line); ok {
+		pos.Character = utf16Column(line, pos.Character)
+	}
+	return pos
+}
+
+func (p *lspPositions) rangeOf(uri string, r Range) Range {
+	return Range{Start: p.position(uri, r.Start), End: p.position(uri, r.End)}
+}
+
+func (p *lspPositions) ranges(uri string, ranges []Range) []Range {
+	converted := make([]Range, len(ranges))
+	for i, r := range ranges {
+		converted[i] = p.rangeOf(uri, r)
+	}
+	return converted
+}
+
+func (p *lspPositions) location(loc Location) Location {
+	return Location{URI: loc.URI, Range: p.rangeOf(loc.URI, loc.Range)}
+}
+
+func (p *lspPositions) locations(locations []Location) []Location {
+	converted := make([]Location, len(locations))
+	for i, loc := range locations {
+		converted[i] = p.location(loc)
+	}
+	return converted
+}
+
+func (p *lspPositions) textEdits(uri string, edits []TextEdit) []TextEdit {
+	converted := make([]TextEdit, len(edits))
+	for i, edit := range edits {
+		converted[i] = TextEdit{Range: p.rangeOf(uri, edit.Range), NewText: edit.NewText}
+	}
+	return converted
+}
+
+func (p *lspPositions) workspaceEdit(edit WorkspaceEdit) WorkspaceEdit {
+	if edit.Changes == nil {
+		return edit
+	}
+	converted := WorkspaceEdit{Changes: make(map[string][]TextEdit, len(edit.Changes))}
+	for uri, edits := range edit.Changes {
+		converted.Changes[uri] = p.textEdits(uri, edits)
+	}
+	return converted
+}
+
+func (p *lspPositions) diagnostics(diagnostics []Diagnostic) []Diagnostic {
+	converted := make([]Diagnostic, len(diagnostics))
+	for i, d := range diagnostics {
+		converted[i] = d
+		converted[i].Location = p.location(d.Location)
+	}
+	return converted
+}
+
+func (p *lspPositions) documentSymbols(uri string, symbols []DocumentSymbol) []DocumentSymbol {
+	converted := make([]DocumentSymbol, len(symbols))
+	for i, symbol := range symbols {
+		converted[i] = symbol
+		converted[i].Range = p.rangeOf(uri, symbol.Range)
+		converted[i].SelectionRange = p.rangeOf(uri, symbol.SelectionRange)
+		converted[i].Children = p.documentSymbols(uri, symbol.Children)
+	}
+	return converted
+}
+
+func (p *lspPositions) callHierarchyItem(item CallHierarchyItem) CallHierarchyItem {
+	item.Range = p.rangeOf(item.URI, item.Range)
+	item.SelectionRange = p.rangeOf(item.URI, item.SelectionRange)
+	return item
+}
@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLSPColumns(t *testing.T) {
+	line := `s := "é😀x"`
+	tests := []struct {
+		name  string
+		bytes int
+		utf16 int
+	}{
+		{"start", 0, 0},
+		{"ascii", 5, 5},
+		{"after two bytes", 8, 7},
+		{"after surrogate pair", 12, 9},
+		{"end", len(line), 11},
+		{"beyond the end", len(line) + 2, 13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.utf16, utf16Column(line, tt.bytes))
+			assert.Equal(t, tt.bytes, byteColumn(line, tt.utf16))
+		})
+	}
+
+	// Offsets inside a character move to its start
+	assert.Equal(t, 8, byteColumn(line, 8))
+	assert.Equal(t, -1, byteColumn(line, -1))
+}
+
+func TestLSPPositionEncoding(t *testing.T) {
+	source := "package p\n\nvar s = \"héllo😀\"; var x = 1\n"
+
+	tests := []struct {
+		name      string
+		encodings string
+		encoding  string
+		character int // Of x on line 2
+	}{
+		{"no encodings", `{}`, lspEncodingUTF16, 23},
+		{"utf-16 only", `{"general":{"positionEncodings":["utf-16"]}}`, lspEncodingUTF16, 23},
+		{"utf-8 offered", `{"general":{"positionEncodings":["utf-16","utf-8"]}}`, lspEncodingUTF8, 26},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.WorkspaceRoot = t.TempDir()
+			s := NewServer(nil, WithConfig(cfg))
+			s.AddLanguageServerHandler()
+
+			open, err := json.Marshal(lspDidOpenParams{TextDocument: lspTextDocumentItem{URI: "p.go", LanguageID: "go", Version: 1, Text: source}})
+			require.NoError(t, err)
+			change, err := json.Marshal(lspDidChangeParams{
+				TextDocument: lspTextDocumentIdentifier{URI: "p.go", Version: 2},
+				ContentChanges: []TextDocumentContentChangeEvent{{
+					Range: &Range{Start: Position{Line: 2, Character: tt.character}, End: Position{Line: 2, Character: tt.character}},
+					Text:  "y",
+				}},
+			})
+			require.NoError(t, err)
+			initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":` + tt.encodings + `}}`
+			symbols := `{"jsonrpc":"2.0","id":2,"method":"textDocument/documentSymbol","params":{"textDocument":{"uri":"p.go"}}}`
+			// Requests are answered concurrently, so the change is sent in a
+			// second session of the same documents
+			responses, _ := serveLSP(t, s, initialize,
+				`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":`+string(open)+`}`, symbols)
+			changed, _ := serveLSP(t, s, initialize,
+				`{"jsonrpc":"2.0","method":"textDocument/didChange","params":`+string(change)+`}`, symbols)
+
+			var init lspInitializeResult
+			require.NoError(t, json.Unmarshal(responses["1"].Result, &init))
+			assert.Equal(t, tt.encoding, init.Capabilities.PositionEncoding)
+
+			var found []lspDocumentSymbol
+			require.NoError(t, json.Unmarshal(responses["2"].Result, &found))
+			require.Len(t, found, 2)
+			assert.Equal(t, "x", found[1].Name)
+			assert.Equal(t, Position{Line: 2, Character: tt.character}, found[1].SelectionRange.Start)
+
+			// The change lands before x
+			require.NoError(t, json.Unmarshal(changed["2"].Result, &found))
+			require.Len(t, found, 2)
+			assert.Equal(t, "yx", found[1].Name)
+			assert.Equal(t, Position{Line: 2, Character: tt.character + 2}, found[1].SelectionRange.End)
+		})
+	}
+}
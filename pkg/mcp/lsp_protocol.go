@@ -0,0 +1,1016 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// LSP error codes, next to the JSON-RPC ones
+const (
+	lspServerNotInitialized = -32002
+	lspRequestFailed        = -32803
+)
+
+// LSP text document sync kinds
+const (
+	lspSyncFull        = 1
+	lspSyncIncremental = 2
+)
+
+// lspSymbolKinds maps the kinds of symbols to LSP SymbolKind numbers
+var lspSymbolKinds = map[string]int{
+	"method":    6,
+	"field":     8,
+	"interface": 11,
+	"function":  12,
+	"variable":  13,
+	"constant":  14,
+	"struct":    23,
+	"type":      5, // Class, as other Go types are shown by editors
+}
+
+// lspHighlightKinds maps the kinds of highlights to LSP
+// DocumentHighlightKind numbers
+var lspHighlightKinds = map[string]int{
+	HighlightRead:  2,
+	HighlightWrite: 3,
+}
+
+// lspSeverities maps diagnostic severities to LSP DiagnosticSeverity numbers
+var lspSeverities = map[string]int{
+	"error":   1,
+	"warning": 2,
+	"info":    3,
+	"hint":    4,
+}
+
+type lspTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version,omitempty"`
+}
+
+type lspTextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type lspDidOpenParams struct {
+	TextDocument lspTextDocumentItem `json:"textDocument"`
+}
+
+type lspDidChangeParams struct {
+	TextDocument   lspTextDocumentIdentifier        `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+// lspDocumentParams are the params of requests about a document, at a
+// position, in a range or with a new name depending on the method
+type lspDocumentParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Position     Position                  `json:"position"`
+	Range        *Range                    `json:"range"`
+	NewName      string                    `json:"newName"`
+	Context      struct {
+		Only []string `json:"only"`
+	} `json:"context"`
+}
+
+type lspCancelParams struct {
+	ID json.RawMessage `json:"id"`
+}
+
+type lspInitializeResult struct {
+	Capabilities lspServerCapabilities `json:"capabilities"`
+	ServerInfo   mcpImplementation     `json:"serverInfo"`
+}
+
+// lspServerCapabilities lists the requests the server answers, and the
+// position encoding negotiated with the editor
+type lspServerCapabilities struct {
+	PositionEncoding                string                     `json:"positionEncoding"`
+	TextDocumentSync                lspTextDocumentSyncOptions `json:"textDocumentSync"`
+	CompletionProvider              lspCompletionOptions       `json:"completionProvider"`
+	HoverProvider                   bool                       `json:"hoverProvider"`
+	DefinitionProvider              bool                       `json:"definitionProvider"`
+	ImplementationProvider          bool                       `json:"implementationProvider"`
+	DocumentHighlightProvider       bool                       `json:"documentHighlightProvider"`
+	DocumentSymbolProvider          bool                       `json:"documentSymbolProvider"`
+	WorkspaceSymbolProvider         bool                       `json:"workspaceSymbolProvider"`
+	RenameProvider                  bool                       `json:"renameProvider"`
+	DocumentFormattingProvider      bool                       `json:"documentFormattingProvider"`
+	DocumentRangeFormattingProvider bool                       `json:"documentRangeFormattingProvider"`
+	CodeActionProvider              bool                       `json:"codeActionProvider"`
+	FoldingRangeProvider            bool                       `json:"foldingRangeProvider"`
+	CallHierarchyProvider           bool                       `json:"callHierarchyProvider"`
+	ExecuteCommandProvider          lspExecuteCommandOptions   `json:"executeCommandProvider"`
+}
+
+type lspTextDocumentSyncOptions struct {
+	OpenClose bool `json:"openClose"`
+	Change    int  `json:"change"`
+}
+
+type lspCompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters"`
+}
+
+type lspExecuteCommandOptions struct {
+	Commands []string `json:"commands"`
+}
+
+// lspDocumentSymbol is a DocumentSymbol with an LSP SymbolKind
+type lspDocumentSymbol struct {
+	Name           string              `json:"name"`
+	Detail         string              `json:"detail,omitempty"`
+	Kind           int                 `json:"kind"`
+	Range          Range               `json:"range"`
+	SelectionRange Range               `json:"selectionRange"`
+	Children       []lspDocumentSymbol `json:"children,omitempty"`
+}
+
+type lspSymbolInformation struct {
+	Name          string   `json:"name"`
+	Kind          int      `json:"kind"`
+	Location      Location `json:"location"`
+	ContainerName string   `json:"containerName,omitempty"`
+}
+
+type lspDiagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Code     string `json:"code,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Message  string `json:"message"`
+}
+
+type lspPublishDiagnosticsParams struct {
+	URI         string          `json:"uri"`
+	Version     int             `json:"version,omitempty"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+type lspCodeAction struct {
+	Title       string          `json:"title"`
+	Kind        string          `json:"kind"`
+	Diagnostics []lspDiagnostic `json:"diagnostics,omitempty"`
+	Edit        WorkspaceEdit   `json:"edit"`
+	Command     *Command        `json:"command,omitempty"`
+}
+
+type lspCallHierarchyItem struct {
+	Name           string `json:"name"`
+	Kind           int    `json:"kind"`
+	Detail         string `json:"detail,omitempty"`
+	URI            string `json:"uri"`
+	Range          Range  `json:"range"`
+	SelectionRange Range  `json:"selectionRange"`
+}
+
+type lspCallHierarchyCall struct {
+	From       *lspCallHierarchyItem `json:"from,omitempty"`
+	To         *lspCallHierarchyItem `json:"to,omitempty"`
+	FromRanges []Range               `json:"fromRanges"`
+}
+
+type lspDocumentHighlight struct {
+	Range Range `json:"range"`
+	Kind  int   `json:"kind"`
+}
+
+type lspApplyEditParams struct {
+	Label string        `json:"label,omitempty"`
+	Edit  WorkspaceEdit `json:"edit"`
+}
+
+// lspConn reads and writes the JSON-RPC messages of an LSP session
+type lspConn interface {
+	Read() ([]byte, error)
+	Write(data []byte) error
+}
+
+// lspStream frames messages with Content-Length headers, as editors do
+// over stdio
+type lspStream struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+func (c *lspStream) Read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if length < 0 {
+				continue
+			}
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid Content-Length %q", strings.TrimSpace(value))
+			}
+		}
+	}
+	if length > maxStdioMessage {
+		return nil, fmt.Errorf("message of %d bytes is too large", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *lspStream) Write(data []byte) error {
+	_, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+// lspSocket carries one message per WebSocket frame
+type lspSocket struct {
+	ws *websocket.Conn
+}
+
+func (c *lspSocket) Read() ([]byte, error) {
+	var data []byte
+	err := websocket.Message.Receive(c.ws, &data)
+	return data, err
+}
+
+func (c *lspSocket) Write(data []byte) error {
+	return websocket.Message.Send(c.ws, string(data))
+}
+
+// lspSession speaks the Language Server Protocol with an editor, on the
+// documents of the server's LanguageServer
+type lspSession struct {
+	s       *Server
+	ls      *LanguageServer
+	send    func(v interface{})
+	methods map[string]rpcMethod
+
+	mu          sync.Mutex
+	initialized bool
+	encoding    string // Of the characters of positions
+	shutdown    bool
+	exited      bool
+	inflight    map[string]context.CancelFunc
+	nextID      int // Of requests sent to the editor
+}
+
+// ServeLSP speaks the Language Server Protocol over Content-Length framed
+// JSON-RPC messages read from r and written to w, so editors can launch the
+// server as their Go language server. Sessions share the documents of the
+// /lsp routes. Notifications are handled in order and requests
+// concurrently; it returns once the editor sends exit or r reaches EOF and
+// pending requests are answered, or when ctx is done.
+func (s *Server) ServeLSP(ctx context.Context, r io.Reader, w io.Writer) error {
+	return s.serveLSP(ctx, &lspStream{r: bufio.NewReader(r), w: w})
+}
+
+func (s *Server) serveLSP(ctx context.Context, conn lspConn) error {
+	if s.lsp == nil {
+		return fmt.Errorf("%w: %s", ErrFeatureDisabled, FeatureLSP)
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, loggerKey{}, s.logger))
+	defer cancel()
+	var wg sync.WaitGroup
+
+	var writeMu sync.Mutex
+	session := s.newLSPSession(func(v interface{}) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			s.logger.Error("failed to encode lsp message", "error", err)
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := conn.Write(data); err != nil {
+			s.logger.Error("failed to write lsp message", "error", err)
+		}
+	})
+
+	// Reads block, so they run apart from the loop watching ctx
+	messages := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			data, err := conn.Read()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case messages <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case err := <-readErr:
+			wg.Wait()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case data := <-messages:
+			var head struct {
+				Method string          `json:"method"`
+				ID     json.RawMessage `json:"id"`
+			}
+			if err := json.Unmarshal(data, &head); err != nil {
+				session.send(&RPCResponse{
+					JSONRPC: JSONRPCVersion,
+					Error:   &RPCError{Code: RPCParseError, Message: "parse error"},
+					ID:      json.RawMessage("null"),
+				})
+				continue
+			}
+			switch {
+			case head.Method == "":
+				// A response to a request sent to the editor, which needs
+				// no follow-up
+			case requestKey(head.ID) == "" || head.Method == "initialize":
+				// Notifications change documents, so they run in order, as
+				// does initialize, which the messages after it depend on
+				if resp := s.dispatch(ctx, data, session.lookup); resp != nil {
+					session.send(resp)
+				}
+				if session.hasExited() {
+					wg.Wait()
+					return nil
+				}
+			default:
+				// Requests run concurrently, resolved on arrival so they see
+				// the session as the messages before them left it
+				method, found := session.lookup(head.Method)
+				lookup := func(string) (rpcMethod, bool) { return method, found }
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if resp := session.handleRequest(ctx, data, requestKey(head.ID), lookup); resp != nil {
+						session.send(resp)
+					}
+				}()
+			}
+		}
+	}
+}
+
+func (s *Server) newLSPSession(send func(v interface{})) *lspSession {
+	m := &lspSession{
+		s:        s,
+		ls:       s.lsp,
+		send:     send,
+		inflight: make(map[string]context.CancelFunc),
+	}
+	m.methods = map[string]rpcMethod{
+		"initialize":                        m.initialize,
+		"initialized":                       m.ignore,
+		"shutdown":                          m.shutdownRequest,
+		"exit":                              m.exit,
+		"$/cancelRequest":                   m.cancelRequest,
+		"textDocument/didOpen":              m.didOpen,
+		"textDocument/didChange":            m.didChange,
+		"textDocument/didClose":             m.didClose,
+		"textDocument/didSave":              m.ignore,
+		"textDocument/completion":           m.completion,
+		"textDocument/hover":                m.hover,
+		"textDocument/documentHighlight":    m.documentHighlight,
+		"textDocument/implementation":       m.implementation,
+		"textDocument/definition":           m.definition,
+		"textDocument/documentSymbol":       m.documentSymbol,
+		"textDocument/foldingRange":         m.foldingRange,
+		"textDocument/formatting":           m.formatting,
+		"textDocument/rangeFormatting":      m.formatting,
+		"textDocument/rename":               m.rename,
+		"textDocument/codeAction":           m.codeAction,
+		"textDocument/prepareCallHierarchy": m.prepareCallHierarchy,
+		"callHierarchy/incomingCalls":       m.incomingCalls,
+		"callHierarchy/outgoingCalls":       m.outgoingCalls,
+		"workspace/symbol":                  m.workspaceSymbol,
+		"workspace/executeCommand":          m.executeCommand,
+	}
+	return m
+}
+
+// handleRequest runs a request cancellable by $/cancelRequest. Cancelled
+// requests get no response.
+func (m *lspSession) handleRequest(ctx context.Context, raw json.RawMessage, key string, lookup func(string) (rpcMethod, bool)) *RPCResponse {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m.mu.Lock()
+	m.inflight[key] = cancel
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.inflight, key)
+		m.mu.Unlock()
+	}()
+
+	resp := m.s.dispatch(ctx, raw, lookup)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return resp
+}
+
+// lookup finds a method of the session. Until the editor initialized the
+// session only initialize and exit are served, and after shutdown only
+// exit. Notifications of the protocol the server does not handle, which
+// start with $/, are ignored.
+func (m *lspSession) lookup(name string) (rpcMethod, bool) {
+	method, ok := m.methods[name]
+	if !ok {
+		if strings.HasPrefix(name, "$/") {
+			return m.ignore, true
+		}
+		return nil, false
+	}
+	if name != "exit" && !m.s.FeatureEnabled(FeatureLSP) {
+		return func(context.Context, json.RawMessage) (interface{}, error) {
+			return nil, fmt.Errorf("%w: %s", ErrFeatureDisabled, FeatureLSP)
+		}, true
+	}
+
+	m.mu.Lock()
+	initialized, shutdown := m.initialized, m.shutdown
+	m.mu.Unlock()
+	switch {
+	case name == "exit" || name == "initialize" && !initialized:
+		return method, true
+	case !initialized:
+		return func(context.Context, json.RawMessage) (interface{}, error) {
+			return nil, &RPCError{Code: lspServerNotInitialized, Message: "server not initialized"}
+		}, true
+	case shutdown:
+		return func(context.Context, json.RawMessage) (interface{}, error) {
+			return nil, &RPCError{Code: RPCInvalidRequest, Message: "server is shutting down"}
+		}, true
+	}
+	return method, true
+}
+
+func (m *lspSession) hasExited() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.exited
+}
+
+// request sends a request to the editor without waiting for its response
+func (m *lspSession) request(method string, params interface{}) {
+	m.mu.Lock()
+	m.nextID++
+	id := m.nextID
+	m.mu.Unlock()
+	m.send(&struct {
+		JSONRPC string      `json:"jsonrpc"`
+		ID      int         `json:"id"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params"`
+	}{JSONRPCVersion, id, method, params})
+}
+
+// lspError maps the errors of the language server to LSP errors
+func lspError(err error) error {
+	if errors.Is(err, errDocumentNotFound) {
+		return invalidParams(err)
+	}
+	return &RPCError{Code: lspRequestFailed, Message: err.Error()}
+}
+
+func (m *lspSession) initialize(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ClientInfo   mcpImplementation `json:"clientInfo"`
+		RootURI      string            `json:"rootUri"`
+		Capabilities struct {
+			General struct {
+				PositionEncodings []string `json:"positionEncodings"`
+			} `json:"general"`
+		} `json:"capabilities"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	encoding := lspPositionEncoding(p.Capabilities.General.PositionEncodings)
+
+	m.mu.Lock()
+	m.initialized = true
+	m.encoding = encoding
+	m.mu.Unlock()
+	LoggerFromContext(ctx).Info("lsp session initialized", "client", p.ClientInfo.Name, "client_version", p.ClientInfo.Version,
+		"root_uri", p.RootURI, "workspace_root", m.ls.workspaceRoot, "position_encoding", encoding)
+
+	return &lspInitializeResult{
+		Capabilities: lspServerCapabilities{
+			PositionEncoding:                encoding,
+			TextDocumentSync:                lspTextDocumentSyncOptions{OpenClose: true, Change: lspSyncIncremental},
+			CompletionProvider:              lspCompletionOptions{TriggerCharacters: []string{"."}},
+			HoverProvider:                   true,
+			DefinitionProvider:              true,
+			ImplementationProvider:          true,
+			DocumentHighlightProvider:       true,
+			DocumentSymbolProvider:          true,
+			WorkspaceSymbolProvider:         true,
+			RenameProvider:                  true,
+			DocumentFormattingProvider:      true,
+			DocumentRangeFormattingProvider: true,
+			CodeActionProvider:              true,
+			FoldingRangeProvider:            true,
+			CallHierarchyProvider:           true,
+			ExecuteCommandProvider:          lspExecuteCommandOptions{Commands: []string{CommandApplyEdit}},
+		},
+		ServerInfo: mcpImplementation{Name: MCPServerName, Version: APIVersion},
+	}, nil
+}
+
+func (m *lspSession) ignore(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (m *lspSession) shutdownRequest(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	m.mu.Lock()
+	m.shutdown = true
+	m.mu.Unlock()
+	return nil, nil
+}
+
+func (m *lspSession) exit(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	m.mu.Lock()
+	m.exited = true
+	m.mu.Unlock()
+	return nil, nil
+}
+
+func (m *lspSession) cancelRequest(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspCancelParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	cancel, ok := m.inflight[requestKey(p.ID)]
+	m.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return nil, nil
+}
+
+func (m *lspSession) didOpen(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDidOpenParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := m.ls.openDocument(TextDocumentItem{URI: p.TextDocument.URI, Text: p.TextDocument.Text, Version: p.TextDocument.Version}); err != nil {
+		return nil, lspError(err)
+	}
+	m.publishDiagnostics(p.TextDocument.URI)
+	return nil, nil
+}
+
+func (m *lspSession) didChange(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDidChangeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	changes, err := m.contentChanges(p.TextDocument.URI, p.ContentChanges)
+	if err != nil {
+		return nil, lspError(err)
+	}
+	_, changed, err := m.ls.changeDocument(DocumentChange{
+		URI:            p.TextDocument.URI,
+		Version:        p.TextDocument.Version,
+		ContentChanges: changes,
+	})
+	if err != nil {
+		return nil, lspError(err)
+	}
+	if changed {
+		m.publishDiagnostics(p.TextDocument.URI)
+	}
+	return nil, nil
+}
+
+// contentChanges converts the ranges of content changes to bytes, each in
+// the text the previous changes result in. Notifications are handled in
+// order, so the text does not change meanwhile.
+func (m *lspSession) contentChanges(uri string, changes []TextDocumentContentChangeEvent) ([]TextDocumentContentChangeEvent, error) {
+	positions := m.positions()
+	if !positions.utf16 {
+		return changes, nil
+	}
+	text, err := m.ls.text(uri)
+	if err != nil {
+		return nil, err
+	}
+	converted := make([]TextDocumentContentChangeEvent, len(changes))
+	for i, c := range changes {
+		converted[i] = c
+		if c.Range == nil {
+			text = c.Text
+			continue
+		}
+		positions.document(uri, text)
+		converted[i].Range = positions.toBytesRange(uri, c.Range)
+		if text, err = applyTextEdits(text, []TextEdit{{Range: *converted[i].Range, NewText: c.Text}}); err != nil {
+			return nil, fmt.Errorf("content change %d: %v", i, err)
+		}
+	}
+	return converted, nil
+}
+
+func (m *lspSession) didClose(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	m.ls.closeDocument(p.TextDocument.URI)
+	m.publishDiagnostics(p.TextDocument.URI)
+	return nil, nil
+}
+
+// publishDiagnostics sends the syntax errors and analyzer diagnostics of a
+// document, or none once it is closed
+func (m *lspSession) publishDiagnostics(uri string) {
+	published := lspPublishDiagnosticsParams{URI: uri, Diagnostics: []lspDiagnostic{}}
+	positions := m.positions()
+
+	// The analyzer reads positions from the file set, so it runs under the
+	// lock
+	var diagnostics []Diagnostic
+	m.ls.mu.RLock()
+	if doc, ok := m.ls.documents[uri]; ok {
+		published.Version = doc.Version
+		positions.document(uri, doc.Text)
+		diagnostics = append(diagnostics, doc.ParseErrors...)
+		if len(doc.ParseErrors) == 0 {
+			if analysis, err := NewASTAnalyzer(m.ls.fileSet).AnalyzeFile(doc.AST); err == nil {
+				diagnostics = append(diagnostics, analysis.Diagnostics...)
+			}
+		}
+	}
+	m.ls.mu.RUnlock()
+	published.Diagnostics = append(published.Diagnostics, lspDiagnostics(positions.diagnostics(diagnostics))...)
+
+	m.send(&mcpNotification{JSONRPC: JSONRPCVersion, Method: "textDocument/publishDiagnostics", Params: published})
+}
+
+func lspDiagnostics(diagnostics []Diagnostic) []lspDiagnostic {
+	converted := make([]lspDiagnostic, len(diagnostics))
+	for i, d := range diagnostics {
+		converted[i] = lspDiagnostic{
+			Range:    d.Location.Range,
+			Severity: lspSeverities[d.Severity],
+			Code:     d.Code,
+			Source:   d.Source,
+			Message:  d.Message,
+		}
+	}
+	return converted
+}
+
+func (m *lspSession) completion(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	text, err := m.ls.text(p.TextDocument.URI)
+	if err != nil {
+		return nil, lspError(err)
+	}
+	positions := m.positions()
+	positions.document(p.TextDocument.URI, text)
+	items, err := Complete(m.ls.documentPath(p.TextDocument.URI), text, positions.toBytes(p.TextDocument.URI, p.Position))
+	if err != nil {
+		return nil, lspError(err)
+	}
+	return items, nil
+}
+
+func (m *lspSession) hover(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	overlay, _, exists := m.ls.overlay(p.TextDocument.URI)
+	if !exists {
+		return nil, lspError(errDocumentNotFound)
+	}
+	positions := m.positions()
+	hover, err := DescribeSymbol(m.ls.documentPath(p.TextDocument.URI), overlay, positions.toBytes(p.TextDocument.URI, p.Position))
+	if err != nil {
+		return nil, lspError(err)
+	}
+	if hover == nil {
+		return nil, nil
+	}
+	hover.Range = positions.rangeOf(p.TextDocument.URI, hover.Range)
+	return hover, nil
+}
+
+func (m *lspSession) definition(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	positions := m.positions()
+	locations, err := m.ls.definition(p.TextDocument.URI, positions.toBytes(p.TextDocument.URI, p.Position))
+	if err != nil {
+		return nil, lspError(err)
+	}
+	return positions.locations(locations), nil
+}
+
+func (m *lspSession) documentHighlight(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	positions := m.positions()
+	highlights, err := m.ls.highlights(p.TextDocument.URI, positions.toBytes(p.TextDocument.URI, p.Position))
+	if err != nil {
+		return nil, lspError(err)
+	}
+	converted := make([]lspDocumentHighlight, len(highlights))
+	for i, highlight := range highlights {
+		converted[i] = lspDocumentHighlight{Range: positions.rangeOf(p.TextDocument.URI, highlight.Range), Kind: lspHighlightKinds[highlight.Kind]}
+	}
+	return converted, nil
+}
+
+func (m *lspSession) implementation(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	positions := m.positions()
+	found, err := m.ls.implementations(p.TextDocument.URI, positions.toBytes(p.TextDocument.URI, p.Position))
+	if err != nil {
+		return nil, lspError(err)
+	}
+	locations := make([]Location, len(found))
+	for i, impl := range found {
+		locations[i] = impl.Location
+	}
+	return positions.locations(locations), nil
+}
+
+func (m *lspSession) documentSymbol(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	m.ls.mu.RLock()
+	doc, ok := m.ls.documents[p.TextDocument.URI]
+	m.ls.mu.RUnlock()
+	if !ok {
+		return nil, lspError(errDocumentNotFound)
+	}
+	positions := m.positions()
+	positions.document(doc.URI, doc.Text)
+	return lspDocumentSymbols(positions.documentSymbols(doc.URI, doc.Symbols)), nil
+}
+
+func lspDocumentSymbols(symbols []DocumentSymbol) []lspDocumentSymbol {
+	converted := make([]lspDocumentSymbol, len(symbols))
+	for i, symbol := range symbols {
+		kind := symbol.Kind
+		if kind == "type" && (symbol.Detail == "struct" || symbol.Detail == "interface") {
+			kind = symbol.Detail
+		}
+		converted[i] = lspDocumentSymbol{
+			Name:           symbol.Name,
+			Detail:         symbol.Detail,
+			Kind:           lspSymbolKinds[kind],
+			Range:          symbol.Range,
+			SelectionRange: symbol.SelectionRange,
+			Children:       lspDocumentSymbols(symbol.Children),
+		}
+	}
+	return converted
+}
+
+func (m *lspSession) foldingRange(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	m.ls.mu.RLock()
+	defer m.ls.mu.RUnlock()
+	doc, ok := m.ls.documents[p.TextDocument.URI]
+	if !ok {
+		return nil, lspError(errDocumentNotFound)
+	}
+	return m.ls.documentFoldingRanges(doc), nil
+}
+
+func (m *lspSession) formatting(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	positions := m.positions()
+	edits, err := m.ls.format(FormatRequest{URI: p.TextDocument.URI, Range: positions.toBytesRange(p.TextDocument.URI, p.Range)})
+	if err != nil {
+		return nil, lspError(err)
+	}
+	return positions.textEdits(p.TextDocument.URI, edits), nil
+}
+
+func (m *lspSession) rename(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	positions := m.positions()
+	edit, err := m.ls.rename(p.TextDocument.URI, positions.toBytes(p.TextDocument.URI, p.Position), p.NewName)
+	if err != nil {
+		return nil, lspError(err)
+	}
+	return positions.workspaceEdit(edit), nil
+}
+
+func (m *lspSession) codeAction(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	positions := m.positions()
+	actions, err := m.ls.codeActions(CodeActionRequest{URI: p.TextDocument.URI, Range: positions.toBytesRange(p.TextDocument.URI, p.Range), Only: p.Context.Only})
+	if err != nil {
+		return nil, lspError(err)
+	}
+
+	// Editors apply the edit themselves; the command is for those that
+	// only run commands
+	converted := make([]lspCodeAction, len(actions))
+	for i, action := range actions {
+		converted[i] = lspCodeAction{
+			Title:       action.Title,
+			Kind:        action.Kind,
+			Diagnostics: lspDiagnostics(positions.diagnostics(action.Diagnostics)),
+			Edit:        positions.workspaceEdit(action.Edit),
+		}
+		if len(action.Edit.Changes) == 0 {
+			converted[i].Command = action.Command
+		}
+	}
+	return converted, nil
+}
+
+func (m *lspSession) prepareCallHierarchy(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p lspDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	positions := m.positions()
+	hierarchy, err := m.ls.callHierarchy(p.TextDocument.URI, positions.toBytes(p.TextDocument.URI, p.Position), CallsOutgoing)
+	if err != nil {
+		return nil, lspError(err)
+	}
+	return []lspCallHierarchyItem{toLSPCallHierarchyItem(positions.callHierarchyItem(hierarchy.Item))}, nil
+}
+
+// incomingCalls and outgoingCalls look the item up again by the start of
+// its name, which prepareCallHierarchy returned
+func (m *lspSession) incomingCalls(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Item lspCallHierarchyItem `json:"item"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	positions := m.positions()
+	hierarchy, err := m.ls.callHierarchy(p.Item.URI, positions.toBytes(p.Item.URI, p.Item.SelectionRange.Start), CallsIncoming)
+	if err != nil {
+		return nil, lspError(err)
+	}
+	calls := make([]lspCallHierarchyCall, len(hierarchy.Incoming))
+	for i, call := range hierarchy.Incoming {
+		from := toLSPCallHierarchyItem(positions.callHierarchyItem(call.From))
+		calls[i] = lspCallHierarchyCall{From: &from, FromRanges: positions.ranges(call.From.URI, call.FromRanges)}
+	}
+	return calls, nil
+}
+
+func (m *lspSession) outgoingCalls(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Item lspCallHierarchyItem `json:"item"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	positions := m.positions()
+	hierarchy, err := m.ls.callHierarchy(p.Item.URI, positions.toBytes(p.Item.URI, p.Item.SelectionRange.Start), CallsOutgoing)
+	if err != nil {
+		return nil, lspError(err)
+	}
+	calls := make([]lspCallHierarchyCall, len(hierarchy.Outgoing))
+	for i, call := range hierarchy.Outgoing {
+		to := toLSPCallHierarchyItem(positions.callHierarchyItem(call.To))
+		calls[i] = lspCallHierarchyCall{To: &to, FromRanges: positions.ranges(hierarchy.Item.URI, call.FromRanges)}
+	}
+	return calls, nil
+}
+
+func toLSPCallHierarchyItem(item CallHierarchyItem) lspCallHierarchyItem {
+	return lspCallHierarchyItem{
+		Name:           item.Name,
+		Kind:           lspSymbolKinds[item.Kind],
+		Detail:         item.Detail,
+		URI:            item.URI,
+		Range:          item.Range,
+		SelectionRange: item.SelectionRange,
+	}
+}
+
+func (m *lspSession) workspaceSymbol(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Query string `json:"query"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	select {
+	case <-m.ls.symbols.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	symbols := m.ls.symbols.search(p.Query, defaultSymbolLimit)
+	positions := m.positions()
+	converted := make([]lspSymbolInformation, len(symbols))
+	for i, symbol := range symbols {
+		converted[i] = lspSymbolInformation{
+			Name:          symbol.Name,
+			Kind:          lspSymbolKinds[symbol.Kind],
+			Location:      positions.location(symbol.Location),
+			ContainerName: symbol.Container,
+		}
+	}
+	return converted, nil
+}
+
+// executeCommand asks the editor to apply the edit of a command, so the
+// documents it has open change in place. Commands are made by the server,
+// so their edits count bytes.
+func (m *lspSession) executeCommand(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req ExecuteCommandRequest
+	if err := decodeParams(params, &req); err != nil {
+		return nil, err
+	}
+	edit, err := commandEdit(req)
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+	m.request("workspace/applyEdit", lspApplyEditParams{Edit: m.positions().workspaceEdit(edit)})
+	return nil, nil
+}
+
+// lspWebSocket serves LSP sessions over WebSockets. Browsers may only
+// connect from origins allowed by the CORS config.
+func (s *Server) lspWebSocket() http.HandlerFunc {
+	server := websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			origin := r.Header.Get("Origin")
+			if origin != "" && !(s.config.CORS.Enabled && NewCORS(s.config.CORS).originAllowed(origin)) {
+				return fmt.Errorf("origin %s is not allowed", origin)
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			// The connection outlives the deadlines of the upgrade request
+			ws.SetDeadline(time.Time{})
+			if err := s.serveLSP(ws.Request().Context(), &lspSocket{ws: ws}); err != nil && !errors.Is(err, context.Canceled) {
+				LoggerFromContext(ws.Request().Context()).Warn("lsp websocket session failed", "error", err)
+			}
+		},
+	}
+	return server.ServeHTTP
+}
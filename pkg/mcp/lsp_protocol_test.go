@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// lspMessage is a JSON-RPC message received from an LSP session
+type lspMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// serveLSP runs a stdio session over the given messages and returns the
+// responses by ID and the notifications and requests sent to the editor
+func serveLSP(t *testing.T, s *Server, messages ...string) (map[string]lspMessage, []lspMessage) {
+	t.Helper()
+	var in bytes.Buffer
+	for _, message := range messages {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(message), message)
+	}
+	var out bytes.Buffer
+	require.NoError(t, s.ServeLSP(context.Background(), &in, &out))
+
+	responses := make(map[string]lspMessage)
+	var sent []lspMessage
+	stream := &lspStream{r: bufio.NewReader(&out)}
+	for {
+		data, err := stream.Read()
+		if err != nil {
+			break
+		}
+		var message lspMessage
+		require.NoError(t, json.Unmarshal(data, &message), string(data))
+		if message.Method != "" {
+			sent = append(sent, message)
+		} else {
+			responses[string(message.ID)] = message
+		}
+	}
+	return responses, sent
+}
+
+func TestServeLSP(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = t.TempDir()
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+
+	open, err := json.Marshal(lspDidOpenParams{TextDocument: lspTextDocumentItem{URI: "example.go", LanguageID: "go", Version: 1, Text: outlineSource}})
+	require.NoError(t, err)
+	responses, sent := serveLSP(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"textDocument/documentSymbol","params":{"textDocument":{"uri":"example.go"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"clientInfo":{"name":"editor"},"capabilities":{}}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":`+string(open)+`}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"example.go","version":2},`+
+			`"contentChanges":[{"range":{"start":{"line":29,"character":0},"end":{"line":29,"character":0}},"text":"func Added() {}\n"}]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"broken.go","version":1,"text":"package broken\n\nfunc {"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"textDocument/documentSymbol","params":{"textDocument":{"uri":"example.go"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"textDocument/foldingRange","params":{"textDocument":{"uri":"missing.go"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"textDocument/unknown","params":{}}`,
+		`{"jsonrpc":"2.0","method":"$/setTrace","params":{"value":"off"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+		`{"jsonrpc":"2.0","id":7,"method":"shutdown"}`,
+	)
+
+	require.NotNil(t, responses["1"].Error)
+	assert.Equal(t, lspServerNotInitialized, responses["1"].Error.Code)
+
+	var init lspInitializeResult
+	require.NoError(t, json.Unmarshal(responses["2"].Result, &init))
+	assert.Equal(t, MCPServerName, init.ServerInfo.Name)
+	assert.Equal(t, lspSyncIncremental, init.Capabilities.TextDocumentSync.Change)
+	assert.Equal(t, []string{CommandApplyEdit}, init.Capabilities.ExecuteCommandProvider.Commands)
+
+	var symbols []lspDocumentSymbol
+	require.NoError(t, json.Unmarshal(responses["3"].Result, &symbols))
+	require.NotEmpty(t, symbols)
+	assert.Equal(t, "Greeter", symbols[0].Name)
+	assert.Equal(t, 23, symbols[0].Kind)
+	assert.Equal(t, 8, symbols[0].Children[0].Kind)
+	assert.Equal(t, "Added", symbols[len(symbols)-1].Name)
+	assert.Equal(t, 12, symbols[len(symbols)-1].Kind)
+
+	require.NotNil(t, responses["4"].Error)
+	assert.Equal(t, RPCInvalidParams, responses["4"].Error.Code)
+	require.NotNil(t, responses["5"].Error)
+	assert.Equal(t, RPCMethodNotFound, responses["5"].Error.Code)
+	assert.JSONEq(t, `null`, string(responses["6"].Result))
+	assert.NotContains(t, responses, "7", "messages after exit are not read")
+
+	published := make(map[string]lspPublishDiagnosticsParams)
+	for _, message := range sent {
+		require.Equal(t, "textDocument/publishDiagnostics", message.Method)
+		var params lspPublishDiagnosticsParams
+		require.NoError(t, json.Unmarshal(message.Params, &params))
+		published[params.URI] = params
+	}
+	assert.Equal(t, 2, published["example.go"].Version)
+	require.NotEmpty(t, published["broken.go"].Diagnostics)
+	assert.Equal(t, "parse-error", published["broken.go"].Diagnostics[0].Code)
+	assert.Equal(t, 1, published["broken.go"].Diagnostics[0].Severity)
+}
+
+func TestLSPStreamFraming(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		err   bool
+	}{
+		{"content length", "Content-Length: 2\r\n\r\n{}", "{}", false},
+		{"extra headers", "Content-Type: application/vscode-jsonrpc; charset=utf-8\r\ncontent-length: 4\r\n\r\nnull", "null", false},
+		{"blank lines before headers", "\r\nContent-Length: 2\r\n\r\n[]", "[]", false},
+		{"invalid length", "Content-Length: x\r\n\r\n", "", true},
+		{"too large", fmt.Sprintf("Content-Length: %d\r\n\r\n", maxStdioMessage+1), "", true},
+		{"truncated", "Content-Length: 10\r\n\r\n{}", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &lspStream{r: bufio.NewReader(strings.NewReader(tt.input))}
+			data, err := stream.Read()
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+		})
+	}
+}
+
+func TestLSPWebSocket(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = t.TempDir()
+	cfg.CORS.Enabled = true
+	cfg.CORS.AllowedOrigins = []string{"http://editor.test"}
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/lsp/ws"
+
+	_, err := websocket.Dial(url, "", "http://evil.test")
+	assert.Error(t, err, "origins not allowed by CORS are rejected")
+
+	ws, err := websocket.Dial(url, "", "http://editor.test")
+	require.NoError(t, err)
+	defer ws.Close()
+
+	call := func(message string) lspMessage {
+		require.NoError(t, websocket.Message.Send(ws, message))
+		var data []byte
+		require.NoError(t, websocket.Message.Receive(ws, &data))
+		var resp lspMessage
+		require.NoError(t, json.Unmarshal(data, &resp), string(data))
+		return resp
+	}
+
+	resp := call(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	assert.Nil(t, resp.Error)
+
+	// Documents opened over the HTTP routes are shared with the session
+	require.NoError(t, s.lsp.parseDocument("shared.go", "package shared\n\nfunc Shared() {}\n"))
+	resp = call(`{"jsonrpc":"2.0","id":2,"method":"textDocument/documentSymbol","params":{"textDocument":{"uri":"shared.go"}}}`)
+	require.Nil(t, resp.Error)
+	var symbols []lspDocumentSymbol
+	require.NoError(t, json.Unmarshal(resp.Result, &symbols))
+	require.Len(t, symbols, 1)
+	assert.Equal(t, "Shared", symbols[0].Name)
+}
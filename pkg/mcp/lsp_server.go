@@ -1,6 +1,7 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
 	"go/parser"
 	"go/token"
@@ -11,21 +12,192 @@ import (
 type AnalysisRequest struct {
 	URI     string `json:"uri"`
 	Content string `json:"content"`
+	Since   string `json:"since,omitempty"` // Only count the commits of the churn of metrics since this date, e.g. 3.months
 }
 
 // AddAnalysisHandler adds code analysis endpoints to the MCP server
 func (s *Server) AddAnalysisHandler() {
-	// Create analyzers
-	fset := token.NewFileSet()
-	analyzer := NewASTAnalyzer(fset)
+	// Every request is analyzed with an analyzer of its own, reading the
+	// positions of the file set the file was parsed into
+	s.analysis = true
+	coverage := newCoverageStore(s.GetWorkspaceRoot())
 
 	// Register analysis endpoints
-	s.router.HandleFunc("/analyze/file", handleFileAnalysis(analyzer)).Methods("POST")
-	s.router.HandleFunc("/analyze/dependencies", handleDependencyAnalysis(analyzer)).Methods("POST")
-	s.router.HandleFunc("/analyze/metrics", handleMetricsAnalysis(analyzer)).Methods("POST")
+	s.handle(Route{
+		Method: "POST", Path: "/analyze/file", Summary: "Analyze a Go source file, with its coverage when an uploaded coverage profile covers it",
+		Request: AnalysisRequest{}, Response: AnalysisResult{},
+	}, handleFileAnalysis(coverage))
+	s.handle(Route{
+		Method: "POST", Path: "/analyze/dependencies", Summary: "List the dependencies of a Go source file",
+		Request: AnalysisRequest{}, Response: map[string][]string{},
+	}, handleDependencyAnalysis())
+	s.handle(Route{
+		Method: "POST", Path: "/analyze/metrics", Summary: "Compute code metrics of a Go source file, with its git churn and authors when the workspace history has it",
+		Request: AnalysisRequest{}, Response: CodeMetrics{},
+	}, handleMetricsAnalysis(s.GetWorkspaceRoot()))
+	s.handle(Route{
+		Method: "GET", Path: "/analyze/hotspots", Summary: "Rank files by git churn combined with complexity",
+		Query: []QueryParam{
+			{Name: "since", Description: "Only count commits since this date, e.g. 3.months"},
+			{Name: "limit", Description: "Maximum number of files, defaults to 20"},
+			{Name: "path", Description: "Only include files under this path"},
+			{Name: "include_tests", Description: "Include _test.go files"},
+			{Name: "sort", Description: "score (default), commits, lines or authors"},
+		},
+		Response: HotspotReport{},
+	}, handleHotspots(s.GetWorkspaceRoot()))
+	s.handle(Route{
+		Method: "GET", Path: "/analyze/complexity", Summary: "Rank functions by complexity and size, warning about those above the thresholds and checking the budgets of packages of the config",
+		Query: []QueryParam{
+			{Name: "path", Description: "Only include files under this workspace path"},
+			{Name: "limit", Description: "Maximum number of functions, defaults to 20"},
+			{Name: "sort", Description: "complexity, cognitive or lines; defaults to complexity"},
+			{Name: "include_tests", Description: "Include _test.go files"},
+			{Name: "cyclomatic", Description: "Cyclomatic complexity threshold, overriding the config"},
+			{Name: "cognitive", Description: "Cognitive complexity threshold, overriding the config"},
+			{Name: "lines", Description: "Function length threshold, overriding the config"},
+			{Name: "fail", Description: "Respond 422 when a package is over budget"},
+		},
+		Response: ComplexityReport{},
+	}, handleComplexity(s.GetWorkspaceRoot(), s.config.Analysis.Complexity))
+	s.handle(Route{
+		Method: "GET", Path: "/analyze/docs", Summary: "Report the share of exported symbols with a doc comment per package, listing the symbols",
+		Query: []QueryParam{
+			{Name: "path", Description: "Only include files under this workspace path"},
+			{Name: "undocumented", Description: "Only list the undocumented symbols"},
+		},
+		Response: DocCoverageReport{},
+	}, handleDocCoverage(s.GetWorkspaceRoot()))
+	s.handle(Route{
+		Method: "GET", Path: "/analyze/callgraph", Summary: "Build the call graph of the packages of the workspace, as JSON or Graphviz DOT",
+		Query: []QueryParam{
+			{Name: "pattern", Description: "Packages to analyze, e.g. ./pkg/...; defaults to ./..."},
+			{Name: "algorithm", Description: "static (default), cha or rta, the last two resolving calls through interfaces"},
+			{Name: "external", Description: "Include the functions of dependencies and the standard library called"},
+			{Name: "format", Description: "json (default) or dot"},
+		},
+		Response: CallGraphReport{}, Timeout: LongRunningTimeout,
+	}, handleCallGraph(s.GetWorkspaceRoot()))
+	s.handle(Route{
+		Method: "GET", Path: "/analyze/imports", Summary: "Build the import graph of the packages of the workspace, checking the layer rules of the config, as JSON or Graphviz DOT",
+		Query: []QueryParam{
+			{Name: "pattern", Description: "Packages to analyze, e.g. ./pkg/...; defaults to ./..."},
+			{Name: "std", Description: "Include the packages of the standard library"},
+			{Name: "format", Description: "json (default) or dot"},
+		},
+		Response: ImportGraph{}, Timeout: LongRunningTimeout,
+	}, handleImportGraph(s.GetWorkspaceRoot(), s.config.Analysis.Layers))
+	s.handle(Route{
+		Method: "GET", Path: "/analyze/imports/cycles", Summary: "Find the import cycles between the packages of the workspace module, test imports included, and the imports of external tests that would cycle",
+		Query: []QueryParam{
+			{Name: "pattern", Description: "Only report cycles through these packages, e.g. ./pkg/...; defaults to ./..."},
+		},
+		Response: ImportCycleReport{}, Timeout: LongRunningTimeout,
+	}, handleImportCycles(s.GetWorkspaceRoot()))
+	s.handle(Route{
+		Method: "GET", Path: "/analyze/references", Summary: "List the package level declarations, fields and methods of the workspace with their uses across packages",
+		Query: []QueryParam{
+			{Name: "pattern", Description: "Packages to analyze, e.g. ./pkg/...; defaults to ./..."},
+			{Name: "name", Description: "Only the declarations of this name"},
+		},
+		Response: []ReferenceInfo{}, Timeout: LongRunningTimeout,
+	}, handleReferences(s.GetWorkspaceRoot()))
+	s.handle(Route{
+		Method: "GET", Path: "/analyze/module", Summary: "Load the packages of a module with their tests and analyze them: packages, aggregated metrics and diagnostics, type errors included",
+		Query: []QueryParam{
+			{Name: "module", Description: "Directory of the module in the workspace, defaults to the workspace root"},
+			{Name: "path", Description: "Only analyze the packages under this directory of the module"},
+			{Name: "severity", Description: "Comma separated severities of the diagnostics to include"},
+		},
+		Response: ModuleAnalysis{}, Timeout: LongRunningTimeout,
+	}, handleModuleAnalysis(s.GetWorkspaceRoot()))
+	s.handle(Route{
+		Method: "GET", Path: "/analyze/lint", Summary: "Run golangci-lint over the workspace, merging its issues with the diagnostics of the analyzer",
+		Query: []QueryParam{
+			{Name: "path", Description: "Only lint the packages under this workspace path"},
+			{Name: "linters", Description: "Comma separated linters to run instead of those of the golangci-lint configuration"},
+			{Name: "native", Description: "false to leave out the diagnostics of the analyzer"},
+			{Name: "severity", Description: "Comma separated severities to include"},
+		},
+		Response: DiagnosticsReport{}, Timeout: LongRunningTimeout,
+	}, handleLint(s.GetWorkspaceRoot()))
+	s.handle(Route{
+		Method: "POST", Path: "/analyze/coverage", Summary: "Upload a coverage profile written by go test -coverprofile, replacing the previous one",
+		Response: CoverageReport{}, MaxBodyBytes: 100 << 20, Timeout: LongRunningTimeout,
+	}, handleUploadCoverage(coverage))
+	s.handle(Route{
+		Method: "GET", Path: "/analyze/coverage", Summary: "Report the coverage of the files and functions of the uploaded profile next to their complexity",
+		Query: []QueryParam{
+			{Name: "path", Description: "Only include files under this workspace path"},
+		},
+		Response: CoverageReport{}, Timeout: LongRunningTimeout,
+	}, handleCoverageReport(coverage))
+	s.handle(Route{
+		Method: "GET", Path: "/analyze/api", Summary: "Extract the exported API of the packages of the workspace: types, functions, methods, fields, constants and variables",
+		Query: []QueryParam{
+			{Name: "pattern", Description: "Packages to extract, e.g. ./pkg/...; defaults to ./..."},
+			{Name: "internal", Description: "Include internal packages"},
+		},
+		Response: APISurface{}, Timeout: LongRunningTimeout,
+	}, handleAPISurface(s.GetWorkspaceRoot()))
+	s.handle(Route{
+		Method: "POST", Path: "/analyze/api/diff", Summary: "Compare two extractions of an exported API, classifying changes as breaking or not",
+		Request: APIDiffRequest{}, Response: APIDiff{},
+	}, handleAPIDiff())
+
+	s.handleRPC(RPCMethod{
+		Name: "analysis.file", Summary: "Analyze a Go source file", Params: AnalysisRequest{},
+	}, analysisMethod(coverage, func(_ AnalysisRequest, result *AnalysisResult) interface{} {
+		return result
+	}))
+	s.handleRPC(RPCMethod{
+		Name: "analysis.metrics", Summary: "Compute code metrics of a Go source file", Params: AnalysisRequest{},
+	}, analysisMethod(coverage, func(req AnalysisRequest, result *AnalysisResult) interface{} {
+		applyChurn(s.GetWorkspaceRoot(), req, &result.Metrics)
+		return result.Metrics
+	}))
+	s.handleRPC(RPCMethod{
+		Name: "analysis.dependencies", Summary: "List the dependencies of a Go source file", Params: AnalysisRequest{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var req AnalysisRequest
+		if err := decodeParams(params, &req); err != nil {
+			return nil, err
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, req.URI, req.Content, parser.ParseComments)
+		if err != nil {
+			return nil, invalidParams(err)
+		}
+		return NewASTAnalyzer(fset).AnalyzeDependencies(file), nil
+	})
+}
+
+// analysisMethod returns a JSON-RPC method analyzing the file in its params,
+// with its coverage when the profile uploaded covers it, and selecting the
+// part of the result to return
+func analysisMethod(coverage *coverageStore, selectResult func(AnalysisRequest, *AnalysisResult) interface{}) rpcMethod {
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var req AnalysisRequest
+		if err := decodeParams(params, &req); err != nil {
+			return nil, err
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, req.URI, req.Content, parser.ParseComments)
+		if err != nil {
+			return nil, invalidParams(err)
+		}
+		result, err := NewASTAnalyzer(fset).AnalyzeFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if blocks, ok := coverage.blocks(req.URI); ok {
+			applyCoverage(result, blocks)
+		}
+		return selectResult(req, result), nil
+	}
 }
 
-func handleFileAnalysis(analyzer *ASTAnalyzer) http.HandlerFunc {
+func handleFileAnalysis(coverage *coverageStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req AnalysisRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -42,17 +214,20 @@ func handleFileAnalysis(analyzer *ASTAnalyzer) http.HandlerFunc {
 		}
 
 		// Analyze the file
-		result, err := analyzer.AnalyzeFile(file)
+		result, err := NewASTAnalyzer(fset).AnalyzeFile(file)
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
+		if blocks, ok := coverage.blocks(req.URI); ok {
+			applyCoverage(result, blocks)
+		}
 
 		writeJSON(w, http.StatusOK, result)
 	}
 }
 
-func handleDependencyAnalysis(analyzer *ASTAnalyzer) http.HandlerFunc {
+func handleDependencyAnalysis() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req AnalysisRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -69,13 +244,13 @@ func handleDependencyAnalysis(analyzer *ASTAnalyzer) http.HandlerFunc {
 		}
 
 		// Analyze dependencies
-		deps := analyzer.AnalyzeDependencies(file)
+		deps := NewASTAnalyzer(fset).AnalyzeDependencies(file)
 
 		writeJSON(w, http.StatusOK, deps)
 	}
 }
 
-func handleMetricsAnalysis(analyzer *ASTAnalyzer) http.HandlerFunc {
+func handleMetricsAnalysis(root string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req AnalysisRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -92,11 +267,12 @@ func handleMetricsAnalysis(analyzer *ASTAnalyzer) http.HandlerFunc {
 		}
 
 		// Get metrics
-		result, err := analyzer.AnalyzeFile(file)
+		result, err := NewASTAnalyzer(fset).AnalyzeFile(file)
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
+		applyChurn(root, req, &result.Metrics)
 
 		writeJSON(w, http.StatusOK, result.Metrics)
 	}
@@ -109,24 +285,92 @@ func (s *Server) InitializeAnalysis() {
 
 // Add documentation endpoints
 func (s *Server) AddDocumentationEndpoints() {
-	s.router.HandleFunc("/docs/analysis", func(w http.ResponseWriter, r *http.Request) {
+	s.handle(Route{
+		Method: "GET", Path: "/docs/analysis", Summary: "Describe the analysis endpoints",
+		Response: map[string]interface{}{},
+	}, func(w http.ResponseWriter, r *http.Request) {
 		docs := map[string]interface{}{
 			"endpoints": []map[string]string{
 				{
-					"path":        "/analyze/file",
+					"path":        "/" + APIVersion + "/analyze/file",
 					"method":      "POST",
 					"description": "Performs complete analysis of a Go source file",
 				},
 				{
-					"path":        "/analyze/dependencies",
+					"path":        "/" + APIVersion + "/analyze/dependencies",
 					"method":      "POST",
 					"description": "Analyzes package dependencies",
 				},
 				{
-					"path":        "/analyze/metrics",
+					"path":        "/" + APIVersion + "/analyze/metrics",
 					"method":      "POST",
 					"description": "Retrieves code metrics",
 				},
+				{
+					"path":        "/" + APIVersion + "/analyze/hotspots",
+					"method":      "GET",
+					"description": "Ranks files by git churn combined with complexity",
+				},
+				{
+					"path":        "/" + APIVersion + "/analyze/complexity",
+					"method":      "GET",
+					"description": "Ranks functions by complexity, checking thresholds and package budgets",
+				},
+				{
+					"path":        "/" + APIVersion + "/analyze/docs",
+					"method":      "GET",
+					"description": "Reports the documentation coverage of exported symbols per package",
+				},
+				{
+					"path":        "/" + APIVersion + "/analyze/callgraph",
+					"method":      "GET",
+					"description": "Builds the call graph of the workspace packages as JSON or DOT",
+				},
+				{
+					"path":        "/" + APIVersion + "/analyze/imports",
+					"method":      "GET",
+					"description": "Builds the import graph of the workspace packages and checks layer rules",
+				},
+				{
+					"path":        "/" + APIVersion + "/analyze/imports/cycles",
+					"method":      "GET",
+					"description": "Finds import cycles and near cycles between the workspace packages",
+				},
+				{
+					"path":        "/" + APIVersion + "/analyze/references",
+					"method":      "GET",
+					"description": "Lists the declarations of the workspace packages with their uses across packages",
+				},
+				{
+					"path":        "/" + APIVersion + "/analyze/module",
+					"method":      "GET",
+					"description": "Loads every package of a module, reporting packages, metrics and diagnostics",
+				},
+				{
+					"path":        "/" + APIVersion + "/analyze/lint",
+					"method":      "GET",
+					"description": "Runs golangci-lint and merges its issues with the analyzer diagnostics",
+				},
+				{
+					"path":        "/" + APIVersion + "/analyze/coverage",
+					"method":      "POST",
+					"description": "Uploads a coverage profile mapped to the functions of the analyzed files",
+				},
+				{
+					"path":        "/" + APIVersion + "/analyze/coverage",
+					"method":      "GET",
+					"description": "Reports per-file and per-function coverage next to complexity",
+				},
+				{
+					"path":        "/" + APIVersion + "/analyze/api",
+					"method":      "GET",
+					"description": "Extracts the exported API of the workspace packages",
+				},
+				{
+					"path":        "/" + APIVersion + "/analyze/api/diff",
+					"method":      "POST",
+					"description": "Diffs two API extractions, flagging breaking changes",
+				},
 			},
 			"requestFormat": AnalysisRequest{
 				URI:     "path/to/file.go",
@@ -134,5 +378,5 @@ func (s *Server) AddDocumentationEndpoints() {
 			},
 		}
 		writeJSON(w, http.StatusOK, docs)
-	}).Methods("GET")
+	})
 }
@@ -0,0 +1,149 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// lspStateVersion is the version of the format of saved language server
+// state; state of another version is not restored
+const lspStateVersion = 1
+
+// lspState is the language server state saved under .mcp in the workspace:
+// the open documents and the workspace symbol index
+type lspState struct {
+	Version   int              `json:"version"`
+	SavedAt   time.Time        `json:"saved_at"`
+	Documents []savedDocument  `json:"documents"`
+	Index     []savedIndexFile `json:"index"`
+}
+
+type savedDocument struct {
+	URI     string `json:"uri"`
+	Text    string `json:"text"`
+	Version int    `json:"version"`
+}
+
+type savedIndexFile struct {
+	Path    string       `json:"path"`
+	ModTime time.Time    `json:"mod_time"`
+	Symbols []SymbolInfo `json:"symbols"`
+}
+
+// LSPStateStatus describes saved or restored language server state
+type LSPStateStatus struct {
+	Path      string    `json:"path"`
+	Documents int       `json:"documents"`
+	Files     int       `json:"files"` // Files of the workspace index
+	SavedAt   time.Time `json:"saved_at"`
+}
+
+// statePath returns the file the state of the language server is saved to
+func (ls *LanguageServer) statePath() string {
+	return filepath.Join(ls.symbols.root, ".mcp", "lsp-state.json")
+}
+
+// saveState writes the open documents and the workspace index to disk
+func (ls *LanguageServer) saveState() (LSPStateStatus, error) {
+	state := lspState{Version: lspStateVersion, SavedAt: time.Now().UTC()}
+
+	ls.mu.RLock()
+	for _, doc := range ls.documents {
+		state.Documents = append(state.Documents, savedDocument{URI: doc.URI, Text: doc.Text, Version: doc.Version})
+	}
+	ls.mu.RUnlock()
+	sort.Slice(state.Documents, func(i, j int) bool {
+		return state.Documents[i].URI < state.Documents[j].URI
+	})
+
+	ls.symbols.mu.RLock()
+	for path, file := range ls.symbols.files {
+		state.Index = append(state.Index, savedIndexFile{Path: path, ModTime: file.modTime, Symbols: file.symbols})
+	}
+	ls.symbols.mu.RUnlock()
+	sort.Slice(state.Index, func(i, j int) bool {
+		return state.Index[i].Path < state.Index[j].Path
+	})
+
+	status := LSPStateStatus{Path: ls.statePath(), Documents: len(state.Documents), Files: len(state.Index), SavedAt: state.SavedAt}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return status, err
+	}
+	if err := os.MkdirAll(filepath.Dir(status.Path), 0755); err != nil {
+		return status, err
+	}
+
+	// Write to a temporary file first so a crash never leaves truncated state
+	tmp := status.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return status, err
+	}
+	return status, os.Rename(tmp, status.Path)
+}
+
+// restoreState opens the documents saved by saveState and seeds the
+// workspace index with the files it held, which the first refresh only
+// parses again when they were modified since. It must be called before the
+// index is watched. Missing state is not an error.
+func (ls *LanguageServer) restoreState() (LSPStateStatus, error) {
+	status := LSPStateStatus{Path: ls.statePath()}
+	data, err := os.ReadFile(status.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+
+	var state lspState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return status, fmt.Errorf("invalid language server state %s: %w", status.Path, err)
+	}
+	if state.Version != lspStateVersion {
+		return status, fmt.Errorf("language server state %s has version %d, not %d", status.Path, state.Version, lspStateVersion)
+	}
+	status.SavedAt = state.SavedAt
+
+	ls.mu.Lock()
+	for _, doc := range state.Documents {
+		if _, ok := ls.documents[doc.URI]; ok {
+			continue
+		}
+		if err := ls.storeDocument(doc.URI, doc.Text, doc.Version); err != nil {
+			continue
+		}
+		status.Documents++
+	}
+	ls.mu.Unlock()
+
+	ls.symbols.mu.Lock()
+	for _, file := range state.Index {
+		if _, ok := ls.symbols.files[file.Path]; ok {
+			continue
+		}
+		ls.symbols.files[file.Path] = indexedFile{modTime: file.ModTime, symbols: file.Symbols}
+		status.Files++
+	}
+	ls.symbols.mu.Unlock()
+
+	return status, nil
+}
+
+func handleSaveLSPState(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := ls.saveState()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	}
+}
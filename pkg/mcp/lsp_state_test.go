@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLSPStateSaveRestore(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc Alpha() {}\n"), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+	<-s.lsp.symbols.ready
+
+	body, err := json.Marshal(TextDocumentItem{URI: "b.go", Text: "package a\n\nfunc Beta() {}\n", Version: 7})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/document/open", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/state/save", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var saved LSPStateStatus
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&saved))
+	assert.Equal(t, filepath.Join(dir, ".mcp", "lsp-state.json"), saved.Path)
+	assert.Equal(t, 1, saved.Documents)
+	assert.Equal(t, 1, saved.Files)
+	assert.FileExists(t, saved.Path)
+	s.lsp.symbols.stop()
+
+	// A new server opens the documents at their version, and the index
+	// holds the saved symbols before it is built
+	restored := NewLanguageServer(dir)
+	status, err := restored.restoreState()
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Documents)
+	assert.Equal(t, 1, status.Files)
+	doc := restored.documents["b.go"]
+	require.NotNil(t, doc)
+	assert.Equal(t, 7, doc.Version)
+	require.Len(t, doc.Symbols, 1)
+	assert.Equal(t, "Beta", doc.Symbols[0].Name)
+	symbols := restored.symbols.search("Alpha", 10)
+	require.Len(t, symbols, 1)
+	assert.Equal(t, "Alpha", symbols[0].Name)
+
+	// Files modified since are parsed again
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc Gamma() {}\n"), 0644))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "a.go"), saved.SavedAt.Add(time.Second), saved.SavedAt.Add(time.Second)))
+	restored.symbols.refresh()
+	assert.Empty(t, restored.symbols.search("Alpha", 10))
+	assert.Len(t, restored.symbols.search("Gamma", 10), 1)
+
+	// Servers restore the state when they start and save it when they
+	// shut down
+	s = NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+	_, err = s.lsp.text("b.go")
+	require.NoError(t, err)
+	s.lsp.closeDocument("b.go")
+	require.NoError(t, s.Shutdown(context.Background()))
+	status, err = NewLanguageServer(dir).restoreState()
+	require.NoError(t, err)
+	assert.Equal(t, 0, status.Documents)
+}
+
+func TestRestoreLSPStateErrors(t *testing.T) {
+	dir := t.TempDir()
+	ls := NewLanguageServer(dir)
+
+	// Missing state is not an error
+	status, err := ls.restoreState()
+	require.NoError(t, err)
+	assert.True(t, status.SavedAt.IsZero())
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".mcp"), 0755))
+	require.NoError(t, os.WriteFile(ls.statePath(), []byte(`{"version":99}`), 0644))
+	_, err = ls.restoreState()
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(ls.statePath(), []byte("{"), 0644))
+	_, err = ls.restoreState()
+	assert.Error(t, err)
+	assert.Empty(t, ls.documents)
+}
@@ -0,0 +1,143 @@
+package mcp
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleChangeDocument(t *testing.T) {
+	s := NewServer(nil)
+	s.AddLanguageServerHandler()
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(string(data))))
+		return w
+	}
+	symbols := func() []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/symbols?uri=example.go", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var symbols []DocumentSymbol
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&symbols))
+		var names []string
+		for _, symbol := range symbols {
+			names = append(names, symbol.Name)
+		}
+		return names
+	}
+	status := func(w *httptest.ResponseRecorder) map[string]string {
+		t.Helper()
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var status map[string]string
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+		return status
+	}
+
+	w := post("/v1/lsp/document/open", TextDocumentItem{URI: "example.go", Text: "package example\n\nfunc a() {}\n", Version: 3})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Ranges are edited in order, each on the result of the previous change
+	result := status(post("/v1/lsp/document/change", DocumentChange{
+		URI:     "example.go",
+		Version: 4,
+		ContentChanges: []TextDocumentContentChangeEvent{
+			{Range: &Range{Start: Position{Line: 2, Character: 5}, End: Position{Line: 2, Character: 6}}, Text: "first"},
+			{Range: &Range{Start: Position{Line: 3, Character: 0}, End: Position{Line: 3, Character: 0}}, Text: "\nfunc second() {}\n"},
+		},
+	}))
+	assert.Equal(t, map[string]string{"status": "changed", "uri": "example.go", "version": "4"}, result)
+	assert.Equal(t, []string{"first", "second"}, symbols())
+
+	// Versions only go forward
+	w = post("/v1/lsp/document/change", DocumentChange{URI: "example.go", Version: 4, Text: "package example\n"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Unchanged text is not parsed again, but takes the version
+	result = status(post("/v1/lsp/document/change", DocumentChange{
+		URI:            "example.go",
+		ContentChanges: []TextDocumentContentChangeEvent{{Range: &Range{Start: Position{Line: 0, Character: 0}, End: Position{Line: 0, Character: 0}}}},
+	}))
+	assert.Equal(t, "unchanged", result["status"])
+	assert.Equal(t, "5", result["version"])
+
+	// The whole text, without content changes
+	result = status(post("/v1/lsp/document/change", DocumentChange{URI: "example.go", Text: "package example\n\ntype T int\n"}))
+	assert.Equal(t, "changed", result["status"])
+	assert.Equal(t, []string{"T"}, symbols())
+
+	w = post("/v1/lsp/document/change", DocumentChange{
+		URI:            "example.go",
+		ContentChanges: []TextDocumentContentChangeEvent{{Range: &Range{Start: Position{Line: 9, Character: 0}, End: Position{Line: 9, Character: 0}}, Text: "x"}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = post("/v1/lsp/document/change", DocumentChange{URI: "missing.go", Text: "package missing\n"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestStoreDocumentVersions(t *testing.T) {
+	ls := NewLanguageServer(t.TempDir())
+
+	// Documents that never parsed keep their partial AST
+	require.NoError(t, ls.openDocument(TextDocumentItem{URI: "example.go", Text: "package example\n\nfunc a() {}\n\nfunc {", Version: 1}))
+	doc := ls.documents["example.go"]
+	assert.Equal(t, 0, doc.ParsedVersion)
+	require.NotEmpty(t, doc.ParseErrors)
+	assert.Equal(t, "parse-error", doc.ParseErrors[0].Code)
+	assert.Equal(t, Position{Line: 4, Character: 5}, doc.ParseErrors[0].Location.Range.Start)
+
+	require.NoError(t, ls.openDocument(TextDocumentItem{URI: "example.go", Text: "package example\n\nfunc a() {}\n", Version: 2}))
+	doc = ls.documents["example.go"]
+	assert.Equal(t, 2, doc.ParsedVersion)
+	assert.Empty(t, doc.ParseErrors)
+
+	// Reopening with an older version is rejected
+	err := ls.openDocument(TextDocumentItem{URI: "example.go", Text: "package example\n", Version: 2})
+	assert.ErrorIs(t, err, errStaleVersion)
+
+	// Syntax errors keep the outline of the last version that parsed
+	_, changed, err := ls.changeDocument(DocumentChange{URI: "example.go", Version: 3, Text: "package example\n\nfunc a() {}\n\nfunc b("})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	doc = ls.documents["example.go"]
+	assert.Equal(t, 3, doc.Version)
+	assert.Equal(t, 2, doc.ParsedVersion)
+	assert.NotEmpty(t, doc.ParseErrors)
+	require.Len(t, doc.Symbols, 1)
+	assert.Equal(t, "a", doc.Symbols[0].Name)
+	assert.Equal(t, "a", doc.AST.Decls[0].(*ast.FuncDecl).Name.Name)
+	assert.Equal(t, 1, countFiles(ls.fileSet), "the file set only holds the kept AST")
+
+	_, _, err = ls.changeDocument(DocumentChange{URI: "example.go", Version: 4, Text: "package example\n\nfunc b() {}\n"})
+	require.NoError(t, err)
+	doc = ls.documents["example.go"]
+	assert.Equal(t, 4, doc.ParsedVersion)
+	require.Len(t, doc.Symbols, 1)
+	assert.Equal(t, "b", doc.Symbols[0].Name)
+	assert.Equal(t, 1, countFiles(ls.fileSet))
+
+	ls.closeDocument("example.go")
+	assert.Equal(t, 0, countFiles(ls.fileSet))
+}
+
+// countFiles returns the number of files a file set holds positions of
+func countFiles(fset *token.FileSet) int {
+	n := 0
+	fset.Iterate(func(*token.File) bool {
+		n++
+		return true
+	})
+	return n
+}
@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// mcpLogLevels maps the log levels of MCP, which are those of syslog, to
+// slog levels
+var mcpLogLevels = map[string]slog.Level{
+	"debug":     slog.LevelDebug,
+	"info":      slog.LevelInfo,
+	"notice":    slog.LevelInfo + 2,
+	"warning":   slog.LevelWarn,
+	"error":     slog.LevelError,
+	"critical":  slog.LevelError + 4,
+	"alert":     slog.LevelError + 8,
+	"emergency": slog.LevelError + 12,
+}
+
+// mcpLogLevelNames are the MCP log levels from least to most severe
+var mcpLogLevelNames = []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+// mcpLogLevel returns the most severe MCP level a slog level reaches
+func mcpLogLevel(level slog.Level) string {
+	name := mcpLogLevelNames[0]
+	for _, n := range mcpLogLevelNames {
+		if level >= mcpLogLevels[n] {
+			name = n
+		}
+	}
+	return name
+}
+
+type mcpSetLevelParams struct {
+	Level string `json:"level"`
+}
+
+type mcpLogMessage struct {
+	Level  string                 `json:"level"`
+	Logger string                 `json:"logger"`
+	Data   map[string]interface{} `json:"data"`
+}
+
+// setLogLevel starts sending the logs of the host's requests at or above a
+// level as notifications/message
+func (m *mcpSession) setLogLevel(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p mcpSetLevelParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	level, ok := mcpLogLevels[p.Level]
+	if !ok {
+		return nil, &RPCError{Code: RPCInvalidParams, Message: fmt.Sprintf("unknown log level %q", p.Level)}
+	}
+	m.mu.Lock()
+	m.logLevel = &level
+	m.mu.Unlock()
+	return struct{}{}, nil
+}
+
+// sendsLogs reports whether records of a level go to the host
+func (m *mcpSession) sendsLogs(level slog.Level) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.logLevel != nil && level >= *m.logLevel
+}
+
+// mcpLogHandler passes records on to the request logger and sends those at
+// or above the level the host set as notifications/message. Attributes of
+// groups are sent without the group name.
+type mcpLogHandler struct {
+	slog.Handler
+	session *mcpSession
+	attrs   []slog.Attr
+}
+
+func (h *mcpLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.Handler.Enabled(ctx, level) || h.session.sendsLogs(level)
+}
+
+func (h *mcpLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.session.sendsLogs(r.Level) {
+		data := map[string]interface{}{"message": r.Message}
+		for _, a := range h.attrs {
+			data[a.Key] = logValue(a.Value)
+		}
+		r.Attrs(func(a slog.Attr) bool {
+			data[a.Key] = logValue(a.Value)
+			return true
+		})
+		h.session.notify("notifications/message", mcpLogMessage{Level: mcpLogLevel(r.Level), Logger: MCPServerName, Data: data})
+	}
+	if !h.Handler.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *mcpLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &mcpLogHandler{
+		Handler: h.Handler.WithAttrs(attrs),
+		session: h.session,
+		attrs:   append(append([]slog.Attr(nil), h.attrs...), attrs...),
+	}
+}
+
+func (h *mcpLogHandler) WithGroup(name string) slog.Handler {
+	return &mcpLogHandler{Handler: h.Handler.WithGroup(name), session: h.session, attrs: h.attrs}
+}
+
+// logValue returns an attribute value as JSON encodes it usefully; errors
+// would otherwise encode as empty objects
+func logValue(v slog.Value) interface{} {
+	v = v.Resolve()
+	if err, ok := v.Any().(error); ok {
+		return err.Error()
+	}
+	if v.Kind() == slog.KindDuration || v.Kind() == slog.KindTime || v.Kind() == slog.KindGroup {
+		return v.String()
+	}
+	return v.Any()
+}
@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPSession_Logging(t *testing.T) {
+	s := NewServer(nil)
+	session := s.newMCPSession("")
+	var messages []mcpLogMessage
+	session.notify = func(method string, params interface{}) {
+		if method == "notifications/message" {
+			messages = append(messages, params.(mcpLogMessage))
+		}
+	}
+	ctx := context.Background()
+	call := func(body string) RPCResponse {
+		resp, ok := session.handleMessage(ctx, []byte(body)).(*RPCResponse)
+		require.True(t, ok, body)
+		return *resp
+	}
+	failingCall := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"context_get","arguments":{"id":"missing"}}}`
+
+	call(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{}}`)
+	call(failingCall)
+	assert.Empty(t, messages, "no level set")
+
+	resp := call(`{"jsonrpc":"2.0","id":1,"method":"logging/setLevel","params":{"level":"verbose"}}`)
+	assert.Equal(t, RPCInvalidParams, resp.Error.Code)
+	resp = call(`{"jsonrpc":"2.0","id":1,"method":"logging/setLevel","params":{"level":"warning"}}`)
+	require.Nil(t, resp.Error)
+
+	call(failingCall)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "warning", messages[0].Level)
+	assert.Equal(t, MCPServerName, messages[0].Logger)
+	assert.Equal(t, "mcp tool call failed", messages[0].Data["message"])
+	assert.Equal(t, "context_get", messages[0].Data["tool"])
+	assert.Contains(t, messages[0].Data["error"], "not found")
+
+	call(`{"jsonrpc":"2.0","id":3,"method":"logging/setLevel","params":{"level":"error"}}`)
+	call(failingCall)
+	assert.Len(t, messages, 1)
+}
+
+func TestMCPLogLevel(t *testing.T) {
+	assert.Equal(t, "debug", mcpLogLevel(slog.LevelDebug-4))
+	assert.Equal(t, "info", mcpLogLevel(slog.LevelInfo))
+	assert.Equal(t, "notice", mcpLogLevel(slog.LevelInfo+3))
+	assert.Equal(t, "warning", mcpLogLevel(slog.LevelWarn))
+	assert.Equal(t, "emergency", mcpLogLevel(slog.LevelError+20))
+}
@@ -0,0 +1,471 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+)
+
+// GenerateMockRequest represents a request to generate a stub and mock for an interface
+type GenerateMockRequest struct {
+	Package       string `json:"package"`                  // Directory of the package declaring the interface
+	Interface     string `json:"interface"`                // Interface name
+	OutputDir     string `json:"output_dir,omitempty"`     // Destination directory, defaults to <package>/mocks
+	OutputPackage string `json:"output_package,omitempty"` // Destination package name, defaults to the directory name
+	Stub          *bool  `json:"stub,omitempty"`           // Generate an implementation stub (default true)
+	Mock          *bool  `json:"mock,omitempty"`           // Generate a call-recording mock (default true)
+	DryRun        bool   `json:"dry_run,omitempty"`        // Return the files without writing them
+}
+
+// GeneratedFile is a file produced by a generator
+type GeneratedFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// MockGenerator emits implementation stubs and call-recording mocks for interfaces
+type MockGenerator struct {
+	root        string
+	fileManager *ide.FileManager
+}
+
+// NewMockGenerator creates a mock generator for a workspace
+func NewMockGenerator(root string) *MockGenerator {
+	return &MockGenerator{
+		root:        root,
+		fileManager: ide.NewFileManager(root),
+	}
+}
+
+type mockMethod struct {
+	Name     string
+	Params   []mockVar
+	Results  []mockVar
+	Variadic bool
+}
+
+type mockVar struct {
+	Name string
+	Type string
+}
+
+// Generate produces the requested files for the interface
+func (g *MockGenerator) Generate(req GenerateMockRequest) ([]GeneratedFile, error) {
+	if req.Interface == "" {
+		return nil, fmt.Errorf("interface is required")
+	}
+	if req.OutputDir == "" {
+		req.OutputDir = filepath.Join(req.Package, "mocks")
+	}
+
+	iface, srcPkg, err := g.loadInterface(req.Package, req.Interface)
+	if err != nil {
+		return nil, err
+	}
+
+	outPath := importPathFor(g.root, req.OutputDir)
+	outName := req.OutputPackage
+	if outName == "" {
+		outName = existingPackageName(filepath.Join(g.root, req.OutputDir))
+	}
+	if outName == "" {
+		outName = strings.NewReplacer("-", "_", ".", "_").Replace(path.Base(filepath.ToSlash(req.OutputDir)))
+	}
+
+	imports := newImportSet(outPath)
+	methods := make([]mockMethod, iface.NumMethods())
+	for i := 0; i < iface.NumMethods(); i++ {
+		methods[i] = describeMethod(iface.Method(i), imports.qualifier)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+
+	ifaceRef := req.Interface
+	if q := imports.qualifier(srcPkg); q != "" {
+		ifaceRef = q + "." + req.Interface
+	}
+
+	base := strings.ToLower(req.Interface)
+	var files []GeneratedFile
+
+	if req.Stub == nil || *req.Stub {
+		src, err := renderStub(outName, req.Interface, ifaceRef, methods, imports)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, GeneratedFile{Path: filepath.Join(req.OutputDir, base+"_stub.go"), Content: src})
+	}
+
+	if req.Mock == nil || *req.Mock {
+		src, err := renderMock(outName, req.Interface, ifaceRef, methods, imports)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, GeneratedFile{Path: filepath.Join(req.OutputDir, base+"_mock.go"), Content: src})
+	}
+
+	return files, nil
+}
+
+// loadInterface finds the interface via the analyzer, then type-checks the
+// package to get fully resolved method signatures
+func (g *MockGenerator) loadInterface(dir, name string) (*types.Interface, *types.Package, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, filepath.Join(g.root, dir), func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse package: %v", err)
+	}
+
+	var files []*ast.File
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("no Go package found in %s", dir)
+	}
+
+	result, err := NewASTAnalyzer(fset).AnalyzePackage(dir, files)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := false
+	for _, t := range result.Types {
+		if t.Name == name {
+			if t.Kind != "interface" {
+				return nil, nil, fmt.Errorf("%s is a %s, not an interface", name, t.Kind)
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("interface %s not found in %s", name, dir)
+	}
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(importPathFor(g.root, dir), fset, files, nil)
+	if pkg == nil {
+		return nil, nil, fmt.Errorf("failed to type-check %s", dir)
+	}
+
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, nil, fmt.Errorf("interface %s not found in %s", name, dir)
+	}
+	if named, ok := obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+		return nil, nil, fmt.Errorf("generic interfaces are not supported")
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is not an interface", name)
+	}
+
+	return iface.Complete(), pkg, nil
+}
+
+func describeMethod(fn *types.Func, qualifier types.Qualifier) mockMethod {
+	sig := fn.Type().(*types.Signature)
+	m := mockMethod{Name: fn.Name(), Variadic: sig.Variadic()}
+
+	used := make(map[string]bool)
+	for i := 0; i < sig.Params().Len(); i++ {
+		p := sig.Params().At(i)
+		name := p.Name()
+		if name == "" || name == "_" || used[name] {
+			name = fmt.Sprintf("p%d", i)
+		}
+		used[name] = true
+
+		typ := types.TypeString(p.Type(), qualifier)
+		if m.Variadic && i == sig.Params().Len()-1 {
+			typ = "..." + strings.TrimPrefix(typ, "[]")
+		}
+		m.Params = append(m.Params, mockVar{Name: name, Type: typ})
+	}
+
+	for i := 0; i < sig.Results().Len(); i++ {
+		r := sig.Results().At(i)
+		m.Results = append(m.Results, mockVar{
+			Name: fmt.Sprintf("r%d", i),
+			Type: types.TypeString(r.Type(), qualifier),
+		})
+	}
+
+	return m
+}
+
+func (m mockMethod) paramList() string {
+	parts := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		parts[i] = p.Name + " " + p.Type
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (m mockMethod) resultList() string {
+	switch len(m.Results) {
+	case 0:
+		return ""
+	case 1:
+		return " " + m.Results[0].Type
+	}
+	parts := make([]string, len(m.Results))
+	for i, r := range m.Results {
+		parts[i] = r.Type
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+func (m mockMethod) callArgs() string {
+	parts := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		parts[i] = p.Name
+		if m.Variadic && i == len(m.Params)-1 {
+			parts[i] += "..."
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func renderStub(pkgName, iface, ifaceRef string, methods []mockMethod, imports *importSet) (string, error) {
+	var body bytes.Buffer
+	typeName := iface + "Stub"
+
+	fmt.Fprintf(&body, "// %s is a stub implementation of %s\n", typeName, ifaceRef)
+	fmt.Fprintf(&body, "type %s struct{}\n\n", typeName)
+	fmt.Fprintf(&body, "var _ %s = (*%s)(nil)\n", ifaceRef, typeName)
+
+	for _, m := range methods {
+		fmt.Fprintf(&body, "\n// %s implements %s\n", m.Name, ifaceRef)
+		fmt.Fprintf(&body, "func (s *%s) %s(%s)%s {\n", typeName, m.Name, m.paramList(), m.resultList())
+		fmt.Fprintf(&body, "\tpanic(\"not implemented\")\n}\n")
+	}
+
+	// Stubs are meant to be filled in, so they are not marked as generated
+	return formatGenerated(pkgName, false, "", imports.imports(), body.String())
+}
+
+func renderMock(pkgName, iface, ifaceRef string, methods []mockMethod, imports *importSet) (string, error) {
+	var body bytes.Buffer
+	typeName := "Mock" + iface
+
+	fmt.Fprintf(&body, "// %s is a mock implementation of %s that records every call.\n", typeName, ifaceRef)
+	fmt.Fprintf(&body, "// Set the <Method>Func fields to control return values.\n")
+	fmt.Fprintf(&body, "type %s struct {\n", typeName)
+	for _, m := range methods {
+		fmt.Fprintf(&body, "\t%sFunc func(%s)%s\n", m.Name, m.paramList(), m.resultList())
+	}
+	fmt.Fprintf(&body, "\n\tmu sync.Mutex\n")
+	for _, m := range methods {
+		fmt.Fprintf(&body, "\tcalls%s []%s%sCall\n", m.Name, typeName, m.Name)
+	}
+	fmt.Fprintf(&body, "}\n\n")
+	fmt.Fprintf(&body, "var _ %s = (*%s)(nil)\n", ifaceRef, typeName)
+
+	for _, m := range methods {
+		callType := typeName + m.Name + "Call"
+
+		fmt.Fprintf(&body, "\n// %s records the arguments of a call to %s\n", callType, m.Name)
+		if len(m.Params) == 0 {
+			fmt.Fprintf(&body, "type %s struct{}\n", callType)
+		} else {
+			fmt.Fprintf(&body, "type %s struct {\n", callType)
+			for _, p := range m.Params {
+				typ := p.Type
+				if strings.HasPrefix(typ, "...") {
+					typ = "[]" + strings.TrimPrefix(typ, "...")
+				}
+				fmt.Fprintf(&body, "\t%s %s\n", exportName(p.Name), typ)
+			}
+			fmt.Fprintf(&body, "}\n")
+		}
+
+		fmt.Fprintf(&body, "\n// %s implements %s\n", m.Name, ifaceRef)
+		fmt.Fprintf(&body, "func (m *%s) %s(%s)%s {\n", typeName, m.Name, m.paramList(), m.resultList())
+		fmt.Fprintf(&body, "\tm.mu.Lock()\n")
+		fmt.Fprintf(&body, "\tm.calls%s = append(m.calls%s, %s{", m.Name, m.Name, callType)
+		for i, p := range m.Params {
+			if i > 0 {
+				body.WriteString(", ")
+			}
+			fmt.Fprintf(&body, "%s: %s", exportName(p.Name), p.Name)
+		}
+		fmt.Fprintf(&body, "})\n\tm.mu.Unlock()\n\n")
+
+		fmt.Fprintf(&body, "\tif m.%sFunc != nil {\n", m.Name)
+		if len(m.Results) > 0 {
+			fmt.Fprintf(&body, "\t\treturn m.%sFunc(%s)\n", m.Name, m.callArgs())
+		} else {
+			fmt.Fprintf(&body, "\t\tm.%sFunc(%s)\n", m.Name, m.callArgs())
+		}
+		fmt.Fprintf(&body, "\t}\n")
+		if len(m.Results) > 0 {
+			names := make([]string, len(m.Results))
+			for i, r := range m.Results {
+				fmt.Fprintf(&body, "\tvar %s %s\n", r.Name, r.Type)
+				names[i] = r.Name
+			}
+			fmt.Fprintf(&body, "\treturn %s\n", strings.Join(names, ", "))
+		}
+		fmt.Fprintf(&body, "}\n")
+
+		fmt.Fprintf(&body, "\n// %sCalls returns the recorded calls to %s\n", m.Name, m.Name)
+		fmt.Fprintf(&body, "func (m *%s) %sCalls() []%s {\n", typeName, m.Name, callType)
+		fmt.Fprintf(&body, "\tm.mu.Lock()\n\tdefer m.mu.Unlock()\n")
+		fmt.Fprintf(&body, "\treturn append([]%s(nil), m.calls%s...)\n}\n", callType, m.Name)
+	}
+
+	return formatGenerated(pkgName, true, "sync", imports.imports(), body.String())
+}
+
+func formatGenerated(pkgName string, generated bool, extraImport string, imports map[string]string, body string) (string, error) {
+	var buf bytes.Buffer
+	if generated {
+		buf.WriteString("// Code generated by go-mcp. DO NOT EDIT.\n\n")
+	}
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+
+	paths := make([]string, 0, len(imports)+1)
+	for p := range imports {
+		paths = append(paths, p)
+	}
+	if extraImport != "" {
+		if _, ok := imports[extraImport]; !ok {
+			paths = append(paths, extraImport)
+		}
+	}
+	sort.Strings(paths)
+
+	if len(paths) > 0 {
+		buf.WriteString("import (\n")
+		for _, p := range paths {
+			if alias := imports[p]; alias != "" && alias != path.Base(p) {
+				fmt.Fprintf(&buf, "\t%s %q\n", alias, p)
+			} else {
+				fmt.Fprintf(&buf, "\t%q\n", p)
+			}
+		}
+		buf.WriteString(")\n\n")
+	}
+	buf.WriteString(body)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("generated code does not compile: %v", err)
+	}
+	return string(src), nil
+}
+
+// importSet tracks the packages referenced by generated code and the local
+// names they are imported under
+type importSet struct {
+	self   string
+	byPath map[string]string
+	names  map[string]bool
+}
+
+func newImportSet(self string) *importSet {
+	return &importSet{
+		self:   self,
+		byPath: make(map[string]string),
+		names:  make(map[string]bool),
+	}
+}
+
+func (s *importSet) qualifier(pkg *types.Package) string {
+	if pkg == nil || pkg.Path() == s.self {
+		return ""
+	}
+	if name, ok := s.byPath[pkg.Path()]; ok {
+		return name
+	}
+
+	name := pkg.Name()
+	for i := 2; s.names[name]; i++ {
+		name = fmt.Sprintf("%s%d", pkg.Name(), i)
+	}
+	s.names[name] = true
+	s.byPath[pkg.Path()] = name
+	return name
+}
+
+func (s *importSet) imports() map[string]string {
+	return s.byPath
+}
+
+// existingPackageName returns the package name used by Go files already in dir
+func existingPackageName(dir string) string {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, nil, parser.PackageClauseOnly)
+	if err != nil {
+		return ""
+	}
+	for name := range pkgs {
+		if !strings.HasSuffix(name, "_test") {
+			return name
+		}
+	}
+	return ""
+}
+
+func exportName(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func handleGenerateMock(g *MockGenerator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req GenerateMockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if req.Package == "" {
+			req.Package = "."
+		}
+		if !isWithinRoot(g.root, req.Package) || (req.OutputDir != "" && !isWithinRoot(g.root, req.OutputDir)) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("paths must be inside the workspace"))
+			return
+		}
+
+		files, err := g.Generate(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if !req.DryRun {
+			for _, f := range files {
+				if err := g.fileManager.CreateFile(f.Path, []byte(f.Content)); err != nil {
+					writeError(w, http.StatusInternalServerError, err)
+					return
+				}
+			}
+		}
+
+		writeJSON(w, http.StatusCreated, files)
+	}
+}
@@ -0,0 +1,60 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockGenerator_Generate(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/demo\n\ngo 1.22\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "store"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "store", "store.go"), []byte(`package store
+
+import "io"
+
+type Item struct{ ID string }
+
+// Store persists items
+type Store interface {
+	io.Closer
+	Put(item *Item) error
+	Find(ids ...string) ([]Item, bool)
+}
+`), 0644))
+
+	g := NewMockGenerator(root)
+	files, err := g.Generate(GenerateMockRequest{Package: "store", Interface: "Store", OutputDir: "store/mocks"})
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	stub, mock := files[0], files[1]
+	assert.Equal(t, filepath.Join("store", "mocks", "store_stub.go"), stub.Path)
+	assert.Contains(t, stub.Content, "package mocks")
+	assert.Contains(t, stub.Content, "var _ store.Store = (*StoreStub)(nil)")
+	assert.Contains(t, stub.Content, "func (s *StoreStub) Close() error {")
+	assert.NotContains(t, stub.Content, "DO NOT EDIT")
+
+	assert.Equal(t, filepath.Join("store", "mocks", "store_mock.go"), mock.Path)
+	assert.Contains(t, mock.Content, `"example.com/demo/store"`)
+	assert.Contains(t, mock.Content, "func (m *MockStore) Put(item *store.Item) error {")
+	assert.Contains(t, mock.Content, "func (m *MockStore) Find(ids ...string) ([]store.Item, bool) {")
+	assert.Contains(t, mock.Content, "return m.FindFunc(ids...)")
+	assert.Contains(t, mock.Content, "Ids []string")
+	assert.Contains(t, mock.Content, "func (m *MockStore) PutCalls() []MockStorePutCall {")
+}
+
+func TestMockGenerator_NotAnInterface(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.go"), []byte("package a\n\ntype Thing struct{}\n"), 0644))
+
+	_, err := NewMockGenerator(root).Generate(GenerateMockRequest{Package: ".", Interface: "Thing"})
+	assert.EqualError(t, err, "Thing is a struct, not an interface")
+
+	_, err = NewMockGenerator(root).Generate(GenerateMockRequest{Package: ".", Interface: "Missing"})
+	assert.Error(t, err)
+}
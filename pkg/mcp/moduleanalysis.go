@@ -0,0 +1,233 @@
+package mcp
+
+import (
+	"fmt"
+	"go/token"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// PackageAnalysis is the analysis of the files of a package, its tests
+// included
+type PackageAnalysis struct {
+	Path    string      `json:"path"` // Import path, ending with _test for external test packages
+	Name    string      `json:"name"`
+	Dir     string      `json:"dir"`               // Relative to the workspace root
+	Files   []string    `json:"files"`             // Relative to the workspace root
+	Imports []string    `json:"imports,omitempty"` // Packages of the module imported
+	Metrics CodeMetrics `json:"metrics"`
+}
+
+// ModuleAnalysis is the analysis of the packages of a module
+type ModuleAnalysis struct {
+	Module      string            `json:"module"` // Module path
+	Root        string            `json:"root"`   // Directory of the module relative to the workspace root
+	Path        string            `json:"path"`   // Directory of the packages analyzed relative to the module root
+	Packages    []PackageAnalysis `json:"packages"`
+	Diagnostics []Diagnostic      `json:"diagnostics"` // Located relative to the workspace root
+	Metrics     CodeMetrics       `json:"metrics"`
+}
+
+// AnalyzeModule loads the packages under path in the module at module in
+// the workspace root, with their tests and dependencies, and analyzes them
+// like AnalyzePackage. Identifiers resolve across packages, and the
+// errors of loading, parsing and type checking packages are diagnostics.
+func AnalyzeModule(root, module, path string) (*ModuleAnalysis, error) {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedModule,
+		Dir:   filepath.Join(root, module),
+		Tests: true,
+	}
+	pattern := packagePattern(DiagnosticScope{Path: path})
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %v", err)
+	}
+
+	// Packages with tests are loaded twice, without and with their test
+	// files; only the latter is analyzed
+	variants := make(map[string]*packages.Package)
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.PkgPath, ".test") {
+			continue
+		}
+		// Patterns matching no package of a module are reported as a
+		// package without files
+		for _, e := range pkg.Errors {
+			if e.Kind == packages.ListError && len(pkg.GoFiles) == 0 {
+				return nil, fmt.Errorf("failed to load package %s: %v", pkg.PkgPath, e)
+			}
+		}
+		if prev, ok := variants[pkg.PkgPath]; !ok || len(pkg.GoFiles) > len(prev.GoFiles) {
+			variants[pkg.PkgPath] = pkg
+		}
+	}
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("no packages match %s", pattern)
+	}
+
+	analysis := &ModuleAnalysis{
+		Root:        filepath.ToSlash(filepath.Clean(module)),
+		Path:        path,
+		Packages:    []PackageAnalysis{},
+		Diagnostics: []Diagnostic{},
+	}
+	seen := make(map[string]bool)
+	report := func(d Diagnostic) {
+		key := d.Code + d.Location.URI + fmt.Sprint(d.Location.Range.Start) + d.Message
+		if !seen[key] {
+			seen[key] = true
+			analysis.Diagnostics = append(analysis.Diagnostics, d)
+		}
+	}
+
+	halsteadCounts := newHalsteadCounter()
+	for _, pkg := range variants {
+		if pkg.Module != nil && pkg.Module.Main {
+			analysis.Module = pkg.Module.Path
+		}
+		for _, e := range pkg.Errors {
+			report(packageErrorDiagnostic(root, e))
+		}
+
+		a := PackageAnalysis{Path: pkg.PkgPath, Name: pkg.Name, Files: []string{}}
+		for _, file := range pkg.GoFiles {
+			a.Files = append(a.Files, relativePath(root, token.Position{Filename: file}))
+		}
+		sort.Strings(a.Files)
+		if len(a.Files) > 0 {
+			a.Dir = filepath.ToSlash(filepath.Dir(a.Files[0]))
+		}
+		for _, imp := range pkg.Imports {
+			if imp.Module != nil && imp.Module.Main && imp.PkgPath != pkg.PkgPath && !containsString(a.Imports, imp.PkgPath) {
+				a.Imports = append(a.Imports, imp.PkgPath)
+			}
+		}
+		sort.Strings(a.Imports)
+
+		if len(pkg.Syntax) > 0 && pkg.TypesInfo != nil {
+			result, err := newLoadedAnalyzer(pkg.Fset, pkg.TypesInfo, pkg.Syntax).AnalyzePackage(pkg.PkgPath, pkg.Syntax)
+			if err != nil {
+				return nil, fmt.Errorf("failed to analyze package %s: %v", pkg.PkgPath, err)
+			}
+			a.Metrics = result.Metrics
+			for _, d := range result.Diagnostics {
+				d.Location.URI = relativePath(root, token.Position{Filename: d.Location.URI})
+				report(d)
+			}
+			for _, file := range pkg.Syntax {
+				halsteadCounts.add(file)
+			}
+		}
+		analysis.Packages = append(analysis.Packages, a)
+
+		analysis.Metrics.LinesOfCode += a.Metrics.LinesOfCode
+		analysis.Metrics.CommentLines += a.Metrics.CommentLines
+		analysis.Metrics.FunctionCount += a.Metrics.FunctionCount
+		analysis.Metrics.ComplexityScore += a.Metrics.ComplexityScore
+		analysis.Metrics.CognitiveComplexity += a.Metrics.CognitiveComplexity
+		analysis.Metrics.InterfaceCount += a.Metrics.InterfaceCount
+		analysis.Metrics.StructCount += a.Metrics.StructCount
+		analysis.Metrics.TestCount += a.Metrics.TestCount
+		analysis.Metrics.ExportedSymbols += a.Metrics.ExportedSymbols
+		analysis.Metrics.DocumentedSymbols += a.Metrics.DocumentedSymbols
+	}
+	analysis.Metrics.DocCoverage = docCoverage(analysis.Metrics.DocumentedSymbols, analysis.Metrics.ExportedSymbols)
+	analysis.Metrics.Halstead = halsteadCounts.metrics()
+	analysis.Metrics.MaintainabilityIndex = maintainabilityIndex(analysis.Metrics.Halstead.Volume, analysis.Metrics.ComplexityScore, analysis.Metrics.LinesOfCode)
+
+	sort.Slice(analysis.Packages, func(i, j int) bool {
+		return analysis.Packages[i].Path < analysis.Packages[j].Path
+	})
+	sort.SliceStable(analysis.Diagnostics, func(i, j int) bool {
+		a, b := analysis.Diagnostics[i].Location, analysis.Diagnostics[j].Location
+		if a.URI != b.URI {
+			return a.URI < b.URI
+		}
+		return positionBefore(a.Range.Start, b.Range.Start)
+	})
+	return analysis, nil
+}
+
+// packageErrorDiagnostic reports an error of loading a package, whose
+// position is file:line:col, file:line or empty
+func packageErrorDiagnostic(root string, e packages.Error) Diagnostic {
+	code := "load-error"
+	switch e.Kind {
+	case packages.ListError:
+		code = "list-error"
+	case packages.ParseError:
+		code = "parse-error"
+	case packages.TypeError:
+		code = "type-error"
+	}
+
+	file, line, column := e.Pos, 0, 0
+	var numbers []int
+	for len(numbers) < 2 {
+		i := strings.LastIndex(file, ":")
+		n, err := strconv.Atoi(file[i+1:])
+		if i < 0 || err != nil {
+			break
+		}
+		file, numbers = file[:i], append([]int{n}, numbers...)
+	}
+	switch len(numbers) {
+	case 2:
+		line, column = numbers[0], numbers[1]
+	case 1:
+		line = numbers[0]
+	}
+	if file == "-" {
+		file = ""
+	}
+	if file != "" {
+		file = relativePath(root, token.Position{Filename: file})
+	}
+
+	return Diagnostic{
+		Severity: "error",
+		Message:  e.Msg,
+		Location: pointLocation(file, line, column),
+		Code:     code,
+		Source:   SourceAnalyzer,
+	}
+}
+
+func handleModuleAnalysis(root string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		module, path := q.Get("module"), q.Get("path")
+		for _, dir := range []string{module, path} {
+			if dir != "" && !isWithinRoot(root, dir) {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("path %s is outside the workspace", dir))
+				return
+			}
+		}
+
+		analysis, err := AnalyzeModule(root, module, path)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if severities := splitList(q.Get("severity")); len(severities) > 0 {
+			filtered := make([]Diagnostic, 0)
+			for _, d := range analysis.Diagnostics {
+				if containsString(severities, d.Severity) {
+					filtered = append(filtered, d)
+				}
+			}
+			analysis.Diagnostics = filtered
+		}
+		writeJSON(w, http.StatusOK, analysis)
+	}
+}
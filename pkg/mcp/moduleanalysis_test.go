@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestHandleModuleAnalysis(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"mod/go.mod":        "module example.com/m\n\ngo 1.22\n",
+		"mod/a/a.go":        "package a\n\n// Shell runs scripts\nconst Shell = \"sh\"\n\n// A returns one\nfunc A() int { return 1 }\n",
+		"mod/a/a_test.go":   "package a\n\nimport \"testing\"\n\n// TestA tests A\nfunc TestA(t *testing.T) {}\n",
+		"mod/a/ext_test.go": "package a_test\n\nimport (\n\t\"testing\"\n\n\t\"example.com/m/a\"\n)\n\n// TestExt tests A from outside\nfunc TestExt(t *testing.T) { a.A() }\n",
+		"mod/b/b.go":        "package b\n\nimport (\n\t\"os/exec\"\n\n\t\"example.com/m/a\"\n)\n\n// Run runs a script\nfunc Run(script string) error {\n\treturn exec.Command(a.Shell, \"-c\", script).Run()\n}\n",
+		"mod/c/c.go":        "package c\n\nimport \"example.com/m/a\"\n\n// C is missing\nfunc C() int { return a.Missing() }\n",
+	}
+	for name, src := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddAnalysisHandler()
+
+	analyze := func(query string) *ModuleAnalysis {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/module?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var analysis ModuleAnalysis
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&analysis))
+		return &analysis
+	}
+
+	analysis := analyze("module=mod")
+	assert.Equal(t, "example.com/m", analysis.Module)
+	assert.Equal(t, "mod", analysis.Root)
+	require.Len(t, analysis.Packages, 4)
+	assert.Equal(t, PackageAnalysis{
+		Path:    "example.com/m/a",
+		Name:    "a",
+		Dir:     "mod/a",
+		Files:   []string{"mod/a/a.go", "mod/a/a_test.go"},
+		Metrics: analysis.Packages[0].Metrics,
+	}, analysis.Packages[0])
+	assert.Equal(t, 1, analysis.Packages[0].Metrics.TestCount)
+	assert.Equal(t, "example.com/m/a_test", analysis.Packages[1].Path)
+	assert.Equal(t, []string{"example.com/m/a"}, analysis.Packages[1].Imports)
+	assert.Equal(t, []string{"example.com/m/a"}, analysis.Packages[2].Imports)
+	assert.Equal(t, 5, analysis.Metrics.FunctionCount)
+	assert.Equal(t, 2, analysis.Metrics.TestCount)
+
+	// Constants and errors resolve across packages
+	require.Len(t, analysis.Diagnostics, 2, analysis.Diagnostics)
+	assert.Equal(t, "exec-unsanitized-input", analysis.Diagnostics[0].Code)
+	assert.Equal(t, "Subprocess launched with a variable shell script", analysis.Diagnostics[0].Message)
+	assert.Equal(t, "mod/b/b.go", analysis.Diagnostics[0].Location.URI)
+	assert.Equal(t, Position{Line: 10, Character: 8}, analysis.Diagnostics[0].Location.Range.Start)
+	assert.Equal(t, Diagnostic{
+		Severity: "error",
+		Message:  "undefined: a.Missing",
+		Location: pointLocation("mod/c/c.go", 6, 25),
+		Code:     "type-error",
+		Source:   SourceAnalyzer,
+	}, analysis.Diagnostics[1])
+
+	analysis = analyze("module=mod&path=c&severity=warning")
+	require.Len(t, analysis.Packages, 1)
+	assert.Equal(t, "example.com/m/c", analysis.Packages[0].Path)
+	assert.Empty(t, analysis.Diagnostics)
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"outside the workspace", "module=../outside"},
+		{"no module", ""},
+		{"no packages", "module=mod&path=missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/module?"+tt.query, nil))
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestPackageErrorDiagnostic(t *testing.T) {
+	tests := []struct {
+		pos      string
+		location Location
+	}{
+		{"/w/a/a.go:3:14", pointLocation("a/a.go", 3, 14)},
+		{"/w/a/a.go:3", pointLocation("a/a.go", 3, 0)},
+		{"-", pointLocation("", 0, 0)},
+		{"", pointLocation("", 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pos, func(t *testing.T) {
+			d := packageErrorDiagnostic("/w", packages.Error{Pos: tt.pos, Msg: "expected (", Kind: packages.ParseError})
+			assert.Equal(t, tt.location, d.Location)
+			assert.Equal(t, "parse-error", d.Code)
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification the generated
+// document follows
+const OpenAPIVersion = "3.0.3"
+
+// OpenAPIDocument is an OpenAPI 3 description of the API
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Servers    []OpenAPIServer                         `json:"servers"`
+	Tags       []OpenAPITag                            `json:"tags,omitempty"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+type OpenAPITag struct {
+	Name string `json:"name"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema is the subset of JSON Schema used by OpenAPI 3.0
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+}
+
+// OpenAPI describes the routes registered so far
+func (s *Server) OpenAPI() *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info:    OpenAPIInfo{Title: "MCP API", Version: APIVersion},
+		Servers: []OpenAPIServer{{URL: "/" + APIVersion}},
+		Paths:   make(map[string]map[string]*OpenAPIOperation),
+	}
+	schemas := newSchemaGenerator()
+
+	tags := make(map[string]bool)
+	for _, route := range s.Routes() {
+		path, params := openAPIPath(route.Path)
+		tag := strings.SplitN(strings.TrimPrefix(route.Path, "/"), "/", 2)[0]
+		tags[tag] = true
+
+		op := &OpenAPIOperation{
+			OperationID: operationID(route.Method, path),
+			Summary:     route.Summary,
+			Tags:        []string{tag},
+			Responses:   make(map[string]*OpenAPIResponse),
+		}
+
+		for _, name := range params {
+			op.Parameters = append(op.Parameters, OpenAPIParameter{
+				Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+		for _, q := range route.Query {
+			op.Parameters = append(op.Parameters, OpenAPIParameter{
+				Name: q.Name, In: "query", Description: q.Description, Required: q.Required, Schema: &Schema{Type: "string"},
+			})
+		}
+
+		if route.Request != nil {
+			op.RequestBody = &OpenAPIRequestBody{
+				Required: true,
+				Content: map[string]OpenAPIMediaType{
+					"application/json": {Schema: schemas.schemaFor(reflect.TypeOf(route.Request))},
+				},
+			}
+		}
+
+		response := &OpenAPIResponse{Description: http.StatusText(route.Status)}
+		switch {
+		case route.Produces != "":
+			response.Content = map[string]OpenAPIMediaType{
+				route.Produces: {Schema: &Schema{Type: "string", Format: "binary"}},
+			}
+		case route.Response != nil:
+			response.Content = map[string]OpenAPIMediaType{
+				"application/json": {Schema: schemas.schemaFor(reflect.TypeOf(route.Response))},
+			}
+		}
+		op.Responses[strconv.Itoa(route.Status)] = response
+		op.Responses["default"] = &OpenAPIResponse{
+			Description: "Error",
+			Content: map[string]OpenAPIMediaType{
+				"application/json": {Schema: schemas.schemaFor(reflect.TypeOf(ErrorResponse{}))},
+			},
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*OpenAPIOperation)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, OpenAPITag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+
+	doc.Components.Schemas = schemas.components
+	return doc
+}
+
+// routeVar matches mux path variables, which may carry a pattern as in {id:[0-9]+}
+var routeVar = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// openAPIPath converts a mux path template to OpenAPI syntax and returns the
+// names of its variables
+func openAPIPath(path string) (string, []string) {
+	var params []string
+	converted := routeVar.ReplaceAllStringFunc(path, func(v string) string {
+		name := routeVar.FindStringSubmatch(v)[1]
+		params = append(params, name)
+		return "{" + name + "}"
+	})
+	return converted, params
+}
+
+// operationID derives an identifier such as getIdeTasksById from a route
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") {
+			segment = "By" + exportName(strings.Trim(segment, "{}"))
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			b.WriteString(exportName(word))
+		}
+	}
+	return b.String()
+}
+
+// schemaGenerator derives schemas from Go types. Named struct types become
+// shared components; types outside this package are qualified by their
+// package name so they cannot clash, as with ide.CommandResult.
+type schemaGenerator struct {
+	components map[string]*Schema
+	inline     map[reflect.Type]bool // Structs being inlined, nil for components
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{components: make(map[string]*Schema)}
+}
+
+// newInlineSchemaGenerator returns a generator writing named struct types in
+// place, for self-contained schemas. Recursive references accept any value.
+func newInlineSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{inline: make(map[reflect.Type]bool)}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	thisPackage    = reflect.TypeOf(Server{}).PkgPath()
+)
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		schema := g.schemaFor(t.Elem())
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64"} // Nanoseconds
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if g.inline != nil {
+			if g.inline[t] {
+				return &Schema{}
+			}
+			g.inline[t] = true
+			defer delete(g.inline, t)
+			return g.structSchema(t)
+		}
+		name := g.componentName(t)
+		if _, ok := g.components[name]; !ok {
+			// Reserve the name first so recursive types terminate
+			g.components[name] = &Schema{}
+			*g.components[name] = *g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	// Interfaces and anything else accept any value
+	return &Schema{}
+}
+
+func (g *schemaGenerator) componentName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i] // Generic instantiation
+	}
+	if t.PkgPath() == thisPackage {
+		return name
+	}
+	pkg := t.PkgPath()
+	return pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+}
+
+// structSchema describes the JSON encoding of a struct, flattening embedded
+// structs as encoding/json does. Fields tagged required:"true" are
+// required, the description tag describes them and the default tag gives
+// the value of those left out of function arguments.
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields := g.structSchema(embedded)
+				for k, v := range fields.Properties {
+					if _, ok := schema.Properties[k]; !ok {
+						schema.Properties[k] = v
+					}
+				}
+				for _, k := range fields.Required {
+					if schema.Properties[k] == fields.Properties[k] {
+						schema.Required = append(schema.Required, k)
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		property := g.schemaFor(field.Type)
+		if description := field.Tag.Get("description"); description != "" && property.Ref == "" {
+			property.Description = description
+		}
+		if value, ok, err := fieldDefault(field); ok && err == nil && property.Ref == "" {
+			property.Default = value.Interface()
+		}
+		schema.Properties[name] = property
+		if field.Tag.Get("required") == "true" {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+func handleOpenAPI(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.OpenAPI())
+	}
+}
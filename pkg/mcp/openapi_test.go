@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI_CoversRouter(t *testing.T) {
+	s := NewServer(nil)
+	s.AddSSHHandler()
+	s.AddBrowserHandlers()
+	s.AddLanguageServerHandler()
+	s.AddAnalysisHandler()
+	s.AddFunctionHandler()
+	s.AddDocumentationEndpoints()
+	s.AddDiagnosticsHandler()
+	ideServer, err := NewIDEServer(t.TempDir())
+	require.NoError(t, err)
+	s.AddIDEServer(ideServer)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+
+	var doc OpenAPIDocument
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "/v1", doc.Servers[0].URL)
+
+	// Every route the router serves is documented
+	err = s.router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, _ := route.GetPathTemplate()
+		methods, _ := route.GetMethods()
+		if path == "/openapi.json" {
+			return nil
+		}
+		for _, method := range methods {
+			assert.NotNil(t, doc.Paths[path][strings.ToLower(method)], "%s %s is not documented", method, path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	exec := doc.Paths["/ssh/{id}/exec"]["post"]
+	require.NotNil(t, exec)
+	assert.Equal(t, "postSshByIdExec", exec.OperationID)
+	assert.Equal(t, []string{"ssh"}, exec.Tags)
+	assert.Equal(t, "id", exec.Parameters[0].Name)
+	assert.Equal(t, "path", exec.Parameters[0].In)
+	assert.Equal(t, "#/components/schemas/CommandResult", exec.Responses["200"].Content["application/json"].Schema.Ref)
+
+	task := doc.Components.Schemas["ide.Task"]
+	require.NotNil(t, task)
+	assert.Equal(t, "#/components/schemas/ide.TaskTarget", task.Properties["target"].Ref)
+	assert.Equal(t, "array", task.Properties["artifacts"].Type)
+
+	// Embedded structs are flattened like encoding/json does
+	bench := doc.Components.Schemas["BenchRequest"]
+	require.NotNil(t, bench)
+	assert.Contains(t, bench.Properties, "packages")
+	assert.Contains(t, bench.Properties, "baseline")
+
+	// Every reference resolves
+	data, _ := json.Marshal(doc)
+	for _, part := range strings.Split(string(data), `"$ref":"#/components/schemas/`)[1:] {
+		name := part[:strings.Index(part, `"`)]
+		assert.Contains(t, doc.Components.Schemas, name)
+	}
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	path, params := openAPIPath("/ide/tasks/{id:[0-9]+}/logs")
+	assert.Equal(t, "/ide/tasks/{id}/logs", path)
+	assert.Equal(t, []string{"id"}, params)
+}
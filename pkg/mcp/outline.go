@@ -0,0 +1,322 @@
+package mcp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"net/http"
+	"sort"
+)
+
+// Kinds of folding ranges; other ranges are code blocks
+const (
+	FoldingRangeComment = "comment"
+	FoldingRangeImports = "imports"
+)
+
+// DocumentSymbol is a symbol of a document with the symbols it contains:
+// types contain their fields and methods, and functions the functions and
+// types declared in their body
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Kind           string           `json:"kind"`             // function, method, type, field, variable or constant
+	Detail         string           `json:"detail,omitempty"` // Signature or type
+	Range          Range            `json:"range"`            // The whole declaration
+	SelectionRange Range            `json:"selectionRange"`   // The name
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// FoldingRange is a range of lines an editor can collapse; the first line
+// stays visible
+type FoldingRange struct {
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Kind      string `json:"kind,omitempty"` // comment, imports or empty for code
+}
+
+// extractSymbols returns the outline of a file. Methods are listed under
+// their receiver type when it is declared in the file, and at the top level
+// as (T).Method otherwise.
+func (ls *LanguageServer) extractSymbols(file *ast.File) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	if file == nil {
+		return symbols
+	}
+
+	declared := make(map[string]int) // Index of the top-level types in symbols
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, symbol := range ls.specSymbols(gen) {
+			if symbol.Kind == "type" {
+				declared[symbol.Name] = len(symbols)
+			}
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	var methods []DocumentSymbol
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		symbol := ls.funcSymbol(fn.Name, fn, fn.Type, fn.Body)
+		if fn.Recv == nil || len(fn.Recv.List) == 0 {
+			symbols = append(symbols, symbol)
+			continue
+		}
+
+		symbol.Kind = "method"
+		recv := receiverName(fn.Recv.List[0].Type)
+		if i, ok := declared[recv]; ok {
+			symbols[i].Children = append(symbols[i].Children, symbol)
+			continue
+		}
+		if _, pointer := fn.Recv.List[0].Type.(*ast.StarExpr); pointer {
+			recv = "*" + recv
+		}
+		symbol.Name = fmt.Sprintf("(%s).%s", recv, symbol.Name)
+		methods = append(methods, symbol)
+	}
+	symbols = append(symbols, methods...)
+
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return positionBefore(symbols[i].Range.Start, symbols[j].Range.Start)
+	})
+	return symbols
+}
+
+// specSymbols returns the types, variables and constants declared by gen
+func (ls *LanguageServer) specSymbols(gen *ast.GenDecl) []DocumentSymbol {
+	var symbols []DocumentSymbol
+	for _, spec := range gen.Specs {
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			symbol := DocumentSymbol{
+				Name:           spec.Name.Name,
+				Kind:           "type",
+				Detail:         types.ExprString(spec.Type),
+				Range:          ls.nodeRange(spec),
+				SelectionRange: ls.nodeRange(spec.Name),
+			}
+			switch t := spec.Type.(type) {
+			case *ast.StructType:
+				symbol.Detail = "struct"
+				symbol.Children = ls.fieldSymbols(t.Fields, "field")
+			case *ast.InterfaceType:
+				symbol.Detail = "interface"
+				symbol.Children = ls.fieldSymbols(t.Methods, "method")
+			}
+			symbols = append(symbols, symbol)
+
+		case *ast.ValueSpec:
+			kind := "variable"
+			if gen.Tok == token.CONST {
+				kind = "constant"
+			}
+			for i, name := range spec.Names {
+				if name.Name == "_" {
+					continue
+				}
+				symbol := DocumentSymbol{
+					Name:           name.Name,
+					Kind:           kind,
+					Range:          ls.nodeRange(spec),
+					SelectionRange: ls.nodeRange(name),
+				}
+				if spec.Type != nil {
+					symbol.Detail = types.ExprString(spec.Type)
+				}
+				if i < len(spec.Values) {
+					if lit, ok := spec.Values[i].(*ast.FuncLit); ok {
+						symbol = ls.funcSymbol(name, spec, lit.Type, lit.Body)
+					}
+				}
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	return symbols
+}
+
+// fieldSymbols returns the fields of a struct or the methods of an
+// interface, naming embedded ones after their type
+func (ls *LanguageServer) fieldSymbols(fields *ast.FieldList, kind string) []DocumentSymbol {
+	var symbols []DocumentSymbol
+	if fields == nil {
+		return symbols
+	}
+	for _, field := range fields.List {
+		names := field.Names
+		if len(names) == 0 {
+			names = embeddedName(field.Type)
+		}
+		for _, name := range names {
+			symbols = append(symbols, DocumentSymbol{
+				Name:           name.Name,
+				Kind:           kind,
+				Detail:         types.ExprString(field.Type),
+				Range:          ls.nodeRange(field),
+				SelectionRange: ls.nodeRange(name),
+			})
+		}
+	}
+	return symbols
+}
+
+// funcSymbol returns the symbol of a function named name, declared by decl,
+// with the named functions and the types declared in its body as children
+func (ls *LanguageServer) funcSymbol(name *ast.Ident, decl ast.Node, typ *ast.FuncType, body *ast.BlockStmt) DocumentSymbol {
+	symbol := DocumentSymbol{
+		Name:           name.Name,
+		Kind:           "function",
+		Detail:         types.ExprString(typ),
+		Range:          ls.nodeRange(decl),
+		SelectionRange: ls.nodeRange(name),
+	}
+	if body == nil {
+		return symbol
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			// name := func() {...}
+			handled := false
+			for i, rhs := range n.Rhs {
+				lit, ok := rhs.(*ast.FuncLit)
+				if !ok || i >= len(n.Lhs) {
+					continue
+				}
+				if ident, ok := n.Lhs[i].(*ast.Ident); ok && ident.Name != "_" {
+					symbol.Children = append(symbol.Children, ls.funcSymbol(ident, n, lit.Type, lit.Body))
+					handled = true
+				}
+			}
+			return !handled
+		case *ast.GenDecl:
+			if n.Tok == token.TYPE || n.Tok == token.VAR {
+				for _, child := range ls.specSymbols(n) {
+					if child.Kind == "type" || child.Kind == "function" {
+						symbol.Children = append(symbol.Children, child)
+					}
+				}
+				return false
+			}
+		}
+		return true
+	})
+	return symbol
+}
+
+// nodeRange returns the range of a node of a document
+func (ls *LanguageServer) nodeRange(node ast.Node) Range {
+	start := ls.fileSet.Position(node.Pos())
+	end := ls.fileSet.Position(node.End())
+	return Range{
+		Start: Position{Line: start.Line - 1, Character: start.Column - 1},
+		End:   Position{Line: end.Line - 1, Character: end.Column - 1},
+	}
+}
+
+func positionBefore(a, b Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
+
+// documentFoldingRanges returns the folding ranges of a document, or none
+// while its AST is kept from an earlier text, whose lines differ
+func (ls *LanguageServer) documentFoldingRanges(doc *Document) []FoldingRange {
+	if !doc.parsedText() {
+		return []FoldingRange{}
+	}
+	return ls.foldingRanges(doc.AST)
+}
+
+// foldingRanges returns the blocks, literals, parenthesized declarations,
+// multi-line calls and comments of a file spanning several lines. A range
+// ends on the line before its closing delimiter, which stays visible.
+func (ls *LanguageServer) foldingRanges(file *ast.File) []FoldingRange {
+	ranges := []FoldingRange{}
+	if file == nil {
+		return ranges
+	}
+	line := func(pos token.Pos) int {
+		return ls.fileSet.Position(pos).Line - 1
+	}
+	add := func(start, end int, kind string) {
+		if end > start {
+			ranges = append(ranges, FoldingRange{StartLine: start, EndLine: end, Kind: kind})
+		}
+	}
+	delimited := func(open, close token.Pos, kind string) {
+		if open.IsValid() && close.IsValid() {
+			add(line(open), line(close)-1, kind)
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			delimited(n.Lbrace, n.Rbrace, "")
+		case *ast.CaseClause:
+			add(line(n.Colon), line(n.End()), "")
+		case *ast.CommClause:
+			add(line(n.Colon), line(n.End()), "")
+		case *ast.CompositeLit:
+			delimited(n.Lbrace, n.Rbrace, "")
+		case *ast.FieldList:
+			delimited(n.Opening, n.Closing, "")
+		case *ast.CallExpr:
+			delimited(n.Lparen, n.Rparen, "")
+		case *ast.GenDecl:
+			kind := ""
+			if n.Tok == token.IMPORT {
+				kind = FoldingRangeImports
+			}
+			delimited(n.Lparen, n.Rparen, kind)
+		}
+		return true
+	})
+	for _, group := range file.Comments {
+		add(line(group.Pos()), line(group.End()), FoldingRangeComment)
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].StartLine != ranges[j].StartLine {
+			return ranges[i].StartLine < ranges[j].StartLine
+		}
+		return ranges[i].EndLine > ranges[j].EndLine
+	})
+	return ranges
+}
+
+func handleFoldingRanges(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uri := r.URL.Query().Get("uri")
+		if uri == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("uri parameter is required"))
+			return
+		}
+
+		// The file set forgets a document once it is replaced, so compute
+		// the ranges while holding the lock
+		ls.mu.RLock()
+		doc, exists := ls.documents[uri]
+		var ranges []FoldingRange
+		if exists {
+			ranges = ls.documentFoldingRanges(doc)
+		}
+		ls.mu.RUnlock()
+
+		if !exists {
+			writeError(w, http.StatusNotFound, fmt.Errorf("document not found"))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ranges)
+	}
+}
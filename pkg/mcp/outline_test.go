@@ -0,0 +1,161 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const outlineSource = `package example
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Greeter greets
+// people
+type Greeter struct {
+	Name string
+	*strings.Builder
+}
+
+const Version = "1"
+
+func (g *Greeter) Greet() {
+	format := func(name string) string {
+		return "hello " + name
+	}
+	type pair struct{ a, b int }
+	fmt.Println(format(g.Name))
+}
+
+func (o Other) Ignored() {}
+
+type Stringer interface {
+	String() string
+}
+`
+
+// outlineOf returns the names and kinds of symbols, nested like the outline
+func outlineOf(symbols []DocumentSymbol) []string {
+	var names []string
+	for _, symbol := range symbols {
+		names = append(names, symbol.Kind+" "+symbol.Name)
+		for _, child := range outlineOf(symbol.Children) {
+			names = append(names, "  "+child)
+		}
+	}
+	return names
+}
+
+func TestHandleDocumentSymbols(t *testing.T) {
+	s := NewServer(nil)
+	s.AddLanguageServerHandler()
+
+	body, err := json.Marshal(TextDocumentItem{URI: "example.go", Text: outlineSource})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/document/open", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/symbols?uri=example.go", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var symbols []DocumentSymbol
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&symbols))
+
+	assert.Equal(t, []string{
+		"type Greeter",
+		"  field Name",
+		"  field Builder",
+		"  method Greet",
+		"    function format",
+		"    type pair",
+		"      field a",
+		"      field b",
+		"constant Version",
+		"method (Other).Ignored",
+		"type Stringer",
+		"  method String",
+	}, outlineOf(symbols))
+
+	greeter := symbols[0]
+	assert.Equal(t, "struct", greeter.Detail)
+	assert.Equal(t, Range{Start: Position{Line: 9, Character: 5}, End: Position{Line: 12, Character: 1}}, greeter.Range)
+	assert.Equal(t, Range{Start: Position{Line: 9, Character: 5}, End: Position{Line: 9, Character: 12}}, greeter.SelectionRange)
+	assert.Equal(t, "*strings.Builder", greeter.Children[1].Detail)
+	assert.Equal(t, "func()", greeter.Children[2].Detail)
+	assert.Equal(t, "func(name string) string", greeter.Children[2].Children[0].Detail)
+}
+
+func TestHandleFoldingRanges(t *testing.T) {
+	s := NewServer(nil)
+	s.AddLanguageServerHandler()
+
+	body, err := json.Marshal(TextDocumentItem{URI: "example.go", Text: outlineSource})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/document/open", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/folding?uri=example.go", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var ranges []FoldingRange
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&ranges))
+
+	assert.Equal(t, []FoldingRange{
+		{StartLine: 2, EndLine: 4, Kind: FoldingRangeImports},
+		{StartLine: 7, EndLine: 8, Kind: FoldingRangeComment},
+		{StartLine: 9, EndLine: 11},
+		{StartLine: 16, EndLine: 21},
+		{StartLine: 17, EndLine: 18},
+		{StartLine: 26, EndLine: 27},
+	}, ranges)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/folding?uri=missing.go", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFoldingRangesOfKeptAST(t *testing.T) {
+	ls := NewLanguageServer(t.TempDir())
+	fixed := "package p\n\n// moved\n\nfunc a() {\n\treturn\n}\n"
+	folding := func() []FoldingRange {
+		return ls.documentFoldingRanges(ls.documents["p.go"])
+	}
+
+	require.NoError(t, ls.openDocument(TextDocumentItem{URI: "p.go", Text: "package p\n\nfunc a() {\n\treturn\n}\n", Version: 1}))
+	assert.Equal(t, []FoldingRange{{StartLine: 2, EndLine: 3}}, folding())
+
+	// The AST kept from version 1 has lines of another text
+	tests := []struct {
+		name    string
+		text    string
+		folding []FoldingRange
+	}{
+		{"syntax error", fixed + "\nfunc {", []FoldingRange{}},
+		{"unchanged syntax error", fixed + "\nfunc {", []FoldingRange{}},
+		{"parsed again", fixed, []FoldingRange{{StartLine: 4, EndLine: 5}}},
+		{"unchanged", fixed, []FoldingRange{{StartLine: 4, EndLine: 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ls.changeDocument(DocumentChange{URI: "p.go", Text: tt.text})
+			require.NoError(t, err)
+			assert.Equal(t, tt.folding, folding())
+			require.Len(t, ls.documents["p.go"].Symbols, 1)
+			assert.Equal(t, "a", ls.documents["p.go"].Symbols[0].Name)
+		})
+	}
+
+	// Partial ASTs of documents that never parsed are of their text
+	require.NoError(t, ls.openDocument(TextDocumentItem{URI: "broken.go", Text: "package p\n\nfunc a() {\n\treturn\n}\n\nfunc {", Version: 1}))
+	assert.Equal(t, []FoldingRange{{StartLine: 2, EndLine: 3}}, ls.documentFoldingRanges(ls.documents["broken.go"]))
+}
@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+)
+
+// KillProcessRequest selects the signal sent to a task process
+type KillProcessRequest struct {
+	Signal string `json:"signal,omitempty"` // TERM, KILL, INT or HUP; defaults to TERM
+}
+
+// inspectStatus maps inspection errors to HTTP status codes
+func inspectStatus(err error) int {
+	switch {
+	case errors.Is(err, ide.ErrInspectUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, ide.ErrProcessNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ide.ErrNotTaskProcess):
+		return http.StatusForbidden
+	case errors.Is(err, ide.ErrUnsupportedSignal):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func handleListProcesses(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inspection, err := ide.Inspect(ideServer.taskManager.TaskPIDs())
+		if err != nil {
+			writeError(w, inspectStatus(err), err)
+			return
+		}
+
+		processes := inspection.Processes
+		if r.URL.Query().Get("all") != "true" {
+			processes = processes[:0]
+			for _, p := range inspection.Processes {
+				if p.TaskID != "" {
+					processes = append(processes, p)
+				}
+			}
+		}
+
+		writeJSON(w, http.StatusOK, processes)
+	}
+}
+
+// handleListPorts lists the ports held by tasks. Looking up a single port
+// searches the whole host, since whatever holds it is usually the answer to
+// an "address already in use" failure.
+func handleListPorts(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		port := 0
+		if v := query.Get("port"); v != "" {
+			var err error
+			if port, err = strconv.Atoi(v); err != nil || port <= 0 || port > 65535 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid port %s", v))
+				return
+			}
+		}
+
+		inspection, err := ide.Inspect(ideServer.taskManager.TaskPIDs())
+		if err != nil {
+			writeError(w, inspectStatus(err), err)
+			return
+		}
+
+		all := query.Get("all") == "true" || port != 0
+		ports := make([]ide.ListeningPort, 0, len(inspection.Ports))
+		for _, p := range inspection.Ports {
+			if (all || p.TaskID != "") && (port == 0 || p.Port == port) {
+				ports = append(ports, p)
+			}
+		}
+
+		writeJSON(w, http.StatusOK, ports)
+	}
+}
+
+func handleKillProcess(ideServer *IDEServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pid, err := strconv.Atoi(mux.Vars(r)["pid"])
+		if err != nil || pid <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid pid %s", mux.Vars(r)["pid"]))
+			return
+		}
+
+		var req KillProcessRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		process, err := ide.KillTaskProcess(pid, req.Signal, ideServer.taskManager.TaskPIDs())
+		if err != nil {
+			writeError(w, inspectStatus(err), err)
+			return
+		}
+
+		LoggerFromContext(r.Context()).Info("task process signalled",
+			"pid", pid, "task_id", process.TaskID, "signal", req.Signal)
+
+		writeJSON(w, http.StatusOK, process)
+	}
+}
@@ -0,0 +1,246 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// PromptConfig defines a prompt template offered to MCP hosts. Templates
+// are text/templates over the arguments, e.g. {{.focus}}. A prompt with a
+// context type takes the ID of a context of that type as its "context"
+// argument, embeds the context in the prompt, and is only listed while such
+// contexts are stored.
+type PromptConfig struct {
+	Name        string           `yaml:"name"`
+	Description string           `yaml:"description"`
+	Arguments   []PromptArgument `yaml:"arguments"`
+	ContextType string           `yaml:"context_type"`
+	Template    string           `yaml:"template"`
+}
+
+// PromptArgument describes an argument of a prompt
+type PromptArgument struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	Required    bool   `yaml:"required" json:"required"`
+}
+
+// contextArgument names the argument of prompts derived from contexts
+const contextArgument = "context"
+
+// builtinPrompts are offered for the contexts the processors create. Prompts
+// of the same name in the config replace them.
+var builtinPrompts = []PromptConfig{
+	{
+		Name:        "summarize_openapi",
+		Description: "Summarize the API described by an OpenAPI spec",
+		ContextType: "openapi",
+		Arguments:   []PromptArgument{{Name: "focus", Description: "Part of the API to focus on"}},
+		Template: "Summarize the API described by the attached OpenAPI spec: its purpose, authentication, " +
+			"main resources and their operations.{{if .focus}} Focus on {{.focus}}.{{end}} " +
+			"Point out inconsistencies and gaps in the spec.",
+	},
+	{
+		Name:        "summarize_postman",
+		Description: "Summarize the requests of a Postman collection",
+		ContextType: "postman",
+		Template:    "Summarize the attached Postman collection: which API it exercises, how its requests are grouped and which variables they need.",
+	},
+	{
+		Name:        "explain_curl",
+		Description: "Explain a curl collection and suggest tests for it",
+		ContextType: "curl",
+		Template:    "Explain what the requests of the attached curl collection do and how they depend on each other, then suggest tests covering them.",
+	},
+	{
+		Name:        "review_proxy_session",
+		Description: "Review the API traffic of a recorded proxy session",
+		ContextType: "proxy",
+		Template: "Review the API traffic recorded in the attached proxy session: the endpoints used, the order of calls, " +
+			"failed requests and anything the inferred OpenAPI fragment gets wrong.",
+	},
+}
+
+// validatePrompts checks that names are unique and templates parse
+func validatePrompts(prompts []PromptConfig) error {
+	names := make(map[string]bool)
+	for _, p := range prompts {
+		if p.Name == "" {
+			return fmt.Errorf("prompt without name")
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicate prompt %q", p.Name)
+		}
+		names[p.Name] = true
+		if _, err := p.template(); err != nil {
+			return fmt.Errorf("prompt %s: %v", p.Name, err)
+		}
+		args := make(map[string]bool)
+		for _, arg := range p.Arguments {
+			if arg.Name == "" || args[arg.Name] {
+				return fmt.Errorf("prompt %s: missing or duplicate argument name", p.Name)
+			}
+			if arg.Name == contextArgument && p.ContextType != "" {
+				return fmt.Errorf("prompt %s: argument %s is reserved for the context", p.Name, contextArgument)
+			}
+			args[arg.Name] = true
+		}
+	}
+	return nil
+}
+
+func (p PromptConfig) template() (*template.Template, error) {
+	return template.New(p.Name).Option("missingkey=zero").Parse(p.Template)
+}
+
+// arguments returns the arguments of the prompt, including the context
+func (p PromptConfig) arguments() []PromptArgument {
+	if p.ContextType == "" {
+		return p.Arguments
+	}
+	arg := PromptArgument{
+		Name:        contextArgument,
+		Description: fmt.Sprintf("ID of the %s context", p.ContextType),
+		Required:    true,
+	}
+	return append([]PromptArgument{arg}, p.Arguments...)
+}
+
+type mcpPrompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments"`
+}
+
+type mcpGetPromptParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments"`
+}
+
+type mcpPromptMessage struct {
+	Role    string           `json:"role"`
+	Content mcpPromptContent `json:"content"`
+}
+
+type mcpPromptContent struct {
+	Type     string               `json:"type"`
+	Text     string               `json:"text,omitempty"`
+	Resource *mcpResourceContents `json:"resource,omitempty"`
+}
+
+// Prompts returns the built-in prompts and those of the config by name
+func (s *Server) Prompts() []PromptConfig {
+	byName := make(map[string]PromptConfig)
+	for _, p := range builtinPrompts {
+		byName[p.Name] = p
+	}
+	for _, p := range s.config.Prompts {
+		byName[p.Name] = p
+	}
+	prompts := make([]PromptConfig, 0, len(byName))
+	for _, p := range byName {
+		prompts = append(prompts, p)
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts
+}
+
+func (s *Server) prompt(name string) (PromptConfig, bool) {
+	for _, p := range s.Prompts() {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return PromptConfig{}, false
+}
+
+// listPrompts lists the prompts without a context type and those whose
+// contexts are stored
+func (m *mcpSession) listPrompts(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	stored := make(map[string]bool)
+	for _, c := range m.s.store.List() {
+		if contextType, ok := c.Metadata["type"].(string); ok {
+			stored[contextType] = true
+		}
+	}
+
+	prompts := make([]mcpPrompt, 0)
+	for _, p := range m.s.Prompts() {
+		if p.ContextType != "" && !stored[p.ContextType] {
+			continue
+		}
+		args := p.arguments()
+		if args == nil {
+			args = []PromptArgument{}
+		}
+		prompts = append(prompts, mcpPrompt{Name: p.Name, Description: p.Description, Arguments: args})
+	}
+	return map[string][]mcpPrompt{"prompts": prompts}, nil
+}
+
+// getPrompt renders a prompt with the given arguments. The context of a
+// prompt with a context type precedes the instructions as a resource.
+func (m *mcpSession) getPrompt(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p mcpGetPromptParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	invalid := func(format string, args ...interface{}) error {
+		return &RPCError{Code: RPCInvalidParams, Message: fmt.Sprintf(format, args...)}
+	}
+
+	prompt, ok := m.s.prompt(p.Name)
+	if !ok {
+		return nil, invalid("unknown prompt %s", p.Name)
+	}
+	data := make(map[string]string)
+	for _, arg := range prompt.arguments() {
+		value, ok := p.Arguments[arg.Name]
+		if arg.Required && (!ok || value == "") {
+			return nil, invalid("missing argument %s", arg.Name)
+		}
+		data[arg.Name] = value
+	}
+
+	var messages []mcpPromptMessage
+	if prompt.ContextType != "" {
+		c, err := m.s.store.Get(data[contextArgument])
+		if errors.Is(err, ErrContextNotFound) {
+			return nil, invalid("context %s not found", data[contextArgument])
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c.Metadata["type"] != prompt.ContextType {
+			return nil, invalid("context %s is not a %s context", c.ID, prompt.ContextType)
+		}
+		contents, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, mcpPromptMessage{Role: "user", Content: mcpPromptContent{
+			Type:     "resource",
+			Resource: &mcpResourceContents{URI: ContextURI(c.ID), MIMEType: "application/json", Text: string(contents)},
+		}})
+	}
+
+	tmpl, err := prompt.template()
+	if err != nil {
+		return nil, err
+	}
+	var text strings.Builder
+	if err := tmpl.Execute(&text, data); err != nil {
+		return nil, invalid("prompt %s: %v", prompt.Name, err)
+	}
+	messages = append(messages, mcpPromptMessage{Role: "user", Content: mcpPromptContent{Type: "text", Text: text.String()}})
+
+	return map[string]interface{}{
+		"description": prompt.Description,
+		"messages":    messages,
+	}, nil
+}
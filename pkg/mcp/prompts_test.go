@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPSession_Prompts(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Prompts = []PromptConfig{
+		{
+			Name:        "review_handler",
+			Description: "Review an HTTP handler",
+			Arguments:   []PromptArgument{{Name: "handler", Required: true}, {Name: "style"}},
+			Template:    "Review {{.handler}}{{if .style}} for {{.style}}{{end}}.",
+		},
+		{
+			Name:        "explain_curl",
+			ContextType: "curl",
+			Template:    "Shorter: explain the attached collection.",
+		},
+	}
+	require.NoError(t, cfg.Validate())
+	s := NewServer(nil, WithConfig(cfg))
+	session := s.newMCPSession("")
+	ctx := context.Background()
+	call := func(body string) RPCResponse {
+		resp, ok := session.handleMessage(ctx, []byte(body)).(*RPCResponse)
+		require.True(t, ok, body)
+		return *resp
+	}
+	resp := call(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{}}`)
+	assert.Contains(t, string(resp.Result), `"prompts":{"listChanged":true}`)
+
+	var list struct {
+		Prompts []mcpPrompt `json:"prompts"`
+	}
+	names := func() []string {
+		resp := call(`{"jsonrpc":"2.0","id":1,"method":"prompts/list"}`)
+		require.NoError(t, json.Unmarshal(resp.Result, &list))
+		names := make([]string, len(list.Prompts))
+		for i, p := range list.Prompts {
+			names[i] = p.Name
+		}
+		return names
+	}
+	assert.Equal(t, []string{"review_handler"}, names(), "prompts of missing context types are not listed")
+
+	s.store.Create(&Context{ID: "openapi-petstore", Metadata: map[string]interface{}{"type": "openapi", "spec": "petstore"}})
+	s.store.Create(&Context{ID: "curl-login", Metadata: map[string]interface{}{"type": "curl"}})
+	assert.Equal(t, []string{"explain_curl", "review_handler", "summarize_openapi"}, names())
+	assert.Equal(t, []PromptArgument{
+		{Name: "context", Description: "ID of the openapi context", Required: true},
+		{Name: "focus", Description: "Part of the API to focus on"},
+	}, list.Prompts[2].Arguments)
+
+	var prompt struct {
+		Messages []mcpPromptMessage `json:"messages"`
+	}
+	resp = call(`{"jsonrpc":"2.0","id":2,"method":"prompts/get","params":{"name":"review_handler","arguments":{"handler":"handleCreateContext","style":"error handling"}}}`)
+	require.NoError(t, json.Unmarshal(resp.Result, &prompt))
+	require.Len(t, prompt.Messages, 1)
+	assert.Equal(t, "Review handleCreateContext for error handling.", prompt.Messages[0].Content.Text)
+
+	resp = call(`{"jsonrpc":"2.0","id":3,"method":"prompts/get","params":{"name":"summarize_openapi","arguments":{"context":"openapi-petstore","focus":"orders"}}}`)
+	require.NoError(t, json.Unmarshal(resp.Result, &prompt))
+	require.Len(t, prompt.Messages, 2)
+	assert.Equal(t, "resource", prompt.Messages[0].Content.Type)
+	assert.Equal(t, "mcp://context/openapi-petstore", prompt.Messages[0].Content.Resource.URI)
+	assert.Contains(t, prompt.Messages[0].Content.Resource.Text, "petstore")
+	assert.Contains(t, prompt.Messages[1].Content.Text, "Focus on orders.")
+
+	resp = call(`{"jsonrpc":"2.0","id":4,"method":"prompts/get","params":{"name":"explain_curl","arguments":{"context":"curl-login"}}}`)
+	require.NoError(t, json.Unmarshal(resp.Result, &prompt))
+	assert.Equal(t, "Shorter: explain the attached collection.", prompt.Messages[1].Content.Text)
+
+	for _, params := range []string{
+		`{"name":"missing"}`,
+		`{"name":"review_handler","arguments":{}}`,
+		`{"name":"summarize_openapi","arguments":{"context":"curl-login"}}`,
+		`{"name":"summarize_openapi","arguments":{"context":"gone"}}`,
+	} {
+		resp = call(`{"jsonrpc":"2.0","id":5,"method":"prompts/get","params":` + params + `}`)
+		require.NotNil(t, resp.Error, params)
+		assert.Equal(t, RPCInvalidParams, resp.Error.Code, params)
+	}
+}
+
+func TestValidatePrompts(t *testing.T) {
+	assert.NoError(t, validatePrompts(builtinPrompts))
+	assert.Error(t, validatePrompts([]PromptConfig{{Template: "x"}}))
+	assert.Error(t, validatePrompts([]PromptConfig{{Name: "a"}, {Name: "a"}}))
+	assert.Error(t, validatePrompts([]PromptConfig{{Name: "a", Template: "{{.x"}}))
+	assert.Error(t, validatePrompts([]PromptConfig{{Name: "a", ContextType: "curl", Arguments: []PromptArgument{{Name: "context"}}}}))
+}
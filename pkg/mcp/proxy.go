@@ -0,0 +1,188 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ivikasavnish/go-mcp/pkg/contextid"
+	"github.com/ivikasavnish/go-mcp/pkg/recorder"
+)
+
+// ProxyConfig configures the recording proxy. Applications send their API
+// traffic through it; every session is kept as a context holding a curl
+// collection and an inferred OpenAPI fragment.
+type ProxyConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	ListenAddr    string `yaml:"listen_addr"`    // Any form -listen accepts
+	Target        string `yaml:"target"`         // Upstream of reverse proxy mode; a forward proxy when empty
+	SessionHeader string `yaml:"session_header"` // Tags requests with a session, defaults to X-MCP-Session
+	MaxBodyBytes  int64  `yaml:"max_body_bytes"` // Recorded per body, defaults to 1 MiB
+	MaxExchanges  int    `yaml:"max_exchanges"`  // Kept per session, defaults to 1000
+}
+
+// Validate checks the proxy config when the proxy is enabled
+func (c ProxyConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ListenAddr == "" {
+		return fmt.Errorf("listen_addr is required")
+	}
+	if _, err := c.targetURL(); err != nil {
+		return err
+	}
+	if c.MaxBodyBytes < 0 || c.MaxExchanges < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return nil
+}
+
+func (c ProxyConfig) targetURL() (*url.URL, error) {
+	if c.Target == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(c.Target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid target %q: must be an http or https URL", c.Target)
+	}
+	return u, nil
+}
+
+// proxyContextIDs names the contexts of recorded sessions
+var proxyContextIDs, _ = contextid.NewGenerator("proxy-{name}")
+
+// newRecorder creates the recording proxy, which keeps the context of a
+// session up to date as exchanges are recorded
+func (s *Server) newRecorder() *recorder.Recorder {
+	target, _ := s.config.Proxy.targetURL() // Validated with the config
+	return recorder.New(recorder.Config{
+		Target:        target,
+		SessionHeader: s.config.Proxy.SessionHeader,
+		MaxBodyBytes:  s.config.Proxy.MaxBodyBytes,
+		MaxExchanges:  s.config.Proxy.MaxExchanges,
+		Logger:        s.logger.With("component", "proxy"),
+		OnRecord: func(session string, exchanges []recorder.Exchange) {
+			if err := s.storeProxySession(session, exchanges); err != nil {
+				s.logger.Error("failed to store proxy session", "session", session, "error", err)
+			}
+		},
+	})
+}
+
+// storeProxySession creates or replaces the context of a recorded session
+func (s *Server) storeProxySession(session string, exchanges []recorder.Exchange) error {
+	id := proxyContextIDs.ID(contextid.Source{Type: "proxy", Path: session, Name: session})
+
+	// Metadata is stored as decoded JSON, like metadata sent to the API
+	data, err := json.Marshal(map[string]interface{}{
+		"type":       "proxy",
+		"session":    session,
+		"exchanges":  len(exchanges),
+		"collection": recorder.CurlCollection(session, exchanges),
+		"spec":       recorder.OpenAPI(session, exchanges),
+		"timestamp":  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	c, err := s.store.Get(id)
+	if errors.Is(err, ErrContextNotFound) {
+		now := time.Now()
+		return s.createContext(ctx, &Context{ID: id, Metadata: metadata, CreatedAt: now, UpdatedAt: now})
+	}
+	if err != nil {
+		return err
+	}
+	c.Metadata = metadata
+	c.UpdatedAt = time.Now()
+	return s.updateContext(ctx, c)
+}
+
+// StartProxy serves the recording proxy on addr, which may be any address
+// accepted by Listen. It blocks until the proxy fails or Shutdown is called.
+func (s *Server) StartProxy(addr string) error {
+	if s.proxy == nil {
+		return fmt.Errorf("proxy is not enabled")
+	}
+	lis, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.proxyServer = &http.Server{
+		Addr:              lis.Addr().String(),
+		Handler:           s.proxy,
+		ReadHeaderTimeout: s.config.HTTP.ReadHeaderTimeout,
+		IdleTimeout:       s.config.HTTP.IdleTimeout,
+	}
+	srv := s.proxyServer
+	s.mu.Unlock()
+
+	if err := srv.Serve(lis); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func handleListProxySessions(rec *recorder.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, rec.Sessions())
+	}
+}
+
+func handleGetProxySession(rec *recorder.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session := mux.Vars(r)["session"]
+		exchanges, ok := rec.Exchanges(session)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("session %s not found", session))
+			return
+		}
+		writeJSON(w, http.StatusOK, exchanges)
+	}
+}
+
+func handleProxySessionCurl(rec *recorder.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session := mux.Vars(r)["session"]
+		exchanges, ok := rec.Exchanges(session)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("session %s not found", session))
+			return
+		}
+		commands := make([]string, len(exchanges))
+		for i, e := range exchanges {
+			commands[i] = recorder.CurlCommand(e)
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(strings.Join(commands, "\n") + "\n"))
+	}
+}
+
+func handleResetProxySession(rec *recorder.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session := mux.Vars(r)["session"]
+		if !rec.Reset(session) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("session %s not found", session))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"session": session,
+			"status":  "reset",
+		})
+	}
+}
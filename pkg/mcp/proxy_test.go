@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyConfig_Validate(t *testing.T) {
+	assert.NoError(t, ProxyConfig{}.Validate())
+	assert.NoError(t, ProxyConfig{Enabled: true, ListenAddr: ":8081"}.Validate())
+	assert.NoError(t, ProxyConfig{Enabled: true, ListenAddr: ":8081", Target: "https://api.example.com/v2"}.Validate())
+	assert.Error(t, ProxyConfig{Enabled: true}.Validate())
+	assert.Error(t, ProxyConfig{Enabled: true, ListenAddr: ":8081", Target: "api.example.com"}.Validate())
+	assert.Error(t, ProxyConfig{Enabled: true, ListenAddr: ":8081", MaxExchanges: -1}.Validate())
+}
+
+func TestProxy_RecordsSessions(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":12,"done":false}`))
+	}))
+	defer upstream.Close()
+
+	cfg := DefaultConfig()
+	cfg.Proxy = ProxyConfig{Enabled: true, ListenAddr: ":0", Target: upstream.URL}
+	require.NoError(t, cfg.Validate())
+	s := NewServer(nil, WithConfig(cfg))
+	proxy := httptest.NewServer(s.proxy)
+	defer proxy.Close()
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", proxy.URL+"/todos/12", nil)
+		req.Header.Set("X-MCP-Session", "todo-app")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	c, err := s.store.Get("proxy-todo-app")
+	require.NoError(t, err)
+	assert.Equal(t, "proxy", c.Metadata["type"])
+	assert.Equal(t, float64(2), c.Metadata["exchanges"])
+	assert.Contains(t, c.Metadata["spec"].(map[string]interface{})["paths"], "/todos/{id}")
+	assert.Len(t, c.Metadata["collection"].(map[string]interface{})["commands"], 2)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+	rec := do("GET", "/v1/proxy/sessions")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"todo-app","exchanges":2`)
+
+	rec = do("GET", "/v1/proxy/sessions/todo-app/curl")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, strings.Count(rec.Body.String(), "curl '"+upstream.URL+"/todos/12'"))
+
+	assert.Equal(t, http.StatusOK, do("DELETE", "/v1/proxy/sessions/todo-app").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/v1/proxy/sessions/todo-app").Code)
+}
@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bucketIdleTTL is how long an unused per-client bucket is kept around
+const bucketIdleTTL = 10 * time.Minute
+
+// RateLimitConfig configures request rate limiting. Each limit is a token
+// bucket refilled at RequestsPerSecond with room for Burst requests.
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Global limits all traffic to the server
+	Global RateLimit `yaml:"global"`
+	// PerClient limits each API key (or remote address without a key)
+	PerClient RateLimit `yaml:"per_client"`
+	// Groups limits each client per route group, keyed by the first path
+	// segment, e.g. "browser" or "function"
+	Groups map[string]RateLimit `yaml:"groups"`
+}
+
+// RateLimit describes a single token bucket; a zero rate means unlimited
+type RateLimit struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+}
+
+func (l RateLimit) unlimited() bool {
+	return l.RequestsPerSecond <= 0
+}
+
+// tokenBucket is a classic token bucket limiter
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		limit:  limit,
+		tokens: float64(limit.capacity()),
+		last:   now,
+	}
+}
+
+func (l RateLimit) capacity() int {
+	if l.Burst < 1 {
+		return 1
+	}
+	return l.Burst
+}
+
+// refill adds the tokens accumulated since the last call and reports how
+// long to wait for the next token if the bucket is empty
+func (b *tokenBucket) refill(now time.Time) time.Duration {
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+	b.tokens = math.Min(float64(b.limit.capacity()), b.tokens+elapsed*b.limit.RequestsPerSecond)
+
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.limit.RequestsPerSecond * float64(time.Second))
+}
+
+// RateLimiter enforces the configured limits across requests
+type RateLimiter struct {
+	config  RateLimitConfig
+	global  *tokenBucket
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+	sweep   time.Time
+	mu      sync.Mutex
+}
+
+// NewRateLimiter creates a rate limiter from the configuration
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	rl := &RateLimiter{
+		config:  config,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+	if !config.Global.unlimited() {
+		rl.global = newTokenBucket(config.Global, rl.now())
+	}
+	return rl
+}
+
+// Allow reports whether a request from client to the route group may
+// proceed, and if not, how long the client should wait before retrying.
+// Tokens are only taken when every applicable bucket has one to spare.
+func (rl *RateLimiter) Allow(client, group string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	rl.sweepIdle(now)
+
+	var buckets []*tokenBucket
+	if rl.global != nil {
+		buckets = append(buckets, rl.global)
+	}
+	if !rl.config.PerClient.unlimited() {
+		buckets = append(buckets, rl.bucket("client|"+client, rl.config.PerClient, now))
+	}
+	if limit, ok := rl.config.Groups[group]; ok && !limit.unlimited() {
+		buckets = append(buckets, rl.bucket("group|"+group+"|"+client, limit, now))
+	}
+
+	var wait time.Duration
+	for _, b := range buckets {
+		if d := b.refill(now); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return true, 0
+}
+
+func (rl *RateLimiter) bucket(key string, limit RateLimit, now time.Time) *tokenBucket {
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = newTokenBucket(limit, now)
+		rl.buckets[key] = b
+	}
+	return b
+}
+
+// sweepIdle drops per-client buckets that have not been used for a while so
+// the map does not grow without bound
+func (rl *RateLimiter) sweepIdle(now time.Time) {
+	if now.Sub(rl.sweep) < bucketIdleTTL {
+		return
+	}
+	rl.sweep = now
+	for key, b := range rl.buckets {
+		if now.Sub(b.last) > bucketIdleTTL {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// Middleware rejects requests over the limit with 429 Too Many Requests
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := rl.Allow(clientID(r), routeGroup(r.URL.Path))
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			err := fmt.Errorf("rate limit exceeded, retry in %s", wait.Round(time.Millisecond))
+			writeError(w, http.StatusTooManyRequests, newAPIError(CodeRateLimited, err, map[string]interface{}{
+				"retry_after_ms": wait.Milliseconds(),
+			}))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiKey extracts the API key from the X-API-Key header or a bearer token
+func apiKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// clientID identifies the caller by API key, falling back to the remote address
+func clientID(r *http.Request) string {
+	if key := apiKey(r); key != "" {
+		return "key:" + key
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// routeGroup returns the first path segment, e.g. "ssh" for /ssh/{id}/exec
+func routeGroup(path string) string {
+	path = strings.TrimPrefix(path, "/")
+	if i := strings.Index(path, "/"); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
@@ -0,0 +1,185 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRateLimiter returns a limiter on a clock advanced by the test
+func newTestRateLimiter(config RateLimitConfig) (*RateLimiter, *time.Time) {
+	rl := NewRateLimiter(config)
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+	return rl, &now
+}
+
+func TestTokenBucket_Refill(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newTokenBucket(RateLimit{RequestsPerSecond: 2, Burst: 3}, start)
+	assert.Equal(t, 3.0, b.tokens)
+
+	b.tokens = 0
+	assert.Equal(t, 500*time.Millisecond, b.refill(start))
+	assert.Equal(t, 250*time.Millisecond, b.refill(start.Add(250*time.Millisecond)))
+	assert.Zero(t, b.refill(start.Add(500*time.Millisecond)))
+	assert.Equal(t, 1.0, b.tokens)
+
+	// Refills stop at the burst
+	assert.Zero(t, b.refill(start.Add(time.Minute)))
+	assert.Equal(t, 3.0, b.tokens)
+
+	// Without a burst the bucket holds one token
+	assert.Equal(t, 1.0, newTokenBucket(RateLimit{RequestsPerSecond: 1}, start).tokens)
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	tests := []struct {
+		name   string
+		config RateLimitConfig
+		calls  []struct{ client, group string }
+		want   []bool
+	}{
+		{
+			name:   "global shared by clients",
+			config: RateLimitConfig{Global: RateLimit{RequestsPerSecond: 1, Burst: 2}},
+			calls:  []struct{ client, group string }{{"a", "ssh"}, {"b", "ssh"}, {"c", "function"}},
+			want:   []bool{true, true, false},
+		},
+		{
+			name:   "per client",
+			config: RateLimitConfig{PerClient: RateLimit{RequestsPerSecond: 1, Burst: 1}},
+			calls:  []struct{ client, group string }{{"a", "ssh"}, {"a", "function"}, {"b", "ssh"}},
+			want:   []bool{true, false, true},
+		},
+		{
+			name:   "group per client",
+			config: RateLimitConfig{Groups: map[string]RateLimit{"browser": {RequestsPerSecond: 1, Burst: 1}}},
+			calls:  []struct{ client, group string }{{"a", "browser"}, {"a", "browser"}, {"b", "browser"}, {"a", "ssh"}, {"a", "ssh"}},
+			want:   []bool{true, false, true, true, true},
+		},
+		{
+			// A request rejected by one bucket takes no token from the others
+			name: "rejections take no tokens",
+			config: RateLimitConfig{
+				PerClient: RateLimit{RequestsPerSecond: 1, Burst: 2},
+				Groups:    map[string]RateLimit{"browser": {RequestsPerSecond: 1, Burst: 1}},
+			},
+			calls: []struct{ client, group string }{{"a", "browser"}, {"a", "browser"}, {"a", "ssh"}, {"a", "ssh"}},
+			want:  []bool{true, false, true, false},
+		},
+		{
+			name:   "unlimited",
+			config: RateLimitConfig{Groups: map[string]RateLimit{"browser": {}}},
+			calls:  []struct{ client, group string }{{"a", "browser"}, {"a", "browser"}, {"a", "browser"}},
+			want:   []bool{true, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := newTestRateLimiter(tt.config)
+			var got []bool
+			for _, call := range tt.calls {
+				ok, _ := rl.Allow(call.client, call.group)
+				got = append(got, ok)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	rl, now := newTestRateLimiter(RateLimitConfig{
+		Global:    RateLimit{RequestsPerSecond: 10, Burst: 1},
+		PerClient: RateLimit{RequestsPerSecond: 0.5, Burst: 1},
+	})
+	ok, _ := rl.Allow("a", "ssh")
+	require.True(t, ok)
+
+	// The wait is that of the slowest bucket
+	ok, wait := rl.Allow("a", "ssh")
+	assert.False(t, ok)
+	assert.Equal(t, 2*time.Second, wait)
+
+	*now = now.Add(2 * time.Second)
+	ok, _ = rl.Allow("a", "ssh")
+	assert.True(t, ok)
+}
+
+func TestRateLimiter_SweepIdle(t *testing.T) {
+	rl, now := newTestRateLimiter(RateLimitConfig{PerClient: RateLimit{RequestsPerSecond: 1, Burst: 1}})
+	rl.Allow("a", "ssh")
+	*now = now.Add(bucketIdleTTL / 2)
+	rl.Allow("b", "ssh")
+	assert.Len(t, rl.buckets, 2)
+
+	// Buckets idle for longer than the TTL are dropped; a came back full
+	*now = now.Add(bucketIdleTTL/2 + time.Second)
+	ok, _ := rl.Allow("c", "ssh")
+	assert.True(t, ok)
+	assert.Contains(t, rl.buckets, "client|b")
+	assert.Contains(t, rl.buckets, "client|c")
+	assert.NotContains(t, rl.buckets, "client|a")
+
+	// Sweeps run at most once per TTL
+	*now = now.Add(bucketIdleTTL / 2)
+	rl.buckets["client|stale"] = newTokenBucket(rl.config.PerClient, now.Add(-time.Hour))
+	rl.Allow("c", "ssh")
+	assert.Contains(t, rl.buckets, "client|stale")
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.RateLimit = RateLimitConfig{
+		Enabled: true,
+		Groups:  map[string]RateLimit{"context": {RequestsPerSecond: 0.25, Burst: 1}},
+	}
+	s := NewServer(nil, WithConfig(cfg))
+
+	do := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, do("/v1/context/list", "a").Code)
+
+	// Legacy paths count against the same group as versioned ones
+	rec := do("/context/list", "a")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "4", rec.Header().Get("Retry-After"))
+	var body ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, CodeRateLimited, body.Error.Code)
+	assert.True(t, body.Error.Retryable)
+	assert.InDelta(t, 4000, body.Error.Details["retry_after_ms"], 100)
+
+	// Other clients and groups are not limited
+	assert.Equal(t, http.StatusOK, do("/v1/context/list", "b").Code)
+	assert.NotEqual(t, http.StatusTooManyRequests, do("/v1/features", "a").Code)
+}
+
+func TestRouteGroup(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/ssh/web/exec", "ssh"},
+		{"/function/call", "function"},
+		{"/features", "features"},
+		{"/", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, routeGroup(tt.path))
+		})
+	}
+}
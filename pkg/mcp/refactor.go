@@ -0,0 +1,178 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"go/types"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// RefactorRenameRequest asks for a declaration to be renamed across the
+// workspace. The declaration is named like for gorename -from, as
+// "example.com/m/pkg".Name or "example.com/m/pkg".Type.Member, or is the
+// identifier at a position of a file.
+type RefactorRenameRequest struct {
+	From     string    `json:"from,omitempty"`
+	URI      string    `json:"uri,omitempty"`
+	Position *Position `json:"position,omitempty"` // With uri
+	To       string    `json:"to"`
+	Apply    bool      `json:"apply,omitempty"` // Write the edits to the workspace
+}
+
+// RefactorRenameResult contains the edits renaming a declaration and the
+// files they change
+type RefactorRenameResult struct {
+	Edit    WorkspaceEdit `json:"edit"`
+	Files   []string      `json:"files"` // Relative to the workspace root
+	Renamed int           `json:"renamed"`
+	Applied bool          `json:"applied"`
+}
+
+// RenameDeclaration computes the edits renaming the declaration named from,
+// like "example.com/m/pkg".Type.Member, and every reference to it in the
+// packages under root and their tests, to newName, with the checks of
+// RenameSymbol
+func RenameDeclaration(root, from string, overlay map[string][]byte, newName string) (map[string][]TextEdit, error) {
+	if !token.IsIdentifier(newName) || newName == "_" {
+		return nil, fmt.Errorf("%q is not a valid identifier", newName)
+	}
+	pkgPath, name, member, err := parseDeclarationName(from)
+	if err != nil {
+		return nil, err
+	}
+
+	fset, pkgs, err := loadWorkspacePackages(root, overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	var target types.Object
+	found := false
+	for _, pkg := range pkgs {
+		if pkg.PkgPath != pkgPath || pkg.Types == nil {
+			continue
+		}
+		found = true
+		if target = lookupDeclaration(pkg, name, member); target != nil {
+			break
+		}
+	}
+	switch {
+	case !found:
+		return nil, fmt.Errorf("package %s is not part of the workspace", pkgPath)
+	case target == nil && member != "":
+		return nil, fmt.Errorf("%s.%s has no field or method %s", pkgPath, name, member)
+	case target == nil:
+		return nil, fmt.Errorf("package %s does not declare %s", pkgPath, name)
+	}
+	return renameObject(root, fset, pkgs, target, newName)
+}
+
+// parseDeclarationName splits a declaration named like
+// "example.com/m/pkg".Type.Member into its package, name and member
+func parseDeclarationName(from string) (pkgPath, name, member string, err error) {
+	invalid := fmt.Errorf(`invalid declaration %s, expected "package/path".Name or "package/path".Type.Member`, from)
+	quoted, err := strconv.QuotedPrefix(from)
+	if err != nil {
+		return "", "", "", invalid
+	}
+	pkgPath, _ = strconv.Unquote(quoted)
+	rest, ok := strings.CutPrefix(from[len(quoted):], ".")
+	if pkgPath == "" || !ok {
+		return "", "", "", invalid
+	}
+	name, member, _ = strings.Cut(rest, ".")
+	if !token.IsIdentifier(name) || (member != "" && !token.IsIdentifier(member)) {
+		return "", "", "", invalid
+	}
+	return pkgPath, name, member, nil
+}
+
+// lookupDeclaration returns the package level declaration name of pkg, or
+// the field or method member of its type name
+func lookupDeclaration(pkg *packages.Package, name, member string) types.Object {
+	obj := pkg.Types.Scope().Lookup(name)
+	if obj == nil || member == "" {
+		return obj
+	}
+	if _, ok := obj.(*types.TypeName); !ok {
+		return nil
+	}
+	obj, _, _ = types.LookupFieldOrMethod(obj.Type(), true, pkg.Types, member)
+	return obj
+}
+
+// refactorRename renames the declaration of a request, applying the edits
+// through the file manager when asked to
+func (ls *LanguageServer) refactorRename(req RefactorRenameRequest) (*RefactorRenameResult, error) {
+	var edit WorkspaceEdit
+	if req.From != "" {
+		overlay, uris, _ := ls.overlay("")
+		edits, err := RenameDeclaration(ls.workspaceRoot, req.From, overlay, req.To)
+		if err != nil {
+			return nil, err
+		}
+		edit = workspaceEdit(edits, uris)
+	} else {
+		var err error
+		if edit, err = ls.rename(req.URI, *req.Position, req.To); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &RefactorRenameResult{Edit: edit, Files: make([]string, 0, len(edit.Changes))}
+	root, err := filepath.Abs(ls.workspaceRoot)
+	if err != nil {
+		return nil, err
+	}
+	for uri, fileEdits := range edit.Changes {
+		rel, err := filepath.Rel(root, ls.documentPath(uri))
+		if err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, filepath.ToSlash(rel))
+		result.Renamed += len(fileEdits)
+	}
+	sort.Strings(result.Files)
+	return result, nil
+}
+
+func handleRefactorRename(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req RefactorRenameRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if (req.From == "") == (req.URI == "") {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("either from or uri is required"))
+			return
+		}
+		if req.URI != "" && req.Position == nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("position is required with uri"))
+			return
+		}
+
+		result, err := ls.refactorRename(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if req.Apply {
+			if _, err := ls.applyEdit(result.Edit); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			result.Applied = true
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
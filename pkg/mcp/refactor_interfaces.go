@@ -0,0 +1,430 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ExtractInterfaceRequest asks for an interface of the methods of a type,
+// named like "example.com/m/pkg".Type, to be declared after it
+type ExtractInterfaceRequest struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`              // Of the interface
+	Methods []string `json:"methods,omitempty"` // Defaults to the exported methods of *Type
+	Apply   bool     `json:"apply,omitempty"`   // Write the edits to the workspace
+}
+
+// ImplementInterfaceRequest asks for stubs of the methods a type, named like
+// "example.com/m/pkg".Type, lacks to implement an interface named like
+// "io".Reader
+type ImplementInterfaceRequest struct {
+	Type      string `json:"type"`
+	Interface string `json:"interface"`
+	Apply     bool   `json:"apply,omitempty"` // Write the edits to the workspace
+}
+
+// RefactorEditResult contains the edits adding declarations to the file of
+// a type
+type RefactorEditResult struct {
+	Edit    WorkspaceEdit `json:"edit"`
+	Code    string        `json:"code"` // The declarations added, empty when there are none
+	Applied bool          `json:"applied"`
+}
+
+// typeDeclaration is a named type of the workspace and the file declaring
+// it
+type typeDeclaration struct {
+	pkg     *packages.Package
+	named   *types.Named
+	file    *ast.File
+	path    string
+	content string
+}
+
+// ExtractInterface computes the edits declaring the interface name of the
+// methods of the type named from, after the type and its methods. Methods lists the methods
+// of the method set of the pointer to the type to declare, all exported
+// methods when empty.
+func ExtractInterface(root, from string, overlay map[string][]byte, name string, methods []string) (map[string][]TextEdit, string, error) {
+	if !token.IsIdentifier(name) || name == "_" {
+		return nil, "", fmt.Errorf("%q is not a valid identifier", name)
+	}
+	fset, pkgs, err := loadWorkspacePackages(root, overlay)
+	if err != nil {
+		return nil, "", err
+	}
+	decl, err := findTypeDeclaration(root, fset, pkgs, overlay, from)
+	if err != nil {
+		return nil, "", err
+	}
+	if other := decl.pkg.Types.Scope().Lookup(name); other != nil {
+		return nil, "", fmt.Errorf("%s is already declared at %s", name, fset.Position(other.Pos()))
+	}
+
+	wanted := make(map[string]bool)
+	for _, method := range methods {
+		wanted[method] = true
+	}
+	q := &fileQualifier{file: decl.file, self: decl.pkg.Types, missing: make(map[string]string)}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// %s is implemented by *%s\ntype %s interface {\n", name, decl.named.Obj().Name(), name)
+	set := types.NewMethodSet(types.NewPointer(decl.named))
+	for i := 0; i < set.Len(); i++ {
+		fn := set.At(i).Obj().(*types.Func)
+		if (len(wanted) > 0 && !wanted[fn.Name()]) || (len(wanted) == 0 && !fn.Exported()) {
+			continue
+		}
+		delete(wanted, fn.Name())
+		fmt.Fprintf(&b, "\t%s", fn.Name())
+		types.WriteSignature(&b, fn.Type().(*types.Signature), q.qualifier)
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+	for method := range wanted {
+		return nil, "", fmt.Errorf("%s has no method %s", decl.named.Obj().Name(), method)
+	}
+
+	code := b.String()
+	return decl.insert(fset, code, q.missing), code, nil
+}
+
+// ImplementInterface computes the edits declaring stubs of the methods of
+// the interface named iface that the type named from lacks, after the
+// methods of the type. Stubs have the receiver of the other methods of the
+// type, a pointer by default, and panic.
+func ImplementInterface(root, from string, overlay map[string][]byte, iface string) (map[string][]TextEdit, string, error) {
+	ifacePath, ifaceName, member, err := parseDeclarationName(iface)
+	if err != nil {
+		return nil, "", err
+	}
+	if member != "" {
+		return nil, "", fmt.Errorf("%s is not an interface", iface)
+	}
+	fset, pkgs, err := loadWorkspacePackages(root, overlay, ifacePath)
+	if err != nil {
+		return nil, "", err
+	}
+	decl, err := findTypeDeclaration(root, fset, pkgs, overlay, from)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var ifacePkg *packages.Package
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.PkgPath == ifacePath && pkg.ID == pkg.PkgPath && pkg.Types != nil {
+			ifacePkg = pkg
+		}
+	})
+	if ifacePkg == nil {
+		return nil, "", fmt.Errorf("failed to load package %s", ifacePath)
+	}
+	obj, ok := ifacePkg.Types.Scope().Lookup(ifaceName).(*types.TypeName)
+	if !ok {
+		return nil, "", fmt.Errorf("package %s does not declare the type %s", ifacePath, ifaceName)
+	}
+	interfaceType, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, "", fmt.Errorf("%s is not an interface", iface)
+	}
+	if named, ok := obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+		return nil, "", fmt.Errorf("generic interfaces are not supported")
+	}
+
+	typeName := decl.named.Obj().Name()
+	ifaceRef := ifaceName
+	if ifacePkg.Types != decl.pkg.Types {
+		ifaceRef = ifacePkg.Types.Name() + "." + ifaceName
+	}
+	var missing []*types.Func
+	for i := 0; i < interfaceType.NumMethods(); i++ {
+		method := interfaceType.Method(i)
+		if !method.Exported() && method.Pkg() != decl.pkg.Types {
+			return nil, "", fmt.Errorf("cannot implement the unexported method %s of %s", method.Name(), ifaceRef)
+		}
+		existing, _, _ := types.LookupFieldOrMethod(types.NewPointer(decl.named), false, method.Pkg(), method.Name())
+		switch existing := existing.(type) {
+		case nil:
+			missing = append(missing, method)
+		case *types.Func:
+			if !types.Identical(existing.Type(), method.Type()) {
+				return nil, "", fmt.Errorf("%s.%s is %s, %s wants %s", typeName, method.Name(),
+					types.TypeString(existing.Type(), nil), ifaceRef, types.TypeString(method.Type(), nil))
+			}
+		default:
+			return nil, "", fmt.Errorf("%s has a field %s, %s wants a method", typeName, method.Name(), ifaceRef)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Name() < missing[j].Name() })
+	if len(missing) == 0 {
+		return map[string][]TextEdit{}, "", nil
+	}
+
+	// The receiver is named like that of the methods of the type
+	recvName, recvType := strings.ToLower(typeName[:1]), "*"+typeName
+	if decl.named.NumMethods() > 0 {
+		recv := decl.named.Method(0).Type().(*types.Signature).Recv()
+		recvName = recv.Name()
+		if _, ok := recv.Type().(*types.Pointer); !ok {
+			recvType = typeName
+		}
+	}
+
+	q := &fileQualifier{file: decl.file, self: decl.pkg.Types, missing: make(map[string]string)}
+	var stubs []string
+	for _, method := range missing {
+		m := describeMethod(method, q.qualifier)
+		receiver := recvName + " " + recvType
+		for _, p := range m.Params {
+			if p.Name == recvName {
+				receiver = recvType
+			}
+		}
+		if recvName == "" || recvName == "_" {
+			receiver = recvType
+		}
+		stubs = append(stubs, fmt.Sprintf("// %s implements %s\nfunc (%s) %s(%s)%s {\n\tpanic(\"not implemented\")\n}\n",
+			m.Name, ifaceRef, receiver, m.Name, m.paramList(), m.resultList()))
+	}
+
+	code := strings.Join(stubs, "\n")
+	return decl.insert(fset, code, q.missing), code, nil
+}
+
+// findTypeDeclaration finds the named type from, like
+// "example.com/m/pkg".Type, among the packages of the workspace
+func findTypeDeclaration(root string, fset *token.FileSet, pkgs []*packages.Package, overlay map[string][]byte, from string) (*typeDeclaration, error) {
+	pkgPath, name, member, err := parseDeclarationName(from)
+	if err != nil {
+		return nil, err
+	}
+	if member != "" {
+		return nil, fmt.Errorf("%s is not a type", from)
+	}
+
+	decl := &typeDeclaration{}
+	for _, pkg := range pkgs {
+		if pkg.PkgPath != pkgPath || pkg.ID != pkg.PkgPath || pkg.Types == nil {
+			continue
+		}
+		obj, ok := pkg.Types.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("package %s does not declare the type %s", pkgPath, name)
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok || obj.IsAlias() {
+			return nil, fmt.Errorf("%s is not a named type", from)
+		}
+		if _, ok := named.Underlying().(*types.Interface); ok {
+			return nil, fmt.Errorf("%s is an interface", from)
+		}
+		if named.TypeParams().Len() > 0 {
+			return nil, fmt.Errorf("generic types are not supported")
+		}
+		decl.pkg, decl.named = pkg, named
+		decl.path = fset.Position(obj.Pos()).Filename
+		for _, file := range pkg.Syntax {
+			if fset.File(file.Pos()).Name() == decl.path {
+				decl.file = file
+			}
+		}
+	}
+	if decl.pkg == nil {
+		return nil, fmt.Errorf("package %s is not part of the workspace", pkgPath)
+	}
+	if decl.file == nil || !inWorkspace(root, decl.path) {
+		return nil, fmt.Errorf("%s is declared outside the workspace", from)
+	}
+
+	content, ok := overlay[decl.path]
+	if !ok {
+		if content, err = os.ReadFile(decl.path); err != nil {
+			return nil, err
+		}
+	}
+	decl.content = string(content)
+	return decl, nil
+}
+
+// insert returns the edits adding code after the declaration of the type
+// and its methods in its file, and the imports code needs
+func (decl *typeDeclaration) insert(fset *token.FileSet, code string, imports map[string]string) map[string][]TextEdit {
+	name := decl.named.Obj()
+	end := token.NoPos
+	for _, d := range decl.file.Decls {
+		switch d := d.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				if spec, ok := spec.(*ast.TypeSpec); ok && spec.Name.Pos() == name.Pos() {
+					end = max(end, d.End())
+				}
+			}
+		case *ast.FuncDecl:
+			if d.Recv != nil && len(d.Recv.List) > 0 && receiverName(d.Recv.List[0].Type) == name.Name() {
+				end = max(end, d.End())
+			}
+		}
+	}
+	at := positionAt(decl.content, fset.Position(end).Offset)
+
+	var edits []TextEdit
+	if len(imports) > 0 {
+		edits = append(edits, addImportsEdit(fset, decl.content, decl.file, imports))
+	}
+	edits = append(edits, TextEdit{Range: Range{Start: at, End: at}, NewText: "\n\n" + strings.TrimSuffix(code, "\n")})
+	return map[string][]TextEdit{decl.path: edits}
+}
+
+// fileQualifier qualifies packages by the names a file imports them with,
+// collecting those the file does not import
+type fileQualifier struct {
+	file    *ast.File
+	self    *types.Package
+	missing map[string]string // Names of the packages to import by path
+}
+
+func (q *fileQualifier) qualifier(pkg *types.Package) string {
+	if pkg == nil || pkg.Path() == q.self.Path() {
+		return ""
+	}
+	for _, imp := range q.file.Imports {
+		if importPath(imp) == pkg.Path() {
+			if imp.Name != nil {
+				return imp.Name.Name
+			}
+			return pkg.Name()
+		}
+	}
+	q.missing[pkg.Path()] = pkg.Name()
+	return pkg.Name()
+}
+
+// addImportsEdit adds imports, by name and path, to the first import
+// declaration of a file, which is parenthesized if need be, or to a new one
+// after the package clause
+func addImportsEdit(fset *token.FileSet, content string, file *ast.File, imports map[string]string) TextEdit {
+	specs := make(map[string]string)
+	for importPath, name := range imports {
+		specs[importPath] = strconv.Quote(importPath)
+		if name != path.Base(importPath) {
+			specs[importPath] = name + " " + specs[importPath]
+		}
+	}
+	lines := func() string {
+		paths := make([]string, 0, len(specs))
+		for importPath := range specs {
+			paths = append(paths, importPath)
+		}
+		sort.Strings(paths)
+		var b strings.Builder
+		for _, importPath := range paths {
+			b.WriteString("\t" + specs[importPath] + "\n")
+		}
+		return b.String()
+	}
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset
+	}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		if gen.Lparen.IsValid() {
+			at := positionAt(content, offset(gen.Rparen))
+			return TextEdit{Range: Range{Start: at, End: at}, NewText: lines()}
+		}
+		spec := gen.Specs[0].(*ast.ImportSpec)
+		specs[importPath(spec)] = content[offset(spec.Pos()):offset(spec.End())]
+		return TextEdit{
+			Range:   Range{Start: positionAt(content, offset(gen.Pos())), End: positionAt(content, offset(gen.End()))},
+			NewText: "import (\n" + lines() + ")",
+		}
+	}
+	at := positionAt(content, offset(file.Name.End()))
+	return TextEdit{Range: Range{Start: at, End: at}, NewText: "\n\nimport (\n" + lines() + ")"}
+}
+
+// extractInterface extracts an interface in the workspace with its open
+// documents
+func (ls *LanguageServer) extractInterface(req ExtractInterfaceRequest) (*RefactorEditResult, error) {
+	overlay, uris, _ := ls.overlay("")
+	edits, code, err := ExtractInterface(ls.workspaceRoot, req.Type, overlay, req.Name, req.Methods)
+	if err != nil {
+		return nil, err
+	}
+	return &RefactorEditResult{Edit: workspaceEdit(edits, uris), Code: code}, nil
+}
+
+// implementInterface generates stubs in the workspace with its open
+// documents
+func (ls *LanguageServer) implementInterface(req ImplementInterfaceRequest) (*RefactorEditResult, error) {
+	overlay, uris, _ := ls.overlay("")
+	edits, code, err := ImplementInterface(ls.workspaceRoot, req.Type, overlay, req.Interface)
+	if err != nil {
+		return nil, err
+	}
+	return &RefactorEditResult{Edit: workspaceEdit(edits, uris), Code: code}, nil
+}
+
+func handleExtractInterface(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ExtractInterfaceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		result, err := ls.extractInterface(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if req.Apply {
+			if _, err := ls.applyEdit(result.Edit); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			result.Applied = true
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func handleImplementInterface(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ImplementInterfaceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		result, err := ls.implementInterface(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if req.Apply && len(result.Edit.Changes) > 0 {
+			if _, err := ls.applyEdit(result.Edit); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			result.Applied = true
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
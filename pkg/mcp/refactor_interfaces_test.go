@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestRefactorInterfaces(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.22\n",
+		"store/store.go": `package store
+
+import "context"
+
+// Store holds values
+type Store struct {
+	values map[string]string
+}
+
+// Get returns a value
+func (s *Store) Get(ctx context.Context, key string) (string, error) { return s.values[key], nil }
+
+// Len counts the values
+func (s *Store) Len() int { return len(s.values) }
+
+func (s *Store) reset() {}
+`,
+		"api/api.go": `package api
+
+import (
+	"context"
+	"net/http"
+)
+
+type Handler interface {
+	Get(ctx context.Context, key string) (string, error)
+	Serve(w http.ResponseWriter, s *http.Request)
+}
+
+type Counter interface {
+	Len() string
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+
+	refactor := func(path string, req interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/refactor/"+path, strings.NewReader(string(body))))
+		return w
+	}
+	storeURI := "file://" + filepath.ToSlash(filepath.Join(dir, "store", "store.go"))
+
+	w := refactor("extractInterface", ExtractInterfaceRequest{Type: `"example.com/m/store".Store`, Name: "Getter"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result RefactorEditResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, "// Getter is implemented by *Store\ntype Getter interface {\n\tGet(ctx context.Context, key string) (string, error)\n\tLen() int\n}\n", result.Code)
+	assert.Equal(t, []TextEdit{{
+		Range:   Range{Start: Position{Line: 15, Character: 26}, End: Position{Line: 15, Character: 26}},
+		NewText: "\n\n" + strings.TrimSuffix(result.Code, "\n"),
+	}}, result.Edit.Changes[storeURI])
+	assert.False(t, result.Applied)
+
+	w = refactor("extractInterface", ExtractInterfaceRequest{Type: `"example.com/m/store".Store`, Name: "resetter", Methods: []string{"reset"}, Apply: true})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.True(t, result.Applied)
+
+	// Stubs need the imports of their signatures, and their parameters may
+	// hide the receiver
+	w = refactor("implementInterface", ImplementInterfaceRequest{Type: `"example.com/m/store".Store`, Interface: `"example.com/m/api".Handler`, Apply: true})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, "// Serve implements api.Handler\nfunc (*Store) Serve(w http.ResponseWriter, s *http.Request) {\n\tpanic(\"not implemented\")\n}\n", result.Code)
+	w = refactor("implementInterface", ImplementInterfaceRequest{Type: `"example.com/m/store".Store`, Interface: `"io".ReadWriter`, Apply: true})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Contains(t, result.Code, "func (s *Store) Read(p []byte) (int, error) {")
+
+	content, err := os.ReadFile(filepath.Join(dir, "store", "store.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "package store\n\nimport (\n\t\"context\"\n\t\"net/http\"\n)\n")
+	assert.Contains(t, string(content), "func (s *Store) reset() {}\n\n// Serve implements api.Handler\n")
+	_, pkgs, err := loadWorkspacePackages(dir, nil)
+	require.NoError(t, err)
+	assert.Zero(t, packages.PrintErrors(pkgs))
+
+	// Nothing is left to implement
+	w = refactor("implementInterface", ImplementInterfaceRequest{Type: `"example.com/m/store".Store`, Interface: `"example.com/m/api".Handler`})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var done RefactorEditResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&done))
+	assert.Empty(t, done.Code)
+	assert.Empty(t, done.Edit.Changes)
+
+	tests := []struct {
+		name string
+		path string
+		req  interface{}
+	}{
+		{"declared interface", "extractInterface", ExtractInterfaceRequest{Type: `"example.com/m/store".Store`, Name: "resetter"}},
+		{"unknown method", "extractInterface", ExtractInterfaceRequest{Type: `"example.com/m/store".Store`, Name: "Putter", Methods: []string{"Put"}}},
+		{"not a type", "extractInterface", ExtractInterfaceRequest{Type: `"example.com/m/store".Store.Get`, Name: "Getter2"}},
+		{"interface", "extractInterface", ExtractInterfaceRequest{Type: `"example.com/m/api".Handler`, Name: "Handler2"}},
+		{"other signature", "implementInterface", ImplementInterfaceRequest{Type: `"example.com/m/store".Store`, Interface: `"example.com/m/api".Counter`}},
+		{"not an interface", "implementInterface", ImplementInterfaceRequest{Type: `"example.com/m/store".Store`, Interface: `"example.com/m/store".Store`}},
+		{"unknown interface", "implementInterface", ImplementInterfaceRequest{Type: `"example.com/m/store".Store`, Interface: `"io".Missing`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := refactor(tt.path, tt.req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
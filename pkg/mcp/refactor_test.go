@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRefactorRename(t *testing.T) {
+	dir := renameWorkspace(t)
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+
+	rename := func(req RefactorRenameRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/refactor/rename", strings.NewReader(string(body))))
+		return w
+	}
+
+	// Fields are renamed in every package, tests included, without writing
+	w := rename(RefactorRenameRequest{From: `"example.com/m/store".Store.Size`, To: "Len"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result RefactorRenameResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.False(t, result.Applied)
+	assert.Equal(t, []string{"main.go", "store/store.go", "store/store_test.go"}, result.Files)
+	assert.Equal(t, 5, result.Renamed)
+	content, err := os.ReadFile(filepath.Join(dir, "store", "store.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "Size int")
+
+	w = rename(RefactorRenameRequest{From: `"example.com/m/store".New`, To: "Open", Apply: true})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.True(t, result.Applied)
+	content, err = os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "s := store.Open(3)")
+
+	w = rename(RefactorRenameRequest{URI: "store/store.go", Position: &Position{Line: 13, Character: 17}, To: "IsFull"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, []string{"main.go", "store/store.go"}, result.Files)
+
+	tests := []struct {
+		name string
+		req  RefactorRenameRequest
+	}{
+		{"no declaration", RefactorRenameRequest{To: "X"}},
+		{"both declarations", RefactorRenameRequest{From: `"example.com/m/store".Open`, URI: "main.go", To: "X"}},
+		{"no position", RefactorRenameRequest{URI: "main.go", To: "X"}},
+		{"unquoted package", RefactorRenameRequest{From: "store.Open", To: "X"}},
+		{"unknown package", RefactorRenameRequest{From: `"example.com/other".Open`, To: "X"}},
+		{"unknown declaration", RefactorRenameRequest{From: `"example.com/m/store".Missing`, To: "X"}},
+		{"unknown member", RefactorRenameRequest{From: `"example.com/m/store".Store.Missing`, To: "X"}},
+		{"conflict", RefactorRenameRequest{From: `"example.com/m/store".Store.Size`, To: "max"}},
+		{"invalid name", RefactorRenameRequest{From: `"example.com/m/store".Open`, To: "1x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := rename(tt.req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestParseDeclarationName(t *testing.T) {
+	tests := []struct {
+		from                  string
+		pkgPath, name, member string
+		valid                 bool
+	}{
+		{`"example.com/m".Name`, "example.com/m", "Name", "", true},
+		{`"example.com/m/pkg".Type.Method`, "example.com/m/pkg", "Type", "Method", true},
+		{`"example.com/m"`, "", "", "", false},
+		{`"example.com/m".`, "", "", "", false},
+		{`"example.com/m".A.B.C`, "", "", "", false},
+		{`"".Name`, "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			pkgPath, name, member, err := parseDeclarationName(tt.from)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{tt.pkgPath, tt.name, tt.member}, []string{pkgPath, name, member})
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package mcp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"net/http"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// AnalyzeReferences returns the package level objects, fields and methods
+// declared in the packages matching pattern in root, and their tests, with
+// their uses in all of those packages. Objects are matched by declaration,
+// since every package and test variant has objects of its own. When name is
+// set, only the objects of that name are returned.
+func AnalyzeReferences(root, pattern, name string) ([]ReferenceInfo, error) {
+	fset := token.NewFileSet()
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:   root,
+		Fset:  fset,
+		Tests: true,
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %v", err)
+	}
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			if e.Kind == packages.ListError {
+				return nil, fmt.Errorf("failed to load package %s: %v", pkg.PkgPath, e)
+			}
+		}
+	}
+
+	location := func(ident *ast.Ident) Location {
+		pos := fset.Position(ident.Pos())
+		return Location{
+			URI: "file://" + filepath.ToSlash(pos.Filename),
+			Range: Range{
+				Start: Position{Line: pos.Line - 1, Character: pos.Column - 1},
+				End:   Position{Line: pos.Line - 1, Character: pos.Column - 1 + len(ident.Name)},
+			},
+		}
+	}
+	declaredAt := func(obj types.Object) string {
+		return fset.Position(obj.Pos()).String()
+	}
+
+	refs := make(map[string]*ReferenceInfo)
+	analyzer := NewASTAnalyzer(fset)
+	for _, pkg := range pkgs {
+		for ident, obj := range pkg.TypesInfo.Defs {
+			if obj == nil || ident.Name == "_" || (name != "" && ident.Name != name) {
+				continue
+			}
+			scope := objectScope(obj)
+			if scope == "local" || refs[declaredAt(obj)] != nil {
+				continue
+			}
+			refs[declaredAt(obj)] = &ReferenceInfo{
+				Name:     ident.Name,
+				Kind:     analyzer.getIdentKind(obj),
+				Location: location(ident),
+				UsedAt:   make([]Location, 0),
+				Scope:    scope,
+			}
+		}
+	}
+
+	// Files shared by test variants are parsed by each, so uses are
+	// matched by location
+	seen := make(map[Location]bool)
+	for _, pkg := range pkgs {
+		for ident, obj := range pkg.TypesInfo.Uses {
+			ref, ok := refs[declaredAt(originObject(obj))]
+			if !ok || seen[location(ident)] {
+				continue
+			}
+			seen[location(ident)] = true
+			ref.UsedAt = append(ref.UsedAt, location(ident))
+		}
+	}
+
+	references := make([]ReferenceInfo, 0, len(refs))
+	for _, ref := range refs {
+		sortLocations(ref.UsedAt)
+		references = append(references, *ref)
+	}
+	sort.Slice(references, func(i, j int) bool {
+		return locationBefore(references[i].Location, references[j].Location)
+	})
+	return references, nil
+}
+
+func sortLocations(locations []Location) {
+	sort.Slice(locations, func(i, j int) bool {
+		return locationBefore(locations[i], locations[j])
+	})
+}
+
+func locationBefore(a, b Location) bool {
+	if a.URI != b.URI {
+		return a.URI < b.URI
+	}
+	return positionBefore(a.Range.Start, b.Range.Start)
+}
+
+func handleReferences(root string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		pattern := q.Get("pattern")
+		if pattern == "" {
+			pattern = "./..."
+		}
+		if !localPattern(pattern) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("pattern must be a package path or relative to the workspace"))
+			return
+		}
+
+		references, err := AnalyzeReferences(root, pattern, q.Get("name"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, references)
+	}
+}
@@ -0,0 +1,80 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleReferences(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module example.com/m\n\ngo 1.22\n",
+		"a/a.go":          "package a\n\ntype Box[T any] struct{ Value T }\n\nfunc (b Box[T]) Get() T { return b.Value }\n\nfunc Value() int { return 1 }\n",
+		"a/a_test.go":     "package a\n\nimport \"testing\"\n\nfunc TestGet(t *testing.T) { _ = Box[int]{}.Get() }\n",
+		"b/b.go":          "package b\n\nimport \"example.com/m/a\"\n\nfunc Use() int {\n\tbox := a.Box[int]{Value: a.Value()}\n\treturn box.Get() + box.Value\n}\n",
+		"b/b_ext_test.go": "package b_test\n\nimport (\n\t\"testing\"\n\n\t\"example.com/m/b\"\n)\n\nfunc TestUse(t *testing.T) { b.Use() }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddAnalysisHandler()
+
+	references := func(query string) map[string][]string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/references?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var refs []ReferenceInfo
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&refs))
+		uses := make(map[string][]string)
+		for _, ref := range refs {
+			key := fmt.Sprintf("%s %s %s", ref.Kind, ref.Scope, relativeURI(dir, ref.Location))
+			uses[key] = []string{}
+			for _, loc := range ref.UsedAt {
+				uses[key] = append(uses[key], relativeURI(dir, loc))
+			}
+		}
+		return uses
+	}
+
+	// Uses across packages and their tests, through instances of generics,
+	// once per file
+	uses := references("")
+	assert.Equal(t, []string{"a/a_test.go:5:45", "b/b.go:7:13"}, uses["function member a/a.go:5:17"])
+	assert.Equal(t, []string{"a/a.go:5:36", "b/b.go:6:20", "b/b.go:7:25"}, uses["variable member a/a.go:3:25"])
+	assert.Equal(t, []string{"b/b.go:6:29"}, uses["function package a/a.go:7:6"])
+	assert.Equal(t, []string{"b/b_ext_test.go:9:32"}, uses["function package b/b.go:5:6"])
+	assert.NotContains(t, uses, "variable local b/b.go:6:2", "locals are left out")
+
+	// Declarations of a name
+	uses = references("name=Value&pattern=./a")
+	assert.Len(t, uses, 2)
+	assert.Contains(t, uses, "function package a/a.go:7:6")
+	assert.Empty(t, uses["function package a/a.go:7:6"], "uses outside the pattern are not loaded")
+
+	for _, query := range []string{"pattern=../...", "pattern=./missing"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/analyze/references?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+// relativeURI formats a location in dir as path:line:column
+func relativeURI(dir string, loc Location) string {
+	rel, _ := filepath.Rel(dir, filepath.FromSlash(loc.URI[len("file://"):]))
+	return fmt.Sprintf("%s:%d:%d", filepath.ToSlash(rel), loc.Range.Start.Line+1, loc.Range.Start.Character+1)
+}
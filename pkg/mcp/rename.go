@@ -0,0 +1,428 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// RenameRequest asks for the identifier at a position to be renamed
+type RenameRequest struct {
+	URI      string   `json:"uri"`
+	Position Position `json:"position"`
+	NewName  string   `json:"new_name"`
+	Apply    bool     `json:"apply,omitempty"` // Write the edits to the workspace
+}
+
+// RenameResult contains the edits renaming an identifier
+type RenameResult struct {
+	Edit    WorkspaceEdit `json:"edit"`
+	Applied bool          `json:"applied"`
+}
+
+// renameReference is an identifier denoting the renamed object, in one of
+// the packages that were loaded
+type renameReference struct {
+	pkg   *packages.Package
+	ident *ast.Ident
+	obj   types.Object // The renamed object as seen by pkg
+	def   bool
+}
+
+// RenameSymbol computes the edits renaming the identifier at pos in the Go
+// file at path, and every reference to it in the packages under root and
+// their tests, to newName. The edits are keyed by file. It fails instead of
+// changing what a name refers to: when newName is already declared where
+// the object is, when a reference would resolve to another declaration of
+// newName, when another reference of newName would resolve to the renamed
+// object, and when unexporting a name used by other packages. Overlay
+// replaces the contents of files on disk, as for FindDefinition.
+func RenameSymbol(root, path string, overlay map[string][]byte, pos Position, newName string) (map[string][]TextEdit, error) {
+	if !token.IsIdentifier(newName) || newName == "_" {
+		return nil, fmt.Errorf("%q is not a valid identifier", newName)
+	}
+	content, ok := overlay[path]
+	if !ok {
+		var err error
+		if content, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	offset, err := offsetAt(string(content), pos)
+	if err != nil {
+		return nil, err
+	}
+
+	fset, pkgs, err := loadWorkspacePackages(root, overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	var target types.Object
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			if target != nil || fset.File(file.Pos()).Name() != path {
+				continue
+			}
+			ident := identAt(fset, file, offset)
+			if ident == nil {
+				return nil, fmt.Errorf("no identifier at %d:%d", pos.Line, pos.Character)
+			}
+			if target = pkg.TypesInfo.Defs[ident]; target == nil {
+				target = pkg.TypesInfo.Uses[ident]
+			}
+			if target == nil {
+				return nil, fmt.Errorf("cannot rename %s", ident.Name)
+			}
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("%s is not part of a package of the workspace", path)
+	}
+	return renameObject(root, fset, pkgs, target, newName)
+}
+
+// loadWorkspacePackages loads the packages of the workspace and their
+// tests, and those of patterns, with the syntax and types of every
+// identifier
+func loadWorkspacePackages(root string, overlay map[string][]byte, patterns ...string) (*token.FileSet, []*packages.Package, error) {
+	fset := token.NewFileSet()
+	cfg := &packages.Config{
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:     root,
+		Fset:    fset,
+		Overlay: overlay,
+		Tests:   true,
+	}
+	pkgs, err := packages.Load(cfg, append([]string{"./..."}, patterns...)...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load packages: %v", err)
+	}
+	return fset, pkgs, nil
+}
+
+// renameObject computes the edits renaming target, and every reference to
+// it in pkgs, to newName
+func renameObject(root string, fset *token.FileSet, pkgs []*packages.Package, target types.Object, newName string) (map[string][]TextEdit, error) {
+	switch {
+	case target.Pkg() == nil:
+		return nil, fmt.Errorf("cannot rename the builtin %s", target.Name())
+	case isPkgName(target):
+		return nil, fmt.Errorf("cannot rename the import %s", target.Name())
+	case !inWorkspace(root, fset.Position(target.Pos()).Filename):
+		return nil, fmt.Errorf("%s is declared outside the workspace", target.Name())
+	}
+	edits := make(map[string][]TextEdit)
+	if target.Name() == newName {
+		return edits, nil
+	}
+
+	// Objects are compared by their declaration, since every package and
+	// test variant has objects of its own
+	declaredAt := func(obj types.Object) string {
+		return fset.Position(obj.Pos()).String()
+	}
+	key := declaredAt(target)
+	var refs []renameReference
+	for _, pkg := range pkgs {
+		for ident, obj := range pkg.TypesInfo.Defs {
+			if obj != nil && obj.Name() == target.Name() && declaredAt(obj) == key {
+				refs = append(refs, renameReference{pkg: pkg, ident: ident, obj: obj, def: true})
+			}
+		}
+		for ident, obj := range pkg.TypesInfo.Uses {
+			if obj.Name() == target.Name() && declaredAt(obj) == key {
+				refs = append(refs, renameReference{pkg: pkg, ident: ident, obj: obj})
+			}
+		}
+	}
+
+	if err := checkRename(fset, refs, target, newName); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		at := fset.Position(ref.ident.Pos())
+		if seen[at.String()] {
+			continue
+		}
+		seen[at.String()] = true
+		start := Position{Line: at.Line - 1, Character: at.Column - 1}
+		edits[at.Filename] = append(edits[at.Filename], TextEdit{
+			Range:   Range{Start: start, End: Position{Line: start.Line, Character: start.Character + len(target.Name())}},
+			NewText: newName,
+		})
+	}
+	for _, fileEdits := range edits {
+		sort.Slice(fileEdits, func(i, j int) bool {
+			a, b := fileEdits[i].Range.Start, fileEdits[j].Range.Start
+			return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+		})
+	}
+	return edits, nil
+}
+
+// checkRename reports the first reference whose meaning would change by
+// renaming target to newName
+func checkRename(fset *token.FileSet, refs []renameReference, target types.Object, newName string) error {
+	conflict := func(obj types.Object) error {
+		return fmt.Errorf("renaming %s to %s conflicts with %s at %s", target.Name(), newName, obj.Name(), fset.Position(obj.Pos()))
+	}
+
+	for _, ref := range refs {
+		obj := ref.obj
+		if obj.Exported() && !token.IsExported(newName) && ref.pkg.PkgPath != obj.Pkg().Path() {
+			return fmt.Errorf("cannot unexport %s, it is used by %s", target.Name(), ref.pkg.PkgPath)
+		}
+
+		if ref.def {
+			if scope := obj.Parent(); scope != nil {
+				if other := scope.Lookup(newName); other != nil {
+					return conflict(other)
+				}
+				if scope == obj.Pkg().Scope() {
+					// The imports of every file share the package scope
+					for _, file := range ref.pkg.Syntax {
+						if other := ref.pkg.TypesInfo.Scopes[file].Lookup(newName); other != nil {
+							return conflict(other)
+						}
+					}
+				}
+			} else if other := memberConflict(ref.pkg, obj, newName); other != nil {
+				return conflict(other)
+			}
+			continue
+		}
+
+		// A reference by name must not resolve to another declaration
+		if obj.Parent() == nil || ref.pkg.Types != obj.Pkg() {
+			continue
+		}
+		inner := ref.pkg.Types.Scope().Innermost(ref.ident.Pos())
+		if inner == nil {
+			continue
+		}
+		if scope, other := inner.LookupParent(newName, ref.ident.Pos()); other != nil && !encloses(scope, obj.Parent()) {
+			return conflict(other)
+		}
+	}
+
+	// Nor may a reference to another declaration of newName come to resolve
+	// to the renamed object
+	for _, ref := range refs {
+		scope := ref.obj.Parent()
+		if !ref.def || scope == nil {
+			continue
+		}
+		packageLevel := scope == ref.pkg.Types.Scope()
+		for ident, other := range ref.pkg.TypesInfo.Uses {
+			if ident.Name != newName || other.Parent() == nil || other.Parent() == scope || !encloses(other.Parent(), scope) {
+				continue
+			}
+			inner := ref.pkg.Types.Scope().Innermost(ident.Pos())
+			if encloses(scope, inner) && (packageLevel || ident.Pos() > ref.obj.Pos()) {
+				return fmt.Errorf("renaming %s to %s would shadow %s at %s", target.Name(), newName, other.Name(), fset.Position(ident.Pos()))
+			}
+		}
+	}
+	return nil
+}
+
+// memberConflict returns the field or method named newName of a type that
+// has the field or method obj
+func memberConflict(pkg *packages.Package, obj types.Object, newName string) types.Object {
+	var owners []types.Type
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			owners = append(owners, recv.Type())
+		}
+	} else if field, ok := obj.(*types.Var); ok && field.IsField() {
+		for _, tv := range pkg.TypesInfo.Types {
+			if st, ok := tv.Type.(*types.Struct); ok && hasField(st, field) {
+				owners = append(owners, st)
+			}
+		}
+		for _, def := range pkg.TypesInfo.Defs {
+			if typeName, ok := def.(*types.TypeName); ok {
+				if st, ok := typeName.Type().Underlying().(*types.Struct); ok && hasField(st, field) {
+					owners = append(owners, typeName.Type())
+				}
+			}
+		}
+	}
+	for _, owner := range owners {
+		if other, _, _ := types.LookupFieldOrMethod(owner, true, obj.Pkg(), newName); other != nil {
+			return other
+		}
+	}
+	return nil
+}
+
+func hasField(st *types.Struct, field *types.Var) bool {
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i) == field {
+			return true
+		}
+	}
+	return false
+}
+
+// encloses reports whether outer is inner or one of its parents
+func encloses(outer, inner *types.Scope) bool {
+	for ; inner != nil; inner = inner.Parent() {
+		if inner == outer {
+			return true
+		}
+	}
+	return false
+}
+
+// inWorkspace reports whether the file at path is under root
+func inWorkspace(root, path string) bool {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	return err == nil && isWithinRoot(root, rel)
+}
+
+func isPkgName(obj types.Object) bool {
+	_, ok := obj.(*types.PkgName)
+	return ok
+}
+
+// applyTextEdits applies non-overlapping edits to content
+func applyTextEdits(content string, edits []TextEdit) (string, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+	spans := make([]span, len(edits))
+	for i, edit := range edits {
+		start, err := offsetAt(content, edit.Range.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := offsetAt(content, edit.Range.End)
+		if err != nil {
+			return "", err
+		}
+		spans[i] = span{start, end, edit.NewText}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last || s.end < s.start {
+			return "", fmt.Errorf("overlapping edits")
+		}
+		b.WriteString(content[last:s.start])
+		b.WriteString(s.text)
+		last = s.end
+	}
+	b.WriteString(content[last:])
+	return b.String(), nil
+}
+
+// rename computes the edits renaming the symbol at pos in a document. Open
+// documents are named by their URI, other files by file URIs.
+func (ls *LanguageServer) rename(uri string, pos Position, newName string) (WorkspaceEdit, error) {
+	overlay, uris, _ := ls.overlay(uri)
+	edits, err := RenameSymbol(ls.workspaceRoot, ls.documentPath(uri), overlay, pos, newName)
+	if err != nil {
+		return WorkspaceEdit{}, err
+	}
+	return workspaceEdit(edits, uris), nil
+}
+
+// workspaceEdit keys edits of files by the URI of their open document, or
+// by file URI
+func workspaceEdit(edits map[string][]TextEdit, uris map[string]string) WorkspaceEdit {
+	edit := WorkspaceEdit{Changes: make(map[string][]TextEdit)}
+	for path, fileEdits := range edits {
+		fileURI := "file://" + filepath.ToSlash(path)
+		if docURI, ok := uris[fileURI]; ok {
+			fileURI = docURI
+		}
+		edit.Changes[fileURI] = fileEdits
+	}
+	return edit
+}
+
+func handleRename(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req RenameRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.URI == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("uri is required"))
+			return
+		}
+
+		edit, err := ls.rename(req.URI, req.Position, req.NewName)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		result := RenameResult{Edit: edit}
+		if req.Apply {
+			if _, err := ls.applyEdit(edit); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			result.Applied = true
+		}
+
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// applyFileEdits applies edits to files through the file manager and updates
+// the open documents among them
+func (ls *LanguageServer) applyFileEdits(edits map[string][]TextEdit, overlay map[string][]byte, uris map[string]string) error {
+	for path, fileEdits := range edits {
+		content, ok := overlay[path]
+		if !ok {
+			var err error
+			if content, err = os.ReadFile(path); err != nil {
+				return err
+			}
+		}
+		text, err := applyTextEdits(string(content), fileEdits)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		root, err := filepath.Abs(ls.workspaceRoot)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if err := ls.fileManager.CreateFile(rel, []byte(text)); err != nil {
+			return err
+		}
+		if docURI, ok := uris["file://"+filepath.ToSlash(path)]; ok {
+			if err := ls.parseDocument(docURI, text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
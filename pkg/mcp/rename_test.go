@@ -0,0 +1,145 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// renameWorkspace writes a module of two packages and returns its root
+func renameWorkspace(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.22\n",
+		"store/store.go": `package store
+
+// Store holds values
+type Store struct {
+	Size int
+	max  int
+}
+
+func New(limit int) *Store {
+	size := 0
+	return &Store{Size: size, max: limit}
+}
+
+func (s *Store) Full() bool { return s.Size >= s.max }
+`,
+		"store/store_test.go": `package store_test
+
+import "example.com/m/store"
+
+func ExampleNew() {
+	println(store.New(1).Size)
+}
+`,
+		"main.go": `package main
+
+import "example.com/m/store"
+
+func main() {
+	s := store.New(3)
+	println(s.Size, s.Full())
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestRenameSymbol(t *testing.T) {
+	dir := renameWorkspace(t)
+	storePath := filepath.Join(dir, "store", "store.go")
+
+	// A field, from its declaration, across packages and tests
+	edits, err := RenameSymbol(dir, storePath, nil, Position{Line: 4, Character: 1}, "Len")
+	require.NoError(t, err)
+	require.Len(t, edits, 3)
+	assert.Len(t, edits[storePath], 3)
+	assert.Equal(t, []TextEdit{{Range: Range{Start: Position{Line: 6, Character: 11}, End: Position{Line: 6, Character: 15}}, NewText: "Len"}},
+		edits[filepath.Join(dir, "main.go")])
+	assert.Len(t, edits[filepath.Join(dir, "store", "store_test.go")], 1)
+
+	content, err := os.ReadFile(storePath)
+	require.NoError(t, err)
+	renamed, err := applyTextEdits(string(content), edits[storePath])
+	require.NoError(t, err)
+	assert.Contains(t, renamed, "return &Store{Len: size, max: limit}")
+	assert.Contains(t, renamed, "return s.Len >= s.max")
+
+	// A function, from a reference in another package
+	edits, err = RenameSymbol(dir, filepath.Join(dir, "main.go"), nil, Position{Line: 5, Character: 12}, "Open")
+	require.NoError(t, err)
+	assert.Len(t, edits, 3)
+
+	tests := []struct {
+		name    string
+		pos     Position
+		newName string
+		wantErr string
+	}{
+		{"invalid", Position{Line: 8, Character: 5}, "func", "not a valid identifier"},
+		{"declared", Position{Line: 8, Character: 5}, "Store", "conflicts with Store"},
+		{"field", Position{Line: 4, Character: 1}, "max", "conflicts with max"},
+		{"method", Position{Line: 13, Character: 17}, "Size", "conflicts with Size"},
+		{"resolves elsewhere", Position{Line: 8, Character: 9}, "size", "conflicts with size"},
+		{"shadows", Position{Line: 8, Character: 9}, "int", "would shadow int"},
+		{"unexport", Position{Line: 8, Character: 5}, "newStore", "cannot unexport New"},
+		{"builtin", Position{Line: 13, Character: 24}, "boolean", "builtin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenameSymbol(dir, storePath, nil, tt.pos, tt.newName)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestHandleRename(t *testing.T) {
+	dir := renameWorkspace(t)
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+
+	// The open document has unsaved changes
+	src := "package main\n\nimport \"example.com/m/store\"\n\nfunc main() {\n\ts := store.New(3)\n\tprintln(s.Full())\n}\n"
+	body, err := json.Marshal(TextDocumentItem{URI: "main.go", Text: src})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/document/open", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	body, err = json.Marshal(RenameRequest{URI: "main.go", Position: Position{Line: 6, Character: 12}, NewName: "Done", Apply: true})
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/rename", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result RenameResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.True(t, result.Applied)
+	assert.Len(t, result.Edit.Changes["main.go"], 1)
+	assert.Len(t, result.Edit.Changes["file://"+filepath.ToSlash(filepath.Join(dir, "store", "store.go"))], 1)
+
+	content, err := os.ReadFile(filepath.Join(dir, "store", "store.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "func (s *Store) Done() bool")
+	// The open document is written with its changes and updated
+	content, err = os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, strings.Replace(src, "Full", "Done", 1), string(content))
+}
@@ -0,0 +1,206 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ContextURIPrefix prefixes the URIs of contexts exposed as MCP resources,
+// e.g. mcp://context/openapi-petstore
+const ContextURIPrefix = "mcp://context/"
+
+// mcpResourceNotFound is the MCP error code of unknown resources
+const mcpResourceNotFound = -32002
+
+// Context events
+const (
+	contextCreated = "created"
+	contextUpdated = "updated"
+	contextDeleted = "deleted"
+)
+
+// ContextURI returns the MCP resource URI of a context
+func ContextURI(id string) string {
+	return ContextURIPrefix + id
+}
+
+type mcpResource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType"`
+}
+
+type mcpResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType"`
+}
+
+type mcpResourceContents struct {
+	URI      string `json:"uri"`
+	MIMEType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type mcpResourceParams struct {
+	URI string `json:"uri"`
+}
+
+// contextEvent reports that a stored context was created, updated or deleted
+type contextEvent struct {
+	ID string
+	Op string
+}
+
+// contextFeed delivers context events to subscribers. Subscribers that fall
+// behind miss events rather than block changes to the store.
+type contextFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan contextEvent]struct{}
+}
+
+// subscribe returns a channel receiving context events and a function to
+// unsubscribe, which closes the channel
+func (f *contextFeed) subscribe() (<-chan contextEvent, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subscribers == nil {
+		f.subscribers = make(map[chan contextEvent]struct{})
+	}
+	ch := make(chan contextEvent, 64)
+	f.subscribers[ch] = struct{}{}
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subscribers[ch]; ok {
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (f *contextFeed) publish(id, op string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subscribers {
+		select {
+		case ch <- contextEvent{ID: id, Op: op}:
+		default:
+		}
+	}
+}
+
+// notifyContexts forwards context events to the host until events is
+// closed: resource and prompt list changes when contexts come and go, since
+// prompts derived from contexts are listed only while contexts exist, and
+// updates of the resources the host subscribed to
+func (m *mcpSession) notifyContexts(events <-chan contextEvent, notify func(method string, params interface{})) {
+	for event := range events {
+		uri := ContextURI(event.ID)
+		m.mu.Lock()
+		initialized, subscribed := m.initialized, m.subscriptions[uri]
+		m.mu.Unlock()
+		if !initialized {
+			continue
+		}
+		config := m.s.config.MCP
+		if event.Op != contextUpdated && config.Resources {
+			notify("notifications/resources/list_changed", nil)
+		}
+		if event.Op != contextUpdated && config.Prompts {
+			notify("notifications/prompts/list_changed", nil)
+		}
+		if subscribed {
+			notify("notifications/resources/updated", mcpResourceParams{URI: uri})
+		}
+	}
+}
+
+func (m *mcpSession) listResources(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	contexts := m.s.store.List()
+	resources := make([]mcpResource, 0, len(contexts))
+	for _, c := range contexts {
+		resource := mcpResource{URI: ContextURI(c.ID), Name: c.ID, MIMEType: "application/json"}
+		if contextType, ok := c.Metadata["type"].(string); ok {
+			resource.Description = fmt.Sprintf("%s context", contextType)
+		}
+		resources = append(resources, resource)
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].URI < resources[j].URI })
+	return map[string][]mcpResource{"resources": resources}, nil
+}
+
+func (m *mcpSession) listResourceTemplates(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	return map[string][]mcpResourceTemplate{"resourceTemplates": {{
+		URITemplate: ContextURIPrefix + "{id}",
+		Name:        "context",
+		Description: "A stored context by ID, such as an ingested OpenAPI spec or curl collection",
+		MIMEType:    "application/json",
+	}}}, nil
+}
+
+func (m *mcpSession) readResource(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p mcpResourceParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	c, err := m.resourceContext(p.URI)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]mcpResourceContents{
+		"contents": {{URI: p.URI, MIMEType: "application/json", Text: string(data)}},
+	}, nil
+}
+
+// subscribe asks for notifications/resources/updated when the context of a
+// resource changes
+func (m *mcpSession) subscribe(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p mcpResourceParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if _, err := m.resourceContext(p.URI); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.subscriptions[p.URI] = true
+	m.mu.Unlock()
+	return struct{}{}, nil
+}
+
+func (m *mcpSession) unsubscribe(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p mcpResourceParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	delete(m.subscriptions, p.URI)
+	m.mu.Unlock()
+	return struct{}{}, nil
+}
+
+// resourceContext returns the context a resource URI names
+func (m *mcpSession) resourceContext(uri string) (*Context, error) {
+	notFound := &RPCError{Code: mcpResourceNotFound, Message: "resource not found", Data: map[string]string{"uri": uri}}
+	id, ok := strings.CutPrefix(uri, ContextURIPrefix)
+	if !ok || id == "" {
+		return nil, notFound
+	}
+	c, err := m.s.store.Get(id)
+	if errors.Is(err, ErrContextNotFound) {
+		return nil, notFound
+	}
+	return c, err
+}
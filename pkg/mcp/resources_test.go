@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPSession_Resources(t *testing.T) {
+	s := NewServer(nil)
+	session := s.newMCPSession("")
+	ctx := context.Background()
+	call := func(body string) RPCResponse {
+		resp, ok := session.handleMessage(ctx, []byte(body)).(*RPCResponse)
+		require.True(t, ok, body)
+		return *resp
+	}
+	resp := call(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{}}`)
+	assert.Contains(t, string(resp.Result), `"resources":{"subscribe":true,"listChanged":true}`)
+
+	s.store.Create(&Context{ID: "openapi-petstore", Metadata: map[string]interface{}{"type": "openapi"}})
+	resp = call(`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`)
+	assert.JSONEq(t, `{"resources":[{"uri":"mcp://context/openapi-petstore","name":"openapi-petstore","description":"openapi context","mimeType":"application/json"}]}`, string(resp.Result))
+	resp = call(`{"jsonrpc":"2.0","id":2,"method":"resources/templates/list"}`)
+	assert.Contains(t, string(resp.Result), `"uriTemplate":"mcp://context/{id}"`)
+
+	resp = call(`{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"mcp://context/openapi-petstore"}}`)
+	var read struct {
+		Contents []mcpResourceContents `json:"contents"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Result, &read))
+	require.Len(t, read.Contents, 1)
+	assert.Equal(t, "mcp://context/openapi-petstore", read.Contents[0].URI)
+	assert.Contains(t, read.Contents[0].Text, `"type": "openapi"`)
+
+	for _, uri := range []string{"mcp://context/missing", "mcp://context/", "context://openapi-petstore"} {
+		resp = call(`{"jsonrpc":"2.0","id":4,"method":"resources/read","params":{"uri":"` + uri + `"}}`)
+		assert.Equal(t, mcpResourceNotFound, resp.Error.Code, uri)
+	}
+	resp = call(`{"jsonrpc":"2.0","id":5,"method":"resources/subscribe","params":{"uri":"mcp://context/missing"}}`)
+	assert.Equal(t, mcpResourceNotFound, resp.Error.Code)
+}
+
+func TestMCPSession_ResourceNotifications(t *testing.T) {
+	s := NewServer(nil)
+	session := s.newMCPSession("")
+	ctx := context.Background()
+	session.handleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{}}`))
+	require.NoError(t, s.createContext(ctx, &Context{ID: "spec", Metadata: map[string]interface{}{}}))
+	require.NoError(t, s.createContext(ctx, &Context{ID: "other", Metadata: map[string]interface{}{}}))
+	resp := session.handleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"mcp://context/spec"}}`)).(*RPCResponse)
+	require.Nil(t, resp.Error)
+
+	type notification struct {
+		method string
+		params interface{}
+	}
+	var notifications []notification
+	events, unsubscribe := s.contexts.subscribe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		session.notifyContexts(events, func(method string, params interface{}) {
+			notifications = append(notifications, notification{method, params})
+		})
+	}()
+
+	require.NoError(t, s.updateContext(ctx, &Context{ID: "other", Metadata: map[string]interface{}{}}))
+	require.NoError(t, s.updateContext(ctx, &Context{ID: "spec", Metadata: map[string]interface{}{"v": 2}}))
+	require.NoError(t, s.createContext(ctx, &Context{ID: "new", Metadata: map[string]interface{}{}}))
+	require.NoError(t, s.deleteContext("spec"))
+	unsubscribe()
+	<-done
+
+	assert.Equal(t, []notification{
+		{"notifications/resources/updated", mcpResourceParams{URI: "mcp://context/spec"}},
+		{"notifications/resources/list_changed", nil},
+		{"notifications/prompts/list_changed", nil},
+		{"notifications/resources/list_changed", nil},
+		{"notifications/prompts/list_changed", nil},
+		{"notifications/resources/updated", mcpResourceParams{URI: "mcp://context/spec"}},
+	}, notifications)
+
+	session.handleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":2,"method":"resources/unsubscribe","params":{"uri":"mcp://context/spec"}}`))
+	assert.Empty(t, session.subscriptions)
+}
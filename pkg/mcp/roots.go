@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// rootsTimeout bounds how long the host may take to list its roots
+const rootsTimeout = 30 * time.Second
+
+// MCPRoot is a directory the host grants the server, as a file:// URI
+type MCPRoot struct {
+	URI  string `json:"uri"`
+	Name string `json:"name,omitempty"`
+}
+
+// rootsChanged asks the host for its roots when the session is initialized
+// and whenever the host reports a change. It returns at once, since the
+// answer arrives as a message of its own.
+func (m *mcpSession) rootsChanged(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	m.mu.Lock()
+	_, capable := m.clientCapabilities["roots"]
+	m.mu.Unlock()
+	if capable && m.s.config.MCP.Roots {
+		go m.listRoots(LoggerFromContext(ctx))
+	}
+	return nil, nil
+}
+
+// listRoots sends roots/list to the host and moves the workspace to the
+// directories it grants
+func (m *mcpSession) listRoots(logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), rootsTimeout)
+	defer cancel()
+	raw, err := m.request(ctx, "roots/list", struct{}{})
+	if err != nil {
+		logger.Warn("mcp host did not list its roots", "error", err)
+		return
+	}
+	var result struct {
+		Roots []MCPRoot `json:"roots"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		logger.Warn("mcp host listed invalid roots", "error", err)
+		return
+	}
+
+	var dirs []string
+	for _, root := range result.Roots {
+		dir, err := rootDir(root.URI)
+		if err != nil {
+			logger.Warn("ignoring mcp root", "uri", root.URI, "error", err)
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	workspace, err := m.s.grantRoots(dirs)
+	if err != nil {
+		logger.Warn("cannot move the workspace to the mcp roots", "roots", dirs, "error", err)
+		return
+	}
+	logger.Info("mcp roots granted", "roots", dirs, "workspace", workspace)
+}
+
+// rootDir returns the directory named by a root URI
+func rootDir(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("only file roots are supported, not %s", u.Scheme)
+	}
+	dir := filepath.FromSlash(u.Path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	return dir, nil
+}
+
+// grantRoots moves the projects of the IDE servers, and with them tasks, git
+// and file operations, to the first directory an MCP host grants, or back to
+// the configured workspace when it grants none. With several hosts, the
+// latest grant wins. It returns the new workspace.
+func (s *Server) grantRoots(dirs []string) (string, error) {
+	workspace := s.GetWorkspaceRoot()
+	if len(dirs) > 0 {
+		workspace = dirs[0]
+	}
+
+	s.mu.Lock()
+	ideServers := append([]*IDEServer(nil), s.ideServers...)
+	s.mu.Unlock()
+	for _, ideServer := range ideServers {
+		if err := ideServer.projectManager.SetRoot(workspace); err != nil {
+			return "", err
+		}
+	}
+	return workspace, nil
+}
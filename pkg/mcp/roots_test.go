@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPSession_Roots(t *testing.T) {
+	workspace := t.TempDir()
+	granted := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(granted, ".mcp"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(granted, ".mcp", "project.json"), []byte(`{"name":"granted","test_command":"make test"}`), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = workspace
+	s := NewServer(nil, WithConfig(cfg))
+	ideServer, err := NewIDEServer(workspace)
+	require.NoError(t, err)
+	s.AddIDEServer(ideServer)
+	session, sent := samplingSession(t, s, `{"roots":{"listChanged":true}}`)
+
+	// answerRoots waits for roots/list and answers it with the given roots
+	answerRoots := func(roots ...MCPRoot) {
+		t.Helper()
+		var req RPCRequest
+		require.NoError(t, json.Unmarshal(<-sent, &req))
+		require.Equal(t, "roots/list", req.Method)
+		answer, err := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0", "id": req.ID, "result": map[string]interface{}{"roots": roots},
+		})
+		require.NoError(t, err)
+		assert.Nil(t, session.handleMessage(context.Background(), answer))
+	}
+	root := func() string {
+		return ideServer.projectManager.GetConfig().Root
+	}
+
+	assert.Nil(t, session.handleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)))
+	answerRoots(
+		MCPRoot{URI: "https://example.com/repo"},
+		MCPRoot{URI: "file://" + filepath.ToSlash(granted), Name: "granted"},
+	)
+	require.Eventually(t, func() bool { return root() == granted }, time.Second, 10*time.Millisecond)
+	config := ideServer.projectManager.GetConfig()
+	assert.Equal(t, "granted", config.Name)
+	assert.Equal(t, "make test", config.TestCommand)
+	_, err = os.Stat(filepath.Join(workspace, ".mcp", "project.json"))
+	assert.NoError(t, err, "the config of the workspace stays")
+
+	// Granting no roots returns to the configured workspace
+	assert.Nil(t, session.handleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","method":"notifications/roots/list_changed"}`)))
+	answerRoots()
+	require.Eventually(t, func() bool { return root() == workspace }, time.Second, 10*time.Millisecond)
+
+	// Hosts without roots are not asked
+	session, sent = samplingSession(t, s, `{}`)
+	assert.Nil(t, session.handleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)))
+	select {
+	case msg := <-sent:
+		t.Fatalf("unexpected message %s", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRootDir(t *testing.T) {
+	dir := t.TempDir()
+	got, err := rootDir("file://" + filepath.ToSlash(dir))
+	require.NoError(t, err)
+	assert.Equal(t, dir, got)
+
+	_, err = rootDir("https://example.com")
+	assert.ErrorContains(t, err, "only file roots")
+	_, err = rootDir("file://" + filepath.ToSlash(filepath.Join(dir, "missing")))
+	assert.Error(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), nil, 0644))
+	_, err = rootDir("file://" + filepath.ToSlash(filepath.Join(dir, "file")))
+	assert.ErrorContains(t, err, "not a directory")
+}
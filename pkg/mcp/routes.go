@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Route describes an API endpoint. Handlers are registered together with
+// their route so /openapi.json always documents exactly what is served.
+type Route struct {
+	Method   string
+	Path     string // Relative to the API version, e.g. /ssh/{id}/exec
+	Summary  string
+	Query    []QueryParam
+	Request  interface{} // Value of the JSON request body type, nil if none
+	Response interface{} // Value of the JSON response body type, nil if none
+	Status   int         // Success status, defaults to 200
+	Produces string      // Content type of non-JSON responses
+
+	// MaxBodyBytes and Timeout override the server defaults, e.g.
+	// LongRunningTimeout for remote commands or NoTimeout for streams
+	MaxBodyBytes int64
+	Timeout      time.Duration
+}
+
+// QueryParam describes a query string parameter of a route
+type QueryParam struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// handle registers the handler for a route and records the route for the
+// OpenAPI document. Routes of a feature are rejected while it is switched off,
+// and every route is subject to body and time limits.
+func (s *Server) handle(route Route, handler http.HandlerFunc) {
+	if route.Status == 0 {
+		route.Status = http.StatusOK
+	}
+	handler = s.limitRoute(route, handler)
+	if dependency := routeDependency(route.Path); dependency != "" {
+		handler = s.requireDependency(dependency, handler)
+	}
+	if feature := routeFeature(route.Path); feature != "" {
+		handler = s.requireFeature(feature, handler)
+	}
+
+	s.mu.Lock()
+	s.routes = append(s.routes, route)
+	s.mu.Unlock()
+
+	s.router.HandleFunc(route.Path, handler).Methods(route.Method)
+}
+
+// Routes returns the registered API routes in registration order
+func (s *Server) Routes() []Route {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Route(nil), s.routes...)
+}
+
+// Use adds middleware around every API route, inside the built-in rate
+// limiting, usage and chaos middleware, e.g. to authenticate requests.
+// Middleware sees paths relative to the API version, as routes do. Call Use
+// before the server starts; it is not safe while requests are served.
+func (s *Server) Use(middleware ...func(http.Handler) http.Handler) {
+	for _, mw := range middleware {
+		s.router.Use(mw)
+	}
+}
+
+// Mount serves a handler for every path under prefix, which is stripped from
+// the request path, so custom subsystems are served next to the built-in
+// ones under /v1/PREFIX. Mounted handlers pass through the middleware and
+// the body and time limits of the prefix's route group, but are not
+// documented in /openapi.json. The prefix must not overlap registered routes.
+func (s *Server) Mount(prefix string, handler http.Handler) error {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "" {
+		return fmt.Errorf("mount prefix must not be empty")
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	under := func(path string) bool {
+		return path == prefix || strings.HasPrefix(path, prefix+"/")
+	}
+	for _, route := range s.Routes() {
+		if under(route.Path) {
+			return fmt.Errorf("mount prefix %s overlaps route %s %s", prefix, route.Method, route.Path)
+		}
+	}
+
+	limited := s.limitRoute(Route{Path: prefix}, http.StripPrefix(prefix, handler).ServeHTTP)
+	s.router.MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+		return under(r.URL.Path)
+	}).Handler(limited)
+	return nil
+}
@@ -0,0 +1,49 @@
+package mcp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_UseAndMount(t *testing.T) {
+	s := NewServer(nil)
+	s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	require.NoError(t, s.Mount("/ext/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"path": r.URL.Path})
+	})))
+
+	do := func(path string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if auth {
+			req.Header.Set("Authorization", "Bearer token")
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do("/v1/context/list", false).Code)
+	assert.Equal(t, http.StatusOK, do("/v1/context/list", true).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("/v1/ext/hello", false).Code)
+
+	rec := do("/v1/ext/hello", true)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"path":"/hello"}`, rec.Body.String())
+	assert.Equal(t, http.StatusNotFound, do("/v1/extras", true).Code)
+
+	assert.Error(t, s.Mount("/context", http.NotFoundHandler()))
+	assert.Error(t, s.Mount("/", http.NotFoundHandler()))
+}
@@ -0,0 +1,262 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+// JSONRPCVersion is the protocol version spoken by /rpc
+const JSONRPCVersion = "2.0"
+
+// JSON-RPC error codes. Codes from -32000 down are server defined.
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+	RPCNotFound       = -32001
+	RPCConflict       = -32002
+	RPCForbidden      = -32003
+	RPCQuotaExceeded  = -32004
+	RPCUnavailable    = -32005
+)
+
+// RPCRequest is a JSON-RPC 2.0 request. Requests without an ID are
+// notifications and get no response.
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// RPCResponse is a JSON-RPC 2.0 response carrying either a result or an error
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// RPCError is a JSON-RPC 2.0 error object
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// invalidParams wraps a params decoding or validation error
+func invalidParams(err error) *RPCError {
+	return &RPCError{Code: RPCInvalidParams, Message: err.Error()}
+}
+
+// rpcMethod implements a JSON-RPC method. Params are the raw params of the
+// request, nil when omitted.
+type rpcMethod func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// RPCMethod describes a JSON-RPC method. Summary and Params document it for
+// MCP hosts, which list the methods as tools.
+type RPCMethod struct {
+	Name    string
+	Summary string
+	Params  interface{} // Example value of the params, nil when there are none
+}
+
+// registeredRPC is a JSON-RPC method with its description
+type registeredRPC struct {
+	RPCMethod
+	call rpcMethod
+}
+
+// handleRPC registers a JSON-RPC method served by /rpc
+func (s *Server) handleRPC(m RPCMethod, method rpcMethod) {
+	s.mu.Lock()
+	if s.rpcMethods == nil {
+		s.rpcMethods = make(map[string]registeredRPC)
+	}
+	s.rpcMethods[m.Name] = registeredRPC{RPCMethod: m, call: method}
+	s.mu.Unlock()
+	s.tools.publish()
+}
+
+// rpcMethod returns the registered method called name
+func (s *Server) rpcMethod(name string) (rpcMethod, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.rpcMethods[name]
+	return m.call, ok
+}
+
+// RPCMethods returns the sorted names of the registered JSON-RPC methods
+func (s *Server) RPCMethods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.rpcMethods))
+	for name := range s.rpcMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// idParams are the params of methods taking just an ID
+type idParams struct {
+	ID string `json:"id"`
+}
+
+// decodeParams decodes by-name params into v. Omitted params leave v zero.
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if params[0] != '{' {
+		return &RPCError{Code: RPCInvalidParams, Message: "params must be an object"}
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return invalidParams(err)
+	}
+	return nil
+}
+
+// rpcError maps errors of the underlying subsystems to JSON-RPC errors
+func rpcError(err error) *RPCError {
+	var rpcErr *RPCError
+	var fnErr *FunctionError
+	switch {
+	case errors.As(err, &rpcErr):
+		return rpcErr
+	case errors.Is(err, ErrContextNotFound), errors.Is(err, ErrFunctionNotFound), errors.Is(err, ErrConnectionNotFound),
+		errors.Is(err, ErrBrowserNotFound), errors.Is(err, ErrLeaseNotFound), errors.Is(err, ErrForwardNotFound):
+		return &RPCError{Code: RPCNotFound, Message: err.Error()}
+	case errors.Is(err, ErrContextExists), errors.Is(err, ErrConnectionExists), errors.Is(err, ErrBrowserExists):
+		return &RPCError{Code: RPCConflict, Message: err.Error()}
+	case errors.Is(err, ErrFeatureDisabled), errors.Is(err, ErrCapabilityDisabled), errors.Is(err, ErrScopeRequired):
+		return &RPCError{Code: RPCForbidden, Message: err.Error()}
+	case errors.Is(err, ErrQuotaExceeded):
+		return &RPCError{Code: RPCQuotaExceeded, Message: err.Error()}
+	case errors.Is(err, ErrDependencyUnavailable), errors.Is(err, ErrSamplingUnavailable),
+		errors.Is(err, ErrUpstreamUnavailable), errors.Is(err, ErrForwardUnsupported):
+		return &RPCError{Code: RPCUnavailable, Message: err.Error()}
+	case errors.Is(err, ErrInvalidID), errors.Is(err, ErrInvalidMetadata), errors.Is(err, ErrInvalidLabels),
+		errors.Is(err, ErrNoContextsSelected), errors.Is(err, ErrUnknownOperation), errors.Is(err, ErrInvalidForward):
+		return invalidParams(err)
+	case errors.As(err, &fnErr):
+		return &RPCError{Code: RPCInternalError, Message: err.Error(), Data: map[string]interface{}{"function": fnErr.Function}}
+	default:
+		return &RPCError{Code: RPCInternalError, Message: err.Error()}
+	}
+}
+
+// call runs a single request against the methods served by /rpc and returns
+// its response, or nil for a notification
+func (s *Server) call(ctx context.Context, raw json.RawMessage) *RPCResponse {
+	return s.dispatch(ctx, raw, s.rpcMethod)
+}
+
+// dispatch runs a single request against the methods found by lookup
+func (s *Server) dispatch(ctx context.Context, raw json.RawMessage, lookup func(string) (rpcMethod, bool)) *RPCResponse {
+	var req RPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != JSONRPCVersion || req.Method == "" {
+		return &RPCResponse{
+			JSONRPC: JSONRPCVersion,
+			Error:   &RPCError{Code: RPCInvalidRequest, Message: "invalid request"},
+			ID:      json.RawMessage("null"),
+		}
+	}
+
+	method, ok := lookup(req.Method)
+	var result interface{}
+	var err error
+	if !ok {
+		err = &RPCError{Code: RPCMethodNotFound, Message: fmt.Sprintf("method %s not found", req.Method)}
+	} else if err = s.checkFeature(rpcFeature(req.Method)); err == nil {
+		if err = s.checkDependency(rpcDependency(req.Method)); err == nil {
+			result, err = method(ctx, req.Params)
+		}
+	}
+
+	if err != nil {
+		LoggerFromContext(ctx).Warn("rpc call failed", "method", req.Method, "error", err)
+	}
+	if req.ID == nil {
+		return nil
+	}
+
+	resp := &RPCResponse{JSONRPC: JSONRPCVersion, ID: req.ID}
+	if err == nil {
+		resp.Result, err = json.Marshal(result)
+	}
+	if err != nil {
+		resp.Result = nil
+		resp.Error = rpcError(err)
+	}
+	return resp
+}
+
+// serveRPC answers a JSON-RPC request or batch. Responses always use HTTP 200
+// except when there is nothing to return, as for a batch of notifications.
+func (s *Server) serveRPC(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	body = bytes.TrimSpace(body)
+
+	parseError := &RPCResponse{
+		JSONRPC: JSONRPCVersion,
+		Error:   &RPCError{Code: RPCParseError, Message: "parse error"},
+		ID:      json.RawMessage("null"),
+	}
+	if !json.Valid(body) {
+		writeJSON(w, http.StatusOK, parseError)
+		return
+	}
+
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			writeJSON(w, http.StatusOK, parseError)
+			return
+		}
+		if len(batch) == 0 {
+			writeJSON(w, http.StatusOK, &RPCResponse{
+				JSONRPC: JSONRPCVersion,
+				Error:   &RPCError{Code: RPCInvalidRequest, Message: "empty batch"},
+				ID:      json.RawMessage("null"),
+			})
+			return
+		}
+
+		responses := make([]*RPCResponse, 0, len(batch))
+		for _, raw := range batch {
+			if resp := s.call(r.Context(), raw); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, responses)
+		return
+	}
+
+	resp := s.call(r.Context(), body)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
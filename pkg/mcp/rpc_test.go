@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postRPC(t *testing.T, s *Server, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/rpc", strings.NewReader(body)))
+	return rec
+}
+
+func TestRPC_Call(t *testing.T) {
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+	s.AddAnalysisHandler()
+	s.AddSSHHandler()
+
+	rec := postRPC(t, s, `{"jsonrpc":"2.0","method":"context.create","params":{"id":"ctx-1","metadata":{"k":"v"}},"id":1}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp RPCResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Nil(t, resp.Error)
+	assert.JSONEq(t, "1", string(resp.ID))
+	assert.Contains(t, string(resp.Result), `"id":"ctx-1"`)
+
+	// The REST API shares the store
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/context/get?id=ctx-1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	cases := []struct {
+		body string
+		code int
+	}{
+		{`{"jsonrpc":"2.0","method":"context.get","params":{"id":"missing"},"id":"a"}`, RPCNotFound},
+		{`{"jsonrpc":"2.0","method":"context.create","params":{"id":"ctx-1","metadata":{}},"id":"a"}`, RPCConflict},
+		{`{"jsonrpc":"2.0","method":"context.get","params":["ctx-1"],"id":"a"}`, RPCInvalidParams},
+		{`{"jsonrpc":"2.0","method":"function.call","params":{"name":"echo","arguments":[]},"id":"a"}`, RPCInvalidParams},
+		{`{"jsonrpc":"2.0","method":"analysis.metrics","params":{"uri":"x.go","content":"not go"},"id":"a"}`, RPCInvalidParams},
+		{`{"jsonrpc":"2.0","method":"ssh.exec","params":{"id":"web-1","command":"true"},"id":"a"}`, RPCNotFound},
+		{`{"jsonrpc":"2.0","method":"teleport","id":"a"}`, RPCMethodNotFound},
+		{`{"jsonrpc":"1.0","method":"context.list","id":"a"}`, RPCInvalidRequest},
+		{`{"jsonrpc":"2.0",`, RPCParseError},
+		{`[]`, RPCInvalidRequest},
+	}
+	for _, c := range cases {
+		rec := postRPC(t, s, c.body)
+		require.Equal(t, http.StatusOK, rec.Code, c.body)
+		var resp RPCResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp), c.body)
+		require.NotNil(t, resp.Error, c.body)
+		assert.Equal(t, c.code, resp.Error.Code, c.body)
+		assert.Nil(t, resp.Result, c.body)
+	}
+}
+
+func TestRPC_Batch(t *testing.T) {
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+
+	rec := postRPC(t, s, `[
+		{"jsonrpc":"2.0","method":"function.call","params":{"name":"echo","arguments":["hi"]},"id":1},
+		{"jsonrpc":"2.0","method":"context.create","params":{"id":"note","metadata":{}}},
+		{"jsonrpc":"2.0","method":"context.delete","params":{"id":"note"},"id":2},
+		42
+	]`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var responses []RPCResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &responses))
+	require.Len(t, responses, 3) // The notification gets no response
+
+	assert.JSONEq(t, `"hi"`, string(responses[0].Result))
+	assert.JSONEq(t, "2", string(responses[1].ID))
+	assert.JSONEq(t, "null", string(responses[1].Result))
+	assert.Equal(t, RPCInvalidRequest, responses[2].Error.Code)
+	assert.JSONEq(t, "null", string(responses[2].ID))
+
+	// Nothing to answer for notifications only
+	rec = postRPC(t, s, `[{"jsonrpc":"2.0","method":"context.list"}]`)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
@@ -0,0 +1,193 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// defaultSamplingTimeout bounds how long a sampling request waits for the
+// host, which may ask its user to approve it first
+const defaultSamplingTimeout = 5 * time.Minute
+
+// ErrSamplingUnavailable is returned when no MCP host can sample for a
+// request: it did not arrive over MCP, the host did not declare the sampling
+// capability, or sampling is switched off
+var ErrSamplingUnavailable = errors.New("sampling is unavailable")
+
+// SamplingMessage is a message of the conversation sent to the host LLM
+type SamplingMessage struct {
+	Role    string          `json:"role"` // user or assistant
+	Content SamplingContent `json:"content"`
+}
+
+// SamplingContent is text, or an image or audio clip encoded as base64
+type SamplingContent struct {
+	Type     string `json:"type"` // text, image or audio
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// ModelPreferences are hints for the host choosing a model. Priorities range
+// from 0 to 1.
+type ModelPreferences struct {
+	Hints                []ModelHint `json:"hints,omitempty"`
+	CostPriority         *float64    `json:"costPriority,omitempty"`
+	SpeedPriority        *float64    `json:"speedPriority,omitempty"`
+	IntelligencePriority *float64    `json:"intelligencePriority,omitempty"`
+}
+
+// ModelHint names a model or model family, e.g. "claude-3-5-sonnet" or
+// "sonnet"
+type ModelHint struct {
+	Name string `json:"name"`
+}
+
+// SamplingRequest are the params of sampling/createMessage
+type SamplingRequest struct {
+	Messages         []SamplingMessage `json:"messages"`
+	ModelPreferences *ModelPreferences `json:"modelPreferences,omitempty"`
+	SystemPrompt     string            `json:"systemPrompt,omitempty"`
+	IncludeContext   string            `json:"includeContext,omitempty"` // none, thisServer or allServers
+	Temperature      *float64          `json:"temperature,omitempty"`
+	MaxTokens        int               `json:"maxTokens"`
+	StopSequences    []string          `json:"stopSequences,omitempty"`
+}
+
+// SamplingResult is the message the host LLM generated
+type SamplingResult struct {
+	Role       string          `json:"role"`
+	Content    SamplingContent `json:"content"`
+	Model      string          `json:"model"`
+	StopReason string          `json:"stopReason,omitempty"`
+}
+
+// Sampler asks the LLM of an MCP host to generate a message
+type Sampler interface {
+	CreateMessage(ctx context.Context, req *SamplingRequest) (*SamplingResult, error)
+}
+
+type samplerKey struct{}
+
+// SamplerFromContext returns the sampler of the MCP session a request
+// arrived over, if any
+func SamplerFromContext(ctx context.Context) (Sampler, bool) {
+	sampler, ok := ctx.Value(samplerKey{}).(Sampler)
+	return sampler, ok
+}
+
+func withSampler(ctx context.Context, sampler Sampler) context.Context {
+	return context.WithValue(ctx, samplerKey{}, sampler)
+}
+
+// sampleText asks the host LLM of ctx a single question and returns the text
+// of its answer
+func sampleText(ctx context.Context, systemPrompt, prompt string, maxTokens int) (string, error) {
+	sampler, ok := SamplerFromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("%w: the request did not arrive over MCP", ErrSamplingUnavailable)
+	}
+	result, err := sampler.CreateMessage(ctx, &SamplingRequest{
+		Messages:     []SamplingMessage{{Role: "user", Content: SamplingContent{Type: "text", Text: prompt}}},
+		SystemPrompt: systemPrompt,
+		MaxTokens:    maxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.Content.Type != "text" {
+		return "", fmt.Errorf("the host answered with %s content instead of text", result.Content.Type)
+	}
+	return result.Content.Text, nil
+}
+
+// CreateMessage sends sampling/createMessage to the host and waits for the
+// message its LLM generates
+func (m *mcpSession) CreateMessage(ctx context.Context, req *SamplingRequest) (*SamplingResult, error) {
+	if !m.s.config.MCP.Sampling {
+		return nil, fmt.Errorf("%w: switched off in the config", ErrSamplingUnavailable)
+	}
+	m.mu.Lock()
+	_, capable := m.clientCapabilities["sampling"]
+	m.mu.Unlock()
+	if !capable {
+		return nil, fmt.Errorf("%w: the host did not declare the sampling capability", ErrSamplingUnavailable)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultSamplingTimeout)
+		defer cancel()
+	}
+	raw, err := m.request(ctx, "sampling/createMessage", req)
+	if err != nil {
+		return nil, fmt.Errorf("sampling: %w", err)
+	}
+	var result SamplingResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("sampling: invalid result: %w", err)
+	}
+	return &result, nil
+}
+
+// request sends a request to the host and waits for its response. Requests
+// given up on are cancelled at the host.
+func (m *mcpSession) request(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.lastRequestID++
+	id := json.RawMessage(strconv.Quote(fmt.Sprintf("server-%d", m.lastRequestID)))
+	key := requestKey(id)
+	responses := make(chan *RPCResponse, 1)
+	m.pending[key] = responses
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.pending, key)
+		m.mu.Unlock()
+	}()
+
+	m.send(&RPCRequest{JSONRPC: JSONRPCVersion, Method: method, Params: data, ID: id})
+	select {
+	case resp := <-responses:
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-ctx.Done():
+		m.notify("notifications/cancelled", mcpCancelledParams{RequestID: id, Reason: ctx.Err().Error()})
+		return nil, ctx.Err()
+	}
+}
+
+// resolve passes a response of the host to the request waiting for it,
+// reporting whether the message was a response
+func (m *mcpSession) resolve(raw json.RawMessage) bool {
+	var msg struct {
+		Method string          `json:"method"`
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+		ID     json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(raw, &msg) != nil || msg.Method != "" || (msg.Result == nil && msg.Error == nil) {
+		return false
+	}
+
+	m.mu.Lock()
+	key := requestKey(msg.ID)
+	responses, ok := m.pending[key]
+	delete(m.pending, key)
+	m.mu.Unlock()
+	if ok {
+		responses <- &RPCResponse{JSONRPC: JSONRPCVersion, Result: msg.Result, Error: msg.Error, ID: msg.ID}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// samplingSession initializes a session with the given client capabilities
+// and returns it with the messages it sends to the host
+func samplingSession(t *testing.T, s *Server, capabilities string) (*mcpSession, <-chan []byte) {
+	t.Helper()
+	session := s.newMCPSession("")
+	sent := make(chan []byte, 16)
+	session.send = func(message interface{}) {
+		data, err := json.Marshal(message)
+		require.NoError(t, err)
+		sent <- data
+	}
+	session.notify = func(method string, params interface{}) {
+		session.send(&mcpNotification{JSONRPC: JSONRPCVersion, Method: method, Params: params})
+	}
+	resp := session.handleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":`+capabilities+`}}`))
+	require.Nil(t, resp.(*RPCResponse).Error)
+	return session, sent
+}
+
+func TestMCPSession_Sampling(t *testing.T) {
+	s := NewServer(nil)
+	require.NoError(t, s.store.Create(&Context{ID: "pets", Metadata: map[string]interface{}{"type": "openapi"}, Labels: map[string]string{"team": "payments"}}))
+	session, sent := samplingSession(t, s, `{"sampling":{}}`)
+
+	done := make(chan interface{})
+	go func() {
+		done <- session.handleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"context_bulk","arguments":{"selector":"team=payments","operation":"summarize"}}}`))
+	}()
+
+	var req RPCRequest
+	require.NoError(t, json.Unmarshal(<-sent, &req))
+	assert.Equal(t, "sampling/createMessage", req.Method)
+	var params SamplingRequest
+	require.NoError(t, json.Unmarshal(req.Params, &params))
+	require.Len(t, params.Messages, 1)
+	assert.Contains(t, params.Messages[0].Content.Text, `"type":"openapi"`)
+	assert.Positive(t, params.MaxTokens)
+
+	answer, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0", "id": req.ID,
+		"result": SamplingResult{Role: "assistant", Content: SamplingContent{Type: "text", Text: " The pet store API. "}, Model: "test"},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, session.handleMessage(context.Background(), answer), "responses of the host get no response")
+
+	resp := (<-done).(*RPCResponse)
+	require.Nil(t, resp.Error)
+	var result mcpToolResult
+	require.NoError(t, json.Unmarshal(resp.Result, &result))
+	require.False(t, result.IsError, result.Content)
+	c, err := s.store.Get("pets")
+	require.NoError(t, err)
+	assert.Equal(t, "The pet store API.", c.Metadata["summary"])
+}
+
+func TestMCPSession_SamplingUnavailable(t *testing.T) {
+	s := NewServer(nil)
+	session, _ := samplingSession(t, s, `{}`)
+	ctx := withSampler(context.Background(), session)
+	_, err := sampleText(ctx, "", "hello", 10)
+	assert.ErrorIs(t, err, ErrSamplingUnavailable)
+	assert.Equal(t, RPCUnavailable, rpcError(err).Code)
+
+	_, err = sampleText(context.Background(), "", "hello", 10)
+	assert.ErrorIs(t, err, ErrSamplingUnavailable)
+
+	// Without a host the summary is the outline
+	c := &Context{ID: "c", Metadata: map[string]interface{}{"type": "docs", "package": "example.com/x"}}
+	assert.Equal(t, "Documentation of package example.com/x", sampleSummary(ctx, c))
+}
+
+func TestMCPSession_SamplingCancelled(t *testing.T) {
+	s := NewServer(nil)
+	session, sent := samplingSession(t, s, `{"sampling":{}}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := session.CreateMessage(ctx, &SamplingRequest{MaxTokens: 10})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var req RPCRequest
+	require.NoError(t, json.Unmarshal(<-sent, &req))
+	var cancelled struct {
+		Method string             `json:"method"`
+		Params mcpCancelledParams `json:"params"`
+	}
+	require.NoError(t, json.Unmarshal(<-sent, &cancelled))
+	assert.Equal(t, "notifications/cancelled", cancelled.Method)
+	assert.JSONEq(t, string(req.ID), string(cancelled.Params.RequestID))
+
+	// Late responses are dropped
+	assert.Nil(t, session.handleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":`+string(req.ID)+`,"result":{}}`)))
+	assert.Empty(t, session.pending)
+}
@@ -0,0 +1,255 @@
+package mcp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// credentialName matches the names of variables, constants, fields and
+	// map keys holding credentials
+	credentialName = regexp.MustCompile(`(?i)passw(or)?d|pwd|secret|token|api_?key|access_?key|private_?key|credential`)
+	// notCredentialName matches the names of things about credentials, like
+	// tokenHeader or passwordEnv, rather than credentials
+	notCredentialName = regexp.MustCompile(`(?i)(header|env|path|file|url|uri|name|type|kind|prefix|field|param|format|pattern|len|length|count|size|ttl|id)$`)
+)
+
+// weakTLSVersions are the protocol versions older than TLS 1.2
+var weakTLSVersions = map[string]string{
+	"VersionSSL30": "SSL 3.0",
+	"VersionTLS10": "TLS 1.0",
+	"VersionTLS11": "TLS 1.1",
+}
+
+// shells run the script following their -c flag
+var shells = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "cmd": true, "cmd.exe": true, "powershell": true, "pwsh": true}
+
+// securityDiagnostics checks a file against rules after those of gosec:
+// hardcoded credentials (G101), ssh.InsecureIgnoreHostKey (G106),
+// subprocesses launched with variable input (G204) and TLS configurations
+// skipping verification or allowing versions older than 1.2 (G402).
+// Findings are errors.
+func (a *ASTAnalyzer) securityDiagnostics(file *ast.File) []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(node ast.Node, code, format string, args ...interface{}) {
+		start := a.fileSet.Position(node.Pos())
+		end := a.fileSet.Position(node.End())
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: "error",
+			Message:  fmt.Sprintf(format, args...),
+			Location: Location{
+				URI: start.Filename,
+				Range: Range{
+					Start: Position{Line: start.Line - 1, Character: start.Column - 1},
+					End:   Position{Line: end.Line - 1, Character: end.Column - 1},
+				},
+			},
+			Code:   code,
+			Source: SourceAnalyzer,
+		})
+	}
+	credential := func(name string, value ast.Expr) {
+		if isCredentialName(name) && nonEmptyString(value) {
+			report(value, "hardcoded-credential", "Possible hardcoded credential in %s", name)
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.ValueSpec:
+			for i, name := range node.Names {
+				if i < len(node.Values) {
+					credential(name.Name, node.Values[i])
+				}
+			}
+		case *ast.AssignStmt:
+			if len(node.Lhs) != len(node.Rhs) {
+				break
+			}
+			for i, lhs := range node.Lhs {
+				name := assignedName(lhs)
+				credential(name, node.Rhs[i])
+				if message := a.weakTLSSetting(file, name, node.Rhs[i]); message != "" {
+					report(node, "weak-tls-config", "%s", message)
+				}
+			}
+		case *ast.KeyValueExpr:
+			switch key := node.Key.(type) {
+			case *ast.Ident:
+				credential(key.Name, node.Value)
+			case *ast.BasicLit:
+				if name, err := strconv.Unquote(key.Value); err == nil && key.Kind == token.STRING {
+					credential(name, node.Value)
+				}
+			}
+		case *ast.SelectorExpr:
+			if a.selectsFrom(file, node, "golang.org/x/crypto/ssh", "InsecureIgnoreHostKey") {
+				report(node, "insecure-host-key", "ssh.InsecureIgnoreHostKey accepts any host key, allowing man-in-the-middle attacks")
+			}
+		case *ast.CompositeLit:
+			if node.Type != nil && a.selectsFrom(file, node.Type, "crypto/tls", "Config") {
+				for _, elt := range node.Elts {
+					kv, ok := elt.(*ast.KeyValueExpr)
+					if !ok {
+						continue
+					}
+					if key, ok := kv.Key.(*ast.Ident); ok {
+						if message := a.weakTLSSetting(file, key.Name, kv.Value); message != "" {
+							report(kv, "weak-tls-config", "%s", message)
+						}
+					}
+				}
+			}
+		case *ast.CallExpr:
+			if message := a.unsanitizedCommand(file, node); message != "" {
+				report(node, "exec-unsanitized-input", "%s", message)
+			}
+		}
+		return true
+	})
+
+	return diagnostics
+}
+
+// unsanitizedCommand returns why a call of exec.Command or
+// exec.CommandContext may run input it was not meant to: the program is
+// not a constant, or the script a shell runs with -c is not
+func (a *ASTAnalyzer) unsanitizedCommand(file *ast.File, call *ast.CallExpr) string {
+	args := call.Args
+	switch {
+	case a.selectsFrom(file, call.Fun, "os/exec", "Command"):
+	case a.selectsFrom(file, call.Fun, "os/exec", "CommandContext") && len(args) > 0:
+		args = args[1:]
+	default:
+		return ""
+	}
+	if len(args) == 0 {
+		return ""
+	}
+
+	program, ok := a.constantString(args[0])
+	if !ok {
+		return "Subprocess launched with a variable program"
+	}
+	if !shells[strings.ToLower(path.Base(program))] {
+		return ""
+	}
+	last := args[len(args)-1]
+	if _, ok := a.constantString(last); !ok && call.Ellipsis.IsValid() {
+		return "Subprocess launched with variable shell arguments"
+	}
+	for i, arg := range args {
+		if flag, _ := a.constantString(arg); flag != "-c" && flag != "/c" && !strings.EqualFold(flag, "-command") {
+			continue
+		}
+		for _, script := range args[i+1:] {
+			if _, ok := a.constantString(script); !ok {
+				return "Subprocess launched with a variable shell script"
+			}
+		}
+		break
+	}
+	return ""
+}
+
+// weakTLSSetting returns what is wrong with setting the field name of a
+// tls.Config to value: skipping the verification of certificates, or
+// allowing versions older than TLS 1.2
+func (a *ASTAnalyzer) weakTLSSetting(file *ast.File, name string, value ast.Expr) string {
+	switch name {
+	case "InsecureSkipVerify":
+		if a.isTrue(value) {
+			return "TLS InsecureSkipVerify disables the verification of certificates"
+		}
+	case "MinVersion", "MaxVersion":
+		for member, version := range weakTLSVersions {
+			if a.selectsFrom(file, value, "crypto/tls", member) {
+				return fmt.Sprintf("TLS %s allows %s, older than TLS 1.2", name, version)
+			}
+		}
+	}
+	return ""
+}
+
+// selectsFrom reports whether expr is the member name of the package
+// imported from importPath. Without type information, the package is
+// matched by the name it is imported as.
+func (a *ASTAnalyzer) selectsFrom(file *ast.File, expr ast.Expr, importPath, name string) bool {
+	sel, ok := ast.Unparen(expr).(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	if obj := a.typeInfo.Uses[ident]; obj != nil {
+		pkg, ok := obj.(*types.PkgName)
+		return ok && pkg.Imported().Path() == importPath
+	}
+	if ident.Obj != nil || ident.Name != importLocalName(file, importPath) {
+		return false
+	}
+	for _, imp := range file.Imports {
+		if strings.Trim(imp.Path.Value, "\"") == importPath {
+			return true
+		}
+	}
+	return false
+}
+
+// constantString returns the value of a constant string expression
+func (a *ASTAnalyzer) constantString(expr ast.Expr) (string, bool) {
+	if tv, ok := a.typeInfo.Types[expr]; ok && tv.Value != nil {
+		if tv.Value.Kind() != constant.String {
+			return "", false
+		}
+		return constant.StringVal(tv.Value), true
+	}
+	if lit, ok := ast.Unparen(expr).(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		s, err := strconv.Unquote(lit.Value)
+		return s, err == nil
+	}
+	return "", false
+}
+
+// isTrue reports whether expr is the constant true
+func (a *ASTAnalyzer) isTrue(expr ast.Expr) bool {
+	if tv, ok := a.typeInfo.Types[expr]; ok && tv.Value != nil {
+		return tv.Value.Kind() == constant.Bool && constant.BoolVal(tv.Value)
+	}
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	return ok && ident.Name == "true"
+}
+
+// assignedName returns the name of the variable or field assigned to
+func assignedName(lhs ast.Expr) string {
+	switch lhs := lhs.(type) {
+	case *ast.Ident:
+		return lhs.Name
+	case *ast.SelectorExpr:
+		return lhs.Sel.Name
+	}
+	return ""
+}
+
+func isCredentialName(name string) bool {
+	return credentialName.MatchString(name) && !notCredentialName.MatchString(name)
+}
+
+// nonEmptyString reports whether expr is a string literal with some text
+func nonEmptyString(expr ast.Expr) bool {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return err == nil && strings.TrimSpace(s) != ""
+}
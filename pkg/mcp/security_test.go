@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityDiagnostics(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		codes []string
+	}{
+		{
+			name: "hardcoded credentials",
+			src: `package p
+
+const apiKey = "d41d8cd98f00b204"
+
+type Config struct{ Password string }
+
+var config = Config{Password: "hunter2"}
+
+var headers = map[string]string{"token": "abc123"}
+
+func f(c *Config) {
+	c.Password = "hunter2"
+	secret := "s3cr3t"
+	_ = secret
+}`,
+			codes: []string{"hardcoded-credential", "hardcoded-credential", "hardcoded-credential", "hardcoded-credential", "hardcoded-credential"},
+		},
+		{
+			name: "names about credentials",
+			src: `package p
+
+const tokenHeader = "X-Token"
+
+var passwordEnv = "APP_PASSWORD"
+
+var password = ""`,
+		},
+		{
+			name: "insecure host key",
+			src: `package p
+
+import "golang.org/x/crypto/ssh"
+
+var config = &ssh.ClientConfig{HostKeyCallback: ssh.InsecureIgnoreHostKey()}`,
+			codes: []string{"insecure-host-key"},
+		},
+		{
+			name: "variable program",
+			src: `package p
+
+import (
+	"context"
+	"os/exec"
+)
+
+func run(ctx context.Context, name string) {
+	exec.Command(name)
+	exec.CommandContext(ctx, name, "-v")
+}`,
+			codes: []string{"exec-unsanitized-input", "exec-unsanitized-input"},
+		},
+		{
+			name: "variable shell script",
+			src: `package p
+
+import "os/exec"
+
+func run(script string, args []string) {
+	exec.Command("sh", "-c", script)
+	exec.Command("/bin/bash", args...)
+}`,
+			codes: []string{"exec-unsanitized-input", "exec-unsanitized-input"},
+		},
+		{
+			name: "constant commands",
+			src: `package p
+
+import "os/exec"
+
+const shell = "sh"
+
+func run(dir string) {
+	exec.Command("git", "status", dir)
+	exec.Command(shell, "-c", "ls | wc -l")
+}`,
+		},
+		{
+			name: "weak tls",
+			src: `package p
+
+import "crypto/tls"
+
+var config = &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS10}
+
+func f(c *tls.Config) {
+	c.MaxVersion = tls.VersionTLS11
+	c.MinVersion = tls.VersionTLS12
+	c.InsecureSkipVerify = false
+}`,
+			codes: []string{"weak-tls-config", "weak-tls-config", "weak-tls-config"},
+		},
+		{
+			name: "packages of the same name",
+			src: `package p
+
+import ssh "example.com/ssh"
+
+var callback = ssh.InsecureIgnoreHostKey()`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "p.go", tt.src, parser.ParseComments)
+			require.NoError(t, err)
+
+			result, err := NewASTAnalyzer(fset).AnalyzeFile(file)
+			require.NoError(t, err)
+			var codes []string
+			for _, d := range result.Diagnostics {
+				if d.Severity == "error" {
+					codes = append(codes, d.Code)
+					assert.Equal(t, "p.go", d.Location.URI)
+					assert.Equal(t, SourceAnalyzer, d.Source)
+				}
+			}
+			assert.Equal(t, tt.codes, codes)
+		})
+	}
+}
+
+func TestSecurityDiagnosticLocation(t *testing.T) {
+	src := "package p\n\nimport \"crypto/tls\"\n\nvar config = tls.Config{\n\tInsecureSkipVerify: true,\n}\n"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	require.NoError(t, err)
+
+	diagnostics := NewASTAnalyzer(fset).securityDiagnostics(file)
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, "TLS InsecureSkipVerify disables the verification of certificates", diagnostics[0].Message)
+	assert.Equal(t, Range{Start: Position{Line: 5, Character: 1}, End: Position{Line: 5, Character: 25}}, diagnostics[0].Location.Range)
+}
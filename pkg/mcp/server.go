@@ -2,74 +2,649 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+	"github.com/ivikasavnish/go-mcp/pkg/recorder"
+	"google.golang.org/grpc"
 )
 
 // Server represents the MCP server
 type Server struct {
-	store  Store
-	router *mux.Router
+	config       *ServerConfig
+	logger       *slog.Logger
+	store        Store
+	router       *mux.Router
+	handler      http.Handler
+	diagnostics  *DiagnosticsAggregator
+	browsers     *BrowserManager
+	ssh          *SSHManager
+	ideServers   []*IDEServer
+	functions    *FunctionHandler
+	functionJobs *functionJobs
+	analysis     bool            // The analysis routes are registered
+	lsp          *LanguageServer // Open documents of the /lsp routes and of LSP editors
+	routes       []Route
+	preflight    *ide.DoctorReport
+	rpcMethods   map[string]registeredRPC
+	toggles      *toggles
+	usage        *UsageTracker
+	chaos        *Chaos
+	proxy        *recorder.Recorder
+	mcpHTTP      *mcpHTTPTransport
+	leases       *LeaseManager
+	upstreams    *upstreams
+	contexts     contextFeed // Changes made through the API
+	tools        toolFeed
+
+	dependencies        map[string]Dependency
+	dependencyOverrides map[string]bool
+
+	sshDialer       SSHDialer
+	browserLauncher BrowserLauncher
+
+	httpServer  *http.Server
+	grpcServer  *grpc.Server
+	proxyServer *http.Server
+	storeClosed bool
+	mu          sync.Mutex
+}
+
+// ServerOption configures a Server
+type ServerOption func(*Server)
+
+// WithConfig sets the server configuration
+func WithConfig(cfg *ServerConfig) ServerOption {
+	return func(s *Server) {
+		s.config = cfg
+	}
+}
+
+// WithLogger sets the logger used for request and subsystem logs
+func WithLogger(logger *slog.Logger) ServerOption {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithSSHDialer replaces how SSH connections are opened, e.g. with fakes in
+// tests
+func WithSSHDialer(dial SSHDialer) ServerOption {
+	return func(s *Server) {
+		s.sshDialer = dial
+	}
+}
+
+// WithBrowserLauncher replaces how browser instances are created, e.g. with
+// stubs in tests
+func WithBrowserLauncher(launch BrowserLauncher) ServerOption {
+	return func(s *Server) {
+		s.browserLauncher = launch
+	}
 }
 
 // NewServer creates a new MCP server instance
-func NewServer(store Store) *Server {
+func NewServer(store Store, opts ...ServerOption) *Server {
 	if store == nil {
 		store = NewMemoryStore()
 	}
 
 	s := &Server{
+		config: DefaultConfig(),
 		store:  store,
 		router: mux.NewRouter(),
 	}
 
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.logger == nil {
+		s.logger = slog.Default()
+	}
+	s.toggles = newToggles(s.config)
+	if s.config.Usage.Enabled {
+		s.usage = NewUsageTracker(s.config.Usage)
+	}
+	if s.config.Chaos.Enabled {
+		s.chaos = NewChaos(s.config.Chaos)
+	}
+	if s.config.Proxy.Enabled {
+		s.proxy = s.newRecorder()
+	}
+	if s.config.MCPHTTP.Enabled {
+		s.mcpHTTP = newMCPHTTPTransport(s)
+	}
+	s.leases = NewLeaseManager(s.config.Leases, s.logger)
+	s.upstreams = newUpstreams(s.config.Upstreams)
+	s.detectDependencies()
+
+	s.setupMiddleware()
 	s.setupRoutes()
 	return s
 }
 
+// NewServerFromConfig creates a server with the configured store backend and
+// registers the handlers of every enabled feature
+func NewServerFromConfig(cfg *ServerConfig) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	store, err := NewStoreFromConfig(cfg.Store)
+	if err != nil {
+		return nil, err
+	}
+
+	logger, err := NewLogger(cfg.Logging, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewServer(store, WithConfig(cfg), WithLogger(logger))
+	if err := s.EnableFeatures(); err != nil {
+		return nil, err
+	}
+	s.logMissingDependencies()
+	return s, nil
+}
+
+// Config returns the server configuration
+func (s *Server) Config() *ServerConfig {
+	return s.config
+}
+
+// Logger returns the server logger
+func (s *Server) Logger() *slog.Logger {
+	return s.logger
+}
+
+// Functions returns the function handler, for registering functions before
+// the server starts. It is nil while the functions feature is disabled and
+// AddFunctionHandler was not called.
+func (s *Server) Functions() *FunctionHandler {
+	return s.functions
+}
+
+// EnableFeatures registers the handlers of every feature enabled in the config
+func (s *Server) EnableFeatures() error {
+	cfg := s.config
+
+	if cfg.FeatureEnabled(FeatureCurl) {
+		s.AddCurlHandler()
+	}
+	if cfg.FeatureEnabled(FeatureFunctions) {
+		s.AddFunctionHandler()
+	}
+	if cfg.FeatureEnabled(FeatureSSH) {
+		s.AddSSHHandler()
+	}
+	if cfg.FeatureEnabled(FeatureBrowser) {
+		s.AddBrowserHandlers()
+	}
+	if cfg.FeatureEnabled(FeatureLSP) {
+		s.AddLanguageServerHandler()
+	}
+	if cfg.FeatureEnabled(FeatureAnalysis) {
+		s.AddAnalysisHandler()
+	}
+	if cfg.FeatureEnabled(FeatureIDE) {
+		ideServer, err := NewIDEServer(s.GetWorkspaceRoot())
+		if err != nil {
+			return err
+		}
+		s.AddIDEServer(ideServer)
+	}
+	if cfg.FeatureEnabled(FeatureDiagnostics) {
+		s.AddDiagnosticsHandler()
+	}
+	if cfg.FeatureEnabled(FeatureGenerate) {
+		s.AddGenerateHandlers()
+	}
+	if cfg.FeatureEnabled(FeatureDocs) {
+		s.AddDocumentationEndpoints()
+	}
+
+	return nil
+}
+
+func (s *Server) setupMiddleware() {
+	if s.config.RateLimit.Enabled {
+		s.router.Use(NewRateLimiter(s.config.RateLimit).Middleware)
+	}
+	if s.usage != nil {
+		s.router.Use(s.usage.Middleware)
+	}
+	s.router.Use(s.leases.Middleware)
+	if s.chaos != nil {
+		s.logger.Warn("chaos mode is enabled, faults will be injected into responses")
+		s.router.Use(s.chaos.Middleware)
+	}
+
+	// Handlers that must also see requests no route matches, such as CORS
+	// preflights, wrap the versioned router itself
+	versions := newAPIVersions(s.config.API)
+	versions.mount(APIVersion, s.router)
+	versions.handleRoot("/openapi.json", handleOpenAPI(s))
+	versions.handleRoot("/readyz", handleReadyz(s))
+	versions.handleRoot("/metrics", handleMetrics(s))
+	s.handler = versions
+	if s.config.CORS.Enabled {
+		s.handler = NewCORS(s.config.CORS).Handler(s.handler)
+	}
+	if s.config.Compression.Enabled {
+		s.handler = NewCompressor(s.config.Compression).Handler(s.handler)
+	}
+	s.handler = RequestLogger(s.logger, s.handler)
+}
+
 func (s *Server) setupRoutes() {
-	s.router.HandleFunc("/context/create", s.handleCreateContext).Methods("POST")
-	s.router.HandleFunc("/context/get", s.handleGetContext).Methods("GET")
-	s.router.HandleFunc("/context/update", s.handleUpdateContext).Methods("PUT")
-	s.router.HandleFunc("/context/delete", s.handleDeleteContext).Methods("DELETE")
-	s.router.HandleFunc("/context/list", s.handleListContexts).Methods("GET")
+	s.router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no route for %s", r.URL.Path))
+	})
+	s.router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed for %s", r.Method, r.URL.Path))
+	})
+
+	s.router.HandleFunc("/openapi.json", handleOpenAPI(s)).Methods("GET")
+
+	contextID := []QueryParam{{Name: "id", Description: "Context ID", Required: true}}
+	s.handle(Route{
+		Method: "POST", Path: "/context/create", Summary: "Create a context",
+		Request: CreateContextRequest{}, Response: Context{}, Status: http.StatusCreated,
+	}, s.handleCreateContext)
+	s.handle(Route{
+		Method: "GET", Path: "/context/get", Summary: "Get a context",
+		Query: contextID, Response: Context{},
+	}, s.handleGetContext)
+	s.handle(Route{
+		Method: "PUT", Path: "/context/update", Summary: "Update the metadata of a context",
+		Query: contextID, Request: UpdateContextRequest{}, Response: Context{},
+	}, s.handleUpdateContext)
+	s.handle(Route{
+		Method: "DELETE", Path: "/context/delete", Summary: "Delete a context",
+		Query: contextID, Status: http.StatusNoContent,
+	}, s.handleDeleteContext)
+	s.handle(Route{
+		Method: "GET", Path: "/context/list", Summary: "List contexts",
+		Query: []QueryParam{
+			{Name: "selector", Description: "Only list contexts whose labels match, e.g. team=payments,!deprecated"},
+		},
+		Response: []*Context{},
+	}, s.handleListContexts)
+	s.handle(Route{
+		Method: "POST", Path: "/context/labels", Summary: "Add and remove labels of contexts by ID or label selector",
+		Request: LabelContextsRequest{}, Response: []*Context{},
+	}, s.handleLabelContexts)
+	s.handle(Route{
+		Method: "POST", Path: "/context/bulk", Summary: "Delete, export or summarize the contexts matching a label selector",
+		Request: BulkContextRequest{}, Response: BulkContextResult{}, Timeout: LongRunningTimeout,
+	}, s.handleBulkContexts)
+
+	s.handle(Route{
+		Method: "POST", Path: "/rpc", Summary: "Call methods with JSON-RPC 2.0, singly or in batches",
+		Request: RPCRequest{}, Response: RPCResponse{}, Timeout: LongRunningTimeout,
+	}, s.serveRPC)
+	s.addContextMethods()
+
+	s.handle(Route{
+		Method: "GET", Path: "/capabilities", Summary: "Advertise the enabled features, capabilities and JSON-RPC methods",
+		Response: ServerCapabilities{},
+	}, handleCapabilities(s))
+	s.handle(Route{
+		Method: "GET", Path: "/admin/features", Summary: "Get the runtime state of features and capabilities",
+		Response: FeatureState{},
+	}, handleGetFeatures(s))
+	s.handle(Route{
+		Method: "PUT", Path: "/admin/features", Summary: "Switch features and capabilities on or off",
+		Request: FeatureState{}, Response: FeatureState{},
+	}, handleUpdateFeatures(s))
+
+	s.handle(Route{
+		Method: "POST", Path: "/leases", Summary: "Create a lease that browsers, SSH connections and tasks are tied to",
+		Request: LeaseRequest{}, Response: Lease{}, Status: http.StatusCreated,
+	}, handleCreateLease(s))
+	s.handle(Route{
+		Method: "GET", Path: "/leases", Summary: "List the leases of the caller's tenant",
+		Response: []Lease{},
+	}, handleListLeases(s))
+	s.handle(Route{
+		Method: "GET", Path: "/leases/{id}", Summary: "Get a lease and its resources",
+		Response: Lease{},
+	}, handleGetLease(s))
+	s.handle(Route{
+		Method: "POST", Path: "/leases/{id}/renew", Summary: "Renew a lease, optionally with a new TTL",
+		Request: LeaseRequest{}, Response: Lease{},
+	}, handleRenewLease(s))
+	s.handle(Route{
+		Method: "DELETE", Path: "/leases/{id}", Summary: "Release a lease and the resources tied to it",
+		Response: Lease{},
+	}, handleReleaseLease(s))
+
+	s.handle(Route{
+		Method: "GET", Path: "/upstreams", Summary: "Get the state, tools and resources of the aggregated upstream MCP servers",
+		Response: []UpstreamStatus{},
+	}, handleListUpstreams(s))
+
+	if s.usage != nil {
+		s.handle(Route{
+			Method: "GET", Path: "/usage", Summary: "Get the usage and quota of the caller's tenant",
+			Response: TenantUsage{},
+		}, handleUsage(s))
+		s.handle(Route{
+			Method: "GET", Path: "/admin/usage", Summary: "Get the usage of every tenant",
+			Response: []TenantUsage{},
+		}, handleAllUsage(s))
+	}
+
+	if s.chaos != nil {
+		s.handle(Route{
+			Method: "GET", Path: "/admin/chaos", Summary: "Get the injected faults of every route group",
+			Response: map[string]Fault{},
+		}, handleGetChaos(s.chaos))
+		s.handle(Route{
+			Method: "PUT", Path: "/admin/chaos", Summary: "Replace the injected faults of every route group",
+			Request: map[string]Fault{}, Response: map[string]Fault{},
+		}, handleSetChaos(s.chaos))
+	}
+
+	if s.proxy != nil {
+		s.handle(Route{
+			Method: "GET", Path: "/proxy/sessions", Summary: "List the sessions recorded by the proxy",
+			Response: []recorder.Session{},
+		}, handleListProxySessions(s.proxy))
+		s.handle(Route{
+			Method: "GET", Path: "/proxy/sessions/{session}", Summary: "Get the exchanges of a recorded session",
+			Response: []recorder.Exchange{},
+		}, handleGetProxySession(s.proxy))
+		s.handle(Route{
+			Method: "GET", Path: "/proxy/sessions/{session}/curl", Summary: "Get the requests of a recorded session as curl commands",
+			Produces: "text/plain",
+		}, handleProxySessionCurl(s.proxy))
+		s.handle(Route{
+			Method: "DELETE", Path: "/proxy/sessions/{session}", Summary: "Forget the exchanges of a recorded session",
+			Response: map[string]string{},
+		}, handleResetProxySession(s.proxy))
+	}
+
+	if s.config.WebDAV.Enabled {
+		s.Mount("/dav", s.newWebDAV())
+	}
+
+	if s.mcpHTTP != nil {
+		s.handle(Route{
+			Method: "POST", Path: "/mcp", Summary: "Send MCP messages; initialize opens a session named by the Mcp-Session-Id header",
+			Request: RPCRequest{}, Response: RPCResponse{}, Timeout: LongRunningTimeout,
+		}, s.mcpHTTP.handlePost)
+		s.handle(Route{
+			Method: "GET", Path: "/mcp", Summary: "Stream the MCP notifications of a session as server-sent events",
+			Produces: "text/event-stream", Timeout: NoTimeout,
+		}, s.mcpHTTP.handleStream)
+		s.handle(Route{
+			Method: "DELETE", Path: "/mcp", Summary: "End an MCP session",
+			Status: http.StatusNoContent,
+		}, s.mcpHTTP.handleDelete)
+	}
+}
+
+// contextParams are the params of the context.* JSON-RPC methods
+type contextParams struct {
+	ID       string                 `json:"id"`
+	Metadata map[string]interface{} `json:"metadata"`
+	Labels   map[string]string      `json:"labels,omitempty"`
+}
+
+type listContextsParams struct {
+	Selector string `json:"selector,omitempty"`
+}
+
+// addContextMethods registers the context.* JSON-RPC methods
+func (s *Server) addContextMethods() {
+	s.handleRPC(RPCMethod{
+		Name: "context.create", Summary: "Create a context", Params: contextParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p contextParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		c := &Context{ID: p.ID, Metadata: p.Metadata, Labels: p.Labels, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := s.createContext(ctx, c); err != nil {
+			return nil, err
+		}
+		return c, nil
+	})
+	s.handleRPC(RPCMethod{
+		Name: "context.get", Summary: "Get a context", Params: idParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p contextParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.store.Get(p.ID)
+	})
+	s.handleRPC(RPCMethod{
+		Name: "context.update", Summary: "Replace the metadata of a context", Params: contextParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p contextParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		c, err := s.store.Get(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		c.Metadata = p.Metadata
+		c.UpdatedAt = time.Now()
+		if err := s.updateContext(ctx, c); err != nil {
+			return nil, err
+		}
+		return c, nil
+	})
+	s.handleRPC(RPCMethod{
+		Name: "context.delete", Summary: "Delete a context", Params: idParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p contextParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return nil, s.deleteContext(p.ID)
+	})
+	s.handleRPC(RPCMethod{
+		Name: "context.list", Summary: "List contexts, optionally those matching a label selector", Params: listContextsParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p listContextsParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.listContexts(p.Selector)
+	})
+	s.handleRPC(RPCMethod{
+		Name: "context.label", Summary: "Add and remove labels of contexts by ID or label selector", Params: LabelContextsRequest{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p LabelContextsRequest
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.labelContexts(ctx, p)
+	})
+	s.handleRPC(RPCMethod{
+		Name: "context.bulk", Summary: "Delete, export or summarize the contexts matching a label selector", Params: BulkContextRequest{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p BulkContextRequest
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.bulkContexts(ctx, p)
+	})
 }
 
 // ServeHTTP implements the http.Handler interface
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.router.ServeHTTP(w, r)
+	s.handler.ServeHTTP(w, r)
 }
 
-// Start starts the server on the specified address
+// Start starts the server on the specified address, which may be any
+// address accepted by Listen, such as unix:/run/mcp.sock. It blocks like
+// Serve.
 func (s *Server) Start(addr string) error {
-	return http.ListenAndServe(addr, s)
+	lis, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+// Serve serves the API on lis and runs preflight checks in the background. It
+// blocks until the server fails or Shutdown is called, in which case it
+// returns nil.
+func (s *Server) Serve(lis net.Listener) error {
+	go s.RunPreflight(context.Background())
+
+	s.mu.Lock()
+	s.httpServer = &http.Server{
+		Addr:              lis.Addr().String(),
+		Handler:           s,
+		ReadHeaderTimeout: s.config.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       s.config.HTTP.ReadTimeout,
+		WriteTimeout:      s.config.HTTP.WriteTimeout,
+		IdleTimeout:       s.config.HTTP.IdleTimeout,
+	}
+	srv := s.httpServer
+	s.mu.Unlock()
+
+	if err := srv.Serve(lis); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown gracefully stops the server. It stops accepting new connections,
+// waits for in-flight requests and gRPC calls to drain, then stops
+// background tasks, closes browser instances, SSH connections and upstream
+// MCP servers, saves the language server state, and finally
+// closes the store if it implements io.Closer. The context bounds how long
+// draining may take. Calling it again is safe; the store is closed once.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	srv := s.httpServer
+	grpcSrv := s.grpcServer
+	proxySrv := s.proxyServer
+	ideServers := append([]*IDEServer(nil), s.ideServers...)
+	closeStore := !s.storeClosed
+	s.storeClosed = true
+	s.mu.Unlock()
+
+	var errs []error
+
+	// Event streams last until their session ends
+	if s.mcpHTTP != nil {
+		s.mcpHTTP.closeAll()
+	}
+	s.leases.stop()
+	if s.lsp != nil {
+		s.lsp.symbols.stop()
+	}
+
+	if srv != nil {
+		if err := srv.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if proxySrv != nil {
+		if err := proxySrv.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if grpcSrv != nil {
+		if err := stopGRPC(ctx, grpcSrv); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if s.functionJobs != nil {
+		if err := s.functionJobs.stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, ideServer := range ideServers {
+		if err := ideServer.taskManager.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if s.browsers != nil {
+		if err := s.browsers.CloseAll(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if s.ssh != nil {
+		if err := s.ssh.CloseAll(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := s.upstreams.closeAll(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// Editors reconnecting to the next server find their documents open
+	if s.lsp != nil {
+		if _, err := s.lsp.saveState(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save language server state: %w", err))
+		}
+	}
+
+	if closer, ok := s.store.(io.Closer); ok && closeStore {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 // Request/Response types
 type CreateContextRequest struct {
 	ID       string                 `json:"id"`
 	Metadata map[string]interface{} `json:"metadata"`
+	Labels   map[string]string      `json:"labels,omitempty"`
 }
 
 type UpdateContextRequest struct {
 	Metadata map[string]interface{} `json:"metadata"`
 }
 
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
-
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(v)
 }
 
+// writeError writes an error response with the code of the error and the
+// request ID for correlation. Errors from reading a body over its limit are
+// always reported as 413 Request Entity Too Large.
 func writeError(w http.ResponseWriter, status int, err error) {
-	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		status = http.StatusRequestEntityTooLarge
+	}
+	e := classifyError(status, err)
+	e.CorrelationID = w.Header().Get(RequestIDHeader)
+	writeJSON(w, status, ErrorResponse{Error: e})
 }
 
 func (s *Server) handleCreateContext(w http.ResponseWriter, r *http.Request) {
@@ -82,16 +657,19 @@ func (s *Server) handleCreateContext(w http.ResponseWriter, r *http.Request) {
 	ctx := &Context{
 		ID:        req.ID,
 		Metadata:  req.Metadata,
+		Labels:    req.Labels,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	if err := s.store.Create(ctx); err != nil {
+	if err := s.createContext(r.Context(), ctx); err != nil {
 		status := http.StatusInternalServerError
 		if err == ErrContextExists {
 			status = http.StatusConflict
-		} else if err == ErrInvalidID || err == ErrInvalidMetadata {
+		} else if err == ErrInvalidID || err == ErrInvalidMetadata || errors.Is(err, ErrInvalidLabels) {
 			status = http.StatusBadRequest
+		} else if errors.Is(err, ErrQuotaExceeded) {
+			status = http.StatusTooManyRequests
 		}
 		writeError(w, status, err)
 		return
@@ -146,8 +724,12 @@ func (s *Server) handleUpdateContext(w http.ResponseWriter, r *http.Request) {
 	ctx.Metadata = req.Metadata
 	ctx.UpdatedAt = time.Now()
 
-	if err := s.store.Update(ctx); err != nil {
-		writeError(w, http.StatusInternalServerError, err)
+	if err := s.updateContext(r.Context(), ctx); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrQuotaExceeded) {
+			status = http.StatusTooManyRequests
+		}
+		writeError(w, status, err)
 		return
 	}
 
@@ -161,7 +743,7 @@ func (s *Server) handleDeleteContext(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.store.Delete(id); err != nil {
+	if err := s.deleteContext(id); err != nil {
 		status := http.StatusInternalServerError
 		if err == ErrContextNotFound {
 			status = http.StatusNotFound
@@ -174,6 +756,10 @@ func (s *Server) handleDeleteContext(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleListContexts(w http.ResponseWriter, r *http.Request) {
-	contexts := s.store.List()
+	contexts, err := s.listContexts(r.URL.Query().Get("selector"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
 	writeJSON(w, http.StatusOK, contexts)
 }
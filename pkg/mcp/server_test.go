@@ -0,0 +1,114 @@
+package mcp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closingStore records when the store is closed
+type closingStore struct {
+	Store
+	closed func()
+}
+
+func (s *closingStore) Close() error {
+	s.closed()
+	return nil
+}
+
+// closingConn records when the SSH connection is closed
+type closingConn struct {
+	SSHConn
+	closed func()
+}
+
+func (c *closingConn) Close() error {
+	c.closed()
+	return nil
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(step string) func() {
+		return func() {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, step)
+		}
+	}
+	steps := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), order...)
+	}
+
+	s := NewServer(&closingStore{Store: NewMemoryStore(), closed: record("store")})
+	s.AddFunctionHandler()
+	s.AddSSHHandler()
+	s.ssh.clients["db"] = &closingConn{closed: record("ssh")}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, s.functions.RegisterFunction("slow", func() string {
+		close(started)
+		<-release
+		record("request")()
+		return "done"
+	}))
+	require.NoError(t, s.functions.RegisterFunction("wait", func(ctx context.Context) error {
+		<-ctx.Done()
+		record("job")()
+		return ctx.Err()
+	}))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(lis) }()
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/function/call?async=true", strings.NewReader(`{"name":"wait"}`)))
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Post("http://"+lis.Addr().String()+"/v1/function/call", "application/json", strings.NewReader(`{"name":"slow"}`))
+		assert.NoError(t, err)
+		responses <- resp
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+
+	// Nothing is torn down while the request is in flight
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned before the request drained")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Empty(t, steps())
+
+	close(release)
+	resp := <-responses
+	require.NotNil(t, resp)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, <-shutdown)
+	require.NoError(t, <-served)
+	assert.Equal(t, []string{"request", "job", "ssh", "store"}, steps())
+
+	// A second shutdown has nothing left to stop and leaves the store closed
+	require.NoError(t, s.Shutdown(context.Background()))
+	assert.Equal(t, []string{"request", "job", "ssh", "store"}, steps())
+}
@@ -0,0 +1,353 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHExecutor runs task commands on a remote host through an SSH connection.
+// Cancelled commands are sent SIGKILL before their session is closed; servers
+// that ignore signal requests may leave the remote process running.
+type SSHExecutor struct {
+	client   *SSHClient
+	dir      string
+	env      map[string]string
+	denySudo bool
+}
+
+var _ ide.Executor = (*SSHExecutor)(nil)
+
+// NewSSHExecutor creates an executor running commands in dir on the client's host
+func NewSSHExecutor(client *SSHClient, dir string) *SSHExecutor {
+	return &SSHExecutor{
+		client: client,
+		dir:    dir,
+		env:    make(map[string]string),
+	}
+}
+
+// DenySudo makes the executor refuse commands invoking sudo
+func (e *SSHExecutor) DenySudo() {
+	e.denySudo = true
+}
+
+// SetEnv sets an environment variable for remote commands
+func (e *SSHExecutor) SetEnv(key, value string) {
+	e.env[key] = value
+}
+
+// remoteCommand wraps command with the working directory and environment,
+// since most SSH servers reject environment requests. Variables are exported
+// so the command sees them when the shell expands it, as it does locally.
+func (e *SSHExecutor) remoteCommand(command string) string {
+	var b strings.Builder
+	if e.dir != "" {
+		fmt.Fprintf(&b, "cd %s || exit 1; ", shellQuote(e.dir))
+	}
+	if len(e.env) > 0 {
+		keys := make([]string, 0, len(e.env))
+		for k := range e.env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b.WriteString("export")
+		for _, k := range keys {
+			fmt.Fprintf(&b, " %s=%s", k, shellQuote(e.env[k]))
+		}
+		b.WriteString("; ")
+	}
+	b.WriteString(command)
+	return b.String()
+}
+
+func (e *SSHExecutor) newSession() (*ssh.Session, error) {
+	if err := e.client.Connect(); err != nil {
+		return nil, err
+	}
+	session, err := e.client.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	return session, nil
+}
+
+// Execute runs a command to completion on the remote host
+func (e *SSHExecutor) Execute(ctx context.Context, command string) (*ide.CommandResult, error) {
+	start := time.Now()
+
+	var stdout, stderr bytes.Buffer
+	process, err := e.Start(ctx, command, &stdout, &stderr)
+	if err != nil {
+		return nil, err
+	}
+	waitErr := process.Wait()
+
+	return &ide.CommandResult{
+		Success:       waitErr == nil,
+		Output:        stdout.String(),
+		Error:         stderr.String(),
+		ExitCode:      process.ExitCode(),
+		ExecutionTime: time.Since(start),
+	}, nil
+}
+
+// Start starts a command on the remote host
+func (e *SSHExecutor) Start(ctx context.Context, command string, stdout, stderr io.Writer) (ide.Process, error) {
+	if e.denySudo && usesSudo(command) {
+		return nil, fmt.Errorf("%w: %s", ErrCapabilityDisabled, CapabilitySSHSudo)
+	}
+	session, err := e.newSession()
+	if err != nil {
+		return nil, err
+	}
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	if err := session.Start(e.remoteCommand(command)); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to start remote command: %v", err)
+	}
+
+	p := &sshProcess{session: session, done: make(chan struct{}), exitCode: -1}
+	go func() {
+		select {
+		case <-ctx.Done():
+			session.Signal(ssh.SIGKILL)
+			session.Close()
+		case <-p.done:
+		}
+	}()
+	return p, nil
+}
+
+// sshProcess is a command running in an SSH session
+type sshProcess struct {
+	session  *ssh.Session
+	done     chan struct{}
+	exitCode int
+}
+
+func (p *sshProcess) Wait() error {
+	err := p.session.Wait()
+	close(p.done)
+	p.session.Close()
+
+	switch e := err.(type) {
+	case nil:
+		p.exitCode = 0
+	case *ssh.ExitError:
+		p.exitCode = e.ExitStatus()
+	}
+	return err
+}
+
+func (p *sshProcess) ExitCode() int {
+	return p.exitCode
+}
+
+func (p *sshProcess) Pid() int {
+	return 0
+}
+
+// hostExecutor pairs an executor with the name of the host it runs on
+type hostExecutor struct {
+	host     string
+	executor ide.Executor
+}
+
+// groupExecutor runs every command on all hosts of a group concurrently,
+// prefixing each output line with the host it came from
+type groupExecutor struct {
+	hosts []hostExecutor
+}
+
+var _ ide.Executor = (*groupExecutor)(nil)
+
+// Execute runs the command on every host. It succeeds only if all hosts do;
+// the exit code is the first non-zero one.
+func (g *groupExecutor) Execute(ctx context.Context, command string) (*ide.CommandResult, error) {
+	start := time.Now()
+
+	var stdout, stderr bytes.Buffer
+	process, err := g.Start(ctx, command, &stdout, &stderr)
+	if err != nil {
+		return nil, err
+	}
+	waitErr := process.Wait()
+
+	return &ide.CommandResult{
+		Success:       waitErr == nil,
+		Output:        stdout.String(),
+		Error:         stderr.String(),
+		ExitCode:      process.ExitCode(),
+		ExecutionTime: time.Since(start),
+	}, nil
+}
+
+// Start starts the command on every host, stopping those already started if
+// any host fails
+func (g *groupExecutor) Start(ctx context.Context, command string, stdout, stderr io.Writer) (ide.Process, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	group := &groupProcess{cancel: cancel}
+
+	// Hosts share the writers, so output is serialized a line at a time
+	var mu sync.Mutex
+	for _, h := range g.hosts {
+		out := &prefixWriter{w: stdout, prefix: "[" + h.host + "] ", mu: &mu}
+		errOut := &prefixWriter{w: stderr, prefix: "[" + h.host + "] ", mu: &mu}
+
+		process, err := h.executor.Start(ctx, command, out, errOut)
+		if err != nil {
+			cancel()
+			group.Wait()
+			return nil, fmt.Errorf("%s: %v", h.host, err)
+		}
+		group.members = append(group.members, groupMember{process: process, writers: []*prefixWriter{out, errOut}})
+	}
+
+	return group, nil
+}
+
+type groupMember struct {
+	process ide.Process
+	writers []*prefixWriter
+}
+
+type groupProcess struct {
+	members  []groupMember
+	cancel   context.CancelFunc
+	exitCode int
+}
+
+func (p *groupProcess) Wait() error {
+	defer p.cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(p.members))
+	for i, m := range p.members {
+		wg.Add(1)
+		go func(i int, m groupMember) {
+			defer wg.Done()
+			errs[i] = m.process.Wait()
+			for _, w := range m.writers {
+				w.Flush()
+			}
+		}(i, m)
+	}
+	wg.Wait()
+
+	p.exitCode = 0
+	var firstErr error
+	for i, m := range p.members {
+		if code := m.process.ExitCode(); code != 0 && p.exitCode == 0 {
+			p.exitCode = code
+		}
+		if errs[i] != nil && firstErr == nil {
+			firstErr = errs[i]
+		}
+	}
+	return firstErr
+}
+
+func (p *groupProcess) ExitCode() int {
+	return p.exitCode
+}
+
+func (p *groupProcess) Pid() int {
+	return 0
+}
+
+// prefixWriter writes complete lines, each with a prefix, to a shared writer
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	mu     *sync.Mutex
+	buf    bytes.Buffer
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.buf.Write(p)
+	for {
+		i := bytes.IndexByte(pw.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		pw.writeLine(pw.buf.Next(i + 1))
+	}
+	return len(p), nil
+}
+
+// Flush writes any trailing partial line
+func (pw *prefixWriter) Flush() {
+	if pw.buf.Len() > 0 {
+		pw.writeLine(append(pw.buf.Bytes(), '\n'))
+		pw.buf.Reset()
+	}
+}
+
+func (pw *prefixWriter) writeLine(line []byte) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	io.WriteString(pw.w, pw.prefix)
+	pw.w.Write(line)
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// remoteExecutor resolves a task target to an executor running on the
+// target's SSH connection or on every connection of a host group. Whether
+// sudo is allowed is decided when the task starts.
+func (s *Server) remoteExecutor(target *ide.TaskTarget, env map[string]string) (ide.Executor, error) {
+	if s.ssh == nil || !s.FeatureEnabled(FeatureSSH) {
+		return nil, fmt.Errorf("remote tasks require the %s feature", FeatureSSH)
+	}
+	if (target.Connection == "") == (target.Group == "") {
+		return nil, fmt.Errorf("target must name either a connection or a group")
+	}
+
+	ids := []string{target.Connection}
+	if target.Group != "" {
+		var ok bool
+		if ids, ok = s.ssh.Group(target.Group); !ok {
+			return nil, fmt.Errorf("host group %s not found", target.Group)
+		}
+	}
+
+	group := &groupExecutor{}
+	for _, id := range ids {
+		conn, ok := s.ssh.Client(id)
+		if !ok {
+			return nil, fmt.Errorf("connection %s not found", id)
+		}
+		client, ok := conn.(*SSHClient)
+		if !ok {
+			return nil, fmt.Errorf("connection %s cannot run remote tasks", id)
+		}
+		executor := NewSSHExecutor(client, target.Dir)
+		for k, v := range env {
+			executor.SetEnv(k, v)
+		}
+		if !s.CapabilityEnabled(CapabilitySSHSudo) {
+			executor.DenySudo()
+		}
+		group.hosts = append(group.hosts, hostExecutor{host: id, executor: executor})
+	}
+
+	if target.Connection != "" {
+		return group.hosts[0].executor, nil
+	}
+	return group, nil
+}
@@ -0,0 +1,299 @@
+package mcp
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// startTestSSHServer runs a minimal SSH server that executes commands with
+// sh -c and returns a config for connecting to it
+func startTestSSHServer(t *testing.T) SSHConfig {
+	return newTestSSHServer(t).config
+}
+
+// testSSHServer is a test SSH server whose connections can be dropped
+type testSSHServer struct {
+	config   SSHConfig
+	listener net.Listener
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	addr := listener.Addr().(*net.TCPAddr)
+	server := &testSSHServer{
+		config:   SSHConfig{Host: "127.0.0.1", Port: addr.Port, User: "test", Password: "test"},
+		listener: listener,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.mu.Lock()
+			server.conns = append(server.conns, conn)
+			server.mu.Unlock()
+			go serveTestSSHConn(conn, config)
+		}
+	}()
+	return server
+}
+
+// drop closes the open connections, as a NAT forgetting them would
+func (s *testSSHServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func serveTestSSHConn(conn net.Conn, config *ssh.ServerConfig) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go serveTestSSHRequests(sconn, reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() == "direct-tcpip" {
+			go serveTestSSHForward(newChannel)
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer channel.Close()
+			for req := range requests {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+				req.Reply(true, nil)
+
+				command := string(req.Payload[4:])
+				cmd := exec.Command("sh", "-c", command)
+				cmd.Stdout = channel
+				cmd.Stderr = channel.Stderr()
+				cmd.Run()
+
+				status := make([]byte, 4)
+				binary.BigEndian.PutUint32(status, uint32(cmd.ProcessState.ExitCode()))
+				channel.SendRequest("exit-status", false, status)
+				return
+			}
+		}()
+	}
+}
+
+// serveTestSSHForward dials the target of a direct-tcpip channel and pipes
+// the channel to it
+func serveTestSSHForward(newChannel ssh.NewChannel) {
+	var target struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	pipeTestSSHChannel(channel, conn)
+}
+
+// serveTestSSHRequests listens on the addresses of tcpip-forward requests
+// until they are cancelled or the connection closes, and rejects other
+// global requests
+func serveTestSSHRequests(conn *ssh.ServerConn, reqs <-chan *ssh.Request) {
+	listeners := make(map[string]net.Listener)
+	defer func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}()
+
+	for req := range reqs {
+		var bind struct {
+			Addr string
+			Port uint32
+		}
+		if req.Type != "tcpip-forward" && req.Type != "cancel-tcpip-forward" || ssh.Unmarshal(req.Payload, &bind) != nil {
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+			continue
+		}
+		addr := net.JoinHostPort(bind.Addr, strconv.Itoa(int(bind.Port)))
+		if req.Type == "cancel-tcpip-forward" {
+			if listener, ok := listeners[addr]; ok {
+				listener.Close()
+				delete(listeners, addr)
+			}
+			req.Reply(true, nil)
+			continue
+		}
+
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			req.Reply(false, nil)
+			continue
+		}
+		port := uint32(listener.Addr().(*net.TCPAddr).Port)
+		listeners[net.JoinHostPort(bind.Addr, strconv.Itoa(int(port)))] = listener
+		req.Reply(true, ssh.Marshal(struct{ Port uint32 }{port}))
+		go acceptTestSSHForwards(conn, listener, bind.Addr, port)
+	}
+}
+
+// acceptTestSSHForwards opens a forwarded-tcpip channel to the client for
+// every connection accepted by listener
+func acceptTestSSHForwards(conn *ssh.ServerConn, listener net.Listener, addr string, port uint32) {
+	for {
+		accepted, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			origin := accepted.RemoteAddr().(*net.TCPAddr)
+			channel, requests, err := conn.OpenChannel("forwarded-tcpip", ssh.Marshal(struct {
+				Addr       string
+				Port       uint32
+				OriginAddr string
+				OriginPort uint32
+			}{addr, port, origin.IP.String(), uint32(origin.Port)}))
+			if err != nil {
+				accepted.Close()
+				return
+			}
+			go ssh.DiscardRequests(requests)
+			pipeTestSSHChannel(channel, accepted)
+		}()
+	}
+}
+
+// pipeTestSSHChannel copies between a channel and a connection until both
+// directions end
+func pipeTestSSHChannel(channel ssh.Channel, conn net.Conn) {
+	go func() {
+		io.Copy(channel, conn)
+		channel.CloseWrite()
+	}()
+	io.Copy(conn, channel)
+	conn.Close()
+	channel.Close()
+}
+
+func TestSSHExecutor(t *testing.T) {
+	client, err := NewSSHClient(startTestSSHServer(t))
+	require.NoError(t, err)
+	defer client.Close()
+
+	executor := NewSSHExecutor(client, "/tmp")
+	executor.SetEnv("GREETING", "it's remote")
+
+	result, err := executor.Execute(context.Background(), `echo "$GREETING from $(pwd)"; echo oops >&2; exit 3`)
+	require.NoError(t, err)
+	assert.Equal(t, "it's remote from /tmp\n", result.Output)
+	assert.Equal(t, "oops\n", result.Error)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.False(t, result.Success)
+}
+
+func TestRemoteExecutor_Group(t *testing.T) {
+	s := NewServer(nil)
+	s.AddSSHHandler()
+
+	for _, id := range []string{"web-1", "web-2"} {
+		client, err := NewSSHClient(startTestSSHServer(t))
+		require.NoError(t, err)
+		s.ssh.clients[id] = client
+	}
+	defer s.ssh.CloseAll()
+	require.NoError(t, s.ssh.SetGroup("web", []string{"web-1", "web-2"}))
+
+	executor, err := s.remoteExecutor(&ide.TaskTarget{Group: "web"}, nil)
+	require.NoError(t, err)
+
+	result, err := executor.Execute(context.Background(), "echo up")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	lines := strings.Split(strings.TrimSpace(result.Output), "\n")
+	assert.ElementsMatch(t, []string{"[web-1] up", "[web-2] up"}, lines)
+
+	_, err = s.remoteExecutor(&ide.TaskTarget{Group: "db"}, nil)
+	assert.EqualError(t, err, "host group db not found")
+
+	// Remote tasks share the task manager's streaming and status handling
+	tm := ide.NewTaskManager()
+	task := &ide.Task{ID: "remote", Command: "echo " + strconv.Itoa(42)}
+	require.NoError(t, tm.StartTask(task, executor))
+
+	events, unsubscribe := tm.TaskLog("remote").Subscribe()
+	defer unsubscribe()
+	deadline := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case _, ok := <-events:
+			done = !ok
+		case <-deadline:
+			t.Fatal("task did not finish")
+		}
+	}
+
+	var output []string
+	for _, event := range tm.TaskLog("remote").Since(0) {
+		if event.Type == ide.TaskEventStdout {
+			output = append(output, event.Message)
+		}
+	}
+	assert.ElementsMatch(t, []string{"[web-1] 42", "[web-2] 42"}, output)
+	assert.Equal(t, "completed", tm.GetTask("remote").Status)
+}
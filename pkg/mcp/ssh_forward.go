@@ -0,0 +1,518 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Errors returned by port forwarding
+var (
+	ErrForwardNotFound    = errors.New("forward not found")
+	ErrInvalidForward     = errors.New("invalid forward")
+	ErrForwardUnsupported = errors.New("connection does not support port forwarding")
+)
+
+// Directions of port forwards
+const (
+	ForwardLocal  = "local"  // A port on the server reaches an address seen from the remote host
+	ForwardRemote = "remote" // A port on the remote host reaches an address next to the server
+)
+
+// defaultForwardAddr binds forwards without a listening address to a free
+// loopback port
+const defaultForwardAddr = "127.0.0.1:0"
+
+// Delays between attempts to listen again for remote forwards whose
+// connection dropped
+const (
+	forwardRetry    = 100 * time.Millisecond
+	maxForwardRetry = 30 * time.Second
+)
+
+// SSHForwardRequest represents a request to forward a port. Local forwards
+// listen on LocalAddr and dial RemoteAddr from the remote host; remote
+// forwards listen on RemoteAddr of the remote host and dial LocalAddr.
+type SSHForwardRequest struct {
+	Direction  string `json:"direction,omitempty"`   // local by default
+	LocalAddr  string `json:"local_addr,omitempty"`  // Loopback host:port on the server
+	RemoteAddr string `json:"remote_addr,omitempty"` // host:port on the remote host
+}
+
+// SSHForward is a port forwarded over an SSH connection, with its traffic
+// so far
+type SSHForward struct {
+	ID            string    `json:"id"`
+	Connection    string    `json:"connection"`
+	Direction     string    `json:"direction"`
+	LocalAddr     string    `json:"local_addr"`
+	RemoteAddr    string    `json:"remote_addr"`
+	CreatedAt     time.Time `json:"created_at"`
+	Listening     bool      `json:"listening"`      // False while a remote forward waits for its connection
+	Restarts      int       `json:"restarts"`       // Times a remote forward listened again after its connection dropped
+	Active        int       `json:"active"`         // Open forwarded connections
+	Connections   int       `json:"connections"`    // Accepted since the forward opened
+	BytesSent     int64     `json:"bytes_sent"`     // Local to remote
+	BytesReceived int64     `json:"bytes_received"` // Remote to local
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// sshForward accepts connections on one side of the SSH connection and
+// pipes each to its target on the other
+type sshForward struct {
+	info     SSHForward
+	target   string
+	listen   func() (net.Listener, error) // Listens again after the listener failed; nil for local forwards
+	dial     func(network, addr string) (net.Conn, error)
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	done     chan struct{} // Closed with the forward
+	mu       sync.Mutex
+}
+
+// checkForward validates the addresses of a forward. The server side binds
+// and dials loopback only, so forwards never expose services of one network
+// to the other beyond the hosts themselves.
+func checkForward(req SSHForwardRequest) error {
+	if req.Direction != ForwardLocal && req.Direction != ForwardRemote {
+		return fmt.Errorf("%w: unknown direction %q", ErrInvalidForward, req.Direction)
+	}
+	if _, _, err := net.SplitHostPort(req.RemoteAddr); err != nil {
+		return fmt.Errorf("%w: remote address: %v", ErrInvalidForward, err)
+	}
+	host, _, err := net.SplitHostPort(req.LocalAddr)
+	if err != nil {
+		return fmt.Errorf("%w: local address: %v", ErrInvalidForward, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("%w: local address %s is not a loopback address", ErrInvalidForward, req.LocalAddr)
+	}
+	return nil
+}
+
+// Forward opens a port forward over connection id: a local port reaching
+// an address seen from the remote host, or with ForwardRemote a port of the
+// remote host reaching an address next to the server
+func (m *SSHManager) Forward(id string, req SSHForwardRequest) (SSHForward, error) {
+	switch req.Direction {
+	case "", ForwardLocal:
+		req.Direction = ForwardLocal
+		if req.LocalAddr == "" {
+			req.LocalAddr = defaultForwardAddr
+		}
+	case ForwardRemote:
+		if req.RemoteAddr == "" {
+			req.RemoteAddr = defaultForwardAddr
+		}
+	}
+	if err := checkForward(req); err != nil {
+		return SSHForward{}, err
+	}
+
+	// Listening may reconnect with backoff, so it runs without the lock
+	client, ok := m.Client(id)
+	if !ok {
+		return SSHForward{}, fmt.Errorf("%w: %s", ErrConnectionNotFound, id)
+	}
+	f := &sshForward{
+		info: SSHForward{
+			ID:         newRequestID(),
+			Connection: id,
+			Direction:  req.Direction,
+			LocalAddr:  req.LocalAddr,
+			RemoteAddr: req.RemoteAddr,
+			CreatedAt:  time.Now(),
+			Listening:  true,
+		},
+		conns: make(map[net.Conn]struct{}),
+		done:  make(chan struct{}),
+	}
+
+	var err error
+	if req.Direction == ForwardLocal {
+		dialer, ok := client.(interface {
+			Dial(network, addr string) (net.Conn, error)
+		})
+		if !ok {
+			return SSHForward{}, fmt.Errorf("%w: %s", ErrForwardUnsupported, id)
+		}
+		if f.listener, err = net.Listen("tcp", req.LocalAddr); err != nil {
+			return SSHForward{}, err
+		}
+		f.info.LocalAddr = f.listener.Addr().String()
+		f.target, f.dial = req.RemoteAddr, dialer.Dial
+	} else {
+		listener, ok := client.(interface {
+			Listen(network, addr string) (net.Listener, error)
+		})
+		if !ok {
+			return SSHForward{}, fmt.Errorf("%w: %s", ErrForwardUnsupported, id)
+		}
+		if f.listener, err = listener.Listen("tcp", req.RemoteAddr); err != nil {
+			return SSHForward{}, err
+		}
+		// Listen again on the port bound first, even if a free one was asked
+		f.info.RemoteAddr = f.listener.Addr().String()
+		f.listen = func() (net.Listener, error) { return listener.Listen("tcp", f.info.RemoteAddr) }
+		f.target, f.dial = req.LocalAddr, net.Dial
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[id]; !ok {
+		f.listener.Close()
+		return SSHForward{}, fmt.Errorf("%w: %s", ErrConnectionNotFound, id)
+	}
+	m.forwards[f.info.ID] = f
+	go f.serve()
+	return f.status(), nil
+}
+
+// Forwards returns the forwards of connection id, oldest first
+func (m *SSHManager) Forwards(id string) ([]SSHForward, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.clients[id]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrConnectionNotFound, id)
+	}
+	return m.forwardsOf(id), nil
+}
+
+// forwardsOf returns the forwards of connection id. The caller holds m.mu.
+func (m *SSHManager) forwardsOf(id string) []SSHForward {
+	forwards := []SSHForward{}
+	for _, f := range m.forwards {
+		if f.info.Connection == id {
+			forwards = append(forwards, f.status())
+		}
+	}
+	sort.Slice(forwards, func(i, j int) bool { return forwards[i].CreatedAt.Before(forwards[j].CreatedAt) })
+	return forwards
+}
+
+// CloseForward stops a forward of connection id and closes its connections
+func (m *SSHManager) CloseForward(id, forward string) error {
+	m.mu.Lock()
+	f, ok := m.forwards[forward]
+	if !ok || f.info.Connection != id {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrForwardNotFound, forward)
+	}
+	delete(m.forwards, forward)
+	m.mu.Unlock()
+
+	// Closing a remote listener is a round trip to the remote host
+	return f.close()
+}
+
+// closeForwards stops the forwards of connection id, or of every connection
+// if id is empty. The caller holds m.mu.
+func (m *SSHManager) closeForwards(id string) {
+	for key, f := range m.forwards {
+		if id == "" || f.info.Connection == id {
+			f.close()
+			delete(m.forwards, key)
+		}
+	}
+}
+
+func (f *sshForward) serve() {
+	for {
+		f.mu.Lock()
+		listener := f.listener
+		f.mu.Unlock()
+
+		conn, err := listener.Accept()
+		if err == nil {
+			go f.pipe(conn)
+			continue
+		}
+		if f.listen == nil || !f.relisten(err) {
+			return
+		}
+	}
+}
+
+// relisten opens the listener of a remote forward again after it failed, as
+// it does when the connection drops, retrying with backoff until it succeeds
+// or the forward is closed
+func (f *sshForward) relisten(cause error) bool {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return false
+	}
+	f.info.Listening = false
+	f.info.LastError = cause.Error()
+	failed := f.listener
+	f.mu.Unlock()
+	// Closing the failed listener releases what it holds, such as its
+	// connection of a pool
+	failed.Close()
+
+	delay := forwardRetry
+	for {
+		listener, err := f.listen()
+		f.mu.Lock()
+		if f.closed {
+			f.mu.Unlock()
+			if err == nil {
+				listener.Close()
+			}
+			return false
+		}
+		if err == nil {
+			f.listener = listener
+			f.info.Listening = true
+			f.info.Restarts++
+			f.mu.Unlock()
+			return true
+		}
+		f.info.LastError = err.Error()
+		f.mu.Unlock()
+
+		select {
+		case <-f.done:
+			return false
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxForwardRetry {
+			delay = maxForwardRetry
+		}
+	}
+}
+
+// pipe copies between an accepted connection and a new one to the target
+// until either side closes
+func (f *sshForward) pipe(accepted net.Conn) {
+	dialed, err := f.dial("tcp", f.target)
+	if err != nil {
+		f.mu.Lock()
+		f.info.LastError = err.Error()
+		f.mu.Unlock()
+		accepted.Close()
+		return
+	}
+	if !f.track(accepted, dialed) {
+		accepted.Close()
+		dialed.Close()
+		return
+	}
+
+	local, remote := accepted, dialed
+	if f.info.Direction == ForwardRemote {
+		local, remote = dialed, accepted
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	transfer := func(dst, src net.Conn, count *int64) {
+		defer wg.Done()
+		io.Copy(&countingWriter{w: dst, n: count, mu: &f.mu}, src)
+		// Closing both ends unblocks the copy in the other direction
+		local.Close()
+		remote.Close()
+	}
+	go transfer(remote, local, &f.info.BytesSent)
+	go transfer(local, remote, &f.info.BytesReceived)
+	wg.Wait()
+
+	f.mu.Lock()
+	delete(f.conns, local)
+	delete(f.conns, remote)
+	f.info.Active--
+	f.mu.Unlock()
+}
+
+// track registers the ends of a forwarded connection, unless the forward is
+// already closed
+func (f *sshForward) track(accepted, dialed net.Conn) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return false
+	}
+	f.conns[accepted] = struct{}{}
+	f.conns[dialed] = struct{}{}
+	f.info.Active++
+	f.info.Connections++
+	return true
+}
+
+func (f *sshForward) status() SSHForward {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.info
+}
+
+func (f *sshForward) close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	close(f.done)
+	err := f.listener.Close()
+	for conn := range f.conns {
+		conn.Close()
+	}
+	f.info.Listening = false
+	return err
+}
+
+// countingWriter adds the bytes written through it to a counter guarded by mu
+type countingWriter struct {
+	w  io.Writer
+	n  *int64
+	mu *sync.Mutex
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.mu.Lock()
+	*c.n += int64(n)
+	c.mu.Unlock()
+	return n, err
+}
+
+// addSSHForwarding registers the port forwarding routes and ssh.forward*
+// JSON-RPC methods
+func (s *Server) addSSHForwarding(manager *SSHManager) {
+	s.handle(Route{
+		Method: "POST", Path: "/ssh/{id}/forwards", Summary: "Forward a port between the server and the remote host over an SSH connection",
+		Request: SSHForwardRequest{}, Response: SSHForward{}, Status: http.StatusCreated,
+	}, handleSSHForward(manager))
+	s.handle(Route{
+		Method: "GET", Path: "/ssh/{id}/forwards", Summary: "List the port forwards of an SSH connection with their traffic",
+		Response: []SSHForward{},
+	}, handleListSSHForwards(manager))
+	s.handle(Route{
+		Method: "DELETE", Path: "/ssh/{id}/forwards/{forward}", Summary: "Close a port forward",
+		Response: map[string]string{},
+	}, handleCloseSSHForward(manager))
+
+	s.handleRPC(RPCMethod{
+		Name: "ssh.forward", Summary: "Forward a port between the server and the remote host over an SSH connection", Params: sshForwardParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p sshForwardParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		forward, err := manager.Forward(p.ID, p.SSHForwardRequest)
+		if err != nil {
+			return nil, err
+		}
+		LoggerFromContext(ctx).Info("ssh forward opened", "connection", p.ID, "forward", forward.ID, "direction", forward.Direction,
+			"local_addr", forward.LocalAddr, "remote_addr", forward.RemoteAddr)
+		return forward, nil
+	})
+	s.handleRPC(RPCMethod{
+		Name: "ssh.forwards", Summary: "List the port forwards of an SSH connection", Params: idParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p idParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return manager.Forwards(p.ID)
+	})
+	s.handleRPC(RPCMethod{
+		Name: "ssh.unforward", Summary: "Close a port forward", Params: sshUnforwardParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p sshUnforwardParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if err := manager.CloseForward(p.ID, p.Forward); err != nil {
+			return nil, err
+		}
+		LoggerFromContext(ctx).Info("ssh forward closed", "connection", p.ID, "forward", p.Forward)
+		return map[string]string{"id": p.Forward, "status": "closed"}, nil
+	})
+}
+
+type sshForwardParams struct {
+	ID string `json:"id"`
+	SSHForwardRequest
+}
+
+type sshUnforwardParams struct {
+	ID      string `json:"id"`
+	Forward string `json:"forward"`
+}
+
+// forwardStatus maps errors of port forwarding to HTTP statuses
+func forwardStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrConnectionNotFound), errors.Is(err, ErrForwardNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidForward):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrForwardUnsupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func handleSSHForward(manager *SSHManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		var req SSHForwardRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		forward, err := manager.Forward(id, req)
+		if err != nil {
+			writeError(w, forwardStatus(err), err)
+			return
+		}
+		LoggerFromContext(r.Context()).Info("ssh forward opened", "connection", id, "forward", forward.ID, "direction", forward.Direction,
+			"local_addr", forward.LocalAddr, "remote_addr", forward.RemoteAddr)
+
+		writeJSON(w, http.StatusCreated, forward)
+	}
+}
+
+func handleListSSHForwards(manager *SSHManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		forwards, err := manager.Forwards(mux.Vars(r)["id"])
+		if err != nil {
+			writeError(w, forwardStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, forwards)
+	}
+}
+
+func handleCloseSSHForward(manager *SSHManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		if err := manager.CloseForward(vars["id"], vars["forward"]); err != nil {
+			writeError(w, forwardStatus(err), err)
+			return
+		}
+		LoggerFromContext(r.Context()).Info("ssh forward closed", "connection", vars["id"], "forward", vars["forward"])
+
+		writeJSON(w, http.StatusOK, map[string]string{
+			"id":     vars["forward"],
+			"status": "closed",
+		})
+	}
+}
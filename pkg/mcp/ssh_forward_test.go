@@ -0,0 +1,314 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startEchoServer runs a TCP server that echoes what it reads
+func startEchoServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestSSHManager_Forward(t *testing.T) {
+	s := NewServer(nil)
+	s.AddSSHHandler()
+	client, err := NewSSHClient(startTestSSHServer(t))
+	require.NoError(t, err)
+	s.ssh.clients["db"] = client
+	s.ssh.clients["fake"] = struct{ SSHConn }{}
+	echo := startEchoServer(t)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("POST", "/v1/ssh/db/forwards", `{"remote_addr":"`+echo+`"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var forward SSHForward
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &forward))
+	assert.Equal(t, "db", forward.Connection)
+	assert.Equal(t, echo, forward.RemoteAddr)
+	assert.True(t, strings.HasPrefix(forward.LocalAddr, "127.0.0.1:"))
+
+	conn, err := net.Dial("tcp", forward.LocalAddr)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(conn, reply)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(reply))
+
+	var forwards []SSHForward
+	require.Eventually(t, func() bool {
+		rec := do("GET", "/v1/ssh/db/forwards", "")
+		forwards = nil
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &forwards))
+		return len(forwards) == 1 && forwards[0].BytesReceived == 4
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, forward.ID, forwards[0].ID)
+	assert.Equal(t, int64(4), forwards[0].BytesSent)
+	assert.Equal(t, 1, forwards[0].Active)
+	assert.Equal(t, 1, forwards[0].Connections)
+
+	// Closing the forward closes its open connections
+	assert.Equal(t, http.StatusOK, do("DELETE", "/v1/ssh/db/forwards/"+forward.ID, "").Code)
+	_, err = conn.Read(reply)
+	assert.Error(t, err)
+	conn.Close()
+	_, err = net.Dial("tcp", forward.LocalAddr)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/v1/ssh/db/forwards/"+forward.ID, "").Code)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"public local address", "/v1/ssh/db/forwards", `{"local_addr":"0.0.0.0:0","remote_addr":"` + echo + `"}`, http.StatusBadRequest},
+		{"missing remote port", "/v1/ssh/db/forwards", `{"remote_addr":"db.internal"}`, http.StatusBadRequest},
+		{"unknown connection", "/v1/ssh/missing/forwards", `{"remote_addr":"` + echo + `"}`, http.StatusNotFound},
+		{"connection without dialing", "/v1/ssh/fake/forwards", `{"remote_addr":"` + echo + `"}`, http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.status, do("POST", tt.path, tt.body).Code)
+		})
+	}
+	delete(s.ssh.clients, "fake")
+
+	// Disconnecting stops the forwards of the connection
+	forward, err = s.ssh.Forward("db", SSHForwardRequest{LocalAddr: "localhost:0", RemoteAddr: echo})
+	require.NoError(t, err)
+	require.NoError(t, s.ssh.Disconnect("db"))
+	_, err = net.Dial("tcp", forward.LocalAddr)
+	assert.Error(t, err)
+	assert.Empty(t, s.ssh.forwards)
+}
+
+func TestSSHManager_RemoteForward(t *testing.T) {
+	s := NewServer(nil)
+	s.AddSSHHandler()
+	server := newTestSSHServer(t)
+	config := server.config
+	config.KeepAlive = -1
+	client, err := NewSSHClient(config)
+	require.NoError(t, err)
+	s.ssh.clients["app"] = client
+	defer s.ssh.CloseAll()
+	echo := startEchoServer(t)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+	// ping sends a message through the port the remote host listens on
+	ping := func(addr string) {
+		t.Helper()
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = conn.Write([]byte("ping"))
+		require.NoError(t, err)
+		reply := make([]byte, 4)
+		_, err = io.ReadFull(conn, reply)
+		require.NoError(t, err)
+		assert.Equal(t, "ping", string(reply))
+	}
+	current := func() SSHForward {
+		forwards, err := s.ssh.Forwards("app")
+		require.NoError(t, err)
+		require.Len(t, forwards, 1)
+		return forwards[0]
+	}
+
+	rec := do("POST", "/v1/ssh/app/forwards", `{"direction":"remote","local_addr":"`+echo+`"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var forward SSHForward
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &forward))
+	assert.Equal(t, ForwardRemote, forward.Direction)
+	assert.Equal(t, echo, forward.LocalAddr)
+	assert.True(t, forward.Listening)
+	assert.NotEqual(t, "127.0.0.1:0", forward.RemoteAddr)
+
+	ping(forward.RemoteAddr)
+	require.Eventually(t, func() bool {
+		status := current()
+		return status.BytesSent == 4 && status.BytesReceived == 4 && status.Active == 0
+	}, 5*time.Second, 5*time.Millisecond)
+
+	// The forward listens again on the same port once the connection is back
+	server.drop()
+	require.Eventually(t, func() bool {
+		status := current()
+		return status.Listening && status.Restarts == 1
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, forward.RemoteAddr, current().RemoteAddr)
+	ping(forward.RemoteAddr)
+
+	rec = do("GET", "/v1/ssh/app/health", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var health SSHHealth
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &health))
+	assert.Equal(t, 1, health.Forwards)
+	assert.Zero(t, health.ForwardsDown)
+	assert.Equal(t, 1, health.Reconnects)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"unknown direction", `{"direction":"sideways","local_addr":"` + echo + `"}`},
+		{"missing local address", `{"direction":"remote"}`},
+		{"public local address", `{"direction":"remote","local_addr":"10.0.0.1:5432"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, http.StatusBadRequest, do("POST", "/v1/ssh/app/forwards", tt.body).Code)
+		})
+	}
+
+	assert.Equal(t, http.StatusOK, do("DELETE", "/v1/ssh/app/forwards/"+forward.ID, "").Code)
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", forward.RemoteAddr)
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	}, 5*time.Second, 5*time.Millisecond)
+	health, err = s.ssh.Health("app")
+	require.NoError(t, err)
+	assert.Zero(t, health.Forwards)
+}
+
+// listenConn is a connection whose remote listeners come from listen
+type listenConn struct {
+	SSHConn
+	listen func(network, addr string) (net.Listener, error)
+}
+
+func (c *listenConn) Listen(network, addr string) (net.Listener, error) {
+	return c.listen(network, addr)
+}
+
+func (c *listenConn) Close() error { return nil }
+
+func TestSSHManager_StalledListen(t *testing.T) {
+	m := NewSSHManager()
+	defer m.CloseAll()
+	echo := startEchoServer(t)
+
+	// The first listen succeeds; later ones stall like a reconnect with backoff
+	release := make(chan struct{})
+	var first net.Listener
+	m.clients["app"] = &listenConn{listen: func(network, addr string) (net.Listener, error) {
+		if first == nil {
+			var err error
+			first, err = net.Listen(network, addr)
+			return first, err
+		}
+		<-release
+		return net.Listen(network, addr)
+	}}
+	m.clients["db"] = &listenConn{listen: func(network, addr string) (net.Listener, error) {
+		<-release
+		return nil, errors.New("refused")
+	}}
+
+	forward, err := m.Forward("app", SSHForwardRequest{Direction: ForwardRemote, LocalAddr: echo})
+	require.NoError(t, err)
+
+	// Losing the remote listener starts a relisten that stalls
+	first.Close()
+	require.Eventually(t, func() bool {
+		forwards, err := m.Forwards("app")
+		require.NoError(t, err)
+		return !forwards[0].Listening
+	}, 5*time.Second, 5*time.Millisecond)
+
+	// So does opening another forward
+	opened := make(chan error, 1)
+	go func() {
+		_, err := m.Forward("db", SSHForwardRequest{Direction: ForwardRemote, LocalAddr: echo})
+		opened <- err
+	}()
+
+	health := make(chan SSHHealth, 1)
+	go func() {
+		h, err := m.Health("app")
+		require.NoError(t, err)
+		health <- h
+	}()
+	select {
+	case h := <-health:
+		assert.Equal(t, 1, h.Forwards)
+		assert.Equal(t, 1, h.ForwardsDown)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Health blocked by a stalled listen")
+	}
+
+	close(release)
+	assert.Error(t, <-opened)
+	require.Eventually(t, func() bool {
+		forwards, err := m.Forwards("app")
+		require.NoError(t, err)
+		return forwards[0].Listening && forwards[0].Restarts == 1
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, forward.RemoteAddr, first.Addr().String())
+}
+
+func TestSSHManager_RemoteForwardOnPool(t *testing.T) {
+	server := newTestSSHServer(t)
+	config := server.config
+	config.KeepAlive = -1
+	config.PoolSize = 2
+	m := NewSSHManager()
+	require.NoError(t, m.Connect("app", config))
+	defer m.CloseAll()
+	pool := m.clients["app"].(*SSHPool)
+
+	_, err := m.Forward("app", SSHForwardRequest{Direction: ForwardRemote, LocalAddr: startEchoServer(t)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, pool.Health().Busy)
+
+	// Listening again after a drop does not leave the failed listener busy
+	server.drop()
+	require.Eventually(t, func() bool {
+		forwards, err := m.Forwards("app")
+		require.NoError(t, err)
+		return forwards[0].Listening && forwards[0].Restarts == 1
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, pool.Health().Busy)
+}
@@ -0,0 +1,214 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// maxSSHPoolSize bounds the connections of a pool; servers limit the
+// connections of a user too
+const maxSSHPoolSize = 32
+
+// SSHPool is an SSHConn spreading the commands and transfers of a host over
+// up to a pool size of connections. Connections are opened as parallel
+// commands need them and reused after; each command runs on the connection
+// with the fewest running.
+type SSHPool struct {
+	config SSHConfig
+	size   int
+
+	mu      sync.Mutex
+	clients []*pooledSSHClient
+	closed  bool
+}
+
+type pooledSSHClient struct {
+	*SSHClient
+	busy int // Commands and transfers running
+}
+
+// NewSSHPool opens the first connection of a pool of config.PoolSize
+func NewSSHPool(config SSHConfig) (*SSHPool, error) {
+	if config.PoolSize > maxSSHPoolSize {
+		return nil, fmt.Errorf("pool size must be at most %d", maxSSHPoolSize)
+	}
+	size := config.PoolSize
+	if size < 1 {
+		size = 1
+	}
+	client, err := NewSSHClient(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Connect(); err != nil {
+		return nil, err
+	}
+	return &SSHPool{
+		config:  config,
+		size:    size,
+		clients: []*pooledSSHClient{{SSHClient: client}},
+	}, nil
+}
+
+// acquire returns the connection to run a command on, adding one to the
+// pool when all are busy and it has room
+func (p *SSHPool) acquire() (*pooledSSHClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, errors.New("connection pool is closed")
+	}
+
+	least := p.clients[0]
+	for _, c := range p.clients[1:] {
+		if c.busy < least.busy {
+			least = c
+		}
+	}
+	if least.busy > 0 && len(p.clients) < p.size {
+		client, err := NewSSHClient(p.config)
+		if err != nil {
+			return nil, err
+		}
+		// Connected by the command, outside the lock
+		least = &pooledSSHClient{SSHClient: client}
+		p.clients = append(p.clients, least)
+	}
+	least.busy++
+	return least, nil
+}
+
+func (p *SSHPool) release(c *pooledSSHClient) {
+	p.mu.Lock()
+	c.busy--
+	p.mu.Unlock()
+}
+
+// ExecuteCommand runs a command on the least busy connection
+func (p *SSHPool) ExecuteCommand(command string) (*CommandResult, error) {
+	c, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(c)
+	return c.ExecuteCommand(command)
+}
+
+// UploadFile uploads a file over the least busy connection
+func (p *SSHPool) UploadFile(localPath, remotePath string) error {
+	c, err := p.acquire()
+	if err != nil {
+		return err
+	}
+	defer p.release(c)
+	return c.UploadFile(localPath, remotePath)
+}
+
+// DownloadFile downloads a file over the least busy connection
+func (p *SSHPool) DownloadFile(remotePath, localPath string) error {
+	c, err := p.acquire()
+	if err != nil {
+		return err
+	}
+	defer p.release(c)
+	return c.DownloadFile(remotePath, localPath)
+}
+
+// Dial opens a TCP connection to addr from the remote host over the least
+// busy connection, which counts as busy until the TCP connection closes
+func (p *SSHPool) Dial(network, addr string) (net.Conn, error) {
+	c, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := c.Dial(network, addr)
+	if err != nil {
+		p.release(c)
+		return nil, err
+	}
+	return &pooledConn{Conn: conn, release: func() { p.release(c) }}, nil
+}
+
+// Listen asks the remote host to listen on addr over the least busy
+// connection, which counts as busy until the listener closes
+func (p *SSHPool) Listen(network, addr string) (net.Listener, error) {
+	c, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := c.Listen(network, addr)
+	if err != nil {
+		p.release(c)
+		return nil, err
+	}
+	return &pooledListener{Listener: listener, release: func() { p.release(c) }}, nil
+}
+
+// pooledConn and pooledListener release their pool connection once closed
+type pooledConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *pooledConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
+
+type pooledListener struct {
+	net.Listener
+	once    sync.Once
+	release func()
+}
+
+func (l *pooledListener) Close() error {
+	l.once.Do(l.release)
+	return l.Listener.Close()
+}
+
+// Close closes every connection of the pool
+func (p *SSHPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	var errs []error
+	for _, c := range p.clients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Health returns the state of the pool: connected while any connection is,
+// with the reconnects of all and the last error of any. The connections are
+// checked after releasing the lock of the pool, so commands are not held up.
+func (p *SSHPool) Health() SSHHealth {
+	health := SSHHealth{PoolSize: p.size}
+	p.mu.Lock()
+	clients := make([]*SSHClient, len(p.clients))
+	for i, c := range p.clients {
+		clients[i] = c.SSHClient
+		health.Busy += c.busy
+	}
+	p.mu.Unlock()
+
+	for _, c := range clients {
+		h := c.Health()
+		if h.Connected {
+			health.Connected = true
+			health.Connections++
+		}
+		health.Reconnects += h.Reconnects
+		if h.LastKeepAlive != nil && (health.LastKeepAlive == nil || h.LastKeepAlive.After(*health.LastKeepAlive)) {
+			health.LastKeepAlive = h.LastKeepAlive
+		}
+		if h.LastError != "" {
+			health.LastError = h.LastError
+		}
+	}
+	return health
+}
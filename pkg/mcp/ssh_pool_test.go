@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSHPool(t *testing.T) {
+	server := newTestSSHServer(t)
+	config := server.config
+	config.PoolSize = 3
+	conn, err := dialSSH(config)
+	require.NoError(t, err)
+	pool, ok := conn.(*SSHPool)
+	require.True(t, ok)
+	defer pool.Close()
+
+	// Serial commands reuse the first connection
+	for i := 0; i < 3; i++ {
+		result, err := pool.ExecuteCommand("echo serial")
+		require.NoError(t, err)
+		assert.Equal(t, "serial\n", result.Stdout)
+	}
+	assert.Len(t, pool.clients, 1)
+
+	// Parallel commands open connections up to the pool size
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	results := make([]string, 6)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-release
+			result, err := pool.ExecuteCommand(fmt.Sprintf("sleep 0.1; echo %d", i))
+			if assert.NoError(t, err) {
+				results[i] = result.Stdout
+			}
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+	for i, stdout := range results {
+		assert.Equal(t, fmt.Sprintf("%d\n", i), stdout)
+	}
+	assert.Len(t, pool.clients, 3)
+
+	health := pool.Health()
+	assert.True(t, health.Connected)
+	assert.Equal(t, 3, health.PoolSize)
+	assert.Equal(t, 3, health.Connections)
+	assert.Zero(t, health.Busy)
+
+	require.NoError(t, pool.Close())
+	_, err = pool.ExecuteCommand("echo closed")
+	assert.Error(t, err)
+
+	config.PoolSize = maxSSHPoolSize + 1
+	_, err = dialSSH(config)
+	assert.Error(t, err)
+}
+
+func TestSSHPool_ForwardsHoldConnections(t *testing.T) {
+	server := newTestSSHServer(t)
+	config := server.config
+	config.PoolSize = 2
+	pool, err := NewSSHPool(config)
+	require.NoError(t, err)
+	defer pool.Close()
+	echo := startEchoServer(t)
+
+	// A forwarded connection keeps its pool connection busy while open, so
+	// commands go to another one
+	conn, err := pool.Dial("tcp", echo)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pool.Health().Busy)
+	_, err = pool.ExecuteCommand("echo beside")
+	require.NoError(t, err)
+	assert.Len(t, pool.clients, 2)
+	assert.Equal(t, 1, pool.clients[0].busy)
+	assert.Zero(t, pool.clients[1].busy)
+
+	require.NoError(t, conn.Close())
+	conn.Close() // Releases only once
+	assert.Zero(t, pool.Health().Busy)
+
+	listener, err := pool.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	assert.Equal(t, 1, pool.Health().Busy)
+	require.NoError(t, listener.Close())
+	assert.Zero(t, pool.Health().Busy)
+}
+
+func TestSSHClient_ClosedStaysClosed(t *testing.T) {
+	client, err := NewSSHClient(startTestSSHServer(t))
+	require.NoError(t, err)
+	_, err = client.ExecuteCommand("echo open")
+	require.NoError(t, err)
+
+	require.NoError(t, client.Close())
+	_, err = client.ExecuteCommand("echo closed")
+	assert.ErrorContains(t, err, errSSHClientClosed.Error())
+	assert.False(t, client.Health().Connected)
+
+	// Connect reopens it deliberately
+	require.NoError(t, client.Connect())
+	defer client.Close()
+	result, err := client.ExecuteCommand("echo reopened")
+	require.NoError(t, err)
+	assert.Equal(t, "reopened\n", result.Stdout)
+}
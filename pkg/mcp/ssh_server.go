@@ -1,17 +1,40 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"net/http"
 	"sync"
 )
 
+// Errors returned by SSHManager
+var (
+	ErrConnectionNotFound = errors.New("connection not found")
+	ErrConnectionExists   = errors.New("connection already exists")
+)
+
+// SSHConn is an open SSH connection. *SSHClient is the real implementation;
+// tests substitute fakes through WithSSHDialer.
+type SSHConn interface {
+	ExecuteCommand(command string) (*CommandResult, error)
+	UploadFile(localPath, remotePath string) error
+	DownloadFile(remotePath, localPath string) error
+	Close() error
+}
+
+// SSHDialer opens a connection to the host described by config
+type SSHDialer func(config SSHConfig) (SSHConn, error)
+
 // SSHManager manages SSH connections
 type SSHManager struct {
-	clients map[string]*SSHClient
-	mu      sync.RWMutex
+	clients  map[string]SSHConn
+	groups   map[string][]string // Host groups of connection IDs
+	forwards map[string]*sshForward
+	dial     SSHDialer
+	mu       sync.RWMutex
 }
 
 // SSHConnectionRequest represents an SSH connection request
@@ -25,6 +48,11 @@ type SSHCommandRequest struct {
 	Command string `json:"command"`
 }
 
+// SSHGroupRequest represents a request to define a host group
+type SSHGroupRequest struct {
+	Connections []string `json:"connections"`
+}
+
 // SSHFileTransferRequest represents a file transfer request
 type SSHFileTransferRequest struct {
 	LocalPath  string `json:"local_path"`
@@ -34,56 +62,393 @@ type SSHFileTransferRequest struct {
 // NewSSHManager creates a new SSH manager
 func NewSSHManager() *SSHManager {
 	return &SSHManager{
-		clients: make(map[string]*SSHClient),
+		clients:  make(map[string]SSHConn),
+		groups:   make(map[string][]string),
+		forwards: make(map[string]*sshForward),
+		dial:     dialSSH,
+	}
+}
+
+// dialSSH connects an SSHClient, or an SSHPool of several
+func dialSSH(config SSHConfig) (SSHConn, error) {
+	if config.PoolSize > 1 {
+		return NewSSHPool(config)
+	}
+	client, err := NewSSHClient(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Connect(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Client returns the connection with the given ID
+func (m *SSHManager) Client(id string) (SSHConn, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	client, ok := m.clients[id]
+	return client, ok
+}
+
+// Connect opens a connection and registers it under id
+func (m *SSHManager) Connect(id string, config SSHConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.clients[id]; exists {
+		return fmt.Errorf("%w: %s", ErrConnectionExists, id)
+	}
+
+	client, err := m.dial(config)
+	if err != nil {
+		return err
+	}
+
+	m.clients[id] = client
+	return nil
+}
+
+// connectSSH opens a connection and ties it to the lease of ctx
+func (s *Server) connectSSH(ctx context.Context, manager *SSHManager, id string, config SSHConfig) error {
+	if err := manager.Connect(id, config); err != nil {
+		return err
+	}
+	return s.leaseResource(ctx, LeaseSSH, id, func() error { return manager.Disconnect(id) })
+}
+
+// Disconnect closes a connection and forgets it
+func (m *SSHManager) Disconnect(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, exists := m.clients[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrConnectionNotFound, id)
+	}
+	m.closeForwards(id)
+	if err := client.Close(); err != nil {
+		return err
+	}
+
+	delete(m.clients, id)
+	return nil
+}
+
+// Health returns the state of a connection and its port forwards.
+// Connections that do not track it, such as fakes, are reported connected.
+func (m *SSHManager) Health(id string) (SSHHealth, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	client, ok := m.clients[id]
+	if !ok {
+		return SSHHealth{}, fmt.Errorf("%w: %s", ErrConnectionNotFound, id)
+	}
+	health := SSHHealth{Connected: true}
+	if reporter, ok := client.(interface{ Health() SSHHealth }); ok {
+		health = reporter.Health()
+	}
+	for _, forward := range m.forwardsOf(id) {
+		health.Forwards++
+		if !forward.Listening {
+			health.ForwardsDown++
+		}
+	}
+	return health, nil
+}
+
+// Group returns the connection IDs of a host group
+func (m *SSHManager) Group(name string) ([]string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids, ok := m.groups[name]
+	return append([]string(nil), ids...), ok
+}
+
+// SetGroup defines a host group. Connections are resolved when a task runs,
+// so they need not be connected yet.
+func (m *SSHManager) SetGroup(name string, ids []string) error {
+	if len(ids) == 0 {
+		return fmt.Errorf("host group %s has no connections", name)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.groups[name] = append([]string(nil), ids...)
+	return nil
+}
+
+// CloseAll closes every managed SSH connection
+func (m *SSHManager) CloseAll() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closeForwards("")
+	var errs []error
+	for id, client := range m.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("connection %s: %w", id, err))
+		}
+		delete(m.clients, id)
 	}
+	return errors.Join(errs...)
 }
 
 // AddSSHHandler adds SSH handling capabilities to the MCP server
 func (s *Server) AddSSHHandler() {
 	manager := NewSSHManager()
+	if s.sshDialer != nil {
+		manager.dial = s.sshDialer
+	}
+	s.ssh = manager
 
 	// Connection management
-	s.router.HandleFunc("/ssh/connect", handleSSHConnect(manager)).Methods("POST")
-	s.router.HandleFunc("/ssh/{id}", handleSSHDisconnect(manager)).Methods("DELETE")
+	s.handle(Route{
+		Method: "POST", Path: "/ssh/connect", Summary: "Open an SSH connection",
+		Request: SSHConnectionRequest{}, Response: map[string]string{}, Status: http.StatusCreated, Timeout: LongRunningTimeout,
+	}, handleSSHConnect(s, manager))
+	s.handle(Route{
+		Method: "DELETE", Path: "/ssh/{id}", Summary: "Close an SSH connection",
+		Response: map[string]string{},
+	}, handleSSHDisconnect(s, manager))
+
+	s.handle(Route{
+		Method: "GET", Path: "/ssh/{id}/health", Summary: "Get the state of an SSH connection: keepalives, reconnects and errors",
+		Response: SSHHealth{},
+	}, handleSSHHealth(manager))
 
 	// Command execution
-	s.router.HandleFunc("/ssh/{id}/exec", handleSSHExec(manager)).Methods("POST")
+	s.handle(Route{
+		Method: "POST", Path: "/ssh/{id}/exec", Summary: "Run a command over SSH",
+		Request: SSHCommandRequest{}, Response: CommandResult{}, Timeout: LongRunningTimeout,
+	}, handleSSHExec(s, manager))
 
 	// File transfer
-	s.router.HandleFunc("/ssh/{id}/upload", handleSSHUpload(manager)).Methods("POST")
-	s.router.HandleFunc("/ssh/{id}/download", handleSSHDownload(manager)).Methods("POST")
+	s.handle(Route{
+		Method: "POST", Path: "/ssh/{id}/upload", Summary: "Upload a file to the remote host",
+		Request: SSHFileTransferRequest{}, Response: map[string]string{}, Timeout: LongRunningTimeout,
+	}, handleSSHUpload(manager))
+	s.handle(Route{
+		Method: "POST", Path: "/ssh/{id}/download", Summary: "Download a file from the remote host",
+		Request: SSHFileTransferRequest{}, Response: map[string]string{}, Timeout: LongRunningTimeout,
+	}, handleSSHDownload(manager))
+
+	// Host groups, used as targets of remote tasks
+	s.handle(Route{
+		Method: "GET", Path: "/ssh/groups", Summary: "List host groups",
+		Response: map[string][]string{},
+	}, handleListSSHGroups(manager))
+	s.handle(Route{
+		Method: "PUT", Path: "/ssh/groups/{name}", Summary: "Define a host group",
+		Request: SSHGroupRequest{}, Response: map[string]interface{}{},
+	}, handleSetSSHGroup(manager))
+	s.handle(Route{
+		Method: "DELETE", Path: "/ssh/groups/{name}", Summary: "Delete a host group",
+		Response: map[string]string{},
+	}, handleDeleteSSHGroup(manager))
+
+	s.addSSHForwarding(manager)
+	s.addSSHMethods(manager)
+}
+
+// sshParams are the params of the ssh.* JSON-RPC methods; each method uses
+// the fields of the matching HTTP request
+type sshParams struct {
+	ID string `json:"id"`
+	SSHConnectionRequest
+	SSHCommandRequest
+	SSHFileTransferRequest
+}
+
+// sshExecParams and sshTransferParams document the params of single methods
+type sshExecParams struct {
+	ID string `json:"id"`
+	SSHCommandRequest
+}
+
+type sshTransferParams struct {
+	ID string `json:"id"`
+	SSHFileTransferRequest
+}
+
+// addSSHMethods registers the ssh.* JSON-RPC methods
+func (s *Server) addSSHMethods(manager *SSHManager) {
+	// client decodes the params and looks up the connection they name
+	client := func(params json.RawMessage) (SSHConn, *sshParams, error) {
+		var p sshParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, nil, err
+		}
+		client, ok := manager.Client(p.ID)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %s", ErrConnectionNotFound, p.ID)
+		}
+		return client, &p, nil
+	}
+
+	s.handleRPC(RPCMethod{
+		Name: "ssh.connect", Summary: "Open an SSH connection", Params: SSHConnectionRequest{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p sshParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if err := s.connectSSH(ctx, manager, p.ID, p.Config); err != nil {
+			return nil, err
+		}
+		LoggerFromContext(ctx).Info("ssh connected", "connection", p.ID, "host", p.Config.Host, "user", p.Config.User)
+		return map[string]string{"id": p.ID, "status": "connected"}, nil
+	})
+	s.handleRPC(RPCMethod{
+		Name: "ssh.disconnect", Summary: "Close an SSH connection", Params: idParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p sshParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if err := manager.Disconnect(p.ID); err != nil {
+			return nil, err
+		}
+		s.unleaseResource(LeaseSSH, p.ID)
+		LoggerFromContext(ctx).Info("ssh disconnected", "connection", p.ID)
+		return map[string]string{"id": p.ID, "status": "disconnected"}, nil
+	})
+	s.handleRPC(RPCMethod{
+		Name: "ssh.health", Summary: "Get the state of an SSH connection", Params: idParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p sshParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return manager.Health(p.ID)
+	})
+	s.handleRPC(RPCMethod{
+		Name: "ssh.exec", Summary: "Run a command over an SSH connection", Params: sshExecParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		client, p, err := client(params)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkSudo(p.Command); err != nil {
+			return nil, err
+		}
+		done, err := s.meterMinutes(ctx, MetricSSHExecMinutes)
+		if err != nil {
+			return nil, err
+		}
+		result, err := client.ExecuteCommand(p.Command)
+		done()
+		if err != nil {
+			return nil, err
+		}
+		LoggerFromContext(ctx).Info("ssh command executed", "connection", p.ID, "command", p.Command, "exit_code", result.ExitCode)
+		return result, nil
+	})
+	s.handleRPC(RPCMethod{
+		Name: "ssh.upload", Summary: "Upload a file over an SSH connection", Params: sshTransferParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		client, p, err := client(params)
+		if err != nil {
+			return nil, err
+		}
+		if err := client.UploadFile(p.LocalPath, p.RemotePath); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "uploaded", "path": p.RemotePath}, nil
+	})
+	s.handleRPC(RPCMethod{
+		Name: "ssh.download", Summary: "Download a file over an SSH connection", Params: sshTransferParams{},
+	}, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		client, p, err := client(params)
+		if err != nil {
+			return nil, err
+		}
+		if err := client.DownloadFile(p.RemotePath, p.LocalPath); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "downloaded", "path": p.LocalPath}, nil
+	})
 }
 
-func handleSSHConnect(manager *SSHManager) http.HandlerFunc {
+func handleListSSHGroups(manager *SSHManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var req SSHConnectionRequest
+		manager.mu.RLock()
+		groups := make(map[string][]string, len(manager.groups))
+		for name, ids := range manager.groups {
+			groups[name] = ids
+		}
+		manager.mu.RUnlock()
+
+		writeJSON(w, http.StatusOK, groups)
+	}
+}
+
+func handleSetSSHGroup(manager *SSHManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+
+		var req SSHGroupRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			writeError(w, http.StatusBadRequest, err)
 			return
 		}
 
+		if err := manager.SetGroup(name, req.Connections); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"name":        name,
+			"connections": req.Connections,
+		})
+	}
+}
+
+func handleDeleteSSHGroup(manager *SSHManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+
 		manager.mu.Lock()
-		if _, exists := manager.clients[req.ID]; exists {
-			manager.mu.Unlock()
-			writeError(w, http.StatusConflict, fmt.Errorf("connection with ID %s already exists", req.ID))
+		_, exists := manager.groups[name]
+		delete(manager.groups, name)
+		manager.mu.Unlock()
+
+		if !exists {
+			writeError(w, http.StatusNotFound, fmt.Errorf("host group not found"))
 			return
 		}
 
-		client, err := NewSSHClient(req.Config)
-		if err != nil {
-			manager.mu.Unlock()
-			writeError(w, http.StatusInternalServerError, err)
+		writeJSON(w, http.StatusOK, map[string]string{
+			"name":   name,
+			"status": "deleted",
+		})
+	}
+}
+
+func handleSSHConnect(s *Server, manager *SSHManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SSHConnectionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		if err := client.Connect(); err != nil {
-			manager.mu.Unlock()
-			writeError(w, http.StatusInternalServerError, err)
+		if err := s.connectSSH(r.Context(), manager, req.ID, req.Config); err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, ErrConnectionExists):
+				status = http.StatusConflict
+			case errors.Is(err, ErrLeaseNotFound):
+				status = http.StatusNotFound
+			}
+			writeError(w, status, err)
 			return
 		}
 
-		manager.clients[req.ID] = client
-		manager.mu.Unlock()
+		LoggerFromContext(r.Context()).Info("ssh connected",
+			"connection", req.ID, "host", req.Config.Host, "user", req.Config.User)
 
 		writeJSON(w, http.StatusCreated, map[string]string{
 			"id":     req.ID,
@@ -92,26 +457,21 @@ func handleSSHConnect(manager *SSHManager) http.HandlerFunc {
 	}
 }
 
-func handleSSHDisconnect(manager *SSHManager) http.HandlerFunc {
+func handleSSHDisconnect(s *Server, manager *SSHManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := mux.Vars(r)["id"]
 
-		manager.mu.Lock()
-		client, exists := manager.clients[id]
-		if !exists {
-			manager.mu.Unlock()
-			writeError(w, http.StatusNotFound, fmt.Errorf("connection not found"))
-			return
-		}
-
-		if err := client.Close(); err != nil {
-			manager.mu.Unlock()
-			writeError(w, http.StatusInternalServerError, err)
+		if err := manager.Disconnect(id); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrConnectionNotFound) {
+				status = http.StatusNotFound
+			}
+			writeError(w, status, err)
 			return
 		}
+		s.unleaseResource(LeaseSSH, id)
 
-		delete(manager.clients, id)
-		manager.mu.Unlock()
+		LoggerFromContext(r.Context()).Info("ssh disconnected", "connection", id)
 
 		writeJSON(w, http.StatusOK, map[string]string{
 			"id":     id,
@@ -120,7 +480,18 @@ func handleSSHDisconnect(manager *SSHManager) http.HandlerFunc {
 	}
 }
 
-func handleSSHExec(manager *SSHManager) http.HandlerFunc {
+func handleSSHHealth(manager *SSHManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health, err := manager.Health(mux.Vars(r)["id"])
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, health)
+	}
+}
+
+func handleSSHExec(s *Server, manager *SSHManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := mux.Vars(r)["id"]
 
@@ -129,21 +500,31 @@ func handleSSHExec(manager *SSHManager) http.HandlerFunc {
 			writeError(w, http.StatusBadRequest, err)
 			return
 		}
+		if err := s.checkSudo(req.Command); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
 
-		manager.mu.RLock()
-		client, exists := manager.clients[id]
-		manager.mu.RUnlock()
-
+		client, exists := manager.Client(id)
 		if !exists {
-			writeError(w, http.StatusNotFound, fmt.Errorf("connection not found"))
+			writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrConnectionNotFound, id))
 			return
 		}
 
+		done, err := s.meterMinutes(r.Context(), MetricSSHExecMinutes)
+		if err != nil {
+			writeError(w, http.StatusTooManyRequests, err)
+			return
+		}
+		logger := LoggerFromContext(r.Context()).With("connection", id)
 		result, err := client.ExecuteCommand(req.Command)
+		done()
 		if err != nil {
+			logger.Error("ssh command failed", "command", req.Command, "error", err)
 			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
+		logger.Info("ssh command executed", "command", req.Command, "exit_code", result.ExitCode)
 
 		writeJSON(w, http.StatusOK, result)
 	}
@@ -159,19 +540,19 @@ func handleSSHUpload(manager *SSHManager) http.HandlerFunc {
 			return
 		}
 
-		manager.mu.RLock()
-		client, exists := manager.clients[id]
-		manager.mu.RUnlock()
-
+		client, exists := manager.Client(id)
 		if !exists {
-			writeError(w, http.StatusNotFound, fmt.Errorf("connection not found"))
+			writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrConnectionNotFound, id))
 			return
 		}
 
+		logger := LoggerFromContext(r.Context()).With("connection", id)
 		if err := client.UploadFile(req.LocalPath, req.RemotePath); err != nil {
+			logger.Error("ssh upload failed", "local_path", req.LocalPath, "remote_path", req.RemotePath, "error", err)
 			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
+		logger.Info("ssh upload completed", "local_path", req.LocalPath, "remote_path", req.RemotePath)
 
 		writeJSON(w, http.StatusOK, map[string]string{
 			"status": "uploaded",
@@ -190,19 +571,19 @@ func handleSSHDownload(manager *SSHManager) http.HandlerFunc {
 			return
 		}
 
-		manager.mu.RLock()
-		client, exists := manager.clients[id]
-		manager.mu.RUnlock()
-
+		client, exists := manager.Client(id)
 		if !exists {
-			writeError(w, http.StatusNotFound, fmt.Errorf("connection not found"))
+			writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrConnectionNotFound, id))
 			return
 		}
 
+		logger := LoggerFromContext(r.Context()).With("connection", id)
 		if err := client.DownloadFile(req.RemotePath, req.LocalPath); err != nil {
+			logger.Error("ssh download failed", "remote_path", req.RemotePath, "local_path", req.LocalPath, "error", err)
 			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
+		logger.Info("ssh download completed", "remote_path", req.RemotePath, "local_path", req.LocalPath)
 
 		writeJSON(w, http.StatusOK, map[string]string{
 			"status": "downloaded",
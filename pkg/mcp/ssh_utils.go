@@ -2,19 +2,40 @@ package mcp
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"github.com/pkg/sftp"
 	"io"
 	_ "io/ioutil"
+	"net"
 	"os"
 	_ "path/filepath"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"golang.org/x/crypto/ssh"
 )
 
-// SSHClient represents an SSH connection client
+// Defaults of SSH connections
+const (
+	defaultSSHKeepAlive         = 30 * time.Second
+	defaultSSHReconnectAttempts = 3
+	defaultSSHReconnectBackoff  = time.Second
+	maxMissedKeepAlives         = 3
+)
+
+// errKeepAliveTimeout is the error of keepalive requests left unanswered
+var errKeepAliveTimeout = errors.New("keepalive request timed out")
+
+// errSSHClientClosed is returned by clients closed deliberately, which are
+// not reconnected
+var errSSHClientClosed = errors.New("ssh connection is closed")
+
+// SSHClient represents an SSH connection client. Connections answer
+// keepalive requests while open; those that stop are dropped, and
+// reconnected before the next command.
 type SSHClient struct {
 	config    *ssh.ClientConfig
 	client    *ssh.Client
@@ -23,6 +44,30 @@ type SSHClient struct {
 	port      int
 	connected bool
 	mu        sync.Mutex
+
+	keepAlive time.Duration
+	attempts  int
+	backoff   time.Duration // Before the second attempt to reconnect, doubling after
+	stop      chan struct{} // Stops the keepalive requests of the connection
+	dialing   chan struct{} // Closed once the connection being dialed is established or failed
+	dropped   bool          // The connection died and was not reconnected yet
+	closed    bool          // Closed by Close, until connected again by Connect
+	health    SSHHealth
+}
+
+// SSHHealth is the state of an SSH connection
+type SSHHealth struct {
+	Connected     bool       `json:"connected"`
+	LastKeepAlive *time.Time `json:"last_keepalive,omitempty"` // Last answered keepalive request
+	Reconnects    int        `json:"reconnects"`               // Times the connection was reconnected after dying
+	LastError     string     `json:"last_error,omitempty"`     // Why the connection died or failed to reconnect
+	// Of pools: their size, the connections open and the commands running
+	PoolSize    int `json:"pool_size,omitempty"`
+	Connections int `json:"connections,omitempty"`
+	Busy        int `json:"busy,omitempty"`
+	// Port forwards of the connection, and those not listening
+	Forwards     int `json:"forwards,omitempty"`
+	ForwardsDown int `json:"forwards_down,omitempty"`
 }
 
 // SSHConfig represents SSH connection configuration
@@ -33,6 +78,16 @@ type SSHConfig struct {
 	Password      string `json:"password,omitempty"`
 	PrivateKey    string `json:"private_key,omitempty"`
 	KeyPassphrase string `json:"key_passphrase,omitempty"`
+	// KeepAlive is the interval of keepalive requests in seconds, 30 when
+	// zero; a negative interval disables them. Connections missing three
+	// in a row are dropped.
+	KeepAlive int `json:"keepalive_seconds,omitempty"`
+	// ReconnectAttempts are the tries to reconnect a dropped connection
+	// before a command, 3 when zero
+	ReconnectAttempts int `json:"reconnect_attempts,omitempty"`
+	// PoolSize is the number of connections parallel commands may open,
+	// at most 32; 0 and 1 keep a single connection
+	PoolSize int `json:"pool_size,omitempty"`
 }
 
 // CommandResult represents the result of an SSH command execution
@@ -72,41 +127,221 @@ func NewSSHClient(config SSHConfig) (*SSHClient, error) {
 		Timeout:         30 * time.Second,
 	}
 
+	keepAlive := time.Duration(config.KeepAlive) * time.Second
+	if config.KeepAlive == 0 {
+		keepAlive = defaultSSHKeepAlive
+	}
+	attempts := config.ReconnectAttempts
+	if attempts <= 0 {
+		attempts = defaultSSHReconnectAttempts
+	}
+
 	return &SSHClient{
-		config: sshConfig,
-		host:   config.Host,
-		port:   config.Port,
+		config:    sshConfig,
+		host:      config.Host,
+		port:      config.Port,
+		keepAlive: keepAlive,
+		attempts:  attempts,
+		backoff:   defaultSSHReconnectBackoff,
 	}, nil
 }
 
-// Connect establishes an SSH connection
+// Connect establishes an SSH connection, reopening a closed client
 func (c *SSHClient) Connect() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.closed = false
+	return c.connect()
+}
 
+// connect establishes the connection unless the client was closed. The
+// caller holds c.mu, which is released while dialing so health checks and
+// Close are not held up by an unreachable host; other callers wait for the
+// dial to finish.
+func (c *SSHClient) connect() error {
+	for c.dialing != nil {
+		dialing := c.dialing
+		c.mu.Unlock()
+		<-dialing
+		c.mu.Lock()
+	}
+	if c.closed {
+		return errSSHClientClosed
+	}
 	if c.connected {
 		return nil
 	}
 
+	dialing := make(chan struct{})
+	c.dialing = dialing
+	c.mu.Unlock()
 	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", c.host, c.port), c.config)
+	c.mu.Lock()
+	c.dialing = nil
+	close(dialing)
 	if err != nil {
+		c.health.LastError = err.Error()
 		return fmt.Errorf("failed to dial: %v", err)
 	}
+	if c.closed {
+		// Closed while dialing
+		client.Close()
+		return errSSHClientClosed
+	}
 
 	c.client = client
 	c.connected = true
+	c.health.Connected = true
+	if c.dropped {
+		c.dropped = false
+		c.health.Reconnects++
+	}
+	if c.keepAlive > 0 {
+		c.stop = make(chan struct{})
+		go c.keepAliveLoop(client, c.stop)
+	}
 	return nil
 }
 
-// Close closes the SSH connection
+// Health returns the state of the connection
+func (c *SSHClient) Health() SSHHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	health := c.health
+	if health.LastKeepAlive != nil {
+		last := *health.LastKeepAlive
+		health.LastKeepAlive = &last
+	}
+	return health
+}
+
+// keepAliveLoop sends keepalive requests over a connection until stopped,
+// and drops the connection once it fails to answer them
+func (c *SSHClient) keepAliveLoop(client *ssh.Client, stop chan struct{}) {
+	ticker := time.NewTicker(c.keepAlive)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		err := sendKeepAlive(client, c.keepAlive)
+		switch {
+		case err == nil:
+			now := time.Now()
+			c.mu.Lock()
+			c.health.LastKeepAlive = &now
+			c.mu.Unlock()
+			missed = 0
+		case errors.Is(err, errKeepAliveTimeout):
+			if missed++; missed < maxMissedKeepAlives {
+				continue
+			}
+			c.drop(client, err)
+			return
+		default:
+			c.drop(client, err)
+			return
+		}
+	}
+}
+
+// sendKeepAlive sends a keepalive request, waiting at most timeout for the
+// answer. Servers answer requests they do not know with a failure, which
+// shows they are alive all the same.
+func sendKeepAlive(client *ssh.Client, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return errKeepAliveTimeout
+	}
+}
+
+// drop closes a connection found dead, unless it was replaced already
+func (c *SSHClient) drop(client *ssh.Client, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.connected || c.client != client {
+		return
+	}
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+	client.Close()
+	c.connected = false
+	c.dropped = true
+	c.health.Connected = false
+	c.health.LastError = fmt.Sprintf("connection lost: %v", err)
+}
+
+// live returns the connection, connecting again with backoff when it was
+// closed or dropped
+func (c *SSHClient) live() (*ssh.Client, error) {
+	delay := c.backoff
+	var err error
+	for attempt := 0; attempt < c.attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		c.mu.Lock()
+		err = c.connect()
+		client := c.client
+		c.mu.Unlock()
+		if err == nil {
+			return client, nil
+		}
+		if errors.Is(err, errSSHClientClosed) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// newSession opens a session, reconnecting once when the connection turns
+// out to be dead before keepalive requests noticed
+func (c *SSHClient) newSession() (*ssh.Session, error) {
+	client, err := c.live()
+	if err != nil {
+		return nil, err
+	}
+	session, err := client.NewSession()
+	if err == nil {
+		return session, nil
+	}
+	c.drop(client, err)
+	if client, err = c.live(); err != nil {
+		return nil, err
+	}
+	return client.NewSession()
+}
+
+// Close closes the SSH connection. Commands fail after, rather than
+// reconnecting, until Connect is called again.
 func (c *SSHClient) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.closed = true
 	if !c.connected {
 		return nil
 	}
 
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
 	if c.session != nil {
 		c.session.Close()
 		c.session = nil
@@ -117,16 +352,13 @@ func (c *SSHClient) Close() error {
 	}
 
 	c.connected = false
+	c.health.Connected = false
 	return nil
 }
 
 // ExecuteCommand executes a command over SSH
 func (c *SSHClient) ExecuteCommand(command string) (*CommandResult, error) {
-	if err := c.Connect(); err != nil {
-		return nil, err
-	}
-
-	session, err := c.client.NewSession()
+	session, err := c.newSession()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %v", err)
 	}
@@ -152,14 +384,48 @@ func (c *SSHClient) ExecuteCommand(command string) (*CommandResult, error) {
 	}, nil
 }
 
+// Dial opens a TCP connection to addr from the remote host, reconnecting
+// first if the connection dropped
+func (c *SSHClient) Dial(network, addr string) (net.Conn, error) {
+	client, err := c.live()
+	if err != nil {
+		return nil, err
+	}
+	return client.Dial(network, addr)
+}
+
+// Listen asks the remote host to listen on addr and forward the
+// connections it accepts, reconnecting first if the connection dropped. A
+// refused request is only retried when the connection proves dead.
+func (c *SSHClient) Listen(network, addr string) (net.Listener, error) {
+	client, err := c.live()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := client.Listen(network, addr)
+	if err == nil {
+		return listener, nil
+	}
+	probe := sendKeepAlive(client, c.config.Timeout)
+	if probe == nil {
+		return nil, err
+	}
+	c.drop(client, probe)
+	if client, err = c.live(); err != nil {
+		return nil, err
+	}
+	return client.Listen(network, addr)
+}
+
 // UploadFile uploads a file to the remote server
 func (c *SSHClient) UploadFile(localPath, remotePath string) error {
-	if err := c.Connect(); err != nil {
+	client, err := c.live()
+	if err != nil {
 		return err
 	}
 
 	// Create SFTP client
-	sftpClient, err := c.createSFTPClient()
+	sftpClient, err := sftp.NewClient(client)
 	if err != nil {
 		return err
 	}
@@ -190,12 +456,13 @@ func (c *SSHClient) UploadFile(localPath, remotePath string) error {
 
 // DownloadFile downloads a file from the remote server
 func (c *SSHClient) DownloadFile(remotePath, localPath string) error {
-	if err := c.Connect(); err != nil {
+	client, err := c.live()
+	if err != nil {
 		return err
 	}
 
 	// Create SFTP client
-	sftpClient, err := c.createSFTPClient()
+	sftpClient, err := sftp.NewClient(client)
 	if err != nil {
 		return err
 	}
@@ -224,6 +491,60 @@ func (c *SSHClient) DownloadFile(remotePath, localPath string) error {
 	return nil
 }
 
+// usesSudo reports whether a shell command may invoke sudo. It errs on the
+// side of caution: any word sudo, or a path ending in /sudo, counts, both as
+// the shell reads the words, with quotes and escapes removed, and as they
+// are written, which splits quoted command substitutions into words too.
+func usesSudo(command string) bool {
+	words := strings.FieldsFunc(command, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(";&|()`$'\"", r)
+	})
+	for _, word := range append(words, shellWords(command)...) {
+		if word == "sudo" || strings.HasSuffix(word, "/sudo") {
+			return true
+		}
+	}
+	return false
+}
+
+// shellWords splits a command into words the way a shell does after
+// removing quotes and backslashes, so "su""do" and s\udo are both sudo.
+// Operators, substitutions and brace expansions end words.
+func shellWords(command string) []string {
+	var words []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, r := range command {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case unicode.IsSpace(r) || strings.ContainsRune(";&|()`$<>{},", r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
 // Helper functions
 
 func parsePrivateKey(keyData string, passphrase string) (ssh.Signer, error) {
@@ -244,7 +565,3 @@ func parsePrivateKey(keyData string, passphrase string) (ssh.Signer, error) {
 
 	return key, nil
 }
-
-func (c *SSHClient) createSFTPClient() (*sftp.Client, error) {
-	return sftp.NewClient(c.client)
-}
@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSHClient_ReconnectsBeforeCommands(t *testing.T) {
+	server := newTestSSHServer(t)
+	config := server.config
+	config.KeepAlive = -1
+	client, err := NewSSHClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := client.ExecuteCommand("echo before")
+	require.NoError(t, err)
+	assert.Equal(t, "before\n", result.Stdout)
+
+	// The dead connection is only noticed when opening a session on it
+	server.drop()
+	result, err = client.ExecuteCommand("echo after")
+	require.NoError(t, err)
+	assert.Equal(t, "after\n", result.Stdout)
+
+	health := client.Health()
+	assert.True(t, health.Connected)
+	assert.Equal(t, 1, health.Reconnects)
+	assert.Contains(t, health.LastError, "connection lost")
+}
+
+func TestSSHClient_KeepAlive(t *testing.T) {
+	server := newTestSSHServer(t)
+	client, err := NewSSHClient(server.config)
+	require.NoError(t, err)
+	client.keepAlive = 10 * time.Millisecond
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	require.Eventually(t, func() bool { return client.Health().LastKeepAlive != nil }, 5*time.Second, 5*time.Millisecond)
+	assert.True(t, client.Health().Connected)
+
+	server.drop()
+	require.Eventually(t, func() bool { return !client.Health().Connected }, 5*time.Second, 5*time.Millisecond)
+	assert.Contains(t, client.Health().LastError, "connection lost")
+
+	result, err := client.ExecuteCommand("echo back")
+	require.NoError(t, err)
+	assert.Equal(t, "back\n", result.Stdout)
+	assert.Equal(t, 1, client.Health().Reconnects)
+	assert.True(t, client.Health().Connected)
+}
+
+func TestSSHClient_ReconnectGivesUp(t *testing.T) {
+	server := newTestSSHServer(t)
+	config := server.config
+	config.KeepAlive = -1
+	config.ReconnectAttempts = 2
+	client, err := NewSSHClient(config)
+	require.NoError(t, err)
+	client.backoff = time.Millisecond
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	server.listener.Close()
+	server.drop()
+	_, err = client.ExecuteCommand("echo never")
+	require.Error(t, err)
+	health := client.Health()
+	assert.False(t, health.Connected)
+	assert.Contains(t, health.LastError, "connection refused")
+}
+
+func TestSSHClient_HealthWhileDialing(t *testing.T) {
+	// The server accepts connections but never answers the handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	client, err := NewSSHClient(SSHConfig{
+		Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port, User: "test", Password: "secret", KeepAlive: -1,
+	})
+	require.NoError(t, err)
+	connected := make(chan error, 1)
+	go func() { connected <- client.Connect() }()
+	conn := <-accepted
+	defer conn.Close()
+
+	// Health and Close do not wait for the dial
+	health := make(chan SSHHealth, 1)
+	go func() { health <- client.Health() }()
+	select {
+	case h := <-health:
+		assert.False(t, h.Connected)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Health waited for the dial")
+	}
+	require.NoError(t, client.Close())
+
+	conn.Close()
+	assert.Error(t, <-connected)
+	assert.False(t, client.Health().Connected)
+}
@@ -0,0 +1,592 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MCPProtocolVersions are the Model Context Protocol revisions ServeStdio
+// negotiates, newest first
+var MCPProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// MCPServerName identifies the server to MCP hosts
+const MCPServerName = "go-mcp"
+
+// maxStdioMessage bounds the size of a single message read from stdin
+const maxStdioMessage = 16 << 20
+
+type mcpImplementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// MCPConfig switches the capabilities offered to MCP hosts over every
+// transport. Hosts are not offered what is off, and its methods are not
+// found, so they can fall back to what the server has.
+type MCPConfig struct {
+	Tools     bool `yaml:"tools"`     // JSON-RPC methods of enabled features
+	Resources bool `yaml:"resources"` // Stored contexts
+	Prompts   bool `yaml:"prompts"`
+	Logging   bool `yaml:"logging"`  // Logs of the host's requests, from the level it sets
+	Sampling  bool `yaml:"sampling"` // Asking the host LLM, when the host supports it
+	Roots     bool `yaml:"roots"`    // Moving the workspace to the directories the host grants
+}
+
+// offers reports whether the capability serving a method is on. Methods of
+// no capability, such as ping, are always served.
+func (c MCPConfig) offers(method string) bool {
+	switch namespace, _, _ := strings.Cut(method, "/"); namespace {
+	case "tools":
+		return c.Tools
+	case "resources":
+		return c.Resources
+	case "prompts":
+		return c.Prompts
+	case "logging":
+		return c.Logging
+	default:
+		return true
+	}
+}
+
+type mcpInitializeParams struct {
+	ProtocolVersion string                     `json:"protocolVersion"`
+	Capabilities    map[string]json.RawMessage `json:"capabilities"`
+	ClientInfo      mcpImplementation          `json:"clientInfo"`
+}
+
+type mcpInitializeResult struct {
+	ProtocolVersion string            `json:"protocolVersion"`
+	Capabilities    mcpCapabilities   `json:"capabilities"`
+	ServerInfo      mcpImplementation `json:"serverInfo"`
+}
+
+// mcpCapabilities lists the capabilities of the server. Hosts must not use
+// those left out.
+type mcpCapabilities struct {
+	Tools     *mcpListCapability      `json:"tools,omitempty"`
+	Resources *mcpResourcesCapability `json:"resources,omitempty"`
+	Prompts   *mcpListCapability      `json:"prompts,omitempty"`
+	Logging   *struct{}               `json:"logging,omitempty"`
+}
+
+type mcpListCapability struct {
+	ListChanged bool `json:"listChanged"`
+}
+
+type mcpResourcesCapability struct {
+	Subscribe   bool `json:"subscribe"`
+	ListChanged bool `json:"listChanged"`
+}
+
+// mcpNotification is a message to the host that expects no response
+type mcpNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// MCPTool is a JSON-RPC method offered to MCP hosts as a tool
+type MCPTool struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	InputSchema *Schema `json:"inputSchema"`
+	method      string
+	function    string // Registered function called instead of a method
+}
+
+type mcpToolCallParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError"`
+}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpCancelledParams struct {
+	RequestID json.RawMessage `json:"requestId"`
+	Reason    string          `json:"reason"`
+}
+
+// mcpSession is the state of a connection to an MCP host
+type mcpSession struct {
+	s       *Server
+	methods map[string]rpcMethod
+	send    func(message interface{})               // Set by the transport
+	notify  func(method string, params interface{}) // Set by the transport
+	tenant  string
+	lease   string // Holds the browsers, SSH connections and tasks the host creates
+
+	mu                 sync.Mutex
+	initialized        bool
+	protocolVersion    string
+	clientCapabilities map[string]json.RawMessage
+	logLevel           *slog.Level                   // Logs sent to the host, none until it sets a level
+	inflight           map[string]context.CancelFunc // By compacted request ID
+	subscriptions      map[string]bool               // Resource URIs
+	pending            map[string]chan *RPCResponse  // Requests to the host by compacted ID
+	lastRequestID      int
+}
+
+// toolFeed tells subscribers that the tools on offer may have changed:
+// functions or methods were registered, or features toggled. Changes in
+// quick succession reach a subscriber once.
+type toolFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+// subscribe returns a channel receiving changes and a function to
+// unsubscribe, which closes the channel
+func (f *toolFeed) subscribe() (<-chan struct{}, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subscribers == nil {
+		f.subscribers = make(map[chan struct{}]struct{})
+	}
+	ch := make(chan struct{}, 1)
+	f.subscribers[ch] = struct{}{}
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subscribers[ch]; ok {
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (f *toolFeed) publish() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default: // A change is already pending
+		}
+	}
+}
+
+// notifyTools tells the host to list tools again after changes until
+// changes is closed
+func (m *mcpSession) notifyTools(changes <-chan struct{}, notify func(method string, params interface{})) {
+	for range changes {
+		m.mu.Lock()
+		initialized := m.initialized
+		m.mu.Unlock()
+		if initialized && m.s.config.MCP.Tools {
+			notify("notifications/tools/list_changed", nil)
+		}
+	}
+}
+
+// watch forwards changes of contexts and tools to the host. The returned
+// function stops it, waiting for notifications under way.
+func (m *mcpSession) watch() (stop func()) {
+	events, unsubscribeContexts := m.s.contexts.subscribe()
+	changes, unsubscribeTools := m.s.tools.subscribe()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		m.notifyContexts(events, m.notify)
+	}()
+	go func() {
+		defer wg.Done()
+		m.notifyTools(changes, m.notify)
+	}()
+	return func() {
+		unsubscribeContexts()
+		unsubscribeTools()
+		wg.Wait()
+	}
+}
+
+// MCPTools returns the JSON-RPC methods of enabled features and the
+// registered functions as MCP tools, sorted by name. Tool names replace the
+// dots of method names, which not every host accepts, by underscores.
+func (s *Server) MCPTools() []MCPTool {
+	s.mu.Lock()
+	methods := make([]RPCMethod, 0, len(s.rpcMethods))
+	for _, m := range s.rpcMethods {
+		methods = append(methods, m.RPCMethod)
+	}
+	s.mu.Unlock()
+
+	tools := make([]MCPTool, 0, len(methods))
+	for _, m := range methods {
+		if s.checkFeature(rpcFeature(m.Name)) != nil || s.checkDependency(rpcDependency(m.Name)) != nil {
+			continue
+		}
+		schema := &Schema{Type: "object"}
+		if m.Params != nil {
+			schema = newInlineSchemaGenerator().schemaFor(reflect.TypeOf(m.Params))
+		}
+		tools = append(tools, MCPTool{
+			Name:        strings.ReplaceAll(m.Name, ".", "_"),
+			Description: m.Summary,
+			InputSchema: schema,
+			method:      m.Name,
+		})
+	}
+	if s.functions != nil && s.checkFeature(FeatureFunctions) == nil {
+		names := make(map[string]bool, len(tools))
+		for _, tool := range tools {
+			names[tool.Name] = true
+		}
+		for _, tool := range s.functions.tools() {
+			if !names[tool.Name] { // Methods take precedence over functions of the same name
+				tools = append(tools, tool)
+			}
+		}
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// ServeStdio speaks the Model Context Protocol over newline delimited
+// JSON-RPC messages read from r and written to w, so MCP hosts can launch
+// the server as a subprocess. The JSON-RPC methods of enabled features and
+// registered functions are offered as tools, contexts as resources hosts may
+// subscribe to, and prompt templates as prompts. Requests run concurrently;
+// it returns once r reaches EOF and pending requests are answered, or when
+// ctx is done.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, loggerKey{}, s.logger))
+	defer cancel()
+	var wg sync.WaitGroup
+
+	session := s.newMCPSession("")
+	defer session.end()
+	var writeMu sync.Mutex
+	write := func(v interface{}) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			s.logger.Error("failed to encode mcp message", "error", err)
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			s.logger.Error("failed to write mcp message", "error", err)
+		}
+	}
+	session.send = write
+	session.notify = func(method string, params interface{}) {
+		write(&mcpNotification{JSONRPC: JSONRPCVersion, Method: method, Params: params})
+	}
+
+	defer session.watch()()
+
+	// Reads block, so they run apart from the loop watching ctx
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64<<10), maxStdioMessage)
+		for scanner.Scan() {
+			select {
+			case lines <- bytes.Clone(scanner.Bytes()):
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case err := <-readErr:
+			wg.Wait()
+			return err
+		case line := <-lines:
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if resp := session.handleMessage(ctx, line); resp != nil {
+					write(resp)
+				}
+			}()
+		}
+	}
+}
+
+func (s *Server) newMCPSession(tenant string) *mcpSession {
+	m := &mcpSession{
+		s:             s,
+		send:          func(interface{}) {},
+		notify:        func(string, interface{}) {},
+		tenant:        tenant,
+		lease:         s.leases.Create(tenant, 0).ID,
+		inflight:      make(map[string]context.CancelFunc),
+		subscriptions: make(map[string]bool),
+		pending:       make(map[string]chan *RPCResponse),
+	}
+	m.methods = map[string]rpcMethod{
+		"initialize":                m.initialize,
+		"ping":                      m.ping,
+		"notifications/initialized": m.rootsChanged,
+		"notifications/cancelled":   m.cancelled,
+		"tools/list":                m.listTools,
+		"tools/call":                m.callTool,
+		"resources/list":            m.listResources,
+		"resources/read":            m.readResource,
+		"resources/templates/list":  m.listResourceTemplates,
+		"resources/subscribe":       m.subscribe,
+		"resources/unsubscribe":     m.unsubscribe,
+		"prompts/list":              m.listPrompts,
+		"prompts/get":               m.getPrompt,
+		"logging/setLevel":          m.setLogLevel,
+
+		"notifications/roots/list_changed": m.rootsChanged,
+	}
+	return m
+}
+
+// end releases the browsers, SSH connections and tasks the host left open
+// when it disconnects
+func (m *mcpSession) end() {
+	m.s.leases.Release(m.tenant, m.lease)
+}
+
+// handleMessage answers a request or batch, returning nil when there is
+// nothing to answer
+func (m *mcpSession) handleMessage(ctx context.Context, data []byte) interface{} {
+	parseError := &RPCResponse{
+		JSONRPC: JSONRPCVersion,
+		Error:   &RPCError{Code: RPCParseError, Message: "parse error"},
+		ID:      json.RawMessage("null"),
+	}
+	if !json.Valid(data) {
+		return parseError
+	}
+	if data[0] != '[' {
+		if m.resolve(data) {
+			return nil
+		}
+		if resp := m.handleRequest(ctx, data); resp != nil {
+			return resp
+		}
+		return nil
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return parseError
+	}
+	var responses []*RPCResponse
+	for _, raw := range batch {
+		if m.resolve(raw) {
+			continue
+		}
+		if resp := m.handleRequest(ctx, raw); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	return responses
+}
+
+// handleRequest runs a request cancellable by notifications/cancelled.
+// Cancelled requests get no response.
+func (m *mcpSession) handleRequest(ctx context.Context, raw json.RawMessage) *RPCResponse {
+	var head struct {
+		ID json.RawMessage `json:"id"`
+	}
+	json.Unmarshal(raw, &head)
+	key := requestKey(head.ID)
+
+	if key != "" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		m.mu.Lock()
+		m.inflight[key] = cancel
+		m.mu.Unlock()
+		defer func() {
+			m.mu.Lock()
+			delete(m.inflight, key)
+			m.mu.Unlock()
+		}()
+	}
+
+	if m.s.config.MCP.Logging {
+		ctx = context.WithValue(ctx, loggerKey{}, slog.New(&mcpLogHandler{Handler: LoggerFromContext(ctx).Handler(), session: m}))
+	}
+	ctx = withSampler(withLease(ctx, m.lease), m)
+	resp := m.s.dispatch(ctx, raw, m.lookup)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return resp
+}
+
+// requestKey normalizes a request ID for lookups, "" for notifications
+func requestKey(id json.RawMessage) string {
+	var b bytes.Buffer
+	if len(id) == 0 || json.Compact(&b, id) != nil || b.String() == "null" {
+		return ""
+	}
+	return b.String()
+}
+
+// lookup finds a method of the session. Until the host initialized the
+// session, only initialize, ping and notifications are served. Methods of
+// capabilities switched off in the config are not found.
+func (m *mcpSession) lookup(name string) (rpcMethod, bool) {
+	method, ok := m.methods[name]
+	if !ok || !m.s.config.MCP.offers(name) {
+		return nil, false
+	}
+	m.mu.Lock()
+	initialized := m.initialized
+	m.mu.Unlock()
+	if !initialized && name != "initialize" && name != "ping" && !strings.HasPrefix(name, "notifications/") {
+		return func(context.Context, json.RawMessage) (interface{}, error) {
+			return nil, &RPCError{Code: RPCInvalidRequest, Message: "session not initialized"}
+		}, true
+	}
+	return method, true
+}
+
+// initialize negotiates the protocol version, answering with the requested
+// one when supported and the newest otherwise, and advertises capabilities
+func (m *mcpSession) initialize(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p mcpInitializeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	version := MCPProtocolVersions[0]
+	if containsString(MCPProtocolVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+	clientCapabilities := make([]string, 0, len(p.Capabilities))
+	for name := range p.Capabilities {
+		clientCapabilities = append(clientCapabilities, name)
+	}
+	sort.Strings(clientCapabilities)
+
+	m.mu.Lock()
+	m.initialized = true
+	m.protocolVersion = version
+	m.clientCapabilities = p.Capabilities
+	m.mu.Unlock()
+	LoggerFromContext(ctx).Info("mcp session initialized", "client", p.ClientInfo.Name, "client_version", p.ClientInfo.Version,
+		"protocol_version", version, "client_capabilities", clientCapabilities)
+
+	return &mcpInitializeResult{
+		ProtocolVersion: version,
+		Capabilities:    m.s.offeredCapabilities(),
+		ServerInfo:      mcpImplementation{Name: MCPServerName, Version: APIVersion},
+	}, nil
+}
+
+// offeredCapabilities returns the capabilities switched on in the config.
+// Tools are offered while enabled features provide any.
+func (s *Server) offeredCapabilities() mcpCapabilities {
+	config := s.config.MCP
+	var caps mcpCapabilities
+	if config.Tools && len(s.MCPTools()) > 0 {
+		caps.Tools = &mcpListCapability{ListChanged: true}
+	}
+	if config.Resources {
+		caps.Resources = &mcpResourcesCapability{Subscribe: true, ListChanged: true}
+	}
+	if config.Prompts {
+		caps.Prompts = &mcpListCapability{ListChanged: true}
+	}
+	if config.Logging {
+		caps.Logging = &struct{}{}
+	}
+	return caps
+}
+
+func (m *mcpSession) ping(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	return struct{}{}, nil
+}
+
+func (m *mcpSession) cancelled(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p mcpCancelledParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	cancel, ok := m.inflight[requestKey(p.RequestID)]
+	m.mu.Unlock()
+	if ok {
+		LoggerFromContext(ctx).Info("mcp request cancelled", "request_id", string(p.RequestID), "reason", p.Reason)
+		cancel()
+	}
+	return nil, nil
+}
+
+func (m *mcpSession) listTools(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	return map[string][]MCPTool{"tools": m.s.MCPTools()}, nil
+}
+
+// callTool runs the method of a tool. Failures of the method are reported
+// in the result so the model sees them; unknown tools are protocol errors.
+func (m *mcpSession) callTool(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p mcpToolCallParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	var tool *MCPTool
+	tools := m.s.MCPTools()
+	for i := range tools {
+		if tools[i].Name == p.Name {
+			tool = &tools[i]
+		}
+	}
+	if tool == nil {
+		return nil, &RPCError{Code: RPCInvalidParams, Message: fmt.Sprintf("unknown tool %s", p.Name)}
+	}
+	method, ok := m.s.rpcMethod(tool.method)
+	if tool.function != "" {
+		method, ok = m.s.functionTool(tool.function), true
+	}
+	if !ok {
+		return nil, &RPCError{Code: RPCInvalidParams, Message: fmt.Sprintf("unknown tool %s", p.Name)}
+	}
+
+	result, err := method(ctx, p.Arguments)
+	if err != nil {
+		LoggerFromContext(ctx).Warn("mcp tool call failed", "tool", p.Name, "error", err)
+		return &mcpToolResult{Content: []mcpContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	text := "ok"
+	if result != nil {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	return &mcpToolResult{Content: []mcpContent{{Type: "text", Text: text}}}, nil
+}
@@ -0,0 +1,307 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveStdio runs a session over the given messages and returns the
+// responses by ID
+func serveStdio(t *testing.T, s *Server, messages ...string) map[string]RPCResponse {
+	t.Helper()
+	var out bytes.Buffer
+	require.NoError(t, s.ServeStdio(context.Background(), strings.NewReader(strings.Join(messages, "\n")), &out))
+
+	responses := make(map[string]RPCResponse)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var resp RPCResponse
+		require.NoError(t, json.Unmarshal([]byte(line), &resp), line)
+		responses[string(resp.ID)] = resp
+	}
+	return responses
+}
+
+func TestServeStdio(t *testing.T) {
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+
+	responses := serveStdio(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+		``,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+		`{"jsonrpc":"2.0",`,
+	)
+	require.Len(t, responses, 3)
+	var init mcpInitializeResult
+	require.NoError(t, json.Unmarshal(responses["1"].Result, &init))
+	assert.Equal(t, "2025-03-26", init.ProtocolVersion)
+	assert.Equal(t, MCPServerName, init.ServerInfo.Name)
+	assert.JSONEq(t, `{}`, string(responses["2"].Result))
+	assert.Equal(t, RPCParseError, responses["null"].Error.Code)
+
+	// Unsupported versions get the newest one
+	initialize := `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`
+	responses = serveStdio(t, s, initialize)
+	require.NoError(t, json.Unmarshal(responses["0"].Result, &init))
+	assert.Equal(t, MCPProtocolVersions[0], init.ProtocolVersion)
+}
+
+func TestMCPSession(t *testing.T) {
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+	session := s.newMCPSession("")
+	ctx := context.Background()
+	call := func(body string) RPCResponse {
+		resp, ok := session.handleMessage(ctx, []byte(body)).(*RPCResponse)
+		require.True(t, ok, body)
+		return *resp
+	}
+
+	resp := call(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	assert.Equal(t, RPCInvalidRequest, resp.Error.Code)
+	call(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`)
+	assert.Nil(t, session.handleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)))
+
+	resp = call(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	var tools struct {
+		Tools []MCPTool `json:"tools"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Result, &tools))
+	names := make([]string, 0, len(tools.Tools))
+	for _, tool := range tools.Tools {
+		names = append(names, tool.Name)
+		assert.Equal(t, "object", tool.InputSchema.Type, tool.Name)
+	}
+	assert.Contains(t, names, "context_create")
+	assert.Contains(t, names, "function_call")
+	assert.NotContains(t, names, "ssh_exec")
+	assert.Contains(t, string(resp.Result), `"metadata":{"type":"object"`)
+
+	var result mcpToolResult
+	resp = call(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"function_call","arguments":{"name":"echo","arguments":["hi"]}}}`)
+	require.NoError(t, json.Unmarshal(resp.Result, &result))
+	assert.False(t, result.IsError)
+	assert.Equal(t, `"hi"`, result.Content[0].Text)
+
+	resp = call(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"context_get","arguments":{"id":"missing"}}}`)
+	require.NoError(t, json.Unmarshal(resp.Result, &result))
+	assert.True(t, result.IsError)
+
+	resp = call(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"teleport"}}`)
+	assert.Equal(t, RPCInvalidParams, resp.Error.Code)
+
+	resp = call(`{"jsonrpc":"2.0","id":9,"method":"context.list"}`)
+	assert.Equal(t, RPCMethodNotFound, resp.Error.Code)
+}
+
+func TestMCPSession_SubsystemTools(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Method", r.Method)
+		w.Write([]byte("pong " + r.Header.Get("X-Token")))
+	}))
+	defer upstream.Close()
+
+	s := NewServer(nil, WithDependency(DependencyChromium, true))
+	s.AddCurlHandler()
+	s.AddSSHHandler()
+	s.AddBrowserHandlers()
+	s.AddAnalysisHandler()
+	session := s.newMCPSession("")
+	ctx := context.Background()
+	session.handleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{}}`))
+
+	names := make([]string, 0)
+	for _, tool := range s.MCPTools() {
+		names = append(names, tool.Name)
+	}
+	for _, name := range []string{"ssh_exec", "browser_navigate", "browser_scrape", "analysis_file", "curl_replay"} {
+		assert.Contains(t, names, name)
+	}
+
+	call := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"curl_replay","arguments":{"command":"curl -X PUT -H 'X-Token: abc' %s"}}}`, upstream.URL)
+	resp := session.handleMessage(ctx, []byte(call)).(*RPCResponse)
+	var result mcpToolResult
+	require.NoError(t, json.Unmarshal(resp.Result, &result))
+	require.False(t, result.IsError, result.Content[0].Text)
+	var replay CurlReplayResult
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].Text), &replay))
+	assert.Equal(t, http.StatusOK, replay.StatusCode)
+	assert.Equal(t, "PUT", replay.Headers["X-Method"])
+	assert.Equal(t, "pong abc", replay.Body)
+
+	resp = session.handleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"browser_scrape","arguments":{"id":"missing"}}}`)).(*RPCResponse)
+	require.NoError(t, json.Unmarshal(resp.Result, &result))
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].Text, "browser not found")
+}
+
+func TestMCPSession_CapabilityFlags(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MCP.Prompts = false
+	cfg.MCP.Logging = false
+	s := NewServer(nil, WithConfig(cfg))
+	session := s.newMCPSession("")
+	ctx := context.Background()
+	call := func(body string) RPCResponse {
+		resp, ok := session.handleMessage(ctx, []byte(body)).(*RPCResponse)
+		require.True(t, ok, body)
+		return *resp
+	}
+
+	resp := call(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{"roots":{}}}}`)
+	var init struct {
+		Capabilities map[string]json.RawMessage `json:"capabilities"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Result, &init))
+	assert.Contains(t, init.Capabilities, "tools")
+	assert.Contains(t, init.Capabilities, "resources")
+	assert.NotContains(t, init.Capabilities, "prompts")
+	assert.NotContains(t, init.Capabilities, "logging")
+
+	resp = call(`{"jsonrpc":"2.0","id":1,"method":"prompts/list"}`)
+	assert.Equal(t, RPCMethodNotFound, resp.Error.Code)
+	resp = call(`{"jsonrpc":"2.0","id":2,"method":"logging/setLevel","params":{"level":"info"}}`)
+	assert.Equal(t, RPCMethodNotFound, resp.Error.Code)
+	resp = call(`{"jsonrpc":"2.0","id":3,"method":"resources/list"}`)
+	assert.Nil(t, resp.Error)
+
+	cfg = DefaultConfig()
+	cfg.MCP.Tools = false
+	session = NewServer(nil, WithConfig(cfg)).newMCPSession("")
+	resp = call(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{}}`)
+	init.Capabilities = nil
+	require.NoError(t, json.Unmarshal(resp.Result, &init))
+	assert.NotContains(t, init.Capabilities, "tools")
+	assert.Contains(t, init.Capabilities, "logging")
+	resp = call(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	assert.Equal(t, RPCMethodNotFound, resp.Error.Code)
+}
+
+func TestMCPSession_ToolsListChanged(t *testing.T) {
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+	session := s.newMCPSession("")
+	notifications := make(chan string, 16)
+	session.notify = func(method string, params interface{}) {
+		notifications <- method
+	}
+	stop := session.watch()
+	defer stop()
+	next := func() string {
+		select {
+		case method := <-notifications:
+			return method
+		case <-time.After(time.Second):
+			return "timed out"
+		}
+	}
+
+	resp, ok := session.handleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`)).(*RPCResponse)
+	require.True(t, ok)
+	var init struct {
+		Capabilities map[string]json.RawMessage `json:"capabilities"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Result, &init))
+	assert.JSONEq(t, `{"listChanged":true}`, string(init.Capabilities["tools"]))
+
+	require.NoError(t, s.functions.RegisterFunction("greet", func(name string) string { return "hello " + name }))
+	assert.Equal(t, "notifications/tools/list_changed", next())
+	assert.True(t, s.functions.UnregisterFunction("greet"))
+	assert.Equal(t, "notifications/tools/list_changed", next())
+	assert.False(t, s.functions.UnregisterFunction("greet"))
+
+	_, err := s.UpdateFeatures(FeatureState{Features: map[string]bool{FeatureFunctions: false}})
+	require.NoError(t, err)
+	assert.Equal(t, "notifications/tools/list_changed", next())
+
+	require.NoError(t, s.createContext(context.Background(), &Context{ID: "notes", Metadata: map[string]interface{}{}}))
+	assert.Equal(t, "notifications/resources/list_changed", next())
+	assert.Equal(t, "notifications/prompts/list_changed", next())
+}
+
+func TestMCPSession_FunctionTools(t *testing.T) {
+	type greeting struct {
+		Name  string `json:"name"`
+		Shout bool   `json:"shout"`
+	}
+	s := NewServer(nil)
+	s.AddFunctionHandler()
+	require.NoError(t, s.functions.RegisterFunction("add", func(a, b int) int { return a + b }))
+	require.NoError(t, s.functions.RegisterFunction("greet", func(g greeting) (string, error) {
+		if g.Name == "" {
+			return "", fmt.Errorf("name is required")
+		}
+		if g.Shout {
+			return strings.ToUpper("hello " + g.Name), nil
+		}
+		return "hello " + g.Name, nil
+	}))
+	// Methods win over functions of the same tool name
+	require.NoError(t, s.functions.RegisterFunction("call", func() string { return "shadowed" }))
+
+	session := s.newMCPSession("")
+	ctx := context.Background()
+	call := func(body string) RPCResponse {
+		resp, ok := session.handleMessage(ctx, []byte(body)).(*RPCResponse)
+		require.True(t, ok, body)
+		return *resp
+	}
+	call(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`)
+
+	tools := make(map[string]MCPTool)
+	for _, tool := range s.MCPTools() {
+		tools[tool.Name] = tool
+	}
+	require.Contains(t, tools, "function_add")
+	require.Contains(t, tools, "function_greet")
+	require.Contains(t, tools, "function_echo")
+	assert.Equal(t, "function.call", tools["function_call"].method)
+	schema, err := json.Marshal(tools["function_add"].InputSchema)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"object","properties":{"arg0":{"type":"integer","format":"int64"},"arg1":{"type":"integer","format":"int64"}},"required":["arg0","arg1"]}`, string(schema))
+	schema, err = json.Marshal(tools["function_greet"].InputSchema)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"object","properties":{"name":{"type":"string"},"shout":{"type":"boolean"}}}`, string(schema))
+
+	toolText := func(id int, name, arguments string) (string, bool) {
+		resp := call(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":%q,"arguments":%s}}`, id, name, arguments))
+		require.Nil(t, resp.Error)
+		var result mcpToolResult
+		require.NoError(t, json.Unmarshal(resp.Result, &result))
+		return result.Content[0].Text, result.IsError
+	}
+	text, isError := toolText(1, "function_add", `{"arg0":2,"arg1":3}`)
+	assert.False(t, isError)
+	assert.Equal(t, "5", text)
+	text, isError = toolText(2, "function_greet", `{"name":"ada","shout":true}`)
+	assert.False(t, isError)
+	assert.Equal(t, `"HELLO ADA"`, text)
+	text, isError = toolText(3, "function_greet", `{}`)
+	assert.True(t, isError)
+	assert.Equal(t, "name is required", text)
+	text, isError = toolText(4, "function_add", `{"arg0":2}`)
+	assert.True(t, isError)
+	assert.Contains(t, text, "missing argument arg1")
+
+	// The HTTP API calls the same functions
+	r := httptest.NewRequest("POST", "/v1/function/call", strings.NewReader(`{"name":"add","arguments":[4,5]}`))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.JSONEq(t, `{"result":9}`, w.Body.String())
+
+	_, err = s.UpdateFeatures(FeatureState{Features: map[string]bool{FeatureFunctions: false}})
+	require.NoError(t, err)
+	for _, tool := range s.MCPTools() {
+		assert.NotEqual(t, "function_add", tool.Name)
+	}
+}
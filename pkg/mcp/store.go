@@ -1,6 +1,11 @@
 package mcp
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 )
 
@@ -92,3 +97,89 @@ func (s *MemoryStore) List() []*Context {
 	}
 	return contexts
 }
+
+// FileStore implements Store on top of MemoryStore, persisting all contexts
+// to a JSON file after every change
+type FileStore struct {
+	*MemoryStore
+	path   string
+	saveMu sync.Mutex
+}
+
+// NewFileStore creates a file-backed context store, loading any contexts
+// already saved at path
+func NewFileStore(path string) (*FileStore, error) {
+	fs := &FileStore{
+		MemoryStore: &MemoryStore{contexts: make(map[string]*Context)},
+		path:        path,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read store file: %w", err)
+	}
+
+	if len(data) > 0 {
+		var contexts []*Context
+		if err := json.Unmarshal(data, &contexts); err != nil {
+			return nil, fmt.Errorf("failed to parse store file: %w", err)
+		}
+		for _, ctx := range contexts {
+			fs.contexts[ctx.ID] = ctx
+		}
+	}
+
+	return fs, nil
+}
+
+func (s *FileStore) Create(ctx *Context) error {
+	if err := s.MemoryStore.Create(ctx); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStore) Update(ctx *Context) error {
+	if err := s.MemoryStore.Update(ctx); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStore) Delete(id string) error {
+	if err := s.MemoryStore.Delete(id); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// Close flushes the store to disk
+func (s *FileStore) Close() error {
+	return s.save()
+}
+
+func (s *FileStore) save() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	contexts := s.List()
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].ID < contexts[j].ID
+	})
+
+	data, err := json.MarshalIndent(contexts, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated store
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,328 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MCPSessionHeader carries the session ID of the streamable HTTP transport.
+// The server assigns it in the response to initialize and hosts send it with
+// every later request.
+const MCPSessionHeader = "Mcp-Session-Id"
+
+// MCPProtocolVersionHeader carries the protocol version negotiated by
+// initialize with later requests of a session
+const MCPProtocolVersionHeader = "Mcp-Protocol-Version"
+
+// Defaults of the streamable HTTP transport
+const (
+	defaultMCPSessionTimeout = 30 * time.Minute
+	defaultMCPMaxSessions    = 100
+	mcpKeepAlive             = 30 * time.Second // Comments keeping idle event streams open through proxies
+	mcpOutboxSize            = 64               // Notifications held for a host without an open event stream
+)
+
+// MCPHTTPConfig configures the streamable HTTP transport of the Model Context
+// Protocol, served at /v1/mcp, so remote MCP hosts can connect to a running
+// server: POST sends messages, GET opens an event stream of notifications and
+// DELETE ends the session.
+type MCPHTTPConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	SessionTimeout time.Duration `yaml:"session_timeout"` // Idle sessions are closed after it, defaults to 30 minutes
+	MaxSessions    int           `yaml:"max_sessions"`    // Defaults to 100
+}
+
+// Validate checks the transport config when it is enabled
+func (c MCPHTTPConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.SessionTimeout < 0 || c.MaxSessions < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return nil
+}
+
+// mcpHTTPTransport holds the sessions of remote MCP hosts
+type mcpHTTPTransport struct {
+	s              *Server
+	sessionTimeout time.Duration
+	maxSessions    int
+
+	mu       sync.Mutex
+	sessions map[string]*mcpHTTPSession
+}
+
+// mcpHTTPSession is an MCP session whose notifications wait in an outbox
+// until the host reads them from its event stream
+type mcpHTTPSession struct {
+	*mcpSession
+	id     string
+	outbox chan []byte
+	closed chan struct{}
+
+	// Guarded by the transport
+	lastSeen  time.Time
+	streaming bool
+
+	unwatch func()
+}
+
+func newMCPHTTPTransport(s *Server) *mcpHTTPTransport {
+	t := &mcpHTTPTransport{
+		s:              s,
+		sessionTimeout: s.config.MCPHTTP.SessionTimeout,
+		maxSessions:    s.config.MCPHTTP.MaxSessions,
+		sessions:       make(map[string]*mcpHTTPSession),
+	}
+	if t.sessionTimeout == 0 {
+		t.sessionTimeout = defaultMCPSessionTimeout
+	}
+	if t.maxSessions == 0 {
+		t.maxSessions = defaultMCPMaxSessions
+	}
+	return t
+}
+
+// open starts a session of a tenant, closing expired ones first to make room
+func (t *mcpHTTPTransport) open(tenant string) (*mcpHTTPSession, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expire()
+	if len(t.sessions) >= t.maxSessions {
+		return nil, fmt.Errorf("too many mcp sessions, at most %d are open", t.maxSessions)
+	}
+
+	session := &mcpHTTPSession{
+		mcpSession: t.s.newMCPSession(tenant),
+		id:         newRequestID(),
+		outbox:     make(chan []byte, mcpOutboxSize),
+		closed:     make(chan struct{}),
+		lastSeen:   time.Now(),
+	}
+	session.send = func(message interface{}) {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return
+		}
+		select {
+		case session.outbox <- data:
+		default: // Nobody is listening
+		}
+	}
+	session.notify = func(method string, params interface{}) {
+		session.send(&mcpNotification{JSONRPC: JSONRPCVersion, Method: method, Params: params})
+	}
+	session.unwatch = session.watch()
+	t.sessions[session.id] = session
+	return session, nil
+}
+
+// get returns an open session and marks it used
+func (t *mcpHTTPTransport) get(id string) (*mcpHTTPSession, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expire()
+	session, ok := t.sessions[id]
+	if ok {
+		session.lastSeen = time.Now()
+	}
+	return session, ok
+}
+
+// expire closes sessions idle for longer than the timeout. Sessions with an
+// open event stream are in use. Callers hold t.mu.
+func (t *mcpHTTPTransport) expire() {
+	for id, session := range t.sessions {
+		if !session.streaming && time.Since(session.lastSeen) > t.sessionTimeout {
+			delete(t.sessions, id)
+			session.close()
+		}
+	}
+}
+
+// remove closes a session, reporting whether it was open
+func (t *mcpHTTPTransport) remove(id string) bool {
+	t.mu.Lock()
+	session, ok := t.sessions[id]
+	delete(t.sessions, id)
+	t.mu.Unlock()
+	if ok {
+		session.close()
+	}
+	return ok
+}
+
+// closeAll closes every session, ending their event streams
+func (t *mcpHTTPTransport) closeAll() {
+	t.mu.Lock()
+	sessions := t.sessions
+	t.sessions = make(map[string]*mcpHTTPSession)
+	t.mu.Unlock()
+	for _, session := range sessions {
+		session.close()
+	}
+}
+
+// close stops notifications, cancels running requests, releases the
+// resources of the session and ends its event stream
+func (m *mcpHTTPSession) close() {
+	m.unwatch()
+	m.mu.Lock()
+	for _, cancel := range m.inflight {
+		cancel()
+	}
+	m.mu.Unlock()
+	m.end()
+	close(m.closed)
+}
+
+// checkProtocolVersion rejects requests naming another protocol version than
+// the session negotiated. Hosts of versions before the header omit it.
+func (m *mcpHTTPSession) checkProtocolVersion(r *http.Request) error {
+	version := r.Header.Get(MCPProtocolVersionHeader)
+	m.mu.Lock()
+	negotiated := m.protocolVersion
+	m.mu.Unlock()
+	if version != "" && version != negotiated {
+		return fmt.Errorf("protocol version %s was not negotiated, the session uses %s", version, negotiated)
+	}
+	return nil
+}
+
+// isInitialize reports whether a message is a single initialize request.
+// The protocol does not allow initialize in batches.
+func isInitialize(body []byte) bool {
+	var req RPCRequest
+	return json.Unmarshal(body, &req) == nil && req.Method == "initialize" && req.ID != nil
+}
+
+// handlePost answers the messages of a host. The initialize request opens a
+// session; every other message must name one. Messages without requests,
+// such as notifications, are accepted without a body.
+func (t *mcpHTTPTransport) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var session *mcpHTTPSession
+	id := r.Header.Get(MCPSessionHeader)
+	switch {
+	case id != "":
+		var ok bool
+		if session, ok = t.get(id); !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("mcp session %s not found, initialize a new one", id))
+			return
+		}
+		if err := session.checkProtocolVersion(r); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	case isInitialize(body):
+		if session, err = t.open(TenantFromContext(r.Context())); err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		LoggerFromContext(r.Context()).Info("mcp session opened", "session", session.id)
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing %s header, send initialize first", MCPSessionHeader))
+		return
+	}
+
+	resp := session.handleMessage(r.Context(), body)
+	if id == "" {
+		// A failed initialize leaves nothing to come back to
+		if rpcResp, ok := resp.(*RPCResponse); !ok || rpcResp.Error != nil {
+			t.remove(session.id)
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+	}
+
+	w.Header().Set(MCPSessionHeader, session.id)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleStream sends the notifications of a session as server-sent events
+// until the host disconnects or the session ends. A session has at most one
+// stream.
+func (t *mcpHTTPTransport) handleStream(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		writeError(w, http.StatusNotAcceptable, fmt.Errorf("the event stream needs Accept: text/event-stream"))
+		return
+	}
+	session, ok := t.get(r.Header.Get(MCPSessionHeader))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("mcp session %q not found", r.Header.Get(MCPSessionHeader)))
+		return
+	}
+	if err := session.checkProtocolVersion(r); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	t.mu.Lock()
+	streaming := session.streaming
+	session.streaming = true
+	t.mu.Unlock()
+	if streaming {
+		writeError(w, http.StatusConflict, fmt.Errorf("mcp session %s already has an event stream", session.id))
+		return
+	}
+	defer func() {
+		t.mu.Lock()
+		session.streaming = false
+		session.lastSeen = time.Now()
+		t.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set(MCPSessionHeader, session.id)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(mcpKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-session.closed:
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case data := <-session.outbox:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+// handleDelete ends a session at the request of its host
+func (t *mcpHTTPTransport) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(MCPSessionHeader)
+	if !t.remove(id) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("mcp session %q not found", id))
+		return
+	}
+	LoggerFromContext(r.Context()).Info("mcp session closed", "session", id)
+	w.WriteHeader(http.StatusNoContent)
+}
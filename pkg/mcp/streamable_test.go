@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mcpInitialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`
+
+// postMCP sends a message to the streamable HTTP transport
+func postMCP(t *testing.T, url, session, message string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest("POST", url+"/v1/mcp", strings.NewReader(message))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if session != "" {
+		req.Header.Set(MCPSessionHeader, session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func mcpRequest(t *testing.T, method, url, session string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url+"/v1/mcp", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(MCPSessionHeader, session)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestMCPHTTP_Session(t *testing.T) {
+	srv := httptest.NewServer(NewServer(nil))
+	t.Cleanup(srv.Close)
+
+	resp := postMCP(t, srv.URL, "", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = postMCP(t, srv.URL, "", mcpInitialize)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	session := resp.Header.Get(MCPSessionHeader)
+	require.NotEmpty(t, session)
+	var rpcResp RPCResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rpcResp))
+	require.Nil(t, rpcResp.Error)
+	assert.Contains(t, string(rpcResp.Result), `"protocolVersion":"2025-03-26"`)
+
+	resp = postMCP(t, srv.URL, session, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	resp = postMCP(t, srv.URL, session, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rpcResp))
+	assert.Contains(t, string(rpcResp.Result), "context_create")
+
+	req, err := http.NewRequest("POST", srv.URL+"/v1/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":3,"method":"ping"}`))
+	require.NoError(t, err)
+	req.Header.Set(MCPSessionHeader, session)
+	req.Header.Set(MCPProtocolVersionHeader, "2024-11-05")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "the session negotiated 2025-03-26")
+
+	resp = postMCP(t, srv.URL, "unknown", `{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = mcpRequest(t, "DELETE", srv.URL, session)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = postMCP(t, srv.URL, session, `{"jsonrpc":"2.0","id":4,"method":"ping"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestMCPHTTP_EventStream(t *testing.T) {
+	srv := httptest.NewServer(NewServer(nil))
+	t.Cleanup(srv.Close)
+
+	resp := postMCP(t, srv.URL, "", mcpInitialize)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	session := resp.Header.Get(MCPSessionHeader)
+
+	stream := mcpRequest(t, "GET", srv.URL, session)
+	require.Equal(t, http.StatusOK, stream.StatusCode)
+	assert.Equal(t, "text/event-stream", stream.Header.Get("Content-Type"))
+	resp = mcpRequest(t, "GET", srv.URL, session)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	created, err := http.Post(srv.URL+"/v1/context/create", "application/json", strings.NewReader(`{"id":"ctx1","metadata":{}}`))
+	require.NoError(t, err)
+	created.Body.Close()
+	require.Equal(t, http.StatusCreated, created.StatusCode)
+
+	reader := bufio.NewReader(stream.Body)
+	var data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "data: "); ok {
+			data = rest
+		}
+	}
+	assert.JSONEq(t, `{"jsonrpc":"2.0","method":"notifications/resources/list_changed"}`, data)
+
+	// Ending the session ends its stream
+	resp = mcpRequest(t, "DELETE", srv.URL, session)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	_, err = io.ReadAll(reader)
+	assert.NoError(t, err)
+}
+
+func TestMCPHTTP_MaxSessions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MCPHTTP.MaxSessions = 1
+	srv := httptest.NewServer(NewServer(nil, WithConfig(cfg)))
+	t.Cleanup(srv.Close)
+
+	// A failed initialize does not hold on to its session
+	resp := postMCP(t, srv.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":"invalid"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(MCPSessionHeader))
+
+	resp = postMCP(t, srv.URL, "", mcpInitialize)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = postMCP(t, srv.URL, "", mcpInitialize)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
@@ -0,0 +1,433 @@
+package mcp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// unusedDiagnostics reports the parameters of functions a file declares that
+// are never used, and the values assigned to local variables that are never
+// used: overwritten in the same block before being read, or not read at all
+// after the assignment. Methods, which may implement interfaces, functions
+// used as values or called by the testing package and functions without a
+// body are left out, and so are variables captured by closures or whose
+// address is taken.
+func (a *ASTAnalyzer) unusedDiagnostics(file *ast.File) []Diagnostic {
+	var diagnostics []Diagnostic
+	valueFuncs := a.funcValues()
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		if fn.Recv == nil && len(fn.Body.List) > 0 && !valueFuncs[a.typeInfo.Defs[fn.Name]] && !testFunction(fn) {
+			for _, param := range fn.Type.Params.List {
+				for _, name := range param.Names {
+					obj := a.typeInfo.Defs[name]
+					if obj == nil || name.Name == "_" || a.referenced(fn.Body, obj) {
+						continue
+					}
+					diagnostics = append(diagnostics, Diagnostic{
+						Severity: "warning",
+						Message:  fmt.Sprintf("Parameter %s of %s is never used", name.Name, fn.Name.Name),
+						Location: a.nodeLocation(name),
+						Code:     "unused-parameter",
+						Source:   SourceAnalyzer,
+					})
+				}
+			}
+		}
+
+		for _, name := range a.ineffectiveAssignments(fn) {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: "warning",
+				Message:  fmt.Sprintf("Value assigned to %s is never used", name.Name),
+				Location: a.nodeLocation(name),
+				Code:     "unused-assignment",
+				Source:   SourceAnalyzer,
+			})
+		}
+	}
+	return diagnostics
+}
+
+// unusedFieldDiagnostics reports the fields of struct types the files of a
+// package declare that are never read, only set in composite literals and
+// assigned to. Values compared or converted to interfaces have all their
+// fields read. Exported fields, which other packages may read, and tagged
+// fields, which are read through reflection, are left out.
+func (a *ASTAnalyzer) unusedFieldDiagnostics(files []*ast.File) []Diagnostic {
+	type field struct {
+		name     *ast.Ident
+		typeName string
+	}
+	var fields []field
+	read := make(map[types.Object]bool)
+	for _, file := range files {
+		written := a.writtenIdents(file)
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.TypeSpec:
+				st, ok := n.Type.(*ast.StructType)
+				if !ok {
+					break
+				}
+				for _, f := range st.Fields.List {
+					if f.Tag != nil {
+						continue
+					}
+					for _, name := range f.Names {
+						if !name.IsExported() && name.Name != "_" {
+							fields = append(fields, field{name: name, typeName: n.Name.Name})
+						}
+					}
+				}
+			case *ast.BinaryExpr:
+				if n.Op == token.EQL || n.Op == token.NEQ {
+					markFieldsRead(a.typeInfo.TypeOf(n.X), read)
+				}
+			case *ast.CallExpr:
+				sig, ok := a.typeInfo.TypeOf(n.Fun).(*types.Signature)
+				if !ok {
+					break
+				}
+				for i, arg := range n.Args {
+					param := sig.Params().Len() - 1
+					if i < param || !sig.Variadic() {
+						param = i
+					}
+					if param < 0 || param >= sig.Params().Len() {
+						break
+					}
+					t := sig.Params().At(param).Type()
+					if sig.Variadic() && param == sig.Params().Len()-1 && !n.Ellipsis.IsValid() {
+						t = t.(*types.Slice).Elem()
+					}
+					if types.IsInterface(t) {
+						markFieldsRead(a.typeInfo.TypeOf(arg), read)
+					}
+				}
+			}
+			return a.markRead(n, written, read)
+		})
+	}
+
+	var diagnostics []Diagnostic
+	for _, f := range fields {
+		obj := a.typeInfo.Defs[f.name]
+		if obj == nil || read[obj] {
+			continue
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: "warning",
+			Message:  fmt.Sprintf("Field %s of %s is never read", f.name.Name, f.typeName),
+			Location: a.nodeLocation(f.name),
+			Code:     "unused-field",
+			Source:   SourceAnalyzer,
+		})
+	}
+	return diagnostics
+}
+
+// markFieldsRead records the fields of a struct type as read, with those of
+// the elements of pointers, slices, arrays and maps of it, for values
+// compared or converted to interfaces, which fmt and reflection read
+func markFieldsRead(t types.Type, read map[types.Object]bool) {
+	for t != nil {
+		switch u := t.Underlying().(type) {
+		case *types.Pointer:
+			t = u.Elem()
+		case *types.Slice:
+			t = u.Elem()
+		case *types.Array:
+			t = u.Elem()
+		case *types.Map:
+			t = u.Elem()
+		case *types.Struct:
+			for i := 0; i < u.NumFields(); i++ {
+				read[u.Field(i)] = true
+			}
+			return
+		default:
+			return
+		}
+	}
+}
+
+// markRead records the object an identifier that is not assigned to uses
+func (a *ASTAnalyzer) markRead(n ast.Node, written map[*ast.Ident]bool, read map[types.Object]bool) bool {
+	if ident, ok := n.(*ast.Ident); ok && !written[ident] {
+		if obj := a.typeInfo.Uses[ident]; obj != nil {
+			read[obj] = true
+		}
+	}
+	return true
+}
+
+// writtenIdents returns the identifiers a file only assigns to: variables
+// and fields on the left of = and :=, the names of value specs and the keys
+// of composite literals
+func (a *ASTAnalyzer) writtenIdents(file *ast.File) map[*ast.Ident]bool {
+	written := make(map[*ast.Ident]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok != token.ASSIGN && n.Tok != token.DEFINE {
+				break
+			}
+			for _, lhs := range n.Lhs {
+				switch lhs := ast.Unparen(lhs).(type) {
+				case *ast.Ident:
+					written[lhs] = true
+				case *ast.SelectorExpr:
+					written[lhs.Sel] = true
+				}
+			}
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				written[name] = true
+			}
+		case *ast.CompositeLit:
+			for _, elt := range n.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					if key, ok := kv.Key.(*ast.Ident); ok {
+						written[key] = true
+					}
+				}
+			}
+		}
+		return true
+	})
+	return written
+}
+
+// funcValues returns the functions of the files checked that are used other
+// than by calling them, whose signature is then not theirs to change
+func (a *ASTAnalyzer) funcValues() map[types.Object]bool {
+	values := make(map[types.Object]bool)
+	for file := range a.checked {
+		called := make(map[*ast.Ident]bool)
+		ast.Inspect(file, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				switch fun := ast.Unparen(call.Fun).(type) {
+				case *ast.Ident:
+					called[fun] = true
+				case *ast.SelectorExpr:
+					called[fun.Sel] = true
+				}
+			}
+			return true
+		})
+		ast.Inspect(file, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && !called[ident] {
+				if fn, ok := a.typeInfo.Uses[ident].(*types.Func); ok {
+					values[fn] = true
+				}
+			}
+			return true
+		})
+	}
+	return values
+}
+
+// testFunction reports whether fn is a test, benchmark or fuzz test the
+// testing package calls
+func testFunction(fn *ast.FuncDecl) bool {
+	for _, prefix := range []string{"Test", "Benchmark", "Fuzz"} {
+		if strings.HasPrefix(fn.Name.Name, prefix) && fn.Type.Params.NumFields() == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// referenced reports whether an identifier of node refers to obj
+func (a *ASTAnalyzer) referenced(node ast.Node, obj types.Object) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && a.typeInfo.Uses[ident] == obj {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// ineffectiveAssignments returns the identifiers assigned values that are
+// never used in the body of fn: values overwritten by a later statement of
+// the same block before being read, and values not read at all after the
+// assignment, in a loop of it included. Named results, which are returned
+// implicitly, variables captured by closures or whose address is taken and
+// functions with goto statements are left out.
+func (a *ASTAnalyzer) ineffectiveAssignments(fn *ast.FuncDecl) []*ast.Ident {
+	// assignment is the statement assigning to an identifier, which takes
+	// effect at its end, after the values it assigns are evaluated
+	type assignment struct {
+		block *ast.BlockStmt // Block the statement is part of, if any
+		end   token.Pos
+	}
+	type occurrence struct {
+		ident *ast.Ident
+		pos   token.Pos
+		write *assignment
+		loops []ast.Node // Loops enclosing the occurrence
+	}
+	occurrences := make(map[*types.Var][]occurrence)
+	excluded := make(map[types.Object]bool)
+	if fn.Type.Results != nil {
+		for _, field := range fn.Type.Results.List {
+			for _, name := range field.Names {
+				excluded[a.typeInfo.Defs[name]] = true
+			}
+		}
+	}
+
+	local := func(ident *ast.Ident) *types.Var {
+		obj := a.typeInfo.Defs[ident]
+		if obj == nil {
+			obj = a.typeInfo.Uses[ident]
+		}
+		v, ok := obj.(*types.Var)
+		if !ok || v.IsField() || ident.Name == "_" || v.Pkg() == nil || v.Parent() == nil || v.Parent() == v.Pkg().Scope() {
+			return nil
+		}
+		return v
+	}
+	written := make(map[*ast.Ident]*assignment)
+	var stack, loops []ast.Node
+	hasGoto := false
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if n == nil {
+			if len(loops) > 0 && loops[len(loops)-1] == stack[len(stack)-1] {
+				loops = loops[:len(loops)-1]
+			}
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		var block *ast.BlockStmt
+		if len(stack) > 0 {
+			block, _ = stack[len(stack)-1].(*ast.BlockStmt)
+		}
+		stack = append(stack, n)
+
+		switch n := n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			loops = append(loops, n)
+		case *ast.BranchStmt:
+			hasGoto = hasGoto || n.Tok == token.GOTO
+		case *ast.FuncLit:
+			// Variables closures capture are left out
+			ast.Inspect(n.Body, func(n ast.Node) bool {
+				if ident, ok := n.(*ast.Ident); ok {
+					if obj := a.typeInfo.Uses[ident]; obj != nil {
+						excluded[obj] = true
+					}
+				}
+				return true
+			})
+			stack = stack[:len(stack)-1]
+			return false
+		case *ast.UnaryExpr:
+			if ident, ok := ast.Unparen(n.X).(*ast.Ident); ok && n.Op == token.AND {
+				if obj := a.typeInfo.Uses[ident]; obj != nil {
+					excluded[obj] = true
+				}
+			}
+		case *ast.AssignStmt:
+			if n.Tok != token.ASSIGN && n.Tok != token.DEFINE {
+				break
+			}
+			for _, lhs := range n.Lhs {
+				if ident, ok := ast.Unparen(lhs).(*ast.Ident); ok {
+					written[ident] = &assignment{block: block, end: n.End()}
+				}
+			}
+		case *ast.DeclStmt:
+			gen, ok := n.Decl.(*ast.GenDecl)
+			if !ok {
+				break
+			}
+			for _, spec := range gen.Specs {
+				if spec, ok := spec.(*ast.ValueSpec); ok && len(spec.Values) > 0 {
+					for _, name := range spec.Names {
+						written[name] = &assignment{block: block, end: n.End()}
+					}
+				}
+			}
+		case *ast.Ident:
+			v := local(n)
+			if v == nil {
+				break
+			}
+			write := written[n]
+			if write == nil && a.typeInfo.Defs[n] != nil {
+				// Declarations without a value, range variables and
+				// the like
+				break
+			}
+			occ := occurrence{ident: n, pos: n.Pos(), write: write, loops: append([]ast.Node(nil), loops...)}
+			if write != nil {
+				occ.pos = write.end
+			}
+			occurrences[v] = append(occurrences[v], occ)
+		}
+		return true
+	})
+	if hasGoto {
+		return nil
+	}
+
+	var ineffective []*ast.Ident
+	for v, occs := range occurrences {
+		if excluded[v] {
+			continue
+		}
+		sort.SliceStable(occs, func(i, j int) bool { return occs[i].pos < occs[j].pos })
+		for i, occ := range occs {
+			if occ.write == nil {
+				continue
+			}
+			// The value is read after the assignment, before a later
+			// statement of its block assigns another, or by the next
+			// iteration of a loop the assignment is in
+			used := false
+			for _, later := range occs[i+1:] {
+				if later.write == nil {
+					used = true
+					break
+				}
+				if later.write.block != nil && later.write.block == occ.write.block {
+					break
+				}
+			}
+			for _, loop := range occ.loops {
+				for _, other := range occs {
+					if other.write == nil && other.pos >= loop.Pos() && other.pos < loop.End() {
+						used = true
+					}
+				}
+			}
+			if !used {
+				ineffective = append(ineffective, occ.ident)
+			}
+		}
+	}
+	sort.Slice(ineffective, func(i, j int) bool { return ineffective[i].Pos() < ineffective[j].Pos() })
+	return ineffective
+}
+
+// nodeLocation returns the location of the range of a node
+func (a *ASTAnalyzer) nodeLocation(node ast.Node) Location {
+	start, end := a.fileSet.Position(node.Pos()), a.fileSet.Position(node.End())
+	return Location{
+		URI: start.Filename,
+		Range: Range{
+			Start: Position{Line: start.Line - 1, Character: start.Column - 1},
+			End:   Position{Line: end.Line - 1, Character: end.Column - 1},
+		},
+	}
+}
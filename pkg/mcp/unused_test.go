@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnusedDiagnostics(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		found  []string
+	}{
+		{"unused parameter", "func f(a, b int) int { return a }", []string{"Parameter b of f is never used"}},
+		{"blank and unnamed parameters", "func f(_ int, _ string) {}\n\nfunc g(int) { println() }", nil},
+		{"empty body", "func f(a int) {}", nil},
+		{"method", "type T struct{}\n\nfunc (T) f(a int) { println() }", nil},
+		{"function value", "func f(a int) { println() }\n\nvar g = f", nil},
+		{"test function", "func TestF(t *T) { println() }\n\ntype T struct{}", nil},
+		{"parameter used by a closure", "func f(a int) func() int { return func() int { return a } }", nil},
+		{"overwritten value", "func f() int { x := 1; x = 2; return x }", []string{"Value assigned to x is never used"}},
+		{"value read by the assignment", "func f() int { x := 1; x = x + 1; return x }", nil},
+		{"last value", "func f(a int) int { x := a; println(x); x = 2; return a }", []string{"Value assigned to x is never used"}},
+		{"conditional overwrite", "func f(c bool) int { x := 1; if c { x = 2 }; return x }", nil},
+		{"overwrite after a branch", "func f(c bool) int { x := 1; if c { x = 2 }; x = 3; return x }", []string{"Value assigned to x is never used"}},
+		{"loop", "func f(n int) int { x := 0; for i := 0; i < n; i++ { println(x); x = i }; return n }", nil},
+		{"declaration", "func f() int { var x = 1; x = 2; return x }", []string{"Value assigned to x is never used"}},
+		{"parameter overwritten", "func f(a int) int { a = 2; return 1 }", []string{"Value assigned to a is never used"}},
+		{"named result", "func f() (x int) { x = 1; return }", nil},
+		{"captured variable", "func f() int { x := 1; g := func() int { return x }; x = 2; return g() }", nil},
+		{"address taken", "func f() int { x := 1; p := &x; x = 2; return *p }", nil},
+		{"redeclared error", "func g() (int, error) { return 0, nil }\n\nfunc f() (int, error) { a, err := g(); b, err := g(); return a + b, err }", []string{"Value assigned to err is never used"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "p.go", "package p\n\n"+tt.source+"\n", 0)
+			require.NoError(t, err)
+			result, err := NewASTAnalyzer(fset).AnalyzeFile(file)
+			require.NoError(t, err)
+
+			var found []string
+			for _, d := range result.Diagnostics {
+				if d.Code == "unused-parameter" || d.Code == "unused-assignment" {
+					found = append(found, d.Message)
+				}
+			}
+			assert.Equal(t, tt.found, found)
+		})
+	}
+}
+
+func TestUnusedDiagnosticLocation(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", "package p\n\nfunc f(a, unused int) int {\n\tx := a\n\tx = 2\n\treturn a\n}\n", 0)
+	require.NoError(t, err)
+	result, err := NewASTAnalyzer(fset).AnalyzeFile(file)
+	require.NoError(t, err)
+
+	locations := make(map[string]Range)
+	for _, d := range result.Diagnostics {
+		locations[d.Code] = d.Location.Range
+	}
+	assert.Equal(t, Range{Start: Position{Line: 2, Character: 10}, End: Position{Line: 2, Character: 16}}, locations["unused-parameter"])
+	assert.Equal(t, Range{Start: Position{Line: 4, Character: 1}, End: Position{Line: 4, Character: 2}}, locations["unused-assignment"])
+}
+
+func TestUnusedFieldDiagnostics(t *testing.T) {
+	sources := []string{`package p
+
+type cache struct {
+	entries map[string]int
+	hits    int
+	misses  int
+	Size    int
+	name    string ` + "`json:\"name\"`" + `
+}
+
+type stamp struct {
+	size int
+}
+
+type event struct {
+	kind string
+}
+
+func newCache() *cache {
+	return &cache{entries: make(map[string]int), misses: 0}
+}
+`, `package p
+
+func (c *cache) get(key string) int {
+	c.hits++
+	c.misses = 0
+	return c.entries[key]
+}
+
+func changed(a, b stamp) bool { return a != b }
+
+func record(events []event) { println(len(events)); report(events) }
+
+func report(v interface{}) {}
+`}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for i, src := range sources {
+		file, err := parser.ParseFile(fset, []string{"a.go", "b.go"}[i], src, 0)
+		require.NoError(t, err)
+		files = append(files, file)
+	}
+	result, err := NewASTAnalyzer(fset).AnalyzePackage("p", files)
+	require.NoError(t, err)
+
+	var found []Diagnostic
+	for _, d := range result.Diagnostics {
+		if d.Code == "unused-field" {
+			found = append(found, d)
+		}
+	}
+	// Incremented fields are read, fields compared or converted to
+	// interfaces too
+	require.Len(t, found, 1, found)
+	assert.Equal(t, "Field misses of cache is never read", found[0].Message)
+	assert.Equal(t, Location{URI: "a.go", Range: Range{Start: Position{Line: 5, Character: 1}, End: Position{Line: 5, Character: 7}}}, found[0].Location)
+}
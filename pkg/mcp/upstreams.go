@@ -0,0 +1,307 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/mcpclient"
+)
+
+const (
+	// upstreamConnectTimeout bounds starting and initializing an upstream
+	upstreamConnectTimeout = 30 * time.Second
+	// upstreamCallTimeout bounds a call of an exposed upstream tool
+	upstreamCallTimeout = 5 * time.Minute
+)
+
+var (
+	// ErrUpstreamUnavailable is returned when calling a tool of an upstream
+	// that is not connected
+	ErrUpstreamUnavailable = errors.New("upstream is unavailable")
+	// ErrUpstreamTool is returned when an upstream tool reports a failure
+	ErrUpstreamTool = errors.New("upstream tool failed")
+)
+
+// UpstreamConfig names another MCP server whose tools this server
+// aggregates. It is reached by running Command, speaking over its stdin and
+// stdout, or at URL, speaking HTTP with server-sent events.
+type UpstreamConfig struct {
+	Name    string            `yaml:"name"`
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args"`
+	Env     map[string]string `yaml:"env"` // Added to the environment of the command
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"` // Sent with every request to URL
+	Expose  bool              `yaml:"expose"`  // Register its tools as functions named <name>.<tool>
+}
+
+// Validate checks the upstream configuration for obvious mistakes
+func (c UpstreamConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("upstream without name")
+	}
+	if (c.Command == "") == (c.URL == "") {
+		return fmt.Errorf("upstream %s: exactly one of command and url is required", c.Name)
+	}
+	return nil
+}
+
+func validateUpstreams(upstreams []UpstreamConfig) error {
+	names := make(map[string]bool)
+	for _, u := range upstreams {
+		if err := u.Validate(); err != nil {
+			return err
+		}
+		if names[u.Name] {
+			return fmt.Errorf("duplicate upstream %q", u.Name)
+		}
+		names[u.Name] = true
+	}
+	return nil
+}
+
+// UpstreamStatus is the state of the connection to an upstream
+type UpstreamStatus struct {
+	Name            string   `json:"name"`
+	Transport       string   `json:"transport"` // stdio or sse
+	Connected       bool     `json:"connected"`
+	Server          string   `json:"server,omitempty"`
+	ProtocolVersion string   `json:"protocol_version,omitempty"`
+	Tools           []string `json:"tools,omitempty"`
+	Resources       []string `json:"resources,omitempty"`
+	Exposed         []string `json:"exposed,omitempty"` // Functions registered for its tools
+	Error           string   `json:"error,omitempty"`
+}
+
+// upstreams holds the clients of the configured upstreams
+type upstreams struct {
+	mu      sync.Mutex
+	clients map[string]*mcpclient.Client
+	status  map[string]*UpstreamStatus
+}
+
+func newUpstreams(configs []UpstreamConfig) *upstreams {
+	u := &upstreams{
+		clients: make(map[string]*mcpclient.Client),
+		status:  make(map[string]*UpstreamStatus),
+	}
+	for _, cfg := range configs {
+		transport := "stdio"
+		if cfg.URL != "" {
+			transport = "sse"
+		}
+		u.status[cfg.Name] = &UpstreamStatus{Name: cfg.Name, Transport: transport}
+	}
+	return u
+}
+
+func (u *upstreams) client(name string) (*mcpclient.Client, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	client, ok := u.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUpstreamUnavailable, name)
+	}
+	select {
+	case <-client.Done():
+		return nil, fmt.Errorf("%w: %s: %v", ErrUpstreamUnavailable, name, client.Err())
+	default:
+		return client, nil
+	}
+}
+
+// list returns the status of every upstream, sorted by name
+func (u *upstreams) list() []UpstreamStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	statuses := make([]UpstreamStatus, 0, len(u.status))
+	for name, status := range u.status {
+		s := *status
+		if client, ok := u.clients[name]; ok {
+			select {
+			case <-client.Done():
+				s.Connected = false
+				s.Exposed = nil
+				s.Error = client.Err().Error()
+			default:
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (u *upstreams) closeAll() error {
+	u.mu.Lock()
+	clients := u.clients
+	u.clients = make(map[string]*mcpclient.Client)
+	u.mu.Unlock()
+
+	var errs []error
+	for name, client := range clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("upstream %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ConnectUpstreams connects to every configured upstream in parallel, lists
+// its tools and resources and registers the tools of exposed upstreams as
+// functions, which are unregistered again when the connection ends. Upstreams
+// that fail are logged and reported in GET /upstreams; they are not retried.
+func (s *Server) ConnectUpstreams(ctx context.Context) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(s.config.Upstreams))
+	for i, cfg := range s.config.Upstreams {
+		wg.Add(1)
+		go func(i int, cfg UpstreamConfig) {
+			defer wg.Done()
+			if err := s.connectUpstream(ctx, cfg); err != nil {
+				s.logger.Warn("upstream MCP server unavailable", "upstream", cfg.Name, "error", err)
+				s.upstreams.mu.Lock()
+				s.upstreams.status[cfg.Name].Error = err.Error()
+				s.upstreams.mu.Unlock()
+				errs[i] = fmt.Errorf("upstream %s: %w", cfg.Name, err)
+			}
+		}(i, cfg)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (s *Server) connectUpstream(ctx context.Context, cfg UpstreamConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, upstreamConnectTimeout)
+	defer cancel()
+
+	transport, err := upstreamTransport(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	client, err := mcpclient.Connect(ctx, transport, mcpclient.WithClientInfo(MCPServerName, APIVersion))
+	if err != nil {
+		return err
+	}
+
+	status := UpstreamStatus{
+		Connected:       true,
+		Server:          client.Server().Name + " " + client.Server().Version,
+		ProtocolVersion: client.ProtocolVersion(),
+	}
+	var tools []mcpclient.Tool
+	if client.Capabilities().Tools != nil {
+		if tools, err = client.ListTools(ctx); err != nil {
+			client.Close()
+			return fmt.Errorf("list tools: %w", err)
+		}
+		for _, tool := range tools {
+			status.Tools = append(status.Tools, tool.Name)
+		}
+	}
+	if client.Capabilities().Resources != nil {
+		resources, err := client.ListResources(ctx)
+		if err != nil {
+			client.Close()
+			return fmt.Errorf("list resources: %w", err)
+		}
+		for _, resource := range resources {
+			status.Resources = append(status.Resources, resource.URI)
+		}
+	}
+
+	if cfg.Expose {
+		if s.functions == nil {
+			s.logger.Warn("cannot expose upstream tools with the functions feature disabled", "upstream", cfg.Name)
+		} else {
+			for _, tool := range tools {
+				name := cfg.Name + "." + tool.Name
+				if err := s.functions.RegisterFunction(name, s.upstreamTool(cfg.Name, tool.Name)); err != nil {
+					s.logger.Warn("cannot expose upstream tool", "upstream", cfg.Name, "tool", tool.Name, "error", err)
+					continue
+				}
+				status.Exposed = append(status.Exposed, name)
+			}
+		}
+	}
+
+	s.upstreams.mu.Lock()
+	s.upstreams.clients[cfg.Name] = client
+	status.Name = cfg.Name
+	status.Transport = s.upstreams.status[cfg.Name].Transport
+	s.upstreams.status[cfg.Name] = &status
+	s.upstreams.mu.Unlock()
+	s.logger.Info("connected upstream MCP server", "upstream", cfg.Name, "server", status.Server, "tools", len(status.Tools))
+	go s.unexpose(cfg.Name, client, status.Exposed)
+	return nil
+}
+
+// unexpose unregisters the functions of an upstream once its connection ends
+func (s *Server) unexpose(upstream string, client *mcpclient.Client, functions []string) {
+	<-client.Done()
+	for _, name := range functions {
+		s.functions.UnregisterFunction(name)
+	}
+	s.logger.Warn("upstream MCP server disconnected", "upstream", upstream, "error", client.Err())
+}
+
+func upstreamTransport(ctx context.Context, cfg UpstreamConfig) (mcpclient.Transport, error) {
+	if cfg.URL != "" {
+		header := make(http.Header)
+		for k, v := range cfg.Headers {
+			header.Set(k, v)
+		}
+		return mcpclient.NewSSETransport(ctx, cfg.URL, header)
+	}
+
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stderr = os.Stderr
+	return mcpclient.NewCommandTransport(cmd)
+}
+
+// upstreamTool returns a function calling a tool of an upstream with named
+// arguments, abandoned with the request calling it
+func (s *Server) upstreamTool(upstream, tool string) func(ctx context.Context, arguments map[string]interface{}) (*mcpclient.ToolResult, error) {
+	return func(ctx context.Context, arguments map[string]interface{}) (*mcpclient.ToolResult, error) {
+		client, err := s.upstreams.client(upstream)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(ctx, upstreamCallTimeout)
+		defer cancel()
+
+		result, err := client.CallTool(ctx, tool, arguments)
+		if err != nil {
+			var rpcErr *mcpclient.RPCError
+			if errors.As(err, &rpcErr) {
+				return nil, fmt.Errorf("%w: %s.%s: %v", ErrUpstreamTool, upstream, tool, err)
+			}
+			return nil, fmt.Errorf("%w: %s: %v", ErrUpstreamUnavailable, upstream, err)
+		}
+		if result.IsError {
+			return result, fmt.Errorf("%w: %s.%s: %s", ErrUpstreamTool, upstream, tool, result.Text())
+		}
+		return result, nil
+	}
+}
+
+func isUpstreamError(err error) bool {
+	return errors.Is(err, ErrUpstreamUnavailable) || errors.Is(err, ErrUpstreamTool)
+}
+
+func handleListUpstreams(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.upstreams.list())
+	}
+}
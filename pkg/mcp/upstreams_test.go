@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/mcpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveLegacySSE serves s to MCP clients over HTTP with server-sent events,
+// bridging every stream to a stdio session
+func serveLegacySSE(t *testing.T, s *Server) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	sessions := make(map[string]io.Writer)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		inR, inW := io.Pipe()
+		outR, outW := io.Pipe()
+		id := newRequestID()
+		mu.Lock()
+		sessions[id] = inW
+		mu.Unlock()
+		go func() {
+			s.ServeStdio(r.Context(), inR, outW)
+			inW.Close()
+			outW.Close()
+		}()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, ": connected\n\nevent: endpoint\ndata: /message?session=%s\n\n", id)
+		w.(http.Flusher).Flush()
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", scanner.Bytes())
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux.HandleFunc("/message", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		in, ok := sessions[r.URL.Query().Get("session")]
+		mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		in.Write(append(body, '\n'))
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestUpstreams(t *testing.T) {
+	upstream := NewServer(nil)
+	upstream.AddFunctionHandler()
+	srv := serveLegacySSE(t, upstream)
+
+	cfg := DefaultConfig()
+	cfg.Upstreams = []UpstreamConfig{
+		{Name: "peer", URL: srv.URL + "/sse", Expose: true},
+		{Name: "gone", URL: srv.URL + "/missing"},
+	}
+	require.NoError(t, cfg.Validate())
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddFunctionHandler()
+
+	err := s.ConnectUpstreams(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upstream gone")
+
+	// Exposed tools are functions taking the named arguments of the tool
+	result, err := s.functions.Call("peer.function_call", []interface{}{
+		map[string]interface{}{"name": "echo", "arguments": []interface{}{"hi"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `"hi"`, result.(*mcpclient.ToolResult).Text())
+
+	_, err = s.functions.Call("peer.function_call", []interface{}{map[string]interface{}{"name": "missing"}})
+	assert.ErrorIs(t, err, ErrUpstreamTool)
+
+	r := httptest.NewRequest("POST", "/v1/function/call", strings.NewReader(`{"name":"peer.function_call","arguments":[{"name":"missing"}]}`))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/upstreams", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var statuses []UpstreamStatus
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&statuses))
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "gone", statuses[0].Name)
+	assert.False(t, statuses[0].Connected)
+	assert.Contains(t, statuses[0].Error, "404")
+	assert.Equal(t, "peer", statuses[1].Name)
+	assert.True(t, statuses[1].Connected)
+	assert.Equal(t, "sse", statuses[1].Transport)
+	assert.Contains(t, statuses[1].Tools, "function_call")
+	assert.Contains(t, statuses[1].Exposed, "peer.function_call")
+	assert.Equal(t, MCPServerName+" "+APIVersion, statuses[1].Server)
+
+	// Exposed tools go away with their upstream
+	require.NoError(t, s.Shutdown(context.Background()))
+	assert.Eventually(t, func() bool {
+		_, err := s.functions.Call("peer.function_call", []interface{}{map[string]interface{}{"name": "echo"}})
+		return errors.Is(err, ErrFunctionNotFound)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestUpstreamConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		upstreams []UpstreamConfig
+		wantErr   string
+	}{
+		{"command", []UpstreamConfig{{Name: "fs", Command: "mcp-fs"}}, ""},
+		{"url", []UpstreamConfig{{Name: "web", URL: "http://localhost:3000/sse"}}, ""},
+		{"no name", []UpstreamConfig{{Command: "mcp-fs"}}, "without name"},
+		{"neither", []UpstreamConfig{{Name: "fs"}}, "exactly one"},
+		{"both", []UpstreamConfig{{Name: "fs", Command: "mcp-fs", URL: "http://localhost"}}, "exactly one"},
+		{"duplicate", []UpstreamConfig{{Name: "fs", Command: "a"}, {Name: "fs", Command: "b"}}, "duplicate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Upstreams = tt.upstreams
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,472 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// ErrQuotaExceeded is returned when a tenant has used up a quota
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Usage metrics. Storage is a level that goes down again when contexts are
+// deleted; the others accumulate until the usage window resets.
+const (
+	MetricRequests       = "requests"
+	MetricSSHExecMinutes = "ssh_exec_minutes"
+	MetricBrowserMinutes = "browser_minutes"
+	MetricStorageBytes   = "storage_bytes"
+	MetricFunctionCalls  = "function_calls"
+)
+
+func allMetrics() []string {
+	return []string{MetricRequests, MetricSSHExecMinutes, MetricBrowserMinutes, MetricStorageBytes, MetricFunctionCalls}
+}
+
+// anonymousTenant is charged for requests without an API key
+const anonymousTenant = "anonymous"
+
+// ScopeAdmin grants changing the runtime state of the server, such as
+// switching features and capabilities through /admin/features
+const ScopeAdmin = "admin"
+
+// UsageConfig configures per-tenant usage accounting. A tenant is the owner
+// of one or more API keys; keys not assigned to a tenant are tenants of their
+// own with the default quota and scopes. Usage is kept in memory only.
+type UsageConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Window is the accounting period after which usage resets, e.g. 24h.
+	// Usage never resets when zero.
+	Window        time.Duration           `yaml:"window"`
+	Default       Usage                   `yaml:"default"`        // Quota of unassigned keys
+	DefaultScopes []string                `yaml:"default_scopes"` // Scopes of unassigned keys and requests without a key
+	Tenants       map[string]TenantConfig `yaml:"tenants"`
+}
+
+// TenantConfig assigns API keys, a quota and scopes to a tenant. Scopes
+// grant calling the functions registered as requiring them.
+type TenantConfig struct {
+	Keys   []string `yaml:"keys"`
+	Quota  Usage    `yaml:"quota"`
+	Scopes []string `yaml:"scopes"`
+}
+
+// Usage maps metrics to amounts. As a quota, missing or zero metrics are
+// unlimited.
+type Usage map[string]float64
+
+// validate checks the metrics of a quota
+func (u Usage) validate() error {
+	for metric, amount := range u {
+		if !containsString(allMetrics(), metric) {
+			return fmt.Errorf("unknown usage metric %q", metric)
+		}
+		if amount < 0 {
+			return fmt.Errorf("negative quota for %s", metric)
+		}
+	}
+	return nil
+}
+
+// Validate checks the tenants and quotas
+func (c UsageConfig) Validate() error {
+	if err := c.Default.validate(); err != nil {
+		return err
+	}
+	owners := make(map[string]string)
+	for name, tenant := range c.Tenants {
+		if err := tenant.Quota.validate(); err != nil {
+			return fmt.Errorf("tenant %s: %v", name, err)
+		}
+		for _, key := range tenant.Keys {
+			if owner, ok := owners[key]; ok {
+				return fmt.Errorf("API key is assigned to both %s and %s", owner, name)
+			}
+			owners[key] = name
+		}
+	}
+	return nil
+}
+
+// TenantUsage reports the usage of a tenant in the current window
+type TenantUsage struct {
+	Tenant      string     `json:"tenant"`
+	Usage       Usage      `json:"usage"`
+	Quota       Usage      `json:"quota"`
+	WindowStart time.Time  `json:"window_start"`
+	ResetsAt    *time.Time `json:"resets_at,omitempty"`
+}
+
+type tenantUsage struct {
+	usage       Usage
+	windowStart time.Time
+}
+
+// storageCharge is the storage a tenant is charged for an object
+type storageCharge struct {
+	tenant string
+	bytes  float64
+}
+
+// UsageTracker accounts usage per tenant and enforces quotas. A nil tracker
+// accounts nothing and allows everything.
+type UsageTracker struct {
+	config  UsageConfig
+	keys    map[string]string // Tenant by API key
+	tenants map[string]*tenantUsage
+	storage map[string]storageCharge // By object, e.g. context:ID
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// NewUsageTracker creates a tracker from the configuration
+func NewUsageTracker(config UsageConfig) *UsageTracker {
+	t := &UsageTracker{
+		config:  config,
+		keys:    make(map[string]string),
+		tenants: make(map[string]*tenantUsage),
+		storage: make(map[string]storageCharge),
+		now:     time.Now,
+	}
+	for name, tenant := range config.Tenants {
+		for _, key := range tenant.Keys {
+			t.keys[key] = name
+		}
+	}
+	return t
+}
+
+// Tenant returns the tenant owning an API key. Unassigned keys are
+// identified by a digest so reports never reveal them.
+func (t *UsageTracker) Tenant(key string) string {
+	if key == "" {
+		return anonymousTenant
+	}
+	if name, ok := t.keys[key]; ok {
+		return name
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:6])
+}
+
+// Scopes returns the scopes granted to a tenant
+func (t *UsageTracker) Scopes(tenant string) []string {
+	scopes := t.config.DefaultScopes
+	if c, ok := t.config.Tenants[tenant]; ok {
+		scopes = c.Scopes
+	}
+	return append([]string{}, scopes...)
+}
+
+func (t *UsageTracker) quota(tenant string) Usage {
+	if c, ok := t.config.Tenants[tenant]; ok {
+		return c.Quota
+	}
+	return t.config.Default
+}
+
+// current returns the usage of a tenant, starting a new window when the
+// previous one has ended. Storage carries over. The caller holds t.mu.
+func (t *UsageTracker) current(tenant string) *tenantUsage {
+	now := t.now()
+	u, ok := t.tenants[tenant]
+	if !ok {
+		u = &tenantUsage{usage: make(Usage), windowStart: now}
+		t.tenants[tenant] = u
+	} else if t.config.Window > 0 && now.Sub(u.windowStart) >= t.config.Window {
+		u.usage = Usage{MetricStorageBytes: u.usage[MetricStorageBytes]}
+		u.windowStart = now
+	}
+	return u
+}
+
+// exceeds returns an error wrapping ErrQuotaExceeded if used is over the
+// tenant's quota for metric
+func (t *UsageTracker) exceeds(tenant, metric string, used float64) error {
+	if limit := t.quota(tenant)[metric]; limit > 0 && used > limit {
+		return quotaError(tenant, metric, limit)
+	}
+	return nil
+}
+
+func quotaError(tenant, metric string, limit float64) error {
+	return fmt.Errorf("%w: %s of %s (limit %s)", ErrQuotaExceeded, metric, tenant, strconv.FormatFloat(limit, 'f', -1, 64))
+}
+
+// Check reports whether a tenant has any of a metric's quota left, for
+// metrics charged once the amount is known, such as minutes
+func (t *UsageTracker) Check(tenant, metric string) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	used := t.current(tenant).usage[metric]
+	if limit := t.quota(tenant)[metric]; limit > 0 && used >= limit {
+		return quotaError(tenant, metric, limit)
+	}
+	return nil
+}
+
+// Consume charges amount to a tenant unless that would exceed its quota
+func (t *UsageTracker) Consume(tenant, metric string, amount float64) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	u := t.current(tenant)
+	if err := t.exceeds(tenant, metric, u.usage[metric]+amount); err != nil {
+		return err
+	}
+	u.usage[metric] += amount
+	return nil
+}
+
+// Charge adds amount to a tenant's usage even past its quota, for usage that
+// has already happened
+func (t *UsageTracker) Charge(tenant, metric string, amount float64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current(tenant).usage[metric] += amount
+}
+
+// CheckStorage reports whether a tenant may store an object of the given
+// size, counting the object's current size as freed
+func (t *UsageTracker) CheckStorage(tenant, object string, bytes int) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	used := t.current(tenant).usage[MetricStorageBytes]
+	if prev, ok := t.storage[object]; ok && prev.tenant == tenant {
+		used -= prev.bytes
+	}
+	return t.exceeds(tenant, MetricStorageBytes, used+float64(bytes))
+}
+
+// SetStorage charges a stored object to a tenant, replacing any previous
+// charge for it
+func (t *UsageTracker) SetStorage(tenant, object string, bytes int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.release(object)
+	t.current(tenant).usage[MetricStorageBytes] += float64(bytes)
+	t.storage[object] = storageCharge{tenant: tenant, bytes: float64(bytes)}
+}
+
+// ReleaseStorage stops charging for a deleted object
+func (t *UsageTracker) ReleaseStorage(object string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.release(object)
+}
+
+func (t *UsageTracker) release(object string) {
+	prev, ok := t.storage[object]
+	if !ok {
+		return
+	}
+	delete(t.storage, object)
+	if u, ok := t.tenants[prev.tenant]; ok {
+		u.usage[MetricStorageBytes] -= prev.bytes
+	}
+}
+
+// Report returns the usage and quota of a tenant
+func (t *UsageTracker) Report(tenant string) TenantUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.report(tenant, t.current(tenant))
+}
+
+// Reports returns the usage of every tenant seen so far, sorted by tenant
+func (t *UsageTracker) Reports() []TenantUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	reports := make([]TenantUsage, 0, len(t.tenants))
+	for name := range t.tenants {
+		reports = append(reports, t.report(name, t.current(name)))
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Tenant < reports[j].Tenant })
+	return reports
+}
+
+func (t *UsageTracker) report(tenant string, u *tenantUsage) TenantUsage {
+	r := TenantUsage{Tenant: tenant, Usage: make(Usage), Quota: make(Usage), WindowStart: u.windowStart}
+	for metric, amount := range u.usage {
+		r.Usage[metric] = amount
+	}
+	for metric, amount := range t.quota(tenant) {
+		r.Quota[metric] = amount
+	}
+	if t.config.Window > 0 {
+		resets := u.windowStart.Add(t.config.Window)
+		r.ResetsAt = &resets
+	}
+	return r
+}
+
+type (
+	tenantKey struct{}
+	scopesKey struct{}
+)
+
+// TenantFromContext returns the tenant a request is accounted to, or an empty
+// string when usage accounting is disabled
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// ScopesFromContext returns the scopes granted to the tenant of a request.
+// Requests of no tenant, when usage accounting is disabled or the call is
+// made in process, are not limited by scopes, and ok is false.
+func ScopesFromContext(ctx context.Context) (scopes []string, ok bool) {
+	scopes, ok = ctx.Value(scopesKey{}).([]string)
+	return scopes, ok
+}
+
+// requireScope returns an error wrapping ErrScopeRequired unless the caller
+// was granted scope. Callers of no tenant, when usage accounting is
+// disabled, are granted no scopes.
+func requireScope(ctx context.Context, scope string) error {
+	granted, _ := ScopesFromContext(ctx)
+	if !containsString(granted, scope) {
+		return fmt.Errorf("%w: %s", ErrScopeRequired, scope)
+	}
+	return nil
+}
+
+// withTenant stores the tenant of a request and its scopes in ctx
+func (t *UsageTracker) withTenant(ctx context.Context, tenant string) context.Context {
+	ctx = context.WithValue(ctx, tenantKey{}, tenant)
+	return context.WithValue(ctx, scopesKey{}, t.Scopes(tenant))
+}
+
+// Middleware accounts every request to the tenant of its API key and
+// rejects requests once the tenant's request quota is used up
+func (t *UsageTracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := t.Tenant(apiKey(r))
+		if err := t.Consume(tenant, MetricRequests, 1); err != nil {
+			writeError(w, http.StatusTooManyRequests, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(t.withTenant(r.Context(), tenant)))
+	})
+}
+
+// grpcTenant returns the tenant of a call's API key, read from x-api-key or
+// bearer authorization metadata
+func (t *UsageTracker) grpcTenant(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get("x-api-key"); len(keys) > 0 {
+		return t.Tenant(keys[0])
+	}
+	if auth := md.Get("authorization"); len(auth) > 0 && strings.HasPrefix(auth[0], "Bearer ") {
+		return t.Tenant(strings.TrimPrefix(auth[0], "Bearer "))
+	}
+	return t.Tenant("")
+}
+
+// contextObject names a context in storage accounting
+func contextObject(id string) string {
+	return "context:" + id
+}
+
+func metadataSize(c *Context) int {
+	data, _ := json.Marshal(c.Metadata)
+	return len(data)
+}
+
+// createContext stores a new context, charging its metadata to the storage
+// quota of the caller
+func (s *Server) createContext(ctx context.Context, c *Context) error {
+	tenant, size := TenantFromContext(ctx), metadataSize(c)
+	if err := s.usage.CheckStorage(tenant, contextObject(c.ID), size); err != nil {
+		return err
+	}
+	if err := s.store.Create(c); err != nil {
+		return err
+	}
+	s.usage.SetStorage(tenant, contextObject(c.ID), size)
+	s.contexts.publish(c.ID, contextCreated)
+	return nil
+}
+
+// updateContext stores an updated context; the caller is charged for it from
+// then on
+func (s *Server) updateContext(ctx context.Context, c *Context) error {
+	tenant, size := TenantFromContext(ctx), metadataSize(c)
+	if err := s.usage.CheckStorage(tenant, contextObject(c.ID), size); err != nil {
+		return err
+	}
+	if err := s.store.Update(c); err != nil {
+		return err
+	}
+	s.usage.SetStorage(tenant, contextObject(c.ID), size)
+	s.contexts.publish(c.ID, contextUpdated)
+	return nil
+}
+
+// deleteContext deletes a context and stops charging for it
+func (s *Server) deleteContext(id string) error {
+	if err := s.store.Delete(id); err != nil {
+		return err
+	}
+	s.usage.ReleaseStorage(contextObject(id))
+	s.contexts.publish(id, contextDeleted)
+	return nil
+}
+
+// meterMinutes checks that the tenant of ctx has minutes of a metric left
+// and returns a function charging the time elapsed until it is called
+func (s *Server) meterMinutes(ctx context.Context, metric string) (func(), error) {
+	tenant := TenantFromContext(ctx)
+	if err := s.usage.Check(tenant, metric); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	return func() {
+		s.usage.Charge(tenant, metric, time.Since(start).Minutes())
+	}, nil
+}
+
+func handleUsage(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.usage.Report(TenantFromContext(r.Context())))
+	}
+}
+
+func handleAllUsage(s *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.usage.Reports())
+	}
+}
@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageTracker_Window(t *testing.T) {
+	tracker := NewUsageTracker(UsageConfig{
+		Enabled: true,
+		Window:  time.Hour,
+		Tenants: map[string]TenantConfig{
+			"team-a": {Keys: []string{"key-a1", "key-a2"}, Quota: Usage{MetricFunctionCalls: 2, MetricStorageBytes: 100}},
+		},
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	assert.Equal(t, "team-a", tracker.Tenant("key-a2"))
+	assert.Equal(t, anonymousTenant, tracker.Tenant(""))
+	assert.NotContains(t, tracker.Tenant("secret"), "secret")
+
+	require.NoError(t, tracker.Consume("team-a", MetricFunctionCalls, 1))
+	require.NoError(t, tracker.Consume("team-a", MetricFunctionCalls, 1))
+	assert.ErrorIs(t, tracker.Consume("team-a", MetricFunctionCalls, 1), ErrQuotaExceeded)
+	assert.ErrorIs(t, tracker.Check("team-a", MetricFunctionCalls), ErrQuotaExceeded)
+
+	// Replacing an object only charges the difference
+	tracker.SetStorage("team-a", "context:a", 60)
+	require.NoError(t, tracker.CheckStorage("team-a", "context:a", 90))
+	assert.ErrorIs(t, tracker.CheckStorage("team-a", "context:b", 50), ErrQuotaExceeded)
+
+	// A new window resets counters but not storage
+	now = now.Add(time.Hour)
+	require.NoError(t, tracker.Consume("team-a", MetricFunctionCalls, 1))
+	report := tracker.Report("team-a")
+	assert.Equal(t, 1.0, report.Usage[MetricFunctionCalls])
+	assert.Equal(t, 60.0, report.Usage[MetricStorageBytes])
+	assert.Equal(t, now.Add(time.Hour), *report.ResetsAt)
+
+	tracker.ReleaseStorage("context:a")
+	assert.Equal(t, 0.0, tracker.Report("team-a").Usage[MetricStorageBytes])
+
+	// A nil tracker allows everything
+	var disabled *UsageTracker
+	assert.NoError(t, disabled.Consume("team-a", MetricRequests, 1e9))
+}
+
+func TestUsage_Quotas(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Usage = UsageConfig{
+		Enabled: true,
+		Default: Usage{MetricRequests: 5},
+		Tenants: map[string]TenantConfig{
+			"agents": {Keys: []string{"agent-key"}, Quota: Usage{MetricFunctionCalls: 1, MetricStorageBytes: 30}},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddFunctionHandler()
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	call := `{"name":"echo","arguments":["hi"]}`
+	assert.Equal(t, http.StatusOK, do("POST", "/v1/function/call", "agent-key", call).Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("POST", "/v1/function/call", "agent-key", call).Code)
+
+	assert.Equal(t, http.StatusCreated, do("POST", "/v1/context/create", "agent-key", `{"id":"small","metadata":{"k":"v"}}`).Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("POST", "/v1/context/create", "agent-key", `{"id":"big","metadata":{"k":"a long value over quota"}}`).Code)
+
+	rec := do("GET", "/v1/usage", "agent-key", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var report TenantUsage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, "agents", report.Tenant)
+	assert.Equal(t, 1.0, report.Usage[MetricFunctionCalls])
+	assert.Equal(t, float64(len(`{"k":"v"}`)), report.Usage[MetricStorageBytes])
+	assert.Equal(t, 1.0, report.Quota[MetricFunctionCalls])
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/v1/context/delete?id=small", "agent-key", "").Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/v1/context/create", "agent-key", `{"id":"big","metadata":{"k":"a longer value"}}`).Code)
+
+	// Unassigned keys get the default quota
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusOK, do("GET", "/v1/context/list", "other-key", "").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, do("GET", "/v1/context/list", "other-key", "").Code)
+
+	rec = do("GET", "/v1/admin/usage", "agent-key", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var reports []TenantUsage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &reports))
+	require.Len(t, reports, 2)
+	assert.Equal(t, "agents", reports[0].Tenant)
+	assert.Equal(t, 5.0, reports[1].Usage[MetricRequests])
+}
+
+func TestUsageConfig_Validate(t *testing.T) {
+	assert.Error(t, UsageConfig{Default: Usage{"gpu_hours": 1}}.Validate())
+	assert.Error(t, UsageConfig{Tenants: map[string]TenantConfig{
+		"a": {Keys: []string{"k"}}, "b": {Keys: []string{"k"}},
+	}}.Validate())
+}
@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIVersion is the current API version. Routes are served under /v1/...
+const APIVersion = "v1"
+
+// APIConfig configures API versioning
+type APIConfig struct {
+	// LegacyRoutes keeps serving unversioned routes such as /context/list for
+	// clients written before versioning. Responses are marked deprecated.
+	LegacyRoutes bool `yaml:"legacy_routes"`
+	// LegacySunset is an optional HTTP date announced in the Sunset header of
+	// legacy responses
+	LegacySunset string `yaml:"legacy_sunset"`
+}
+
+// apiVersions dispatches /{version}/... requests to the handler registered
+// for that version, and unversioned requests to the current version when
+// legacy routes are enabled. A future breaking version gets its own router
+// here while older versions keep being served.
+type apiVersions struct {
+	versions map[string]http.Handler
+	root     map[string]http.Handler
+	current  string
+	config   APIConfig
+}
+
+func newAPIVersions(config APIConfig) *apiVersions {
+	return &apiVersions{
+		versions: make(map[string]http.Handler),
+		root:     make(map[string]http.Handler),
+		current:  APIVersion,
+		config:   config,
+	}
+}
+
+// mount serves handler under /{version}; handler routes are unprefixed
+func (v *apiVersions) mount(version string, handler http.Handler) {
+	v.versions[version] = handler
+}
+
+// handleRoot serves handler at an unversioned path that is not deprecated,
+// such as /openapi.json
+func (v *apiVersions) handleRoot(path string, handler http.Handler) {
+	v.root[path] = handler
+}
+
+func (v *apiVersions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler, ok := v.root[r.URL.Path]; ok {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	segment := strings.TrimPrefix(r.URL.Path, "/")
+	if i := strings.Index(segment, "/"); i >= 0 {
+		segment = segment[:i]
+	}
+
+	if handler, ok := v.versions[segment]; ok {
+		http.StripPrefix("/"+segment, handler).ServeHTTP(w, r)
+		return
+	}
+
+	successor := "/" + v.current + r.URL.Path
+	if !v.config.LegacyRoutes {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unversioned routes are disabled, use %s", successor))
+		return
+	}
+
+	h := w.Header()
+	h.Set("Deprecation", "true")
+	h.Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+	if v.config.LegacySunset != "" {
+		h.Set("Sunset", v.config.LegacySunset)
+	}
+
+	// Legacy paths are the current version's routes without the prefix
+	v.versions[v.current].ServeHTTP(w, r)
+}
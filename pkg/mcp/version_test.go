@@ -0,0 +1,37 @@
+package mcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIVersions(t *testing.T) {
+	serve := func(s *Server, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	s := NewServer(nil)
+
+	rec := serve(s, "/v1/context/list")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+
+	rec = serve(s, "/context/list")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Equal(t, `</v1/context/list>; rel="successor-version"`, rec.Header().Get("Link"))
+
+	cfg := DefaultConfig()
+	cfg.API = APIConfig{LegacyRoutes: false}
+	s = NewServer(nil, WithConfig(cfg))
+
+	assert.Equal(t, http.StatusOK, serve(s, "/v1/context/list").Code)
+	rec = serve(s, "/context/list")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "/v1/context/list")
+}
@@ -0,0 +1,287 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/webdav"
+)
+
+// WebDAVConfig configures the WebDAV view of the workspace, so editors and
+// tools can mount the project. Each root is served under /v1/dav/NAME/.
+type WebDAVConfig struct {
+	Enabled  bool              `yaml:"enabled"`
+	Roots    map[string]string `yaml:"roots"`    // Directories by mount name, defaults to the workspace root as "workspace"
+	Writable bool              `yaml:"writable"` // Allow changes; read-only by default
+	Hidden   []string          `yaml:"hidden"`   // Path patterns that can neither be read nor written
+}
+
+// defaultHidden keeps repository internals and secrets out of mounts
+var defaultHidden = []string{".git", ".env", ".env.*"}
+
+// Validate checks the WebDAV config when WebDAV is enabled
+func (c WebDAVConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	for name, dir := range c.Roots {
+		if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+			return fmt.Errorf("invalid root name %q", name)
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("root %s: %v", name, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("root %s: %s is not a directory", name, dir)
+		}
+	}
+	for _, pattern := range c.Hidden {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid hidden pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// webdavWriteMethods change the served files or their locks
+var webdavWriteMethods = map[string]bool{
+	"PUT": true, "DELETE": true, "MKCOL": true, "COPY": true, "MOVE": true,
+	"PROPPATCH": true, "LOCK": true, "UNLOCK": true,
+}
+
+// newWebDAV creates the WebDAV handler of the configured roots. Changes are
+// logged with the tenant making them. Deleting files, including by moving
+// them or overwriting them with copies, needs the file.delete capability.
+func (s *Server) newWebDAV() http.Handler {
+	config := s.config.WebDAV
+	roots := config.Roots
+	if len(roots) == 0 {
+		roots = map[string]string{"workspace": s.GetWorkspaceRoot()}
+	}
+	hidden := append(append([]string(nil), defaultHidden...), config.Hidden...)
+
+	handlers := make(map[string]*webdav.Handler, len(roots))
+	for name, dir := range roots {
+		fsys, err := newSandboxFS(dir, config.Writable, hidden, func() error {
+			return s.checkCapability(CapabilityFileDelete)
+		})
+		if err != nil {
+			s.logger.Error("webdav root unavailable", "root", name, "dir", dir, "error", err)
+			continue
+		}
+		handlers[name] = &webdav.Handler{
+			FileSystem: fsys,
+			LockSystem: webdav.NewMemLS(),
+			Logger:     auditWebDAV(name),
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if name == "" {
+			writeJSON(w, http.StatusOK, sortedRootNames(handlers))
+			return
+		}
+		h, ok := handlers[name]
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("webdav root %s not found", name))
+			return
+		}
+		if webdavWriteMethods[r.Method] && !config.Writable {
+			writeError(w, http.StatusForbidden, fmt.Errorf("webdav root %s is read-only", name))
+			return
+		}
+		if r.Method == "DELETE" || r.Method == "MOVE" {
+			if err := s.checkCapability(CapabilityFileDelete); err != nil {
+				writeError(w, http.StatusForbidden, newAPIError(CodeFeatureDisabled, err, map[string]interface{}{"capability": CapabilityFileDelete}))
+				return
+			}
+		}
+
+		// Responses and Destination headers use the paths clients see, so
+		// the handler works on the request path before prefixes were stripped
+		external := requestPath(r)
+		prefix := strings.TrimSuffix(external, r.URL.Path) + "/" + name
+		r2 := r.Clone(r.Context())
+		r2.URL.Path, r2.URL.RawPath = external, ""
+		handler := *h
+		handler.Prefix = prefix
+		handler.ServeHTTP(w, r2)
+	})
+}
+
+// requestPath returns the path a client requested, before handlers stripped
+// prefixes from the request URL
+func requestPath(r *http.Request) string {
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil && strings.HasSuffix(u.Path, r.URL.Path) {
+		return u.Path
+	}
+	return r.URL.Path
+}
+
+// auditWebDAV logs changes, and failures, made through a root
+func auditWebDAV(root string) func(*http.Request, error) {
+	return func(r *http.Request, err error) {
+		logger := LoggerFromContext(r.Context())
+		attrs := []interface{}{"root", root, "method", r.Method, "path", r.URL.Path, "tenant", TenantFromContext(r.Context())}
+		if dest := r.Header.Get("Destination"); dest != "" {
+			attrs = append(attrs, "destination", dest)
+		}
+		switch {
+		case err != nil:
+			logger.Warn("webdav request failed", append(attrs, "error", err)...)
+		case webdavWriteMethods[r.Method]:
+			logger.Info("webdav change", attrs...)
+		default:
+			logger.Debug("webdav request", attrs...)
+		}
+	}
+}
+
+func sortedRootNames(handlers map[string]*webdav.Handler) []string {
+	names := make([]string, 0, len(handlers))
+	for name := range handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sandboxFS confines WebDAV to a directory. Symbolic links leading out of it
+// are refused, hidden paths do not exist, changes fail unless writable and
+// removals fail while canRemove does.
+type sandboxFS struct {
+	dir       webdav.Dir
+	root      string // Absolute, with symbolic links resolved
+	writable  bool
+	hidden    []string
+	canRemove func() error
+}
+
+func newSandboxFS(dir string, writable bool, hidden []string, canRemove func() error) (*sandboxFS, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, err
+	}
+	return &sandboxFS{dir: webdav.Dir(root), root: root, writable: writable, hidden: hidden, canRemove: canRemove}, nil
+}
+
+// isHidden reports whether a slash separated path or one of its parents
+// matches a hidden pattern
+func (f *sandboxFS) isHidden(name string) bool {
+	for _, segment := range strings.Split(strings.Trim(path.Clean("/"+name), "/"), "/") {
+		for _, pattern := range f.hidden {
+			if ok, _ := path.Match(pattern, segment); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// check refuses hidden paths, changes to a read-only root and paths whose
+// nearest existing ancestor resolves outside the root
+func (f *sandboxFS) check(name string, write bool) error {
+	if f.isHidden(name) {
+		return os.ErrNotExist
+	}
+	if write && !f.writable {
+		return os.ErrPermission
+	}
+	p := filepath.Join(f.root, filepath.FromSlash(path.Clean("/"+name)))
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			if resolved != f.root && !strings.HasPrefix(resolved, f.root+string(filepath.Separator)) {
+				return os.ErrPermission
+			}
+			return nil
+		}
+		if !os.IsNotExist(err) || p == f.root {
+			return err
+		}
+		p = filepath.Dir(p)
+	}
+}
+
+func (f *sandboxFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if err := f.check(name, true); err != nil {
+		return err
+	}
+	return f.dir.Mkdir(ctx, name, perm)
+}
+
+func (f *sandboxFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	write := flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0
+	if err := f.check(name, write); err != nil {
+		return nil, err
+	}
+	file, err := f.dir.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &sandboxFile{File: file, fs: f, name: name}, nil
+}
+
+func (f *sandboxFS) RemoveAll(ctx context.Context, name string) error {
+	if err := f.check(name, true); err != nil {
+		return err
+	}
+	if f.canRemove() != nil {
+		return os.ErrPermission
+	}
+	if strings.Trim(path.Clean("/"+name), "/") == "" {
+		return os.ErrPermission // The root itself
+	}
+	return f.dir.RemoveAll(ctx, name)
+}
+
+func (f *sandboxFS) Rename(ctx context.Context, oldName, newName string) error {
+	if err := f.check(oldName, true); err != nil {
+		return err
+	}
+	if err := f.check(newName, true); err != nil {
+		return err
+	}
+	if f.canRemove() != nil {
+		return os.ErrPermission
+	}
+	return f.dir.Rename(ctx, oldName, newName)
+}
+
+func (f *sandboxFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if err := f.check(name, false); err != nil {
+		return nil, err
+	}
+	return f.dir.Stat(ctx, name)
+}
+
+// sandboxFile leaves hidden entries out of directory listings
+type sandboxFile struct {
+	webdav.File
+	fs   *sandboxFS
+	name string
+}
+
+func (f *sandboxFile) Readdir(count int) ([]fs.FileInfo, error) {
+	infos, err := f.File.Readdir(count)
+	visible := infos[:0]
+	for _, info := range infos {
+		if !f.fs.isHidden(path.Join(f.name, info.Name())) {
+			visible = append(visible, info)
+		}
+	}
+	return visible, err
+}
@@ -0,0 +1,155 @@
+package mcp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWebDAVServer(t *testing.T, writable bool) (*httptest.Server, string) {
+	t.Helper()
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".git", "config"), []byte("[core]\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret\n"), 0o644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+
+	cfg := DefaultConfig()
+	cfg.WebDAV = WebDAVConfig{Enabled: true, Roots: map[string]string{"project": root}, Writable: writable}
+	require.NoError(t, cfg.Validate())
+	srv := httptest.NewServer(NewServer(nil, WithConfig(cfg)))
+	t.Cleanup(srv.Close)
+	return srv, root
+}
+
+func davRequest(t *testing.T, method, url, body string, header map[string]string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestWebDAV_ReadOnly(t *testing.T) {
+	srv, _ := newWebDAVServer(t, false)
+	base := srv.URL + "/v1/dav/project"
+
+	status, body := davRequest(t, "GET", base+"/main.go", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "package main\n", body)
+
+	status, body = davRequest(t, "PROPFIND", base+"/", "", map[string]string{"Depth": "1"})
+	require.Equal(t, http.StatusMultiStatus, status)
+	assert.Contains(t, body, "<D:href>/v1/dav/project/main.go</D:href>")
+	assert.NotContains(t, body, ".git")
+
+	status, _ = davRequest(t, "GET", base+"/.git/config", "", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = davRequest(t, "GET", base+"/escape/secret", "", nil)
+	assert.NotEqual(t, http.StatusOK, status)
+	status, _ = davRequest(t, "PUT", base+"/new.go", "package new\n", nil)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, body = davRequest(t, "GET", srv.URL+"/v1/dav/", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `["project"]`, body)
+	status, _ = davRequest(t, "GET", srv.URL+"/v1/dav/missing/main.go", "", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestWebDAV_Writable(t *testing.T) {
+	srv, root := newWebDAVServer(t, true)
+	base := srv.URL + "/v1/dav/project"
+
+	status, _ := davRequest(t, "PUT", base+"/pkg/util.go", "package pkg\n", nil)
+	assert.Equal(t, http.StatusConflict, status, "parent collection is missing")
+	status, _ = davRequest(t, "MKCOL", base+"/pkg", "", nil)
+	require.Equal(t, http.StatusCreated, status)
+	status, _ = davRequest(t, "PUT", base+"/pkg/util.go", "package pkg\n", nil)
+	require.Equal(t, http.StatusCreated, status)
+
+	status, _ = davRequest(t, "MOVE", base+"/pkg/util.go", "", map[string]string{"Destination": base + "/pkg/helpers.go"})
+	require.Equal(t, http.StatusCreated, status)
+	data, err := os.ReadFile(filepath.Join(root, "pkg", "helpers.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package pkg\n", string(data))
+
+	status, _ = davRequest(t, "PUT", base+"/.git/config", "[hooks]\n", nil)
+	assert.NotEqual(t, http.StatusCreated, status)
+	status, _ = davRequest(t, "PUT", base+"/escape/secret", "gotcha\n", nil)
+	assert.NotEqual(t, http.StatusCreated, status)
+	status, _ = davRequest(t, "DELETE", base+"/", "", nil)
+	assert.NotEqual(t, http.StatusNoContent, status)
+
+	data, err = os.ReadFile(filepath.Join(root, "escape", "secret"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret\n", string(data))
+	_, err = os.Stat(filepath.Join(root, "main.go"))
+	assert.NoError(t, err)
+}
+
+func TestWebDAV_FileDelete(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "old.go"), []byte("package old\n"), 0o644))
+	cfg := DefaultConfig()
+	cfg.WebDAV = WebDAVConfig{Enabled: true, Roots: map[string]string{"project": root}, Writable: true}
+	cfg.Capabilities[CapabilityFileDelete] = false
+	srv := httptest.NewServer(NewServer(nil, WithConfig(cfg)))
+	defer srv.Close()
+	base := srv.URL + "/v1/dav/project"
+
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string
+	}{
+		{"delete", "DELETE", nil},
+		{"move", "MOVE", map[string]string{"Destination": base + "/moved.go"}},
+		{"move over another file", "MOVE", map[string]string{"Destination": base + "/old.go"}},
+		{"copy over another file", "COPY", map[string]string{"Destination": base + "/old.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _ := davRequest(t, tt.method, base+"/main.go", "", tt.header)
+			assert.Equal(t, http.StatusForbidden, status)
+			data, err := os.ReadFile(filepath.Join(root, "main.go"))
+			require.NoError(t, err)
+			assert.Equal(t, "package main\n", string(data))
+			data, err = os.ReadFile(filepath.Join(root, "old.go"))
+			require.NoError(t, err)
+			assert.Equal(t, "package old\n", string(data))
+		})
+	}
+
+	// Adding files is still allowed
+	status, _ := davRequest(t, "COPY", base+"/main.go", "", map[string]string{"Destination": base + "/copy.go"})
+	assert.Equal(t, http.StatusCreated, status)
+	status, _ = davRequest(t, "PUT", base+"/new.go", "package new\n", nil)
+	assert.Equal(t, http.StatusCreated, status)
+}
+
+func TestWebDAVConfig_Validate(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, WebDAVConfig{}.Validate())
+	assert.NoError(t, WebDAVConfig{Enabled: true}.Validate())
+	assert.NoError(t, WebDAVConfig{Enabled: true, Roots: map[string]string{"src": dir}, Hidden: []string{"*.key"}}.Validate())
+	assert.Error(t, WebDAVConfig{Enabled: true, Roots: map[string]string{"a/b": dir}}.Validate())
+	assert.Error(t, WebDAVConfig{Enabled: true, Roots: map[string]string{"src": filepath.Join(dir, "missing")}}.Validate())
+	assert.Error(t, WebDAVConfig{Enabled: true, Hidden: []string{"["}}.Validate())
+}
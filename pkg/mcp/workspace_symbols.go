@@ -0,0 +1,405 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/ivikasavnish/go-mcp/pkg/ide"
+)
+
+const (
+	// symbolIndexTTL is how long the symbol index is used before a query
+	// refreshes it in the background
+	symbolIndexTTL = 30 * time.Second
+	// defaultSymbolLimit bounds the symbols returned by a search
+	defaultSymbolLimit = 100
+)
+
+// indexedFile holds the symbols of a file as of its modification time
+type indexedFile struct {
+	modTime time.Time
+	symbols []SymbolInfo
+}
+
+// symbolIndexIgnore excludes from the watcher the directories refresh skips
+var symbolIndexIgnore = []string{"**/.*/**", "**/_*/**", "**/vendor/**", "**/testdata/**", "**/node_modules/**"}
+
+// symbolIndex holds the symbols of the Go files under a directory. It is
+// built in the background and refreshed incrementally, parsing only the
+// files that changed.
+type symbolIndex struct {
+	root  string
+	ready chan struct{} // Closed once the first build is done
+	ctx   context.Context
+	stop  context.CancelFunc // Stops watching
+
+	mu         sync.RWMutex
+	files      map[string]indexedFile
+	indexed    time.Time
+	refreshing bool
+}
+
+// IndexStatus describes the workspace index
+type IndexStatus struct {
+	Files     int       `json:"files"`
+	Symbols   int       `json:"symbols"`
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+func newSymbolIndex(root string) *symbolIndex {
+	ctx, stop := context.WithCancel(context.Background())
+	return &symbolIndex{
+		root:  root,
+		ready: make(chan struct{}),
+		ctx:   ctx,
+		stop:  stop,
+		files: make(map[string]indexedFile),
+	}
+}
+
+// watch indexes the workspace and keeps the index up to date as files are
+// written and deleted, until stop is called. Periodic refreshes catch what
+// the watcher misses.
+func (idx *symbolIndex) watch() {
+	changes := ide.NewWatcher(idx.root, []string{"**/*.go"}, symbolIndexIgnore).Watch(idx.ctx, 0)
+	idx.refresh()
+	for changed := range changes {
+		idx.update(changed)
+	}
+}
+
+// update indexes files changed under root, given as slash separated paths
+// relative to it, and drops the deleted ones
+func (idx *symbolIndex) update(changed []string) {
+	updates := make(map[string]*indexedFile, len(changed))
+	for _, rel := range changed {
+		path := filepath.Join(idx.root, filepath.FromSlash(rel))
+		info, err := os.Stat(path)
+		if err != nil {
+			updates[path] = nil
+			continue
+		}
+		updates[path] = &indexedFile{modTime: info.ModTime(), symbols: fileSymbols(path)}
+	}
+
+	// Refreshes read the files without the lock, so they are replaced
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	files := make(map[string]indexedFile, len(idx.files)+len(updates))
+	for path, file := range idx.files {
+		files[path] = file
+	}
+	for path, file := range updates {
+		if file == nil {
+			delete(files, path)
+		} else {
+			files[path] = *file
+		}
+	}
+	idx.files = files
+}
+
+// status returns the size of the index
+func (idx *symbolIndex) status() IndexStatus {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	status := IndexStatus{Files: len(idx.files), IndexedAt: idx.indexed}
+	for _, file := range idx.files {
+		status.Symbols += len(file.symbols)
+	}
+	return status
+}
+
+// refresh walks the tree and parses new and modified files, unless another
+// refresh is running
+func (idx *symbolIndex) refresh() {
+	idx.mu.Lock()
+	if idx.refreshing {
+		idx.mu.Unlock()
+		return
+	}
+	idx.refreshing = true
+	previous := idx.files
+	idx.mu.Unlock()
+
+	files := make(map[string]indexedFile, len(previous))
+	filepath.WalkDir(idx.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != idx.root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if prev, ok := previous[path]; ok && prev.modTime.Equal(info.ModTime()) {
+			files[path] = prev
+			return nil
+		}
+		files[path] = indexedFile{modTime: info.ModTime(), symbols: fileSymbols(path)}
+		return nil
+	})
+
+	idx.mu.Lock()
+	idx.files = files
+	idx.indexed = time.Now()
+	idx.refreshing = false
+	idx.mu.Unlock()
+
+	select {
+	case <-idx.ready:
+	default:
+		close(idx.ready)
+	}
+}
+
+// fileSymbols parses a Go file and returns its top-level declarations, the
+// methods with their receiver type as container and the fields and
+// interface methods with their type
+func fileSymbols(path string) []SymbolInfo {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+
+	uri := "file://" + filepath.ToSlash(path)
+	var symbols []SymbolInfo
+	add := func(ident *ast.Ident, kind, container string) {
+		if ident == nil || ident.Name == "_" {
+			return
+		}
+		start := fset.Position(ident.Pos())
+		symbols = append(symbols, SymbolInfo{
+			Name: ident.Name,
+			Kind: kind,
+			Location: Location{
+				URI: uri,
+				Range: Range{
+					Start: Position{Line: start.Line - 1, Character: start.Column - 1},
+					End:   Position{Line: start.Line - 1, Character: start.Column - 1 + len(ident.Name)},
+				},
+			},
+			Container: container,
+		})
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				add(decl.Name, "method", receiverName(decl.Recv.List[0].Type))
+			} else {
+				add(decl.Name, "function", "")
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					add(spec.Name, "type", "")
+					var members *ast.FieldList
+					kind := "field"
+					switch t := spec.Type.(type) {
+					case *ast.StructType:
+						members = t.Fields
+					case *ast.InterfaceType:
+						members, kind = t.Methods, "method"
+					}
+					if members == nil {
+						continue
+					}
+					for _, member := range members.List {
+						for _, name := range member.Names {
+							add(name, kind, spec.Name.Name)
+						}
+					}
+				case *ast.ValueSpec:
+					kind := "variable"
+					if decl.Tok == token.CONST {
+						kind = "constant"
+					}
+					for _, name := range spec.Names {
+						add(name, kind, "")
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// receiverName returns the name of the type of a method receiver
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// search returns the symbols matching query, best first. A query with a dot
+// is matched against the container and name, like Server.Handle.
+func (idx *symbolIndex) search(query string, limit int) []SymbolInfo {
+	idx.mu.RLock()
+	stale := time.Since(idx.indexed) > symbolIndexTTL
+	type match struct {
+		symbol SymbolInfo
+		score  int
+	}
+	var matches []match
+	for _, file := range idx.files {
+		for _, symbol := range file.symbols {
+			name := symbol.Name
+			if strings.Contains(query, ".") && symbol.Container != "" {
+				name = symbol.Container + "." + symbol.Name
+			}
+			if score, ok := fuzzyScore(query, name); ok {
+				matches = append(matches, match{symbol, score})
+			}
+		}
+	}
+	idx.mu.RUnlock()
+	if stale {
+		go idx.refresh()
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if len(a.symbol.Name) != len(b.symbol.Name) {
+			return len(a.symbol.Name) < len(b.symbol.Name)
+		}
+		if a.symbol.Name != b.symbol.Name {
+			return a.symbol.Name < b.symbol.Name
+		}
+		return a.symbol.Location.URI < b.symbol.Location.URI
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	symbols := make([]SymbolInfo, len(matches))
+	for i, m := range matches {
+		symbols[i] = m.symbol
+	}
+	return symbols
+}
+
+// fuzzyScore matches the characters of pattern in order against name,
+// ignoring case, and scores the match: matches at the start of the name
+// or of a word in it and consecutive matches score higher, and an exact
+// name scores highest
+func fuzzyScore(pattern, name string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+	if strings.EqualFold(pattern, name) {
+		return 1000, true
+	}
+
+	p := []rune(strings.ToLower(pattern))
+	runes := []rune(name)
+	score, pi := 0, 0
+	consecutive := false
+	for i, r := range runes {
+		if pi == len(p) {
+			break
+		}
+		if unicode.ToLower(r) != p[pi] {
+			consecutive = false
+			continue
+		}
+		switch {
+		case i == 0:
+			score += 10
+		case isWordStart(runes, i):
+			score += 8
+		}
+		if consecutive {
+			score += 5
+		}
+		score++
+		consecutive = true
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+	if strings.HasPrefix(strings.ToLower(name), string(p)) {
+		score += 20
+	}
+	return score, true
+}
+
+// isWordStart reports whether the rune at i starts a word of a camel case
+// or snake case name
+func isWordStart(runes []rune, i int) bool {
+	prev, r := runes[i-1], runes[i]
+	return prev == '_' || prev == '.' || (unicode.IsUpper(r) && !unicode.IsUpper(prev)) ||
+		(unicode.IsDigit(r) && !unicode.IsDigit(prev))
+}
+
+func handleWorkspaceSymbols(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit := defaultSymbolLimit
+		if value := query.Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be a positive number"))
+				return
+			}
+			limit = n
+		}
+
+		// The first search waits for the index to be built
+		select {
+		case <-ls.symbols.ready:
+		case <-r.Context().Done():
+			writeError(w, http.StatusServiceUnavailable, fmt.Errorf("symbol index is not ready"))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ls.symbols.search(query.Get("query"), limit))
+	}
+}
+
+func handleWorkspaceIndex(ls *LanguageServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ls.symbols.refresh()
+		writeJSON(w, http.StatusOK, ls.symbols.status())
+	}
+}
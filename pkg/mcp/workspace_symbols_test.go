@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"", "Anything", true},
+		{"srv", "Server", true},
+		{"newsrv", "NewServer", true},
+		{"hdlsrv", "Server.Handle", false},
+		{"Server.hdl", "Server.Handle", true},
+		{"revres", "Server", false},
+		{"serverx", "Server", false},
+	}
+	for _, tt := range tests {
+		_, match := fuzzyScore(tt.pattern, tt.name)
+		assert.Equal(t, tt.match, match, "%s in %s", tt.pattern, tt.name)
+	}
+
+	// Exact names beat prefixes, which beat word starts, which beat
+	// scattered characters
+	exact, _ := fuzzyScore("server", "Server")
+	prefix, _ := fuzzyScore("server", "ServerConfig")
+	word, _ := fuzzyScore("server", "NewServer")
+	scattered, _ := fuzzyScore("server", "UsersOverview")
+	assert.Greater(t, exact, prefix)
+	assert.Greater(t, prefix, word)
+	assert.Greater(t, word, scattered)
+}
+
+func TestHandleWorkspaceSymbols(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "server"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "vendor", "dep"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server", "server.go"), []byte("package server\n\ntype Server struct {\n\tAddr string\n}\n\nfunc NewServer() *Server { return nil }\n\nfunc (s *Server) Handle() {}\n\nconst DefaultAddr = \":80\"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor", "dep", "dep.go"), []byte("package dep\n\ntype Server struct{}\n"), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+
+	search := func(query string) []SymbolInfo {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/workspace/symbols?query="+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var symbols []SymbolInfo
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&symbols))
+		return symbols
+	}
+
+	// Files that were never opened are indexed, vendored ones are not
+	symbols := search("srv")
+	require.Len(t, symbols, 2)
+	assert.Equal(t, "Server", symbols[0].Name)
+	assert.Equal(t, "type", symbols[0].Kind)
+	assert.Equal(t, "file://"+filepath.ToSlash(filepath.Join(dir, "server", "server.go")), symbols[0].Location.URI)
+	assert.Equal(t, Range{Start: Position{Line: 2, Character: 5}, End: Position{Line: 2, Character: 11}}, symbols[0].Location.Range)
+	assert.Equal(t, "NewServer", symbols[1].Name)
+
+	// Methods and fields are qualified by their type
+	symbols = search("Server.hdl")
+	require.Len(t, symbols, 1)
+	assert.Equal(t, "Handle", symbols[0].Name)
+	assert.Equal(t, "method", symbols[0].Kind)
+	assert.Equal(t, "Server", symbols[0].Container)
+
+	symbols = search("addr")
+	require.Len(t, symbols, 2)
+	assert.Equal(t, "Addr", symbols[0].Name)
+	assert.Equal(t, "field", symbols[0].Kind)
+	assert.Equal(t, "DefaultAddr", symbols[1].Name)
+
+	// A stale index is refreshed in the background, picking up new files
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server", "client.go"), []byte("package server\n\nfunc Dial() {}\n"), 0644))
+	ls := NewLanguageServer(dir)
+	ls.symbols.refresh()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server", "dial.go"), []byte("package server\n\nfunc DialTimeout() {}\n"), 0644))
+	assert.Len(t, ls.symbols.search("Dial", 10), 1)
+	ls.symbols.mu.Lock()
+	ls.symbols.indexed = time.Now().Add(-symbolIndexTTL)
+	ls.symbols.mu.Unlock()
+	ls.symbols.search("Dial", 10)
+	assert.Eventually(t, func() bool {
+		return len(ls.symbols.search("Dial", 10)) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, ls.symbols.search("", 3), 3)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lsp/workspace/symbols?limit=0", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestWorkspaceIndexWatch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc Alpha() {}\n"), 0644))
+
+	cfg := DefaultConfig()
+	cfg.WorkspaceRoot = dir
+	s := NewServer(nil, WithConfig(cfg))
+	s.AddLanguageServerHandler()
+	defer s.lsp.symbols.stop()
+	<-s.lsp.symbols.ready
+
+	// Written and deleted files are picked up without a refresh
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.go"), []byte("package a\n\nfunc Beta() {}\n"), 0644))
+	assert.Eventually(t, func() bool {
+		return len(s.lsp.symbols.search("Beta", 10)) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+	require.NoError(t, os.Remove(filepath.Join(dir, "a.go")))
+	assert.Eventually(t, func() bool {
+		return len(s.lsp.symbols.search("Alpha", 10)) == 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/lsp/workspace/index", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var status IndexStatus
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+	assert.Equal(t, 1, status.Files)
+	assert.Equal(t, 1, status.Symbols)
+	assert.False(t, status.IndexedAt.IsZero())
+}
@@ -0,0 +1,336 @@
+// Package mcpclient connects to Model Context Protocol servers over stdio or
+// HTTP with server-sent events, lists their tools and resources and calls
+// them. The go-mcp server uses it to aggregate the tools of other servers.
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// ProtocolVersions are the protocol revisions the client speaks, newest
+// first. It asks servers for the newest.
+var ProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// ErrClosed is returned by calls on a client whose connection ended
+var ErrClosed = errors.New("mcp connection closed")
+
+// Client is a connection to an MCP server. It is safe for concurrent use.
+type Client struct {
+	transport      Transport
+	info           Implementation
+	onNotification func(method string, params json.RawMessage)
+
+	mu      sync.Mutex
+	lastID  int
+	pending map[string]chan *response
+	err     error // Why the connection ended
+	done    chan struct{}
+
+	// Set by initialize
+	server          Implementation
+	capabilities    ServerCapabilities
+	protocolVersion string
+	instructions    string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithClientInfo sets the name and version the client introduces itself with
+func WithClientInfo(name, version string) Option {
+	return func(c *Client) {
+		c.info = Implementation{Name: name, Version: version}
+	}
+}
+
+// WithNotificationHandler receives the notifications of the server, such as
+// notifications/tools/list_changed. It runs on the read loop of the client
+// and must not call the client.
+func WithNotificationHandler(fn func(method string, params json.RawMessage)) Option {
+	return func(c *Client) {
+		c.onNotification = fn
+	}
+}
+
+// Connect initializes a session with the server at the other end of the
+// transport. The transport is closed when connecting fails.
+func Connect(ctx context.Context, transport Transport, opts ...Option) (*Client, error) {
+	c := &Client{
+		transport: transport,
+		info:      Implementation{Name: "go-mcp", Version: "v1"},
+		pending:   make(map[string]chan *response),
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	go c.readLoop()
+
+	if err := c.initialize(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	var result initializeResult
+	err := c.call(ctx, "initialize", initializeParams{
+		ProtocolVersion: ProtocolVersions[0],
+		Capabilities:    struct{}{},
+		ClientInfo:      c.info,
+	}, &result)
+	if err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if !supported(result.ProtocolVersion) {
+		return fmt.Errorf("initialize: unsupported protocol version %q", result.ProtocolVersion)
+	}
+
+	c.mu.Lock()
+	c.server = result.ServerInfo
+	c.capabilities = result.Capabilities
+	c.protocolVersion = result.ProtocolVersion
+	c.instructions = result.Instructions
+	c.mu.Unlock()
+	return c.notify(ctx, "notifications/initialized", nil)
+}
+
+func supported(version string) bool {
+	for _, v := range ProtocolVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Server returns the name and version of the server
+func (c *Client) Server() Implementation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.server
+}
+
+// Capabilities returns the capabilities the server offered
+func (c *Client) Capabilities() ServerCapabilities {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.capabilities
+}
+
+// ProtocolVersion returns the protocol revision the server chose
+func (c *Client) ProtocolVersion() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.protocolVersion
+}
+
+// Instructions returns how the server asks to be used, if it said so
+func (c *Client) Instructions() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.instructions
+}
+
+// Ping checks that the server answers
+func (c *Client) Ping(ctx context.Context) error {
+	return c.call(ctx, "ping", nil, nil)
+}
+
+// ListTools returns every tool of the server, following pagination
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	tools := make([]Tool, 0)
+	var cursor string
+	for {
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", cursorParams{Cursor: cursor}, &page); err != nil {
+			return nil, err
+		}
+		tools = append(tools, page.Tools...)
+		if cursor = page.NextCursor; cursor == "" {
+			return tools, nil
+		}
+	}
+}
+
+// CallTool calls a tool with arguments. Failures of the tool itself are
+// reported by ToolResult.IsError, not as errors.
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (*ToolResult, error) {
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	var result ToolResult
+	if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: arguments}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListResources returns every resource of the server, following pagination
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	resources := make([]Resource, 0)
+	var cursor string
+	for {
+		var page struct {
+			Resources  []Resource `json:"resources"`
+			NextCursor string     `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "resources/list", cursorParams{Cursor: cursor}, &page); err != nil {
+			return nil, err
+		}
+		resources = append(resources, page.Resources...)
+		if cursor = page.NextCursor; cursor == "" {
+			return resources, nil
+		}
+	}
+}
+
+// ReadResource returns the contents of a resource
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]ResourceContents, error) {
+	var result struct {
+		Contents []ResourceContents `json:"contents"`
+	}
+	if err := c.call(ctx, "resources/read", map[string]string{"uri": uri}, &result); err != nil {
+		return nil, err
+	}
+	return result.Contents, nil
+}
+
+// Close ends the session and closes the transport
+func (c *Client) Close() error {
+	err := c.transport.Close()
+	<-c.done
+	return err
+}
+
+// Done is closed when the connection ends
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection ended, or nil while it lasts
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// call sends a request and decodes the result of its response into out,
+// unless nil. Requests given up on are cancelled at the server.
+func (c *Client) call(ctx context.Context, method string, params, out interface{}) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.lastID++
+	id := c.lastID
+	responses := make(chan *response, 1)
+	c.pending[strconv.Itoa(id)] = responses
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, strconv.Itoa(id))
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(ctx, &request{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		return err
+	}
+	select {
+	case resp := <-responses:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if out == nil {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, out); err != nil {
+			return fmt.Errorf("%s: invalid result: %w", method, err)
+		}
+		return nil
+	case <-c.done:
+		return c.Err()
+	case <-ctx.Done():
+		c.notify(context.Background(), "notifications/cancelled", map[string]interface{}{"requestId": id, "reason": ctx.Err().Error()})
+		return ctx.Err()
+	}
+}
+
+func (c *Client) notify(ctx context.Context, method string, params interface{}) error {
+	return c.send(ctx, &request{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (c *Client) send(ctx context.Context, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return c.transport.Send(ctx, data)
+}
+
+// readLoop passes responses to the calls waiting for them, answers requests
+// of the server and hands notifications to the handler until the transport
+// ends
+func (c *Client) readLoop() {
+	defer close(c.done)
+	for {
+		data, err := c.transport.Receive()
+		if err != nil {
+			c.mu.Lock()
+			c.err = fmt.Errorf("%w: %v", ErrClosed, err)
+			c.mu.Unlock()
+			return
+		}
+
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue // Not for us to answer; servers do not expect responses to garbage
+		}
+		switch {
+		case msg.Method == "" && msg.ID != nil:
+			c.mu.Lock()
+			responses, ok := c.pending[idKey(msg.ID)]
+			c.mu.Unlock()
+			if ok {
+				responses <- &response{Result: msg.Result, Error: msg.Error}
+			}
+		case msg.ID != nil:
+			go c.answer(msg)
+		case c.onNotification != nil:
+			c.onNotification(msg.Method, msg.Params)
+		}
+	}
+}
+
+// answer responds to requests of the server. The client offers no
+// capabilities, so only ping is served.
+func (c *Client) answer(msg message) {
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID}
+	if msg.Method == "ping" {
+		resp["result"] = struct{}{}
+	} else {
+		resp["error"] = &RPCError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	c.send(context.Background(), resp)
+}
+
+// idKey normalizes request IDs, which servers echo as numbers or strings
+func idKey(id json.RawMessage) string {
+	var n json.Number
+	if err := json.Unmarshal(id, &n); err == nil {
+		return n.String()
+	}
+	var s string
+	json.Unmarshal(id, &s)
+	return s
+}
@@ -0,0 +1,174 @@
+package mcpclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/mcp"
+	"github.com/ivikasavnish/go-mcp/pkg/mcpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connectStdio connects a client to s served over a pair of pipes
+func connectStdio(t *testing.T, s *mcp.Server) *mcpclient.Client {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		s.ServeStdio(context.Background(), serverIn, serverOut)
+		serverOut.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := mcpclient.Connect(ctx, mcpclient.NewStreamTransport(clientIn, clientOut), mcpclient.WithClientInfo("test", "1"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		client.Close()
+		<-served
+	})
+	return client
+}
+
+func TestClient(t *testing.T) {
+	store := mcp.NewMemoryStore()
+	s := mcp.NewServer(store)
+	s.AddFunctionHandler()
+	client := connectStdio(t, s)
+	ctx := context.Background()
+
+	assert.Equal(t, mcp.MCPServerName, client.Server().Name)
+	assert.Equal(t, mcpclient.ProtocolVersions[0], client.ProtocolVersion())
+	require.NotNil(t, client.Capabilities().Tools)
+	require.NoError(t, client.Ping(ctx))
+
+	tools, err := client.ListTools(ctx)
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	assert.Contains(t, names, "function_call")
+
+	result, err := client.CallTool(ctx, "function_call", map[string]interface{}{"name": "echo", "arguments": []string{"hi"}})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, `"hi"`, result.Text())
+
+	// Failing tools are results, unknown tools are errors
+	result, err = client.CallTool(ctx, "function_call", map[string]interface{}{"name": "missing"})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	_, err = client.CallTool(ctx, "missing", nil)
+	var rpcErr *mcpclient.RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, mcp.RPCInvalidParams, rpcErr.Code)
+
+	require.NoError(t, store.Create(&mcp.Context{ID: "notes", Metadata: map[string]interface{}{"text": "hello"}}))
+	resources, err := client.ListResources(ctx)
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	contents, err := client.ReadResource(ctx, resources[0].URI)
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+	assert.Contains(t, contents[0].Text, "hello")
+
+	require.NoError(t, client.Close())
+	assert.ErrorIs(t, client.Ping(ctx), mcpclient.ErrClosed)
+}
+
+func TestClient_Cancel(t *testing.T) {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	received := make(chan map[string]interface{}, 4)
+	go func() {
+		dec := json.NewDecoder(serverIn)
+		for {
+			var msg map[string]interface{}
+			if dec.Decode(&msg) != nil {
+				serverOut.Close()
+				return
+			}
+			received <- msg
+			if msg["method"] == "initialize" {
+				fmt.Fprintf(serverOut, `{"jsonrpc":"2.0","id":%v,"result":{"protocolVersion":"2024-11-05","capabilities":{},"serverInfo":{"name":"slow","version":"0"}}}`+"\n", msg["id"])
+			}
+		}
+	}()
+
+	client, err := mcpclient.Connect(context.Background(), mcpclient.NewStreamTransport(clientIn, clientOut))
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, "2024-11-05", client.ProtocolVersion())
+	assert.Nil(t, client.Capabilities().Tools)
+	assert.Equal(t, "initialize", (<-received)["method"])
+	assert.Equal(t, "notifications/initialized", (<-received)["method"])
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, client.Ping(ctx), context.DeadlineExceeded)
+	assert.Equal(t, "ping", (<-received)["method"])
+	cancelled := <-received
+	assert.Equal(t, "notifications/cancelled", cancelled["method"])
+	assert.EqualValues(t, 2, cancelled["params"].(map[string]interface{})["requestId"])
+}
+
+func TestSSETransport(t *testing.T) {
+	posted := make(chan string, 4)
+	messages := make(chan string, 4)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp/sse", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		// Endpoints are relative to the stream
+		fmt.Fprint(w, ": keep-alive\n\nevent: endpoint\ndata: messages?session=1\n\n")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case msg := <-messages:
+				fmt.Fprintf(w, "data: %s\n\n", msg)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/mcp/messages", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "1", r.URL.Query().Get("session"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		posted <- string(body)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	transport, err := mcpclient.NewSSETransport(ctx, srv.URL+"/mcp/sse", http.Header{"Authorization": {"Bearer token"}})
+	require.NoError(t, err)
+
+	require.NoError(t, transport.Send(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"method":"ping"}`, <-posted)
+	messages <- `{"jsonrpc":"2.0","id":1,"result":{}}`
+	msg, err := transport.Receive()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":{}}`, string(msg))
+
+	require.NoError(t, transport.Close())
+	_, err = transport.Receive()
+	assert.Error(t, err)
+
+	_, err = mcpclient.NewSSETransport(ctx, srv.URL+"/missing", nil)
+	assert.ErrorContains(t, err, "404")
+}
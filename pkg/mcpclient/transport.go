@@ -0,0 +1,266 @@
+package mcpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxMessageSize bounds a single message read from a server
+const maxMessageSize = 16 << 20
+
+// commandGracePeriod is how long a server command may take to exit after its
+// stdin closed before it is killed
+const commandGracePeriod = 5 * time.Second
+
+// Transport carries JSON-RPC messages to and from a server
+type Transport interface {
+	// Send writes a message to the server
+	Send(ctx context.Context, message []byte) error
+	// Receive blocks until the server sends a message. It returns io.EOF
+	// once the transport closed.
+	Receive() ([]byte, error)
+	// Close ends the connection
+	Close() error
+}
+
+// streamTransport exchanges newline-delimited messages over a pair of
+// streams, as stdio servers do
+type streamTransport struct {
+	scanner *bufio.Scanner
+	w       io.WriteCloser
+
+	mu sync.Mutex // Serializes writes
+}
+
+// NewStreamTransport exchanges newline-delimited messages, reading from r and
+// writing to w. Closing the transport closes w.
+func NewStreamTransport(r io.Reader, w io.WriteCloser) Transport {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxMessageSize)
+	return &streamTransport{scanner: scanner, w: w}
+}
+
+func (t *streamTransport) Send(ctx context.Context, message []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.w.Write(append(message, '\n'))
+	return err
+}
+
+func (t *streamTransport) Receive() ([]byte, error) {
+	for t.scanner.Scan() {
+		if line := bytes.TrimSpace(t.scanner.Bytes()); len(line) > 0 {
+			return append([]byte(nil), line...), nil
+		}
+	}
+	if err := t.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (t *streamTransport) Close() error {
+	return t.w.Close()
+}
+
+// commandTransport talks to a server command over its stdin and stdout
+type commandTransport struct {
+	Transport
+	cmd  *exec.Cmd
+	once sync.Once
+	err  error
+}
+
+// NewCommandTransport starts a server command and talks to it over its stdin
+// and stdout. Its stderr is left as configured on cmd. Closing the transport
+// closes stdin and waits for the command to exit, killing it if it lingers.
+func NewCommandTransport(cmd *exec.Cmd) (Transport, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", cmd.Path, err)
+	}
+	return &commandTransport{Transport: NewStreamTransport(stdout, stdin), cmd: cmd}, nil
+}
+
+func (t *commandTransport) Close() error {
+	t.once.Do(func() {
+		t.err = t.Transport.Close()
+		exited := make(chan error, 1)
+		go func() { exited <- t.cmd.Wait() }()
+		select {
+		case <-exited:
+		case <-time.After(commandGracePeriod):
+			t.cmd.Process.Kill()
+			<-exited
+		}
+	})
+	return t.err
+}
+
+// sseTransport speaks the HTTP with server-sent events transport: the server
+// streams messages as events and names an endpoint that messages are POSTed to
+type sseTransport struct {
+	client   *http.Client
+	header   http.Header
+	endpoint string
+	body     io.ReadCloser
+	reader   *bufio.Reader
+	cancel   context.CancelFunc
+}
+
+// NewSSETransport opens the event stream of the server at rawURL and waits
+// for it to name its message endpoint. Header is sent with every request,
+// e.g. for authorization.
+func NewSSETransport(ctx context.Context, rawURL string, header http.Header) (Transport, error) {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// The stream outlives ctx, which only bounds connecting
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	t := &sseTransport{client: http.DefaultClient, header: header, cancel: cancel}
+	connected := make(chan error, 1)
+	go func() {
+		resp, err := t.client.Do(req)
+		if err != nil {
+			connected <- err
+			return
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			connected <- fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+			return
+		}
+		t.body = resp.Body
+		t.reader = bufio.NewReader(resp.Body)
+
+		event, data, err := t.next()
+		if err != nil {
+			connected <- err
+			return
+		}
+		if event != "endpoint" {
+			connected <- fmt.Errorf("expected an endpoint event, got %q", event)
+			return
+		}
+		endpoint, err := base.Parse(string(data))
+		if err != nil {
+			connected <- fmt.Errorf("invalid endpoint: %w", err)
+			return
+		}
+		t.endpoint = endpoint.String()
+		connected <- nil
+	}()
+
+	select {
+	case err := <-connected:
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+		return t, nil
+	case <-ctx.Done():
+		cancel() // Ends the connecting goroutine, which may still hold the stream
+		return nil, ctx.Err()
+	}
+}
+
+func (t *sseTransport) Send(ctx context.Context, message []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(message))
+	if err != nil {
+		return err
+	}
+	for k, v := range t.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s: %s", t.endpoint, resp.Status)
+	}
+	return nil
+}
+
+func (t *sseTransport) Receive() ([]byte, error) {
+	for {
+		event, data, err := t.next()
+		if err != nil {
+			return nil, err
+		}
+		if event == "message" {
+			return data, nil
+		}
+	}
+}
+
+// next reads the next event of the stream. Events without a name are
+// messages.
+func (t *sseTransport) next() (string, []byte, error) {
+	event := "message"
+	var data [][]byte
+	for {
+		line, err := t.reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, io.ErrUnexpectedEOF) {
+				err = io.EOF
+			}
+			return "", nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				return event, bytes.Join(data, []byte("\n")), nil
+			}
+			event = "message"
+		case strings.HasPrefix(line, ":"): // Comment, e.g. keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			value := strings.TrimPrefix(line, "data:")
+			data = append(data, []byte(strings.TrimPrefix(value, " ")))
+		}
+	}
+}
+
+func (t *sseTransport) Close() error {
+	t.cancel()
+	if t.body != nil {
+		return t.body.Close()
+	}
+	return nil
+}
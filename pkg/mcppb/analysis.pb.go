@@ -0,0 +1,1851 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: mcp/v1/analysis.proto
+
+package mcppb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type AnalysisRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Uri     string `protobuf:"bytes,1,opt,name=uri,proto3" json:"uri,omitempty"`
+	Content string `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+}
+
+func (x *AnalysisRequest) Reset() {
+	*x = AnalysisRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AnalysisRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalysisRequest) ProtoMessage() {}
+
+func (x *AnalysisRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalysisRequest.ProtoReflect.Descriptor instead.
+func (*AnalysisRequest) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AnalysisRequest) GetUri() string {
+	if x != nil {
+		return x.Uri
+	}
+	return ""
+}
+
+func (x *AnalysisRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+type FileAnalysis struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Uri    string          `protobuf:"bytes,1,opt,name=uri,proto3" json:"uri,omitempty"`
+	Result *AnalysisResult `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`
+	Error  string          `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *FileAnalysis) Reset() {
+	*x = FileAnalysis{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileAnalysis) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileAnalysis) ProtoMessage() {}
+
+func (x *FileAnalysis) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileAnalysis.ProtoReflect.Descriptor instead.
+func (*FileAnalysis) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FileAnalysis) GetUri() string {
+	if x != nil {
+		return x.Uri
+	}
+	return ""
+}
+
+func (x *FileAnalysis) GetResult() *AnalysisResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *FileAnalysis) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type AnalysisResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Imports     []*ImportInfo    `protobuf:"bytes,1,rep,name=imports,proto3" json:"imports,omitempty"`
+	Functions   []*FunctionInfo  `protobuf:"bytes,2,rep,name=functions,proto3" json:"functions,omitempty"`
+	Types       []*TypeInfo      `protobuf:"bytes,3,rep,name=types,proto3" json:"types,omitempty"`
+	Variables   []*VariableInfo  `protobuf:"bytes,4,rep,name=variables,proto3" json:"variables,omitempty"`
+	References  []*ReferenceInfo `protobuf:"bytes,5,rep,name=references,proto3" json:"references,omitempty"`
+	Diagnostics []*Diagnostic    `protobuf:"bytes,6,rep,name=diagnostics,proto3" json:"diagnostics,omitempty"`
+	Metrics     *CodeMetrics     `protobuf:"bytes,7,opt,name=metrics,proto3" json:"metrics,omitempty"`
+}
+
+func (x *AnalysisResult) Reset() {
+	*x = AnalysisResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AnalysisResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalysisResult) ProtoMessage() {}
+
+func (x *AnalysisResult) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalysisResult.ProtoReflect.Descriptor instead.
+func (*AnalysisResult) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AnalysisResult) GetImports() []*ImportInfo {
+	if x != nil {
+		return x.Imports
+	}
+	return nil
+}
+
+func (x *AnalysisResult) GetFunctions() []*FunctionInfo {
+	if x != nil {
+		return x.Functions
+	}
+	return nil
+}
+
+func (x *AnalysisResult) GetTypes() []*TypeInfo {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *AnalysisResult) GetVariables() []*VariableInfo {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+func (x *AnalysisResult) GetReferences() []*ReferenceInfo {
+	if x != nil {
+		return x.References
+	}
+	return nil
+}
+
+func (x *AnalysisResult) GetDiagnostics() []*Diagnostic {
+	if x != nil {
+		return x.Diagnostics
+	}
+	return nil
+}
+
+func (x *AnalysisResult) GetMetrics() *CodeMetrics {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type CodeMetrics struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	LinesOfCode     int64 `protobuf:"varint,1,opt,name=lines_of_code,json=linesOfCode,proto3" json:"lines_of_code,omitempty"`
+	CommentLines    int64 `protobuf:"varint,2,opt,name=comment_lines,json=commentLines,proto3" json:"comment_lines,omitempty"`
+	FunctionCount   int64 `protobuf:"varint,3,opt,name=function_count,json=functionCount,proto3" json:"function_count,omitempty"`
+	ComplexityScore int64 `protobuf:"varint,4,opt,name=complexity_score,json=complexityScore,proto3" json:"complexity_score,omitempty"`
+	InterfaceCount  int64 `protobuf:"varint,5,opt,name=interface_count,json=interfaceCount,proto3" json:"interface_count,omitempty"`
+	StructCount     int64 `protobuf:"varint,6,opt,name=struct_count,json=structCount,proto3" json:"struct_count,omitempty"`
+	TestCount       int64 `protobuf:"varint,7,opt,name=test_count,json=testCount,proto3" json:"test_count,omitempty"`
+}
+
+func (x *CodeMetrics) Reset() {
+	*x = CodeMetrics{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CodeMetrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CodeMetrics) ProtoMessage() {}
+
+func (x *CodeMetrics) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CodeMetrics.ProtoReflect.Descriptor instead.
+func (*CodeMetrics) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CodeMetrics) GetLinesOfCode() int64 {
+	if x != nil {
+		return x.LinesOfCode
+	}
+	return 0
+}
+
+func (x *CodeMetrics) GetCommentLines() int64 {
+	if x != nil {
+		return x.CommentLines
+	}
+	return 0
+}
+
+func (x *CodeMetrics) GetFunctionCount() int64 {
+	if x != nil {
+		return x.FunctionCount
+	}
+	return 0
+}
+
+func (x *CodeMetrics) GetComplexityScore() int64 {
+	if x != nil {
+		return x.ComplexityScore
+	}
+	return 0
+}
+
+func (x *CodeMetrics) GetInterfaceCount() int64 {
+	if x != nil {
+		return x.InterfaceCount
+	}
+	return 0
+}
+
+func (x *CodeMetrics) GetStructCount() int64 {
+	if x != nil {
+		return x.StructCount
+	}
+	return 0
+}
+
+func (x *CodeMetrics) GetTestCount() int64 {
+	if x != nil {
+		return x.TestCount
+	}
+	return 0
+}
+
+// Dependencies maps each used import path to the symbols used from it
+type Dependencies struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Imports map[string]*Symbols `protobuf:"bytes,1,rep,name=imports,proto3" json:"imports,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *Dependencies) Reset() {
+	*x = Dependencies{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Dependencies) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Dependencies) ProtoMessage() {}
+
+func (x *Dependencies) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Dependencies.ProtoReflect.Descriptor instead.
+func (*Dependencies) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Dependencies) GetImports() map[string]*Symbols {
+	if x != nil {
+		return x.Imports
+	}
+	return nil
+}
+
+type Symbols struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Names []string `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`
+}
+
+func (x *Symbols) Reset() {
+	*x = Symbols{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Symbols) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Symbols) ProtoMessage() {}
+
+func (x *Symbols) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Symbols.ProtoReflect.Descriptor instead.
+func (*Symbols) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Symbols) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+type ImportInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Used bool   `protobuf:"varint,3,opt,name=used,proto3" json:"used,omitempty"`
+}
+
+func (x *ImportInfo) Reset() {
+	*x = ImportInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ImportInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportInfo) ProtoMessage() {}
+
+func (x *ImportInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportInfo.ProtoReflect.Descriptor instead.
+func (*ImportInfo) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ImportInfo) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ImportInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ImportInfo) GetUsed() bool {
+	if x != nil {
+		return x.Used
+	}
+	return false
+}
+
+type FunctionInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name       string           `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Signature  string           `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	Doc        string           `protobuf:"bytes,3,opt,name=doc,proto3" json:"doc,omitempty"`
+	Location   *Location        `protobuf:"bytes,4,opt,name=location,proto3" json:"location,omitempty"`
+	Complexity int64            `protobuf:"varint,5,opt,name=complexity,proto3" json:"complexity,omitempty"`
+	IsMethod   bool             `protobuf:"varint,6,opt,name=is_method,json=isMethod,proto3" json:"is_method,omitempty"`
+	Receiver   string           `protobuf:"bytes,7,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	Parameters []*ParameterInfo `protobuf:"bytes,8,rep,name=parameters,proto3" json:"parameters,omitempty"`
+	Returns    []*ParameterInfo `protobuf:"bytes,9,rep,name=returns,proto3" json:"returns,omitempty"`
+}
+
+func (x *FunctionInfo) Reset() {
+	*x = FunctionInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FunctionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FunctionInfo) ProtoMessage() {}
+
+func (x *FunctionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FunctionInfo.ProtoReflect.Descriptor instead.
+func (*FunctionInfo) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *FunctionInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FunctionInfo) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+func (x *FunctionInfo) GetDoc() string {
+	if x != nil {
+		return x.Doc
+	}
+	return ""
+}
+
+func (x *FunctionInfo) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *FunctionInfo) GetComplexity() int64 {
+	if x != nil {
+		return x.Complexity
+	}
+	return 0
+}
+
+func (x *FunctionInfo) GetIsMethod() bool {
+	if x != nil {
+		return x.IsMethod
+	}
+	return false
+}
+
+func (x *FunctionInfo) GetReceiver() string {
+	if x != nil {
+		return x.Receiver
+	}
+	return ""
+}
+
+func (x *FunctionInfo) GetParameters() []*ParameterInfo {
+	if x != nil {
+		return x.Parameters
+	}
+	return nil
+}
+
+func (x *FunctionInfo) GetReturns() []*ParameterInfo {
+	if x != nil {
+		return x.Returns
+	}
+	return nil
+}
+
+type ParameterInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name     string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type     string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Variadic bool   `protobuf:"varint,3,opt,name=variadic,proto3" json:"variadic,omitempty"`
+}
+
+func (x *ParameterInfo) Reset() {
+	*x = ParameterInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ParameterInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParameterInfo) ProtoMessage() {}
+
+func (x *ParameterInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParameterInfo.ProtoReflect.Descriptor instead.
+func (*ParameterInfo) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ParameterInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ParameterInfo) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ParameterInfo) GetVariadic() bool {
+	if x != nil {
+		return x.Variadic
+	}
+	return false
+}
+
+type TypeInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name       string        `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Kind       string        `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	Doc        string        `protobuf:"bytes,3,opt,name=doc,proto3" json:"doc,omitempty"`
+	Location   *Location     `protobuf:"bytes,4,opt,name=location,proto3" json:"location,omitempty"`
+	Fields     []*FieldInfo  `protobuf:"bytes,5,rep,name=fields,proto3" json:"fields,omitempty"`
+	Methods    []*MethodInfo `protobuf:"bytes,6,rep,name=methods,proto3" json:"methods,omitempty"`
+	Implements []string      `protobuf:"bytes,7,rep,name=implements,proto3" json:"implements,omitempty"`
+}
+
+func (x *TypeInfo) Reset() {
+	*x = TypeInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TypeInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TypeInfo) ProtoMessage() {}
+
+func (x *TypeInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TypeInfo.ProtoReflect.Descriptor instead.
+func (*TypeInfo) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *TypeInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *TypeInfo) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *TypeInfo) GetDoc() string {
+	if x != nil {
+		return x.Doc
+	}
+	return ""
+}
+
+func (x *TypeInfo) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *TypeInfo) GetFields() []*FieldInfo {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *TypeInfo) GetMethods() []*MethodInfo {
+	if x != nil {
+		return x.Methods
+	}
+	return nil
+}
+
+func (x *TypeInfo) GetImplements() []string {
+	if x != nil {
+		return x.Implements
+	}
+	return nil
+}
+
+type FieldInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type  string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Doc   string `protobuf:"bytes,3,opt,name=doc,proto3" json:"doc,omitempty"`
+	Tags  string `protobuf:"bytes,4,opt,name=tags,proto3" json:"tags,omitempty"`
+	Embed bool   `protobuf:"varint,5,opt,name=embed,proto3" json:"embed,omitempty"`
+}
+
+func (x *FieldInfo) Reset() {
+	*x = FieldInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FieldInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldInfo) ProtoMessage() {}
+
+func (x *FieldInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldInfo.ProtoReflect.Descriptor instead.
+func (*FieldInfo) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FieldInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FieldInfo) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *FieldInfo) GetDoc() string {
+	if x != nil {
+		return x.Doc
+	}
+	return ""
+}
+
+func (x *FieldInfo) GetTags() string {
+	if x != nil {
+		return x.Tags
+	}
+	return ""
+}
+
+func (x *FieldInfo) GetEmbed() bool {
+	if x != nil {
+		return x.Embed
+	}
+	return false
+}
+
+type MethodInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name       string           `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Signature  string           `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	Doc        string           `protobuf:"bytes,3,opt,name=doc,proto3" json:"doc,omitempty"`
+	Parameters []*ParameterInfo `protobuf:"bytes,4,rep,name=parameters,proto3" json:"parameters,omitempty"`
+	Returns    []*ParameterInfo `protobuf:"bytes,5,rep,name=returns,proto3" json:"returns,omitempty"`
+}
+
+func (x *MethodInfo) Reset() {
+	*x = MethodInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MethodInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MethodInfo) ProtoMessage() {}
+
+func (x *MethodInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MethodInfo.ProtoReflect.Descriptor instead.
+func (*MethodInfo) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *MethodInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *MethodInfo) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+func (x *MethodInfo) GetDoc() string {
+	if x != nil {
+		return x.Doc
+	}
+	return ""
+}
+
+func (x *MethodInfo) GetParameters() []*ParameterInfo {
+	if x != nil {
+		return x.Parameters
+	}
+	return nil
+}
+
+func (x *MethodInfo) GetReturns() []*ParameterInfo {
+	if x != nil {
+		return x.Returns
+	}
+	return nil
+}
+
+type VariableInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name     string    `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type     string    `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Location *Location `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	Constant bool      `protobuf:"varint,4,opt,name=constant,proto3" json:"constant,omitempty"`
+	Value    string    `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+	Doc      string    `protobuf:"bytes,6,opt,name=doc,proto3" json:"doc,omitempty"`
+	Scope    string    `protobuf:"bytes,7,opt,name=scope,proto3" json:"scope,omitempty"`
+}
+
+func (x *VariableInfo) Reset() {
+	*x = VariableInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VariableInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VariableInfo) ProtoMessage() {}
+
+func (x *VariableInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VariableInfo.ProtoReflect.Descriptor instead.
+func (*VariableInfo) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *VariableInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *VariableInfo) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *VariableInfo) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *VariableInfo) GetConstant() bool {
+	if x != nil {
+		return x.Constant
+	}
+	return false
+}
+
+func (x *VariableInfo) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *VariableInfo) GetDoc() string {
+	if x != nil {
+		return x.Doc
+	}
+	return ""
+}
+
+func (x *VariableInfo) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+type ReferenceInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name     string      `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Kind     string      `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	Location *Location   `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	UsedAt   []*Location `protobuf:"bytes,4,rep,name=used_at,json=usedAt,proto3" json:"used_at,omitempty"`
+	Scope    string      `protobuf:"bytes,5,opt,name=scope,proto3" json:"scope,omitempty"`
+}
+
+func (x *ReferenceInfo) Reset() {
+	*x = ReferenceInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReferenceInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReferenceInfo) ProtoMessage() {}
+
+func (x *ReferenceInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReferenceInfo.ProtoReflect.Descriptor instead.
+func (*ReferenceInfo) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ReferenceInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ReferenceInfo) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *ReferenceInfo) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *ReferenceInfo) GetUsedAt() []*Location {
+	if x != nil {
+		return x.UsedAt
+	}
+	return nil
+}
+
+func (x *ReferenceInfo) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+type Diagnostic struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Severity string    `protobuf:"bytes,1,opt,name=severity,proto3" json:"severity,omitempty"`
+	Message  string    `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Location *Location `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	Code     string    `protobuf:"bytes,4,opt,name=code,proto3" json:"code,omitempty"`
+	Source   string    `protobuf:"bytes,5,opt,name=source,proto3" json:"source,omitempty"`
+}
+
+func (x *Diagnostic) Reset() {
+	*x = Diagnostic{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Diagnostic) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Diagnostic) ProtoMessage() {}
+
+func (x *Diagnostic) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Diagnostic.ProtoReflect.Descriptor instead.
+func (*Diagnostic) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Diagnostic) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *Diagnostic) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+type Location struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Uri   string `protobuf:"bytes,1,opt,name=uri,proto3" json:"uri,omitempty"`
+	Range *Range `protobuf:"bytes,2,opt,name=range,proto3" json:"range,omitempty"`
+}
+
+func (x *Location) Reset() {
+	*x = Location{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Location) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Location) ProtoMessage() {}
+
+func (x *Location) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Location.ProtoReflect.Descriptor instead.
+func (*Location) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Location) GetUri() string {
+	if x != nil {
+		return x.Uri
+	}
+	return ""
+}
+
+func (x *Location) GetRange() *Range {
+	if x != nil {
+		return x.Range
+	}
+	return nil
+}
+
+type Range struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Start *Position `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	End   *Position `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
+}
+
+func (x *Range) Reset() {
+	*x = Range{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Range) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Range) ProtoMessage() {}
+
+func (x *Range) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Range.ProtoReflect.Descriptor instead.
+func (*Range) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Range) GetStart() *Position {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *Range) GetEnd() *Position {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+type Position struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Line      int64 `protobuf:"varint,1,opt,name=line,proto3" json:"line,omitempty"`
+	Character int64 `protobuf:"varint,2,opt,name=character,proto3" json:"character,omitempty"`
+}
+
+func (x *Position) Reset() {
+	*x = Position{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_analysis_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Position) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Position) ProtoMessage() {}
+
+func (x *Position) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_analysis_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Position.ProtoReflect.Descriptor instead.
+func (*Position) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_analysis_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Position) GetLine() int64 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *Position) GetCharacter() int64 {
+	if x != nil {
+		return x.Character
+	}
+	return 0
+}
+
+var File_mcp_v1_analysis_proto protoreflect.FileDescriptor
+
+var file_mcp_v1_analysis_proto_rawDesc = []byte{
+	0x0a, 0x15, 0x6d, 0x63, 0x70, 0x2f, 0x76, 0x31, 0x2f, 0x61, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69,
+	0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x22,
+	0x3d, 0x0a, 0x0f, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x69, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x75, 0x72, 0x69, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x22, 0x66,
+	0x0a, 0x0c, 0x46, 0x69, 0x6c, 0x65, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x12, 0x10,
+	0x0a, 0x03, 0x75, 0x72, 0x69, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x69,
+	0x12, 0x2e, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x16, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x73,
+	0x69, 0x73, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0xea, 0x02, 0x0a, 0x0e, 0x41, 0x6e, 0x61, 0x6c, 0x79,
+	0x73, 0x69, 0x73, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x2c, 0x0a, 0x07, 0x69, 0x6d, 0x70,
+	0x6f, 0x72, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6d, 0x63, 0x70,
+	0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07,
+	0x69, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x12, 0x32, 0x0a, 0x09, 0x66, 0x75, 0x6e, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x6d, 0x63, 0x70,
+	0x2e, 0x76, 0x31, 0x2e, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x09, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x26, 0x0a, 0x05, 0x74,
+	0x79, 0x70, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x6d, 0x63, 0x70,
+	0x2e, 0x76, 0x31, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x05, 0x74, 0x79,
+	0x70, 0x65, 0x73, 0x12, 0x32, 0x0a, 0x09, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73,
+	0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e,
+	0x56, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x09, 0x76, 0x61,
+	0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73, 0x12, 0x35, 0x0a, 0x0a, 0x72, 0x65, 0x66, 0x65, 0x72,
+	0x65, 0x6e, 0x63, 0x65, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x6d, 0x63,
+	0x70, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x49, 0x6e,
+	0x66, 0x6f, 0x52, 0x0a, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x73, 0x12, 0x34,
+	0x0a, 0x0b, 0x64, 0x69, 0x61, 0x67, 0x6e, 0x6f, 0x73, 0x74, 0x69, 0x63, 0x73, 0x18, 0x06, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x69, 0x61,
+	0x67, 0x6e, 0x6f, 0x73, 0x74, 0x69, 0x63, 0x52, 0x0b, 0x64, 0x69, 0x61, 0x67, 0x6e, 0x6f, 0x73,
+	0x74, 0x69, 0x63, 0x73, 0x12, 0x2d, 0x0a, 0x07, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x43,
+	0x6f, 0x64, 0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x52, 0x07, 0x6d, 0x65, 0x74, 0x72,
+	0x69, 0x63, 0x73, 0x22, 0x93, 0x02, 0x0a, 0x0b, 0x43, 0x6f, 0x64, 0x65, 0x4d, 0x65, 0x74, 0x72,
+	0x69, 0x63, 0x73, 0x12, 0x22, 0x0a, 0x0d, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x5f, 0x6f, 0x66, 0x5f,
+	0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x6c, 0x69, 0x6e, 0x65,
+	0x73, 0x4f, 0x66, 0x43, 0x6f, 0x64, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x63, 0x6f, 0x6d, 0x6d, 0x65,
+	0x6e, 0x74, 0x5f, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c,
+	0x63, 0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x12, 0x25, 0x0a, 0x0e,
+	0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f,
+	0x75, 0x6e, 0x74, 0x12, 0x29, 0x0a, 0x10, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x78, 0x69, 0x74,
+	0x79, 0x5f, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0f, 0x63,
+	0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x78, 0x69, 0x74, 0x79, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x12, 0x27,
+	0x0a, 0x0f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x5f, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61,
+	0x63, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x74, 0x72, 0x75, 0x63,
+	0x74, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x73,
+	0x74, 0x72, 0x75, 0x63, 0x74, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x65,
+	0x73, 0x74, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09,
+	0x74, 0x65, 0x73, 0x74, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x98, 0x01, 0x0a, 0x0c, 0x44, 0x65,
+	0x70, 0x65, 0x6e, 0x64, 0x65, 0x6e, 0x63, 0x69, 0x65, 0x73, 0x12, 0x3b, 0x0a, 0x07, 0x69, 0x6d,
+	0x70, 0x6f, 0x72, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x6d, 0x63,
+	0x70, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x65, 0x70, 0x65, 0x6e, 0x64, 0x65, 0x6e, 0x63, 0x69, 0x65,
+	0x73, 0x2e, 0x49, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07,
+	0x69, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x1a, 0x4b, 0x0a, 0x0c, 0x49, 0x6d, 0x70, 0x6f, 0x72,
+	0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x25, 0x0a, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76,
+	0x31, 0x2e, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x73, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x22, 0x1f, 0x0a, 0x07, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x73, 0x12,
+	0x14, 0x0a, 0x05, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05,
+	0x6e, 0x61, 0x6d, 0x65, 0x73, 0x22, 0x48, 0x0a, 0x0a, 0x49, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x49,
+	0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x75,
+	0x73, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x75, 0x73, 0x65, 0x64, 0x22,
+	0xc1, 0x02, 0x0a, 0x0c, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x66, 0x6f,
+	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75,
+	0x72, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x64, 0x6f, 0x63, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x64, 0x6f, 0x63, 0x12, 0x2c, 0x0a, 0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e,
+	0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x78, 0x69, 0x74, 0x79,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x78, 0x69,
+	0x74, 0x79, 0x12, 0x1b, 0x0a, 0x09, 0x69, 0x73, 0x5f, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x69, 0x73, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x12,
+	0x1a, 0x0a, 0x08, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x72, 0x12, 0x35, 0x0a, 0x0a, 0x70,
+	0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x15, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74,
+	0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x0a, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65,
+	0x72, 0x73, 0x12, 0x2f, 0x0a, 0x07, 0x72, 0x65, 0x74, 0x75, 0x72, 0x6e, 0x73, 0x18, 0x09, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x61, 0x72,
+	0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x72, 0x65, 0x74, 0x75,
+	0x72, 0x6e, 0x73, 0x22, 0x53, 0x0a, 0x0d, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72,
+	0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1a, 0x0a, 0x08,
+	0x76, 0x61, 0x72, 0x69, 0x61, 0x64, 0x69, 0x63, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08,
+	0x76, 0x61, 0x72, 0x69, 0x61, 0x64, 0x69, 0x63, 0x22, 0xeb, 0x01, 0x0a, 0x08, 0x54, 0x79, 0x70,
+	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6b, 0x69, 0x6e,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x10, 0x0a,
+	0x03, 0x64, 0x6f, 0x63, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x64, 0x6f, 0x63, 0x12,
+	0x2c, 0x0a, 0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x10, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f, 0x63, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x29, 0x0a,
+	0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e,
+	0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x12, 0x2c, 0x0a, 0x07, 0x6d, 0x65, 0x74, 0x68,
+	0x6f, 0x64, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6d, 0x63, 0x70, 0x2e,
+	0x76, 0x31, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x6d,
+	0x65, 0x74, 0x68, 0x6f, 0x64, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x6d, 0x70, 0x6c, 0x65, 0x6d,
+	0x65, 0x6e, 0x74, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x69, 0x6d, 0x70, 0x6c,
+	0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x22, 0x6f, 0x0a, 0x09, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x49,
+	0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x64,
+	0x6f, 0x63, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x64, 0x6f, 0x63, 0x12, 0x12, 0x0a,
+	0x04, 0x74, 0x61, 0x67, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x61, 0x67,
+	0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x6d, 0x62, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x05, 0x65, 0x6d, 0x62, 0x65, 0x64, 0x22, 0xb8, 0x01, 0x0a, 0x0a, 0x4d, 0x65, 0x74, 0x68,
+	0x6f, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69,
+	0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73,
+	0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x64, 0x6f, 0x63, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x64, 0x6f, 0x63, 0x12, 0x35, 0x0a, 0x0a, 0x70, 0x61,
+	0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15,
+	0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65,
+	0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x0a, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72,
+	0x73, 0x12, 0x2f, 0x0a, 0x07, 0x72, 0x65, 0x74, 0x75, 0x72, 0x6e, 0x73, 0x18, 0x05, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x15, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x61, 0x72, 0x61,
+	0x6d, 0x65, 0x74, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x72, 0x65, 0x74, 0x75, 0x72,
+	0x6e, 0x73, 0x22, 0xbe, 0x01, 0x0a, 0x0c, 0x56, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x49,
+	0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x2c, 0x0a, 0x08, 0x6c,
+	0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e,
+	0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x6f, 0x6e,
+	0x73, 0x74, 0x61, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x63, 0x6f, 0x6e,
+	0x73, 0x74, 0x61, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x64,
+	0x6f, 0x63, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x64, 0x6f, 0x63, 0x12, 0x14, 0x0a,
+	0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x63,
+	0x6f, 0x70, 0x65, 0x22, 0xa6, 0x01, 0x0a, 0x0d, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63,
+	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6b, 0x69, 0x6e,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x2c, 0x0a,
+	0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x10, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x52, 0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x29, 0x0a, 0x07, 0x75,
+	0x73, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x6d,
+	0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06,
+	0x75, 0x73, 0x65, 0x64, 0x41, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x22, 0x9c, 0x01, 0x0a,
+	0x0a, 0x44, 0x69, 0x61, 0x67, 0x6e, 0x6f, 0x73, 0x74, 0x69, 0x63, 0x12, 0x1a, 0x0a, 0x08, 0x73,
+	0x65, 0x76, 0x65, 0x72, 0x69, 0x74, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73,
+	0x65, 0x76, 0x65, 0x72, 0x69, 0x74, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67,
+	0x65, 0x12, 0x2c, 0x0a, 0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x63,
+	0x6f, 0x64, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x22, 0x41, 0x0a, 0x08, 0x4c,
+	0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x69, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x69, 0x12, 0x23, 0x0a, 0x05, 0x72, 0x61, 0x6e,
+	0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76,
+	0x31, 0x2e, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x05, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x22, 0x53,
+	0x0a, 0x05, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x26, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e,
+	0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x12,
+	0x22, 0x0a, 0x03, 0x65, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x6d,
+	0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x03,
+	0x65, 0x6e, 0x64, 0x22, 0x3c, 0x0a, 0x08, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x12, 0x0a, 0x04, 0x6c, 0x69, 0x6e, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x6c,
+	0x69, 0x6e, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x68, 0x61, 0x72, 0x61, 0x63, 0x74, 0x65, 0x72,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x68, 0x61, 0x72, 0x61, 0x63, 0x74, 0x65,
+	0x72, 0x32, 0x9a, 0x02, 0x0a, 0x0f, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x3e, 0x0a, 0x0b, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x7a, 0x65,
+	0x46, 0x69, 0x6c, 0x65, 0x12, 0x17, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x6e,
+	0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e,
+	0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x44, 0x0a, 0x13, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x7a, 0x65,
+	0x44, 0x65, 0x70, 0x65, 0x6e, 0x64, 0x65, 0x6e, 0x63, 0x69, 0x65, 0x73, 0x12, 0x17, 0x2e, 0x6d,
+	0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x44,
+	0x65, 0x70, 0x65, 0x6e, 0x64, 0x65, 0x6e, 0x63, 0x69, 0x65, 0x73, 0x12, 0x3e, 0x0a, 0x0e, 0x41,
+	0x6e, 0x61, 0x6c, 0x79, 0x7a, 0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x17, 0x2e,
+	0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e,
+	0x43, 0x6f, 0x64, 0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x41, 0x0a, 0x0c, 0x41,
+	0x6e, 0x61, 0x6c, 0x79, 0x7a, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x17, 0x2e, 0x6d, 0x63,
+	0x70, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x69,
+	0x6c, 0x65, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x28, 0x01, 0x30, 0x01, 0x42, 0x2a,
+	0x5a, 0x28, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x69, 0x76, 0x69,
+	0x6b, 0x61, 0x73, 0x61, 0x76, 0x6e, 0x69, 0x73, 0x68, 0x2f, 0x67, 0x6f, 0x2d, 0x6d, 0x63, 0x70,
+	0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x6d, 0x63, 0x70, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_mcp_v1_analysis_proto_rawDescOnce sync.Once
+	file_mcp_v1_analysis_proto_rawDescData = file_mcp_v1_analysis_proto_rawDesc
+)
+
+func file_mcp_v1_analysis_proto_rawDescGZIP() []byte {
+	file_mcp_v1_analysis_proto_rawDescOnce.Do(func() {
+		file_mcp_v1_analysis_proto_rawDescData = protoimpl.X.CompressGZIP(file_mcp_v1_analysis_proto_rawDescData)
+	})
+	return file_mcp_v1_analysis_proto_rawDescData
+}
+
+var file_mcp_v1_analysis_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_mcp_v1_analysis_proto_goTypes = []any{
+	(*AnalysisRequest)(nil), // 0: mcp.v1.AnalysisRequest
+	(*FileAnalysis)(nil),    // 1: mcp.v1.FileAnalysis
+	(*AnalysisResult)(nil),  // 2: mcp.v1.AnalysisResult
+	(*CodeMetrics)(nil),     // 3: mcp.v1.CodeMetrics
+	(*Dependencies)(nil),    // 4: mcp.v1.Dependencies
+	(*Symbols)(nil),         // 5: mcp.v1.Symbols
+	(*ImportInfo)(nil),      // 6: mcp.v1.ImportInfo
+	(*FunctionInfo)(nil),    // 7: mcp.v1.FunctionInfo
+	(*ParameterInfo)(nil),   // 8: mcp.v1.ParameterInfo
+	(*TypeInfo)(nil),        // 9: mcp.v1.TypeInfo
+	(*FieldInfo)(nil),       // 10: mcp.v1.FieldInfo
+	(*MethodInfo)(nil),      // 11: mcp.v1.MethodInfo
+	(*VariableInfo)(nil),    // 12: mcp.v1.VariableInfo
+	(*ReferenceInfo)(nil),   // 13: mcp.v1.ReferenceInfo
+	(*Diagnostic)(nil),      // 14: mcp.v1.Diagnostic
+	(*Location)(nil),        // 15: mcp.v1.Location
+	(*Range)(nil),           // 16: mcp.v1.Range
+	(*Position)(nil),        // 17: mcp.v1.Position
+	nil,                     // 18: mcp.v1.Dependencies.ImportsEntry
+}
+var file_mcp_v1_analysis_proto_depIdxs = []int32{
+	2,  // 0: mcp.v1.FileAnalysis.result:type_name -> mcp.v1.AnalysisResult
+	6,  // 1: mcp.v1.AnalysisResult.imports:type_name -> mcp.v1.ImportInfo
+	7,  // 2: mcp.v1.AnalysisResult.functions:type_name -> mcp.v1.FunctionInfo
+	9,  // 3: mcp.v1.AnalysisResult.types:type_name -> mcp.v1.TypeInfo
+	12, // 4: mcp.v1.AnalysisResult.variables:type_name -> mcp.v1.VariableInfo
+	13, // 5: mcp.v1.AnalysisResult.references:type_name -> mcp.v1.ReferenceInfo
+	14, // 6: mcp.v1.AnalysisResult.diagnostics:type_name -> mcp.v1.Diagnostic
+	3,  // 7: mcp.v1.AnalysisResult.metrics:type_name -> mcp.v1.CodeMetrics
+	18, // 8: mcp.v1.Dependencies.imports:type_name -> mcp.v1.Dependencies.ImportsEntry
+	15, // 9: mcp.v1.FunctionInfo.location:type_name -> mcp.v1.Location
+	8,  // 10: mcp.v1.FunctionInfo.parameters:type_name -> mcp.v1.ParameterInfo
+	8,  // 11: mcp.v1.FunctionInfo.returns:type_name -> mcp.v1.ParameterInfo
+	15, // 12: mcp.v1.TypeInfo.location:type_name -> mcp.v1.Location
+	10, // 13: mcp.v1.TypeInfo.fields:type_name -> mcp.v1.FieldInfo
+	11, // 14: mcp.v1.TypeInfo.methods:type_name -> mcp.v1.MethodInfo
+	8,  // 15: mcp.v1.MethodInfo.parameters:type_name -> mcp.v1.ParameterInfo
+	8,  // 16: mcp.v1.MethodInfo.returns:type_name -> mcp.v1.ParameterInfo
+	15, // 17: mcp.v1.VariableInfo.location:type_name -> mcp.v1.Location
+	15, // 18: mcp.v1.ReferenceInfo.location:type_name -> mcp.v1.Location
+	15, // 19: mcp.v1.ReferenceInfo.used_at:type_name -> mcp.v1.Location
+	15, // 20: mcp.v1.Diagnostic.location:type_name -> mcp.v1.Location
+	16, // 21: mcp.v1.Location.range:type_name -> mcp.v1.Range
+	17, // 22: mcp.v1.Range.start:type_name -> mcp.v1.Position
+	17, // 23: mcp.v1.Range.end:type_name -> mcp.v1.Position
+	5,  // 24: mcp.v1.Dependencies.ImportsEntry.value:type_name -> mcp.v1.Symbols
+	0,  // 25: mcp.v1.AnalysisService.AnalyzeFile:input_type -> mcp.v1.AnalysisRequest
+	0,  // 26: mcp.v1.AnalysisService.AnalyzeDependencies:input_type -> mcp.v1.AnalysisRequest
+	0,  // 27: mcp.v1.AnalysisService.AnalyzeMetrics:input_type -> mcp.v1.AnalysisRequest
+	0,  // 28: mcp.v1.AnalysisService.AnalyzeFiles:input_type -> mcp.v1.AnalysisRequest
+	2,  // 29: mcp.v1.AnalysisService.AnalyzeFile:output_type -> mcp.v1.AnalysisResult
+	4,  // 30: mcp.v1.AnalysisService.AnalyzeDependencies:output_type -> mcp.v1.Dependencies
+	3,  // 31: mcp.v1.AnalysisService.AnalyzeMetrics:output_type -> mcp.v1.CodeMetrics
+	1,  // 32: mcp.v1.AnalysisService.AnalyzeFiles:output_type -> mcp.v1.FileAnalysis
+	29, // [29:33] is the sub-list for method output_type
+	25, // [25:29] is the sub-list for method input_type
+	25, // [25:25] is the sub-list for extension type_name
+	25, // [25:25] is the sub-list for extension extendee
+	0,  // [0:25] is the sub-list for field type_name
+}
+
+func init() { file_mcp_v1_analysis_proto_init() }
+func file_mcp_v1_analysis_proto_init() {
+	if File_mcp_v1_analysis_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_mcp_v1_analysis_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*AnalysisRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*FileAnalysis); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*AnalysisResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*CodeMetrics); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*Dependencies); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*Symbols); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*ImportInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*FunctionInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*ParameterInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*TypeInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*FieldInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[11].Exporter = func(v any, i int) any {
+			switch v := v.(*MethodInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[12].Exporter = func(v any, i int) any {
+			switch v := v.(*VariableInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[13].Exporter = func(v any, i int) any {
+			switch v := v.(*ReferenceInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[14].Exporter = func(v any, i int) any {
+			switch v := v.(*Diagnostic); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[15].Exporter = func(v any, i int) any {
+			switch v := v.(*Location); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[16].Exporter = func(v any, i int) any {
+			switch v := v.(*Range); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_analysis_proto_msgTypes[17].Exporter = func(v any, i int) any {
+			switch v := v.(*Position); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_mcp_v1_analysis_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   19,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_mcp_v1_analysis_proto_goTypes,
+		DependencyIndexes: file_mcp_v1_analysis_proto_depIdxs,
+		MessageInfos:      file_mcp_v1_analysis_proto_msgTypes,
+	}.Build()
+	File_mcp_v1_analysis_proto = out.File
+	file_mcp_v1_analysis_proto_rawDesc = nil
+	file_mcp_v1_analysis_proto_goTypes = nil
+	file_mcp_v1_analysis_proto_depIdxs = nil
+}
@@ -0,0 +1,238 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: mcp/v1/analysis.proto
+
+package mcppb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	AnalysisService_AnalyzeFile_FullMethodName         = "/mcp.v1.AnalysisService/AnalyzeFile"
+	AnalysisService_AnalyzeDependencies_FullMethodName = "/mcp.v1.AnalysisService/AnalyzeDependencies"
+	AnalysisService_AnalyzeMetrics_FullMethodName      = "/mcp.v1.AnalysisService/AnalyzeMetrics"
+	AnalysisService_AnalyzeFiles_FullMethodName        = "/mcp.v1.AnalysisService/AnalyzeFiles"
+)
+
+// AnalysisServiceClient is the client API for AnalysisService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AnalysisService analyzes Go source files
+type AnalysisServiceClient interface {
+	AnalyzeFile(ctx context.Context, in *AnalysisRequest, opts ...grpc.CallOption) (*AnalysisResult, error)
+	AnalyzeDependencies(ctx context.Context, in *AnalysisRequest, opts ...grpc.CallOption) (*Dependencies, error)
+	AnalyzeMetrics(ctx context.Context, in *AnalysisRequest, opts ...grpc.CallOption) (*CodeMetrics, error)
+	// AnalyzeFiles analyzes each file as it arrives. A file that fails to
+	// parse yields a result with an error rather than ending the stream.
+	AnalyzeFiles(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AnalysisRequest, FileAnalysis], error)
+}
+
+type analysisServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAnalysisServiceClient(cc grpc.ClientConnInterface) AnalysisServiceClient {
+	return &analysisServiceClient{cc}
+}
+
+func (c *analysisServiceClient) AnalyzeFile(ctx context.Context, in *AnalysisRequest, opts ...grpc.CallOption) (*AnalysisResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AnalysisResult)
+	err := c.cc.Invoke(ctx, AnalysisService_AnalyzeFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analysisServiceClient) AnalyzeDependencies(ctx context.Context, in *AnalysisRequest, opts ...grpc.CallOption) (*Dependencies, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Dependencies)
+	err := c.cc.Invoke(ctx, AnalysisService_AnalyzeDependencies_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analysisServiceClient) AnalyzeMetrics(ctx context.Context, in *AnalysisRequest, opts ...grpc.CallOption) (*CodeMetrics, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CodeMetrics)
+	err := c.cc.Invoke(ctx, AnalysisService_AnalyzeMetrics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analysisServiceClient) AnalyzeFiles(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AnalysisRequest, FileAnalysis], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AnalysisService_ServiceDesc.Streams[0], AnalysisService_AnalyzeFiles_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[AnalysisRequest, FileAnalysis]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AnalysisService_AnalyzeFilesClient = grpc.BidiStreamingClient[AnalysisRequest, FileAnalysis]
+
+// AnalysisServiceServer is the server API for AnalysisService service.
+// All implementations must embed UnimplementedAnalysisServiceServer
+// for forward compatibility.
+//
+// AnalysisService analyzes Go source files
+type AnalysisServiceServer interface {
+	AnalyzeFile(context.Context, *AnalysisRequest) (*AnalysisResult, error)
+	AnalyzeDependencies(context.Context, *AnalysisRequest) (*Dependencies, error)
+	AnalyzeMetrics(context.Context, *AnalysisRequest) (*CodeMetrics, error)
+	// AnalyzeFiles analyzes each file as it arrives. A file that fails to
+	// parse yields a result with an error rather than ending the stream.
+	AnalyzeFiles(grpc.BidiStreamingServer[AnalysisRequest, FileAnalysis]) error
+	mustEmbedUnimplementedAnalysisServiceServer()
+}
+
+// UnimplementedAnalysisServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAnalysisServiceServer struct{}
+
+func (UnimplementedAnalysisServiceServer) AnalyzeFile(context.Context, *AnalysisRequest) (*AnalysisResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnalyzeFile not implemented")
+}
+func (UnimplementedAnalysisServiceServer) AnalyzeDependencies(context.Context, *AnalysisRequest) (*Dependencies, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnalyzeDependencies not implemented")
+}
+func (UnimplementedAnalysisServiceServer) AnalyzeMetrics(context.Context, *AnalysisRequest) (*CodeMetrics, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnalyzeMetrics not implemented")
+}
+func (UnimplementedAnalysisServiceServer) AnalyzeFiles(grpc.BidiStreamingServer[AnalysisRequest, FileAnalysis]) error {
+	return status.Errorf(codes.Unimplemented, "method AnalyzeFiles not implemented")
+}
+func (UnimplementedAnalysisServiceServer) mustEmbedUnimplementedAnalysisServiceServer() {}
+func (UnimplementedAnalysisServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeAnalysisServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AnalysisServiceServer will
+// result in compilation errors.
+type UnsafeAnalysisServiceServer interface {
+	mustEmbedUnimplementedAnalysisServiceServer()
+}
+
+func RegisterAnalysisServiceServer(s grpc.ServiceRegistrar, srv AnalysisServiceServer) {
+	// If the following call pancis, it indicates UnimplementedAnalysisServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AnalysisService_ServiceDesc, srv)
+}
+
+func _AnalysisService_AnalyzeFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnalysisRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServiceServer).AnalyzeFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalysisService_AnalyzeFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServiceServer).AnalyzeFile(ctx, req.(*AnalysisRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalysisService_AnalyzeDependencies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnalysisRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServiceServer).AnalyzeDependencies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalysisService_AnalyzeDependencies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServiceServer).AnalyzeDependencies(ctx, req.(*AnalysisRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalysisService_AnalyzeMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnalysisRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServiceServer).AnalyzeMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalysisService_AnalyzeMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServiceServer).AnalyzeMetrics(ctx, req.(*AnalysisRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalysisService_AnalyzeFiles_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AnalysisServiceServer).AnalyzeFiles(&grpc.GenericServerStream[AnalysisRequest, FileAnalysis]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AnalysisService_AnalyzeFilesServer = grpc.BidiStreamingServer[AnalysisRequest, FileAnalysis]
+
+// AnalysisService_ServiceDesc is the grpc.ServiceDesc for AnalysisService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AnalysisService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mcp.v1.AnalysisService",
+	HandlerType: (*AnalysisServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AnalyzeFile",
+			Handler:    _AnalysisService_AnalyzeFile_Handler,
+		},
+		{
+			MethodName: "AnalyzeDependencies",
+			Handler:    _AnalysisService_AnalyzeDependencies_Handler,
+		},
+		{
+			MethodName: "AnalyzeMetrics",
+			Handler:    _AnalysisService_AnalyzeMetrics_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "AnalyzeFiles",
+			Handler:       _AnalysisService_AnalyzeFiles_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "mcp/v1/analysis.proto",
+}
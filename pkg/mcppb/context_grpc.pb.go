@@ -0,0 +1,288 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: mcp/v1/context.proto
+
+package mcppb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ContextService_CreateContext_FullMethodName = "/mcp.v1.ContextService/CreateContext"
+	ContextService_GetContext_FullMethodName    = "/mcp.v1.ContextService/GetContext"
+	ContextService_UpdateContext_FullMethodName = "/mcp.v1.ContextService/UpdateContext"
+	ContextService_DeleteContext_FullMethodName = "/mcp.v1.ContextService/DeleteContext"
+	ContextService_ListContexts_FullMethodName  = "/mcp.v1.ContextService/ListContexts"
+)
+
+// ContextServiceClient is the client API for ContextService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ContextService manages model contexts in the server's context store. It
+// shares the store with the HTTP API.
+type ContextServiceClient interface {
+	CreateContext(ctx context.Context, in *CreateContextRequest, opts ...grpc.CallOption) (*Context, error)
+	GetContext(ctx context.Context, in *GetContextRequest, opts ...grpc.CallOption) (*Context, error)
+	// UpdateContext replaces the metadata of a context
+	UpdateContext(ctx context.Context, in *UpdateContextRequest, opts ...grpc.CallOption) (*Context, error)
+	DeleteContext(ctx context.Context, in *DeleteContextRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ListContexts streams every context in the store
+	ListContexts(ctx context.Context, in *ListContextsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Context], error)
+}
+
+type contextServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewContextServiceClient(cc grpc.ClientConnInterface) ContextServiceClient {
+	return &contextServiceClient{cc}
+}
+
+func (c *contextServiceClient) CreateContext(ctx context.Context, in *CreateContextRequest, opts ...grpc.CallOption) (*Context, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Context)
+	err := c.cc.Invoke(ctx, ContextService_CreateContext_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contextServiceClient) GetContext(ctx context.Context, in *GetContextRequest, opts ...grpc.CallOption) (*Context, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Context)
+	err := c.cc.Invoke(ctx, ContextService_GetContext_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contextServiceClient) UpdateContext(ctx context.Context, in *UpdateContextRequest, opts ...grpc.CallOption) (*Context, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Context)
+	err := c.cc.Invoke(ctx, ContextService_UpdateContext_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contextServiceClient) DeleteContext(ctx context.Context, in *DeleteContextRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ContextService_DeleteContext_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contextServiceClient) ListContexts(ctx context.Context, in *ListContextsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Context], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ContextService_ServiceDesc.Streams[0], ContextService_ListContexts_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListContextsRequest, Context]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ContextService_ListContextsClient = grpc.ServerStreamingClient[Context]
+
+// ContextServiceServer is the server API for ContextService service.
+// All implementations must embed UnimplementedContextServiceServer
+// for forward compatibility.
+//
+// ContextService manages model contexts in the server's context store. It
+// shares the store with the HTTP API.
+type ContextServiceServer interface {
+	CreateContext(context.Context, *CreateContextRequest) (*Context, error)
+	GetContext(context.Context, *GetContextRequest) (*Context, error)
+	// UpdateContext replaces the metadata of a context
+	UpdateContext(context.Context, *UpdateContextRequest) (*Context, error)
+	DeleteContext(context.Context, *DeleteContextRequest) (*emptypb.Empty, error)
+	// ListContexts streams every context in the store
+	ListContexts(*ListContextsRequest, grpc.ServerStreamingServer[Context]) error
+	mustEmbedUnimplementedContextServiceServer()
+}
+
+// UnimplementedContextServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedContextServiceServer struct{}
+
+func (UnimplementedContextServiceServer) CreateContext(context.Context, *CreateContextRequest) (*Context, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateContext not implemented")
+}
+func (UnimplementedContextServiceServer) GetContext(context.Context, *GetContextRequest) (*Context, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetContext not implemented")
+}
+func (UnimplementedContextServiceServer) UpdateContext(context.Context, *UpdateContextRequest) (*Context, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateContext not implemented")
+}
+func (UnimplementedContextServiceServer) DeleteContext(context.Context, *DeleteContextRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteContext not implemented")
+}
+func (UnimplementedContextServiceServer) ListContexts(*ListContextsRequest, grpc.ServerStreamingServer[Context]) error {
+	return status.Errorf(codes.Unimplemented, "method ListContexts not implemented")
+}
+func (UnimplementedContextServiceServer) mustEmbedUnimplementedContextServiceServer() {}
+func (UnimplementedContextServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeContextServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ContextServiceServer will
+// result in compilation errors.
+type UnsafeContextServiceServer interface {
+	mustEmbedUnimplementedContextServiceServer()
+}
+
+func RegisterContextServiceServer(s grpc.ServiceRegistrar, srv ContextServiceServer) {
+	// If the following call pancis, it indicates UnimplementedContextServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ContextService_ServiceDesc, srv)
+}
+
+func _ContextService_CreateContext_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateContextRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContextServiceServer).CreateContext(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContextService_CreateContext_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContextServiceServer).CreateContext(ctx, req.(*CreateContextRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContextService_GetContext_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetContextRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContextServiceServer).GetContext(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContextService_GetContext_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContextServiceServer).GetContext(ctx, req.(*GetContextRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContextService_UpdateContext_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateContextRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContextServiceServer).UpdateContext(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContextService_UpdateContext_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContextServiceServer).UpdateContext(ctx, req.(*UpdateContextRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContextService_DeleteContext_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteContextRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContextServiceServer).DeleteContext(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContextService_DeleteContext_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContextServiceServer).DeleteContext(ctx, req.(*DeleteContextRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContextService_ListContexts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListContextsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ContextServiceServer).ListContexts(m, &grpc.GenericServerStream[ListContextsRequest, Context]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ContextService_ListContextsServer = grpc.ServerStreamingServer[Context]
+
+// ContextService_ServiceDesc is the grpc.ServiceDesc for ContextService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ContextService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mcp.v1.ContextService",
+	HandlerType: (*ContextServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateContext",
+			Handler:    _ContextService_CreateContext_Handler,
+		},
+		{
+			MethodName: "GetContext",
+			Handler:    _ContextService_GetContext_Handler,
+		},
+		{
+			MethodName: "UpdateContext",
+			Handler:    _ContextService_UpdateContext_Handler,
+		},
+		{
+			MethodName: "DeleteContext",
+			Handler:    _ContextService_DeleteContext_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListContexts",
+			Handler:       _ContextService_ListContexts_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "mcp/v1/context.proto",
+}
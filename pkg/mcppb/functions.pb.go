@@ -0,0 +1,526 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: mcp/v1/functions.proto
+
+package mcppb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListFunctionsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListFunctionsRequest) Reset() {
+	*x = ListFunctionsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_functions_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListFunctionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFunctionsRequest) ProtoMessage() {}
+
+func (x *ListFunctionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_functions_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFunctionsRequest.ProtoReflect.Descriptor instead.
+func (*ListFunctionsRequest) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_functions_proto_rawDescGZIP(), []int{0}
+}
+
+type ListFunctionsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Functions []*FunctionMetadata `protobuf:"bytes,1,rep,name=functions,proto3" json:"functions,omitempty"`
+}
+
+func (x *ListFunctionsResponse) Reset() {
+	*x = ListFunctionsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_functions_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListFunctionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFunctionsResponse) ProtoMessage() {}
+
+func (x *ListFunctionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_functions_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFunctionsResponse.ProtoReflect.Descriptor instead.
+func (*ListFunctionsResponse) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_functions_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListFunctionsResponse) GetFunctions() []*FunctionMetadata {
+	if x != nil {
+		return x.Functions
+	}
+	return nil
+}
+
+type FunctionMetadata struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name       string          `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Arguments  []*ArgumentInfo `protobuf:"bytes,2,rep,name=arguments,proto3" json:"arguments,omitempty"`
+	ReturnType string          `protobuf:"bytes,3,opt,name=return_type,json=returnType,proto3" json:"return_type,omitempty"`
+}
+
+func (x *FunctionMetadata) Reset() {
+	*x = FunctionMetadata{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_functions_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FunctionMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FunctionMetadata) ProtoMessage() {}
+
+func (x *FunctionMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_functions_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FunctionMetadata.ProtoReflect.Descriptor instead.
+func (*FunctionMetadata) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_functions_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FunctionMetadata) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FunctionMetadata) GetArguments() []*ArgumentInfo {
+	if x != nil {
+		return x.Arguments
+	}
+	return nil
+}
+
+func (x *FunctionMetadata) GetReturnType() string {
+	if x != nil {
+		return x.ReturnType
+	}
+	return ""
+}
+
+type ArgumentInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name     string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type     string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Required bool   `protobuf:"varint,3,opt,name=required,proto3" json:"required,omitempty"`
+}
+
+func (x *ArgumentInfo) Reset() {
+	*x = ArgumentInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_functions_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ArgumentInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArgumentInfo) ProtoMessage() {}
+
+func (x *ArgumentInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_functions_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArgumentInfo.ProtoReflect.Descriptor instead.
+func (*ArgumentInfo) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_functions_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ArgumentInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ArgumentInfo) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ArgumentInfo) GetRequired() bool {
+	if x != nil {
+		return x.Required
+	}
+	return false
+}
+
+type CallFunctionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name      string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Arguments []*structpb.Value `protobuf:"bytes,2,rep,name=arguments,proto3" json:"arguments,omitempty"`
+}
+
+func (x *CallFunctionRequest) Reset() {
+	*x = CallFunctionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_functions_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CallFunctionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallFunctionRequest) ProtoMessage() {}
+
+func (x *CallFunctionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_functions_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallFunctionRequest.ProtoReflect.Descriptor instead.
+func (*CallFunctionRequest) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_functions_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CallFunctionRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CallFunctionRequest) GetArguments() []*structpb.Value {
+	if x != nil {
+		return x.Arguments
+	}
+	return nil
+}
+
+type CallFunctionResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Result *structpb.Value `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+}
+
+func (x *CallFunctionResponse) Reset() {
+	*x = CallFunctionResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mcp_v1_functions_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CallFunctionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallFunctionResponse) ProtoMessage() {}
+
+func (x *CallFunctionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mcp_v1_functions_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallFunctionResponse.ProtoReflect.Descriptor instead.
+func (*CallFunctionResponse) Descriptor() ([]byte, []int) {
+	return file_mcp_v1_functions_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CallFunctionResponse) GetResult() *structpb.Value {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+var File_mcp_v1_functions_proto protoreflect.FileDescriptor
+
+var file_mcp_v1_functions_proto_rawDesc = []byte{
+	0x0a, 0x16, 0x6d, 0x63, 0x70, 0x2f, 0x76, 0x31, 0x2f, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31,
+	0x1a, 0x1c, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2f, 0x73, 0x74, 0x72, 0x75, 0x63, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x16,
+	0x0a, 0x14, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x4f, 0x0a, 0x15, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x75,
+	0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x36, 0x0a, 0x09, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x75, 0x6e, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x52, 0x09, 0x66, 0x75,
+	0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x7b, 0x0a, 0x10, 0x46, 0x75, 0x6e, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x32, 0x0a, 0x09, 0x61, 0x72, 0x67, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x14, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x72, 0x67, 0x75,
+	0x6d, 0x65, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x09, 0x61, 0x72, 0x67, 0x75, 0x6d, 0x65,
+	0x6e, 0x74, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x65, 0x74, 0x75, 0x72, 0x6e, 0x5f, 0x74, 0x79,
+	0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x72, 0x65, 0x74, 0x75, 0x72, 0x6e,
+	0x54, 0x79, 0x70, 0x65, 0x22, 0x52, 0x0a, 0x0c, 0x41, 0x72, 0x67, 0x75, 0x6d, 0x65, 0x6e, 0x74,
+	0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1a, 0x0a, 0x08,
+	0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08,
+	0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x64, 0x22, 0x5f, 0x0a, 0x13, 0x43, 0x61, 0x6c, 0x6c,
+	0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x12, 0x34, 0x0a, 0x09, 0x61, 0x72, 0x67, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x73,
+	0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x09,
+	0x61, 0x72, 0x67, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x22, 0x46, 0x0a, 0x14, 0x43, 0x61, 0x6c,
+	0x6c, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x2e, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x32, 0xaa, 0x01, 0x0a, 0x0f, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x4c, 0x0a, 0x0d, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x75, 0x6e,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1c, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e,
+	0x4c, 0x69, 0x73, 0x74, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69,
+	0x73, 0x74, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x49, 0x0a, 0x0c, 0x43, 0x61, 0x6c, 0x6c, 0x46, 0x75, 0x6e, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x61, 0x6c,
+	0x6c, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x1c, 0x2e, 0x6d, 0x63, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x61, 0x6c, 0x6c, 0x46, 0x75,
+	0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x2a,
+	0x5a, 0x28, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x69, 0x76, 0x69,
+	0x6b, 0x61, 0x73, 0x61, 0x76, 0x6e, 0x69, 0x73, 0x68, 0x2f, 0x67, 0x6f, 0x2d, 0x6d, 0x63, 0x70,
+	0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x6d, 0x63, 0x70, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_mcp_v1_functions_proto_rawDescOnce sync.Once
+	file_mcp_v1_functions_proto_rawDescData = file_mcp_v1_functions_proto_rawDesc
+)
+
+func file_mcp_v1_functions_proto_rawDescGZIP() []byte {
+	file_mcp_v1_functions_proto_rawDescOnce.Do(func() {
+		file_mcp_v1_functions_proto_rawDescData = protoimpl.X.CompressGZIP(file_mcp_v1_functions_proto_rawDescData)
+	})
+	return file_mcp_v1_functions_proto_rawDescData
+}
+
+var file_mcp_v1_functions_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_mcp_v1_functions_proto_goTypes = []any{
+	(*ListFunctionsRequest)(nil),  // 0: mcp.v1.ListFunctionsRequest
+	(*ListFunctionsResponse)(nil), // 1: mcp.v1.ListFunctionsResponse
+	(*FunctionMetadata)(nil),      // 2: mcp.v1.FunctionMetadata
+	(*ArgumentInfo)(nil),          // 3: mcp.v1.ArgumentInfo
+	(*CallFunctionRequest)(nil),   // 4: mcp.v1.CallFunctionRequest
+	(*CallFunctionResponse)(nil),  // 5: mcp.v1.CallFunctionResponse
+	(*structpb.Value)(nil),        // 6: google.protobuf.Value
+}
+var file_mcp_v1_functions_proto_depIdxs = []int32{
+	2, // 0: mcp.v1.ListFunctionsResponse.functions:type_name -> mcp.v1.FunctionMetadata
+	3, // 1: mcp.v1.FunctionMetadata.arguments:type_name -> mcp.v1.ArgumentInfo
+	6, // 2: mcp.v1.CallFunctionRequest.arguments:type_name -> google.protobuf.Value
+	6, // 3: mcp.v1.CallFunctionResponse.result:type_name -> google.protobuf.Value
+	0, // 4: mcp.v1.FunctionService.ListFunctions:input_type -> mcp.v1.ListFunctionsRequest
+	4, // 5: mcp.v1.FunctionService.CallFunction:input_type -> mcp.v1.CallFunctionRequest
+	1, // 6: mcp.v1.FunctionService.ListFunctions:output_type -> mcp.v1.ListFunctionsResponse
+	5, // 7: mcp.v1.FunctionService.CallFunction:output_type -> mcp.v1.CallFunctionResponse
+	6, // [6:8] is the sub-list for method output_type
+	4, // [4:6] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_mcp_v1_functions_proto_init() }
+func file_mcp_v1_functions_proto_init() {
+	if File_mcp_v1_functions_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_mcp_v1_functions_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*ListFunctionsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_functions_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*ListFunctionsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_functions_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*FunctionMetadata); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_functions_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*ArgumentInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_functions_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*CallFunctionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mcp_v1_functions_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*CallFunctionResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_mcp_v1_functions_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_mcp_v1_functions_proto_goTypes,
+		DependencyIndexes: file_mcp_v1_functions_proto_depIdxs,
+		MessageInfos:      file_mcp_v1_functions_proto_msgTypes,
+	}.Build()
+	File_mcp_v1_functions_proto = out.File
+	file_mcp_v1_functions_proto_rawDesc = nil
+	file_mcp_v1_functions_proto_goTypes = nil
+	file_mcp_v1_functions_proto_depIdxs = nil
+}
@@ -0,0 +1,163 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: mcp/v1/functions.proto
+
+package mcppb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	FunctionService_ListFunctions_FullMethodName = "/mcp.v1.FunctionService/ListFunctions"
+	FunctionService_CallFunction_FullMethodName  = "/mcp.v1.FunctionService/CallFunction"
+)
+
+// FunctionServiceClient is the client API for FunctionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// FunctionService calls the functions registered with the server
+type FunctionServiceClient interface {
+	ListFunctions(ctx context.Context, in *ListFunctionsRequest, opts ...grpc.CallOption) (*ListFunctionsResponse, error)
+	CallFunction(ctx context.Context, in *CallFunctionRequest, opts ...grpc.CallOption) (*CallFunctionResponse, error)
+}
+
+type functionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFunctionServiceClient(cc grpc.ClientConnInterface) FunctionServiceClient {
+	return &functionServiceClient{cc}
+}
+
+func (c *functionServiceClient) ListFunctions(ctx context.Context, in *ListFunctionsRequest, opts ...grpc.CallOption) (*ListFunctionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFunctionsResponse)
+	err := c.cc.Invoke(ctx, FunctionService_ListFunctions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *functionServiceClient) CallFunction(ctx context.Context, in *CallFunctionRequest, opts ...grpc.CallOption) (*CallFunctionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CallFunctionResponse)
+	err := c.cc.Invoke(ctx, FunctionService_CallFunction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FunctionServiceServer is the server API for FunctionService service.
+// All implementations must embed UnimplementedFunctionServiceServer
+// for forward compatibility.
+//
+// FunctionService calls the functions registered with the server
+type FunctionServiceServer interface {
+	ListFunctions(context.Context, *ListFunctionsRequest) (*ListFunctionsResponse, error)
+	CallFunction(context.Context, *CallFunctionRequest) (*CallFunctionResponse, error)
+	mustEmbedUnimplementedFunctionServiceServer()
+}
+
+// UnimplementedFunctionServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedFunctionServiceServer struct{}
+
+func (UnimplementedFunctionServiceServer) ListFunctions(context.Context, *ListFunctionsRequest) (*ListFunctionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFunctions not implemented")
+}
+func (UnimplementedFunctionServiceServer) CallFunction(context.Context, *CallFunctionRequest) (*CallFunctionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CallFunction not implemented")
+}
+func (UnimplementedFunctionServiceServer) mustEmbedUnimplementedFunctionServiceServer() {}
+func (UnimplementedFunctionServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeFunctionServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FunctionServiceServer will
+// result in compilation errors.
+type UnsafeFunctionServiceServer interface {
+	mustEmbedUnimplementedFunctionServiceServer()
+}
+
+func RegisterFunctionServiceServer(s grpc.ServiceRegistrar, srv FunctionServiceServer) {
+	// If the following call pancis, it indicates UnimplementedFunctionServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&FunctionService_ServiceDesc, srv)
+}
+
+func _FunctionService_ListFunctions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFunctionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FunctionServiceServer).ListFunctions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FunctionService_ListFunctions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FunctionServiceServer).ListFunctions(ctx, req.(*ListFunctionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FunctionService_CallFunction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CallFunctionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FunctionServiceServer).CallFunction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FunctionService_CallFunction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FunctionServiceServer).CallFunction(ctx, req.(*CallFunctionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// FunctionService_ServiceDesc is the grpc.ServiceDesc for FunctionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FunctionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mcp.v1.FunctionService",
+	HandlerType: (*FunctionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListFunctions",
+			Handler:    _FunctionService_ListFunctions_Handler,
+		},
+		{
+			MethodName: "CallFunction",
+			Handler:    _FunctionService_CallFunction_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "mcp/v1/functions.proto",
+}
@@ -0,0 +1,5 @@
+// Package mcppb holds the protobuf messages and gRPC stubs of the MCP gRPC
+// API, generated from the definitions in proto/mcp/v1.
+package mcppb
+
+//go:generate protoc --proto_path=../../proto --go_out=../.. --go_opt=module=github.com/ivikasavnish/go-mcp --go-grpc_out=../.. --go-grpc_opt=module=github.com/ivikasavnish/go-mcp mcp/v1/context.proto mcp/v1/functions.proto mcp/v1/analysis.proto
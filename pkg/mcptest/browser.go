@@ -0,0 +1,185 @@
+package mcptest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/browser"
+	"github.com/ivikasavnish/go-mcp/pkg/mcp"
+)
+
+// FakeBrowser is the browser backend of a test server. Instances start
+// instantly, answer navigations with scripted pages and record the
+// automation sequences they run without executing them.
+type FakeBrowser struct {
+	pages       map[string]browser.NavigationResult
+	elements    map[string]map[string][]string // Texts by URL and selector
+	failures    map[string]error               // By URL
+	stepErrors  map[string]error               // By step type
+	navigations []string
+	sequences   []browser.AutomationSequence
+	running     int
+	mu          sync.Mutex
+}
+
+// NewFakeBrowser creates a browser backend. Pages that are not scripted load
+// successfully with status 200 and no title.
+func NewFakeBrowser() *FakeBrowser {
+	return &FakeBrowser{
+		pages:      make(map[string]browser.NavigationResult),
+		elements:   make(map[string]map[string][]string),
+		failures:   make(map[string]error),
+		stepErrors: make(map[string]error),
+	}
+}
+
+// Page scripts the result of navigating to url
+func (f *FakeBrowser) Page(url string, result browser.NavigationResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pages[url] = result
+}
+
+// Elements scripts the texts of the elements matching selector on the page
+// at url, for scraping
+func (f *FakeBrowser) Elements(url, selector string, texts ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.elements[url] == nil {
+		f.elements[url] = make(map[string][]string)
+	}
+	f.elements[url][selector] = texts
+}
+
+// FailNavigation makes navigating to url fail with err
+func (f *FakeBrowser) FailNavigation(url string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[url] = err
+}
+
+// FailStep makes automation steps of a type fail with err
+func (f *FakeBrowser) FailStep(stepType string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stepErrors[stepType] = err
+}
+
+// Navigations returns the URLs navigated to so far, including navigate steps
+// of automation sequences
+func (f *FakeBrowser) Navigations() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.navigations...)
+}
+
+// Sequences returns the automation sequences run so far
+func (f *FakeBrowser) Sequences() []browser.AutomationSequence {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]browser.AutomationSequence(nil), f.sequences...)
+}
+
+// Running returns the number of started instances
+func (f *FakeBrowser) Running() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running
+}
+
+// Launch creates a stub instance; it is the mcp.BrowserLauncher of test
+// servers
+func (f *FakeBrowser) Launch(config *browser.BrowserConfig) mcp.BrowserInstance {
+	return &fakeInstance{browser: f}
+}
+
+func (f *FakeBrowser) navigate(url string) (*browser.NavigationResult, error) {
+	f.navigations = append(f.navigations, url)
+	if err := f.failures[url]; err != nil {
+		return nil, err
+	}
+	if result, ok := f.pages[url]; ok {
+		return &result, nil
+	}
+	return &browser.NavigationResult{Success: true, URL: url, StatusCode: 200}, nil
+}
+
+// fakeInstance is a browser instance of a FakeBrowser
+type fakeInstance struct {
+	browser *FakeBrowser
+	started bool
+	url     string // Last page navigated to
+}
+
+func (b *fakeInstance) Start() error {
+	b.browser.mu.Lock()
+	defer b.browser.mu.Unlock()
+	if !b.started {
+		b.started = true
+		b.browser.running++
+	}
+	return nil
+}
+
+func (b *fakeInstance) Stop() error {
+	b.browser.mu.Lock()
+	defer b.browser.mu.Unlock()
+	if b.started {
+		b.started = false
+		b.browser.running--
+	}
+	return nil
+}
+
+func (b *fakeInstance) Navigate(url string) (*browser.NavigationResult, error) {
+	b.browser.mu.Lock()
+	defer b.browser.mu.Unlock()
+	result, err := b.browser.navigate(url)
+	if err == nil {
+		b.url = url
+	}
+	return result, err
+}
+
+// Scrape returns the scripted texts of the current page like the real
+// browser: a string for a single match and a list otherwise
+func (b *fakeInstance) Scrape(selectors map[string]string) (*browser.ScrapingResult, error) {
+	b.browser.mu.Lock()
+	defer b.browser.mu.Unlock()
+	if b.url == "" {
+		return nil, fmt.Errorf("no page loaded")
+	}
+
+	result := &browser.ScrapingResult{URL: b.url, Data: make(map[string]interface{}), Timestamp: time.Now()}
+	for key, selector := range selectors {
+		texts := b.browser.elements[b.url][selector]
+		if len(texts) == 1 {
+			result.Data[key] = texts[0]
+		} else {
+			result.Data[key] = append([]string{}, texts...)
+		}
+	}
+	return result, nil
+}
+
+func (b *fakeInstance) ExecuteSequence(seq *browser.AutomationSequence) error {
+	f := b.browser
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sequences = append(f.sequences, *seq)
+	for _, step := range seq.Steps {
+		if err := f.stepErrors[step.Type]; err != nil {
+			return fmt.Errorf("step %s failed: %w", step.Type, err)
+		}
+		if step.Type != "navigate" {
+			continue
+		}
+		url, _ := step.Params["url"].(string)
+		if _, err := f.navigate(url); err != nil {
+			return fmt.Errorf("step %s failed: %w", step.Type, err)
+		}
+	}
+	return nil
+}
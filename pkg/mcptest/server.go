@@ -0,0 +1,236 @@
+// Package mcptest runs an in-process go-mcp server for integration tests.
+// SSH connections go to fake hosts, browsers are scripted stubs and the
+// context store can be seeded, so tests need neither SSH servers nor
+// Chromium.
+package mcptest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/mcp"
+)
+
+// Server is a go-mcp server listening on a local httptest server. It is
+// shut down when the test finishes.
+type Server struct {
+	*mcp.Server
+	URL     string // Base URL of the current API version, e.g. http://127.0.0.1:1234/v1
+	Store   mcp.Store
+	SSH     *FakeSSH
+	Browser *FakeBrowser
+
+	t    testing.TB
+	http *httptest.Server
+}
+
+// Option configures a test server
+type Option func(*options)
+
+type options struct {
+	config   *mcp.ServerConfig
+	contexts []*mcp.Context
+	server   []mcp.ServerOption
+}
+
+// WithConfig replaces the server configuration. By default only the
+// function, SSH and browser features are enabled.
+func WithConfig(cfg *mcp.ServerConfig) Option {
+	return func(o *options) {
+		o.config = cfg
+	}
+}
+
+// WithContexts seeds the store with contexts
+func WithContexts(contexts ...*mcp.Context) Option {
+	return func(o *options) {
+		o.contexts = append(o.contexts, contexts...)
+	}
+}
+
+// WithServerOptions passes options on to mcp.NewServer
+func WithServerOptions(opts ...mcp.ServerOption) Option {
+	return func(o *options) {
+		o.server = append(o.server, opts...)
+	}
+}
+
+// DefaultConfig returns the configuration of test servers: only features
+// that need no local tooling, and no compression
+func DefaultConfig() *mcp.ServerConfig {
+	cfg := mcp.DefaultConfig()
+	for name := range cfg.Features {
+		cfg.Features[name] = false
+	}
+	for _, name := range []string{mcp.FeatureFunctions, mcp.FeatureSSH, mcp.FeatureBrowser} {
+		cfg.Features[name] = true
+	}
+	cfg.Compression.Enabled = false
+	return cfg
+}
+
+// NewServer starts a test server with every enabled feature registered
+func NewServer(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	o := &options{config: DefaultConfig()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.config.Validate(); err != nil {
+		t.Fatalf("mcptest: invalid config: %v", err)
+	}
+
+	store := mcp.NewMemoryStore()
+	now := time.Now()
+	for _, c := range o.contexts {
+		c = c.Clone()
+		if c.CreatedAt.IsZero() {
+			c.CreatedAt = now
+		}
+		if c.UpdatedAt.IsZero() {
+			c.UpdatedAt = c.CreatedAt
+		}
+		if err := store.Create(c); err != nil {
+			t.Fatalf("mcptest: failed to seed context %s: %v", c.ID, err)
+		}
+	}
+
+	s := &Server{Store: store, SSH: NewFakeSSH(), Browser: NewFakeBrowser(), t: t}
+	serverOpts := append([]mcp.ServerOption{
+		mcp.WithConfig(o.config),
+		mcp.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		mcp.WithSSHDialer(s.SSH.Dial),
+		mcp.WithBrowserLauncher(s.Browser.Launch),
+	}, o.server...)
+	s.Server = mcp.NewServer(store, serverOpts...)
+	if err := s.EnableFeatures(); err != nil {
+		t.Fatalf("mcptest: failed to enable features: %v", err)
+	}
+
+	s.http = httptest.NewServer(s.Server)
+	s.URL = s.http.URL + "/" + mcp.APIVersion
+	t.Cleanup(func() {
+		s.http.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("mcptest: shutdown failed: %v", err)
+		}
+	})
+	return s
+}
+
+// Client returns an HTTP client for the server
+func (s *Server) Client() *http.Client {
+	return s.http.Client()
+}
+
+// Do sends a request to a path relative to URL, with body encoded as JSON
+// unless it is nil, decodes the response into out unless it is nil, and
+// returns the status code
+func (s *Server) Do(method, path string, body, out interface{}) int {
+	s.t.Helper()
+
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("mcptest: failed to encode request: %v", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.URL+path, r)
+	if err != nil {
+		s.t.Fatalf("mcptest: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		s.t.Fatalf("mcptest: %s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			s.t.Fatalf("mcptest: failed to decode %s %s response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// RequireContext returns a stored context, failing the test now if it does
+// not exist
+func (s *Server) RequireContext(id string) *mcp.Context {
+	s.t.Helper()
+	c, err := s.Store.Get(id)
+	if err != nil {
+		s.t.Fatalf("mcptest: context %s: %v", id, err)
+	}
+	return c
+}
+
+// AssertContext checks that a context exists with the given metadata. Values
+// are compared as they round trip through JSON, so numbers may be given as
+// any numeric type.
+func (s *Server) AssertContext(id string, metadata map[string]interface{}) bool {
+	s.t.Helper()
+	c, err := s.Store.Get(id)
+	if err != nil {
+		s.t.Errorf("mcptest: context %s: %v", id, err)
+		return false
+	}
+
+	want, got := normalize(s.t, metadata), normalize(s.t, c.Metadata)
+	if !reflect.DeepEqual(want, got) {
+		s.t.Errorf("mcptest: context %s has metadata %v, want %v", id, got, want)
+		return false
+	}
+	return true
+}
+
+// AssertNoContext checks that a context does not exist
+func (s *Server) AssertNoContext(id string) bool {
+	s.t.Helper()
+	_, err := s.Store.Get(id)
+	if !errors.Is(err, mcp.ErrContextNotFound) {
+		s.t.Errorf("mcptest: context %s exists, want none", id)
+		return false
+	}
+	return true
+}
+
+// AssertContextCount checks the number of stored contexts
+func (s *Server) AssertContextCount(n int) bool {
+	s.t.Helper()
+	if got := len(s.Store.List()); got != n {
+		s.t.Errorf("mcptest: store has %d contexts, want %d", got, n)
+		return false
+	}
+	return true
+}
+
+func normalize(t testing.TB, metadata map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		t.Fatalf("mcptest: failed to encode metadata: %v", err)
+	}
+	out := make(map[string]interface{})
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("mcptest: failed to decode metadata: %v", err)
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package mcptest
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ivikasavnish/go-mcp/pkg/browser"
+	"github.com/ivikasavnish/go-mcp/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Contexts(t *testing.T) {
+	s := NewServer(t, WithContexts(&mcp.Context{ID: "seeded", Metadata: map[string]interface{}{"n": 1}}))
+
+	s.AssertContext("seeded", map[string]interface{}{"n": 1})
+	s.AssertContextCount(1)
+
+	status := s.Do("POST", "/context/create", mcp.CreateContextRequest{ID: "new", Metadata: map[string]interface{}{"k": "v"}}, nil)
+	require.Equal(t, http.StatusCreated, status)
+	s.AssertContext("new", map[string]interface{}{"k": "v"})
+
+	require.Equal(t, http.StatusNoContent, s.Do("DELETE", "/context/delete?id=seeded", nil, nil))
+	s.AssertNoContext("seeded")
+	assert.Equal(t, "new", s.RequireContext("new").ID)
+}
+
+func TestServer_SSH(t *testing.T) {
+	s := NewServer(t)
+	s.SSH.Handle("uptime", mcp.CommandResult{Stdout: "up 3 days\n"})
+	s.SSH.Refuse("down.example.com", errors.New("connection refused"))
+
+	connect := func(id, host string) int {
+		return s.Do("POST", "/ssh/connect", mcp.SSHConnectionRequest{
+			ID: id, Config: mcp.SSHConfig{Host: host, Port: 22, User: "test", Password: "secret"},
+		}, nil)
+	}
+	require.Equal(t, http.StatusCreated, connect("web", "web.example.com"))
+	assert.Equal(t, http.StatusInternalServerError, connect("down", "down.example.com"))
+	assert.Equal(t, 1, s.SSH.Open())
+
+	var result mcp.CommandResult
+	require.Equal(t, http.StatusOK, s.Do("POST", "/ssh/web/exec", mcp.SSHCommandRequest{Command: "uptime"}, &result))
+	assert.Equal(t, "up 3 days\n", result.Stdout)
+	require.Equal(t, http.StatusOK, s.Do("POST", "/ssh/web/exec", mcp.SSHCommandRequest{Command: "whoami"}, &result))
+	assert.Equal(t, 127, result.ExitCode)
+	assert.Equal(t, []Command{{"web.example.com", "uptime"}, {"web.example.com", "whoami"}}, s.SSH.Commands())
+
+	local := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(local, []byte("debug=true"), 0644))
+	require.Equal(t, http.StatusOK, s.Do("POST", "/ssh/web/upload", mcp.SSHFileTransferRequest{LocalPath: local, RemotePath: "/etc/app"}, nil))
+	data, ok := s.SSH.File("web.example.com", "/etc/app")
+	require.True(t, ok)
+	assert.Equal(t, "debug=true", string(data))
+
+	require.Equal(t, http.StatusOK, s.Do("DELETE", "/ssh/web", nil, nil))
+	assert.Equal(t, 0, s.SSH.Open())
+}
+
+func TestServer_Browser(t *testing.T) {
+	s := NewServer(t)
+	s.Browser.Page("https://example.com", browser.NavigationResult{Success: true, URL: "https://example.com", Title: "Example"})
+	s.Browser.FailStep("click", errors.New("element not found"))
+
+	require.Equal(t, http.StatusCreated, s.Do("POST", "/browser/create", mcp.CreateBrowserRequest{ID: "b1"}, nil))
+	assert.Equal(t, 1, s.Browser.Running())
+
+	var result browser.NavigationResult
+	require.Equal(t, http.StatusOK, s.Do("POST", "/browser/b1/navigate", mcp.NavigateRequest{URL: "https://example.com"}, &result))
+	assert.Equal(t, "Example", result.Title)
+
+	s.Browser.Elements("https://example.com", "h1", "Example Domain")
+	var scraped browser.ScrapingResult
+	require.Equal(t, http.StatusOK, s.Do("POST", "/browser/b1/scrape", mcp.ScrapingRequest{Selectors: map[string]string{"heading": "h1"}}, &scraped))
+	assert.Equal(t, "Example Domain", scraped.Data["heading"])
+	assert.Equal(t, http.StatusNotFound, s.Do("POST", "/browser/b2/scrape", mcp.ScrapingRequest{}, nil))
+
+	seq := browser.AutomationSequence{Name: "login", Steps: []browser.AutomationStep{
+		{Type: "navigate", Params: map[string]interface{}{"url": "https://example.com/login"}},
+		{Type: "click", Params: map[string]interface{}{"selector": "#submit"}},
+	}}
+	assert.Equal(t, http.StatusInternalServerError, s.Do("POST", "/browser/b1/automate", mcp.AutomationRequest{Sequence: seq}, nil))
+	assert.Equal(t, []string{"https://example.com", "https://example.com/login"}, s.Browser.Navigations())
+	assert.Len(t, s.Browser.Sequences(), 1)
+
+	require.Equal(t, http.StatusOK, s.Do("DELETE", "/browser/b1", nil, nil))
+	assert.Equal(t, 0, s.Browser.Running())
+}
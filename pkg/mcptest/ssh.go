@@ -0,0 +1,166 @@
+package mcptest
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ivikasavnish/go-mcp/pkg/mcp"
+)
+
+// Command is a command run on a fake SSH host
+type Command struct {
+	Host    string
+	Command string
+}
+
+// CommandFunc answers commands no scripted result matches
+type CommandFunc func(host, command string) (*mcp.CommandResult, error)
+
+// FakeSSH is the SSH backend of a test server. Every host accepts any
+// credentials unless it is refused, answers commands with scripted results
+// and keeps uploaded files in memory. Connections to fake hosts cannot run
+// remote IDE tasks.
+type FakeSSH struct {
+	results  map[string]mcp.CommandResult
+	fallback CommandFunc
+	refused  map[string]error
+	files    map[string]map[string][]byte // Host to remote path to content
+	commands []Command
+	open     int
+	mu       sync.Mutex
+}
+
+// NewFakeSSH creates a fake SSH backend. Commands without a scripted result
+// exit with code 127.
+func NewFakeSSH() *FakeSSH {
+	return &FakeSSH{
+		results: make(map[string]mcp.CommandResult),
+		refused: make(map[string]error),
+		files:   make(map[string]map[string][]byte),
+	}
+}
+
+// Handle scripts the result of a command on every host
+func (f *FakeSSH) Handle(command string, result mcp.CommandResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result.Command = command
+	f.results[command] = result
+}
+
+// HandleFunc answers every command without a scripted result
+func (f *FakeSSH) HandleFunc(fn CommandFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fallback = fn
+}
+
+// Refuse makes connecting to a host fail with err
+func (f *FakeSSH) Refuse(host string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.refused[host] = err
+}
+
+// SetFile stores a file on a host, to be downloaded
+func (f *FakeSSH) SetFile(host, path string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.files[host] == nil {
+		f.files[host] = make(map[string][]byte)
+	}
+	f.files[host][path] = append([]byte(nil), data...)
+}
+
+// File returns a file stored on a host, e.g. by an upload
+func (f *FakeSSH) File(host, path string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.files[host][path]
+	return append([]byte(nil), data...), ok
+}
+
+// Commands returns the commands run so far, in order
+func (f *FakeSSH) Commands() []Command {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Command(nil), f.commands...)
+}
+
+// Open returns the number of open connections
+func (f *FakeSSH) Open() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.open
+}
+
+// Dial connects to a fake host; it is the mcp.SSHDialer of test servers
+func (f *FakeSSH) Dial(config mcp.SSHConfig) (mcp.SSHConn, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.refused[config.Host]; err != nil {
+		return nil, fmt.Errorf("failed to dial: %v", err)
+	}
+	f.open++
+	return &fakeConn{ssh: f, host: config.Host}, nil
+}
+
+// fakeConn is a connection to a fake host
+type fakeConn struct {
+	ssh    *FakeSSH
+	host   string
+	closed bool
+}
+
+func (c *fakeConn) ExecuteCommand(command string) (*mcp.CommandResult, error) {
+	f := c.ssh
+	f.mu.Lock()
+	if c.closed {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("connection closed")
+	}
+	f.commands = append(f.commands, Command{Host: c.host, Command: command})
+	result, ok := f.results[command]
+	fallback := f.fallback
+	f.mu.Unlock()
+
+	if ok {
+		return &result, nil
+	}
+	if fallback != nil {
+		return fallback(c.host, command)
+	}
+	return &mcp.CommandResult{
+		Command:  command,
+		Stderr:   fmt.Sprintf("%s: command not found\n", command),
+		ExitCode: 127,
+	}, nil
+}
+
+func (c *fakeConn) UploadFile(localPath, remotePath string) error {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %v", err)
+	}
+	c.ssh.SetFile(c.host, remotePath, data)
+	return nil
+}
+
+func (c *fakeConn) DownloadFile(remotePath, localPath string) error {
+	data, ok := c.ssh.File(c.host, remotePath)
+	if !ok {
+		return fmt.Errorf("failed to open remote file: %s does not exist", remotePath)
+	}
+	return os.WriteFile(localPath, data, 0644)
+}
+
+func (c *fakeConn) Close() error {
+	c.ssh.mu.Lock()
+	defer c.ssh.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		c.ssh.open--
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivikasavnish/go-mcp/pkg/curlprocessor"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification of inferred
+// fragments
+const OpenAPIVersion = "3.0.3"
+
+// skippedHeaders are added by the HTTP stack or proxies rather than the
+// application, so conversions leave them out
+var skippedHeaders = map[string]bool{
+	"Accept-Encoding":     true,
+	"Connection":          true,
+	"Content-Length":      true,
+	"Forwarded":           true,
+	"Proxy-Authorization": true,
+	"Proxy-Connection":    true,
+	"X-Forwarded-For":     true,
+	"X-Forwarded-Host":    true,
+	"X-Forwarded-Proto":   true,
+}
+
+// idSegment matches path segments that look like resource IDs: numbers,
+// UUIDs and long hex strings
+var idSegment = regexp.MustCompile(`^(\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// CurlCollection converts the requests of exchanges into a curl collection
+// like the ones imported through the curl processor
+func CurlCollection(name string, exchanges []Exchange) *curlprocessor.CurlCollection {
+	collection := &curlprocessor.CurlCollection{Name: name, Commands: make([]curlprocessor.CurlCommand, 0, len(exchanges))}
+	for _, e := range exchanges {
+		cmd := curlprocessor.CurlCommand{
+			Method:      e.Method,
+			URL:         e.URL,
+			Headers:     make(map[string]string),
+			Body:        e.RequestBody,
+			QueryParams: make(url.Values),
+		}
+		if u, err := url.Parse(e.URL); err == nil {
+			cmd.QueryParams = u.Query()
+		}
+		for k := range e.RequestHeader {
+			if !skippedHeaders[k] {
+				cmd.Headers[k] = e.RequestHeader.Get(k)
+			}
+		}
+		collection.Commands = append(collection.Commands, cmd)
+	}
+	return collection
+}
+
+// CurlCommand renders the request of an exchange as a curl command line
+func CurlCommand(e Exchange) string {
+	var b strings.Builder
+	b.WriteString("curl")
+	if e.Method != http.MethodGet {
+		b.WriteString(" -X " + e.Method)
+	}
+	b.WriteString(" " + shellQuote(e.URL))
+
+	names := make([]string, 0, len(e.RequestHeader))
+	for k := range e.RequestHeader {
+		if !skippedHeaders[k] {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		for _, v := range e.RequestHeader[k] {
+			b.WriteString(" -H " + shellQuote(k+": "+v))
+		}
+	}
+	if e.RequestBody != "" {
+		b.WriteString(" --data-raw " + shellQuote(e.RequestBody))
+	}
+	return b.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// operation collects what the exchanges of one method and path template
+// revealed about it
+type operation struct {
+	params      map[string]map[string]interface{} // By "in:name"
+	requestBody map[string]interface{}
+	responses   map[int]map[string]interface{} // Schema by status, nil for non-JSON bodies
+}
+
+// OpenAPI infers an OpenAPI fragment from exchanges. Path segments that look
+// like IDs become path parameters, and JSON bodies are described by schemas
+// merged over every exchange of an operation.
+func OpenAPI(title string, exchanges []Exchange) map[string]interface{} {
+	operations := make(map[string]map[string]*operation)
+	servers := make(map[string]bool)
+
+	for _, e := range exchanges {
+		u, err := url.Parse(e.URL)
+		if err != nil || e.Error != "" {
+			continue
+		}
+		servers[u.Scheme+"://"+u.Host] = true
+
+		path, pathParams := templatePath(u.Path)
+		if operations[path] == nil {
+			operations[path] = make(map[string]*operation)
+		}
+		method := strings.ToLower(e.Method)
+		op := operations[path][method]
+		if op == nil {
+			op = &operation{params: make(map[string]map[string]interface{}), responses: make(map[int]map[string]interface{})}
+			operations[path][method] = op
+		}
+
+		for _, name := range pathParams {
+			op.params["path:"+name] = map[string]interface{}{
+				"name": name, "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+			}
+		}
+		for name := range u.Query() {
+			op.params["query:"+name] = map[string]interface{}{
+				"name": name, "in": "query", "schema": map[string]interface{}{"type": "string"},
+			}
+		}
+		if schema := jsonBodySchema(e.RequestHeader, e.RequestBody, e.Truncated); schema != nil {
+			op.requestBody = mergeSchema(op.requestBody, schema)
+		}
+		if e.StatusCode > 0 {
+			schema := jsonBodySchema(e.ResponseHeader, e.ResponseBody, e.Truncated)
+			op.responses[e.StatusCode] = mergeSchema(op.responses[e.StatusCode], schema)
+		}
+	}
+
+	paths := make(map[string]interface{}, len(operations))
+	for path, methods := range operations {
+		item := make(map[string]interface{}, len(methods))
+		for method, op := range methods {
+			item[method] = op.render(strings.ToUpper(method) + " " + path)
+		}
+		paths[path] = item
+	}
+
+	serverList := make([]interface{}, 0, len(servers))
+	for _, server := range sortedKeys(servers) {
+		serverList = append(serverList, map[string]interface{}{"url": server})
+	}
+
+	return map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info":    map[string]interface{}{"title": title, "version": "recorded"},
+		"servers": serverList,
+		"paths":   paths,
+	}
+}
+
+func (op *operation) render(summary string) map[string]interface{} {
+	rendered := map[string]interface{}{"summary": summary}
+
+	if len(op.params) > 0 {
+		keys := make([]string, 0, len(op.params))
+		for k := range op.params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		params := make([]interface{}, 0, len(keys))
+		for _, k := range keys {
+			params = append(params, op.params[k])
+		}
+		rendered["parameters"] = params
+	}
+
+	if op.requestBody != nil {
+		rendered["requestBody"] = map[string]interface{}{"content": jsonContent(op.requestBody)}
+	}
+
+	responses := make(map[string]interface{}, len(op.responses))
+	for status, schema := range op.responses {
+		response := map[string]interface{}{"description": http.StatusText(status)}
+		if schema != nil {
+			response["content"] = jsonContent(schema)
+		}
+		responses[fmt.Sprint(status)] = response
+	}
+	rendered["responses"] = responses
+	return rendered
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// templatePath replaces ID segments of a path by parameters named id, id2,
+// id3, ... and returns the template with the parameter names
+func templatePath(path string) (string, []string) {
+	if path == "" {
+		return "/", nil
+	}
+	segments := strings.Split(path, "/")
+	var names []string
+	for i, segment := range segments {
+		if !idSegment.MatchString(segment) {
+			continue
+		}
+		name := "id"
+		if len(names) > 0 {
+			name = fmt.Sprintf("id%d", len(names)+1)
+		}
+		names = append(names, name)
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), names
+}
+
+// jsonBodySchema infers the schema of a complete JSON body, or returns nil
+func jsonBodySchema(header http.Header, body string, truncated bool) map[string]interface{} {
+	if body == "" || truncated {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return nil
+	}
+	return inferSchema(v)
+}
+
+// inferSchema describes a decoded JSON value
+func inferSchema(v interface{}) map[string]interface{} {
+	switch v := v.(type) {
+	case nil:
+		return map[string]interface{}{"nullable": true}
+	case bool:
+		return map[string]interface{}{"type": "boolean"}
+	case float64:
+		if v == math.Trunc(v) {
+			return map[string]interface{}{"type": "integer"}
+		}
+		return map[string]interface{}{"type": "number"}
+	case string:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		return map[string]interface{}{"type": "string"}
+	case []interface{}:
+		var items map[string]interface{}
+		for _, item := range v {
+			items = mergeSchema(items, inferSchema(item))
+		}
+		if items == nil {
+			items = map[string]interface{}{}
+		}
+		return map[string]interface{}{"type": "array", "items": items}
+	case map[string]interface{}:
+		properties := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			properties[k] = inferSchema(item)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	}
+	return map[string]interface{}{}
+}
+
+// mergeSchema combines two inferred schemas: objects get the properties of
+// both, integers widen to numbers and nulls make the other schema nullable.
+// Otherwise the first schema wins.
+func mergeSchema(a, b map[string]interface{}) map[string]interface{} {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	}
+	if a["type"] == nil && a["nullable"] == true {
+		return withNullable(b)
+	}
+	if b["type"] == nil && b["nullable"] == true {
+		return withNullable(a)
+	}
+
+	merged := make(map[string]interface{}, len(a))
+	for k, v := range a {
+		merged[k] = v
+	}
+	switch {
+	case a["type"] == "integer" && b["type"] == "number":
+		merged["type"] = "number"
+	case a["type"] == "object" && b["type"] == "object":
+		properties := make(map[string]interface{})
+		for k, v := range a["properties"].(map[string]interface{}) {
+			properties[k] = v
+		}
+		for k, v := range b["properties"].(map[string]interface{}) {
+			if existing, ok := properties[k]; ok {
+				properties[k] = mergeSchema(existing.(map[string]interface{}), v.(map[string]interface{}))
+			} else {
+				properties[k] = v
+			}
+		}
+		merged["properties"] = properties
+	case a["type"] == "array" && b["type"] == "array":
+		merged["items"] = mergeSchema(a["items"].(map[string]interface{}), b["items"].(map[string]interface{}))
+	}
+	return merged
+}
+
+func withNullable(schema map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{"nullable": true}
+	for k, v := range schema {
+		merged[k] = v
+	}
+	return merged
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
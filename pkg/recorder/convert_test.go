@@ -0,0 +1,80 @@
+package recorder
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonHeader() http.Header {
+	return http.Header{"Content-Type": {"application/json"}}
+}
+
+func TestCurlCommand(t *testing.T) {
+	e := Exchange{
+		Method:        "POST",
+		URL:           "https://api.example.com/notes",
+		RequestHeader: http.Header{"Content-Type": {"application/json"}, "Content-Length": {"14"}},
+		RequestBody:   `{"text":"it's"}`,
+	}
+	assert.Equal(t, `curl -X POST 'https://api.example.com/notes' -H 'Content-Type: application/json' --data-raw '{"text":"it'\''s"}'`, CurlCommand(e))
+	assert.Equal(t, `curl 'https://api.example.com/'`, CurlCommand(Exchange{Method: "GET", URL: "https://api.example.com/"}))
+
+	collection := CurlCollection("notes", []Exchange{e})
+	require.Len(t, collection.Commands, 1)
+	assert.Equal(t, "POST", collection.Commands[0].Method)
+	assert.Equal(t, map[string]string{"Content-Type": "application/json"}, collection.Commands[0].Headers)
+}
+
+func TestOpenAPI(t *testing.T) {
+	exchanges := []Exchange{
+		{
+			Method: "GET", URL: "https://api.example.com/users/1?fields=name",
+			StatusCode: 200, ResponseHeader: jsonHeader(), ResponseBody: `{"id":1,"name":"ada","manager":null}`,
+		},
+		{
+			Method: "GET", URL: "https://api.example.com/users/550e8400-e29b-41d4-a716-446655440000",
+			StatusCode: 200, ResponseHeader: jsonHeader(), ResponseBody: `{"id":2.5,"manager":{"id":1},"tags":["a"]}`,
+		},
+		{
+			Method: "POST", URL: "https://api.example.com/users",
+			RequestHeader: jsonHeader(), RequestBody: `{"name":"bob"}`,
+			StatusCode: 201, ResponseHeader: http.Header{"Content-Type": {"text/plain"}}, ResponseBody: "created",
+		},
+		{Method: "GET", URL: "https://down.example.com/", Error: "connection refused"},
+	}
+
+	spec := OpenAPI("users", exchanges)
+	assert.Equal(t, OpenAPIVersion, spec["openapi"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"url": "https://api.example.com"}}, spec["servers"])
+
+	paths := spec["paths"].(map[string]interface{})
+	require.Len(t, paths, 2)
+	get := paths["/users/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Len(t, get["parameters"], 2)
+	schema := get["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+	properties := schema["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "number"}, properties["id"])
+	assert.Equal(t, map[string]interface{}{"type": "string"}, properties["name"])
+	assert.Equal(t, true, properties["manager"].(map[string]interface{})["nullable"])
+	assert.Equal(t, "object", properties["manager"].(map[string]interface{})["type"])
+	assert.Equal(t, "array", properties["tags"].(map[string]interface{})["type"])
+
+	post := paths["/users"].(map[string]interface{})["post"].(map[string]interface{})
+	assert.Contains(t, post, "requestBody")
+	created := post["responses"].(map[string]interface{})["201"].(map[string]interface{})
+	assert.Equal(t, "Created", created["description"])
+	assert.NotContains(t, created, "content")
+}
+
+func TestTemplatePath(t *testing.T) {
+	path, names := templatePath("/orgs/7/repos/deadbeefdeadbeef/files")
+	assert.Equal(t, "/orgs/{id}/repos/{id2}/files", path)
+	assert.Equal(t, []string{"id", "id2"}, names)
+
+	path, names = templatePath("")
+	assert.Equal(t, "/", path)
+	assert.Empty(t, names)
+}
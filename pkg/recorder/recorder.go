@@ -0,0 +1,290 @@
+// Package recorder is an HTTP proxy that records the traffic passing through
+// it. Recorded exchanges are grouped into sessions, tagged by a request
+// header, and can be converted into curl collections and OpenAPI fragments.
+// It captures API traffic of any client, where the browser only captures
+// what a page does.
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults for Config
+const (
+	DefaultSessionHeader = "X-MCP-Session"
+	DefaultSession       = "default"
+	DefaultMaxBodyBytes  = 1 << 20
+	DefaultMaxExchanges  = 1000
+)
+
+// Exchange is a recorded request and its response. Bodies are cut at the
+// configured maximum size.
+type Exchange struct {
+	Session        string        `json:"session"`
+	Time           time.Time     `json:"time"`
+	Duration       time.Duration `json:"duration"`
+	Method         string        `json:"method"`
+	URL            string        `json:"url"`
+	RequestHeader  http.Header   `json:"request_header"`
+	RequestBody    string        `json:"request_body,omitempty"`
+	StatusCode     int           `json:"status_code"`
+	ResponseHeader http.Header   `json:"response_header"`
+	ResponseBody   string        `json:"response_body,omitempty"`
+	Truncated      bool          `json:"truncated,omitempty"`
+	Error          string        `json:"error,omitempty"` // The upstream could not be reached
+}
+
+// Session summarizes the exchanges recorded for a session tag
+type Session struct {
+	Name      string    `json:"name"`
+	Exchanges int       `json:"exchanges"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Config configures a Recorder
+type Config struct {
+	// Target is the upstream of reverse proxy mode. Without a target the
+	// recorder is a forward proxy for clients configured with HTTP_PROXY.
+	Target *url.URL
+	// SessionHeader tags requests with a session; it is removed before the
+	// request is forwarded
+	SessionHeader string
+	MaxBodyBytes  int64
+	MaxExchanges  int // Per session; the oldest exchanges are dropped first
+	// OnRecord is called with the exchanges of a session after each exchange
+	// recorded for it
+	OnRecord func(session string, exchanges []Exchange)
+	Logger   *slog.Logger
+}
+
+// Recorder is a recording proxy. It is an http.Handler.
+type Recorder struct {
+	config   Config
+	proxy    *httputil.ReverseProxy
+	sessions map[string][]Exchange
+	mu       sync.Mutex
+	recordMu sync.Mutex // Keeps OnRecord calls in the order of exchanges
+}
+
+// New creates a recorder, applying defaults for unset config values
+func New(config Config) *Recorder {
+	if config.SessionHeader == "" {
+		config.SessionHeader = DefaultSessionHeader
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if config.MaxExchanges <= 0 {
+		config.MaxExchanges = DefaultMaxExchanges
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	r := &Recorder{config: config, sessions: make(map[string][]Exchange)}
+	r.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if config.Target != nil {
+				pr.SetURL(config.Target)
+				pr.SetXForwarded()
+			}
+			// Forward proxy requests carry the absolute upstream URL
+		},
+		ModifyResponse: r.captureResponse,
+		ErrorHandler:   r.handleError,
+	}
+	return r
+}
+
+// pending is an exchange whose response is still being read, stored in the
+// request context
+type pending struct {
+	exchange Exchange
+	request  *capture
+	start    time.Time
+}
+
+type pendingKey struct{}
+
+func contextWithPending(ctx context.Context, p *pending) context.Context {
+	return context.WithValue(ctx, pendingKey{}, p)
+}
+
+func pendingFromContext(ctx context.Context) *pending {
+	p, _ := ctx.Value(pendingKey{}).(*pending)
+	return p
+}
+
+// ServeHTTP forwards a request and records it with its response. HTTPS
+// tunnels are refused since their traffic could not be recorded.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodConnect {
+		http.Error(w, "HTTPS tunnels cannot be recorded; use reverse proxy mode with an https target", http.StatusNotImplemented)
+		return
+	}
+	if r.config.Target == nil && !req.URL.IsAbs() {
+		http.Error(w, "forward proxy requests need an absolute URL", http.StatusBadRequest)
+		return
+	}
+
+	session := req.Header.Get(r.config.SessionHeader)
+	if session == "" {
+		session = DefaultSession
+	}
+	req.Header.Del(r.config.SessionHeader)
+
+	target := *req.URL
+	if r.config.Target != nil {
+		target = *r.config.Target.JoinPath(req.URL.Path)
+		target.RawQuery = req.URL.RawQuery
+	}
+
+	p := &pending{
+		exchange: Exchange{
+			Session:       session,
+			Time:          time.Now(),
+			Method:        req.Method,
+			URL:           target.String(),
+			RequestHeader: req.Header.Clone(),
+		},
+		request: newCapture(req.Body, r.config.MaxBodyBytes),
+		start:   time.Now(),
+	}
+	req.Body = p.request
+	r.proxy.ServeHTTP(w, req.WithContext(contextWithPending(req.Context(), p)))
+}
+
+// captureResponse records the exchange once the response body is read
+func (r *Recorder) captureResponse(resp *http.Response) error {
+	p := pendingFromContext(resp.Request.Context())
+	if p == nil {
+		return nil
+	}
+	p.exchange.StatusCode = resp.StatusCode
+	p.exchange.ResponseHeader = resp.Header.Clone()
+
+	body := newCapture(resp.Body, r.config.MaxBodyBytes)
+	body.onClose = func() {
+		p.exchange.Duration = time.Since(p.start)
+		p.exchange.RequestBody = p.request.String()
+		p.exchange.ResponseBody = body.String()
+		p.exchange.Truncated = p.request.truncated || body.truncated
+		r.record(p.exchange)
+	}
+	resp.Body = body
+	return nil
+}
+
+func (r *Recorder) handleError(w http.ResponseWriter, req *http.Request, err error) {
+	r.config.Logger.Warn("proxy request failed", "url", req.URL.String(), "error", err)
+	if p := pendingFromContext(req.Context()); p != nil {
+		p.exchange.Duration = time.Since(p.start)
+		p.exchange.RequestBody = p.request.String()
+		p.exchange.StatusCode = http.StatusBadGateway
+		p.exchange.Error = err.Error()
+		r.record(p.exchange)
+	}
+	http.Error(w, fmt.Sprintf("upstream request failed: %v", err), http.StatusBadGateway)
+}
+
+func (r *Recorder) record(e Exchange) {
+	r.recordMu.Lock()
+	defer r.recordMu.Unlock()
+
+	r.mu.Lock()
+	exchanges := append(r.sessions[e.Session], e)
+	if len(exchanges) > r.config.MaxExchanges {
+		exchanges = exchanges[len(exchanges)-r.config.MaxExchanges:]
+	}
+	r.sessions[e.Session] = exchanges
+	snapshot := append([]Exchange(nil), exchanges...)
+	r.mu.Unlock()
+
+	r.config.Logger.Debug("proxy exchange recorded", "session", e.Session, "method", e.Method, "url", e.URL, "status", e.StatusCode)
+	if r.config.OnRecord != nil {
+		r.config.OnRecord(e.Session, snapshot)
+	}
+}
+
+// Sessions returns the recorded sessions sorted by name
+func (r *Recorder) Sessions() []Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := make([]Session, 0, len(r.sessions))
+	for name, exchanges := range r.sessions {
+		sessions = append(sessions, Session{
+			Name:      name,
+			Exchanges: len(exchanges),
+			LastSeen:  exchanges[len(exchanges)-1].Time,
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Name < sessions[j].Name })
+	return sessions
+}
+
+// Exchanges returns the exchanges of a session, oldest first, and whether the
+// session exists
+func (r *Recorder) Exchanges(session string) ([]Exchange, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	exchanges, ok := r.sessions[session]
+	return append([]Exchange(nil), exchanges...), ok
+}
+
+// Reset forgets the exchanges of a session and reports whether it existed
+func (r *Recorder) Reset(session string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.sessions[session]
+	delete(r.sessions, session)
+	return ok
+}
+
+// capture passes a body through, keeping a copy of its first max bytes
+type capture struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	max       int64
+	truncated bool
+	onClose   func()
+	closeOnce sync.Once
+}
+
+func newCapture(body io.ReadCloser, max int64) *capture {
+	if body == nil {
+		body = http.NoBody
+	}
+	return &capture{ReadCloser: body, max: max}
+}
+
+func (c *capture) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	keep := min(int64(n), c.max-int64(c.buf.Len()))
+	c.buf.Write(p[:keep])
+	if int64(n) > keep {
+		c.truncated = true
+	}
+	return n, err
+}
+
+func (c *capture) Close() error {
+	err := c.ReadCloser.Close()
+	if c.onClose != nil {
+		c.closeOnce.Do(c.onClose)
+	}
+	return err
+}
+
+func (c *capture) String() string {
+	return c.buf.String()
+}
@@ -0,0 +1,133 @@
+package recorder
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Session-Seen", r.Header.Get(DefaultSessionHeader))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"path":"` + r.URL.Path + `","size":` + strings.Repeat("1", len(body)+1) + `}`))
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func TestRecorder_ReverseProxy(t *testing.T) {
+	upstream := newUpstream(t)
+	target, _ := url.Parse(upstream.URL + "/api")
+
+	var mu sync.Mutex
+	recorded := make(map[string]int)
+	rec := New(Config{
+		Target: target,
+		OnRecord: func(session string, exchanges []Exchange) {
+			mu.Lock()
+			recorded[session] = len(exchanges)
+			mu.Unlock()
+		},
+	})
+	proxy := httptest.NewServer(rec)
+	defer proxy.Close()
+
+	req, _ := http.NewRequest("POST", proxy.URL+"/users/42?expand=true", strings.NewReader(`{"name":"ada"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DefaultSessionHeader, "signup")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, `{"path":"/api/users/42","size":111111111111111}`, string(body))
+	assert.Empty(t, resp.Header.Get("X-Session-Seen"), "session header is not forwarded")
+
+	resp, err = http.Get(proxy.URL + "/health")
+	require.NoError(t, err)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	assert.Equal(t, []string{"default", "signup"}, sessionNames(rec.Sessions()))
+	exchanges, ok := rec.Exchanges("signup")
+	require.True(t, ok)
+	require.Len(t, exchanges, 1)
+	e := exchanges[0]
+	assert.Equal(t, "POST", e.Method)
+	assert.Equal(t, upstream.URL+"/api/users/42?expand=true", e.URL)
+	assert.Equal(t, `{"name":"ada"}`, e.RequestBody)
+	assert.Equal(t, string(body), e.ResponseBody)
+	assert.Equal(t, http.StatusCreated, e.StatusCode)
+	assert.Equal(t, "application/json", e.ResponseHeader.Get("Content-Type"))
+	assert.False(t, e.Truncated)
+
+	mu.Lock()
+	assert.Equal(t, map[string]int{"signup": 1, "default": 1}, recorded)
+	mu.Unlock()
+
+	assert.True(t, rec.Reset("signup"))
+	assert.False(t, rec.Reset("signup"))
+	_, ok = rec.Exchanges("signup")
+	assert.False(t, ok)
+}
+
+func TestRecorder_ForwardProxy(t *testing.T) {
+	upstream := newUpstream(t)
+	rec := New(Config{MaxBodyBytes: 4, MaxExchanges: 2})
+	proxy := httptest.NewServer(rec)
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	for _, path := range []string{"/a", "/b", "/c"} {
+		resp, err := client.Post(upstream.URL+path, "text/plain", strings.NewReader("payload"))
+		require.NoError(t, err)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	exchanges, ok := rec.Exchanges(DefaultSession)
+	require.True(t, ok)
+	require.Len(t, exchanges, 2, "oldest exchanges are dropped")
+	assert.Equal(t, upstream.URL+"/b", exchanges[0].URL)
+	assert.Equal(t, "payl", exchanges[1].RequestBody)
+	assert.True(t, exchanges[1].Truncated)
+
+	// Relative URLs only make sense in reverse proxy mode
+	resp, err := http.Get(proxy.URL + "/a")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestRecorder_UpstreamError(t *testing.T) {
+	target, _ := url.Parse("http://127.0.0.1:1")
+	rec := New(Config{Target: target})
+	w := httptest.NewRecorder()
+	rec.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+
+	exchanges, _ := rec.Exchanges(DefaultSession)
+	require.Len(t, exchanges, 1)
+	assert.Equal(t, http.StatusBadGateway, exchanges[0].StatusCode)
+	assert.NotEmpty(t, exchanges[0].Error)
+}
+
+func sessionNames(sessions []Session) []string {
+	names := make([]string, len(sessions))
+	for i, s := range sessions {
+		names[i] = s.Name
+	}
+	return names
+}
@@ -2,66 +2,25 @@
 package specprocessor
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"net/http"
 	"path/filepath"
 	"strings"
 
+	"github.com/ivikasavnish/go-mcp/pkg/client"
+	"github.com/ivikasavnish/go-mcp/pkg/contextid"
 	"gopkg.in/yaml.v3"
 )
 
-// MCPClient handles communication with the MCP server
-type MCPClient struct {
-	baseURL string
-	client  *http.Client
-}
-
-// NewMCPClient creates a new MCP client
-func NewMCPClient(baseURL string) *MCPClient {
-	return &MCPClient{
-		baseURL: baseURL,
-		client:  &http.Client{},
-	}
-}
-
-// CreateContext sends a request to create a new context in MCP
-func (c *MCPClient) CreateContext(id string, metadata map[string]interface{}) error {
-	payload := map[string]interface{}{
-		"id":       id,
-		"metadata": metadata,
-	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal context data: %w", err)
-	}
-
-	resp, err := c.client.Post(
-		fmt.Sprintf("%s/context/create", c.baseURL),
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create context: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("failed to create context, status: %d, body: %s", resp.StatusCode, string(body))
-	}
-
-	return nil
-}
-
 // Processor handles processing of API specifications
 type Processor struct {
-	mcpClient *MCPClient
-	logger    *log.Logger
+	client *client.Client
+	ids    *contextid.Generator
+	policy contextid.Policy
+	logger *log.Logger
 }
 
 // ProcessorOption defines options for creating a new Processor
@@ -74,11 +33,35 @@ func WithLogger(logger *log.Logger) ProcessorOption {
 	}
 }
 
+// WithClient sets the client used to store contexts, e.g. one with an API key
+func WithClient(c *client.Client) ProcessorOption {
+	return func(p *Processor) {
+		p.client = c
+	}
+}
+
+// WithIDGenerator sets how context IDs are derived from specification files
+func WithIDGenerator(ids *contextid.Generator) ProcessorOption {
+	return func(p *Processor) {
+		p.ids = ids
+	}
+}
+
+// WithCollisionPolicy sets what happens when a context ID is taken; the
+// import fails by default
+func WithCollisionPolicy(policy contextid.Policy) ProcessorOption {
+	return func(p *Processor) {
+		p.policy = policy
+	}
+}
+
 // NewProcessor creates a new specification processor
 func NewProcessor(mcpBaseURL string, opts ...ProcessorOption) *Processor {
 	p := &Processor{
-		mcpClient: NewMCPClient(mcpBaseURL),
-		logger:    log.New(ioutil.Discard, "", 0),
+		client: client.New(mcpBaseURL),
+		ids:    contextid.Default(),
+		policy: contextid.PolicyError,
+		logger: log.New(ioutil.Discard, "", 0),
 	}
 
 	for _, opt := range opts {
@@ -172,8 +155,7 @@ func (p *Processor) ProcessOpenAPISpec(filePath string) error {
 		"source": filePath,
 	}
 
-	contextID := fmt.Sprintf("openapi-%s", strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath)))
-	return p.mcpClient.CreateContext(contextID, metadata)
+	return p.createContext(contextid.Source{Type: "openapi", Path: filePath, Content: data}, metadata)
 }
 
 // ProcessPostmanCollection processes a Postman collection file
@@ -190,8 +172,11 @@ func (p *Processor) ProcessPostmanCollection(filePath string) error {
 		return fmt.Errorf("failed to parse Postman collection: %w", err)
 	}
 
-	// Validate that it's actually a Postman collection
-	if _, ok := collection["info"]; !ok {
+	// Validate that it's actually a Postman collection. OpenAPI specs have an
+	// info object too, but no items.
+	_, hasInfo := collection["info"]
+	_, hasItems := collection["item"]
+	if !hasInfo || !hasItems {
 		return fmt.Errorf("not a valid Postman collection")
 	}
 
@@ -201,6 +186,14 @@ func (p *Processor) ProcessPostmanCollection(filePath string) error {
 		"source":     filePath,
 	}
 
-	contextID := fmt.Sprintf("postman-%s", strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath)))
-	return p.mcpClient.CreateContext(contextID, metadata)
+	return p.createContext(contextid.Source{Type: "postman", Path: filePath, Content: data}, metadata)
+}
+
+func (p *Processor) createContext(src contextid.Source, metadata map[string]interface{}) error {
+	id, err := contextid.Create(context.Background(), p.client, p.policy, p.ids.ID(src), metadata)
+	if err != nil {
+		return fmt.Errorf("failed to create context: %w", err)
+	}
+	p.logger.Printf("Stored %s as context %s", src.Path, id)
+	return nil
 }
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/ivikasavnish/go-mcp/pkg/contextid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -42,7 +43,7 @@ func TestProcessor_ProcessOpenAPISpec(t *testing.T) {
 	// Create a test server
 	var receivedPayload map[string]interface{}
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		assert.Equal(t, "/context/create", r.URL.Path)
+		assert.Equal(t, "/v1/context/create", r.URL.Path)
 		assert.Equal(t, "POST", r.Method)
 
 		err := json.NewDecoder(r.Body).Decode(&receivedPayload)
@@ -58,7 +59,8 @@ func TestProcessor_ProcessOpenAPISpec(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify the created context
-	assert.Equal(t, "openapi-openapi", receivedPayload["id"])
+	assert.Equal(t, contextid.Default().ID(contextid.Source{Type: "openapi", Path: specPath}), receivedPayload["id"])
+	assert.Regexp(t, `^openapi-openapi-[0-9a-f]{8}$`, receivedPayload["id"])
 	metadata := receivedPayload["metadata"].(map[string]interface{})
 	assert.Equal(t, "openapi", metadata["type"])
 	assert.Equal(t, specPath, metadata["source"])
@@ -93,7 +95,7 @@ func TestProcessor_ProcessPostmanCollection(t *testing.T) {
 	// Create a test server
 	var receivedPayload map[string]interface{}
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		assert.Equal(t, "/context/create", r.URL.Path)
+		assert.Equal(t, "/v1/context/create", r.URL.Path)
 		assert.Equal(t, "POST", r.Method)
 
 		err := json.NewDecoder(r.Body).Decode(&receivedPayload)
@@ -109,7 +111,7 @@ func TestProcessor_ProcessPostmanCollection(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify the created context
-	assert.Equal(t, "postman-collection", receivedPayload["id"])
+	assert.Regexp(t, `^postman-collection-[0-9a-f]{8}$`, receivedPayload["id"])
 	metadata := receivedPayload["metadata"].(map[string]interface{})
 	assert.Equal(t, "postman", metadata["type"])
 	assert.Equal(t, collectionPath, metadata["source"])